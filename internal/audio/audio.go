@@ -1,12 +1,10 @@
 package audio
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -15,6 +13,7 @@ import (
 
 	ffmpeg "github.com/u2takey/ffmpeg-go"
 
+	"github.com/mgpai22/lipi/internal/errs"
 	ffmpegbin "github.com/mgpai22/lipi/internal/ffmpeg"
 )
 
@@ -28,10 +27,14 @@ type ChunkInfo struct {
 
 // settings for audio compression
 type CompressionOptions struct {
-	Format     string // Output format (mp3, aac, etc.)
+	Format     string // Output format (mp3, aac, opus, wav)
 	SampleRate int    // Sample rate in Hz
 	Channels   int    // Number of channels (1=mono, 2=stereo)
 	Bitrate    string // Bitrate (e.g., "64k", "128k")
+	// skip corrupt packets and decode errors instead of failing
+	IgnoreDecodeErrors bool
+	// excerpt of the input to compress: from Start, for Length when set
+	Start, Length time.Duration
 }
 
 // defaults for transcription
@@ -44,49 +47,38 @@ func DefaultCompressionOptions() CompressionOptions {
 	}
 }
 
-// JSON output from ffprobe
-type ffprobeOutput struct {
-	Format struct {
-		Duration string `json:"duration"`
-	} `json:"format"`
+// checks if the format can be used for compressed chunks
+func IsValidCompressionFormat(format string) bool {
+	switch format {
+	case "mp3", "aac", "opus", "wav":
+		return true
+	default:
+		return false
+	}
 }
 
-// duration of an audio/video file
-func GetDuration(filePath string) (time.Duration, error) {
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return 0, fmt.Errorf("file not found: %s", filePath)
+// file extension for a compression format
+func ExtensionForFormat(format string) string {
+	switch format {
+	case "aac":
+		return ".aac"
+	case "opus":
+		// Opus is muxed into an Ogg container, which every provider accepts
+		return ".ogg"
+	case "wav":
+		return ".wav"
+	default:
+		return ".mp3"
 	}
+}
 
-	ffprobePath, err := ffmpegbin.FFprobePath()
+// duration of an audio/video file
+func GetDuration(ctx context.Context, filePath string) (time.Duration, error) {
+	probe, err := ffmpegbin.Probe(ctx, filePath)
 	if err != nil {
 		return 0, err
 	}
-
-	cmd := exec.Command(ffprobePath,
-		"-v", "quiet",
-		"-print_format", "json",
-		"-show_format",
-		filePath,
-	)
-
-	var out bytes.Buffer
-	cmd.Stdout = &out
-
-	if err := cmd.Run(); err != nil {
-		return 0, fmt.Errorf("ffprobe failed: %w", err)
-	}
-
-	var probe ffprobeOutput
-	if err := json.Unmarshal(out.Bytes(), &probe); err != nil {
-		return 0, fmt.Errorf("failed to parse ffprobe output: %w", err)
-	}
-
-	var seconds float64
-	if _, err := fmt.Sscanf(probe.Format.Duration, "%f", &seconds); err != nil {
-		return 0, fmt.Errorf("failed to parse duration: %w", err)
-	}
-
-	return time.Duration(seconds * float64(time.Second)), nil
+	return probe.Duration, nil
 }
 
 // compresses an audio file with the given options
@@ -98,7 +90,30 @@ func CompressAudio(
 	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
 		return fmt.Errorf("input file not found: %s", inputPath)
 	}
+	return compress(ctx, inputPath, nil, outputPath, opts)
+}
+
+// CompressReader is CompressAudio for media read from a stream, such as an
+// upload, so the original never has to be written to disk. ffmpeg reads it
+// through a pipe, which rules out containers that keep their index at the
+// end, like MP4 files that were not written for streaming.
+func CompressReader(
+	ctx context.Context,
+	r io.Reader,
+	outputPath string,
+	opts CompressionOptions,
+) error {
+	return compress(ctx, "pipe:0", r, outputPath, opts)
+}
 
+// runs ffmpeg on input, feeding it stdin when set
+func compress(
+	ctx context.Context,
+	input string,
+	stdin io.Reader,
+	outputPath string,
+	opts CompressionOptions,
+) error {
 	outputDir := filepath.Dir(outputPath)
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
@@ -122,6 +137,13 @@ func CompressAudio(
 		if opts.Bitrate != "" {
 			kwargs["b:a"] = opts.Bitrate
 		}
+	case "opus":
+		kwargs["acodec"] = "libopus"
+		if opts.Bitrate != "" {
+			kwargs["b:a"] = opts.Bitrate
+		}
+	case "wav":
+		kwargs["acodec"] = "pcm_s16le"
 	default:
 		kwargs["acodec"] = "libmp3lame"
 		if opts.Bitrate != "" {
@@ -134,14 +156,32 @@ func CompressAudio(
 		return err
 	}
 
-	err = ffmpeg.Input(inputPath).
+	var inputArgs []ffmpeg.KwArgs
+	if opts.IgnoreDecodeErrors {
+		inputArgs = append(inputArgs, ffmpegbin.TolerantInputArgs())
+		kwargs["af"] = ffmpegbin.ResyncAudioFilter
+	}
+	if opts.Start > 0 {
+		inputArgs = append(inputArgs, ffmpeg.KwArgs{"ss": opts.Start.Seconds()})
+	}
+	if opts.Length > 0 {
+		kwargs["t"] = opts.Length.Seconds()
+	}
+	cmd := ffmpeg.Input(input, inputArgs...).
 		Output(outputPath, kwargs).
 		OverWriteOutput().
 		SetFfmpegPath(ffmpegPath).
-		Run()
+		Compile()
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	err = ffmpegbin.RunContext(ctx, cmd)
 
 	if err != nil {
-		return fmt.Errorf("compression failed: %w", err)
+		return errs.Wrap(
+			errs.KindFFmpeg,
+			fmt.Errorf("compression failed: %w", err),
+		)
 	}
 
 	return nil
@@ -174,8 +214,60 @@ func ChunkAudioConcurrent(
 	outputDir string,
 	concurrency int,
 ) ([]ChunkInfo, error) {
+	out := make(chan ChunkInfo)
+	errChan := make(chan error, 1)
+
+	go func() {
+		errChan <- ChunkAudioStream(
+			ctx,
+			audioPath,
+			chunkDuration,
+			outputDir,
+			concurrency,
+			out,
+		)
+	}()
+
+	var chunks []ChunkInfo
+	for chunk := range out {
+		chunks = append(chunks, chunk)
+	}
+	if err := <-errChan; err != nil {
+		return nil, err
+	}
+
+	// sort chunks by index to maintain order
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].Index < chunks[j].Index
+	})
+
+	return chunks, nil
+}
+
+// number of chunks ChunkAudioStream will produce for a file of this length
+func ChunkCount(totalDuration, chunkDuration time.Duration) int {
+	if chunkDuration <= 0 || totalDuration <= 0 {
+		return 0
+	}
+	return int((totalDuration + chunkDuration - 1) / chunkDuration)
+}
+
+// ChunkAudioStream cuts chunks like ChunkAudioConcurrent but sends each one on
+// out as soon as ffmpeg finishes it, so consumers can start transcribing while
+// later chunks are still being cut. Chunks are started in index order but may
+// arrive slightly out of order. out is always closed before returning.
+func ChunkAudioStream(
+	ctx context.Context,
+	audioPath string,
+	chunkDuration time.Duration,
+	outputDir string,
+	concurrency int,
+	out chan<- ChunkInfo,
+) error {
+	defer close(out)
+
 	if chunkDuration <= 0 {
-		return nil, fmt.Errorf(
+		return fmt.Errorf(
 			"chunk duration must be positive, got %v",
 			chunkDuration,
 		)
@@ -186,16 +278,16 @@ func ChunkAudioConcurrent(
 	}
 
 	if _, err := os.Stat(audioPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("audio file not found: %s", audioPath)
+		return fmt.Errorf("audio file not found: %s", audioPath)
 	}
 
-	totalDuration, err := GetDuration(audioPath)
+	totalDuration, err := GetDuration(ctx, audioPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get audio duration: %w", err)
+		return fmt.Errorf("failed to get audio duration: %w", err)
 	}
 
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create output directory: %w", err)
+		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
 	baseName := strings.TrimSuffix(
@@ -206,7 +298,7 @@ func ChunkAudioConcurrent(
 
 	ffmpegPath, err := ffmpegbin.FFmpegPath()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	chunkSeconds := chunkDuration.Seconds()
@@ -237,133 +329,118 @@ func ChunkAudioConcurrent(
 		})
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	var (
 		mu       sync.Mutex
-		chunks   []ChunkInfo
 		firstErr error
 		wg       sync.WaitGroup
 	)
 
-	// Create a semaphore to limit concurrency
-	sem := make(chan struct{}, concurrency)
-
-	for _, job := range jobs {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-		}
-
-		mu.Lock()
-		hasErr := firstErr != nil
-		mu.Unlock()
-		if hasErr {
-			break
-		}
-
-		wg.Add(1)
-		go func(j chunkJob) {
-			defer wg.Done()
+	jobChan := make(chan chunkJob)
 
-			sem <- struct{}{}
-			defer func() { <-sem }()
+	for i := 0; i < concurrency; i++ {
+		wg.Go(func() {
+			for j := range jobChan {
+				if ctx.Err() != nil {
+					return
+				}
 
-			select {
-			case <-ctx.Done():
-				return
-			default:
-			}
+				kwargs := ffmpeg.KwArgs{
+					"ss": j.startSeconds,
+					"t":  j.endSeconds - j.startSeconds,
+					"y":  "",
+					"c":  "copy", // Copy codec for speed
+				}
 
-			mu.Lock()
-			hasErr := firstErr != nil
-			mu.Unlock()
-			if hasErr {
-				return
-			}
+				cmd := ffmpeg.Input(audioPath).
+					Output(j.chunkPath, kwargs).
+					OverWriteOutput().
+					SetFfmpegPath(ffmpegPath).
+					Compile()
+				err := ffmpegbin.RunContext(ctx, cmd)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = errs.Wrap(errs.KindFFmpeg, fmt.Errorf(
+							"failed to create chunk %d: %w",
+							j.index,
+							err,
+						))
+					}
+					mu.Unlock()
+					cancel()
+					return
+				}
 
-			kwargs := ffmpeg.KwArgs{
-				"ss": j.startSeconds,
-				"t":  j.endSeconds - j.startSeconds,
-				"y":  "",
-				"c":  "copy", // Copy codec for speed
-			}
+				chunk := ChunkInfo{
+					Path:      j.chunkPath,
+					Index:     j.index,
+					StartTime: time.Duration(j.startSeconds * float64(time.Second)),
+					EndTime:   time.Duration(j.endSeconds * float64(time.Second)),
+				}
 
-			err := ffmpeg.Input(audioPath).
-				Output(j.chunkPath, kwargs).
-				OverWriteOutput().
-				SetFfmpegPath(ffmpegPath).
-				Run()
-
-			mu.Lock()
-			defer mu.Unlock()
-
-			if err != nil {
-				if firstErr == nil {
-					firstErr = fmt.Errorf(
-						"failed to create chunk %d: %w",
-						j.index,
-						err,
-					)
+				select {
+				case out <- chunk:
+				case <-ctx.Done():
+					return
 				}
-				return
 			}
+		})
+	}
 
-			chunks = append(chunks, ChunkInfo{
-				Path:      j.chunkPath,
-				Index:     j.index,
-				StartTime: time.Duration(j.startSeconds * float64(time.Second)),
-				EndTime:   time.Duration(j.endSeconds * float64(time.Second)),
-			})
-		}(job)
+feed:
+	for _, job := range jobs {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobChan <- job:
+		}
 	}
+	close(jobChan)
 
 	wg.Wait()
 
 	if firstErr != nil {
-		return nil, firstErr
+		return firstErr
 	}
+	return ctx.Err()
+}
 
-	// sort chunks by index to maintain order
-	sort.Slice(chunks, func(i, j int) bool {
-		return chunks[i].Index < chunks[j].Index
-	})
+var videoExts = map[string]bool{
+	".mp4":  true,
+	".mkv":  true,
+	".avi":  true,
+	".mov":  true,
+	".wmv":  true,
+	".flv":  true,
+	".webm": true,
+	".m4v":  true,
+	".mpeg": true,
+	".mpg":  true,
+	".3gp":  true,
+}
 
-	return chunks, nil
+var audioExts = map[string]bool{
+	".mp3":  true,
+	".wav":  true,
+	".aac":  true,
+	".flac": true,
+	".ogg":  true,
+	".m4a":  true,
+	".wma":  true,
+	".aiff": true,
 }
 
 // checks if the file is a video based on extension
 func IsVideoFile(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	videoExts := map[string]bool{
-		".mp4":  true,
-		".mkv":  true,
-		".avi":  true,
-		".mov":  true,
-		".wmv":  true,
-		".flv":  true,
-		".webm": true,
-		".m4v":  true,
-		".mpeg": true,
-		".mpg":  true,
-		".3gp":  true,
-	}
-	return videoExts[ext]
+	return videoExts[strings.ToLower(filepath.Ext(path))]
 }
 
 // checks if the file is an audio file based on extension
 func IsAudioFile(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	audioExts := map[string]bool{
-		".mp3":  true,
-		".wav":  true,
-		".aac":  true,
-		".flac": true,
-		".ogg":  true,
-		".m4a":  true,
-		".wma":  true,
-		".aiff": true,
-	}
-	return audioExts[ext]
+	return audioExts[strings.ToLower(filepath.Ext(path))]
 }
 
 // checks if the file is either audio or video
@@ -371,6 +448,18 @@ func IsMediaFile(path string) bool {
 	return IsAudioFile(path) || IsVideoFile(path)
 }
 
+// sorted extensions (without the dot) of the supported audio and video files
+func MediaExtensions() []string {
+	exts := make([]string, 0, len(videoExts)+len(audioExts))
+	for _, set := range []map[string]bool{videoExts, audioExts} {
+		for ext := range set {
+			exts = append(exts, strings.TrimPrefix(ext, "."))
+		}
+	}
+	sort.Strings(exts)
+	return exts
+}
+
 // removes all chunk files
 func CleanupChunks(chunks []ChunkInfo) error {
 	var lastErr error
@@ -0,0 +1,74 @@
+package audio
+
+import (
+	"fmt"
+	"time"
+)
+
+// approximate encoded bytes per second of mono 16 kHz audio for each
+// compression format, used to size the work directory before processing
+var bytesPerSecond = map[string]int64{
+	"mp3":  8_000,  // 64 kbps
+	"aac":  8_000,  // 64 kbps
+	"opus": 8_000,  // 64 kbps
+	"wav":  32_000, // 16-bit PCM
+}
+
+// separated stems are written as 44.1 kHz stereo 16-bit WAV (two stems)
+const isolationBytesPerSecond = 2 * 176_400
+
+// EstimateWorkspace returns the approximate number of bytes the work
+// directory needs: the compressed audio plus an equal amount for chunks, and
+// the separator stems when voice isolation is enabled.
+func EstimateWorkspace(
+	duration time.Duration,
+	format string,
+	isolateVoice bool,
+) int64 {
+	rate, ok := bytesPerSecond[format]
+	if !ok {
+		rate = bytesPerSecond["mp3"]
+	}
+	seconds := int64(duration.Seconds()) + 1
+
+	total := 2 * rate * seconds
+	if isolateVoice {
+		total += isolationBytesPerSecond*seconds + rate*seconds
+	}
+	return total
+}
+
+// CheckDiskSpace verifies there is room for need bytes under dir. It returns
+// an error when space is insufficient, and warn=true when the run would leave
+// less than 20% headroom. Unknown free space (unsupported platform) passes.
+func CheckDiskSpace(dir string, need int64) (warn bool, err error) {
+	free, err := AvailableDiskSpace(dir)
+	if err != nil || free == 0 {
+		return false, nil
+	}
+
+	if uint64(need) > free {
+		return false, fmt.Errorf(
+			"insufficient disk space in %s: need about %s, %s available (use --work-dir to pick another volume)",
+			dir,
+			FormatBytes(uint64(need)),
+			FormatBytes(free),
+		)
+	}
+
+	return uint64(need) > free/5*4, nil
+}
+
+// human-readable byte size
+func FormatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,66 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimateWorkspace(t *testing.T) {
+	hour := time.Hour
+
+	mp3 := EstimateWorkspace(hour, "mp3", false)
+	wav := EstimateWorkspace(hour, "wav", false)
+	isolated := EstimateWorkspace(hour, "mp3", true)
+
+	if mp3 <= 0 {
+		t.Fatalf("expected positive estimate, got %d", mp3)
+	}
+	if wav <= mp3 {
+		t.Errorf("wav estimate %d should exceed mp3 estimate %d", wav, mp3)
+	}
+	if isolated <= mp3 {
+		t.Errorf(
+			"isolation estimate %d should exceed plain estimate %d",
+			isolated,
+			mp3,
+		)
+	}
+	if unknown := EstimateWorkspace(hour, "xyz", false); unknown != mp3 {
+		t.Errorf("unknown format should fall back to mp3 rate")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{0, "0 B"},
+		{512, "512 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 30, "5.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := CheckDiskSpace(dir, 1); err != nil {
+		t.Errorf("expected 1 byte to fit, got %v", err)
+	}
+
+	free, err := AvailableDiskSpace(dir)
+	if err != nil || free == 0 {
+		t.Skip("free space unavailable on this platform")
+	}
+	if _, err := CheckDiskSpace(dir, int64(free/2*3)); err == nil {
+		t.Error("expected error when need exceeds free space")
+	}
+}
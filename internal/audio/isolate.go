@@ -0,0 +1,212 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// stem separation backend
+type Separator string
+
+const (
+	SeparatorDemucs   Separator = "demucs"
+	SeparatorSpleeter Separator = "spleeter"
+)
+
+// settings for voice isolation
+type IsolationOptions struct {
+	// Separator is "demucs", "spleeter", or a custom command template that
+	// contains {input} and {output} placeholders, e.g.
+	// "vocal-onnx --model mdx.onnx -i {input} -o {output}"
+	Separator string
+	// Executable overrides the binary used for demucs/spleeter, or the first
+	// word of a custom command. When empty, LIPI_SEPARATOR_PATH is consulted
+	// for demucs/spleeter before falling back to $PATH.
+	Executable string
+}
+
+// defaults for voice isolation
+func DefaultIsolationOptions() IsolationOptions {
+	return IsolationOptions{
+		Separator: string(SeparatorDemucs),
+	}
+}
+
+// IsolateVoice runs an external stem separator over inputPath and returns the
+// path of the extracted vocal stem, written somewhere under outputDir.
+func IsolateVoice(
+	ctx context.Context,
+	inputPath, outputDir string,
+	opts IsolationOptions,
+) (string, error) {
+	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("input file not found: %s", inputPath)
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	exe, args, vocalPath, err := separatorCommand(opts, inputPath, outputDir)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := exec.LookPath(exe)
+	if err != nil {
+		return "", fmt.Errorf(
+			"voice separator %q not found: install it or set LIPI_SEPARATOR_PATH",
+			exe,
+		)
+	}
+
+	cmd := exec.CommandContext(ctx, resolved, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf(
+			"voice separation failed: %w (%s)",
+			err,
+			lastLine(stderr.String()),
+		)
+	}
+
+	if _, err := os.Stat(vocalPath); err != nil {
+		return "", fmt.Errorf(
+			"voice separation produced no vocal stem at %s",
+			vocalPath,
+		)
+	}
+
+	return vocalPath, nil
+}
+
+// the separator's executable and arguments for inputPath, and the path of
+// the vocal stem it writes
+func separatorCommand(
+	opts IsolationOptions,
+	inputPath, outputDir string,
+) (exe string, args []string, vocalPath string, err error) {
+	separator := strings.TrimSpace(opts.Separator)
+	if separator == "" {
+		separator = string(SeparatorDemucs)
+	}
+
+	baseName := strings.TrimSuffix(
+		filepath.Base(inputPath),
+		filepath.Ext(inputPath),
+	)
+
+	switch Separator(strings.ToLower(separator)) {
+	case SeparatorDemucs:
+		exe = separatorExecutable(opts.Executable, "demucs")
+		args = []string{
+			"--two-stems=vocals",
+			"-n", "htdemucs",
+			"-o", outputDir,
+			inputPath,
+		}
+		vocalPath = filepath.Join(outputDir, "htdemucs", baseName, "vocals.wav")
+	case SeparatorSpleeter:
+		exe = separatorExecutable(opts.Executable, "spleeter")
+		args = []string{
+			"separate",
+			"-p", "spleeter:2stems",
+			"-o", outputDir,
+			inputPath,
+		}
+		vocalPath = filepath.Join(outputDir, baseName, "vocals.wav")
+	default:
+		if !strings.Contains(separator, "{input}") ||
+			!strings.Contains(separator, "{output}") {
+			return "", nil, "", fmt.Errorf(
+				"unsupported separator %q: use demucs, spleeter, or a command containing {input} and {output}",
+				separator,
+			)
+		}
+		// the template is split before the paths go in, so paths with
+		// spaces stay one argument
+		fields, err := splitTemplate(separator)
+		if err != nil {
+			return "", nil, "", fmt.Errorf("invalid separator command %q: %w", separator, err)
+		}
+		vocalPath = filepath.Join(outputDir, baseName+"_vocals.wav")
+		for i, field := range fields {
+			field = strings.ReplaceAll(field, "{input}", inputPath)
+			field = strings.ReplaceAll(field, "{output}", vocalPath)
+			fields[i] = field
+		}
+		exe, args = fields[0], fields[1:]
+		if opts.Executable != "" {
+			exe = opts.Executable
+		}
+	}
+	return exe, args, vocalPath, nil
+}
+
+// splits a command template into arguments at spaces outside single or
+// double quotes, which are removed
+func splitTemplate(template string) ([]string, error) {
+	var (
+		fields  []string
+		current strings.Builder
+		inField bool
+		quote   rune
+	)
+	for _, r := range template {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inField = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		default:
+			current.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inField {
+		fields = append(fields, current.String())
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return fields, nil
+}
+
+func separatorExecutable(override, fallback string) string {
+	if override != "" {
+		return override
+	}
+	if env := os.Getenv("LIPI_SEPARATOR_PATH"); env != "" {
+		return env
+	}
+	return fallback
+}
+
+// last non-empty line of tool output, for compact error messages
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return line
+		}
+	}
+	return "no output"
+}
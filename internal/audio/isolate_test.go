@@ -0,0 +1,90 @@
+package audio
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSeparatorCommand(t *testing.T) {
+	t.Setenv("LIPI_SEPARATOR_PATH", "")
+	input := filepath.Join("/tmp/my show", "episode 1.wav")
+	outputDir := filepath.Join("/tmp/work dir", "stems")
+	vocals := filepath.Join(outputDir, "episode 1_vocals.wav")
+
+	tests := []struct {
+		name       string
+		opts       IsolationOptions
+		wantExe    string
+		wantArgs   []string
+		wantVocals string
+		wantErr    bool
+	}{
+		{
+			name:       "demucs",
+			opts:       IsolationOptions{Separator: "demucs"},
+			wantExe:    "demucs",
+			wantArgs:   []string{"--two-stems=vocals", "-n", "htdemucs", "-o", outputDir, input},
+			wantVocals: filepath.Join(outputDir, "htdemucs", "episode 1", "vocals.wav"),
+		},
+		{
+			name:       "spleeter with executable",
+			opts:       IsolationOptions{Separator: "Spleeter", Executable: "/opt/venv/bin/spleeter"},
+			wantExe:    "/opt/venv/bin/spleeter",
+			wantArgs:   []string{"separate", "-p", "spleeter:2stems", "-o", outputDir, input},
+			wantVocals: filepath.Join(outputDir, "episode 1", "vocals.wav"),
+		},
+		{
+			name:       "custom with spaces in paths",
+			opts:       IsolationOptions{Separator: "vocal-onnx -i {input} -o {output}"},
+			wantExe:    "vocal-onnx",
+			wantArgs:   []string{"-i", input, "-o", vocals},
+			wantVocals: vocals,
+		},
+		{
+			name:       "custom with quotes and a joined placeholder",
+			opts:       IsolationOptions{Separator: `sep --model "my model.onnx" --in={input} '{output}'`},
+			wantExe:    "sep",
+			wantArgs:   []string{"--model", "my model.onnx", "--in=" + input, vocals},
+			wantVocals: vocals,
+		},
+		{
+			name:       "custom with executable",
+			opts:       IsolationOptions{Separator: "sep {input} {output}", Executable: "/usr/local/bin/sep"},
+			wantExe:    "/usr/local/bin/sep",
+			wantArgs:   []string{input, vocals},
+			wantVocals: vocals,
+		},
+		{
+			name:    "custom without placeholders",
+			opts:    IsolationOptions{Separator: "sep --fast"},
+			wantErr: true,
+		},
+		{
+			name:    "unterminated quote",
+			opts:    IsolationOptions{Separator: `sep "{input} {output}`},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exe, args, vocalPath, err := separatorCommand(tt.opts, input, outputDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("separatorCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if exe != tt.wantExe {
+				t.Errorf("exe = %q, want %q", exe, tt.wantExe)
+			}
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("args = %q, want %q", args, tt.wantArgs)
+			}
+			if vocalPath != tt.wantVocals {
+				t.Errorf("vocal path = %q, want %q", vocalPath, tt.wantVocals)
+			}
+		})
+	}
+}
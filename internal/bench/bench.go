@@ -0,0 +1,186 @@
+package bench
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// how far apart the starts of matching entries may be
+const maxOffset = 10 * time.Second
+
+// a transcript scored against reference subtitles
+type Score struct {
+	Words      int     // words in the reference
+	WordErrors int     // words substituted, deleted, or inserted
+	WER        float64 // word error rate: WordErrors per reference word
+	Chars      int     // letters and digits in the reference
+	CharErrors int
+	CER        float64 // character error rate, for scripts written without spaces
+	Offsets    Offsets
+}
+
+// how far a transcript's entry starts are from the reference's
+type Offsets struct {
+	Entries int           // reference entries
+	Matched int           // reference entries a transcript entry starts with the same words as
+	Mean    time.Duration // signed, positive when the transcript is late
+	Median  time.Duration // absolute
+	P90     time.Duration // absolute
+}
+
+// Compare scores hypothesis against reference. Text is compared
+// lowercased, without punctuation or formatting tags, so only the words
+// count.
+func Compare(reference, hypothesis []subtitle.Entry) Score {
+	refWords, hypWords := words(reference), words(hypothesis)
+	refChars, hypChars := chars(refWords), chars(hypWords)
+	score := Score{
+		Words:      len(refWords),
+		WordErrors: editDistance(refWords, hypWords),
+		Chars:      len(refChars),
+		CharErrors: editDistance(refChars, hypChars),
+		Offsets:    offsets(reference, hypothesis),
+	}
+	score.WER = rate(score.WordErrors, score.Words)
+	score.CER = rate(score.CharErrors, score.Chars)
+	return score
+}
+
+// WordCount is the number of words Compare counts in entries
+func WordCount(entries []subtitle.Entry) int {
+	return len(words(entries))
+}
+
+// errors per reference unit; any error against an empty reference is a
+// full miss
+func rate(errors, total int) float64 {
+	if total == 0 {
+		if errors == 0 {
+			return 0
+		}
+		return 1
+	}
+	return float64(errors) / float64(total)
+}
+
+// formatting that is not spoken: HTML-like tags and ASS override blocks
+var tagRegex = regexp.MustCompile(`<[^>]*>|\{[^}]*\}`)
+
+// the words of text, lowercased, keeping only letters and digits
+func textWords(text string) []string {
+	text = tagRegex.ReplaceAllString(text, " ")
+	text = strings.ReplaceAll(text, `\N`, " ")
+	var result []string
+	for _, field := range strings.Fields(strings.ToLower(text)) {
+		word := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, field)
+		if word != "" {
+			result = append(result, word)
+		}
+	}
+	return result
+}
+
+func words(entries []subtitle.Entry) []string {
+	var result []string
+	for _, entry := range entries {
+		result = append(result, textWords(entry.Text)...)
+	}
+	return result
+}
+
+// the characters of words without the spaces between them, so scripts
+// written with and without spaces compare alike
+func chars(words []string) []rune {
+	return []rune(strings.Join(words, ""))
+}
+
+// Levenshtein distance, keeping two rows so long transcripts fit in memory
+func editDistance[T comparable](a, b []T) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// offsets between reference entries and the nearest transcript entry that
+// starts with the same words; entries split differently are left out
+func offsets(reference, hypothesis []subtitle.Entry) Offsets {
+	result := Offsets{Entries: len(reference)}
+	hypLeads := make([]string, len(hypothesis))
+	for i, entry := range hypothesis {
+		hypLeads[i] = lead(entry.Text)
+	}
+
+	var diffs []time.Duration
+	for _, ref := range reference {
+		key := lead(ref.Text)
+		if key == "" {
+			continue
+		}
+		best, found := time.Duration(0), false
+		for i, hyp := range hypothesis {
+			diff := hyp.StartTime - ref.StartTime
+			if hypLeads[i] != key || diff.Abs() > maxOffset {
+				continue
+			}
+			if !found || diff.Abs() < best.Abs() {
+				best, found = diff, true
+			}
+		}
+		if found {
+			diffs = append(diffs, best)
+		}
+	}
+	if len(diffs) == 0 {
+		return result
+	}
+
+	result.Matched = len(diffs)
+	var sum time.Duration
+	abs := make([]time.Duration, len(diffs))
+	for i, d := range diffs {
+		sum += d
+		abs[i] = d.Abs()
+	}
+	slices.Sort(abs)
+	result.Mean = sum / time.Duration(len(diffs))
+	result.Median = percentile(abs, 0.5)
+	result.P90 = percentile(abs, 0.9)
+	return result
+}
+
+// the first two words of an entry, which identify it across transcripts
+func lead(text string) string {
+	w := textWords(text)
+	return strings.Join(w[:min(2, len(w))], " ")
+}
+
+// nearest-rank percentile p of sorted
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
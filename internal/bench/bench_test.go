@@ -0,0 +1,136 @@
+package bench
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+func entry(start, end time.Duration, text string) subtitle.Entry {
+	return subtitle.Entry{StartTime: start, EndTime: end, Text: text}
+}
+
+func TestCompare(t *testing.T) {
+	reference := []subtitle.Entry{
+		entry(0, 2*time.Second, "Hello there, my friend."),
+		entry(3*time.Second, 5*time.Second, "<i>How are you</i> today?"),
+		entry(6*time.Second, 8*time.Second, "Fine, thanks."),
+	}
+	tests := []struct {
+		name       string
+		hypothesis []subtitle.Entry
+		wantErrors int
+		wantCER    float64
+		matched    int
+		mean       time.Duration
+	}{
+		{
+			name:       "identical",
+			hypothesis: reference,
+			matched:    3,
+		},
+		{
+			name: "punctuation, case, and tags are ignored",
+			hypothesis: []subtitle.Entry{
+				entry(0, 2*time.Second, "hello there my friend"),
+				entry(3*time.Second, 5*time.Second, "{\\an8}How are you today"),
+				entry(6*time.Second, 8*time.Second, "FINE THANKS"),
+			},
+			matched: 3,
+		},
+		{
+			name: "late, with a wrong word and a missing one",
+			hypothesis: []subtitle.Entry{
+				entry(500*time.Millisecond, 2*time.Second, "Hello there, my fiend."),
+				entry(3500*time.Millisecond, 5*time.Second, "How are you?"),
+				entry(6500*time.Millisecond, 8*time.Second, "Fine, thanks."),
+			},
+			wantErrors: 2,
+			wantCER:    6.0 / 42,
+			matched:    3,
+			mean:       500 * time.Millisecond,
+		},
+		{
+			name: "split differently",
+			hypothesis: []subtitle.Entry{
+				entry(0, 4*time.Second, "Hello there, my friend. How are you today?"),
+				entry(6*time.Second, 8*time.Second, "Fine, thanks."),
+			},
+			matched: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := Compare(reference, tt.hypothesis)
+			if score.Words != 10 || score.WordErrors != tt.wantErrors {
+				t.Errorf("words = %d, errors = %d, want 10 and %d", score.Words, score.WordErrors, tt.wantErrors)
+			}
+			if wantWER := float64(tt.wantErrors) / 10; math.Abs(score.WER-wantWER) > 1e-9 {
+				t.Errorf("WER = %v, want %v", score.WER, wantWER)
+			}
+			if math.Abs(score.CER-tt.wantCER) > 1e-9 {
+				t.Errorf("CER = %v (%d of %d), want %v", score.CER, score.CharErrors, score.Chars, tt.wantCER)
+			}
+			if score.Offsets.Entries != 3 || score.Offsets.Matched != tt.matched {
+				t.Errorf("matched %d of %d entries, want %d of 3", score.Offsets.Matched, score.Offsets.Entries, tt.matched)
+			}
+			if score.Offsets.Mean != tt.mean || score.Offsets.Median != tt.mean.Abs() {
+				t.Errorf("offsets = %+v, want mean and median %s", score.Offsets, tt.mean)
+			}
+		})
+	}
+}
+
+func TestCompareEmpty(t *testing.T) {
+	score := Compare(nil, []subtitle.Entry{entry(0, time.Second, "Hello")})
+	if score.WER != 1 || score.CER != 1 {
+		t.Errorf("WER = %v, CER = %v against an empty reference, want 1", score.WER, score.CER)
+	}
+	if score = Compare(nil, nil); score.WER != 0 || score.Offsets.Matched != 0 {
+		t.Errorf("empty comparison = %+v", score)
+	}
+}
+
+func TestCompareTranslation(t *testing.T) {
+	reference := []subtitle.Entry{
+		entry(0, 2*time.Second, "El gato está sentado en la alfombra."),
+		entry(3*time.Second, 5*time.Second, "¿Dónde está mi sombrero?"),
+	}
+	score := func(texts ...string) TranslationScore {
+		var hyp []subtitle.Entry
+		for i, text := range texts {
+			hyp = append(hyp, entry(time.Duration(i)*time.Second, time.Duration(i+1)*time.Second, text))
+		}
+		return CompareTranslation(reference, hyp)
+	}
+
+	same := score("el gato está sentado en la alfombra", "Dónde está mi sombrero")
+	if math.Abs(same.BLEU-100) > 1e-9 || math.Abs(same.ChrF-100) > 1e-9 || same.LengthRatio != 1 {
+		t.Errorf("identical translation = %+v, want 100, 100, and 1", same)
+	}
+	// entries split differently still compare as one document
+	resplit := score("El gato está sentado en la alfombra. ¿Dónde está mi sombrero?")
+	if resplit != same {
+		t.Errorf("resplit translation = %+v, want %+v", resplit, same)
+	}
+
+	near := score("El gato se sienta en la alfombra.", "¿Dónde está mi sombrero?")
+	far := score("Un perro corre por el parque.", "Hace calor hoy.")
+	if near.BLEU <= far.BLEU || near.ChrF <= far.ChrF {
+		t.Errorf("close translation %+v does not score above a wrong one %+v", near, far)
+	}
+	if near.BLEU >= 100 || near.ChrF >= 100 {
+		t.Errorf("close translation = %+v, want below 100", near)
+	}
+
+	short := score("El gato")
+	if short.BLEU != 0 || short.LengthRatio >= 0.5 {
+		t.Errorf("two-word translation = %+v, want BLEU 0 and a short length ratio", short)
+	}
+	if empty := score(); empty.BLEU != 0 || empty.ChrF != 0 || empty.LengthRatio != 0 {
+		t.Errorf("empty translation = %+v", empty)
+	}
+}
@@ -0,0 +1,121 @@
+package bench
+
+import (
+	"math"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+const (
+	bleuOrder = 4 // longest word n-gram BLEU counts
+	chrFOrder = 6 // longest character n-gram chrF counts
+	chrFBeta  = 2 // weight of recall over precision in chrF
+)
+
+// a translation scored against a reference translation
+type TranslationScore struct {
+	BLEU        float64 // BLEU-4 with brevity penalty, 0-100
+	ChrF        float64 // character n-gram F-score, 0-100
+	LengthRatio float64 // translation characters per reference character
+}
+
+// CompareTranslation scores hypothesis against reference as one document,
+// so the two may split the text into entries differently. Text is compared
+// lowercased, without punctuation or formatting tags, like Compare.
+func CompareTranslation(reference, hypothesis []subtitle.Entry) TranslationScore {
+	refWords, hypWords := words(reference), words(hypothesis)
+	refChars, hypChars := chars(refWords), chars(hypWords)
+	score := TranslationScore{
+		BLEU: bleu(refWords, hypWords),
+		ChrF: chrF(strings.Split(string(refChars), ""), strings.Split(string(hypChars), "")),
+	}
+	if len(refChars) > 0 {
+		score.LengthRatio = float64(len(hypChars)) / float64(len(refChars))
+	}
+	return score
+}
+
+// counts of the n-grams of tokens
+func ngrams(tokens []string, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i+n <= len(tokens); i++ {
+		counts[strings.Join(tokens[i:i+n], "\x00")]++
+	}
+	return counts
+}
+
+// n-grams of hypothesis that also occur in reference, each counted at most
+// as often as the reference has it
+func clippedMatches(reference, hypothesis map[string]int) int {
+	matches := 0
+	for gram, count := range hypothesis {
+		matches += min(count, reference[gram])
+	}
+	return matches
+}
+
+func total(counts map[string]int) int {
+	n := 0
+	for _, count := range counts {
+		n += count
+	}
+	return n
+}
+
+// BLEU with exponential smoothing of orders without matches, as sacreBLEU
+// does by default
+func bleu(reference, hypothesis []string) float64 {
+	if len(hypothesis) == 0 || len(reference) == 0 {
+		return 0
+	}
+	logSum, smooth := 0.0, 1.0
+	for n := 1; n <= bleuOrder; n++ {
+		hyp := ngrams(hypothesis, n)
+		count := total(hyp)
+		if count == 0 {
+			// shorter than n words: nothing to match at this order
+			return 0
+		}
+		matches := clippedMatches(ngrams(reference, n), hyp)
+		precision := float64(matches) / float64(count)
+		if matches == 0 {
+			smooth *= 2
+			precision = 1 / (smooth * float64(count))
+		}
+		logSum += math.Log(precision)
+	}
+	brevity := 1.0
+	if len(hypothesis) < len(reference) {
+		brevity = math.Exp(1 - float64(len(reference))/float64(len(hypothesis)))
+	}
+	return 100 * brevity * math.Exp(logSum/bleuOrder)
+}
+
+// chrF: the F-score of character n-gram precision and recall, each
+// averaged over the orders both texts are long enough for
+func chrF(reference, hypothesis []string) float64 {
+	var precision, recall float64
+	orders := 0
+	for n := 1; n <= chrFOrder; n++ {
+		ref, hyp := ngrams(reference, n), ngrams(hypothesis, n)
+		refCount, hypCount := total(ref), total(hyp)
+		if refCount == 0 || hypCount == 0 {
+			break
+		}
+		matches := float64(clippedMatches(ref, hyp))
+		precision += matches / float64(hypCount)
+		recall += matches / float64(refCount)
+		orders++
+	}
+	if orders == 0 {
+		return 0
+	}
+	precision /= float64(orders)
+	recall /= float64(orders)
+	if precision == 0 && recall == 0 {
+		return 0
+	}
+	beta2 := float64(chrFBeta * chrFBeta)
+	return 100 * (1 + beta2) * precision * recall / (beta2*precision + recall)
+}
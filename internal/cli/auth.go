@@ -0,0 +1,303 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mgpai22/lipi/internal/credentials"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/spf13/cobra"
+)
+
+// env var with the passphrase for the encrypted credentials file, for
+// non-interactive use
+const passphraseEnv = "LIPI_CREDENTIALS_PASSPHRASE"
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Store provider API keys in the OS keychain",
+	Long: `Store provider API keys in the OS keychain so they stay out of shell
+history, config files, and environment variables.
+
+Keys go to the macOS keychain (security) or the Secret Service keyring on
+Linux (secret-tool). Where neither is available, or with --store file, they
+are kept in a file encrypted with a passphrase; set
+LIPI_CREDENTIALS_PASSPHRASE to unlock it without a prompt.
+
+Stored keys are used when no key is given with --api-key, in the config file,
+or in the provider's environment variable.
+
+Examples:
+  lipi auth set gemini
+  pass show gemini | lipi auth set gemini --store file
+  lipi auth status
+  lipi auth delete openai`,
+}
+
+var authSetCmd = &cobra.Command{
+	Use:               "set [provider]",
+	Short:             "Store the API key for a provider",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProviders,
+	RunE:              runAuthSet,
+}
+
+var authDeleteCmd = &cobra.Command{
+	Use:               "delete [provider]",
+	Short:             "Remove the stored API key for a provider",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProviders,
+	RunE:              runAuthDelete,
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show where each provider's API key comes from",
+	Args:  cobra.NoArgs,
+	RunE:  runAuthStatus,
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authSetCmd, authDeleteCmd, authStatusCmd)
+
+	for _, cmd := range []*cobra.Command{authSetCmd, authDeleteCmd} {
+		cmd.Flags().
+			String("store", "auto", "Where to keep the key (auto, keychain, file)")
+		mustRegisterCompletion(cmd, "store", completeValues("auto", "keychain", "file"))
+	}
+}
+
+func completeProviders(
+	cmd *cobra.Command,
+	args []string,
+	toComplete string,
+) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return storedKeyProviders(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// key variables of the providers a key can be stored for: the language
+// model providers, and those that only transcribe or speak
+func storedKeyEnv() map[string]string {
+	env := map[string]string{
+		"groq":       "GROQ_API_KEY",
+		"elevenlabs": "ELEVENLABS_API_KEY",
+	}
+	for provider, envVar := range providerKeyEnv {
+		env[provider] = envVar
+	}
+	return env
+}
+
+func storedKeyProviders() []string {
+	env := storedKeyEnv()
+	providers := make([]string, 0, len(env))
+	for provider := range env {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+func runAuthSet(cmd *cobra.Command, args []string) error {
+	provider := strings.ToLower(args[0])
+	if _, ok := storedKeyEnv()[provider]; !ok {
+		return inputErrorf(
+			"unsupported provider %q: use %s",
+			args[0],
+			strings.Join(storedKeyProviders(), ", "),
+		)
+	}
+	store, err := credentialStore(cmd, true)
+	if err != nil {
+		return err
+	}
+
+	key, err := readSecret(fmt.Sprintf("API key for %s: ", provider))
+	if err != nil {
+		return fmt.Errorf("failed to read API key: %w", err)
+	}
+	if key == "" {
+		return inputErrorf("no API key given")
+	}
+
+	if err := store.Set(provider, key); err != nil {
+		return err
+	}
+	report(map[string]string{"provider": provider, "store": store.Name()}, func() {
+		fmt.Printf("Stored %s API key in %s\n", provider, store.Name())
+	})
+	return nil
+}
+
+func runAuthDelete(cmd *cobra.Command, args []string) error {
+	provider := strings.ToLower(args[0])
+	store, err := credentialStore(cmd, false)
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(provider); err != nil {
+		if errors.Is(err, credentials.ErrNotFound) {
+			return inputErrorf("no %s API key stored in %s", provider, store.Name())
+		}
+		return err
+	}
+	report(map[string]string{"provider": provider, "store": store.Name()}, func() {
+		fmt.Printf("Removed %s API key from %s\n", provider, store.Name())
+	})
+	return nil
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	providers := storedKeyProviders()
+	status := make(map[string]string, len(providers))
+	for _, provider := range providers {
+		status[provider] = apiKeySource(provider)
+	}
+	report(status, func() {
+		for _, provider := range providers {
+			fmt.Printf("%-10s %s\n", provider, status[provider])
+		}
+	})
+	return nil
+}
+
+// where lookupAPIKey would find the provider's key. The encrypted file is
+// only checked when its passphrase is in the environment.
+func apiKeySource(provider string) string {
+	if appConfig.APIKey(provider) != "" {
+		return "config file " + appConfig.Path
+	}
+	if env := storedKeyEnv()[provider]; os.Getenv(env) != "" {
+		return "environment variable " + env
+	}
+	if keychain, ok := credentials.Keychain(); ok {
+		if _, err := keychain.Get(provider); err == nil {
+			return keychain.Name()
+		}
+	}
+	path := credentials.DefaultFilePath()
+	if _, err := os.Stat(path); err != nil {
+		return "not set"
+	}
+	if os.Getenv(passphraseEnv) == "" {
+		return "not set, or in the locked encrypted file " + path
+	}
+	store := credentials.EncryptedFile(path, credentialPassphrase(false))
+	if _, err := store.Get(provider); err != nil {
+		return "not set"
+	}
+	return store.Name()
+}
+
+// the store selected by --store. Creating a new encrypted file asks for the
+// passphrase twice.
+func credentialStore(cmd *cobra.Command, creating bool) (credentials.Store, error) {
+	kind, _ := cmd.Flags().GetString("store")
+	switch kind {
+	case "auto", "keychain":
+		if keychain, ok := credentials.Keychain(); ok {
+			return keychain, nil
+		}
+		if kind == "keychain" {
+			return nil, errs.Wrap(errs.KindInput, errors.New(
+				"no OS keychain available: install secret-tool (libsecret) or use --store file",
+			))
+		}
+	case "file":
+	default:
+		return nil, inputErrorf("unsupported store %q: use auto, keychain, or file", kind)
+	}
+
+	path := credentials.DefaultFilePath()
+	_, err := os.Stat(path)
+	confirm := creating && os.IsNotExist(err)
+	return credentials.EncryptedFile(path, credentialPassphrase(confirm)), nil
+}
+
+// passphrase for the encrypted credentials file from LIPI_CREDENTIALS_PASSPHRASE
+// or a prompt, asked at most once per run
+func credentialPassphrase(confirm bool) credentials.Passphrase {
+	var (
+		once       sync.Once
+		passphrase string
+		err        error
+	)
+	return func() (string, error) {
+		once.Do(func() {
+			if env := os.Getenv(passphraseEnv); env != "" {
+				passphrase = env
+				return
+			}
+			if !stdinIsTerminal() {
+				err = fmt.Errorf("set %s to unlock the credentials file", passphraseEnv)
+				return
+			}
+			if passphrase, err = readSecret("Credentials passphrase: "); err != nil {
+				return
+			}
+			if confirm {
+				again, rerr := readSecret("Repeat passphrase: ")
+				if rerr != nil {
+					err = rerr
+				} else if again != passphrase {
+					err = errs.Wrap(errs.KindInput, errors.New("passphrases do not match"))
+				}
+			}
+		})
+		return passphrase, err
+	}
+}
+
+// reads a line without echoing it when stdin is a terminal. Piped input is
+// read as is, so keys can come from a password manager.
+func readSecret(prompt string) (string, error) {
+	if !stdinIsTerminal() {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+
+	_, _ = fmt.Fprint(os.Stderr, prompt)
+	if runtime.GOOS != "windows" {
+		if setEcho(false) == nil {
+			defer func() {
+				_ = setEcho(true)
+				_, _ = fmt.Fprintln(os.Stderr)
+			}()
+		}
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func setEcho(on bool) error {
+	arg := "-echo"
+	if on {
+		arg = "echo"
+	}
+	stty := exec.Command("stty", arg)
+	stty.Stdin = os.Stdin
+	return stty.Run()
+}
+
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/spf13/cobra"
+)
+
+func TestCredentialStore(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(passphraseEnv, "test passphrase")
+	t.Setenv("GEMINI_API_KEY", "")
+
+	tests := []struct {
+		store       string
+		wantErrKind errs.Kind
+	}{
+		{store: "file"},
+		{store: "vault", wantErrKind: errs.KindInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.store, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "set"}
+			cmd.Flags().String("store", "auto", "")
+			if err := cmd.Flags().Set("store", tt.store); err != nil {
+				t.Fatal(err)
+			}
+
+			store, err := credentialStore(cmd, true)
+			if tt.wantErrKind != errs.KindUnknown {
+				if errs.KindOf(err) != tt.wantErrKind {
+					t.Fatalf("error = %v, want kind %s", err, tt.wantErrKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("credentialStore() error = %v", err)
+			}
+
+			if err := store.Set("gemini", "stored-key"); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if got := apiKeySource("gemini"); got != store.Name() {
+				t.Errorf("apiKeySource() = %q, want %q", got, store.Name())
+			}
+		})
+	}
+}
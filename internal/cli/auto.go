@@ -0,0 +1,363 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/source"
+	"github.com/mgpai22/lipi/internal/video"
+	"github.com/spf13/cobra"
+)
+
+var autoCmd = &cobra.Command{
+	Use:   "auto [media_file]",
+	Short: "Generate, translate, and embed subtitles in one run",
+	Long: `Run the whole subtitling pipeline for a single input: transcribe the
+media, write subtitles, translate them into every --translate-to language,
+and optionally mux all tracks into a copy of the video with --embed, or
+render one into the picture with --burn.
+
+--embed keeps the chapters and metadata of the video, and in .mkv outputs
+its attachments. With --attach-fonts, the fonts ASS tracks use are found in
+--fonts-dir or the system font directories and attached to an .mkv output.
+
+All stages share one work directory, so remote input is fetched once and
+intermediate files from every stage are kept together with --keep-temp.
+Subtitles are written next to the input (or to --output); the embedded or
+burned video is written to --embed-output (default: <name>.subtitled<ext>).
+
+--burn re-encodes the video with the first working hardware encoder
+(VideoToolbox on macOS, NVENC or VAAPI elsewhere) and falls back to software
+x264; pick one with --hwaccel.
+
+Generation flags (provider, model, chunking, etc.) work as in generate.
+
+Examples:
+  lipi auto video.mkv --subtitle-language en --translate-to es --embed
+  lipi auto lecture.mp4 --translate-to es,fr,de
+  lipi auto episode.mkv --translate-to ja --translate-provider anthropic --embed
+  lipi auto talk.mp4 --translate-to es --burn --hwaccel nvenc`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeMediaFile,
+	RunE:              runAuto,
+}
+
+func init() {
+	rootCmd.AddCommand(autoCmd)
+
+	addGenerateFlags(autoCmd)
+	addOutputNamingFlags(autoCmd)
+	addPreviewFlag(autoCmd)
+	addNotifyFlags(autoCmd)
+	autoCmd.Flags().
+		String("subtitle-language", "", "Language of the generated subtitles (same as --transcript-language)")
+	autoCmd.Flags().
+		StringSlice("translate-to", nil, "Languages to translate the subtitles to (comma-separated)")
+	autoCmd.Flags().
+		String("translate-provider", "gemini", "Translation provider (gemini, openai, anthropic)")
+	autoCmd.Flags().
+		String("translate-model", "", "Model to use for translation (provider-specific, uses sensible defaults)")
+	autoCmd.Flags().
+		Bool("overlay", false, "Write bilingual translated subtitles (translated + original)")
+	autoCmd.Flags().
+		Bool("embed", false, "Mux the subtitles into a copy of the video as selectable tracks")
+	autoCmd.Flags().
+		String("embed-output", "", "Path for the video with embedded or burned subtitles (default: <name>.subtitled<ext>)")
+	autoCmd.Flags().
+		Bool("attach-fonts", false, "Attach the fonts ASS subtitles use to the --embed output, so players without them show the text as styled (mkv only)")
+	autoCmd.Flags().
+		StringSlice("fonts-dir", nil, "More directories to look for --attach-fonts fonts in, before the system font directories")
+	autoCmd.Flags().
+		Bool("burn", false, "Burn the subtitles (the first translation, if any) into a re-encoded copy of the video")
+	autoCmd.Flags().
+		String("hwaccel", "auto", "Video encoder for --burn (auto, none, nvenc, vaapi, videotoolbox)")
+
+	autoCmd.MarkFlagsMutuallyExclusive("embed", "burn")
+	mustRegisterCompletion(autoCmd, "hwaccel", completeValues("auto", "none", "nvenc", "vaapi", "videotoolbox"))
+
+	registerTranslateCompletions(autoCmd, "translate-provider", "translate-model")
+}
+
+// auto result as reported by --json
+type autoReport struct {
+	generateReport
+	Translations []string `json:"translations,omitempty"`
+	Video        string   `json:"video,omitempty"`
+}
+
+func runAuto(cmd *cobra.Command, args []string) error {
+	input := args[0]
+
+	if !source.IsRemote(input) {
+		if _, err := os.Stat(input); os.IsNotExist(err) {
+			return inputErrorf("file not found: %s", input)
+		}
+		if !audio.IsMediaFile(input) {
+			return inputErrorf(
+				"unsupported file type: %s (expected audio or video file)",
+				filepath.Ext(input),
+			)
+		}
+	}
+
+	if flagProvided(cmd, "subtitle-language") {
+		subtitleLang, _ := cmd.Flags().GetString("subtitle-language")
+		if err := cmd.Flags().Set("transcript-language", subtitleLang); err != nil {
+			return err
+		}
+	}
+
+	outputPath, _ := cmd.Flags().GetString("output")
+	translateTo, _ := cmd.Flags().GetStringSlice("translate-to")
+	translateProvider, _ := cmd.Flags().GetString("translate-provider")
+	translateModel, _ := cmd.Flags().GetString("translate-model")
+	overlay, _ := cmd.Flags().GetBool("overlay")
+	embed, _ := cmd.Flags().GetBool("embed")
+	embedOutput, _ := cmd.Flags().GetString("embed-output")
+	attachFonts, _ := cmd.Flags().GetBool("attach-fonts")
+	fontsDirs, _ := cmd.Flags().GetStringSlice("fonts-dir")
+	burn, _ := cmd.Flags().GetBool("burn")
+	hwaccelStr, _ := cmd.Flags().GetString("hwaccel")
+	preview, _ := cmd.Flags().GetInt("preview")
+
+	if preview < 0 {
+		return inputErrorf("preview must not be negative, got %d", preview)
+	}
+	if embedOutput != "" && !embed && !burn {
+		return inputErrorf("--embed-output requires --embed or --burn")
+	}
+	if attachFonts && !embed {
+		return inputErrorf("--attach-fonts requires --embed")
+	}
+	if len(fontsDirs) > 0 && !attachFonts {
+		return inputErrorf("--fonts-dir requires --attach-fonts")
+	}
+	hwaccel, err := video.ParseHWAccel(hwaccelStr)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, err)
+	}
+
+	cfg, err := newGenerateConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	var translations []*translateConfig
+	for _, lang := range translateTo {
+		lang = strings.TrimSpace(lang)
+		if lang == "" {
+			continue
+		}
+		tcfg, err := newFollowUpTranslateConfig(
+			cfg,
+			lang,
+			translateProvider,
+			translateModel,
+			overlay,
+		)
+		if err != nil {
+			return err
+		}
+		translations = append(translations, tcfg)
+	}
+
+	ctx := cmd.Context()
+
+	workDir, cleanupWorkDir, err := newWorkDir(cfg.workDir, cfg.keepTemp)
+	if err != nil {
+		return err
+	}
+	defer cleanupWorkDir()
+
+	// every stage works inside the shared directory
+	stageCfg := *cfg
+	stageCfg.workDir = workDir
+
+	// one display follows every stage of the pipeline
+	display := startProgress()
+	defer display.Close()
+	stageCfg.progress = display
+	for _, tcfg := range translations {
+		tcfg.progress = display
+	}
+
+	if source.IsRemote(input) {
+		logger.Infow("Fetching input", "source", input)
+		display.Stage("Fetching input", 0)
+	}
+	sourceOpts := source.DefaultOptions()
+	sourceOpts.MaxBytes = cfg.maxInputBytes
+	sourceOpts.Format = cfg.inputFormat
+	sourceOpts.UseYTDLP = cfg.useYTDLP
+	sourceOpts.Proxy = proxyURL
+	media, err := source.Resolve(
+		ctx,
+		input,
+		filepath.Join(workDir, "input"),
+		sourceOpts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to resolve input: %w", err)
+	}
+	if !audio.IsMediaFile(media.Path) {
+		return inputErrorf(
+			"unsupported file type: %s (expected audio or video file; use --input-format to override)",
+			filepath.Ext(media.Path),
+		)
+	}
+	if (embed || burn) && !audio.IsVideoFile(media.Path) {
+		return inputErrorf(
+			"--embed and --burn require a video input, got %s",
+			filepath.Ext(media.Path),
+		)
+	}
+	if embedOutput == "" && (embed || burn) {
+		embedOutput = embeddedVideoPath(media)
+	}
+	if attachFonts && !video.IsMatroska(embedOutput) {
+		return inputErrorf("--attach-fonts requires an .mkv --embed-output, got %s", embedOutput)
+	}
+
+	if outputPath == "" {
+		outputPath = cfg.outputPathFor(media)
+	}
+
+	generated, err := generateSubtitles(
+		ctx,
+		&stageCfg,
+		media.Path,
+		outputPath,
+		logger,
+	)
+	if err != nil {
+		return err
+	}
+
+	tracks := []video.SubtitleTrack{{
+		Path:     generated.Output,
+		Language: subtitleTrackLanguage(cfg),
+		Default:  true,
+	}}
+
+	var translated []string
+	for _, tcfg := range translations {
+		result, err := translateSubtitles(
+			ctx,
+			tcfg,
+			generated.Output,
+			tcfg.outputPathFor(generated.Output, generated.Basename),
+			logger.With("target_language", tcfg.targetLang),
+		)
+		if err != nil {
+			return err
+		}
+		translated = append(translated, result.Output)
+		tracks = append(tracks, video.SubtitleTrack{
+			Path:     result.Output,
+			Language: tcfg.targetLang,
+		})
+	}
+
+	if embed {
+		var muxOpts video.MuxOptions
+		if attachFonts {
+			paths := make([]string, len(tracks))
+			for i, t := range tracks {
+				paths[i] = t.Path
+			}
+			files, missing, err := assFontFiles(paths, fontsDirs)
+			if err != nil {
+				return err
+			}
+			for _, name := range missing {
+				logger.Warnw("Font not found, not attached", "font", name)
+			}
+			muxOpts.Fonts = files
+		}
+		logger.Infow("Embedding subtitles",
+			"output", embedOutput,
+			"tracks", len(tracks),
+			"fonts", len(muxOpts.Fonts),
+		)
+		display.Stage("Embedding subtitles", 0)
+		processor := video.NewProcessor(workDir)
+		if err := processor.MuxSubtitles(
+			ctx,
+			media.Path,
+			embedOutput,
+			tracks,
+			muxOpts,
+		); err != nil {
+			return fmt.Errorf("failed to embed subtitles: %w", err)
+		}
+	}
+	if burn {
+		// viewers of a translated copy want the translation on screen
+		burned := tracks[0]
+		if len(tracks) > 1 {
+			burned = tracks[1]
+		}
+		logger.Infow("Burning subtitles",
+			"output", embedOutput,
+			"subtitles", burned.Path,
+			"hwaccel", hwaccel,
+		)
+		display.Stage("Burning subtitles", 0)
+		processor := video.NewProcessor(workDir)
+		if err := processor.BurnSubtitles(
+			ctx,
+			media.Path,
+			embedOutput,
+			burned.Path,
+			video.BurnOptions{HWAccel: hwaccel},
+		); err != nil {
+			return fmt.Errorf("failed to burn subtitles: %w", err)
+		}
+	}
+	display.Done()
+
+	rep := autoReport{generateReport: newGenerateReport(generated)}
+	for _, path := range translated {
+		rep.Translations = append(rep.Translations, absPath(path))
+	}
+	if embed || burn {
+		rep.Video = absPath(embedOutput)
+	}
+	report(rep, func() {
+		fmt.Printf("Subtitles generated successfully: %s\n", rep.Output)
+		fmt.Printf("  Entries: %d\n", generated.Entries)
+		fmt.Printf("  Duration: %s\n", generated.Duration.String())
+		printPreview(os.Stdout, generated.Output, preview)
+		for _, path := range rep.Translations {
+			fmt.Printf("  Translation: %s\n", path)
+			printPreview(os.Stdout, path, preview)
+		}
+		if rep.Video != "" {
+			fmt.Printf("  Video: %s\n", rep.Video)
+		}
+	})
+
+	return nil
+}
+
+// language to tag the generated track with: the requested transcript
+// language, or --language when the transcript keeps the original language
+func subtitleTrackLanguage(cfg *generateConfig) string {
+	if cfg.transcriptLang != "" && !strings.EqualFold(cfg.transcriptLang, "native") {
+		return cfg.transcriptLang
+	}
+	return cfg.language
+}
+
+// default path for the video with embedded subtitles
+func embeddedVideoPath(media *source.Media) string {
+	baseName := media.Name
+	if media.Remote {
+		baseName = filepath.Base(baseName)
+	}
+	return baseName + ".subtitled" + filepath.Ext(media.Path)
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/source"
+)
+
+func TestSubtitleTrackLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  generateConfig
+		want string
+	}{
+		{"native uses language", generateConfig{transcriptLang: "native", language: "ja"}, "ja"},
+		{"transcript language wins", generateConfig{transcriptLang: "en", language: "ja"}, "en"},
+		{"unknown", generateConfig{transcriptLang: "NATIVE"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := subtitleTrackLanguage(&tt.cfg); got != tt.want {
+				t.Errorf("subtitleTrackLanguage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddedVideoPath(t *testing.T) {
+	local := &source.Media{Path: "shows/ep01.mkv", Name: "shows/ep01"}
+	if got := embeddedVideoPath(local); got != "shows/ep01.subtitled.mkv" {
+		t.Errorf("embeddedVideoPath(local) = %q", got)
+	}
+
+	remote := &source.Media{
+		Path:   "/tmp/lipi-1/input/clip.mp4",
+		Name:   "downloads/clip",
+		Remote: true,
+	}
+	if got := embeddedVideoPath(remote); got != "clip.subtitled.mp4" {
+		t.Errorf("embeddedVideoPath(remote) = %q", got)
+	}
+}
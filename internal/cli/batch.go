@@ -0,0 +1,641 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/jobs"
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/source"
+	"github.com/mgpai22/lipi/internal/transcribe"
+	"github.com/spf13/cobra"
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch [path|dir|glob]...",
+	Short: "Generate subtitles for many media files",
+	Long: `Generate subtitles for every audio or video file matched by the given
+paths, directories, or glob patterns.
+
+Each file is processed with the same settings as 'lipi generate', and the
+subtitles are written next to the media file. A failed file does not stop
+the batch; a per-file summary is printed at the end and the command exits
+with an error if any file failed.
+
+--concurrency is a limit on transcription requests in flight shared by the
+whole batch: with --jobs 2 and --concurrency 6, two files are processed at a
+time and together never have more than six chunks at the provider, so a file
+with more chunks left can use the slots another file no longer needs. With
+auto (the default) the limit is the provider's comfortable request rate.
+
+Every file is recorded as a job in the store shared with 'lipi serve'. An
+interrupted batch prints its ID; rerun it with --resume ID to process the
+files it did not finish, and any 'lipi jobs retry' put back. A file
+cancelled with 'lipi jobs cancel' is skipped when its turn comes.
+
+Examples:
+  lipi batch ./season1/*.mkv --format srt --skip-existing
+  lipi batch ./season1 --recursive
+  lipi batch "./lectures/*.mp4" --jobs 2 --concurrency 6
+  lipi batch --resume 3f9c2a7d41b05e68`,
+	Args:              cobra.ArbitraryArgs,
+	ValidArgsFunction: completeMediaFiles,
+	RunE:              runBatch,
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+
+	addGenerateFlags(batchCmd)
+	addOutputNamingFlags(batchCmd)
+	addNotifyFlags(batchCmd)
+	batchCmd.Flags().
+		Bool("skip-existing", false, "Skip files whose subtitle output already exists")
+	batchCmd.Flags().
+		IntP("jobs", "j", 1, "Number of files to process at the same time")
+	batchCmd.Flags().
+		BoolP("recursive", "r", false, "Descend into subdirectories of directory arguments")
+	batchCmd.Flags().
+		Bool("fail-fast", false, "Stop the batch after the first failure")
+	batchCmd.Flags().
+		String("resume", "", "Resume the batch with this ID, processing the files it did not finish")
+	batchCmd.Flags().
+		String("data-dir", "", "Directory of the job store (default: user cache dir)")
+}
+
+// what happened to one file in a batch
+type batchStatus string
+
+const (
+	batchSucceeded batchStatus = "ok"
+	batchFailed    batchStatus = "failed"
+	batchSkipped   batchStatus = "skipped"
+)
+
+// per-file batch outcome
+type batchItem struct {
+	Input   string
+	Output  string
+	Status  batchStatus
+	Entries int
+	Elapsed time.Duration
+	Err     error
+	job     *jobs.Job // record of the file in the job store, if any
+}
+
+func runBatch(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	if output, _ := cmd.Flags().GetString("output"); output != "" {
+		return inputErrorf(
+			"--output is not supported by batch: subtitles are written next to each input",
+		)
+	}
+
+	skipExisting, _ := cmd.Flags().GetBool("skip-existing")
+	workers, _ := cmd.Flags().GetInt("jobs")
+	recursive, _ := cmd.Flags().GetBool("recursive")
+	failFast, _ := cmd.Flags().GetBool("fail-fast")
+	resume, _ := cmd.Flags().GetString("resume")
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+
+	if workers <= 0 {
+		return inputErrorf("jobs must be positive, got %d", workers)
+	}
+	if resume == "" && len(args) == 0 {
+		return inputErrorf("requires at least 1 path, or --resume with a batch ID")
+	}
+	if resume != "" && len(args) > 0 {
+		return inputErrorf("--resume takes the files from the batch: remove the path arguments")
+	}
+
+	cfg, err := newGenerateConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	store, err := openJobStore(resolveDataDir(dataDir))
+	if err != nil {
+		if resume != "" {
+			return err
+		}
+		// the batch still runs, it just cannot be resumed
+		logger.Warnw("Failed to open the job store", "error", err)
+	} else {
+		defer func() { _ = store.Close() }()
+	}
+
+	var items []batchItem
+	batchID := resume
+	if resume != "" {
+		if items, err = resumeBatchItems(store, resume); err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			fmt.Printf("Batch %s has no unfinished files\n", resume)
+			return nil
+		}
+	} else {
+		inputs, err := expandBatchInputs(args, recursive)
+		if err != nil {
+			return err
+		}
+		if len(inputs) == 0 {
+			return inputErrorf(
+				"no audio or video files matched %s",
+				strings.Join(args, " "),
+			)
+		}
+		items = make([]batchItem, len(inputs))
+		for i, input := range inputs {
+			items[i] = batchItem{
+				Input:  input,
+				Output: batchOutputPath(input, cfg),
+			}
+		}
+		if store != nil {
+			batchID = jobs.NewID()
+			if err := recordBatchJobs(store, batchID, items); err != nil {
+				logger.Warnw("Failed to record the batch in the job store", "error", err)
+				batchID = ""
+			}
+		}
+	}
+
+	workers = min(workers, len(items))
+	budget := batchRequestBudget(cfg)
+	fileCfg := *cfg
+	fileCfg.limiter = transcribe.NewLimiter(budget)
+
+	logger.Infow("Starting batch",
+		"batch", batchID,
+		"files", len(items),
+		"jobs", workers,
+		"requests_in_flight", budget,
+	)
+
+	opts := batchOptions{
+		jobs:         workers,
+		skipExisting: skipExisting,
+		failFast:     failFast,
+		stage:        "Generating subtitles",
+	}
+	if batchID != "" {
+		opts.store = store
+	}
+	runBatchItems(ctx, &fileCfg, items, opts)
+
+	if err := printBatchSummary(items); err != nil {
+		return err
+	}
+	if err := cmd.Context().Err(); err != nil {
+		if batchID == "" {
+			return fmt.Errorf(
+				"batch interrupted: rerun with --skip-existing to resume: %w",
+				err,
+			)
+		}
+		return fmt.Errorf(
+			"batch interrupted: resume it with 'lipi batch --resume %s': %w",
+			batchID,
+			err,
+		)
+	}
+	return nil
+}
+
+// records each item as a queued job of the batch
+func recordBatchJobs(store jobs.Store, batchID string, items []batchItem) error {
+	created := time.Now().UTC()
+	for i := range items {
+		job := &jobs.Job{
+			ID:        jobs.NewID(),
+			Status:    jobs.StatusQueued,
+			Batch:     batchID,
+			Name:      filepath.Base(items[i].Input),
+			Source:    absPath(items[i].Input),
+			Output:    absPath(items[i].Output),
+			CreatedAt: created,
+		}
+		if err := store.Save(job); err != nil {
+			return err
+		}
+		items[i].job = job
+	}
+	return nil
+}
+
+// the files of batch id that are queued, or were running when it stopped,
+// oldest first
+func resumeBatchItems(store jobs.Store, id string) ([]batchItem, error) {
+	all, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	var items []batchItem
+	for i := len(all) - 1; i >= 0; i-- {
+		job := all[i]
+		if job.Batch != id {
+			continue
+		}
+		found = true
+		if !job.Status.Done() {
+			items = append(items, batchItem{Input: job.Source, Output: job.Output, job: job})
+		}
+	}
+	if !found {
+		return nil, inputErrorf("batch %s not found in the job store", id)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Input < items[j].Input })
+	return items, nil
+}
+
+// how runBatchItems schedules files
+type batchOptions struct {
+	jobs         int // files processed at the same time
+	skipExisting bool
+	failFast     bool
+	stage        string // label of the progress display
+	// when set, each item's job is updated as the file is processed
+	store jobs.Store
+	// when set, runs after a file's subtitles are written; an error fails
+	// the file
+	done func(item *batchItem, result *generateResult) error
+}
+
+// generates subtitles for each item with cfg, recording the outcome in the
+// item. A failed file does not stop the others unless failFast is set.
+func runBatchItems(ctx context.Context, cfg *generateConfig, items []batchItem, opts batchOptions) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	display := startProgress()
+	defer display.Close()
+	display.Stage(opts.stage, len(items))
+
+	indexChan := make(chan int)
+	var wg sync.WaitGroup
+	for range min(opts.jobs, len(items)) {
+		wg.Go(func() {
+			for i := range indexChan {
+				item := &items[i]
+				log := logger.With("file", filepath.Base(item.Input))
+				journal := batchJournal{store: opts.store, job: item.job, log: log}
+				if journal.cancelled() {
+					item.Status = batchSkipped
+					item.Err = errBatchFileCancelled
+					display.Add(1)
+					continue
+				}
+				journal.start()
+
+				fileCfg := *cfg
+				fileCfg.onChunk = journal.progress
+
+				start := time.Now()
+				result, err := generateSubtitles(
+					ctx,
+					&fileCfg,
+					item.Input,
+					item.Output,
+					log,
+				)
+				if err == nil && opts.done != nil {
+					err = opts.done(item, result)
+				}
+				item.Elapsed = time.Since(start)
+				display.Add(1)
+				if err != nil && ctx.Err() != nil {
+					// stopped by Ctrl-C or --fail-fast, not by this file
+					item.Status = batchSkipped
+					item.Err = ctx.Err()
+					journal.requeue()
+					continue
+				}
+				journal.finish(result, err)
+				if err != nil {
+					item.Status = batchFailed
+					item.Err = err
+					log.Errorw("File failed", "error", err)
+					if opts.failFast {
+						cancel()
+					}
+					continue
+				}
+				item.Status = batchSucceeded
+				item.Entries = result.Entries
+			}
+		})
+	}
+
+	for i := range items {
+		if opts.skipExisting {
+			if _, err := os.Stat(items[i].Output); err == nil {
+				items[i].Status = batchSkipped
+				logger.Infow("Skipping existing output",
+					"output", items[i].Output,
+				)
+				journal := batchJournal{store: opts.store, job: items[i].job, log: logger}
+				journal.skip()
+				display.Add(1)
+				continue
+			}
+		}
+		if ctx.Err() != nil {
+			items[i].Status = batchSkipped
+			items[i].Err = ctx.Err()
+			continue
+		}
+		indexChan <- i
+	}
+	close(indexChan)
+	wg.Wait()
+	display.Done()
+}
+
+// reported for a file whose job was cancelled before its turn
+var errBatchFileCancelled = errors.New("job cancelled")
+
+// records a batch file's progress in its job; without a store or job it
+// does nothing, and failures to write are only logged
+type batchJournal struct {
+	store jobs.Store
+	job   *jobs.Job
+	log   *logging.Logger
+}
+
+func (j batchJournal) enabled() bool {
+	return j.store != nil && j.job != nil
+}
+
+func (j batchJournal) save() {
+	if err := j.store.Save(j.job); err != nil {
+		j.log.Warnw("Failed to record job", "job", j.job.ID, "error", err)
+	}
+}
+
+// reports whether the job was cancelled in the store
+func (j batchJournal) cancelled() bool {
+	if !j.enabled() {
+		return false
+	}
+	current, err := j.store.Get(j.job.ID)
+	return err == nil && current.Status == jobs.StatusCancelled
+}
+
+func (j batchJournal) start() {
+	if !j.enabled() {
+		return
+	}
+	started := time.Now().UTC()
+	j.job.Status = jobs.StatusRunning
+	j.job.StartedAt = &started
+	j.job.FinishedAt = nil
+	j.job.Error, j.job.ErrorKind = "", ""
+	j.save()
+}
+
+func (j batchJournal) progress(done, total int) {
+	if !j.enabled() {
+		return
+	}
+	if err := j.store.SaveProgress(j.job.ID, done, total); err != nil {
+		j.log.Warnw("Failed to record job progress", "job", j.job.ID, "error", err)
+	}
+}
+
+// puts an interrupted file back in the queue for --resume
+func (j batchJournal) requeue() {
+	if !j.enabled() {
+		return
+	}
+	j.job.Status = jobs.StatusQueued
+	j.job.StartedAt = nil
+	j.save()
+}
+
+// records a file skipped because its output exists as done
+func (j batchJournal) skip() {
+	j.finish(&generateResult{}, nil)
+}
+
+func (j batchJournal) finish(result *generateResult, err error) {
+	if !j.enabled() {
+		return
+	}
+	if current, getErr := j.store.Get(j.job.ID); getErr == nil {
+		j.job.Chunks, j.job.ChunksDone = current.Chunks, current.ChunksDone
+	}
+	finished := time.Now().UTC()
+	j.job.FinishedAt = &finished
+	if err != nil {
+		j.job.Status = jobs.StatusFailed
+		j.job.Error = err.Error()
+		j.job.ErrorKind = errs.Code(err)
+	} else {
+		j.job.Status = jobs.StatusSucceeded
+		j.job.Entries = result.Entries
+		j.job.Usage = j.job.Usage.Add(result.Usage)
+	}
+	j.save()
+}
+
+// expands files, directories, and glob patterns into a sorted, de-duplicated
+// list of media files
+func expandBatchInputs(args []string, recursive bool) ([]string, error) {
+	return expandPaths(args, recursive, audio.IsMediaFile)
+}
+
+// expands files, directories, and glob patterns into a sorted, de-duplicated
+// list of the files keep accepts
+func expandPaths(args []string, recursive bool, keep func(string) bool) ([]string, error) {
+	seen := make(map[string]bool)
+	var inputs []string
+	add := func(path string) {
+		clean := filepath.Clean(path)
+		if !seen[clean] && keep(clean) {
+			seen[clean] = true
+			inputs = append(inputs, clean)
+		}
+	}
+
+	for _, arg := range args {
+		if source.IsRemote(arg) {
+			return nil, inputErrorf(
+				"batch only accepts local files, directories, and globs, got %q",
+				arg,
+			)
+		}
+
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			matches, err = filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, inputErrorf("no files matched %q", arg)
+			}
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil, inputErrorf("file not found: %s", match)
+				}
+				return nil, err
+			}
+
+			if !info.IsDir() {
+				add(match)
+				continue
+			}
+
+			err = filepath.WalkDir(
+				match,
+				func(path string, d fs.DirEntry, err error) error {
+					if err != nil {
+						return err
+					}
+					if d.IsDir() {
+						if path != match && !recursive {
+							return filepath.SkipDir
+						}
+						return nil
+					}
+					add(path)
+					return nil
+				},
+			)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"failed to read directory %s: %w",
+					match,
+					err,
+				)
+			}
+		}
+	}
+
+	sort.Strings(inputs)
+	return inputs, nil
+}
+
+// transcription requests in flight across the whole batch: --concurrency,
+// or the provider's limit for auto
+func batchRequestBudget(cfg *generateConfig) int {
+	if cfg.concurrency == concurrencyAuto {
+		return transcriptionConcurrencyLimit(cfg.provider)
+	}
+	return cfg.concurrency
+}
+
+func batchOutputPath(input string, cfg *generateConfig) string {
+	return cfg.outputPathFor(
+		&source.Media{Name: strings.TrimSuffix(input, filepath.Ext(input))},
+	)
+}
+
+// prints per-file results and returns an error if any file failed
+// per-file batch outcome as reported by --json
+type batchFileReport struct {
+	Input          string      `json:"input"`
+	Output         string      `json:"output"`
+	Status         batchStatus `json:"status"`
+	Entries        int         `json:"entries,omitempty"`
+	ElapsedSeconds float64     `json:"elapsed_seconds,omitempty"`
+	Error          string      `json:"error,omitempty"`
+}
+
+// batch result as reported by --json
+type batchReport struct {
+	Files     []batchFileReport `json:"files"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Skipped   int               `json:"skipped"`
+}
+
+func printBatchSummary(items []batchItem) error {
+	rep := batchReport{Files: make([]batchFileReport, len(items))}
+	for i, item := range items {
+		file := batchFileReport{
+			Input:          item.Input,
+			Output:         absPath(item.Output),
+			Status:         item.Status,
+			Entries:        item.Entries,
+			ElapsedSeconds: item.Elapsed.Seconds(),
+		}
+		if item.Err != nil {
+			file.Error = item.Err.Error()
+		}
+		rep.Files[i] = file
+
+		switch item.Status {
+		case batchSucceeded:
+			rep.Succeeded++
+		case batchFailed:
+			rep.Failed++
+		case batchSkipped:
+			rep.Skipped++
+		}
+	}
+
+	report(rep, func() {
+		fmt.Println("Batch summary:")
+		for _, item := range items {
+			switch item.Status {
+			case batchSucceeded:
+				fmt.Printf("  [ok]      %s -> %s (%d entries, %s)\n",
+					item.Input,
+					item.Output,
+					item.Entries,
+					item.Elapsed.Round(time.Second),
+				)
+			case batchFailed:
+				fmt.Printf("  [failed]  %s: %v\n", item.Input, item.Err)
+			case batchSkipped:
+				reason := "output exists"
+				if errors.Is(item.Err, context.Canceled) {
+					reason = "batch stopped"
+				} else if errors.Is(item.Err, errBatchFileCancelled) {
+					reason = "job cancelled"
+				}
+				fmt.Printf("  [skipped] %s (%s)\n", item.Input, reason)
+			}
+		}
+		fmt.Printf(
+			"  %d succeeded, %d failed, %d skipped\n",
+			rep.Succeeded,
+			rep.Failed,
+			rep.Skipped,
+		)
+	})
+
+	if rep.Failed > 0 {
+		err := fmt.Errorf("%d of %d files failed", rep.Failed, len(items))
+		if rep.Failed < len(items) {
+			return errs.Wrap(errs.KindPartial, err)
+		}
+		// nothing succeeded: exit as the first failure would have
+		for _, item := range items {
+			if item.Status == batchFailed {
+				return errs.Wrap(errorKind(item.Err), err)
+			}
+		}
+		return err
+	}
+	return nil
+}
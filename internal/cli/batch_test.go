@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/jobs"
+	"github.com/mgpai22/lipi/internal/transcribe"
+)
+
+func TestExpandBatchInputs(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"ep01.mkv",
+		"ep02.mkv",
+		"notes.txt",
+		filepath.Join("extras", "bonus.mp4"),
+	}
+	for _, name := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("media"), 0644); err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		recursive bool
+		want      []string
+	}{
+		{
+			name: "directory",
+			args: []string{dir},
+			want: []string{"ep01.mkv", "ep02.mkv"},
+		},
+		{
+			name:      "recursive directory",
+			args:      []string{dir},
+			recursive: true,
+			want: []string{
+				"ep01.mkv",
+				"ep02.mkv",
+				filepath.Join("extras", "bonus.mp4"),
+			},
+		},
+		{
+			name: "glob",
+			args: []string{filepath.Join(dir, "*.mkv")},
+			want: []string{"ep01.mkv", "ep02.mkv"},
+		},
+		{
+			name: "duplicates collapse",
+			args: []string{
+				filepath.Join(dir, "ep02.mkv"),
+				filepath.Join(dir, "*.mkv"),
+			},
+			want: []string{"ep01.mkv", "ep02.mkv"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandBatchInputs(tt.args, tt.recursive)
+			if err != nil {
+				t.Fatalf("expandBatchInputs error: %v", err)
+			}
+			var want []string
+			for _, name := range tt.want {
+				want = append(want, filepath.Join(dir, name))
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("expandBatchInputs(%v) = %v, want %v", tt.args, got, want)
+			}
+		})
+	}
+}
+
+func TestExpandBatchInputsErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []string{
+		filepath.Join(dir, "missing.mkv"),
+		filepath.Join(dir, "*.mkv"),
+		"https://example.com/episode.mp3",
+	}
+
+	for _, arg := range tests {
+		t.Run(arg, func(t *testing.T) {
+			if _, err := expandBatchInputs([]string{arg}, false); err == nil {
+				t.Errorf("expandBatchInputs(%q) expected error", arg)
+			}
+		})
+	}
+}
+
+func TestBatchRequestBudget(t *testing.T) {
+	tests := []struct {
+		provider    transcribe.Provider
+		concurrency int
+		want        int
+	}{
+		{transcribe.ProviderGemini, 8, 8},
+		{transcribe.ProviderGemini, concurrencyAuto, 6},
+		{transcribe.ProviderOpenAI, concurrencyAuto, 4},
+	}
+
+	for _, tt := range tests {
+		cfg := &generateConfig{provider: tt.provider, concurrency: tt.concurrency}
+		if got := batchRequestBudget(cfg); got != tt.want {
+			t.Errorf(
+				"batchRequestBudget(%s, %d) = %d, want %d",
+				tt.provider,
+				tt.concurrency,
+				got,
+				tt.want,
+			)
+		}
+	}
+}
+
+func TestResumeBatchItems(t *testing.T) {
+	store, err := jobs.NewSQLiteStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore error: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	items := []batchItem{
+		{Input: "ep01.mkv", Output: "ep01.srt"},
+		{Input: "ep02.mkv", Output: "ep02.srt"},
+		{Input: "ep03.mkv", Output: "ep03.srt"},
+	}
+	batchID := jobs.NewID()
+	if err := recordBatchJobs(store, batchID, items); err != nil {
+		t.Fatalf("recordBatchJobs error: %v", err)
+	}
+	batchJournal{store: store, job: items[0].job, log: logger}.finish(&generateResult{Entries: 4}, nil)
+	batchJournal{store: store, job: items[1].job, log: logger}.start()
+
+	resumed, err := resumeBatchItems(store, batchID)
+	if err != nil {
+		t.Fatalf("resumeBatchItems error: %v", err)
+	}
+	var inputs []string
+	for _, item := range resumed {
+		inputs = append(inputs, filepath.Base(item.Input))
+	}
+	if !slices.Equal(inputs, []string{"ep02.mkv", "ep03.mkv"}) {
+		t.Errorf("resumed %v, want the interrupted and queued files", inputs)
+	}
+	if resumed[0].Output != absPath("ep02.srt") {
+		t.Errorf("output = %s", resumed[0].Output)
+	}
+
+	if _, err := resumeBatchItems(store, jobs.NewID()); errs.KindOf(err) != errs.KindInput {
+		t.Errorf("unknown batch error = %v, want an input error", err)
+	}
+}
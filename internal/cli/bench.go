@@ -0,0 +1,328 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/bench"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/source"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench [media_file] [reference_subtitles]",
+	Short: "Compare transcription providers against reference subtitles",
+	Long: `Transcribe a recording with each provider or model in --providers and
+score the results against subtitles known to be correct, to pick the model
+that works best, or the cheapest one that works well enough, for your
+content.
+
+Each run reports the word error rate (WER) and character error rate (CER)
+against the reference, the offset of its entry start times from the
+reference's, how long it took, and the tokens or audio it was billed for.
+Text is compared lowercased and without punctuation; CER ignores spaces, so
+it also works for languages written without them. Offsets are measured on
+the entries that start with the same words in both tracks.
+
+Every run uses the same generate flags, such as --chunk-duration, --prompt,
+or --temperature. Keys are read from the usual places for each provider;
+--api-key is only accepted when every run uses one provider.
+
+Examples:
+  lipi bench episode.mkv episode.en.srt --providers gemini,openai,groq
+  lipi bench talk.mp4 talk.srt --providers gemini:gemini-2.5-flash,gemini:gemini-2.5-pro
+  lipi bench clip.mp3 clip.vtt --providers gemini,groq --output-dir bench/ --json`,
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(
+		cmd *cobra.Command,
+		args []string,
+		toComplete string,
+	) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) == 1 {
+			return []string{"srt", "vtt", "ass", "ssa"}, cobra.ShellCompDirectiveFilterFileExt
+		}
+		return completeMediaFile(cmd, args, toComplete)
+	},
+	RunE: runBench,
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+
+	benchCmd.Flags().
+		StringSlice("providers", nil, "Providers to compare, as provider or provider:model (default: --provider with --model)")
+	benchCmd.Flags().
+		String("output-dir", "", "Keep the subtitles of each run in this directory (default: discard them)")
+	addGenerateFlags(benchCmd)
+
+	mustRegisterCompletion(benchCmd, "providers", completeValues("gemini", "openai", "groq"))
+}
+
+// bench result as reported by --json
+type benchReport struct {
+	Reference string     `json:"reference"`
+	Entries   int        `json:"entries"`
+	Words     int        `json:"words"`
+	Runs      []benchRun `json:"runs"`
+}
+
+// one provider and model scored against the reference
+type benchRun struct {
+	Provider            string      `json:"provider"`
+	Model               string      `json:"model"`
+	Output              string      `json:"output,omitempty"`
+	Entries             int         `json:"entries"`
+	WER                 float64     `json:"wer"`
+	CER                 float64     `json:"cer"`
+	WordErrors          int         `json:"word_errors"`
+	CharErrors          int         `json:"char_errors"`
+	OffsetMatched       int         `json:"offset_matched"`
+	OffsetMeanSeconds   float64     `json:"offset_mean_seconds"`
+	OffsetMedianSeconds float64     `json:"offset_median_seconds"`
+	OffsetP90Seconds    float64     `json:"offset_p90_seconds"`
+	ElapsedSeconds      float64     `json:"elapsed_seconds"`
+	Usage               usage.Usage `json:"usage"`
+	Error               string      `json:"error,omitempty"`
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	mediaPath, referencePath := args[0], args[1]
+	ctx := cmd.Context()
+	providers, _ := cmd.Flags().GetStringSlice("providers")
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+
+	if !source.IsRemote(mediaPath) {
+		if _, err := os.Stat(mediaPath); os.IsNotExist(err) {
+			return inputErrorf("file not found: %s", mediaPath)
+		}
+		if !audio.IsMediaFile(mediaPath) {
+			return inputErrorf(
+				"unsupported file type: %s (expected audio or video file)",
+				filepath.Ext(mediaPath),
+			)
+		}
+	}
+	if !isSubtitlePath(referencePath) {
+		return inputErrorf(
+			"unsupported reference %s: expected srt, vtt, or ass subtitles",
+			filepath.Ext(referencePath),
+		)
+	}
+	if _, err := os.Stat(referencePath); os.IsNotExist(err) {
+		return inputErrorf("reference subtitles not found: %s", referencePath)
+	}
+	reference, err := subtitle.Open(referencePath)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, fmt.Errorf("failed to read reference subtitles: %w", err))
+	}
+	refEntries := reference.Subtitle().Entries
+
+	configs, err := benchConfigs(cmd, providers, newGenerateConfig)
+	if err != nil {
+		return err
+	}
+
+	keep := outputDir != ""
+	outputDir, cleanup, err := benchOutputDir(outputDir, configs[0].workDir)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	rep := benchReport{
+		Reference: absPath(referencePath),
+		Entries:   len(refEntries),
+		Words:     bench.WordCount(refEntries),
+	}
+	failed := 0
+	for _, cfg := range configs {
+		output := filepath.Join(outputDir, benchOutputName(mediaPath, cfg))
+		run, err := benchOne(ctx, cfg, mediaPath, output, refEntries)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			logger.Warnw("Benchmark run failed",
+				"provider", run.Provider,
+				"model", run.Model,
+				"error", err,
+			)
+			run.Error = err.Error()
+			failed++
+		} else if keep {
+			run.Output = absPath(output)
+		}
+		rep.Runs = append(rep.Runs, run)
+	}
+
+	var flushErr error
+	report(rep, func() {
+		fmt.Printf("Reference: %s (%d entries, %d words)\n", rep.Reference, rep.Entries, rep.Words)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "PROVIDER\tMODEL\tWER\tCER\tOFFSET\tMEDIAN\tP90\tMATCHED\tTIME\tBILLED")
+		for _, run := range rep.Runs {
+			if run.Error != "" {
+				_, _ = fmt.Fprintf(w, "%s\t%s\tfailed: %s\n", run.Provider, run.Model, run.Error)
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%.1f%%\t%.1f%%\t%+.2fs\t%.2fs\t%.2fs\t%d/%d\t%s\t%s\n",
+				run.Provider,
+				run.Model,
+				run.WER*100,
+				run.CER*100,
+				run.OffsetMeanSeconds,
+				run.OffsetMedianSeconds,
+				run.OffsetP90Seconds,
+				run.OffsetMatched,
+				rep.Entries,
+				time.Duration(run.ElapsedSeconds*float64(time.Second)).Round(time.Second),
+				billedUsage(run.Usage),
+			)
+		}
+		flushErr = w.Flush()
+		if keep {
+			fmt.Printf("Subtitles kept in %s\n", absPath(outputDir))
+		}
+	})
+	if flushErr != nil {
+		return flushErr
+	}
+
+	if failed > 0 {
+		err := fmt.Errorf("%d of %d benchmark runs failed", failed, len(configs))
+		if failed < len(configs) {
+			return errs.Wrap(errs.KindPartial, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// transcribes input into output with cfg and scores it against reference
+func benchOne(
+	ctx context.Context,
+	cfg *generateConfig,
+	input, output string,
+	reference []subtitle.Entry,
+) (benchRun, error) {
+	run := benchRun{Provider: string(cfg.provider), Model: cfg.model}
+	cfg.progress = startProgress()
+	defer cfg.progress.Close()
+
+	started := time.Now()
+	result, err := generateSubtitles(ctx, cfg, input, output, logger)
+	if err != nil {
+		return run, err
+	}
+	run.ElapsedSeconds = time.Since(started).Seconds()
+
+	hypothesis, err := subtitle.Open(output)
+	if err != nil {
+		return run, fmt.Errorf("failed to read generated subtitles: %w", err)
+	}
+	score := bench.Compare(reference, hypothesis.Subtitle().Entries)
+	run.Entries = result.Entries
+	run.WER, run.CER = score.WER, score.CER
+	run.WordErrors, run.CharErrors = score.WordErrors, score.CharErrors
+	run.OffsetMatched = score.Offsets.Matched
+	run.OffsetMeanSeconds = score.Offsets.Mean.Seconds()
+	run.OffsetMedianSeconds = score.Offsets.Median.Seconds()
+	run.OffsetP90Seconds = score.Offsets.P90.Seconds()
+	run.Usage = result.Usage
+	return run, nil
+}
+
+// the settings newConfig reads for each --providers entry: the shared
+// flags with the entry's provider and model, or --provider and --model
+// without entries
+func benchConfigs[T any](
+	cmd *cobra.Command,
+	entries []string,
+	newConfig func(*cobra.Command) (T, error),
+) ([]T, error) {
+	if len(entries) == 0 {
+		cfg, err := newConfig(cmd)
+		if err != nil {
+			return nil, err
+		}
+		return []T{cfg}, nil
+	}
+
+	type run struct{ provider, model string }
+	runs := make([]run, 0, len(entries))
+	providers := make(map[string]bool)
+	for _, entry := range entries {
+		provider, model, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		provider, model = strings.TrimSpace(provider), strings.TrimSpace(model)
+		if provider == "" {
+			return nil, inputErrorf("invalid --providers entry %q: use provider or provider:model", entry)
+		}
+		runs = append(runs, run{provider, model})
+		providers[provider] = true
+	}
+	if flagProvided(cmd, "api-key") && len(providers) > 1 {
+		return nil, inputErrorf(
+			"--api-key cannot be used with several providers: store their keys with 'lipi auth' or set their environment variables",
+		)
+	}
+
+	configs := make([]T, 0, len(runs))
+	for _, r := range runs {
+		if err := cmd.Flags().Set("provider", r.provider); err != nil {
+			return nil, err
+		}
+		if err := cmd.Flags().Set("model", r.model); err != nil {
+			return nil, err
+		}
+		cfg, err := newConfig(cmd)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+// the directory runs write their output to: dir when set, or a temp
+// directory in workDir removed by cleanup
+func benchOutputDir(dir, workDir string) (string, func(), error) {
+	if dir != "" {
+		dir = expandHome(dir)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+		return dir, func() {}, nil
+	}
+	dir, err := os.MkdirTemp(workDir, "lipi-bench-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	return dir, func() { _ = os.RemoveAll(dir) }, nil
+}
+
+// file name of a run's subtitles: episode.mkv -> episode.gemini-gemini-2.5-flash.srt
+func benchOutputName(input string, cfg *generateConfig) string {
+	base := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+	if source.IsRemote(input) || base == "" {
+		base = "bench"
+	}
+	model := strings.NewReplacer("/", "-", ":", "-").Replace(cfg.model)
+	return fmt.Sprintf("%s.%s-%s.%s", base, cfg.provider, model, cfg.format)
+}
+
+// what a run was billed for: tokens, or audio seconds for Whisper
+func billedUsage(u usage.Usage) string {
+	if u.AudioSeconds > 0 {
+		return fmt.Sprintf("%s audio", time.Duration(u.AudioSeconds*float64(time.Second)).Round(time.Second))
+	}
+	return fmt.Sprintf("%d+%d tokens", u.InputTokens, u.OutputTokens)
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/spf13/cobra"
+)
+
+func TestBenchConfigs(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	tests := []struct {
+		name        string
+		providers   []string
+		want        []string
+		wantErrKind errs.Kind
+	}{
+		{
+			name: "flags without entries",
+			want: []string{"gemini:gemini-2.5-flash"},
+		},
+		{
+			name:      "models of one provider",
+			providers: []string{"gemini", "gemini:gemini-2.5-pro"},
+			want:      []string{"gemini:gemini-2.5-flash", "gemini:gemini-2.5-pro"},
+		},
+		{
+			name:        "api key with several providers",
+			providers:   []string{"gemini", "groq"},
+			wantErrKind: errs.KindInput,
+		},
+		{
+			name:        "missing provider",
+			providers:   []string{":whisper-1"},
+			wantErrKind: errs.KindInput,
+		},
+		{
+			name:        "unknown model",
+			providers:   []string{"gemini:gemini-0"},
+			wantErrKind: errs.KindInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "bench"}
+			addGenerateFlags(cmd)
+			cmd.Flags().String("language", "", "")
+			if err := cmd.Flags().Set("api-key", "test-key"); err != nil {
+				t.Fatal(err)
+			}
+
+			configs, err := benchConfigs(cmd, tt.providers, newGenerateConfig)
+			if tt.wantErrKind != errs.KindUnknown {
+				if errs.KindOf(err) != tt.wantErrKind {
+					t.Fatalf("error = %v, want kind %s", err, tt.wantErrKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("benchConfigs() error = %v", err)
+			}
+			var got []string
+			for _, cfg := range configs {
+				got = append(got, string(cfg.provider)+":"+cfg.model)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("runs = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("runs = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBenchOutputName(t *testing.T) {
+	cfg := &generateConfig{provider: "groq", model: "openai/whisper-large-v3", format: subtitle.FormatVTT}
+	if got := benchOutputName("/media/episode.mkv", cfg); got != "episode.groq-openai-whisper-large-v3.vtt" {
+		t.Errorf("benchOutputName() = %q", got)
+	}
+}
+
+func TestBenchTranslateConfigs(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	cmd := &cobra.Command{Use: "bench-translate"}
+	addTranslateFlags(cmd)
+	cmd.Flags().String("language", "", "")
+	for name, value := range map[string]string{"api-key": "test-key", "target-language": "es"} {
+		if err := cmd.Flags().Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	configs, err := benchConfigs(cmd, []string{"gemini", "gemini:gemini-2.5-pro"}, newTranslateConfig)
+	if err != nil {
+		t.Fatalf("benchConfigs() error = %v", err)
+	}
+	if len(configs) != 2 || configs[0].model != "" || configs[1].model != "gemini-2.5-pro" {
+		t.Fatalf("configs = %+v", configs)
+	}
+	if got := benchTranslateOutputName("/subs/episode.en.srt", configs[1]); got != "episode.en.es.gemini-gemini-2.5-pro.srt" {
+		t.Errorf("benchTranslateOutputName() = %q", got)
+	}
+	if got := benchTranslateOutputName("episode.srt", configs[0]); got != "episode.es.gemini-default.srt" {
+		t.Errorf("benchTranslateOutputName() = %q", got)
+	}
+
+	_, err = benchConfigs(cmd, []string{"gemini", "anthropic"}, newTranslateConfig)
+	if errs.KindOf(err) != errs.KindInput {
+		t.Errorf("error = %v, want kind %s", err, errs.KindInput)
+	}
+}
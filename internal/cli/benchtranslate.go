@@ -0,0 +1,229 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/bench"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var benchTranslateCmd = &cobra.Command{
+	Use:   "bench-translate [subtitle_file] [reference_subtitles]",
+	Short: "Compare translation providers against a reference translation",
+	Long: `Translate subtitles with each provider or model in --providers and score
+the results against a translation known to be good, to pick the model that
+works best, or the cheapest one that works well enough, for your content.
+
+Each run reports its BLEU and chrF scores against the reference (0-100,
+higher is better), the length of the translation relative to the
+reference's, how long it took, and the tokens it was billed for. Text is
+compared lowercased and without punctuation, as one document, so the
+translation may merge or split entries. chrF compares characters and suits
+languages written without spaces better than BLEU.
+
+Every run uses the same translate flags, such as --batch-size, --glossary,
+or --prompt. Keys are read from the usual places for each provider;
+--api-key is only accepted when every run uses one provider.
+
+Examples:
+  lipi bench-translate episode.en.srt episode.es.srt -t es --providers gemini,openai,anthropic
+  lipi bench-translate talk.srt talk.ja.srt -t ja --providers gemini:gemini-2.5-flash,gemini:gemini-2.5-pro
+  lipi bench-translate clip.vtt clip.de.vtt -t de --providers gemini,openai --output-dir bench/ --json`,
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(
+		cmd *cobra.Command,
+		args []string,
+		toComplete string,
+	) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return []string{"srt", "vtt", "ass", "ssa"}, cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: runBenchTranslate,
+}
+
+func init() {
+	rootCmd.AddCommand(benchTranslateCmd)
+
+	benchTranslateCmd.Flags().
+		StringSlice("providers", nil, "Providers to compare, as provider or provider:model (default: --provider with --model)")
+	benchTranslateCmd.Flags().
+		String("output-dir", "", "Keep the translation of each run in this directory (default: discard them)")
+	addTranslateFlags(benchTranslateCmd)
+
+	mustRegisterCompletion(benchTranslateCmd, "providers", completeValues("gemini", "openai", "anthropic"))
+}
+
+// bench-translate result as reported by --json
+type benchTranslateReport struct {
+	Reference string              `json:"reference"`
+	Entries   int                 `json:"entries"`
+	Runs      []benchTranslateRun `json:"runs"`
+}
+
+// one translation provider and model scored against the reference
+type benchTranslateRun struct {
+	Provider       string      `json:"provider"`
+	Model          string      `json:"model"`
+	Output         string      `json:"output,omitempty"`
+	Entries        int         `json:"entries"`
+	BLEU           float64     `json:"bleu"`
+	ChrF           float64     `json:"chrf"`
+	LengthRatio    float64     `json:"length_ratio"`
+	ElapsedSeconds float64     `json:"elapsed_seconds"`
+	Usage          usage.Usage `json:"usage"`
+	Error          string      `json:"error,omitempty"`
+}
+
+func runBenchTranslate(cmd *cobra.Command, args []string) error {
+	sourcePath, referencePath := args[0], args[1]
+	ctx := cmd.Context()
+	providers, _ := cmd.Flags().GetStringSlice("providers")
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+
+	for _, path := range args {
+		if !isSubtitlePath(path) {
+			return inputErrorf(
+				"unsupported subtitles %s: expected srt, vtt, or ass subtitles",
+				filepath.Ext(path),
+			)
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return inputErrorf("file not found: %s", path)
+		}
+	}
+	reference, err := subtitle.Open(referencePath)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, fmt.Errorf("failed to read reference subtitles: %w", err))
+	}
+	refEntries := reference.Subtitle().Entries
+
+	configs, err := benchConfigs(cmd, providers, newTranslateConfig)
+	if err != nil {
+		return err
+	}
+
+	keep := outputDir != ""
+	outputDir, cleanup, err := benchOutputDir(outputDir, "")
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	rep := benchTranslateReport{
+		Reference: absPath(referencePath),
+		Entries:   len(refEntries),
+	}
+	failed := 0
+	for _, cfg := range configs {
+		output := filepath.Join(outputDir, benchTranslateOutputName(sourcePath, cfg))
+		run, err := benchTranslateOne(ctx, cfg, sourcePath, output, refEntries)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			logger.Warnw("Benchmark run failed",
+				"provider", run.Provider,
+				"model", run.Model,
+				"error", err,
+			)
+			run.Error = err.Error()
+			failed++
+		} else if keep {
+			run.Output = absPath(output)
+		}
+		rep.Runs = append(rep.Runs, run)
+	}
+
+	var flushErr error
+	report(rep, func() {
+		fmt.Printf("Reference: %s (%d entries)\n", rep.Reference, rep.Entries)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "PROVIDER\tMODEL\tBLEU\tCHRF\tLENGTH\tENTRIES\tTIME\tBILLED")
+		for _, run := range rep.Runs {
+			if run.Error != "" {
+				_, _ = fmt.Fprintf(w, "%s\t%s\tfailed: %s\n", run.Provider, run.Model, run.Error)
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%.1f\t%.1f\t%.2f\t%d\t%s\t%s\n",
+				run.Provider,
+				run.Model,
+				run.BLEU,
+				run.ChrF,
+				run.LengthRatio,
+				run.Entries,
+				time.Duration(run.ElapsedSeconds*float64(time.Second)).Round(time.Second),
+				billedUsage(run.Usage),
+			)
+		}
+		flushErr = w.Flush()
+		if keep {
+			fmt.Printf("Translations kept in %s\n", absPath(outputDir))
+		}
+	})
+	if flushErr != nil {
+		return flushErr
+	}
+
+	if failed > 0 {
+		err := fmt.Errorf("%d of %d benchmark runs failed", failed, len(configs))
+		if failed < len(configs) {
+			return errs.Wrap(errs.KindPartial, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// translates input into output with cfg and scores it against reference
+func benchTranslateOne(
+	ctx context.Context,
+	cfg *translateConfig,
+	input, output string,
+	reference []subtitle.Entry,
+) (benchTranslateRun, error) {
+	run := benchTranslateRun{Provider: string(cfg.provider), Model: benchModel(cfg.model)}
+	cfg.progress = startProgress()
+	defer cfg.progress.Close()
+
+	started := time.Now()
+	result, err := translateSubtitles(ctx, cfg, input, output, logger)
+	if err != nil {
+		return run, err
+	}
+	run.ElapsedSeconds = time.Since(started).Seconds()
+
+	hypothesis, err := subtitle.Open(output)
+	if err != nil {
+		return run, fmt.Errorf("failed to read translated subtitles: %w", err)
+	}
+	score := bench.CompareTranslation(reference, hypothesis.Subtitle().Entries)
+	run.Entries = result.Entries
+	run.BLEU, run.ChrF, run.LengthRatio = score.BLEU, score.ChrF, score.LengthRatio
+	run.Usage = result.Usage
+	return run, nil
+}
+
+// label of a translation model; the translator picks the provider's
+// default when none is set
+func benchModel(model string) string {
+	if model == "" {
+		return "default"
+	}
+	return model
+}
+
+// file name of a run's translation: episode.en.srt -> episode.en.es.openai-gpt-4o.srt
+func benchTranslateOutputName(input string, cfg *translateConfig) string {
+	ext := filepath.Ext(input)
+	base := strings.TrimSuffix(filepath.Base(input), ext)
+	model := strings.NewReplacer("/", "-", ":", "-").Replace(benchModel(cfg.model))
+	return fmt.Sprintf("%s.%s.%s-%s%s", base, cfg.targetLang, cfg.provider, model, ext)
+}
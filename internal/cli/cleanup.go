@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/transcribe"
+	"github.com/spf13/cobra"
+)
+
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Delete files lipi left behind with a provider",
+	Long: `Delete the audio lipi uploaded to the Gemini Files API that was never
+cleaned up. Each upload is deleted once its chunk is transcribed, but a run
+that crashed or lost its connection can leave files behind; they count
+against the project's storage quota until Gemini expires them after 48
+hours.
+
+Only files lipi uploaded (display names starting with "lipi-") are touched,
+and only those older than --older-than, so uploads of runs still in progress
+are left alone. --dry-run lists the files without deleting them.
+
+Examples:
+  lipi cleanup --provider gemini --dry-run
+  lipi cleanup --provider gemini
+  lipi cleanup --provider gemini --older-than 0`,
+	Args: cobra.NoArgs,
+	RunE: runCleanup,
+}
+
+func init() {
+	rootCmd.AddCommand(cleanupCmd)
+
+	cleanupCmd.Flags().
+		String("provider", "gemini", "Provider to clean up (gemini)")
+	cleanupCmd.Flags().
+		StringP("api-key", "k", "", "API key (or set GEMINI_API_KEY env var)")
+	cleanupCmd.Flags().
+		Duration("older-than", time.Hour, "Only delete files uploaded at least this long ago")
+	cleanupCmd.Flags().
+		Bool("dry-run", false, "List the files that would be deleted without deleting them")
+
+	mustRegisterCompletion(cleanupCmd, "provider", completeValues("gemini"))
+}
+
+// cleanup result as reported by --json
+type cleanupReport struct {
+	Provider string          `json:"provider"`
+	DryRun   bool            `json:"dry_run"`
+	Files    []cleanupFile   `json:"files"`
+	Deleted  int             `json:"deleted"`
+	Failed   []cleanupFailed `json:"failed,omitempty"`
+}
+
+type cleanupFile struct {
+	Name        string    `json:"name"`
+	DisplayName string    `json:"display_name"`
+	Size        int64     `json:"size"`
+	Created     time.Time `json:"created"`
+}
+
+type cleanupFailed struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+func runCleanup(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	provider, _ := cmd.Flags().GetString("provider")
+	apiKey, _ := cmd.Flags().GetString("api-key")
+	olderThan, _ := cmd.Flags().GetDuration("older-than")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	if provider != string(transcribe.ProviderGemini) {
+		return inputErrorf("unsupported provider %q: only gemini uploads files", provider)
+	}
+	if olderThan < 0 {
+		return inputErrorf("older-than must not be negative, got %s", olderThan)
+	}
+	if err := refuseOfflineProvider(provider); err != nil {
+		return err
+	}
+	if apiKey == "" {
+		apiKey = lookupAPIKey(provider, providerKeyEnv[provider])
+	}
+	if apiKey == "" {
+		return missingAPIKeyError(providerKeyEnv[provider])
+	}
+
+	files, err := transcribe.NewGeminiFiles(ctx, apiKey, transcribe.Options{})
+	if err != nil {
+		return err
+	}
+	uploads, err := files.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	rep := cleanupReport{Provider: provider, DryRun: dryRun, Files: []cleanupFile{}}
+	cutoff := time.Now().Add(-olderThan)
+	for _, upload := range uploads {
+		if upload.Created.After(cutoff) {
+			continue
+		}
+		rep.Files = append(rep.Files, cleanupFile{
+			Name:        upload.Name,
+			DisplayName: upload.DisplayName,
+			Size:        upload.Size,
+			Created:     upload.Created,
+		})
+		if dryRun {
+			continue
+		}
+		if err := files.Delete(ctx, upload.Name); err != nil {
+			logger.Warnw("Failed to delete upload", "file", upload.Name, "error", err)
+			rep.Failed = append(rep.Failed, cleanupFailed{Name: upload.Name, Error: err.Error()})
+			continue
+		}
+		rep.Deleted++
+	}
+
+	var flushErr error
+	report(rep, func() {
+		if len(rep.Files) == 0 {
+			fmt.Printf("No %s uploads older than %s to clean up\n", provider, olderThan)
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, f := range rep.Files {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				f.Name, f.DisplayName, audio.FormatBytes(uint64(f.Size)), f.Created.Local().Format(time.DateTime))
+		}
+		flushErr = w.Flush()
+		if dryRun {
+			fmt.Printf("%d files would be deleted (dry run)\n", len(rep.Files))
+		} else {
+			fmt.Printf("Deleted %d of %d files\n", rep.Deleted, len(rep.Files))
+		}
+	})
+	if flushErr != nil {
+		return flushErr
+	}
+	if len(rep.Failed) > 0 {
+		return fmt.Errorf("failed to delete %d of %d files", len(rep.Failed), len(rep.Files))
+	}
+	return nil
+}
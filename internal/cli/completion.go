@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/models"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/transcribe"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate a shell completion script",
+	Long: `Generate a completion script for your shell. Besides commands and flags,
+it completes provider names, the models of the selected --provider (including
+models cached by "lipi models"), output formats, and media files.
+
+Load it for the current session:
+  bash:        source <(lipi completion bash)
+  zsh:         source <(lipi completion zsh)
+  fish:        lipi completion fish | source
+  powershell:  lipi completion powershell | Out-String | Invoke-Expression
+
+To load it for every session, write the script to your shell's completion
+directory, e.g.:
+  lipi completion bash > /etc/bash_completion.d/lipi
+  lipi completion zsh > "${fpath[1]}/_lipi"
+  lipi completion fish > ~/.config/fish/completions/lipi.fish`,
+	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE:      runCompletion,
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	out := os.Stdout
+	switch args[0] {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(out, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(out)
+	case "fish":
+		return rootCmd.GenFishCompletion(out, true)
+	case "powershell":
+		return rootCmd.GenPowerShellCompletionWithDesc(out)
+	}
+	return inputErrorf("unsupported shell %q", args[0])
+}
+
+// completes a flag from a fixed list, without falling back to file names
+func completeValues(values ...string) cobra.CompletionFunc {
+	return cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp)
+}
+
+// completes --model with the transcription models of the selected --provider
+func completeTranscriptionModels(
+	cmd *cobra.Command,
+	args []string,
+	toComplete string,
+) ([]cobra.Completion, cobra.ShellCompDirective) {
+	provider, _ := cmd.Flags().GetString("provider")
+	var ids []string
+	switch strings.ToLower(provider) {
+	case "gemini":
+		ids = modelCandidates("gemini", validGeminiModels)
+	case "openai":
+		ids = modelCandidates("", validOpenAIAudioModels)
+	case "groq":
+		ids = modelCandidates("", validGroqAudioModels)
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completes the translation model flag with the models of the provider
+// named by providerFlag
+func completeTranslationModels(providerFlag string) cobra.CompletionFunc {
+	return func(
+		cmd *cobra.Command,
+		args []string,
+		toComplete string,
+	) ([]cobra.Completion, cobra.ShellCompDirective) {
+		provider, _ := cmd.Flags().GetString(providerFlag)
+		var ids []string
+		switch strings.ToLower(provider) {
+		case "gemini":
+			ids = modelCandidates("gemini", validGeminiModels)
+		case "openai":
+			ids = modelCandidates("openai", validOpenAIModels)
+		case "anthropic":
+			ids = modelCandidates("anthropic", validAnthropicModels)
+		}
+		return ids, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// sorted built-in models plus, when provider is set, the models cached by
+// the last successful "lipi models" query for it
+func modelCandidates(provider string, builtin map[string]bool) []string {
+	seen := make(map[string]bool, len(builtin))
+	for id := range builtin {
+		seen[id] = true
+	}
+	if provider != "" {
+		cached, _ := models.LoadCache(provider)
+		for _, m := range cached {
+			seen[m.ID] = true
+		}
+	}
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// completes positional arguments with audio and video files
+func completeMediaFiles(
+	cmd *cobra.Command,
+	args []string,
+	toComplete string,
+) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return audio.MediaExtensions(), cobra.ShellCompDirectiveFilterFileExt
+}
+
+// completes the single positional argument with an audio or video file
+func completeMediaFile(
+	cmd *cobra.Command,
+	args []string,
+	toComplete string,
+) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeMediaFiles(cmd, args, toComplete)
+}
+
+// completes the positional argument with subtitle files
+func completeSubtitleFiles(
+	cmd *cobra.Command,
+	args []string,
+	toComplete string,
+) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return []string{"srt", "vtt", "ass", "ssa"}, cobra.ShellCompDirectiveFilterFileExt
+}
+
+// completes the positional argument with directories
+func completeDirectories(
+	cmd *cobra.Command,
+	args []string,
+	toComplete string,
+) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveFilterDirs
+}
+
+// registers value completion for the flags added by addGenerateFlags
+func registerGenerateCompletions(cmd *cobra.Command) {
+	mustRegisterCompletion(cmd, "provider", completeValues("gemini", "openai", "groq"))
+	mustRegisterCompletion(cmd, "model", completeTranscriptionModels)
+	mustRegisterCompletion(cmd, "format", completeValues("srt", "vtt", "ass"))
+	mustRegisterCompletion(cmd, "chunk-format", completeValues("mp3", "opus", "wav", "aac"))
+	mustRegisterCompletion(cmd, "input-format", completeValues(audio.MediaExtensions()...))
+	mustRegisterCompletion(cmd, "separator", completeValues("demucs", "spleeter"))
+	mustRegisterCompletion(cmd, "lyrics", completeValues(
+		string(transcribe.LyricsKeep),
+		string(transcribe.LyricsSkip),
+		string(transcribe.LyricsTag),
+	))
+	mustRegisterCompletion(cmd, "speaker-styles", completeValues(
+		string(subtitle.SpeakerStylesColor),
+		string(subtitle.SpeakerStylesSides),
+	))
+}
+
+// registers value completion for a translation provider flag and the
+// model flag that depends on it
+func registerTranslateCompletions(cmd *cobra.Command, providerFlag, modelFlag string) {
+	mustRegisterCompletion(cmd, providerFlag, completeValues("gemini", "openai", "anthropic"))
+	mustRegisterCompletion(cmd, modelFlag, completeTranslationModels(providerFlag))
+}
+
+// registration only fails for a flag that does not exist, which is a bug
+func mustRegisterCompletion(cmd *cobra.Command, flag string, fn cobra.CompletionFunc) {
+	if err := cmd.RegisterFlagCompletionFunc(flag, fn); err != nil {
+		panic(fmt.Sprintf("completion for --%s on %s: %v", flag, cmd.Name(), err))
+	}
+}
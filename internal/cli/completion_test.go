@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func TestModelCandidates(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	builtin := map[string]bool{"gemini-2.5-pro": true, "gemini-2.5-flash": true}
+	if err := models.SaveCache("gemini", []models.Model{
+		{ID: "gemini-2.5-pro"},
+		{ID: "gemini-3-ultra"},
+	}); err != nil {
+		t.Fatalf("SaveCache() error = %v", err)
+	}
+
+	got := modelCandidates("gemini", builtin)
+	want := []string{"gemini-2.5-flash", "gemini-2.5-pro", "gemini-3-ultra"}
+	if !slices.Equal(got, want) {
+		t.Errorf("modelCandidates() = %v, want %v", got, want)
+	}
+
+	got = modelCandidates("", builtin)
+	want = []string{"gemini-2.5-flash", "gemini-2.5-pro"}
+	if !slices.Equal(got, want) {
+		t.Errorf("modelCandidates() without cache = %v, want %v", got, want)
+	}
+}
+
+func TestCompleteTranslationModels(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	tests := []struct {
+		provider string
+		want     string
+	}{
+		{"anthropic", "claude-sonnet-4-5"},
+		{"openai", "gpt-5"},
+		{"gemini", "gemini-2.5-flash"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			cmd.Flags().String("translate-provider", "", "")
+			if err := cmd.Flags().Set("translate-provider", tt.provider); err != nil {
+				t.Fatal(err)
+			}
+
+			got, directive := completeTranslationModels("translate-provider")(cmd, nil, "")
+			if !slices.Contains(got, tt.want) {
+				t.Errorf("completions %v do not include %q", got, tt.want)
+			}
+			if directive != cobra.ShellCompDirectiveNoFileComp {
+				t.Errorf("directive = %v, want NoFileComp", directive)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/transcribe"
+	"github.com/mgpai22/lipi/internal/translate"
+)
+
+// concurrency value of --concurrency auto, resolved once the amount of work
+// is known
+const concurrencyAuto = 0
+
+// parallel requests each provider handles comfortably on a paid tier, used
+// as the ceiling for --concurrency auto
+var (
+	transcriptionConcurrencyLimits = map[transcribe.Provider]int{
+		transcribe.ProviderGemini: 6,
+		transcribe.ProviderOpenAI: 4,
+		transcribe.ProviderGroq:   4,
+	}
+	translationConcurrencyLimits = map[translate.Provider]int{
+		translate.ProviderGemini:    6,
+		translate.ProviderOpenAI:    6,
+		translate.ProviderAnthropic: 4,
+	}
+)
+
+// fallback ceiling for providers without a known limit
+const defaultConcurrencyLimit = 3
+
+// parses --concurrency: a positive worker count or "auto"
+func parseConcurrency(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, "auto") {
+		return concurrencyAuto, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, inputErrorf(
+			"invalid concurrency %q: use a positive number or auto",
+			value,
+		)
+	}
+	return n, nil
+}
+
+// one worker per unit of work (chunk or request batch), up to limit
+func autoConcurrency(units, limit int) int {
+	return max(min(units, limit), 1)
+}
+
+func transcriptionConcurrencyLimit(provider transcribe.Provider) int {
+	if limit, ok := transcriptionConcurrencyLimits[provider]; ok {
+		return limit
+	}
+	return defaultConcurrencyLimit
+}
+
+func translationConcurrencyLimit(provider translate.Provider) int {
+	if limit, ok := translationConcurrencyLimits[provider]; ok {
+		return limit
+	}
+	return defaultConcurrencyLimit
+}
@@ -0,0 +1,45 @@
+package cli
+
+import "testing"
+
+func TestParseConcurrency(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "auto", want: concurrencyAuto},
+		{value: "AUTO", want: concurrencyAuto},
+		{value: "4", want: 4},
+		{value: "0", wantErr: true},
+		{value: "-2", wantErr: true},
+		{value: "many", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseConcurrency(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseConcurrency(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseConcurrency(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestAutoConcurrency(t *testing.T) {
+	tests := []struct {
+		units, limit, want int
+	}{
+		{units: 2, limit: 6, want: 2},
+		{units: 40, limit: 6, want: 6},
+		{units: 0, limit: 6, want: 1},
+	}
+
+	for _, tt := range tests {
+		if got := autoConcurrency(tt.units, tt.limit); got != tt.want {
+			t.Errorf("autoConcurrency(%d, %d) = %d, want %d", tt.units, tt.limit, got, tt.want)
+		}
+	}
+}
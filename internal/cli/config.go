@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/config"
+	"github.com/mgpai22/lipi/internal/credentials"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// config file loaded for this invocation; nil-safe when none is loaded
+var appConfig *config.Config
+
+// prefix of the environment variables that set flags: LIPI_CONCURRENCY sets
+// --concurrency, LIPI_TRANSLATE_TO sets --translate-to
+const envPrefix = "LIPI_"
+
+// flag annotation recording that a value came from the environment or the
+// config file rather than the command line
+const presetAnnotation = "lipi_preset"
+
+// fills in every flag the user did not set on the command line from LIPI_*
+// environment variables, then loads --config (or the default config file)
+// for the rest, so precedence is config < env < flags
+func loadConfig(cmd *cobra.Command) error {
+	// LIPI_CONFIG and LIPI_PROFILE are needed to pick the config file
+	if err := applyEnv(cmd); err != nil {
+		return err
+	}
+
+	path, required := configPath, true
+	if path == "" {
+		path, required = config.DefaultPath(), false
+	}
+
+	cfg, err := config.Load(path, required)
+	if err != nil {
+		return err
+	}
+	if err := cfg.UseProfile(profile); err != nil {
+		return err
+	}
+	appConfig = cfg
+
+	return applyConfig(cmd, cfg)
+}
+
+// environment variable that sets the named flag
+func flagEnvVar(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// sets unset flags on cmd from LIPI_* environment variables. Like config
+// values, they leave Changed meaning "set on the command line".
+func applyEnv(cmd *cobra.Command) error {
+	var errs []error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed || f.Name == "help" {
+			return
+		}
+		envVar := flagEnvVar(f.Name)
+		value, ok := os.LookupEnv(envVar)
+		if !ok {
+			return
+		}
+		if err := f.Value.Set(value); err != nil {
+			errs = append(errs, fmt.Errorf(
+				"invalid value %q for %s in %s: %w",
+				value,
+				f.Name,
+				envVar,
+				err,
+			))
+			return
+		}
+		markPreset(f)
+	})
+	return errors.Join(errs...)
+}
+
+// sets unset flags on cmd from the command's config section or the global
+// defaults, skipping flags already set from the environment. Flags are left
+// unmarked so Changed still means "set by the user".
+func applyConfig(cmd *cobra.Command, cfg *config.Config) error {
+	var errs []error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed || f.Name == "config" || f.Name == "profile" ||
+			f.Name == "help" {
+			return
+		}
+		if _, ok := os.LookupEnv(flagEnvVar(f.Name)); ok {
+			return
+		}
+		value, ok := cfg.Lookup(cmd.Name(), f.Name)
+		if !ok {
+			return
+		}
+		if err := f.Value.Set(value); err != nil {
+			errs = append(errs, fmt.Errorf(
+				"invalid value %q for %s in %s: %w",
+				value,
+				f.Name,
+				cfg.Path,
+				err,
+			))
+			return
+		}
+		markPreset(f)
+	})
+	return errors.Join(errs...)
+}
+
+func markPreset(f *pflag.Flag) {
+	if f.Annotations == nil {
+		f.Annotations = map[string][]string{}
+	}
+	f.Annotations[presetAnnotation] = []string{"true"}
+}
+
+// reports whether a flag was given on the command line, in the
+// environment, or in the config file, for flags whose default means "unset"
+func flagProvided(cmd *cobra.Command, name string) bool {
+	f := cmd.Flags().Lookup(name)
+	if f == nil {
+		return false
+	}
+	_, preset := f.Annotations[presetAnnotation]
+	return f.Changed || preset
+}
+
+// expands a leading ~ so config and profile paths can be written portably
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// API key for provider from the config file, falling back to envVar and
+// then to a key stored with "lipi auth set"
+func lookupAPIKey(provider, envVar string) string {
+	if key := appConfig.APIKey(provider); key != "" {
+		return key
+	}
+	if key := os.Getenv(envVar); key != "" {
+		return key
+	}
+	return credentials.Lookup(provider, credentialPassphrase(false))
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func TestApplyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "concurrency: 8\ngenerate:\n  format: vtt\n  provider: openai\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := config.Load(path, true)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	cmd := &cobra.Command{Use: "generate"}
+	addGenerateFlags(cmd)
+	if err := cmd.Flags().Parse([]string{"--provider", "gemini"}); err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	if err := applyConfig(cmd, cfg); err != nil {
+		t.Fatalf("applyConfig error: %v", err)
+	}
+
+	format, _ := cmd.Flags().GetString("format")
+	concurrency, _ := cmd.Flags().GetString("concurrency")
+	provider, _ := cmd.Flags().GetString("provider")
+
+	if format != "vtt" {
+		t.Errorf("format = %q, want vtt from command section", format)
+	}
+	if concurrency != "8" {
+		t.Errorf("concurrency = %q, want 8 from global defaults", concurrency)
+	}
+	if provider != "gemini" {
+		t.Errorf("provider = %q, want gemini from the command line", provider)
+	}
+	if cmd.Flags().Changed("format") {
+		t.Error("config values should not mark flags as changed")
+	}
+}
+
+func TestApplyConfigInvalidValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("chunk_duration: lots\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := config.Load(path, true)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	cmd := &cobra.Command{Use: "generate"}
+	addGenerateFlags(cmd)
+	if err := applyConfig(cmd, cfg); err == nil {
+		t.Error("expected error for non-numeric chunk_duration")
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "concurrency: 8\ngenerate:\n  format: vtt\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := config.Load(path, true)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	t.Setenv("LIPI_CONCURRENCY", "2")
+	t.Setenv("LIPI_PROVIDER", "openai")
+	t.Setenv("LIPI_TEMPERATURE", "0")
+
+	cmd := &cobra.Command{Use: "generate"}
+	addGenerateFlags(cmd)
+	if err := cmd.Flags().Parse([]string{"--provider", "gemini"}); err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	if err := applyEnv(cmd); err != nil {
+		t.Fatalf("applyEnv error: %v", err)
+	}
+	if err := applyConfig(cmd, cfg); err != nil {
+		t.Fatalf("applyConfig error: %v", err)
+	}
+
+	format, _ := cmd.Flags().GetString("format")
+	concurrency, _ := cmd.Flags().GetString("concurrency")
+	provider, _ := cmd.Flags().GetString("provider")
+
+	if format != "vtt" {
+		t.Errorf("format = %q, want vtt from the config file", format)
+	}
+	if concurrency != "2" {
+		t.Errorf("concurrency = %q, want 2 from LIPI_CONCURRENCY", concurrency)
+	}
+	if provider != "gemini" {
+		t.Errorf("provider = %q, want gemini from the command line", provider)
+	}
+	if !flagProvided(cmd, "temperature") {
+		t.Error("temperature from LIPI_TEMPERATURE should count as provided")
+	}
+	if flagProvided(cmd, "prompt") {
+		t.Error("prompt was not provided anywhere")
+	}
+}
+
+func TestApplyEnvInvalidValue(t *testing.T) {
+	t.Setenv("LIPI_CHUNK_DURATION", "lots")
+
+	cmd := &cobra.Command{Use: "generate"}
+	addGenerateFlags(cmd)
+	if err := applyEnv(cmd); err == nil {
+		t.Error("expected error for non-numeric LIPI_CHUNK_DURATION")
+	}
+}
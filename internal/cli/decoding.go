@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// sampling settings for transcription or translation requests; nil fields
+// keep the provider default
+type decodingSettings struct {
+	temperature    *float64
+	topP           *float64
+	thinkingBudget *int
+}
+
+// what a provider accepts for the decoding flags
+type decodingLimits struct {
+	maxTemperature float64
+	topP           bool // accepts --top-p
+	thinking       bool // accepts --thinking-budget
+}
+
+// registers --top-p and --thinking-budget; commands add --temperature
+// themselves, with the range their providers accept in its help
+func addDecodingFlags(cmd *cobra.Command) {
+	cmd.Flags().
+		Float64("top-p", 0, "Nucleus sampling: only sample from the most likely tokens covering this probability (0-1; default: provider default)")
+	cmd.Flags().
+		Int("thinking-budget", 0, "Tokens Gemini 2.5 models may spend thinking before answering (0 turns thinking off, -1 lets the model decide; default: model default)")
+}
+
+// reads --temperature, --top-p, and --thinking-budget, checking them
+// against what provider accepts
+func newDecodingSettings(
+	cmd *cobra.Command,
+	provider string,
+	limits decodingLimits,
+) (decodingSettings, error) {
+	var d decodingSettings
+	if flagProvided(cmd, "temperature") {
+		t, _ := cmd.Flags().GetFloat64("temperature")
+		d.temperature = &t
+	}
+	if flagProvided(cmd, "top-p") {
+		p, _ := cmd.Flags().GetFloat64("top-p")
+		d.topP = &p
+	}
+	if flagProvided(cmd, "thinking-budget") {
+		b, _ := cmd.Flags().GetInt("thinking-budget")
+		d.thinkingBudget = &b
+	}
+	return d, d.check(provider, limits)
+}
+
+// checks the settings against what provider accepts, also for the
+// fallback models of a run
+func (d decodingSettings) check(provider string, limits decodingLimits) error {
+	if t := d.temperature; t != nil && (*t < 0 || *t > limits.maxTemperature) {
+		return inputErrorf(
+			"temperature for %s must be between 0 and %g, got %g",
+			provider,
+			limits.maxTemperature,
+			*t,
+		)
+	}
+	if p := d.topP; p != nil {
+		if !limits.topP {
+			return inputErrorf("--top-p is not supported by %s", provider)
+		}
+		if *p <= 0 || *p > 1 {
+			return inputErrorf("top-p must be above 0 and at most 1, got %g", *p)
+		}
+	}
+	if b := d.thinkingBudget; b != nil {
+		if !limits.thinking {
+			return inputErrorf("--thinking-budget requires the gemini provider, got %s", provider)
+		}
+		if *b < -1 {
+			return inputErrorf("thinking-budget must be -1 or more, got %d", *b)
+		}
+	}
+	return nil
+}
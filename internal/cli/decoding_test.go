@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/translate"
+	"github.com/spf13/cobra"
+)
+
+func TestNewDecodingSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider translate.Provider
+		flags    map[string]string
+		want     decodingSettings
+		wantErr  bool
+	}{
+		{
+			name:     "defaults",
+			provider: translate.ProviderGemini,
+		},
+		{
+			name:     "gemini",
+			provider: translate.ProviderGemini,
+			flags:    map[string]string{"temperature": "0", "top-p": "0.9", "thinking-budget": "0"},
+			want:     decodingSettings{temperature: ptr(0.0), topP: ptr(0.9), thinkingBudget: ptr(0)},
+		},
+		{
+			name:     "dynamic thinking",
+			provider: translate.ProviderGemini,
+			flags:    map[string]string{"thinking-budget": "-1"},
+			want:     decodingSettings{thinkingBudget: ptr(-1)},
+		},
+		{
+			name:     "anthropic temperature above 1",
+			provider: translate.ProviderAnthropic,
+			flags:    map[string]string{"temperature": "1.5"},
+			wantErr:  true,
+		},
+		{
+			name:     "openai thinking budget",
+			provider: translate.ProviderOpenAI,
+			flags:    map[string]string{"thinking-budget": "1024"},
+			wantErr:  true,
+		},
+		{
+			name:     "top-p out of range",
+			provider: translate.ProviderOpenAI,
+			flags:    map[string]string{"top-p": "0"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "translate"}
+			cmd.Flags().Float64("temperature", 0, "")
+			addDecodingFlags(cmd)
+			for name, value := range tt.flags {
+				if err := cmd.Flags().Set(name, value); err != nil {
+					t.Fatalf("failed to set --%s: %v", name, err)
+				}
+			}
+
+			got, err := newDecodingSettings(cmd, string(tt.provider), translateDecodingLimits(tt.provider))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newDecodingSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !equalPtr(got.temperature, tt.want.temperature) ||
+				!equalPtr(got.topP, tt.want.topP) ||
+				!equalPtr(got.thinkingBudget, tt.want.thinkingBudget) {
+				t.Errorf("newDecodingSettings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	return (a == nil) == (b == nil) && (a == nil || *a == *b)
+}
@@ -0,0 +1,341 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/dubbing"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/pool"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/tts"
+	"github.com/mgpai22/lipi/internal/video"
+	"github.com/spf13/cobra"
+)
+
+var dubCmd = &cobra.Command{
+	Use:   "dub [video_file]",
+	Short: "Dub a video into another language with synthesized speech",
+	Long: `Localize a video end to end: transcribe it, translate the transcript into
+--target-language, speak every translated cue with a text-to-speech voice,
+and mux the speech into a copy of the video as a new default audio track.
+
+Subtitles already next to the video are reused like 'lipi summarize' does,
+and so is an existing translation, so a translation fixed by hand is what
+gets spoken when the command is run again. --subtitles voices a translated
+subtitle file directly, skipping transcription and translation.
+
+Each cue's speech starts with the cue and may run until the next cue
+starts. Longer speech is played faster, by at most --max-speedup, and what
+still overruns is faded out and cut; the counts are reported at the end.
+
+The original audio tracks are kept behind the dub unless --replace-audio
+is given. The dubbed video is written to --dub-output (default:
+<name>.dubbed.<language><ext>).
+
+Speech providers: gemini (GEMINI_API_KEY), openai (OPENAI_API_KEY), and
+elevenlabs (ELEVENLABS_API_KEY, --voice takes a voice ID).
+
+Examples:
+  lipi dub video.mp4 --target-language es
+  lipi dub talk.mkv -t fr --tts-provider openai --voice nova
+  lipi dub film.mkv -t de --tts-provider elevenlabs --voice 21m00Tcm4TlvDq8ikWAM
+  lipi dub video.mp4 -t ja --subtitles video.ja.srt --replace-audio`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeMediaFile,
+	RunE:              runDub,
+}
+
+func init() {
+	rootCmd.AddCommand(dubCmd)
+
+	addGenerateFlags(dubCmd)
+	dubCmd.Flags().
+		StringP("target-language", "t", "", "Language to dub the video into (required)")
+	dubCmd.Flags().
+		String("subtitles", "", "Translated subtitles to voice, instead of transcribing and translating the video")
+	dubCmd.Flags().
+		String("translate-provider", "gemini", "Translation provider (gemini, openai, anthropic)")
+	dubCmd.Flags().
+		String("translate-model", "", "Model to use for translation (provider-specific, uses sensible defaults)")
+	dubCmd.Flags().
+		String("tts-provider", "gemini", "Text-to-speech provider (gemini, openai, elevenlabs)")
+	dubCmd.Flags().
+		String("tts-model", "", "Text-to-speech model (provider-specific, uses sensible defaults)")
+	dubCmd.Flags().
+		String("tts-api-key", "", "Text-to-speech API key (default: --api-key on the same provider, or the provider's env var)")
+	dubCmd.Flags().
+		String("voice", "", "Voice to speak with (default: Kore for gemini, alloy for openai, Rachel for elevenlabs)")
+	dubCmd.Flags().
+		String("voice-style", "", "How the voice should speak, e.g. \"calm and friendly\" (gemini, gpt-4o-mini-tts)")
+	dubCmd.Flags().
+		Int("tts-concurrency", 4, "Number of speech requests in flight at the same time")
+	dubCmd.Flags().
+		Float64("max-speedup", dubbing.DefaultMaxSpeedup, "Fastest a cue's speech may play to fit before it is cut (1: never sped up)")
+	dubCmd.Flags().
+		Bool("replace-audio", false, "Drop the video's own audio tracks instead of keeping them behind the dub")
+	dubCmd.Flags().
+		String("dub-output", "", "Path for the dubbed video (default: <name>.dubbed.<language><ext>)")
+
+	_ = dubCmd.MarkFlagRequired("target-language")
+	registerTranslateCompletions(dubCmd, "translate-provider", "translate-model")
+	mustRegisterCompletion(dubCmd, "tts-provider", completeValues(tts.Providers()...))
+}
+
+// speech key variables, by provider
+var speechKeyEnv = map[string]string{
+	tts.ProviderGemini:     "GEMINI_API_KEY",
+	tts.ProviderOpenAI:     "OPENAI_API_KEY",
+	tts.ProviderElevenLabs: "ELEVENLABS_API_KEY",
+}
+
+// dub result as reported by --json
+type dubReport struct {
+	Output    string `json:"output"`
+	Subtitles string `json:"subtitles"`
+	Language  string `json:"language"`
+	Cues      int    `json:"cues"`
+	SpedUp    int    `json:"sped_up"`
+	Trimmed   int    `json:"trimmed"`
+}
+
+func runDub(cmd *cobra.Command, args []string) error {
+	input := args[0]
+	ctx := cmd.Context()
+
+	targetLang, _ := cmd.Flags().GetString("target-language")
+	subtitlesPath, _ := cmd.Flags().GetString("subtitles")
+	translateProvider, _ := cmd.Flags().GetString("translate-provider")
+	translateModel, _ := cmd.Flags().GetString("translate-model")
+	ttsProvider, _ := cmd.Flags().GetString("tts-provider")
+	ttsModel, _ := cmd.Flags().GetString("tts-model")
+	ttsKey, _ := cmd.Flags().GetString("tts-api-key")
+	voice, _ := cmd.Flags().GetString("voice")
+	voiceStyle, _ := cmd.Flags().GetString("voice-style")
+	ttsConcurrency, _ := cmd.Flags().GetInt("tts-concurrency")
+	maxSpeedup, _ := cmd.Flags().GetFloat64("max-speedup")
+	replaceAudio, _ := cmd.Flags().GetBool("replace-audio")
+	dubOutput, _ := cmd.Flags().GetString("dub-output")
+	workDir, _ := cmd.Flags().GetString("work-dir")
+	keepTemp, _ := cmd.Flags().GetBool("keep-temp")
+
+	if _, err := os.Stat(input); os.IsNotExist(err) {
+		return inputErrorf("file not found: %s", input)
+	}
+	if !audio.IsVideoFile(input) {
+		return inputErrorf("unsupported file type: %s (expected a video file)", filepath.Ext(input))
+	}
+	targetLang = strings.TrimSpace(targetLang)
+	if targetLang == "" {
+		return inputErrorf("target-language must not be empty")
+	}
+	if subtitlesPath != "" {
+		if _, err := os.Stat(subtitlesPath); os.IsNotExist(err) {
+			return inputErrorf("subtitle file not found: %s", subtitlesPath)
+		}
+	}
+	if ttsConcurrency <= 0 {
+		return inputErrorf("tts-concurrency must be positive, got %d", ttsConcurrency)
+	}
+	if maxSpeedup < 1 {
+		return inputErrorf("max-speedup must be at least 1, got %g", maxSpeedup)
+	}
+	if dubOutput == "" {
+		dubOutput = strings.TrimSuffix(input, filepath.Ext(input)) +
+			".dubbed." + video.ShortLanguageCode(targetLang) + filepath.Ext(input)
+	}
+
+	requests, err := newRequestSettings(cmd)
+	if err != nil {
+		return err
+	}
+	// fail on a missing key before spending a transcription on it
+	synth, err := newSynthesizer(ctx, cmd, ttsProvider, ttsKey, tts.Options{
+		Model:        ttsModel,
+		Voice:        voice,
+		Instructions: voiceStyle,
+		Retry:        requests.retryPolicy(),
+		RateLimit:    requests.rateLimit,
+	})
+	if err != nil {
+		return err
+	}
+
+	if subtitlesPath == "" {
+		if subtitlesPath, err = dubSubtitles(ctx, cmd, input, targetLang, translateProvider, translateModel); err != nil {
+			return err
+		}
+	}
+	file, err := subtitle.Open(subtitlesPath)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, fmt.Errorf("failed to parse subtitle file: %w", err))
+	}
+	var lines []dubLine
+	for _, entry := range file.Subtitle().Entries {
+		if text := dubbing.SpeechText(entry.Text); text != "" {
+			lines = append(lines, dubLine{index: len(lines), entry: entry, text: text})
+		}
+	}
+	if len(lines) == 0 {
+		return inputErrorf("subtitle file has no text to speak: %s", subtitlesPath)
+	}
+	length, err := audio.GetDuration(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to read video duration: %w", err)
+	}
+
+	tempDir, cleanup, err := newWorkDir(workDir, keepTemp)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	display := startProgress()
+	defer display.Close()
+
+	logger.Infow("Synthesizing speech",
+		"cues", len(lines),
+		"provider", ttsProvider,
+		"concurrency", ttsConcurrency,
+	)
+	display.Stage("Synthesizing speech", len(lines))
+	results, err := pool.Run(ctx, lines, ttsConcurrency, func(ctx context.Context, line dubLine) (dubbing.TrackClip, error) {
+		speech, err := synth.Synthesize(ctx, line.text)
+		if err != nil {
+			return dubbing.TrackClip{}, fmt.Errorf("failed to synthesize cue %d: %w", line.index+1, err)
+		}
+		path := filepath.Join(tempDir, fmt.Sprintf("cue-%05d%s", line.index+1, speech.Ext))
+		if err := os.WriteFile(path, speech.Data, 0644); err != nil {
+			return dubbing.TrackClip{}, fmt.Errorf("failed to save speech: %w", err)
+		}
+		display.Add(1)
+		return dubbing.TrackClip{Path: path, Start: line.entry.StartTime}, nil
+	})
+	if err != nil {
+		return err
+	}
+	clips := make([]dubbing.TrackClip, len(results))
+	for i, result := range results {
+		clips[i] = result.Value
+	}
+
+	logger.Infow("Fitting speech to the cues", "max_speedup", maxSpeedup)
+	display.Stage("Fitting speech", len(clips))
+	trackPath := filepath.Join(tempDir, "dub.wav")
+	stats, err := dubbing.WriteTrack(ctx, clips, length, trackPath, dubbing.TrackOptions{
+		MaxSpeedup: maxSpeedup,
+		OnClip:     func() { display.Add(1) },
+	})
+	if err != nil {
+		return err
+	}
+	if stats.Trimmed > 0 {
+		logger.Warnw("Some speech was too long for its cue and was cut",
+			"trimmed", stats.Trimmed,
+			"max_speedup", maxSpeedup,
+		)
+	}
+
+	logger.Infow("Muxing dub track", "output", dubOutput, "replace_audio", replaceAudio)
+	display.Stage("Muxing dub track", 0)
+	processor := video.NewProcessor(tempDir)
+	if err := processor.MuxAudio(ctx, input, dubOutput, video.AudioTrack{
+		Path:     trackPath,
+		Language: targetLang,
+		Title:    "Dub (" + targetLang + ")",
+		Replace:  replaceAudio,
+	}); err != nil {
+		return fmt.Errorf("failed to mux dub track: %w", err)
+	}
+	display.Done()
+
+	result := dubReport{
+		Output:    absPath(dubOutput),
+		Subtitles: absPath(subtitlesPath),
+		Language:  targetLang,
+		Cues:      len(clips),
+		SpedUp:    stats.SpedUp,
+		Trimmed:   stats.Trimmed,
+	}
+	report(result, func() {
+		fmt.Printf("Video dubbed: %s\n", result.Output)
+		fmt.Printf("  Subtitles voiced: %s\n", result.Subtitles)
+		fmt.Printf("  Cues: %d (%d sped up, %d cut)\n", result.Cues, result.SpedUp, result.Trimmed)
+	})
+	return nil
+}
+
+// a cue to speak
+type dubLine struct {
+	index int
+	entry subtitle.Entry
+	text  string
+}
+
+// a speech client for provider, with apiKey, the transcription key on the
+// same provider, or the provider's configured key
+func newSynthesizer(
+	ctx context.Context,
+	cmd *cobra.Command,
+	provider, apiKey string,
+	opts tts.Options,
+) (tts.Synthesizer, error) {
+	envVar, ok := speechKeyEnv[provider]
+	if !ok {
+		return nil, inputErrorf(
+			"unsupported speech provider %q: use %s", provider, strings.Join(tts.Providers(), ", "),
+		)
+	}
+	if err := refuseOfflineProvider(provider); err != nil {
+		return nil, err
+	}
+	if transcribeProvider, _ := cmd.Flags().GetString("provider"); apiKey == "" && transcribeProvider == provider {
+		apiKey, _ = cmd.Flags().GetString("api-key")
+	}
+	if apiKey == "" {
+		apiKey = lookupAPIKey(provider, envVar)
+	}
+	if apiKey == "" {
+		return nil, missingAPIKeyError(envVar)
+	}
+	return tts.New(ctx, provider, apiKey, opts)
+}
+
+// the translated subtitles to voice: an existing translation next to the
+// video's subtitles, or a new one of subtitles reused or generated
+func dubSubtitles(
+	ctx context.Context,
+	cmd *cobra.Command,
+	input, targetLang, provider, model string,
+) (string, error) {
+	cfg, err := newGenerateConfig(cmd)
+	if err != nil {
+		return "", err
+	}
+	tcfg, err := newFollowUpTranslateConfig(cfg, targetLang, provider, model, false)
+	if err != nil {
+		return "", err
+	}
+
+	subtitlePath, err := summarySubtitles(ctx, cmd, input)
+	if err != nil {
+		return "", err
+	}
+	translatedPath := tcfg.outputPathFor(subtitlePath, "")
+	if _, err := os.Stat(translatedPath); err == nil {
+		logger.Infow("Reusing existing translation", "subtitles", translatedPath)
+		return translatedPath, nil
+	}
+
+	tcfg.progress = startProgress()
+	defer tcfg.progress.Close()
+	result, err := translateSubtitles(ctx, tcfg, subtitlePath, translatedPath, logger)
+	if err != nil {
+		return "", err
+	}
+	return result.Output, nil
+}
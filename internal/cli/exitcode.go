@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+// process exit codes, documented in the README
+const (
+	exitOK          = 0
+	exitFailure     = 1
+	exitInput       = 2
+	exitAuth        = 3
+	exitRateLimit   = 4
+	exitPartial     = 5
+	exitFFmpeg      = 6
+	exitInterrupted = 130
+)
+
+// ExitCode maps an error returned by Execute to the process exit code
+func ExitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	switch errorKind(err) {
+	case errs.KindInput:
+		return exitInput
+	case errs.KindAuth:
+		return exitAuth
+	case errs.KindRateLimit:
+		return exitRateLimit
+	case errs.KindPartial:
+		return exitPartial
+	case errs.KindFFmpeg:
+		return exitFFmpeg
+	case errs.KindInterrupted:
+		return exitInterrupted
+	default:
+		return exitFailure
+	}
+}
+
+// kind of err, falling back to the HTTP status of provider SDK errors that
+// were not classified where they occurred
+func errorKind(err error) errs.Kind {
+	return errs.KindOf(errs.Classify(err))
+}
+
+// input validation error, reported with exit code 2
+func inputErrorf(format string, args ...any) error {
+	return errs.Wrap(errs.KindInput, fmt.Errorf(format, args...))
+}
+
+// error for a provider without a configured API key, reported with exit
+// code 3
+func missingAPIKeyError(envVar string) error {
+	return errs.Wrap(errs.KindAuth, fmt.Errorf(
+		"API key is required: use --api-key flag, set %s environment variable, add it under api_keys in the config file, or store it with \"lipi auth set\"",
+		envVar,
+	))
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/openai/openai-go"
+	"google.golang.org/genai"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, exitOK},
+		{"untagged", errors.New("boom"), exitFailure},
+		{"input", inputErrorf("file not found: %s", "x.mp4"), exitInput},
+		{"missing key", missingAPIKeyError("GEMINI_API_KEY"), exitAuth},
+		{
+			"wrapped ffmpeg",
+			fmt.Errorf("failed to extract audio: %w", errs.Wrap(errs.KindFFmpeg, errors.New("exit status 1"))),
+			exitFFmpeg,
+		},
+		{"partial", errs.Wrap(errs.KindPartial, errors.New("1 of 3 files failed")), exitPartial},
+		{"cancelled", fmt.Errorf("transcription failed: %w", context.Canceled), exitInterrupted},
+		{
+			"gemini rejected key",
+			fmt.Errorf("chunk 0: %w", genai.APIError{Code: http.StatusForbidden}),
+			exitAuth,
+		},
+		{
+			"gemini rate limit",
+			fmt.Errorf("chunk 0: %w", genai.APIError{Code: http.StatusTooManyRequests}),
+			exitRateLimit,
+		},
+		{
+			"openai rate limit",
+			fmt.Errorf("batch 2: %w", &openai.Error{StatusCode: http.StatusTooManyRequests}),
+			exitRateLimit,
+		},
+		{
+			"provider server error",
+			fmt.Errorf("batch 2: %w", &openai.Error{StatusCode: http.StatusInternalServerError}),
+			exitFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/nle"
+	"github.com/mgpai22/lipi/internal/source"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [subtitle_file]",
+	Short: "Export subtitles as Audacity labels or Premiere and Final Cut markers",
+	Long: `Convert a subtitle file into a file an audio or video editor can import,
+so the transcript lands on the editing timeline:
+
+  audacity  label track (File > Import > Labels), video.labels.txt
+  premiere  marker list in Premiere Pro's marker CSV layout, video.markers.csv
+  fcpxml    Final Cut Pro XML project with a marker per cue, video.fcpxml
+
+Premiere and Final Cut timecodes are counted in frames at --fps; use the
+frame rate of the sequence the markers go into. Timecodes are non-drop-frame.
+
+Pass "-" to read subtitles from stdin, and "-o -" to write to stdout.
+
+Examples:
+  lipi export interview.srt --to audacity
+  lipi export interview.srt --to premiere --fps 29.97
+  lipi export interview.srt --to fcpxml --fps 24 -o markers.fcpxml`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSubtitleFiles,
+	RunE:              runExport,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().
+		String("to", "", "Editor format to export (audacity, premiere, fcpxml)")
+	exportCmd.Flags().
+		String("fps", "25", "Timeline frame rate for premiere and fcpxml, e.g. 24, 25, 29.97, 30000/1001")
+	exportCmd.Flags().
+		String("title", "", "Project and event name in fcpxml (default: the subtitle file name)")
+
+	mustRegisterCompletion(exportCmd, "to", completeValues("audacity", "premiere", "fcpxml"))
+	mustRegisterCompletion(exportCmd, "fps", completeValues("23.976", "24", "25", "29.97", "30", "50", "59.94", "60"))
+}
+
+// export as reported by --json
+type exportReport struct {
+	Output  string `json:"output"`
+	Format  string `json:"format"`
+	Entries int    `json:"entries"`
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	subtitlePath := args[0]
+	to, _ := cmd.Flags().GetString("to")
+	fps, _ := cmd.Flags().GetString("fps")
+	title, _ := cmd.Flags().GetString("title")
+	outputPath, _ := cmd.Flags().GetString("output")
+
+	if to == "" {
+		return inputErrorf("--to is required: audacity, premiere, or fcpxml")
+	}
+	format, err := nle.ParseFormat(to)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, err)
+	}
+	rate, err := nle.ParseRate(fps)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, err)
+	}
+
+	if subtitlePath != source.Stdin {
+		if _, err := os.Stat(subtitlePath); os.IsNotExist(err) {
+			return inputErrorf("subtitle file not found: %s", subtitlePath)
+		}
+	}
+	subFile, err := openSubtitles(subtitlePath)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, fmt.Errorf("failed to parse subtitle file: %w", err))
+	}
+	sub := subFile.Subtitle()
+	if len(sub.Entries) == 0 {
+		return inputErrorf("subtitle file contains no entries")
+	}
+
+	if outputPath == "" {
+		if subtitlePath == source.Stdin {
+			outputPath = source.Stdin
+		} else {
+			outputPath = exportOutputPath(subtitlePath, format)
+		}
+	}
+	if title == "" && subtitlePath != source.Stdin {
+		title = strings.TrimSuffix(filepath.Base(subtitlePath), filepath.Ext(subtitlePath))
+	}
+
+	var buf bytes.Buffer
+	if err := nle.Write(&buf, format, sub, nle.Options{Rate: rate, Title: title}); err != nil {
+		return fmt.Errorf("failed to export subtitles: %w", err)
+	}
+	if outputPath == source.Stdin {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	report(exportReport{
+		Output:  outputPath,
+		Format:  string(format),
+		Entries: len(sub.Entries),
+	}, func() {
+		fmt.Printf("Exported %d cues to %s\n", len(sub.Entries), outputPath)
+	})
+	return nil
+}
+
+// video.srt becomes video.labels.txt, video.markers.csv, or video.fcpxml
+func exportOutputPath(subtitlePath string, format nle.Format) string {
+	return strings.TrimSuffix(subtitlePath, filepath.Ext(subtitlePath)) + format.Extension()
+}
@@ -1,9 +1,7 @@
 package cli
 
 import (
-	"context"
 	"fmt"
-	"path/filepath"
 	"strings"
 
 	"github.com/mgpai22/lipi/internal/video"
@@ -15,27 +13,32 @@ var extractCmd = &cobra.Command{
 	Short: "Extract audio from a video file",
 	Long: `Extract the audio track from a video file and save it as a separate audio file.
 
-Supports multiple output formats: wav, mp3, aac, flac.
+Supports multiple output formats: wav, mp3, aac, opus, flac.
 
 Examples:
   lipi extract video.mp4
   lipi extract video.mp4 -o audio.mp3 -f mp3
   lipi extract video.mp4 --format wav --sample-rate 44100 --channels 2`,
-	Args: cobra.ExactArgs(1),
-	RunE: runExtract,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeMediaFile,
+	RunE:              runExtract,
 }
 
 func init() {
 	rootCmd.AddCommand(extractCmd)
 
 	extractCmd.Flags().
-		StringP("format", "f", "wav", "Output audio format (wav, mp3, aac, flac)")
+		StringP("format", "f", "wav", "Output audio format (wav, mp3, aac, opus, flac)")
 	extractCmd.Flags().
 		IntP("sample-rate", "r", 16000, "Sample rate in Hz (e.g., 16000, 44100, 48000)")
 	extractCmd.Flags().
 		IntP("channels", "c", 1, "Number of audio channels (1=mono, 2=stereo)")
 	extractCmd.Flags().
 		StringP("bitrate", "b", "", "Bitrate for lossy formats (e.g., 128k, 320k)")
+	extractCmd.Flags().
+		Bool("ignore-decode-errors", false, "Skip corrupt packets and decode errors in a damaged video instead of failing")
+
+	mustRegisterCompletion(extractCmd, "format", completeValues("wav", "mp3", "aac", "opus", "flac"))
 }
 
 func runExtract(cmd *cobra.Command, args []string) error {
@@ -45,6 +48,7 @@ func runExtract(cmd *cobra.Command, args []string) error {
 	sampleRate, _ := cmd.Flags().GetInt("sample-rate")
 	channels, _ := cmd.Flags().GetInt("channels")
 	bitrate, _ := cmd.Flags().GetString("bitrate")
+	ignoreDecodeErrors, _ := cmd.Flags().GetBool("ignore-decode-errors")
 	outputPath, _ := cmd.Flags().GetString("output")
 
 	if outputPath == "" {
@@ -56,11 +60,12 @@ func runExtract(cmd *cobra.Command, args []string) error {
 		"wav":  true,
 		"mp3":  true,
 		"aac":  true,
+		"opus": true,
 		"flac": true,
 	}
 	if !validFormats[format] {
-		return fmt.Errorf(
-			"invalid format %q: supported formats are wav, mp3, aac, flac",
+		return inputErrorf(
+			"invalid format %q: supported formats are wav, mp3, aac, opus, flac",
 			format,
 		)
 	}
@@ -80,9 +85,11 @@ func runExtract(cmd *cobra.Command, args []string) error {
 		SampleRate: sampleRate,
 		Channels:   channels,
 		Bitrate:    bitrate,
+
+		IgnoreDecodeErrors: ignoreDecodeErrors,
 	}
 
-	ctx := context.Background()
+	ctx := cmd.Context()
 	if err := processor.ExtractAudio(
 		ctx,
 		videoPath,
@@ -92,8 +99,9 @@ func runExtract(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("extraction failed: %w", err)
 	}
 
-	absOutput, _ := filepath.Abs(outputPath)
-	fmt.Printf("Audio extracted successfully: %s\n", absOutput)
+	report(map[string]string{"output": absPath(outputPath)}, func() {
+		fmt.Printf("Audio extracted successfully: %s\n", absPath(outputPath))
+	})
 
 	return nil
 }
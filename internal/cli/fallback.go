@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/transcribe"
+	"github.com/mgpai22/lipi/internal/translate"
+)
+
+// splits a --model-fallback entry, "model" on the primary provider or
+// "provider:model"
+func splitModelFallback(entry, provider string) (string, string, error) {
+	model := strings.TrimSpace(entry)
+	if p, m, ok := strings.Cut(model, ":"); ok {
+		provider, model = strings.TrimSpace(p), strings.TrimSpace(m)
+	}
+	if provider == "" || model == "" {
+		return "", "", inputErrorf("invalid --model-fallback %q: use model or provider:model", entry)
+	}
+	return provider, model, nil
+}
+
+// API key of a fallback provider: the run's key on its own provider, the
+// stored or environment key on others
+func fallbackAPIKey(provider, primary, apiKey string) (string, error) {
+	if provider == primary {
+		return apiKey, nil
+	}
+	if err := refuseOfflineProvider(provider); err != nil {
+		return "", err
+	}
+	envVar := storedKeyEnv()[provider]
+	if key := lookupAPIKey(provider, envVar); key != "" {
+		return key, nil
+	}
+	return "", missingAPIKeyError(envVar)
+}
+
+// the models a chunk that keeps failing moves to, checked like the
+// primary model and against the settings only some providers take
+func transcriptionFallbacks(
+	entries []string,
+	cfg *generateConfig,
+	modelOverride bool,
+) ([]transcribe.FallbackModel, error) {
+	var models []transcribe.FallbackModel
+	for _, entry := range entries {
+		p, model, err := splitModelFallback(entry, string(cfg.provider))
+		if err != nil {
+			return nil, err
+		}
+		provider := transcribe.Provider(p)
+		if err := checkTranscriptionModel(provider, model, cfg.transcriptLang, modelOverride); err != nil {
+			return nil, err
+		}
+		if err := cfg.decoding.check(p, transcriptionDecodingLimits(provider)); err != nil {
+			return nil, err
+		}
+		if (cfg.diarize || cfg.multilingual) && provider != transcribe.ProviderGemini {
+			return nil, inputErrorf(
+				"--diarize and --multilingual require the gemini provider, got fallback %s", entry,
+			)
+		}
+		apiKey, err := fallbackAPIKey(p, string(cfg.provider), cfg.apiKey)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, transcribe.FallbackModel{Provider: provider, Model: model, APIKey: apiKey})
+	}
+	return models, nil
+}
+
+// the models a batch that keeps failing moves to, checked like the
+// primary model
+func translationFallbacks(c *translateConfig) ([]translate.FallbackModel, error) {
+	var models []translate.FallbackModel
+	for _, entry := range c.modelFallback {
+		p, model, err := splitModelFallback(entry, string(c.provider))
+		if err != nil {
+			return nil, err
+		}
+		provider := translate.Provider(p)
+		if _, ok := providerKeyEnv[p]; !ok {
+			return nil, inputErrorf(
+				"unsupported fallback provider %q: use gemini, openai, or anthropic", p,
+			)
+		}
+		if !c.modelOverride {
+			if err := checkTranslationModel(provider, model); err != nil {
+				return nil, err
+			}
+		}
+		if err := c.decoding.check(p, translateDecodingLimits(provider)); err != nil {
+			return nil, err
+		}
+		apiKey, err := fallbackAPIKey(p, string(c.provider), c.apiKey)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, translate.FallbackModel{Provider: provider, Model: model, APIKey: apiKey})
+	}
+	return models, nil
+}
@@ -0,0 +1,27 @@
+package cli
+
+import "testing"
+
+func TestSplitModelFallback(t *testing.T) {
+	tests := []struct {
+		entry        string
+		wantProvider string
+		wantModel    string
+		wantErr      bool
+	}{
+		{"gemini-2.5-flash", "gemini", "gemini-2.5-flash", false},
+		{" openai:gpt-5-mini ", "openai", "gpt-5-mini", false},
+		{"anthropic: claude-haiku-4-5", "anthropic", "claude-haiku-4-5", false},
+		{"openai:", "", "", true},
+		{":gpt-5", "", "", true},
+		{"", "", "", true},
+	}
+
+	for _, tt := range tests {
+		provider, model, err := splitModelFallback(tt.entry, "gemini")
+		if (err != nil) != tt.wantErr || provider != tt.wantProvider || model != tt.wantModel {
+			t.Errorf("splitModelFallback(%q) = %q, %q, %v, want %q, %q",
+				tt.entry, provider, model, err, tt.wantProvider, tt.wantModel)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/mgpai22/lipi/internal/ffmpeg"
+	"github.com/spf13/cobra"
+)
+
+var ffmpegCmd = &cobra.Command{
+	Use:   "ffmpeg",
+	Short: "Manage the ffmpeg binaries lipi uses",
+	Long: `Manage the ffmpeg and ffprobe binaries lipi uses.
+
+Lipi uses LIPI_FFMPEG_PATH and LIPI_FFPROBE_PATH when set, then ffmpeg on
+PATH, and otherwise a pinned build it downloads (or extracts, for bundled
+binaries) into its cache directory on first use.
+
+Examples:
+  lipi ffmpeg install          # pre-provision the cache, e.g. in a Docker build
+  lipi ffmpeg install --force  # re-download, or upgrade to a newer pinned version
+  lipi ffmpeg path
+  lipi ffmpeg verify
+  lipi ffmpeg remove`,
+}
+
+var ffmpegInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Download the pinned ffmpeg build into the cache",
+	Args:  cobra.NoArgs,
+	RunE:  runFFmpegInstall,
+}
+
+var ffmpegPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Show which ffmpeg and ffprobe lipi will use",
+	Args:  cobra.NoArgs,
+	RunE:  runFFmpegPath,
+}
+
+var ffmpegVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that ffmpeg and ffprobe run, installing them if needed",
+	Args:  cobra.NoArgs,
+	RunE:  runFFmpegVerify,
+}
+
+var ffmpegRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Delete the cached ffmpeg builds",
+	Args:  cobra.NoArgs,
+	RunE:  runFFmpegRemove,
+}
+
+func init() {
+	rootCmd.AddCommand(ffmpegCmd)
+	ffmpegCmd.AddCommand(ffmpegInstallCmd, ffmpegPathCmd, ffmpegVerifyCmd, ffmpegRemoveCmd)
+
+	ffmpegInstallCmd.Flags().
+		Bool("force", false, "Replace an existing install")
+}
+
+type ffmpegPaths struct {
+	FFmpeg    string `json:"ffmpeg"`
+	FFprobe   string `json:"ffprobe"`
+	Source    string `json:"source"`
+	Installed bool   `json:"installed"`
+}
+
+func runFFmpegInstall(cmd *cobra.Command, args []string) error {
+	force, _ := cmd.Flags().GetBool("force")
+
+	paths, err := ffmpeg.Install(force)
+	if err != nil {
+		return err
+	}
+	report(ffmpegPaths{
+		FFmpeg:    paths.FFmpeg,
+		FFprobe:   paths.FFprobe,
+		Source:    string(ffmpeg.SourceCache),
+		Installed: true,
+	}, func() {
+		fmt.Printf("Installed ffmpeg in %s\n", ffmpeg.InstallDir())
+	})
+	return nil
+}
+
+func runFFmpegPath(cmd *cobra.Command, args []string) error {
+	paths, source, ok := ffmpeg.Locate()
+	report(ffmpegPaths{
+		FFmpeg:    paths.FFmpeg,
+		FFprobe:   paths.FFprobe,
+		Source:    string(source),
+		Installed: ok,
+	}, func() {
+		fmt.Printf("ffmpeg:  %s\n", paths.FFmpeg)
+		fmt.Printf("ffprobe: %s\n", paths.FFprobe)
+		fmt.Printf("source:  %s\n", source)
+		if !ok {
+			fmt.Println("not installed yet: run `lipi ffmpeg install` or let the first run download it")
+		}
+	})
+	return nil
+}
+
+func runFFmpegVerify(cmd *cobra.Command, args []string) error {
+	paths, err := ffmpeg.Ensure()
+	if err != nil {
+		return err
+	}
+	ffmpegVersion, err := ffmpeg.Version(cmd.Context(), paths.FFmpeg)
+	if err != nil {
+		return err
+	}
+	ffprobeVersion, err := ffmpeg.Version(cmd.Context(), paths.FFprobe)
+	if err != nil {
+		return err
+	}
+	report(map[string]string{
+		"ffmpeg":  ffmpegVersion,
+		"ffprobe": ffprobeVersion,
+	}, func() {
+		fmt.Printf("ffmpeg:  %s\n", ffmpegVersion)
+		fmt.Printf("ffprobe: %s\n", ffprobeVersion)
+	})
+	return nil
+}
+
+func runFFmpegRemove(cmd *cobra.Command, args []string) error {
+	if err := ffmpeg.Remove(); err != nil {
+		return err
+	}
+	report(map[string]bool{"removed": true}, func() {
+		fmt.Println("Removed cached ffmpeg builds")
+	})
+	return nil
+}
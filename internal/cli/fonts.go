@@ -0,0 +1,275 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/fonts"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/video"
+	"github.com/spf13/cobra"
+)
+
+var fontsCmd = &cobra.Command{
+	Use:   "fonts",
+	Short: "Check the fonts styled subtitles need",
+}
+
+var fontsCheckCmd = &cobra.Command{
+	Use:   "check [subtitle_file]",
+	Short: "Check that the fonts an ASS file uses are available and cover its text",
+	Long: `Scan the styles and \fn override tags of an ASS file for the fonts it
+names, and check each one: whether it is attached to --video, installed on
+this system or in --fonts-dir, and whether it has glyphs for all the text
+drawn in it. A font that is missing, or lacks glyphs, shows up as boxes or a
+fallback font when the subtitles are burned in or played.
+
+With --download, an open-license Noto font covering the script of the text
+is downloaded for each such font into --download-dir (default: your user
+fonts directory), where ffmpeg and most players find it as a fallback.
+Fonts are downloaded whole, not subset, and must match their pinned
+SHA-256 checksums unless --insecure-skip-verify is given.
+
+The command fails when a font is left missing or short of glyphs.
+
+Examples:
+  lipi fonts check episode.ass
+  lipi fonts check episode.ass --video episode.mkv
+  lipi fonts check episode.ass --fonts-dir ./fonts --download`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSubtitleFiles,
+	RunE:              runFontsCheck,
+}
+
+func init() {
+	rootCmd.AddCommand(fontsCmd)
+	fontsCmd.AddCommand(fontsCheckCmd)
+
+	fontsCheckCmd.Flags().
+		String("video", "", "Also look in the fonts attached to this video (mkv)")
+	fontsCheckCmd.Flags().
+		StringSlice("fonts-dir", nil, "More directories to look for fonts in, before the system font directories")
+	fontsCheckCmd.Flags().
+		Bool("download", false, "Download open-license Noto replacements for fonts that are missing or lack glyphs")
+	fontsCheckCmd.Flags().
+		String("download-dir", "", "Directory to download replacement fonts into (default: the user fonts directory)")
+}
+
+// the check of one font, as reported by --json
+type fontCheck struct {
+	Font   string `json:"font"`
+	Status string `json:"status"` // attached, installed, or missing
+	Path   string `json:"path,omitempty"`
+	// characters of the dialogue drawn in the font
+	Characters int `json:"characters"`
+	// characters drawn in the font that it has no glyphs for
+	MissingGlyphs   string `json:"missing_glyphs,omitempty"`
+	Replacement     string `json:"replacement,omitempty"`
+	ReplacementPath string `json:"replacement_path,omitempty"`
+}
+
+const (
+	fontAttached  = "attached"
+	fontInstalled = "installed"
+	fontMissing   = "missing"
+)
+
+// reports whether the font as it is shows all the text drawn in it; a
+// font no line is drawn in never needs to
+func (c fontCheck) ok() bool {
+	return c.Characters == 0 || c.Status != fontMissing && c.MissingGlyphs == ""
+}
+
+// fonts check as reported by --json
+type fontsReport struct {
+	Fonts      []fontCheck `json:"fonts"`
+	Unresolved int         `json:"unresolved"`
+}
+
+func runFontsCheck(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	subtitlePath := args[0]
+	videoPath, _ := cmd.Flags().GetString("video")
+	fontsDirs, _ := cmd.Flags().GetStringSlice("fonts-dir")
+	download, _ := cmd.Flags().GetBool("download")
+	downloadDir, _ := cmd.Flags().GetString("download-dir")
+
+	if downloadDir != "" && !download {
+		return inputErrorf("--download-dir requires --download")
+	}
+	if downloadDir == "" {
+		downloadDir = fonts.UserDir()
+	}
+	if _, err := os.Stat(subtitlePath); os.IsNotExist(err) {
+		return inputErrorf("subtitle file not found: %s", subtitlePath)
+	}
+	f, err := subtitle.Open(subtitlePath)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, fmt.Errorf("failed to parse subtitle file: %w", err))
+	}
+	ass, ok := f.(*subtitle.ASSFile)
+	if !ok {
+		return inputErrorf("fonts check needs an ASS file, got %s", f.Format())
+	}
+
+	var attached []fonts.Font
+	if videoPath != "" {
+		dir, err := os.MkdirTemp("", "lipi-fonts-*")
+		if err != nil {
+			return fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(dir) }()
+		if err := video.NewProcessor(dir).ExtractAttachments(ctx, videoPath, dir); err != nil {
+			return fmt.Errorf("failed to read the fonts of %s: %w", videoPath, err)
+		}
+		attached = fonts.Scan([]string{dir})
+	}
+	installed := fonts.Scan(append(append([]string{}, fontsDirs...), fonts.SystemDirs()...))
+
+	checks := checkFonts(ass.FontUses(), attached, installed)
+	if download {
+		if err := downloadReplacements(ctx, ass.FontUses(), checks, downloadDir); err != nil {
+			return err
+		}
+	}
+
+	unresolved := 0
+	for _, c := range checks {
+		if !c.ok() && c.ReplacementPath == "" {
+			unresolved++
+		}
+	}
+	report(fontsReport{Fonts: checks, Unresolved: unresolved}, func() {
+		printFontChecks(checks)
+		if unresolved > 0 && !download {
+			fmt.Println("Run with --download to install open-license replacements")
+		}
+	})
+	if unresolved > 0 {
+		return fmt.Errorf("%d of %d fonts are missing or lack glyphs", unresolved, len(checks))
+	}
+	return nil
+}
+
+// checks each font used against the attached fonts, which players prefer,
+// and then the installed ones
+func checkFonts(uses []subtitle.FontUse, attached, installed []fonts.Font) []fontCheck {
+	checks := make([]fontCheck, len(uses))
+	for i, use := range uses {
+		c := fontCheck{
+			Font:       use.Font,
+			Status:     fontMissing,
+			Characters: len([]rune(strings.Join(strings.Fields(use.Text), ""))),
+		}
+		font, found := fonts.Find(attached, use.Font)
+		if found {
+			c.Status = fontAttached
+		} else if font, found = fonts.Find(installed, use.Font); found {
+			c.Status = fontInstalled
+		}
+		if found {
+			c.Path = font.Path
+			if charset, err := fonts.Coverage(font); err != nil {
+				logger.Warnw("Could not read the characters of a font", "font", use.Font, "error", err)
+			} else {
+				c.MissingGlyphs = string(charset.Missing(use.Text))
+			}
+		}
+		checks[i] = c
+	}
+	return checks
+}
+
+// downloads a replacement for each font that cannot show its text, for
+// the script of the characters it cannot show
+func downloadReplacements(ctx context.Context, uses []subtitle.FontUse, checks []fontCheck, dir string) error {
+	for i := range checks {
+		c := &checks[i]
+		if c.ok() {
+			continue
+		}
+		chars := []rune(c.MissingGlyphs)
+		if c.Status == fontMissing {
+			chars = []rune(uses[i].Text)
+		}
+		r := fonts.ReplacementFor(chars)
+		logger.Infow("Downloading replacement font", "font", c.Font, "replacement", r.Family)
+		path, err := fonts.Download(ctx, r, dir)
+		if err != nil {
+			return err
+		}
+		c.Replacement = r.Family
+		c.ReplacementPath = path
+	}
+	return nil
+}
+
+func printFontChecks(checks []fontCheck) {
+	if len(checks) == 0 {
+		fmt.Println("The file names no fonts")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, c := range checks {
+		detail := c.Path
+		if c.Characters == 0 {
+			detail += "  (no text drawn in it)"
+		}
+		if c.MissingGlyphs != "" {
+			detail += fmt.Sprintf("  missing glyphs: %s", truncateRunes(c.MissingGlyphs, 20))
+		}
+		if c.Replacement != "" {
+			detail += fmt.Sprintf("  replacement: %s (%s)", c.Replacement, c.ReplacementPath)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", c.Font, c.Status, strings.TrimSpace(detail))
+	}
+	_ = w.Flush()
+}
+
+// the first n characters of s, with an ellipsis when there are more
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
+
+// the fonts the ASS files among paths use, as font files found in dirs or
+// the system font directories, and the names of those not found
+func assFontFiles(paths []string, dirs []string) (files, missing []string, err error) {
+	var names []string
+	for _, path := range paths {
+		if subtitle.GetFormatFromExtension(path) != subtitle.FormatASS {
+			continue
+		}
+		f, err := subtitle.Open(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read fonts of %s: %w", path, err)
+		}
+		if ass, ok := f.(*subtitle.ASSFile); ok {
+			names = append(names, ass.Fonts()...)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil, nil
+	}
+
+	installed := fonts.Scan(append(append([]string{}, dirs...), fonts.SystemDirs()...))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		font, ok := fonts.Find(installed, name)
+		switch {
+		case !ok:
+			missing = append(missing, name)
+		case !seen[font.Path]:
+			seen[font.Path] = true
+			files = append(files, font.Path)
+		}
+	}
+	return files, missing, nil
+}
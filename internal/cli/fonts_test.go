@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"io"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/fonts"
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+func TestCheckFonts(t *testing.T) {
+	prevLogger := logger
+	t.Cleanup(func() { logger = prevLogger })
+	logger = logging.NewLogger(false, io.Discard)
+
+	uses := []subtitle.FontUse{
+		{Font: "Noto Sans JP", Text: "こんにちは"},
+		{Font: "Unused Style Font"},
+		{Font: "Signs", Text: "Exit"},
+	}
+	// the font files are not read for names, so these only stand in for
+	// the fonts found
+	attached := []fonts.Font{{Path: "attached/Signs.ttf", Names: []string{"signs"}}}
+
+	checks := checkFonts(uses, attached, nil)
+	tests := []struct {
+		status     string
+		characters int
+		ok         bool
+	}{
+		{fontMissing, 5, false},
+		{fontMissing, 0, true},
+		{fontAttached, 4, true},
+	}
+	for i, tt := range tests {
+		c := checks[i]
+		// Signs.ttf does not exist, so its glyphs cannot be checked
+		if c.Status != tt.status || c.Characters != tt.characters || c.ok() != tt.ok {
+			t.Errorf("check of %s = %+v (ok %v), want %s with %d characters (ok %v)",
+				c.Font, c, c.ok(), tt.status, tt.characters, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/pipeline"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/video"
+)
+
+// indices of the entries a forced track keeps: those tagged with a
+// language other than the main one, which is mainLang when set and
+// otherwise the language spoken longest. main is empty when no entry is
+// tagged; untagged entries are left out.
+func forcedIndices(entries []subtitle.Entry, mainLang string) (indices []int, main string) {
+	main = languageKey(mainLang)
+	if main == "" {
+		main = longestLanguage(entries)
+	}
+	if main == "" {
+		return nil, ""
+	}
+	for i, entry := range entries {
+		if code := languageKey(entry.Language); code != "" && code != main {
+			indices = append(indices, i)
+		}
+	}
+	return indices, main
+}
+
+// language of the tagged entries with the most time on screen; ties go
+// to the language tagged first
+func longestLanguage(entries []subtitle.Entry) string {
+	var order []string
+	spoken := map[string]time.Duration{}
+	for _, entry := range entries {
+		code := languageKey(entry.Language)
+		if code == "" {
+			continue
+		}
+		if _, ok := spoken[code]; !ok {
+			order = append(order, code)
+		}
+		spoken[code] += entry.EndTime - entry.StartTime
+	}
+	longest := ""
+	for _, code := range order {
+		if longest == "" || spoken[code] > spoken[longest] {
+			longest = code
+		}
+	}
+	return longest
+}
+
+// a language tag or name as compared across entries: its ISO 639-2 code
+// when known, so "es" and "spanish" match, and the tag itself otherwise
+func languageKey(lang string) string {
+	if code := video.LanguageCode(lang); code != "" {
+		return code
+	}
+	return strings.ToLower(strings.TrimSpace(lang))
+}
+
+// runs generate, then keeps only the entries of a forced track
+func forcedNarrativeStage(generate pipeline.Stage, mainLang string) pipeline.Stage {
+	return pipeline.NewStage(pipeline.StageGenerate, func(ctx context.Context, s *pipeline.State) error {
+		if err := generate.Run(ctx, s); err != nil {
+			return err
+		}
+		entries := s.Subtitle.Entries
+		indices, main := forcedIndices(entries, mainLang)
+		kept := make([]subtitle.Entry, len(indices))
+		for i, index := range indices {
+			kept[i] = entries[index]
+			kept[i].Index = i + 1
+		}
+		logForcedEntries(s.Log, len(kept), len(entries), main)
+		s.Subtitle.Entries = kept
+		return nil
+	})
+}
+
+// keeps only the entries of a forced track in file
+func keepForcedEntries(file subtitle.File, mainLang string, log *logging.Logger) error {
+	entries := file.Subtitle().Entries
+	indices, main := forcedIndices(entries, mainLang)
+	if main == "" {
+		return inputErrorf("--forced-narrative needs entries tagged with their language, as generate --multilingual writes in VTT")
+	}
+	if err := file.Keep(indices); err != nil {
+		return fmt.Errorf("failed to keep forced entries: %w", err)
+	}
+	logForcedEntries(log, len(indices), len(entries), main)
+	return nil
+}
+
+func logForcedEntries(log *logging.Logger, kept, total int, main string) {
+	if kept == 0 {
+		log.Warnw("No entries are in a language other than the main one; the forced track is empty",
+			"main_language", main,
+		)
+		return
+	}
+	log.Infow("Keeping entries in other languages for the forced track",
+		"entries", kept,
+		"dropped", total-kept,
+		"main_language", main,
+	)
+}
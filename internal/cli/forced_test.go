@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+func TestForcedIndices(t *testing.T) {
+	entry := func(lang string, seconds int) subtitle.Entry {
+		return subtitle.Entry{EndTime: time.Duration(seconds) * time.Second, Language: lang}
+	}
+	tests := []struct {
+		name     string
+		entries  []subtitle.Entry
+		mainLang string
+		want     []int
+		wantMain string
+	}{
+		{
+			name:     "longest language is main",
+			entries:  []subtitle.Entry{entry("en", 5), entry("tlh", 2), entry("en", 4), entry("", 1), entry("es", 1)},
+			want:     []int{1, 4},
+			wantMain: "eng",
+		},
+		{
+			name:     "main language given by name",
+			entries:  []subtitle.Entry{entry("en", 1), entry("es", 9)},
+			mainLang: "english",
+			want:     []int{1},
+			wantMain: "eng",
+		},
+		{
+			name:     "unknown codes compare as written",
+			entries:  []subtitle.Entry{entry("xx", 1), entry("YY", 3), entry("yy", 1)},
+			want:     []int{0},
+			wantMain: "yy",
+		},
+		{
+			name:    "no tags",
+			entries: []subtitle.Entry{entry("", 1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, main := forcedIndices(tt.entries, tt.mainLang)
+			if !slices.Equal(got, tt.want) || main != tt.wantMain {
+				t.Errorf("forcedIndices() = %v, %q, want %v, %q", got, main, tt.want, tt.wantMain)
+			}
+		})
+	}
+}
@@ -6,12 +6,22 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/errs"
+	ffmpegbin "github.com/mgpai22/lipi/internal/ffmpeg"
+	"github.com/mgpai22/lipi/internal/glossary"
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/models"
+	"github.com/mgpai22/lipi/internal/pipeline"
+	"github.com/mgpai22/lipi/internal/progress"
+	"github.com/mgpai22/lipi/internal/review"
+	"github.com/mgpai22/lipi/internal/source"
 	"github.com/mgpai22/lipi/internal/subtitle"
 	"github.com/mgpai22/lipi/internal/transcribe"
-	"github.com/mgpai22/lipi/internal/video"
+	"github.com/mgpai22/lipi/internal/usage"
 	"github.com/spf13/cobra"
 )
 
@@ -22,6 +32,9 @@ var generateCmd = &cobra.Command{
 
 The command accepts both audio files (mp3, wav, aac, etc.) and video files (mp4, mkv, etc.).
 For video files, audio is automatically extracted before transcription.
+The input may also be an http(s) URL, or "-" to read media from stdin.
+URLs for YouTube, Vimeo, and similar sites are fetched with yt-dlp, which is
+used from $PATH (or LIPI_YTDLP_PATH) and downloaded automatically if missing.
 
 The audio is split into chunks (default 1 minute) and transcribed in parallel.
 Supports multiple providers: Gemini (default) and OpenAI.
@@ -32,9 +45,16 @@ Examples:
   lipi generate audio.mp3 --format vtt
   lipi generate video.mp4 --provider openai --model whisper-1
   lipi generate video.mp4 --api-key YOUR_KEY --chunk-duration 2
-  lipi generate podcast.mp3 -f srt -d 1 --concurrency 5`,
-	Args: cobra.ExactArgs(1),
-	RunE: runGenerate,
+  lipi generate podcast.mp3 -f srt -d 1 --concurrency 5
+  lipi generate anime.mkv --isolate-voice --separator demucs
+  lipi generate lecture.mp4 --chunk-format opus
+  lipi generate lecture.mp4 --timings --concurrency 8
+  lipi generate https://example.com/episode.mp3
+  lipi generate "https://www.youtube.com/watch?v=VIDEO_ID" --format srt
+  cat recording.m4a | lipi generate - --input-format m4a -o recording.srt`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeMediaFile,
+	RunE:              runGenerate,
 }
 
 func init() {
@@ -42,45 +62,337 @@ func init() {
 
 	generateCmd.Flags().
 		Bool("embed", false, "Embed subtitles directly into the video (not yet implemented)")
+	addGenerateFlags(generateCmd)
 	generateCmd.Flags().
+		Bool("timings", false, "After the run, show where each chunk's time went (upload, inference, parse, waiting) and the slowest chunks")
+	addOutputNamingFlags(generateCmd)
+	addPreviewFlag(generateCmd)
+	addNotifyFlags(generateCmd)
+}
+
+// registers the flags shared by generate and batch
+func addGenerateFlags(cmd *cobra.Command) {
+	cmd.Flags().
 		StringP("api-key", "k", "", "API key (or set GEMINI_API_KEY/OPENAI_API_KEY env var)")
-	generateCmd.Flags().
+	cmd.Flags().
 		IntP("chunk-duration", "d", 1, "Chunk duration in minutes for splitting audio")
-	generateCmd.Flags().
+	cmd.Flags().
 		StringP("format", "f", "srt", "Output subtitle format (srt, vtt, ass)")
-	generateCmd.Flags().
-		Int("concurrency", 3, "Number of parallel transcription workers")
-	generateCmd.Flags().
+	cmd.Flags().
+		String("concurrency", "auto", "Number of parallel transcription workers, or auto to size it from the chunk count and provider limits")
+	cmd.Flags().
 		String("model", "", "Model to use for transcription (provider-specific, uses sensible defaults)")
-	generateCmd.Flags().
+	cmd.Flags().
+		Bool("model-override", false, "Allow any custom model, bypassing provider model validation")
+	cmd.Flags().
+		StringSlice("model-fallback", nil, "Models a chunk moves to, in order, when it keeps failing on --model (model, or provider:model for another provider)")
+	cmd.Flags().
 		String("transcript-language", "native", "Output language for transcript (e.g., 'english', 'spanish', or 'native' for original language)")
-	generateCmd.Flags().
-		String("provider", "gemini", "Transcription provider (gemini, openai)")
+	cmd.Flags().
+		String("provider", "gemini", "Transcription provider (gemini, openai, groq)")
+	cmd.Flags().
+		Bool("isolate-voice", false, "Strip music/background with an external stem separator before transcription")
+	cmd.Flags().
+		String("separator", "demucs", "Voice separator (demucs, spleeter, or a command with {input} and {output} placeholders)")
+	cmd.Flags().
+		String("separator-path", "", "Binary of the voice separator (or set LIPI_SEPARATOR_PATH; default: found on PATH)")
+	cmd.Flags().
+		String("chunk-format", "mp3", "Audio codec for transcription chunks (mp3, opus, wav, aac)")
+	cmd.Flags().
+		Int64("max-input-size", source.DefaultMaxBytes>>20, "Maximum size in MB accepted from a URL or stdin")
+	cmd.Flags().
+		String("input-format", "", "Container extension for URL/stdin input when it cannot be detected (e.g. mp3, mkv)")
+	cmd.Flags().
+		Bool("yt-dlp", false, "Fetch URLs with yt-dlp even when the site is not auto-detected")
+	cmd.Flags().
+		String("work-dir", "", "Directory for intermediate audio, chunks, and provider responses (default: system temp)")
+	cmd.Flags().
+		Bool("keep-temp", false, "Keep intermediate files and raw provider responses after the run")
+	cmd.Flags().
+		Bool("skip-space-check", false, "Skip the free disk space check before processing")
+	cmd.Flags().
+		String("glossary", "", "File of names and terms to spell consistently (one per line)")
+	cmd.Flags().
+		String("prompt", "", "Additional instructions for the transcription model")
+	cmd.Flags().
+		String("prompt-file", "", "File with additional instructions for the transcription model (combined with --prompt)")
+	cmd.Flags().
+		Float64("temperature", 0, "Sampling temperature for transcription (gemini: 0-2, openai: 0-1; default: provider default)")
+	addDecodingFlags(cmd)
+	addSafetyFlag(cmd)
+	cmd.Flags().
+		Bool("diarize", false, "Label who is speaking in each entry, as voice tags in VTT and names in ASS (gemini only)")
+	cmd.Flags().
+		StringSlice("speakers", nil, "Names of the people speaking, for --diarize to label them by (comma-separated)")
+	cmd.Flags().
+		String("speaker-styles", "", "Give each --diarize speaker an ASS style: color for a color each, sides to also alternate them left and right")
+	cmd.Flags().
+		Bool("multilingual", false, "Tag the language of each entry, as language spans in VTT, so translation skips entries already in the target language (gemini only)")
+	cmd.Flags().
+		String("lyrics", "", "How sung content is transcribed: keep (verbatim), skip, or tag (between ♪ marks); default: up to the model")
+	cmd.Flags().
+		Bool("forced-narrative", false, "Keep only the entries spoken in a language other than the main one (--language, or the one spoken longest), as a forced track (needs --multilingual)")
+	cmd.Flags().
+		Int("max-line-length", 42, "Maximum characters per subtitle line")
+	cmd.Flags().
+		Int("max-lines", 2, "Maximum lines per subtitle entry")
+	cmd.Flags().
+		Duration("min-duration", time.Second, "Minimum time a subtitle entry stays on screen")
+	cmd.Flags().
+		Duration("max-duration", 7*time.Second, "Maximum time a subtitle entry stays on screen")
+	cmd.Flags().
+		Duration("stretch-into-silence", 0, "Let an entry too short to read stay on screen up to this much longer, into the silence after it (default: off)")
+	cmd.Flags().
+		String("sample", "", "Transcribe only an excerpt to try settings cheaply: the first 3m, or a window such as 10m-13m or 1:02:00-1:05:00")
+	cmd.Flags().
+		Bool("ignore-decode-errors", false, "Skip corrupt packets and decode errors in damaged media, such as crashed or truncated recordings, instead of failing")
+	cmd.Flags().
+		Bool("allow-silent", false, "Write an empty subtitle file for media without an audio stream instead of failing")
+	addRequestFlags(cmd)
+
+	registerGenerateCompletions(cmd)
+}
+
+// checks that provider is available and offers model, and that the model
+// can write the transcript language
+func checkTranscriptionModel(
+	provider transcribe.Provider,
+	model, transcriptLang string,
+	modelOverride bool,
+) error {
+	switch provider {
+	case transcribe.ProviderGemini:
+		if !modelOverride && !isValidGeminiModel(model) {
+			return inputErrorf(
+				"unsupported Gemini model %q: valid models are gemini-3-pro-preview, gemini-3-flash-preview, gemini-2.5-pro, gemini-2.5-flash, gemini-2.5-flash-lite (use --model-override to bypass)",
+				model,
+			)
+		}
+	case transcribe.ProviderOpenAI:
+		if !modelOverride && !isValidOpenAIAudioModel(model) {
+			return inputErrorf(
+				"unsupported OpenAI audio model %q: only whisper-1 is supported (use --model-override to bypass)",
+				model,
+			)
+		}
+		if !isValidOpenAITranscriptLanguage(transcriptLang) {
+			return inputErrorf(
+				"unsupported transcript language %q for OpenAI provider: OpenAI Whisper only supports translation to English; use --transcript-language english (or 'en') to translate, or 'native' to keep the original language",
+				transcriptLang,
+			)
+		}
+	case transcribe.ProviderGroq:
+		if !modelOverride && !isValidGroqAudioModel(model) {
+			return inputErrorf(
+				"unsupported Groq audio model %q: valid models are whisper-large-v3, whisper-large-v3-turbo (use --model-override to bypass)",
+				model,
+			)
+		}
+		if !isValidOpenAITranscriptLanguage(transcriptLang) {
+			return inputErrorf(
+				"unsupported transcript language %q for Groq provider: Whisper only supports translation to English; use --transcript-language english (or 'en') to translate, or 'native' to keep the original language",
+				transcriptLang,
+			)
+		}
+		if model == "whisper-large-v3-turbo" && isEnglishTranscript(transcriptLang) {
+			return inputErrorf(
+				"whisper-large-v3-turbo cannot translate to English: use --model whisper-large-v3",
+			)
+		}
+	default:
+		// providers registered by programs embedding lipi validate their
+		// own models; the built-in whisper backend is not implemented yet
+		if provider == transcribe.ProviderWhisper || !transcribe.Registered(provider) {
+			return inputErrorf(
+				"unsupported provider %q: use gemini, openai, or groq",
+				provider,
+			)
+		}
+	}
+	return nil
+}
+
+// what provider accepts for the decoding flags; Whisper takes only a
+// temperature, and at most 1
+func transcriptionDecodingLimits(provider transcribe.Provider) decodingLimits {
+	if provider == transcribe.ProviderOpenAI || provider == transcribe.ProviderGroq {
+		return decodingLimits{maxTemperature: 1}
+	}
+	return decodingLimits{maxTemperature: 2, topP: true, thinking: true}
+}
+
+// validated settings for one or more generate runs
+type generateConfig struct {
+	apiKey         string
+	provider       transcribe.Provider
+	model          string
+	modelOverride  bool
+	language       string
+	transcriptLang string
+	format         subtitle.Format
+	chunkDuration  time.Duration
+	concurrency    int // concurrencyAuto to size it per input
+	limiter        transcribe.Limiter
+	requests       requestSettings
+	isolateVoice   bool
+	separator      string
+	chunkFormat    string
+	maxInputBytes  int64
+	inputFormat    string
+	useYTDLP       bool
+	workDir        string
+	keepTemp       bool
+	skipSpaceCheck bool
+	allowSilent    bool // write empty subtitles for media without audio
+	glossary       glossary.Glossary
+	diarize        bool
+	speakers       []string
+	speakerStyles  subtitle.SpeakerStyles
+	multilingual   bool
+	forced         bool // keep only entries in other languages than the main one
+	lyrics         transcribe.Lyrics
+	prompt         string
+	decoding       decodingSettings
+	safety         map[string]string // Gemini block threshold by harm category
+	fallbacks      []transcribe.FallbackModel
+	output         outputNamer
+	generator      subtitle.DefaultGenerator
+	progress       *progress.Display
+	// when set, called as chunks are transcribed, with the chunks of the
+	// input done so far and in total
+	onChunk func(done, total int)
+	// decode damaged media past its errors
+	ignoreDecodeErrors bool
+	// excerpt of --sample to transcribe, the whole media when zero
+	sampleStart, sampleLength time.Duration
+	// record the phases of each chunk's requests
+	timings bool
+	// refuse URLs of private addresses, for jobs submitted to a server
+	publicURLsOnly bool
+	// binary of the voice separator, LIPI_SEPARATOR_PATH or $PATH when empty
+	separatorPath string
+}
+
+// outcome of generating subtitles for a single input
+type generateResult struct {
+	Output   string
+	Basename string // input name the output was named after
+	Entries  int
+	Duration time.Duration
+	Usage    usage.Usage // provider usage of this input alone
+	// phases of each chunk's requests, with --timings
+	Timings []transcribe.ChunkTiming
+}
+
+// generate result as reported by --json
+type generateReport struct {
+	Output          string         `json:"output"`
+	Entries         int            `json:"entries"`
+	DurationSeconds float64        `json:"duration_seconds"`
+	Timings         *timingsReport `json:"timings,omitempty"`
+}
+
+func newGenerateReport(result *generateResult) generateReport {
+	return generateReport{
+		Output:          absPath(result.Output),
+		Entries:         result.Entries,
+		DurationSeconds: result.Duration.Seconds(),
+		Timings:         newTimingsReport(result.Timings),
+	}
 }
 
 func runGenerate(cmd *cobra.Command, args []string) error {
 	mediaPath := args[0]
-	ctx := context.Background()
+	ctx := cmd.Context()
+
+	if !source.IsRemote(mediaPath) {
+		if _, err := os.Stat(mediaPath); os.IsNotExist(err) {
+			return inputErrorf("file not found: %s", mediaPath)
+		}
+		if !audio.IsMediaFile(mediaPath) {
+			return inputErrorf(
+				"unsupported file type: %s (expected audio or video file)",
+				filepath.Ext(mediaPath),
+			)
+		}
+	}
 
-	if _, err := os.Stat(mediaPath); os.IsNotExist(err) {
-		return fmt.Errorf("file not found: %s", mediaPath)
+	cfg, err := newGenerateConfig(cmd)
+	if err != nil {
+		return err
 	}
-	if !audio.IsMediaFile(mediaPath) {
-		return fmt.Errorf(
-			"unsupported file type: %s (expected audio or video file)",
-			filepath.Ext(mediaPath),
-		)
+
+	outputPath, _ := cmd.Flags().GetString("output")
+	preview, _ := cmd.Flags().GetInt("preview")
+	if preview < 0 {
+		return inputErrorf("preview must not be negative, got %d", preview)
+	}
+	cfg.progress = startProgress()
+	defer cfg.progress.Close()
+
+	result, err := generateSubtitles(ctx, cfg, mediaPath, outputPath, logger)
+	if err != nil {
+		return err
 	}
 
+	report(newGenerateReport(result), func() {
+		fmt.Printf("Subtitles generated successfully: %s\n", absPath(result.Output))
+		fmt.Printf("  Entries: %d\n", result.Entries)
+		fmt.Printf("  Duration: %s\n", result.Duration.String())
+		if cfg.sampleLength > 0 {
+			fmt.Printf("  Sample: %s to %s\n",
+				review.FormatTimestamp(cfg.sampleStart),
+				review.FormatTimestamp(cfg.sampleStart+cfg.sampleLength),
+			)
+		}
+		printTimings(os.Stdout, result.Timings)
+		printPreview(os.Stdout, result.Output, preview)
+	})
+
+	return nil
+}
+
+// reads and validates the generate flags on cmd
+func newGenerateConfig(cmd *cobra.Command) (*generateConfig, error) {
 	apiKey, _ := cmd.Flags().GetString("api-key")
 	chunkDuration, _ := cmd.Flags().GetInt("chunk-duration")
 	formatStr, _ := cmd.Flags().GetString("format")
-	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	concurrencyStr, _ := cmd.Flags().GetString("concurrency")
 	model, _ := cmd.Flags().GetString("model")
-	outputPath, _ := cmd.Flags().GetString("output")
+	modelOverride, _ := cmd.Flags().GetBool("model-override")
+	modelFallback, _ := cmd.Flags().GetStringSlice("model-fallback")
 	language, _ := cmd.Flags().GetString("language")
 	transcriptLang, _ := cmd.Flags().GetString("transcript-language")
 	providerStr, _ := cmd.Flags().GetString("provider")
+	isolateVoice, _ := cmd.Flags().GetBool("isolate-voice")
+	separator, _ := cmd.Flags().GetString("separator")
+	separatorPath, _ := cmd.Flags().GetString("separator-path")
+	chunkFormat, _ := cmd.Flags().GetString("chunk-format")
+	maxInputMB, _ := cmd.Flags().GetInt64("max-input-size")
+	inputFormat, _ := cmd.Flags().GetString("input-format")
+	useYTDLP, _ := cmd.Flags().GetBool("yt-dlp")
+	workDir, _ := cmd.Flags().GetString("work-dir")
+	keepTemp, _ := cmd.Flags().GetBool("keep-temp")
+	skipSpaceCheck, _ := cmd.Flags().GetBool("skip-space-check")
+	maxLineLength, _ := cmd.Flags().GetInt("max-line-length")
+	maxLines, _ := cmd.Flags().GetInt("max-lines")
+	minDuration, _ := cmd.Flags().GetDuration("min-duration")
+	maxDuration, _ := cmd.Flags().GetDuration("max-duration")
+	stretch, _ := cmd.Flags().GetDuration("stretch-into-silence")
+	glossaryPath, _ := cmd.Flags().GetString("glossary")
+	prompt, _ := cmd.Flags().GetString("prompt")
+	promptFile, _ := cmd.Flags().GetString("prompt-file")
+	diarize, _ := cmd.Flags().GetBool("diarize")
+	speakers, _ := cmd.Flags().GetStringSlice("speakers")
+	speakerStyles, _ := cmd.Flags().GetString("speaker-styles")
+	multilingual, _ := cmd.Flags().GetBool("multilingual")
+	forcedNarrative, _ := cmd.Flags().GetBool("forced-narrative")
+	lyrics, _ := cmd.Flags().GetString("lyrics")
+	allowSilent, _ := cmd.Flags().GetBool("allow-silent")
+	ignoreDecodeErrors, _ := cmd.Flags().GetBool("ignore-decode-errors")
+	sample, _ := cmd.Flags().GetString("sample")
+	// only generate registers --timings, so other commands read false
+	timings, _ := cmd.Flags().GetBool("timings")
 
 	provider := transcribe.Provider(providerStr)
 
@@ -90,74 +402,67 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 			model = "gemini-2.5-flash"
 		case transcribe.ProviderOpenAI:
 			model = "whisper-1"
+		case transcribe.ProviderGroq:
+			model = "whisper-large-v3"
 		}
 	}
 
-	switch provider {
-	case transcribe.ProviderGemini:
-		if !isValidGeminiModel(model) {
-			return fmt.Errorf(
-				"unsupported Gemini model %q: valid models are gemini-3-pro-preview, gemini-3-flash-preview, gemini-2.5-pro, gemini-2.5-flash, gemini-2.5-flash-lite",
-				model,
-			)
-		}
-	case transcribe.ProviderOpenAI:
-		if !isValidOpenAIAudioModel(model) {
-			return fmt.Errorf(
-				"unsupported OpenAI audio model %q: only whisper-1 is supported",
-				model,
-			)
-		}
-		if !isValidOpenAITranscriptLanguage(transcriptLang) {
-			return fmt.Errorf(
-				"unsupported transcript language %q for OpenAI provider: OpenAI Whisper only supports translation to English; use --transcript-language english (or 'en') to translate, or 'native' to keep the original language",
-				transcriptLang,
-			)
-		}
-	default:
-		return fmt.Errorf(
-			"unsupported provider %q: use gemini or openai",
-			providerStr,
-		)
+	if err := checkTranscriptionModel(provider, model, transcriptLang, modelOverride); err != nil {
+		return nil, err
+	}
+	if err := refuseOfflineProvider(string(provider)); err != nil {
+		return nil, err
 	}
 
 	if apiKey == "" {
 		switch provider {
 		case transcribe.ProviderGemini:
-			apiKey = os.Getenv("GEMINI_API_KEY")
+			apiKey = lookupAPIKey("gemini", "GEMINI_API_KEY")
 		case transcribe.ProviderOpenAI:
-			apiKey = os.Getenv("OPENAI_API_KEY")
+			apiKey = lookupAPIKey("openai", "OPENAI_API_KEY")
+		case transcribe.ProviderGroq:
+			apiKey = lookupAPIKey("groq", "GROQ_API_KEY")
 		}
 	}
-	if apiKey == "" {
+	// the mock provider replays fixtures and needs no key
+	if apiKey == "" && provider != transcribe.ProviderMock {
 		var envVar string
 		switch provider {
 		case transcribe.ProviderGemini:
 			envVar = "GEMINI_API_KEY"
 		case transcribe.ProviderOpenAI:
 			envVar = "OPENAI_API_KEY"
+		case transcribe.ProviderGroq:
+			envVar = "GROQ_API_KEY"
 		default:
 			envVar = "API_KEY"
 		}
-		return fmt.Errorf(
-			"API key is required: use --api-key flag or set %s environment variable",
-			envVar,
-		)
+		return nil, missingAPIKeyError(envVar)
 	}
 
 	if chunkDuration <= 0 {
-		return fmt.Errorf(
+		return nil, inputErrorf(
 			"chunk duration must be positive, got %d",
 			chunkDuration,
 		)
 	}
-	if concurrency <= 0 {
-		return fmt.Errorf(
-			"concurrency must be positive, got %d",
-			concurrency,
+	concurrency, err := parseConcurrency(concurrencyStr)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkFormat = strings.ToLower(chunkFormat)
+	if !audio.IsValidCompressionFormat(chunkFormat) {
+		return nil, inputErrorf(
+			"unsupported chunk format %q: use mp3, opus, wav, or aac",
+			chunkFormat,
 		)
 	}
 
+	if err := source.CheckFormat(inputFormat); err != nil {
+		return nil, errs.Wrap(errs.KindInput, err)
+	}
+
 	var format subtitle.Format
 	switch strings.ToLower(formatStr) {
 	case "srt":
@@ -167,177 +472,567 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	case "ass":
 		format = subtitle.FormatASS
 	default:
-		return fmt.Errorf(
+		return nil, inputErrorf(
 			"unsupported format %q: use srt, vtt, or ass",
 			formatStr,
 		)
 	}
 
-	if outputPath == "" {
-		baseName := strings.TrimSuffix(mediaPath, filepath.Ext(mediaPath))
-		outputPath = baseName + subtitle.GetExtensionForFormat(format)
+	if maxInputMB < 0 {
+		return nil, inputErrorf(
+			"max input size must not be negative, got %d",
+			maxInputMB,
+		)
 	}
 
-	logger.Infow("Starting subtitle generation",
-		"input", mediaPath,
-		"output", outputPath,
-		"format", formatStr,
-		"chunk_duration", chunkDuration,
-		"concurrency", concurrency,
-	)
+	if maxLineLength <= 0 || maxLines <= 0 {
+		return nil, inputErrorf(
+			"max line length and max lines must be positive, got %d and %d",
+			maxLineLength,
+			maxLines,
+		)
+	}
+	if minDuration <= 0 || maxDuration < minDuration {
+		return nil, inputErrorf(
+			"invalid entry durations: min %s, max %s",
+			minDuration,
+			maxDuration,
+		)
+	}
+	if stretch < 0 {
+		return nil, inputErrorf("stretch into silence must not be negative, got %s", stretch)
+	}
+	var sampleStart, sampleLength time.Duration
+	if sample != "" {
+		if sampleStart, sampleLength, err = parseSample(sample); err != nil {
+			return nil, inputErrorf("invalid --sample: %v", err)
+		}
+	}
 
-	tempDir, err := os.MkdirTemp("", "lipi-*")
+	decoding, err := newDecodingSettings(cmd, string(provider), transcriptionDecodingLimits(provider))
 	if err != nil {
-		return fmt.Errorf("failed to create temp directory: %w", err)
+		return nil, err
+	}
+	safety, err := newSafetyThresholds(cmd, string(provider))
+	if err != nil {
+		return nil, err
 	}
-	defer func() {
-		_ = os.RemoveAll(tempDir)
-	}()
-
-	var audioPath string
-	compressionOpts := audio.DefaultCompressionOptions()
-
-	if audio.IsVideoFile(mediaPath) {
-		logger.Infow("Extracting audio from video")
-		audioPath = filepath.Join(tempDir, "audio.mp3")
 
-		processor := video.NewProcessor(tempDir)
-		extractOpts := video.ExtractAudioOptions{
-			Format:     compressionOpts.Format,
-			SampleRate: compressionOpts.SampleRate,
-			Channels:   compressionOpts.Channels,
-			Bitrate:    compressionOpts.Bitrate,
+	if diarize && provider != transcribe.ProviderGemini {
+		return nil, inputErrorf("--diarize requires the gemini provider, got %s", provider)
+	}
+	if len(speakers) > 0 && !diarize {
+		return nil, inputErrorf("--speakers requires --diarize")
+	}
+	switch subtitle.SpeakerStyles(speakerStyles) {
+	case "":
+	case subtitle.SpeakerStylesColor, subtitle.SpeakerStylesSides:
+		if !diarize {
+			return nil, inputErrorf("--speaker-styles requires --diarize")
 		}
+		if format != subtitle.FormatASS {
+			return nil, inputErrorf("--speaker-styles requires --format ass, got %s", format)
+		}
+	default:
+		return nil, inputErrorf(
+			"unsupported speaker styles %q: use %s or %s",
+			speakerStyles,
+			subtitle.SpeakerStylesColor,
+			subtitle.SpeakerStylesSides,
+		)
+	}
+	if multilingual && provider != transcribe.ProviderGemini {
+		return nil, inputErrorf("--multilingual requires the gemini provider, got %s", provider)
+	}
+	if multilingual && transcriptLang != "native" {
+		return nil, inputErrorf("--multilingual tags the languages spoken, so it requires --transcript-language native")
+	}
+	switch transcribe.Lyrics(lyrics) {
+	case "", transcribe.LyricsKeep, transcribe.LyricsSkip, transcribe.LyricsTag:
+	default:
+		return nil, inputErrorf(
+			"unsupported lyrics policy %q: use %s, %s, or %s",
+			lyrics,
+			transcribe.LyricsKeep,
+			transcribe.LyricsSkip,
+			transcribe.LyricsTag,
+		)
+	}
+	if forcedNarrative && !multilingual {
+		return nil, inputErrorf("--forced-narrative picks entries by their language, so it requires --multilingual")
+	}
 
-		if err := processor.ExtractAudio(
-			ctx,
-			mediaPath,
-			audioPath,
-			extractOpts,
-		); err != nil {
-			return fmt.Errorf("failed to extract audio: %w", err)
+	if promptFile != "" {
+		data, err := os.ReadFile(expandHome(promptFile))
+		if err != nil {
+			return nil, errs.Wrap(
+				errs.KindInput,
+				fmt.Errorf("failed to read prompt file: %w", err),
+			)
 		}
-	} else {
-		logger.Infow("Compressing audio for transcription")
-		audioPath = filepath.Join(tempDir, "audio.mp3")
-
-		if err := audio.CompressAudio(
-			ctx,
-			mediaPath,
-			audioPath,
-			compressionOpts,
-		); err != nil {
-			return fmt.Errorf("failed to compress audio: %w", err)
+		// the file holds the standing instructions, --prompt adds to them
+		prompt = strings.TrimSpace(strings.TrimSpace(string(data)) + "\n" + prompt)
+	}
+
+	var terms glossary.Glossary
+	if glossaryPath != "" {
+		var err error
+		if terms, err = glossary.Load(expandHome(glossaryPath)); err != nil {
+			return nil, err
 		}
 	}
 
-	duration, err := audio.GetDuration(audioPath)
+	output, err := newOutputNamer(cmd)
+	if err != nil {
+		return nil, err
+	}
+	// a forced track is flagged in templated names and the sidecar
+	output.forced = output.forced || forcedNarrative
+	requests, err := newRequestSettings(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to get audio duration: %w", err)
+		return nil, err
 	}
 
-	logger.Infow("Audio prepared",
-		"duration", duration.String(),
-	)
+	cfg := &generateConfig{
+		apiKey:         apiKey,
+		provider:       provider,
+		model:          model,
+		modelOverride:  modelOverride,
+		language:       language,
+		transcriptLang: transcriptLang,
+		format:         format,
+		chunkDuration:  time.Duration(chunkDuration) * time.Minute,
+		concurrency:    concurrency,
+		isolateVoice:   isolateVoice,
+		separator:      separator,
+		chunkFormat:    chunkFormat,
+		maxInputBytes:  maxInputMB << 20,
+		inputFormat:    inputFormat,
+		useYTDLP:       useYTDLP,
+		workDir:        workDir,
+		keepTemp:       keepTemp,
+		skipSpaceCheck: skipSpaceCheck,
+		allowSilent:    allowSilent,
+		glossary:       terms,
+		diarize:        diarize,
+		speakers:       speakers,
+		speakerStyles:  subtitle.SpeakerStyles(speakerStyles),
+		multilingual:   multilingual,
+		forced:         forcedNarrative,
+		lyrics:         transcribe.Lyrics(lyrics),
+		prompt:         prompt,
+		decoding:       decoding,
+		safety:         safety,
+		requests:       requests,
+		output:         output,
+		generator: subtitle.DefaultGenerator{
+			MaxCharsPerLine:    maxLineLength,
+			MaxLinesPerSub:     maxLines,
+			MinDuration:        minDuration,
+			MaxDuration:        maxDuration,
+			StretchIntoSilence: stretch,
+		},
+		ignoreDecodeErrors: ignoreDecodeErrors,
+		sampleStart:        sampleStart,
+		sampleLength:       sampleLength,
+		timings:            timings,
+		separatorPath:      separatorPath,
+	}
+	if cfg.fallbacks, err = transcriptionFallbacks(modelFallback, cfg, modelOverride); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// runs the full pipeline for one input: fetch, extract, chunk, transcribe,
+// and write subtitles. outputPath may be empty to derive it from the input.
+func generateSubtitles(
+	ctx context.Context,
+	cfg *generateConfig,
+	input, outputPath string,
+	log *logging.Logger,
+) (*generateResult, error) {
+	started := time.Now()
+	remoteInput := source.IsRemote(input)
 
-	chunkDir := filepath.Join(tempDir, "chunks")
-	chunkDur := time.Duration(chunkDuration) * time.Minute
+	tempDir, cleanupWorkDir, err := newWorkDir(cfg.workDir, cfg.keepTemp)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupWorkDir()
 
-	logger.Infow("Splitting audio into chunks",
-		"chunk_duration", chunkDur.String(),
+	if remoteInput {
+		log.Infow("Fetching input", "source", input)
+		cfg.progress.Stage("Fetching input", 0)
+	}
+
+	sourceOpts := source.DefaultOptions()
+	sourceOpts.MaxBytes = cfg.maxInputBytes
+	sourceOpts.Format = cfg.inputFormat
+	sourceOpts.UseYTDLP = cfg.useYTDLP
+	sourceOpts.Proxy = proxyURL
+	sourceOpts.PublicOnly = cfg.publicURLsOnly
+	media, err := source.Resolve(
+		ctx,
+		input,
+		filepath.Join(tempDir, "input"),
+		sourceOpts,
 	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve input: %w", err)
+	}
+	if remoteInput && !audio.IsMediaFile(media.Path) {
+		return nil, inputErrorf(
+			"unsupported file type: %s (expected audio or video file; use --input-format to override)",
+			filepath.Ext(media.Path),
+		)
+	}
+
+	if outputPath == "" {
+		outputPath = cfg.outputPathFor(media)
+	}
 
-	chunks, err := audio.ChunkAudio(ctx, audioPath, chunkDur, chunkDir)
+	silent, err := checkAudioStreams(ctx, media.Path, cfg.allowSilent, log)
 	if err != nil {
-		return fmt.Errorf("failed to split audio: %w", err)
+		return nil, err
 	}
-	if len(chunks) == 0 {
-		return fmt.Errorf("failed to split audio: no chunks were created")
+	if cfg.sampleLength > 0 {
+		if err := checkSample(ctx, media.Path, cfg.sampleStart, cfg.sampleLength, log); err != nil {
+			return nil, err
+		}
 	}
 
-	if concurrency > len(chunks) {
-		logger.Infow(
-			"Requested concurrency exceeds number of chunks; capping concurrency",
-			"requested_concurrency",
-			concurrency,
-			"chunk_count",
-			len(chunks),
-			"effective_concurrency",
-			len(chunks),
+	if cfg.modelOverride && !isKnownTranscriptionModel(cfg.provider, cfg.model) {
+		log.Warnw("Using a model that is not validated for transcription; requests may fail",
+			"provider", string(cfg.provider),
+			"model", cfg.model,
 		)
-		concurrency = len(chunks)
 	}
 
-	logger.Infow("Created audio chunks",
-		"count", len(chunks),
+	log.Infow("Starting subtitle generation",
+		"input", input,
+		"output", outputPath,
+		"format", string(cfg.format),
+		"chunk_duration", cfg.chunkDuration.String(),
+		"concurrency", cfg.concurrency,
 	)
 
+	meter := runUsage.Child()
+	var timings *transcribe.Timings
+	if cfg.timings {
+		timings = &transcribe.Timings{}
+	}
 	transcribeOpts := transcribe.Options{
-		Language:           language,
-		TranscriptLanguage: transcriptLang,
-		Model:              model,
+		Language:           cfg.language,
+		TranscriptLanguage: cfg.transcriptLang,
+		Model:              cfg.model,
+		Prompt:             cfg.prompt,
+		Temperature:        cfg.decoding.temperature,
+		TopP:               cfg.decoding.topP,
+		ThinkingBudget:     cfg.decoding.thinkingBudget,
+		SafetyThresholds:   cfg.safety,
+		Glossary:           cfg.glossary,
+		Diarize:            cfg.diarize,
+		Speakers:           cfg.speakers,
+		Multilingual:       cfg.multilingual,
+		Lyrics:             cfg.lyrics,
+		Fallbacks:          cfg.fallbacks,
+		Usage:              meter,
+		Limiter:            cfg.limiter,
+		Timings:            timings,
+	}
+	cfg.requests.applyTranscribe(&transcribeOpts, log)
+	var chunksDone, chunksTotal atomic.Int64
+	if cfg.progress != nil || cfg.onChunk != nil {
+		transcribeOpts.OnChunk = func() {
+			cfg.progress.Add(1)
+			done := chunksDone.Add(1)
+			if cfg.onChunk != nil {
+				cfg.onChunk(int(done), int(chunksTotal.Load()))
+			}
+		}
+	}
+	if cfg.keepTemp {
+		transcribeOpts.ResponseDir = filepath.Join(tempDir, "responses")
+	} else {
+		transcribeOpts.RemoveChunks = true
 	}
 
 	transcriber, err := transcribe.Factory(
 		ctx,
-		provider,
-		apiKey,
+		cfg.provider,
+		cfg.apiKey,
 		transcribeOpts,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create transcriber: %w", err)
+		return nil, fmt.Errorf("failed to create transcriber: %w", err)
 	}
 
-	logger.Infow("Transcribing audio",
-		"provider", providerStr,
-		"model", model,
-		"concurrency", concurrency,
-	)
+	p := cfg.pipeline(transcriber)
+	defer shutdownProvider(log, p.Shutdown)
+	if silent {
+		// nothing to transcribe: the subtitles are written empty
+		p.Extract, p.Chunk, p.Transcribe = nil, nil, nil
+		p.Generate = pipeline.NewStage(pipeline.StageGenerate, func(ctx context.Context, s *pipeline.State) error {
+			s.Subtitle = &subtitle.Subtitle{Format: string(cfg.format)}
+			return nil
+		})
+	}
+	// stages run one after another, so the timings need no lock
+	stageStarts := map[string]time.Time{}
+	stageSeconds := map[string]float64{}
+	p.Before = append(p.Before, func(ctx context.Context, stage string, s *pipeline.State) error {
+		stageStarts[stage] = time.Now()
+		switch stage {
+		case pipeline.StageExtract:
+			if cfg.skipSpaceCheck {
+				return nil
+			}
+			return checkWorkspace(
+				ctx,
+				s.MediaPath,
+				s.WorkDir,
+				cfg.chunkFormat,
+				cfg.isolateVoice,
+			)
+		case pipeline.StageTranscribe:
+			chunksTotal.Store(int64(s.Chunks))
+			if cfg.onChunk != nil {
+				cfg.onChunk(0, s.Chunks)
+			}
+			log.Infow("Transcribing audio",
+				"provider", string(cfg.provider),
+				"model", cfg.model,
+				"chunk_duration", cfg.chunkDuration.String(),
+				"chunks", s.Chunks,
+				"concurrency", s.Concurrency,
+			)
+		}
+		return nil
+	})
+	p.After = append(p.After, func(ctx context.Context, stage string, s *pipeline.State) error {
+		stageSeconds[stage] = time.Since(stageStarts[stage]).Seconds()
+		if stage != pipeline.StageWrite {
+			return nil
+		}
+		return cfg.output.writeSidecar(s.OutputPath, media.Path, subtitleSidecar{
+			Source:       input,
+			Language:     subtitleTrackLanguage(cfg),
+			Provider:     string(cfg.provider),
+			Model:        cfg.model,
+			Started:      started,
+			StageSeconds: stageSeconds,
+			Usage:        meter.Usage(),
+		})
+	})
+
+	state := &pipeline.State{
+		MediaPath:  media.Path,
+		WorkDir:    tempDir,
+		OutputPath: outputPath,
+		Log:        log,
+		Progress:   cfg.progress,
+	}
+	if err := p.Run(ctx, state); err != nil {
+		return nil, err
+	}
+	cfg.progress.Done()
 
-	var result *transcribe.Result
-	if concurrentTranscriber, ok := transcriber.(transcribe.ConcurrentTranscriber); ok {
-		result, err = concurrentTranscriber.TranscribeWithChunks(
-			ctx,
-			chunks,
-			concurrency,
+	return &generateResult{
+		Output:   outputPath,
+		Basename: mediaBasename(media),
+		Entries:  len(state.Subtitle.Entries),
+		Duration: state.Duration,
+		Usage:    meter.Usage(),
+		Timings:  timings.Chunks(),
+	}, nil
+}
+
+// checks that the media has an audio stream to transcribe, reporting the
+// streams it has when it does not. Silent media is an error, unless
+// allowSilent, when true is returned so the subtitles are written empty.
+// Media ffprobe cannot read is left for extraction to report.
+func checkAudioStreams(
+	ctx context.Context,
+	mediaPath string,
+	allowSilent bool,
+	log *logging.Logger,
+) (bool, error) {
+	probe, err := ffmpegbin.Probe(ctx, mediaPath)
+	if err != nil {
+		log.Debugw("Could not probe the media for audio streams", "error", err)
+		return false, nil
+	}
+	if probe.HasAudio() {
+		return false, nil
+	}
+	streams := describeStreams(probe.Streams)
+	if !allowSilent {
+		return false, inputErrorf(
+			"%s has no audio stream to transcribe (streams: %s); pass --allow-silent to write an empty subtitle file",
+			filepath.Base(mediaPath),
+			streams,
 		)
-	} else {
-		result, err = transcriber.Transcribe(ctx, audioPath)
 	}
+	log.Warnw("Media has no audio stream, writing empty subtitles",
+		"media", mediaPath,
+		"streams", streams,
+	)
+	return true, nil
+}
+
+// parses a --sample window: a length from the start, such as 3m, or a
+// START-END window in the forms parseTimestamp takes
+func parseSample(s string) (start, length time.Duration, err error) {
+	from, to, window := strings.Cut(s, "-")
+	if !window {
+		if length, err = parseTimestamp(s); err != nil {
+			return 0, 0, err
+		}
+		if length == 0 {
+			return 0, 0, fmt.Errorf("sample length must be positive")
+		}
+		return 0, length, nil
+	}
+	if start, err = parseTimestamp(from); err != nil {
+		return 0, 0, err
+	}
+	end, err := parseTimestamp(to)
 	if err != nil {
-		return fmt.Errorf("transcription failed: %w", err)
+		return 0, 0, err
 	}
+	if end <= start {
+		return 0, 0, fmt.Errorf("sample window %q ends before it starts", s)
+	}
+	return start, end - start, nil
+}
 
-	logger.Infow("Transcription complete",
-		"segments", len(result.Segments),
+// checks that the --sample window starts within the media, and notes that
+// only an excerpt is transcribed. Media ffprobe cannot read is left for
+// extraction to report.
+func checkSample(
+	ctx context.Context,
+	mediaPath string,
+	start, length time.Duration,
+	log *logging.Logger,
+) error {
+	if duration, err := audio.GetDuration(ctx, mediaPath); err == nil && duration > 0 && start >= duration {
+		return inputErrorf(
+			"sample starts at %s, after the end of the %s media",
+			review.FormatTimestamp(start),
+			review.FormatTimestamp(duration),
+		)
+	}
+	log.Warnw("Transcribing a sample of the media only",
+		"from", review.FormatTimestamp(start),
+		"to", review.FormatTimestamp(start+length),
 	)
+	return nil
+}
 
-	generator := subtitle.NewDefaultGenerator()
-	subs, err := generator.Generate(result.Segments)
-	if err != nil {
-		return fmt.Errorf("failed to generate subtitles: %w", err)
+// lists streams by type and codec, such as "video (h264), subtitle (ass)"
+func describeStreams(streams []ffmpegbin.Stream) string {
+	if len(streams) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(streams))
+	for i, s := range streams {
+		parts[i] = s.CodecType
+		if s.CodecName != "" {
+			parts[i] += " (" + s.CodecName + ")"
+		}
 	}
+	return strings.Join(parts, ", ")
+}
 
-	subs.Language = language
-	subs.Format = string(format)
+// default stages for cfg, transcribing with transcriber
+func (c *generateConfig) pipeline(transcriber transcribe.Transcriber) *pipeline.Pipeline {
+	generator := c.generator
+	var generate pipeline.Stage = pipeline.GenerateStage{
+		Generator: &generator,
+		Language:  c.language,
+		Format:    c.format,
+	}
+	if c.forced {
+		generate = forcedNarrativeStage(generate, c.language)
+	}
+	return &pipeline.Pipeline{
+		Extract: pipeline.ExtractStage{
+			Format:       c.chunkFormat,
+			IsolateVoice: c.isolateVoice,
+			Separator:    c.separator,
 
-	writer, err := subtitle.NewWriter(format)
-	if err != nil {
-		return fmt.Errorf("failed to create subtitle writer: %w", err)
+			SeparatorPath:      c.separatorPath,
+			IgnoreDecodeErrors: c.ignoreDecodeErrors,
+			Start:              c.sampleStart,
+			Length:             c.sampleLength,
+		},
+		Chunk: pipeline.ChunkStage{
+			ChunkDuration:  c.chunkDuration,
+			Concurrency:    c.concurrency,
+			MaxConcurrency: transcriptionConcurrencyLimit(c.provider),
+		},
+		Transcribe: pipeline.TranscribeStage{
+			Transcriber:   transcriber,
+			ChunkDuration: c.chunkDuration,
+		},
+		Generate: generate,
+		Write:    pipeline.WriteStage{Format: c.format, Writer: c.writer()},
 	}
+}
 
-	if err := writer.Write(subs, outputPath); err != nil {
-		return fmt.Errorf("failed to write subtitles: %w", err)
+// writer for the subtitles, nil for the default writer of the format
+func (c *generateConfig) writer() subtitle.Writer {
+	if c.speakerStyles == "" {
+		return nil
 	}
+	w := subtitle.NewASSWriter()
+	w.SpeakerStyles = c.speakerStyles
+	return w
+}
 
-	absOutput, _ := filepath.Abs(outputPath)
-	fmt.Printf("Subtitles generated successfully: %s\n", absOutput)
-	fmt.Printf("  Entries: %d\n", len(subs.Entries))
-	fmt.Printf("  Duration: %s\n", duration.String())
+// whether model is one of the built-in transcription models of provider
+func isKnownTranscriptionModel(provider transcribe.Provider, model string) bool {
+	switch provider {
+	case transcribe.ProviderGemini:
+		return isValidGeminiModel(model)
+	case transcribe.ProviderOpenAI:
+		return isValidOpenAIAudioModel(model)
+	case transcribe.ProviderGroq:
+		return isValidGroqAudioModel(model)
+	}
+	return false
+}
 
-	return nil
+// subtitle path next to a local input, or in the working directory for
+// downloaded and piped media
+func defaultOutputPath(media *source.Media, format subtitle.Format) string {
+	baseName := media.Name
+	if media.Remote {
+		baseName = filepath.Base(baseName)
+	}
+	return baseName + subtitle.GetExtensionForFormat(format)
 }
 
+// default output path with --output-dir and --output-template applied
+func (c *generateConfig) outputPathFor(media *source.Media) string {
+	defaultPath := defaultOutputPath(media, c.format)
+	if c.forced {
+		defaultPath = withNameFlag(defaultPath, "forced")
+	}
+	return c.output.path(defaultPath, outputNameData{
+		Basename: mediaBasename(media),
+		Lang:     subtitleTrackLanguage(c),
+		Format:   formatField(defaultPath),
+	})
+}
+
+func mediaBasename(media *source.Media) string {
+	return filepath.Base(media.Name)
+}
+
+// model validation accepts the built-in lists plus any model cached by the
+// last `lipi models` run
 var validGeminiModels = map[string]bool{
 	"gemini-3-pro-preview":   true,
 	"gemini-3-flash-preview": true,
@@ -346,8 +1041,9 @@ var validGeminiModels = map[string]bool{
 	"gemini-2.5-flash-lite":  true,
 }
 
+// reports whether model is a known Gemini model
 func isValidGeminiModel(model string) bool {
-	return validGeminiModels[model]
+	return validGeminiModels[model] || models.Cached("gemini", model)
 }
 
 var validOpenAIModels = map[string]bool{
@@ -365,7 +1061,7 @@ var validOpenAIModels = map[string]bool{
 }
 
 func isValidOpenAIModel(model string) bool {
-	return validOpenAIModels[model]
+	return validOpenAIModels[model] || models.Cached("openai", model)
 }
 
 var validOpenAIAudioModels = map[string]bool{
@@ -376,6 +1072,15 @@ func isValidOpenAIAudioModel(model string) bool {
 	return validOpenAIAudioModels[model]
 }
 
+var validGroqAudioModels = map[string]bool{
+	"whisper-large-v3":       true,
+	"whisper-large-v3-turbo": true,
+}
+
+func isValidGroqAudioModel(model string) bool {
+	return validGroqAudioModels[model]
+}
+
 var validAnthropicModels = map[string]bool{
 	"claude-haiku-4-5":  true,
 	"claude-sonnet-4-5": true,
@@ -383,7 +1088,7 @@ var validAnthropicModels = map[string]bool{
 }
 
 func isValidAnthropicModel(model string) bool {
-	return validAnthropicModels[model]
+	return validAnthropicModels[model] || models.Cached("anthropic", model)
 }
 
 // isValidOpenAITranscriptLanguage checks if the transcript language is supported
@@ -399,3 +1104,9 @@ func isValidOpenAITranscriptLanguage(lang string) bool {
 		return false
 	}
 }
+
+// whether lang asks Whisper to translate the speech to English
+func isEnglishTranscript(lang string) bool {
+	normalized := strings.ToLower(strings.TrimSpace(lang))
+	return normalized == "english" || normalized == "en"
+}
@@ -1,6 +1,15 @@
 package cli
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	ffmpegbin "github.com/mgpai22/lipi/internal/ffmpeg"
+	"github.com/spf13/cobra"
+)
 
 func TestIsValidOpenAITranscriptLanguage(t *testing.T) {
 	tests := []struct {
@@ -50,3 +59,238 @@ func TestIsValidOpenAITranscriptLanguage(t *testing.T) {
 		})
 	}
 }
+
+func TestNewGenerateConfigPromptAndTemperature(t *testing.T) {
+	promptFile := filepath.Join(t.TempDir(), "prompt.txt")
+	if err := os.WriteFile(promptFile, []byte("Speakers are Ana and Raj.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		flags       map[string]string
+		wantPrompt  string
+		wantTemp    *float64
+		wantErrKind errs.Kind
+	}{
+		{
+			name:       "defaults",
+			flags:      map[string]string{},
+			wantPrompt: "",
+		},
+		{
+			name:       "prompt file and prompt",
+			flags:      map[string]string{"prompt-file": promptFile, "prompt": "Keep filler words."},
+			wantPrompt: "Speakers are Ana and Raj.\nKeep filler words.",
+		},
+		{
+			name:     "temperature",
+			flags:    map[string]string{"temperature": "0.2"},
+			wantTemp: ptr(0.2),
+		},
+		{
+			name:        "temperature above openai range",
+			flags:       map[string]string{"provider": "openai", "temperature": "1.5"},
+			wantErrKind: errs.KindInput,
+		},
+		{
+			name:        "multilingual with openai",
+			flags:       map[string]string{"provider": "openai", "multilingual": "true"},
+			wantErrKind: errs.KindInput,
+		},
+		{
+			name:        "multilingual translated transcript",
+			flags:       map[string]string{"multilingual": "true", "transcript-language": "english"},
+			wantErrKind: errs.KindInput,
+		},
+		{
+			name:  "speaker styles",
+			flags: map[string]string{"diarize": "true", "format": "ass", "speaker-styles": "sides"},
+		},
+		{
+			name:        "speaker styles without diarize",
+			flags:       map[string]string{"format": "ass", "speaker-styles": "color"},
+			wantErrKind: errs.KindInput,
+		},
+		{
+			name:        "speaker styles in srt",
+			flags:       map[string]string{"diarize": "true", "speaker-styles": "color"},
+			wantErrKind: errs.KindInput,
+		},
+		{
+			name:        "missing prompt file",
+			flags:       map[string]string{"prompt-file": filepath.Join(t.TempDir(), "missing.txt")},
+			wantErrKind: errs.KindInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "generate"}
+			addGenerateFlags(cmd)
+			cmd.Flags().String("language", "", "")
+			if err := cmd.Flags().Set("api-key", "test-key"); err != nil {
+				t.Fatal(err)
+			}
+			for name, value := range tt.flags {
+				if err := cmd.Flags().Set(name, value); err != nil {
+					t.Fatalf("failed to set --%s: %v", name, err)
+				}
+			}
+
+			cfg, err := newGenerateConfig(cmd)
+			if tt.wantErrKind != errs.KindUnknown {
+				if errs.KindOf(err) != tt.wantErrKind {
+					t.Fatalf("error = %v, want kind %s", err, tt.wantErrKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newGenerateConfig() error = %v", err)
+			}
+			if cfg.prompt != tt.wantPrompt {
+				t.Errorf("prompt = %q, want %q", cfg.prompt, tt.wantPrompt)
+			}
+			if (cfg.decoding.temperature == nil) != (tt.wantTemp == nil) ||
+				(cfg.decoding.temperature != nil && *cfg.decoding.temperature != *tt.wantTemp) {
+				t.Errorf("temperature = %v, want %v", cfg.decoding.temperature, tt.wantTemp)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestNewGenerateConfigModelOverride(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	tests := []struct {
+		name     string
+		flags    map[string]string
+		wantErr  bool
+		wantName string
+	}{
+		{
+			name:    "unknown gemini model",
+			flags:   map[string]string{"model": "gemini-4-flash"},
+			wantErr: true,
+		},
+		{
+			name:     "unknown gemini model with override",
+			flags:    map[string]string{"model": "gemini-4-flash", "model-override": "true"},
+			wantName: "gemini-4-flash",
+		},
+		{
+			name:    "unknown openai model",
+			flags:   map[string]string{"provider": "openai", "model": "gpt-4o-transcribe"},
+			wantErr: true,
+		},
+		{
+			name: "unknown openai model with override",
+			flags: map[string]string{
+				"provider":       "openai",
+				"model":          "gpt-4o-transcribe",
+				"model-override": "true",
+			},
+			wantName: "gpt-4o-transcribe",
+		},
+		{
+			name:     "groq default model",
+			flags:    map[string]string{"provider": "groq"},
+			wantName: "whisper-large-v3",
+		},
+		{
+			name:     "groq turbo model",
+			flags:    map[string]string{"provider": "groq", "model": "whisper-large-v3-turbo"},
+			wantName: "whisper-large-v3-turbo",
+		},
+		{
+			name: "groq turbo cannot translate",
+			flags: map[string]string{
+				"provider":            "groq",
+				"model":               "whisper-large-v3-turbo",
+				"transcript-language": "english",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "generate"}
+			addGenerateFlags(cmd)
+			cmd.Flags().String("language", "", "")
+			if err := cmd.Flags().Set("api-key", "test-key"); err != nil {
+				t.Fatal(err)
+			}
+			for name, value := range tt.flags {
+				if err := cmd.Flags().Set(name, value); err != nil {
+					t.Fatalf("failed to set --%s: %v", name, err)
+				}
+			}
+
+			cfg, err := newGenerateConfig(cmd)
+			if tt.wantErr {
+				if errs.KindOf(err) != errs.KindInput {
+					t.Fatalf("error = %v, want an input error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newGenerateConfig() error = %v", err)
+			}
+			if cfg.model != tt.wantName {
+				t.Errorf("model = %q, want %q", cfg.model, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestDescribeStreams(t *testing.T) {
+	tests := []struct {
+		streams []ffmpegbin.Stream
+		want    string
+	}{
+		{nil, "none"},
+		{
+			[]ffmpegbin.Stream{
+				{CodecType: "video", CodecName: "h264"},
+				{CodecType: "subtitle", CodecName: "ass"},
+				{CodecType: "data"},
+			},
+			"video (h264), subtitle (ass), data",
+		},
+	}
+	for _, tt := range tests {
+		if got := describeStreams(tt.streams); got != tt.want {
+			t.Errorf("describeStreams() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestParseSample(t *testing.T) {
+	tests := []struct {
+		in         string
+		start, len time.Duration
+		wantErr    bool
+	}{
+		{in: "3m", len: 3 * time.Minute},
+		{in: "90", len: 90 * time.Second},
+		{in: "10m-13m", start: 10 * time.Minute, len: 3 * time.Minute},
+		{in: "1:02:00-1:05:30", start: time.Hour + 2*time.Minute, len: 3*time.Minute + 30*time.Second},
+		{in: "0", wantErr: true},
+		{in: "13m-10m", wantErr: true},
+		{in: "-3m", wantErr: true},
+		{in: "10m-", wantErr: true},
+		{in: "a while", wantErr: true},
+	}
+	for _, tt := range tests {
+		start, length, err := parseSample(tt.in)
+		if (err != nil) != tt.wantErr || start != tt.start || length != tt.len {
+			t.Errorf("parseSample(%q) = %v, %v, %v, want %v, %v (error %v)",
+				tt.in, start, length, err, tt.start, tt.len, tt.wantErr)
+		}
+	}
+}
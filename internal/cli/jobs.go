@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/jobs"
+	"github.com/spf13/cobra"
+)
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "List, retry, and cancel server and batch jobs",
+	Long: `Manage the jobs 'lipi serve' and 'lipi batch' record in the job store
+under --data-dir.
+
+A retried server job is picked up by the running server, or by the next one
+started; a retried batch file is processed by 'lipi batch --resume'. A
+cancelled job that is running stops within a few seconds on a server, and a
+batch skips a cancelled file when its turn comes.
+
+Examples:
+  lipi jobs list
+  lipi jobs list --status failed --json
+  lipi jobs list --batch 3f9c2a7d41b05e68
+  lipi jobs retry 0a1b2c3d4e5f6789
+  lipi jobs cancel 0a1b2c3d4e5f6789 9876543210fedcba`,
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List jobs, newest first",
+	Args:  cobra.NoArgs,
+	RunE:  runJobsList,
+}
+
+var jobsRetryCmd = &cobra.Command{
+	Use:   "retry [job_id]...",
+	Short: "Queue failed or cancelled jobs again",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeJobs(cmd, args, jobs.Retry)
+	},
+}
+
+var jobsCancelCmd = &cobra.Command{
+	Use:   "cancel [job_id]...",
+	Short: "Cancel queued or running jobs",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeJobs(cmd, args, jobs.Cancel)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(jobsCmd)
+	jobsCmd.AddCommand(jobsListCmd, jobsRetryCmd, jobsCancelCmd)
+
+	jobsCmd.PersistentFlags().
+		String("data-dir", "", "Directory of the job store (default: user cache dir)")
+	jobsListCmd.Flags().
+		String("status", "", "Only list jobs with this status (queued, running, succeeded, failed, cancelled)")
+	jobsListCmd.Flags().
+		String("batch", "", "Only list the files of the batch with this ID")
+
+	mustRegisterCompletion(jobsListCmd, "status", completeValues(
+		string(jobs.StatusQueued),
+		string(jobs.StatusRunning),
+		string(jobs.StatusSucceeded),
+		string(jobs.StatusFailed),
+		string(jobs.StatusCancelled),
+	))
+}
+
+func openJobStoreFlag(cmd *cobra.Command) (*jobs.SQLiteStore, error) {
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	return openJobStore(resolveDataDir(dataDir))
+}
+
+func runJobsList(cmd *cobra.Command, args []string) error {
+	status, _ := cmd.Flags().GetString("status")
+	batch, _ := cmd.Flags().GetString("batch")
+
+	switch jobs.Status(status) {
+	case "", jobs.StatusQueued, jobs.StatusRunning, jobs.StatusSucceeded, jobs.StatusFailed, jobs.StatusCancelled:
+	default:
+		return inputErrorf(
+			"unsupported status %q: use queued, running, succeeded, failed, or cancelled",
+			status,
+		)
+	}
+
+	store, err := openJobStoreFlag(cmd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	all, err := store.List()
+	if err != nil {
+		return err
+	}
+	list := filterJobs(all, jobs.Status(status), batch)
+
+	report(map[string]any{"jobs": list}, func() {
+		if len(list) == 0 {
+			fmt.Println("No jobs")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ID\tSTATUS\tNAME\tBATCH\tCHUNKS\tBILLED\tCREATED")
+		for _, job := range list {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				job.ID,
+				job.Status,
+				job.Name,
+				job.Batch,
+				jobChunks(job),
+				billedUsage(job.Usage),
+				job.CreatedAt.Local().Format(time.DateTime),
+			)
+		}
+		err = w.Flush()
+	})
+	return err
+}
+
+// the jobs with status and of batch, when set
+func filterJobs(all []*jobs.Job, status jobs.Status, batch string) []*jobs.Job {
+	list := make([]*jobs.Job, 0, len(all))
+	for _, job := range all {
+		if status != "" && job.Status != status {
+			continue
+		}
+		if batch != "" && job.Batch != batch {
+			continue
+		}
+		list = append(list, job)
+	}
+	return list
+}
+
+// chunk progress of a job, e.g. 3/10, or - before it is known
+func jobChunks(job *jobs.Job) string {
+	if job.Chunks == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d/%d", job.ChunksDone, job.Chunks)
+}
+
+// applies change, Retry or Cancel, to each job, continuing past the ones
+// it cannot change
+func changeJobs(
+	cmd *cobra.Command,
+	ids []string,
+	change func(jobs.Store, string) (*jobs.Job, error),
+) error {
+	store, err := openJobStoreFlag(cmd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	var changed []*jobs.Job
+	var errList []error
+	for _, id := range ids {
+		job, err := change(store, id)
+		if errors.Is(err, jobs.ErrNotFound) {
+			err = inputErrorf("job %s not found", id)
+		} else if errors.Is(err, jobs.ErrState) {
+			err = errs.Wrap(errs.KindInput, err)
+		}
+		if err != nil {
+			errList = append(errList, err)
+			continue
+		}
+		changed = append(changed, job)
+	}
+
+	report(map[string]any{"jobs": changed}, func() {
+		for _, job := range changed {
+			fmt.Printf("Job %s is %s\n", job.ID, job.Status)
+		}
+	})
+	if len(errList) > 0 && len(changed) > 0 {
+		return errs.Wrap(errs.KindPartial, errors.Join(errList...))
+	}
+	return errors.Join(errList...)
+}
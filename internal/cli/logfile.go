@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logFilePath   string
+	logMaxSize    int
+	logMaxBackups int
+
+	// log file of this invocation and the JSON logger writing to it; nil
+	// without --log-file
+	logFile       *logging.RotatingFile
+	logFileLogger *logging.Logger
+)
+
+// opens --log-file and sends the logger's entries there as well, as JSON
+func openLogFile(cmd *cobra.Command, args []string) error {
+	if logFilePath == "" {
+		return nil
+	}
+	if logMaxSize < 0 {
+		return inputErrorf("log-max-size must not be negative, got %d", logMaxSize)
+	}
+	if logMaxBackups < 0 {
+		return inputErrorf("log-max-backups must not be negative, got %d", logMaxBackups)
+	}
+
+	file, err := logging.OpenRotatingFile(
+		expandHome(logFilePath),
+		int64(logMaxSize)<<20,
+		logMaxBackups,
+	)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, err)
+	}
+	logFile = file
+	logFileLogger = logging.NewJSONLogger(verbose, file)
+	logger = logger.Tee(logFileLogger)
+	logFileLogger.Infow("Command started",
+		"command", cmd.CommandPath(),
+		"args", args,
+	)
+	return nil
+}
+
+// records how the command ended in the log file and closes it
+func closeLogFile(cmd *cobra.Command, err error) {
+	if logFile == nil {
+		return
+	}
+	command := rootCmd.Name()
+	if cmd != nil {
+		command = cmd.CommandPath()
+	}
+	if err != nil {
+		logFileLogger.Errorw("Command failed",
+			"command", command,
+			"error", err,
+			"error_kind", errs.Code(err),
+		)
+	} else {
+		logFileLogger.Infow("Command finished", "command", command)
+	}
+	_ = logFileLogger.Sync()
+	_ = logFile.Close()
+	logFile, logFileLogger = nil, nil
+}
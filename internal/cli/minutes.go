@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/source"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/summarize"
+	"github.com/spf13/cobra"
+)
+
+var minutesCmd = &cobra.Command{
+	Use:   "minutes [media_file|subtitle_file]",
+	Short: "Write meeting minutes with attendees, decisions, and action items",
+	Long: `Take the minutes of a meeting recording: a title, the attendees, a summary,
+the decisions made, and the action items with their owners and due dates,
+each with the timestamp where it came up, written as markdown.
+
+A media file is transcribed first with speaker labels (--diarize, which
+needs the gemini provider) into a WebVTT file that keeps them, so the
+minutes can say who decided and who took on each task. Existing subtitles
+are reused like 'lipi summarize' does; VTT voice tags and ASS names give
+their speakers. Give the names of the people in the meeting with --speakers
+to have speakers labelled by name instead of "Speaker 1".
+
+Speakers are labelled in each audio chunk on its own, so minutes uses
+10-minute chunks to keep the labels consistent; raise --chunk-duration for
+longer meetings if the labels drift.
+
+Examples:
+  lipi minutes standup.m4a
+  lipi minutes recording.m4a --speakers "Ana,Raj,Mei"
+  lipi minutes meeting.vtt --minutes-provider anthropic
+  lipi minutes call.mp4 --minutes-language english -o notes.md`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(
+		cmd *cobra.Command,
+		args []string,
+		toComplete string,
+	) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return append(audio.MediaExtensions(), "srt", "vtt", "ass", "ssa"), cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: runMinutes,
+}
+
+func init() {
+	rootCmd.AddCommand(minutesCmd)
+
+	addGenerateFlags(minutesCmd)
+	setFlagDefault(minutesCmd, "diarize", "true")
+	setFlagDefault(minutesCmd, "format", string(subtitle.FormatVTT))
+	setFlagDefault(minutesCmd, "chunk-duration", "10")
+	minutesCmd.Flags().
+		String("minutes-provider", "gemini", "Language model provider for the minutes (gemini, openai, anthropic)")
+	minutesCmd.Flags().
+		String("minutes-model", "", "Model to use for the minutes (provider-specific, uses sensible defaults)")
+	minutesCmd.Flags().
+		String("minutes-language", "", "Language to write the minutes in (default: the transcript's)")
+	minutesCmd.Flags().
+		String("minutes-prompt", "", "Additional instructions for the minutes model")
+
+	registerTranslateCompletions(minutesCmd, "minutes-provider", "minutes-model")
+}
+
+// changes the default of a shared flag for one command, in its help too
+func setFlagDefault(cmd *cobra.Command, name, value string) {
+	f := cmd.Flags().Lookup(name)
+	if err := f.Value.Set(value); err != nil {
+		panic(err)
+	}
+	f.DefValue = value
+}
+
+// minutes as reported by --json
+type minutesReport struct {
+	Output      string             `json:"output"`
+	Subtitles   string             `json:"subtitles"`
+	Title       string             `json:"title,omitempty"`
+	Attendees   []string           `json:"attendees"`
+	Summary     string             `json:"summary,omitempty"`
+	Decisions   []decisionReport   `json:"decisions"`
+	ActionItems []actionItemReport `json:"action_items"`
+}
+
+type decisionReport struct {
+	At   string `json:"at,omitempty"`
+	Text string `json:"text"`
+}
+
+type actionItemReport struct {
+	At    string `json:"at,omitempty"`
+	Task  string `json:"task"`
+	Owner string `json:"owner,omitempty"`
+	Due   string `json:"due,omitempty"`
+}
+
+func runMinutes(cmd *cobra.Command, args []string) error {
+	input := args[0]
+	ctx := cmd.Context()
+
+	provider, _ := cmd.Flags().GetString("minutes-provider")
+	model, _ := cmd.Flags().GetString("minutes-model")
+	language, _ := cmd.Flags().GetString("minutes-language")
+	prompt, _ := cmd.Flags().GetString("minutes-prompt")
+	speakers, _ := cmd.Flags().GetStringSlice("speakers")
+	outputPath, _ := cmd.Flags().GetString("output")
+
+	if !isSubtitlePath(input) && !source.IsRemote(input) {
+		if _, err := os.Stat(input); os.IsNotExist(err) {
+			return inputErrorf("file not found: %s", input)
+		}
+		if !audio.IsMediaFile(input) {
+			return inputErrorf(
+				"unsupported file type: %s (expected a subtitle, audio, or video file)",
+				filepath.Ext(input),
+			)
+		}
+	}
+	requests, err := newRequestSettings(cmd)
+	if err != nil {
+		return err
+	}
+	settings, err := newLLMSettings(cmd, provider)
+	if err != nil {
+		return err
+	}
+	// the transcription key also works for the minutes on the same provider
+	var apiKey string
+	if transcribeProvider, _ := cmd.Flags().GetString("provider"); transcribeProvider == provider {
+		apiKey, _ = cmd.Flags().GetString("api-key")
+	}
+	// fail on a missing key before spending a transcription on it
+	gen, err := newLLM(ctx, "minutes", provider, model, apiKey, requests, settings)
+	if err != nil {
+		return err
+	}
+
+	subtitlePath, err := summarySubtitles(ctx, cmd, input)
+	if err != nil {
+		return err
+	}
+	file, err := subtitle.Open(subtitlePath)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, fmt.Errorf("failed to parse subtitle file: %w", err))
+	}
+	sub := file.Subtitle()
+	if len(summarize.Speakers(sub)) == 0 {
+		logger.Warnw("Transcript has no speaker labels: the minutes can only name people who are mentioned",
+			"subtitles", subtitlePath,
+		)
+	}
+
+	logger.Infow("Taking minutes", "subtitles", subtitlePath, "entries", len(sub.Entries))
+	minutes, err := summarize.TakeMinutes(ctx, gen, sub, summarize.Options{
+		Language:  language,
+		Prompt:    prompt,
+		Attendees: speakers,
+	})
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := summarize.WriteMinutes(&buf, minutes); err != nil {
+		return err
+	}
+	if outputPath == "" {
+		outputPath = strings.TrimSuffix(subtitlePath, filepath.Ext(subtitlePath)) + ".minutes.md"
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write minutes: %w", err)
+	}
+
+	result := minutesReport{
+		Output:      absPath(outputPath),
+		Subtitles:   absPath(subtitlePath),
+		Title:       minutes.Title,
+		Attendees:   minutes.Attendees,
+		Summary:     minutes.Summary,
+		Decisions:   make([]decisionReport, len(minutes.Decisions)),
+		ActionItems: make([]actionItemReport, len(minutes.ActionItems)),
+	}
+	for i, d := range minutes.Decisions {
+		result.Decisions[i] = decisionReport{At: minutesTimestamp(d.At), Text: d.Text}
+	}
+	for i, a := range minutes.ActionItems {
+		result.ActionItems[i] = actionItemReport{
+			At:    minutesTimestamp(a.At),
+			Task:  a.Task,
+			Owner: a.Owner,
+			Due:   a.Due,
+		}
+	}
+
+	report(result, func() {
+		fmt.Printf("Minutes written: %s\n", result.Output)
+		if result.Title != "" {
+			fmt.Printf("  Title: %s\n", result.Title)
+		}
+		fmt.Printf("  Attendees: %d\n", len(result.Attendees))
+		fmt.Printf("  Decisions: %d\n", len(result.Decisions))
+		fmt.Printf("  Action items: %d\n", len(result.ActionItems))
+	})
+	return nil
+}
+
+// a minutes timestamp, empty when the model gave none
+func minutesTimestamp(d time.Duration) string {
+	if d < 0 {
+		return ""
+	}
+	return summarize.FormatTimestamp(d)
+}
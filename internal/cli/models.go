@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/httpclient"
+	"github.com/mgpai22/lipi/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "List the models available for each provider",
+	Long: `List the models each provider offers for transcription and translation.
+
+The provider's model-list API is queried with your API key. When no key is
+configured, or the request fails, the built-in list lipi validates against is
+shown instead. A successful query is cached so that --model accepts newly
+released models without waiting for a lipi update.
+
+Models marked "built-in" are the ones this version of lipi was tested with.
+
+Examples:
+  lipi models
+  lipi models --provider gemini
+  lipi models --provider anthropic --builtin`,
+	Args: cobra.NoArgs,
+	RunE: runModels,
+}
+
+func init() {
+	rootCmd.AddCommand(modelsCmd)
+
+	modelsCmd.Flags().
+		String("provider", "", "Only list models for this provider (gemini, openai, anthropic)")
+	modelsCmd.Flags().
+		StringP("api-key", "k", "", "API key for the provider (or set GEMINI_API_KEY/OPENAI_API_KEY/ANTHROPIC_API_KEY env var)")
+	modelsCmd.Flags().
+		Bool("builtin", false, "Show the built-in lists without querying the providers")
+
+	mustRegisterCompletion(modelsCmd, "provider", completeValues("gemini", "openai", "anthropic"))
+}
+
+// env var holding each provider's API key
+var providerKeyEnv = map[string]string{
+	"gemini":    "GEMINI_API_KEY",
+	"openai":    "OPENAI_API_KEY",
+	"anthropic": "ANTHROPIC_API_KEY",
+}
+
+// models lipi validates against without a cached live list
+func builtinModels(provider string) []string {
+	var set map[string]bool
+	switch provider {
+	case "gemini":
+		set = validGeminiModels
+	case "openai":
+		set = make(map[string]bool)
+		for id := range validOpenAIModels {
+			set[id] = true
+		}
+		for id := range validOpenAIAudioModels {
+			set[id] = true
+		}
+	case "anthropic":
+		set = validAnthropicModels
+	}
+
+	ids := make([]string, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// models result as reported by --json
+type modelsReport struct {
+	Providers []providerModels `json:"providers"`
+}
+
+type providerModels struct {
+	Provider string        `json:"provider"`
+	Source   string        `json:"source"`
+	Models   []modelReport `json:"models"`
+}
+
+type modelReport struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name,omitempty"`
+	Builtin     bool   `json:"builtin"`
+}
+
+func runModels(cmd *cobra.Command, args []string) error {
+	provider, _ := cmd.Flags().GetString("provider")
+	apiKey, _ := cmd.Flags().GetString("api-key")
+	builtinOnly, _ := cmd.Flags().GetBool("builtin")
+	// live lists need the network
+	builtinOnly = builtinOnly || httpclient.Offline()
+
+	providers := []string{"gemini", "openai", "anthropic"}
+	if provider != "" {
+		if _, ok := providerKeyEnv[provider]; !ok {
+			return inputErrorf(
+				"unsupported provider %q: use gemini, openai, or anthropic",
+				provider,
+			)
+		}
+		providers = []string{provider}
+	} else if apiKey != "" {
+		return inputErrorf("--api-key requires --provider")
+	}
+
+	var rep modelsReport
+	for _, p := range providers {
+		list, source := listProviderModels(cmd.Context(), p, apiKey, builtinOnly)
+		builtin := make(map[string]bool)
+		for _, id := range builtinModels(p) {
+			builtin[id] = true
+		}
+
+		entry := providerModels{Provider: p, Source: source}
+		for _, m := range list {
+			entry.Models = append(entry.Models, modelReport{
+				ID:          m.ID,
+				DisplayName: m.DisplayName,
+				Builtin:     builtin[m.ID],
+			})
+		}
+		rep.Providers = append(rep.Providers, entry)
+	}
+
+	var err error
+	report(rep, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for i, p := range rep.Providers {
+			if i > 0 {
+				_, _ = fmt.Fprintln(w)
+			}
+			_, _ = fmt.Fprintf(w, "%s (%s)\n", p.Provider, p.Source)
+			for _, m := range p.Models {
+				note := ""
+				if m.Builtin {
+					note = "built-in"
+				}
+				_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\n", m.ID, m.DisplayName, note)
+			}
+		}
+		err = w.Flush()
+	})
+	return err
+}
+
+// live models for a provider, falling back to the built-in list, and a
+// label saying which one was used
+func listProviderModels(
+	ctx context.Context,
+	provider, apiKey string,
+	builtinOnly bool,
+) ([]models.Model, string) {
+	fallback := func() []models.Model {
+		ids := builtinModels(provider)
+		out := make([]models.Model, len(ids))
+		for i, id := range ids {
+			out[i] = models.Model{ID: id}
+		}
+		return out
+	}
+
+	if builtinOnly {
+		return fallback(), "built-in list"
+	}
+
+	if apiKey == "" {
+		apiKey = lookupAPIKey(provider, providerKeyEnv[provider])
+	}
+	if apiKey == "" {
+		return fallback(), "built-in list; no API key"
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	live, err := models.List(ctx, provider, apiKey)
+	if err != nil {
+		logger.Warnw("Falling back to built-in model list",
+			"provider", provider,
+			"error", err,
+		)
+		return fallback(), "built-in list; query failed"
+	}
+
+	if err := models.SaveCache(provider, live); err != nil {
+		logger.Warnw("Failed to cache model list",
+			"provider", provider,
+			"error", err,
+		)
+	}
+	return live, "live"
+}
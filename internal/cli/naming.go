@@ -0,0 +1,305 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/usage"
+	"github.com/mgpai22/lipi/internal/video"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// fields available to --output-template
+type outputNameData struct {
+	Basename string // input name without directory and extension
+	Lang     string // subtitle language, empty when unknown
+	Format   string // subtitle extension without the dot: srt, vtt, or ass
+	Flags    string // track flags such as "forced" or "hi", dot-separated
+}
+
+// output naming from --output-dir, --output-template, and --naming. The
+// zero value keeps each command's default naming next to the input.
+type outputNamer struct {
+	dir             string
+	template        *template.Template
+	forced          bool
+	hearingImpaired bool
+	hiFlag          string // name flag for hearingImpaired
+	shortLang       bool   // use two-letter language codes, as media servers expect
+	sidecar         bool
+	options         map[string]string // settings of the run, recorded in the sidecar
+}
+
+// template used by the --naming presets
+const mediaServerTemplate = "{{.Basename}}.{{.Lang}}.{{.Flags}}.{{.Format}}"
+
+// file name flag each media server recognizes for hearing-impaired (SDH)
+// subtitles; all of them read "forced" as is
+var hearingImpairedFlags = map[string]string{
+	"plex":     "sdh",
+	"jellyfin": "sdh",
+	"bazarr":   "hi",
+}
+
+func addOutputNamingFlags(cmd *cobra.Command) {
+	cmd.Flags().
+		String("output-dir", "", "Directory for output files (default: next to the input)")
+	cmd.Flags().
+		String("output-template", "", "Output file name template, e.g. \"{{.Basename}}.{{.Lang}}.{{.Format}}\" (fields: Basename, Lang, Format, Flags)")
+	cmd.Flags().
+		String("naming", "", "Name files for a media server: plex, jellyfin, or bazarr (e.g. Movie (2020).en.srt)")
+	cmd.Flags().
+		Bool("forced", false, "Mark the subtitles as forced in the file name (e.g. .es.forced.srt)")
+	cmd.Flags().
+		Bool("hearing-impaired", false, "Mark the subtitles as SDH/hearing-impaired in the file name (e.g. .en.hi.srt)")
+	cmd.Flags().
+		Bool("sidecar", false, "Write a <subtitle>.lipi.json file recording how the subtitles were produced")
+	mustRegisterCompletion(cmd, "naming", completeValues("plex", "jellyfin", "bazarr"))
+}
+
+func newOutputNamer(cmd *cobra.Command) (outputNamer, error) {
+	dir, _ := cmd.Flags().GetString("output-dir")
+	text, _ := cmd.Flags().GetString("output-template")
+	naming, _ := cmd.Flags().GetString("naming")
+	forced, _ := cmd.Flags().GetBool("forced")
+	hearingImpaired, _ := cmd.Flags().GetBool("hearing-impaired")
+	sidecar, _ := cmd.Flags().GetBool("sidecar")
+
+	namer := outputNamer{
+		dir:             expandHome(dir),
+		forced:          forced,
+		hearingImpaired: hearingImpaired,
+		hiFlag:          "hi",
+		sidecar:         sidecar,
+	}
+	if sidecar {
+		namer.options = runOptions(cmd)
+	}
+	if naming != "" {
+		naming = strings.ToLower(naming)
+		flag, ok := hearingImpairedFlags[naming]
+		if !ok {
+			return outputNamer{}, inputErrorf(
+				"unsupported naming %q: use plex, jellyfin, or bazarr",
+				naming,
+			)
+		}
+		namer.hiFlag = flag
+		namer.shortLang = true
+	}
+	// presets, and flags without a template, use the media server layout
+	if text == "" && (naming != "" || forced || hearingImpaired) {
+		text = mediaServerTemplate
+	}
+	if text == "" {
+		return namer, nil
+	}
+
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return outputNamer{}, inputErrorf("invalid --output-template: %v", err)
+	}
+	// catch unknown fields now rather than after transcribing
+	var sb strings.Builder
+	sample := outputNameData{Basename: "video", Lang: "en", Format: "srt", Flags: "forced"}
+	if err := tmpl.Execute(&sb, sample); err != nil {
+		return outputNamer{}, inputErrorf("invalid --output-template: %v", err)
+	}
+	if strings.TrimSpace(sb.String()) == "" {
+		return outputNamer{}, inputErrorf("--output-template renders an empty file name")
+	}
+	namer.template = tmpl
+	return namer, nil
+}
+
+// applies the naming to a command's default output path. The template
+// replaces the file name; --output-dir replaces the directory.
+func (n outputNamer) path(defaultPath string, data outputNameData) string {
+	name := filepath.Base(defaultPath)
+	data.Flags = n.flagsField()
+	if n.shortLang {
+		data.Lang = video.ShortLanguageCode(data.Lang)
+	}
+	if n.template != nil {
+		if rendered, err := n.render(data); err == nil {
+			name = rendered
+		}
+	}
+
+	dir := filepath.Dir(defaultPath)
+	if n.dir != "" {
+		dir = n.dir
+	}
+	return filepath.Join(dir, name)
+}
+
+// value of the Flags field, e.g. "forced" or "forced.hi"
+func (n outputNamer) flagsField() string {
+	var flags []string
+	if n.forced {
+		flags = append(flags, "forced")
+	}
+	if n.hearingImpaired {
+		flags = append(flags, n.hiFlag)
+	}
+	return strings.Join(flags, ".")
+}
+
+// renders the template, dropping the separator left by an empty field
+// ("Movie.{{.Lang}}.srt" without a language becomes "Movie.srt")
+func (n outputNamer) render(data outputNameData) (string, error) {
+	var sb strings.Builder
+	if err := n.template.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render output template: %w", err)
+	}
+	segments := strings.Split(filepath.ToSlash(sb.String()), "/")
+	for i, segment := range segments {
+		if segment == ".." {
+			continue
+		}
+		for strings.Contains(segment, "..") {
+			segment = strings.ReplaceAll(segment, "..", ".")
+		}
+		segments[i] = strings.Trim(segment, ". ")
+	}
+	return filepath.FromSlash(strings.Join(segments, "/")), nil
+}
+
+// file name flags media servers recognize after the language code
+var subtitleNameFlags = map[string]bool{
+	"forced": true, "sdh": true, "hi": true, "cc": true, "default": true,
+}
+
+// drops trailing language codes and flags from a subtitle file name
+// ("Movie (2020).en.forced" becomes "Movie (2020)")
+func stripSubtitleSuffixes(name string) string {
+	for {
+		ext := filepath.Ext(name)
+		tag := strings.ToLower(strings.TrimPrefix(ext, "."))
+		isLang := len(tag) == 2 && video.LanguageCode(tag) != ""
+		if ext == "" || ext == name || (!isLang && !subtitleNameFlags[tag]) {
+			return name
+		}
+		name = strings.TrimSuffix(name, ext)
+	}
+}
+
+// path with a name flag before its extension: video.srt -> video.forced.srt
+func withNameFlag(path, flag string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + flag + ext
+}
+
+// extension of a path without the dot, as used for the Format field
+func formatField(path string) string {
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+}
+
+// provenance written next to a subtitle file with --sidecar, enough to
+// trace how it was produced and run it again
+type subtitleSidecar struct {
+	Generator       string             `json:"generator"`
+	Version         string             `json:"version"`
+	Source          string             `json:"source"`
+	SourceSHA256    string             `json:"source_sha256,omitempty"`
+	Language        string             `json:"language,omitempty"`
+	Forced          bool               `json:"forced"`
+	HearingImpaired bool               `json:"hearing_impaired"`
+	Provider        string             `json:"provider"`
+	Model           string             `json:"model,omitempty"`
+	Options         map[string]string  `json:"options,omitempty"`
+	Started         time.Time          `json:"started,omitzero"`
+	ElapsedSeconds  float64            `json:"elapsed_seconds,omitempty"`
+	StageSeconds    map[string]float64 `json:"stage_seconds,omitempty"`
+	Usage           usage.Usage        `json:"usage"`
+	Created         time.Time          `json:"created"`
+}
+
+// flags that hold credentials, or URLs that may embed them; they are left
+// out of the sidecar
+var secretFlags = map[string]bool{
+	"api-key":           true,
+	"proxy":             true,
+	"notify-webhook":    true,
+	"client-secret":     true,
+	"password":          true,
+	"opensubtitles-key": true,
+}
+
+// flags given for the run on the command line, in the environment, or in
+// the config file, with their values
+func runOptions(cmd *cobra.Command) map[string]string {
+	options := map[string]string{}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Name == "help" || secretFlags[f.Name] || !flagProvided(cmd, f.Name) {
+			return
+		}
+		options[f.Name] = f.Value.String()
+	})
+	return options
+}
+
+// path of the sidecar for a subtitle file
+func sidecarPath(subtitlePath string) string {
+	return subtitlePath + ".lipi.json"
+}
+
+// writes the sidecar for subtitlePath when --sidecar is set. sourceFile is
+// the local file the subtitles were made from, hashed into the sidecar, or
+// empty when there is none to hash.
+func (n outputNamer) writeSidecar(subtitlePath, sourceFile string, info subtitleSidecar) error {
+	if !n.sidecar {
+		return nil
+	}
+	info.Generator = "lipi"
+	info.Version = Version
+	info.Forced = n.forced
+	info.HearingImpaired = n.hearingImpaired
+	info.Options = n.options
+	if info.Created.IsZero() {
+		info.Created = time.Now().UTC()
+	}
+	if !info.Started.IsZero() {
+		info.Started = info.Started.UTC()
+		info.ElapsedSeconds = info.Created.Sub(info.Started).Seconds()
+	}
+	if sourceFile != "" {
+		sum, err := fileSHA256(sourceFile)
+		if err != nil {
+			return fmt.Errorf("failed to hash source for sidecar: %w", err)
+		}
+		info.SourceSHA256 = sum
+	}
+
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sidecar: %w", err)
+	}
+	if err := os.WriteFile(sidecarPath(subtitlePath), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write sidecar: %w", err)
+	}
+	return nil
+}
+
+// hex SHA-256 of a file's contents
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,250 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newTestOutputNamer(t *testing.T, dir, tmpl string) outputNamer {
+	t.Helper()
+	cmd := &cobra.Command{Use: "test"}
+	addOutputNamingFlags(cmd)
+	if err := cmd.Flags().Set("output-dir", dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Flags().Set("output-template", tmpl); err != nil {
+		t.Fatal(err)
+	}
+	namer, err := newOutputNamer(cmd)
+	if err != nil {
+		t.Fatalf("newOutputNamer() error = %v", err)
+	}
+	return namer
+}
+
+func TestOutputNamerPath(t *testing.T) {
+	defaultPath := filepath.Join("media", "Movie (2020).srt")
+
+	tests := []struct {
+		name string
+		dir  string
+		tmpl string
+		data outputNameData
+		want string
+	}{
+		{
+			name: "defaults",
+			data: outputNameData{Basename: "Movie (2020)", Lang: "en", Format: "srt"},
+			want: defaultPath,
+		},
+		{
+			name: "template",
+			tmpl: "{{.Basename}}.{{.Lang}}.{{.Format}}",
+			data: outputNameData{Basename: "Movie (2020)", Lang: "en", Format: "srt"},
+			want: filepath.Join("media", "Movie (2020).en.srt"),
+		},
+		{
+			name: "empty language",
+			tmpl: "{{.Basename}}.{{.Lang}}.{{.Format}}",
+			data: outputNameData{Basename: "Movie (2020)", Format: "srt"},
+			want: filepath.Join("media", "Movie (2020).srt"),
+		},
+		{
+			name: "output dir",
+			dir:  "subs",
+			data: outputNameData{Basename: "Movie (2020)", Lang: "en", Format: "srt"},
+			want: filepath.Join("subs", "Movie (2020).srt"),
+		},
+		{
+			name: "output dir and template with subdirectory",
+			dir:  "subs",
+			tmpl: "{{.Basename}}/{{.Basename}}.{{.Lang}}.{{.Format}}",
+			data: outputNameData{Basename: "Movie (2020)", Lang: "es", Format: "vtt"},
+			want: filepath.Join("subs", "Movie (2020)", "Movie (2020).es.vtt"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namer := newTestOutputNamer(t, tt.dir, tt.tmpl)
+			if got := namer.path(defaultPath, tt.data); got != tt.want {
+				t.Errorf("path() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewOutputNamerRejectsInvalidTemplates(t *testing.T) {
+	for _, tmpl := range []string{"{{.Basename", "{{.Title}}.srt", " "} {
+		t.Run(tmpl, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			addOutputNamingFlags(cmd)
+			if err := cmd.Flags().Set("output-template", tmpl); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := newOutputNamer(cmd); err == nil {
+				t.Errorf("newOutputNamer(%q) succeeded, want error", tmpl)
+			}
+		})
+	}
+}
+
+func TestTranslateOutputPathForGeneratedSubtitles(t *testing.T) {
+	cfg := &translateConfig{
+		targetLang: "es",
+		output:     newTestOutputNamer(t, "", "{{.Basename}}.{{.Lang}}.{{.Format}}"),
+	}
+	generated := filepath.Join("media", "Movie (2020).en.srt")
+
+	got := cfg.outputPathFor(generated, "Movie (2020)")
+	want := filepath.Join("media", "Movie (2020).es.srt")
+	if got != want {
+		t.Errorf("outputPathFor() = %q, want %q", got, want)
+	}
+
+	got = cfg.outputPathFor(generated, "")
+	want = filepath.Join("media", "Movie (2020).es.srt")
+	if got != want {
+		t.Errorf("outputPathFor() without basename = %q, want %q", got, want)
+	}
+}
+
+func TestOutputNamerMediaServerNaming(t *testing.T) {
+	defaultPath := filepath.Join("media", "Movie (2020).srt")
+	data := outputNameData{Basename: "Movie (2020)", Lang: "english", Format: "srt"}
+
+	tests := []struct {
+		name  string
+		flags map[string]string
+		want  string
+	}{
+		{
+			name:  "plex",
+			flags: map[string]string{"naming": "plex"},
+			want:  "Movie (2020).en.srt",
+		},
+		{
+			name:  "bazarr hearing impaired",
+			flags: map[string]string{"naming": "bazarr", "hearing-impaired": "true"},
+			want:  "Movie (2020).en.hi.srt",
+		},
+		{
+			name:  "jellyfin forced sdh",
+			flags: map[string]string{"naming": "jellyfin", "forced": "true", "hearing-impaired": "true"},
+			want:  "Movie (2020).en.forced.sdh.srt",
+		},
+		{
+			name:  "forced without preset keeps the language as given",
+			flags: map[string]string{"forced": "true"},
+			want:  "Movie (2020).english.forced.srt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			addOutputNamingFlags(cmd)
+			for name, value := range tt.flags {
+				if err := cmd.Flags().Set(name, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+			namer, err := newOutputNamer(cmd)
+			if err != nil {
+				t.Fatalf("newOutputNamer() error = %v", err)
+			}
+			want := filepath.Join("media", tt.want)
+			if got := namer.path(defaultPath, data); got != want {
+				t.Errorf("path() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteSidecar(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "Movie.srt")
+	if err := os.WriteFile(source, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "Movie.es.forced.srt")
+	namer := outputNamer{sidecar: true, forced: true, options: map[string]string{"to": "es"}}
+	if err := namer.writeSidecar(path, source, subtitleSidecar{
+		Source:   "Movie.srt",
+		Language: "es",
+		Provider: "gemini",
+		Started:  time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatalf("writeSidecar() error = %v", err)
+	}
+
+	data, err := os.ReadFile(sidecarPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got subtitleSidecar
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Generator != "lipi" || !got.Forced || got.HearingImpaired || got.Language != "es" {
+		t.Errorf("sidecar = %+v", got)
+	}
+	// sha256 of "hello"
+	if got.SourceSHA256 != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("source_sha256 = %q", got.SourceSHA256)
+	}
+	if got.Options["to"] != "es" || got.ElapsedSeconds < 60 {
+		t.Errorf("options = %v, elapsed = %g", got.Options, got.ElapsedSeconds)
+	}
+
+	path = filepath.Join(t.TempDir(), "Movie.srt")
+	if err := (outputNamer{}).writeSidecar(path, "", subtitleSidecar{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(sidecarPath(path)); !os.IsNotExist(err) {
+		t.Error("sidecar written without --sidecar")
+	}
+}
+
+func TestRunOptions(t *testing.T) {
+	cmd := &cobra.Command{Use: "generate"}
+	addGenerateFlags(cmd)
+	if err := cmd.ParseFlags([]string{"--api-key", "secret", "--format", "vtt"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := runOptions(cmd)
+	if got["format"] != "vtt" {
+		t.Errorf("runOptions() = %v, want format vtt", got)
+	}
+	if _, ok := got["api-key"]; ok {
+		t.Error("runOptions() recorded the API key")
+	}
+	if _, ok := got["chunk-duration"]; ok {
+		t.Error("runOptions() recorded a flag left at its default")
+	}
+}
+
+func TestStripSubtitleSuffixes(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Movie (2020)", "Movie (2020)"},
+		{"Movie (2020).en", "Movie (2020)"},
+		{"Movie (2020).es.forced.hi", "Movie (2020)"},
+		{"Show.S01E02", "Show.S01E02"},
+		{".en", ".en"},
+	}
+
+	for _, tt := range tests {
+		if got := stripSubtitleSuffixes(tt.name); got != tt.want {
+			t.Errorf("stripSubtitleSuffixes(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
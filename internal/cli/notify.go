@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/notify"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// where to report the end of this run, validated before it starts
+	runNotify []notify.Target
+	// inputs of this run, for notification texts
+	runArgs []string
+)
+
+// how long notifications may take once the command has finished
+const notifyTimeout = 30 * time.Second
+
+func addNotifyFlags(cmd *cobra.Command) {
+	cmd.Flags().
+		StringSlice("notify-webhook", nil, "POST the JSON result to this URL when the run finishes or fails (repeatable)")
+}
+
+// targets from --notify-webhook and the config's notify section, for
+// commands that have the flag
+func notifyTargets(cmd *cobra.Command) ([]notify.Target, error) {
+	if cmd.Flags().Lookup("notify-webhook") == nil {
+		return nil, nil
+	}
+	webhooks, _ := cmd.Flags().GetStringSlice("notify-webhook")
+
+	var targets []notify.Target
+	for _, u := range webhooks {
+		t, err := notify.ParseTarget(string(notify.KindWebhook), u)
+		if err != nil {
+			return nil, inputErrorf("invalid --notify-webhook: %v", err)
+		}
+		targets = append(targets, t)
+	}
+
+	configured := appConfig.Notify()
+	kinds := make([]string, 0, len(configured))
+	for kind := range configured {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		for _, u := range configured[kind] {
+			t, err := notify.ParseTarget(kind, u)
+			if err != nil {
+				return nil, inputErrorf("invalid notify target in %s: %v", appConfig.Path, err)
+			}
+			targets = append(targets, t)
+		}
+	}
+	return targets, nil
+}
+
+// reports how the run ended to the notification targets; failures to
+// deliver are only logged
+func notifyCompletion(cmd *cobra.Command, err error) {
+	if len(runNotify) == 0 || cmd == nil {
+		return
+	}
+
+	msg := notify.Message{
+		Title:   fmt.Sprintf("lipi %s finished", cmd.Name()),
+		Failed:  err != nil,
+		Payload: newJSONReport(cmd, err),
+	}
+	var lines []string
+	if len(runArgs) > 0 {
+		lines = append(lines, "Input: "+strings.Join(runArgs, ", "))
+	}
+	if err != nil {
+		msg.Title = fmt.Sprintf("lipi %s failed", cmd.Name())
+		lines = append(lines, "Error: "+err.Error())
+	}
+	if warnings := logger.Warnings(); len(warnings) > 0 {
+		lines = append(lines, fmt.Sprintf("Warnings: %d", len(warnings)))
+	}
+	msg.Text = strings.Join(lines, "\n")
+
+	// the run's context may already be cancelled, and a failure is exactly
+	// what should still be reported
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if sendErr := notify.Send(ctx, nil, runNotify, msg); sendErr != nil && logger != nil {
+		logger.Warnw("Failed to send notification", "error", sendErr)
+	}
+}
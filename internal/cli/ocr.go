@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/llm"
+	"github.com/mgpai22/lipi/internal/ocr"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/spf13/cobra"
+)
+
+var ocrCmd = &cobra.Command{
+	Use:   "ocr [video_file]",
+	Short: "Read burned-in subtitles from the video frames (experimental)",
+	Long: `Recover subtitles that only exist burned into the picture. Frames are
+sampled every --interval, cropped to the bottom --region of the picture
+where subtitles usually sit, and read by a Gemini vision model a batch at a
+time; consecutive frames showing the same text become one timed cue.
+
+Timing is as precise as the sampling: cues start and end at most one
+interval off. A shorter interval is more precise but sends more frames.
+Use --region 1 for subtitles at the top of the picture or karaoke-style
+text elsewhere.
+
+This mode is experimental: check the result before relying on it.
+
+Examples:
+  lipi ocr movie.mkv
+  lipi ocr movie.mkv --interval 500ms -f vtt
+  lipi ocr clip.mp4 --region 1 --language japanese -o clip.ja.srt`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeMediaFile,
+	RunE:              runOCR,
+}
+
+func init() {
+	rootCmd.AddCommand(ocrCmd)
+
+	ocrCmd.Flags().
+		StringP("api-key", "k", "", "Gemini API key (or set GEMINI_API_KEY env var)")
+	ocrCmd.Flags().
+		String("model", "", "Gemini model to read the frames with (default: gemini-2.5-flash)")
+	ocrCmd.Flags().
+		StringP("format", "f", "srt", "Output subtitle format (srt, vtt, ass)")
+	ocrCmd.Flags().
+		Duration("interval", time.Second, "Time between sampled frames")
+	ocrCmd.Flags().
+		Float64("region", 0.3, "Bottom part of the picture to read, from 0 to 1 (1: the whole frame)")
+	ocrCmd.Flags().
+		Int("batch-size", 20, "Frames sent per request")
+	ocrCmd.Flags().
+		Int("concurrency", 2, "Number of requests in flight at the same time")
+	ocrCmd.Flags().
+		String("language", "", "Language of the subtitles, when known")
+	ocrCmd.Flags().
+		String("work-dir", "", "Directory for the sampled frames (default: system temp)")
+	ocrCmd.Flags().
+		Bool("keep-temp", false, "Keep the sampled frames after the run")
+	ocrCmd.Flags().
+		Float64("temperature", 0, "Sampling temperature for reading the frames (0-2; default: provider default)")
+	addDecodingFlags(ocrCmd)
+	addSafetyFlag(ocrCmd)
+	addRequestFlags(ocrCmd)
+
+	mustRegisterCompletion(ocrCmd, "format", completeValues("srt", "vtt", "ass"))
+}
+
+func runOCR(cmd *cobra.Command, args []string) error {
+	videoPath := args[0]
+	ctx := cmd.Context()
+
+	apiKey, _ := cmd.Flags().GetString("api-key")
+	model, _ := cmd.Flags().GetString("model")
+	formatStr, _ := cmd.Flags().GetString("format")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	region, _ := cmd.Flags().GetFloat64("region")
+	batchSize, _ := cmd.Flags().GetInt("batch-size")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	language, _ := cmd.Flags().GetString("language")
+	workDir, _ := cmd.Flags().GetString("work-dir")
+	keepTemp, _ := cmd.Flags().GetBool("keep-temp")
+	outputPath, _ := cmd.Flags().GetString("output")
+
+	if _, err := os.Stat(videoPath); os.IsNotExist(err) {
+		return inputErrorf("video file not found: %s", videoPath)
+	}
+	format := subtitle.Format(strings.ToLower(formatStr))
+	if _, err := subtitle.NewWriter(format); err != nil {
+		return inputErrorf("invalid format %q: use srt, vtt, or ass", formatStr)
+	}
+	if interval < 100*time.Millisecond {
+		return inputErrorf("interval must be at least 100ms, got %s", interval)
+	}
+	if region <= 0 || region > 1 {
+		return inputErrorf("region must be above 0 and at most 1, got %g", region)
+	}
+	if batchSize <= 0 {
+		return inputErrorf("batch-size must be positive, got %d", batchSize)
+	}
+	if concurrency <= 0 {
+		return inputErrorf("concurrency must be positive, got %d", concurrency)
+	}
+	if outputPath == "" {
+		outputPath = strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) +
+			subtitle.GetExtensionForFormat(format)
+	}
+
+	if err := refuseOfflineProvider(llm.ProviderGemini); err != nil {
+		return err
+	}
+	if apiKey == "" {
+		apiKey = lookupAPIKey(llm.ProviderGemini, providerKeyEnv[llm.ProviderGemini])
+	}
+	if apiKey == "" {
+		return missingAPIKeyError(providerKeyEnv[llm.ProviderGemini])
+	}
+	requests, err := newRequestSettings(cmd)
+	if err != nil {
+		return err
+	}
+	settings, err := newLLMSettings(cmd, llm.ProviderGemini)
+	if err != nil {
+		return err
+	}
+	opts := llm.Options{
+		Model:     model,
+		Operation: "ocr",
+		Usage:     runUsage.Child(),
+	}
+	requests.applyLLM(&opts)
+	settings.apply(&opts)
+	vision, err := llm.NewVision(ctx, llm.ProviderGemini, apiKey, opts)
+	if err != nil {
+		return err
+	}
+
+	tempDir, cleanup, err := newWorkDir(workDir, keepTemp)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	display := startProgress()
+	defer display.Close()
+
+	logger.Infow("Sampling frames", "video", videoPath, "interval", interval.String(), "region", region)
+	display.Stage("Sampling frames", 0)
+	frames, err := ocr.Sample(ctx, videoPath, tempDir, ocr.SampleOptions{
+		Interval: interval,
+		Region:   region,
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Infow("Reading subtitles from frames", "frames", len(frames), "batch_size", batchSize)
+	display.Stage("Reading frames", len(frames))
+	texts, err := ocr.Read(ctx, vision, frames, ocr.ReadOptions{
+		BatchSize:   batchSize,
+		Concurrency: concurrency,
+		Language:    language,
+		OnBatch:     display.Add,
+	})
+	if err != nil {
+		return err
+	}
+	display.Done()
+
+	sub := ocr.Build(frames, texts, interval)
+	if len(sub.Entries) == 0 {
+		return fmt.Errorf("no burned-in subtitles found in %d frames: try a larger --region", len(frames))
+	}
+	writer, err := subtitle.NewWriter(format)
+	if err != nil {
+		return err
+	}
+	if err := writer.Write(sub, outputPath); err != nil {
+		return fmt.Errorf("failed to write subtitles: %w", err)
+	}
+
+	result := map[string]any{
+		"output":  absPath(outputPath),
+		"entries": len(sub.Entries),
+		"frames":  len(frames),
+	}
+	report(result, func() {
+		fmt.Printf("Subtitles read: %s\n", absPath(outputPath))
+		fmt.Printf("  Entries: %d from %d frames\n", len(sub.Entries), len(frames))
+	})
+	return nil
+}
@@ -0,0 +1,389 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/httpclient"
+	"github.com/mgpai22/lipi/internal/opensubtitles"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/translate"
+	"github.com/mgpai22/lipi/internal/video"
+	"github.com/spf13/cobra"
+)
+
+var fetchCmd = &cobra.Command{
+	Use:   "fetch [media_file]",
+	Short: "Download matching subtitles from OpenSubtitles",
+	Long: `Download community subtitles for a media file from OpenSubtitles instead
+of generating them. The file is identified by its OpenSubtitles hash, so the
+subtitles found are timed for this exact release; when nothing matches the
+hash, the file name is searched instead.
+
+Of the matches, subtitles found by hash come first, then human translations,
+then those from trusted uploaders, then the most downloaded.
+
+Searching needs an opensubtitles.com API consumer key, from --opensubtitles-key,
+OPENSUBTITLES_API_KEY, or api_keys.opensubtitles in the config file.
+Signing in with --username and --password (or OPENSUBTITLES_USERNAME and
+OPENSUBTITLES_PASSWORD) raises the daily download quota.
+
+With --translate-to the downloaded subtitles are then translated as by
+'lipi translate'.
+
+Examples:
+  lipi fetch movie.mkv --language en
+  lipi fetch movie.mkv -l en --translate-to es
+  lipi fetch episode.mp4 -l pt-BR -o episode.pt.srt`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeMediaFile,
+	RunE:              runFetch,
+}
+
+var uploadSubtitlesCmd = &cobra.Command{
+	Use:   "upload-subtitles [media_file] [subtitle_file]",
+	Short: "Upload subtitles for a media file to OpenSubtitles",
+	Long: `Share subtitles for a media file on opensubtitles.org, linked to the
+file's OpenSubtitles hash so others with the same release find them.
+
+Uploads sign in with your opensubtitles.org account (--username and
+--password, or OPENSUBTITLES_USERNAME and OPENSUBTITLES_PASSWORD; the
+password is prompted for otherwise). Releases OpenSubtitles has not seen
+need their IMDb ID with --imdb-id. Subtitles already on the site are not
+uploaded again.
+
+Subtitles are marked as machine-made unless --machine-made=false: use that
+only for subtitles a person has reviewed.
+
+Examples:
+  lipi upload-subtitles movie.mkv movie.en.srt --language en
+  lipi upload-subtitles movie.mkv movie.es.srt -l es --imdb-id tt0133093
+  lipi upload-subtitles movie.mkv movie.en.srt -l en --machine-made=false --comment "Reviewed"`,
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(
+		cmd *cobra.Command,
+		args []string,
+		toComplete string,
+	) ([]cobra.Completion, cobra.ShellCompDirective) {
+		switch len(args) {
+		case 0:
+			return completeMediaFile(cmd, args, toComplete)
+		case 1:
+			return completeSubtitleFiles(cmd, nil, toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: runUploadSubtitles,
+}
+
+func init() {
+	rootCmd.AddCommand(fetchCmd)
+	rootCmd.AddCommand(uploadSubtitlesCmd)
+
+	for _, cmd := range []*cobra.Command{fetchCmd, uploadSubtitlesCmd} {
+		cmd.Flags().
+			String("username", "", "OpenSubtitles account name (or set OPENSUBTITLES_USERNAME)")
+		cmd.Flags().
+			String("password", "", "OpenSubtitles account password (or set OPENSUBTITLES_PASSWORD)")
+	}
+
+	fetchCmd.Flags().
+		String("opensubtitles-key", "", "OpenSubtitles API consumer key (or set OPENSUBTITLES_API_KEY)")
+	fetchCmd.Flags().
+		Bool("no-hearing-impaired", false, "Skip subtitles made for the hearing impaired")
+	fetchCmd.Flags().
+		String("translate-to", "", "Also translate the downloaded subtitles to this language")
+	fetchCmd.Flags().
+		String("translate-provider", "gemini", "Translation provider (gemini, openai, anthropic)")
+	fetchCmd.Flags().
+		String("translate-model", "", "Model to use for translation (provider-specific, uses sensible defaults)")
+	fetchCmd.Flags().
+		Bool("overlay", false, "Write bilingual translated subtitles (translated + original)")
+	addRequestFlags(fetchCmd)
+	registerTranslateCompletions(fetchCmd, "translate-provider", "translate-model")
+
+	uploadSubtitlesCmd.Flags().
+		String("imdb-id", "", "IMDb ID of the movie or episode, e.g. tt0133093 (needed for releases OpenSubtitles does not know)")
+	uploadSubtitlesCmd.Flags().
+		String("release", "", "Release name (default: the media file name)")
+	uploadSubtitlesCmd.Flags().
+		String("comment", "", "Comment shown with the subtitles")
+	uploadSubtitlesCmd.Flags().
+		Bool("machine-made", true, "Mark the subtitles as machine transcribed or translated")
+}
+
+// user agent sent to OpenSubtitles, which requires one naming the application
+func openSubtitlesUserAgent() string {
+	return "lipi v" + strings.TrimPrefix(Version, "v")
+}
+
+// the account from the flags or the environment
+func openSubtitlesAccount(cmd *cobra.Command, promptPassword bool) (username, password string, err error) {
+	username, _ = cmd.Flags().GetString("username")
+	password, _ = cmd.Flags().GetString("password")
+	if username == "" {
+		username = os.Getenv("OPENSUBTITLES_USERNAME")
+	}
+	if password == "" {
+		password = os.Getenv("OPENSUBTITLES_PASSWORD")
+	}
+	if username != "" && password == "" && promptPassword && stdinIsTerminal() {
+		password, err = readSecret(fmt.Sprintf("OpenSubtitles password for %s: ", username))
+	}
+	return username, password, err
+}
+
+// fetch result as reported by --json
+type fetchReport struct {
+	Output      string           `json:"output"`
+	Entries     int              `json:"entries"`
+	Language    string           `json:"language"`
+	Release     string           `json:"release,omitempty"`
+	HashMatch   bool             `json:"hash_match"`
+	Remaining   int              `json:"downloads_remaining"`
+	Translation *translateReport `json:"translation,omitempty"`
+}
+
+func runFetch(cmd *cobra.Command, args []string) error {
+	mediaPath := args[0]
+	ctx := cmd.Context()
+
+	language, _ := cmd.Flags().GetString("language")
+	outputPath, _ := cmd.Flags().GetString("output")
+	apiKey, _ := cmd.Flags().GetString("opensubtitles-key")
+	noHI, _ := cmd.Flags().GetBool("no-hearing-impaired")
+	translateTo, _ := cmd.Flags().GetString("translate-to")
+	translateProvider, _ := cmd.Flags().GetString("translate-provider")
+	translateModel, _ := cmd.Flags().GetString("translate-model")
+	overlay, _ := cmd.Flags().GetBool("overlay")
+
+	if language == "" {
+		return inputErrorf("--language is required: the language of the subtitles to download")
+	}
+	if !audio.IsMediaFile(mediaPath) {
+		return inputErrorf(
+			"unsupported file type: %s (expected audio or video file)",
+			filepath.Ext(mediaPath),
+		)
+	}
+	hash, err := opensubtitles.Hash(mediaPath)
+	if err != nil {
+		return err
+	}
+
+	// check the translation settings before spending a download on them
+	var tcfg *translateConfig
+	if translateTo != "" {
+		tcfg = &translateConfig{
+			targetLang:  translateTo,
+			inputLang:   language,
+			provider:    translate.Provider(translateProvider),
+			model:       translateModel,
+			concurrency: concurrencyAuto,
+			batchSize:   translate.DefaultBatchSize,
+			overlay:     overlay,
+		}
+		if tcfg.requests, err = newRequestSettings(cmd); err != nil {
+			return err
+		}
+		if err := tcfg.validate(); err != nil {
+			return fmt.Errorf("invalid translation settings: %w", err)
+		}
+	}
+
+	if err := httpclient.Refuse("download subtitles from OpenSubtitles"); err != nil {
+		return err
+	}
+	if apiKey == "" {
+		apiKey = lookupAPIKey("opensubtitles", "OPENSUBTITLES_API_KEY")
+	}
+	if apiKey == "" {
+		return inputErrorf(
+			"set --opensubtitles-key (or OPENSUBTITLES_API_KEY) to an opensubtitles.com API consumer key",
+		)
+	}
+	client := opensubtitles.New(nil, apiKey, openSubtitlesUserAgent())
+	username, password, err := openSubtitlesAccount(cmd, false)
+	if err != nil {
+		return err
+	}
+	if username != "" && password != "" {
+		if err := client.Login(ctx, username, password); err != nil {
+			return err
+		}
+	}
+
+	lang := video.ShortLanguageCode(language)
+	query := opensubtitles.Query{Hash: hash.Hash, Languages: []string{lang}}
+	logger.Infow("Searching OpenSubtitles", "hash", hash.Hash, "language", lang)
+	results, err := client.Search(ctx, query)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		query = opensubtitles.Query{
+			Name:      strings.TrimSuffix(filepath.Base(mediaPath), filepath.Ext(mediaPath)),
+			Languages: []string{lang},
+		}
+		logger.Infow("No subtitles match the file hash; searching by name", "name", query.Name)
+		if results, err = client.Search(ctx, query); err != nil {
+			return err
+		}
+	}
+	var best *opensubtitles.Subtitle
+	for i := range results {
+		if !noHI || !results[i].HearingImpaired {
+			best = &results[i]
+			break
+		}
+	}
+	if best == nil {
+		return inputErrorf("no %s subtitles found on OpenSubtitles for %s", lang, filepath.Base(mediaPath))
+	}
+
+	logger.Infow("Downloading subtitles",
+		"file", best.FileName,
+		"release", best.Release,
+		"hash_match", best.HashMatch,
+		"downloads", best.Downloads,
+	)
+	data, remaining, err := client.Download(ctx, best.FileID)
+	if err != nil {
+		return err
+	}
+	if outputPath == "" {
+		outputPath = fmt.Sprintf("%s.%s.srt", strings.TrimSuffix(mediaPath, filepath.Ext(mediaPath)), lang)
+	}
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write subtitles: %w", err)
+	}
+	file, err := subtitle.Open(outputPath)
+	if err != nil {
+		return fmt.Errorf("downloaded subtitles are not readable: %w", err)
+	}
+
+	result := fetchReport{
+		Output:    absPath(outputPath),
+		Entries:   len(file.Subtitle().Entries),
+		Language:  lang,
+		Release:   best.Release,
+		HashMatch: best.HashMatch,
+		Remaining: remaining,
+	}
+	if tcfg != nil {
+		tcfg.progress = startProgress()
+		translated, err := translateSubtitles(ctx, tcfg, outputPath, "", logger)
+		tcfg.progress.Close()
+		if err != nil {
+			return err
+		}
+		translation := newTranslateReport(tcfg, translated)
+		result.Translation = &translation
+	}
+
+	report(result, func() {
+		fmt.Printf("Subtitles downloaded: %s\n", result.Output)
+		fmt.Printf("  Entries: %d\n", result.Entries)
+		if result.Release != "" {
+			fmt.Printf("  Release: %s\n", result.Release)
+		}
+		if !result.HashMatch {
+			fmt.Println("  Matched by name, not by file hash: timing may be off for this release")
+		}
+		fmt.Printf("  Downloads left today: %d\n", result.Remaining)
+		if result.Translation != nil {
+			fmt.Printf("Subtitles translated: %s\n", result.Translation.Output)
+		}
+	})
+	return nil
+}
+
+// upload result as reported by --json
+type uploadSubtitlesReport struct {
+	URL       string `json:"url,omitempty"`
+	Language  string `json:"language"`
+	Duplicate bool   `json:"duplicate"`
+}
+
+func runUploadSubtitles(cmd *cobra.Command, args []string) error {
+	mediaPath, subtitlePath := args[0], args[1]
+	ctx := cmd.Context()
+
+	language, _ := cmd.Flags().GetString("language")
+	imdbID, _ := cmd.Flags().GetString("imdb-id")
+	release, _ := cmd.Flags().GetString("release")
+	comment, _ := cmd.Flags().GetString("comment")
+	machineMade, _ := cmd.Flags().GetBool("machine-made")
+
+	if language == "" {
+		return inputErrorf("--language is required: OpenSubtitles needs the subtitle language")
+	}
+	langCode := video.LanguageCode(language)
+	if langCode == "" {
+		return inputErrorf("unsupported language %q: use a two-letter or ISO 639-2 code", language)
+	}
+	hash, err := opensubtitles.Hash(mediaPath)
+	if err != nil {
+		return err
+	}
+	file, err := subtitle.Open(subtitlePath)
+	if err != nil {
+		return inputErrorf("failed to read subtitles: %v", err)
+	}
+	if len(file.Subtitle().Entries) == 0 {
+		return inputErrorf("subtitle file contains no entries")
+	}
+	data, err := os.ReadFile(subtitlePath)
+	if err != nil {
+		return fmt.Errorf("failed to read subtitles: %w", err)
+	}
+
+	if err := httpclient.Refuse("upload subtitles to OpenSubtitles"); err != nil {
+		return err
+	}
+	username, password, err := openSubtitlesAccount(cmd, true)
+	if err != nil {
+		return err
+	}
+	if username == "" || password == "" {
+		return inputErrorf(
+			"set --username and --password (or OPENSUBTITLES_USERNAME and OPENSUBTITLES_PASSWORD) to your opensubtitles.org account",
+		)
+	}
+	if release == "" {
+		release = strings.TrimSuffix(filepath.Base(mediaPath), filepath.Ext(mediaPath))
+	}
+
+	logger.Infow("Uploading subtitles to OpenSubtitles", "hash", hash.Hash, "language", langCode)
+	uploader := opensubtitles.NewUploader(nil, openSubtitlesUserAgent())
+	uploaded, err := uploader.Upload(ctx, username, password, opensubtitles.Contribution{
+		Media:       hash,
+		MediaName:   filepath.Base(mediaPath),
+		FileName:    filepath.Base(subtitlePath),
+		Data:        data,
+		Language:    langCode,
+		IMDbID:      imdbID,
+		Release:     release,
+		Comment:     comment,
+		MachineMade: machineMade,
+	})
+	if err != nil {
+		return err
+	}
+
+	report(uploadSubtitlesReport{
+		URL:       uploaded.URL,
+		Language:  langCode,
+		Duplicate: uploaded.Duplicate,
+	}, func() {
+		if uploaded.Duplicate {
+			fmt.Println("OpenSubtitles already has these subtitles; nothing uploaded")
+			return
+		}
+		fmt.Printf("Subtitles uploaded: %s\n", uploaded.URL)
+	})
+	return nil
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mgpai22/lipi/internal/progress"
+	"github.com/mgpai22/lipi/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	jsonOutput bool
+	// provider usage for the whole run, across every stage and file
+	runUsage = &usage.Meter{}
+	// result recorded by the command for --json
+	runResult any
+)
+
+// starts a live progress display when stderr is a terminal and --verbose is
+// not set. Info logs are suppressed while it runs; without it the regular
+// log lines describe each stage. The caller must Close the display.
+func startProgress() *progress.Display {
+	if verbose || !progress.IsTerminal(os.Stderr) {
+		return nil
+	}
+	logger.Quiet()
+	return progress.New(os.Stderr)
+}
+
+// records the command's result for --json and notifications, and unless
+// --json is set runs text to print the human-readable summary
+func report(result any, text func()) {
+	runResult = result
+	if jsonOutput {
+		return
+	}
+	text()
+}
+
+// document written to stdout when --json is set
+type jsonReport struct {
+	Command   string      `json:"command"`
+	OK        bool        `json:"ok"`
+	Error     string      `json:"error,omitempty"`
+	ErrorKind string      `json:"error_kind,omitempty"`
+	Result    any         `json:"result,omitempty"`
+	Usage     usage.Usage `json:"usage"`
+	Warnings  []string    `json:"warnings"`
+}
+
+func writeJSONReport(w io.Writer, cmd *cobra.Command, err error) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newJSONReport(cmd, err))
+}
+
+// the --json document for the run, also posted to notification webhooks
+func newJSONReport(cmd *cobra.Command, err error) jsonReport {
+	rep := jsonReport{
+		OK:       err == nil,
+		Result:   runResult,
+		Usage:    runUsage.Usage(),
+		Warnings: logger.Warnings(),
+	}
+	if cmd != nil {
+		rep.Command = cmd.Name()
+	}
+	if err != nil {
+		rep.Error = err.Error()
+		rep.ErrorKind = errorKind(err).String()
+	}
+	if rep.Warnings == nil {
+		rep.Warnings = []string{}
+	}
+	return rep
+}
+
+// output paths are reported as absolute paths
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+func TestWriteJSONReport(t *testing.T) {
+	prevLogger, prevResult, prevJSON := logger, runResult, jsonOutput
+	t.Cleanup(func() {
+		logger, runResult, jsonOutput = prevLogger, prevResult, prevJSON
+	})
+
+	var logs bytes.Buffer
+	logger = logging.NewLogger(false, &logs)
+	logger.With("file", "ep01.mkv").Warnw("Low disk space")
+	logger.Infow("Not a warning")
+
+	jsonOutput = true
+	printed := false
+	report(generateReport{Output: "/tmp/ep01.srt", Entries: 12}, func() {
+		printed = true
+	})
+	if printed {
+		t.Error("report should not print text in --json mode")
+	}
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{Use: "generate"}
+	if err := writeJSONReport(&out, cmd, errors.New("boom")); err != nil {
+		t.Fatalf("writeJSONReport() error = %v", err)
+	}
+
+	var got struct {
+		Command  string         `json:"command"`
+		OK       bool           `json:"ok"`
+		Error    string         `json:"error"`
+		Result   generateReport `json:"result"`
+		Warnings []string       `json:"warnings"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if got.Command != "generate" || got.OK || got.Error != "boom" {
+		t.Errorf("unexpected report header: %+v", got)
+	}
+	if got.Result.Output != "/tmp/ep01.srt" || got.Result.Entries != 12 {
+		t.Errorf("unexpected result: %+v", got.Result)
+	}
+	if len(got.Warnings) != 1 || got.Warnings[0] != "Low disk space" {
+		t.Errorf("warnings = %v, want [Low disk space]", got.Warnings)
+	}
+}
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/podcast"
+	"github.com/mgpai22/lipi/internal/source"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/transcribe"
+	"github.com/spf13/cobra"
+)
+
+var podcastCmd = &cobra.Command{
+	Use:   "podcast [feed_url|feed_file]",
+	Short: "Transcribe the newest episodes of a podcast RSS feed",
+	Long: `Download the newest episodes of a podcast from its RSS feed and generate
+subtitles for each, with the same settings as 'lipi generate'.
+
+Files are named after the episode's date and title ("2024-05-01 Episode
+Title.srt") in the current directory, or where --output-dir and
+--output-template say. --transcript-json also writes a Podcasting 2.0 JSON
+transcript next to each subtitle file, ready to publish with a
+<podcast:transcript type="application/json"> tag.
+
+Episodes are processed like a batch: a failed episode does not stop the
+others, and --skip-existing resumes an interrupted run.
+
+Examples:
+  lipi podcast https://example.com/feed.xml --latest 5
+  lipi podcast feed.xml --latest 3 --transcript-json --output-dir transcripts/
+  lipi podcast https://example.com/feed.xml --latest 0 --skip-existing --jobs 2`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPodcast,
+}
+
+func init() {
+	rootCmd.AddCommand(podcastCmd)
+
+	addGenerateFlags(podcastCmd)
+	addOutputNamingFlags(podcastCmd)
+	addNotifyFlags(podcastCmd)
+	podcastCmd.Flags().
+		Int("latest", 1, "Number of newest episodes to transcribe (0: all)")
+	podcastCmd.Flags().
+		Bool("transcript-json", false, "Also write a Podcasting 2.0 JSON transcript for each episode")
+	podcastCmd.Flags().
+		Bool("skip-existing", false, "Skip episodes whose subtitle output already exists")
+	podcastCmd.Flags().
+		IntP("jobs", "j", 1, "Number of episodes to process at the same time")
+	podcastCmd.Flags().
+		Bool("fail-fast", false, "Stop after the first failed episode")
+}
+
+func runPodcast(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	if output, _ := cmd.Flags().GetString("output"); output != "" {
+		return inputErrorf(
+			"--output is not supported by podcast: use --output-dir or --output-template",
+		)
+	}
+
+	latest, _ := cmd.Flags().GetInt("latest")
+	transcriptJSON, _ := cmd.Flags().GetBool("transcript-json")
+	skipExisting, _ := cmd.Flags().GetBool("skip-existing")
+	jobs, _ := cmd.Flags().GetInt("jobs")
+	failFast, _ := cmd.Flags().GetBool("fail-fast")
+
+	if latest < 0 {
+		return inputErrorf("latest must not be negative, got %d", latest)
+	}
+	if jobs <= 0 {
+		return inputErrorf("jobs must be positive, got %d", jobs)
+	}
+
+	cfg, err := newGenerateConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	feed, err := podcast.Load(ctx, nil, args[0])
+	if err != nil {
+		return err
+	}
+	episodes := feed.Latest(latest)
+
+	budget := batchRequestBudget(cfg)
+	fileCfg := *cfg
+	fileCfg.limiter = transcribe.NewLimiter(budget)
+
+	logger.Infow("Starting podcast",
+		"feed", feed.Title,
+		"episodes", len(episodes),
+		"jobs", jobs,
+		"requests_in_flight", budget,
+	)
+
+	items := make([]batchItem, len(episodes))
+	for i, episode := range episodes {
+		items[i] = batchItem{
+			Input:  episode.MediaURL,
+			Output: cfg.outputPathFor(&source.Media{Name: episode.Basename()}),
+		}
+	}
+
+	opts := batchOptions{
+		jobs:         jobs,
+		skipExisting: skipExisting,
+		failFast:     failFast,
+		stage:        "Transcribing episodes",
+	}
+	if transcriptJSON {
+		opts.done = func(item *batchItem, result *generateResult) error {
+			return writePodcastTranscript(result.Output)
+		}
+	}
+	runBatchItems(ctx, &fileCfg, items, opts)
+
+	if err := printBatchSummary(items); err != nil {
+		return err
+	}
+	if err := cmd.Context().Err(); err != nil {
+		return fmt.Errorf(
+			"podcast interrupted: rerun with --skip-existing to resume: %w",
+			err,
+		)
+	}
+	return nil
+}
+
+// JSON transcript next to a subtitle file: video.en.srt becomes video.en.json
+func podcastTranscriptPath(subtitlePath string) string {
+	return strings.TrimSuffix(subtitlePath, filepath.Ext(subtitlePath)) + ".json"
+}
+
+func writePodcastTranscript(subtitlePath string) error {
+	file, err := subtitle.Open(subtitlePath)
+	if err != nil {
+		return fmt.Errorf("failed to read subtitles for transcript: %w", err)
+	}
+	return podcast.WriteTranscript(podcastTranscriptPath(subtitlePath), file.Subtitle())
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/review"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/spf13/cobra"
+)
+
+func addPreviewFlag(cmd *cobra.Command) {
+	cmd.Flags().
+		Int("preview", 0, "Print the first N cues of the written subtitles to check language, formatting, and timing")
+}
+
+// prints the first n cues of the subtitle file at path, indented under the
+// command's summary. The file has just been written, so failing to read it
+// back is only noted.
+func printPreview(w io.Writer, path string, n int) {
+	if n <= 0 {
+		return
+	}
+	file, err := subtitle.Open(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "  Preview unavailable: %v\n", err)
+		return
+	}
+
+	entries := file.Subtitle().Entries
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	_, _ = fmt.Fprintf(w, "  Preview (%d of %d cues):\n", len(entries), len(file.Subtitle().Entries))
+	for i, e := range entries {
+		_, _ = fmt.Fprintf(w, "    %d  %s --> %s\n",
+			i+1,
+			review.FormatTimestamp(e.StartTime),
+			review.FormatTimestamp(e.EndTime),
+		)
+		for _, line := range strings.Split(e.Text, "\n") {
+			_, _ = fmt.Fprintf(w, "       %s\n", line)
+		}
+	}
+}
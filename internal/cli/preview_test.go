@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrintPreview(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "video.srt")
+	content := "1\n00:00:01,000 --> 00:00:02,500\nHello\n\n" +
+		"2\n00:00:03,000 --> 00:00:04,000\nTwo\nlines\n\n" +
+		"3\n00:00:05,000 --> 00:00:06,000\nThird\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	printPreview(&buf, path, 2)
+	want := "  Preview (2 of 3 cues):\n" +
+		"    1  00:00:01.000 --> 00:00:02.500\n" +
+		"       Hello\n" +
+		"    2  00:00:03.000 --> 00:00:04.000\n" +
+		"       Two\n" +
+		"       lines\n"
+	if buf.String() != want {
+		t.Errorf("printPreview() = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	printPreview(&buf, path, 0)
+	if buf.Len() != 0 {
+		t.Errorf("printPreview() with n = 0 printed %q", buf.String())
+	}
+
+	buf.Reset()
+	printPreview(&buf, filepath.Join(t.TempDir(), "missing.srt"), 2)
+	if !strings.Contains(buf.String(), "Preview unavailable") {
+		t.Errorf("printPreview() for a missing file = %q", buf.String())
+	}
+}
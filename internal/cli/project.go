@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/glossary"
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/project"
+	"github.com/mgpai22/lipi/internal/source"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/translate"
+	"github.com/mgpai22/lipi/internal/video"
+)
+
+// the project a translation shares names and decisions with
+type translateProject struct {
+	path     string
+	lang     string           // key of the target language in the project
+	settings project.Language // as they were when the translation started
+}
+
+// finds the project of the subtitles being translated: the one --project
+// names, or lipi.project.yaml in their folder or above. It returns nil
+// without one. A project named by --project may not exist yet; it is
+// created once the translation is done.
+func openTranslateProject(cfg *translateConfig, subtitlePath string, log *logging.Logger) (*translateProject, error) {
+	if cfg.noProject {
+		return nil, nil
+	}
+	path := cfg.project
+	if path == "" {
+		dir := filepath.Dir(subtitlePath)
+		if subtitlePath == source.Stdin {
+			dir = "."
+		}
+		if path = project.Find(dir); path == "" {
+			return nil, nil
+		}
+	}
+
+	p, err := project.Load(path)
+	if errors.Is(err, os.ErrNotExist) && cfg.project != "" {
+		p, err = &project.Project{}, nil
+	}
+	if err != nil {
+		return nil, errs.Wrap(errs.KindInput, err)
+	}
+	tp := &translateProject{
+		path: path,
+		lang: strings.ToLower(video.ShortLanguageCode(cfg.targetLang)),
+	}
+	tp.settings = *p.Language(tp.lang)
+	if cfg.honorifics != "" {
+		tp.settings.Honorifics = cfg.honorifics
+	}
+	log.Infow("Using project",
+		"project", path,
+		"language", tp.lang,
+		"characters", len(tp.settings.Characters),
+		"phrases", len(tp.settings.Phrases),
+		"honorifics", string(tp.settings.Honorifics),
+	)
+	return tp, nil
+}
+
+// the glossary of the run: the --glossary terms, then the project's terms
+// it does not already cover
+func (tp *translateProject) glossary(terms glossary.Glossary) glossary.Glossary {
+	if tp == nil {
+		return terms
+	}
+	seen := make(map[string]bool, len(terms))
+	for _, e := range terms {
+		seen[e.Term] = true
+	}
+	merged := append(glossary.Glossary{}, terms...)
+	for _, e := range tp.settings.Glossary() {
+		if !seen[e.Term] {
+			merged = append(merged, e)
+		}
+	}
+	return merged
+}
+
+// the additional instructions of the run, with the project's honorifics
+// decision after --prompt
+func (tp *translateProject) prompt(prompt string) string {
+	if tp == nil {
+		return prompt
+	}
+	instruction := tp.settings.Honorifics.Instruction()
+	if instruction == "" {
+		return prompt
+	}
+	if prompt == "" {
+		return instruction
+	}
+	return prompt + " " + instruction
+}
+
+// adds the names and phrases this translation settled on to the project,
+// along with the episode and a --honorifics decision, and returns how many
+// terms were added. Terms are asked of the translation provider's model;
+// when that fails the episode is still recorded.
+func (tp *translateProject) record(
+	ctx context.Context,
+	cfg *translateConfig,
+	subtitlePath string,
+	sub *subtitle.Subtitle,
+	results []translate.TranslationResult,
+	log *logging.Logger,
+) (int, error) {
+	var terms project.Terms
+	if _, ok := providerKeyEnv[string(cfg.provider)]; ok && len(results) > 0 {
+		learned, err := tp.extract(ctx, cfg, sub, results)
+		if err != nil {
+			log.Warnw("Failed to learn project terms", "project", tp.path, "error", err)
+		}
+		terms = learned
+	}
+
+	added := 0
+	err := project.Update(tp.path, func(p *project.Project) error {
+		l := p.Language(tp.lang)
+		if cfg.honorifics != "" {
+			l.Honorifics = cfg.honorifics
+		}
+		added = l.Learn(terms)
+		if subtitlePath != source.Stdin {
+			l.AddEpisode(filepath.Base(subtitlePath))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	log.Infow("Updated project", "project", tp.path, "new_terms", added)
+	return added, nil
+}
+
+func (tp *translateProject) extract(
+	ctx context.Context,
+	cfg *translateConfig,
+	sub *subtitle.Subtitle,
+	results []translate.TranslationResult,
+) (project.Terms, error) {
+	gen, err := newLLM(
+		ctx, "project", string(cfg.provider), cfg.model, cfg.apiKey, cfg.requests,
+		llmSettings{decoding: cfg.decoding, safety: cfg.safety},
+	)
+	if err != nil {
+		return project.Terms{}, err
+	}
+	pairs := make([]project.Pair, 0, len(results))
+	for _, r := range results {
+		if r.Index >= 0 && r.Index < len(sub.Entries) {
+			pairs = append(pairs, project.Pair{Original: sub.Entries[r.Index].Text, Translation: r.Text})
+		}
+	}
+	return project.Extract(ctx, gen, pairs, cfg.targetLang, tp.glossary(cfg.glossary).Terms())
+}
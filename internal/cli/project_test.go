@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/glossary"
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/project"
+	"github.com/mgpai22/lipi/internal/translate"
+)
+
+func TestTranslateProject(t *testing.T) {
+	t.Setenv(translate.MockTranslationsEnv, filepath.Join("testdata", "translations.json"))
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "season1", "episode.srt")
+	if err := os.MkdirAll(filepath.Dir(input), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "episode.srt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(input, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	projectPath := filepath.Join(dir, project.FileName)
+	if err := os.WriteFile(projectPath, []byte("languages:\n  es:\n    characters:\n      Ana: Anita\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &translateConfig{
+		targetLang: "spanish",
+		provider:   translate.ProviderMock,
+		batchSize:  2,
+		honorifics: project.HonorificsDrop,
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+	log := logging.NewLogger(false, io.Discard)
+	result, err := translateSubtitles(context.Background(), cfg, input, filepath.Join(dir, "out.srt"), log)
+	if err != nil {
+		t.Fatalf("translateSubtitles() error = %v", err)
+	}
+	if result.Project != projectPath {
+		t.Errorf("Project = %q, want %q", result.Project, projectPath)
+	}
+
+	p, err := project.Load(projectPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := p.Languages["es"]
+	if l.Honorifics != project.HonorificsDrop ||
+		l.Characters["Ana"] != "Anita" ||
+		!reflect.DeepEqual(l.Episodes, []string{"episode.srt"}) {
+		t.Errorf("project language = %+v", l)
+	}
+}
+
+func TestTranslateProjectPrompt(t *testing.T) {
+	tp := &translateProject{settings: project.Language{
+		Honorifics: project.HonorificsKeep,
+		Characters: map[string]string{"Ana": "Anita", "Raj": "Raj"},
+	}}
+	got := tp.glossary(glossary.Glossary{{Term: "Ana", Translation: "Ana"}})
+	want := glossary.Glossary{{Term: "Ana", Translation: "Ana"}, {Term: "Raj", Translation: "Raj"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("glossary() = %+v, want %+v", got, want)
+	}
+	if got := tp.prompt("Be brief."); got != "Be brief. "+project.HonorificsKeep.Instruction() {
+		t.Errorf("prompt() = %q", got)
+	}
+
+	var none *translateProject
+	if got := none.prompt("Be brief."); got != "Be brief." {
+		t.Errorf("prompt() without a project = %q", got)
+	}
+}
@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/proofread"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/video"
+	"github.com/spf13/cobra"
+)
+
+var proofreadCmd = &cobra.Command{
+	Use:   "proofread [subtitle_file]",
+	Short: "Fix spelling and grammar mistakes in subtitles",
+	Long: `Proofread the cues of a subtitle file and write a corrected copy
+(video.proofread.srt) together with a diff of every change
+(video.proofread.diff) to review before using it.
+
+The hunspell checker, the default, runs the local hunspell spell checker
+with the dictionary for --language (en_US for en, de_DE for de, ...) or the
+one named by --dictionary, and replaces each misspelled word with its first
+suggestion; words without suggestions are logged and left alone. Add names
+and other words it should accept to a --personal-dictionary file, one per
+line. It needs hunspell and the dictionary installed, and sends nothing
+over the network.
+
+The llm checker asks a language model to fix spelling, grammar, and
+punctuation mistakes, a batch of cues per request, without rewording lines
+that are already correct.
+
+Examples:
+  lipi proofread subs.srt --language en
+  lipi proofread subs.srt --dictionary en_GB --personal-dictionary names.txt
+  lipi proofread episode.de.vtt --checker llm --language german
+  lipi proofread subs.ass --checker llm --provider anthropic -o subs.ass`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSubtitleFiles,
+	RunE:              runProofread,
+}
+
+// proofreading checkers
+const (
+	checkerHunspell = "hunspell"
+	checkerLLM      = "llm"
+)
+
+func init() {
+	rootCmd.AddCommand(proofreadCmd)
+
+	proofreadCmd.Flags().
+		String("checker", checkerHunspell, "How to proofread: hunspell (local spell check) or llm (spelling and grammar by a language model)")
+	proofreadCmd.Flags().
+		String("dictionary", "", "Hunspell dictionary name or path (default: the one for --language)")
+	proofreadCmd.Flags().
+		String("personal-dictionary", "", "File of extra words hunspell should accept, one per line")
+	proofreadCmd.Flags().
+		String("report", "", "Path of the diff of changes (default: next to the output, ending in .diff)")
+	proofreadCmd.Flags().
+		String("provider", "gemini", "Language model provider for --checker llm (gemini, openai, anthropic)")
+	proofreadCmd.Flags().
+		String("model", "", "Model to use for --checker llm (provider-specific, uses sensible defaults)")
+	proofreadCmd.Flags().
+		StringP("api-key", "k", "", "API key (or set GEMINI_API_KEY/OPENAI_API_KEY/ANTHROPIC_API_KEY env var)")
+	proofreadCmd.Flags().
+		String("prompt", "", "Additional instructions for the language model")
+	proofreadCmd.Flags().
+		Int("batch-size", 50, "Cues per language model request")
+	proofreadCmd.Flags().
+		Int("concurrency", 2, "Number of language model requests in flight at the same time")
+	proofreadCmd.Flags().
+		Bool("preserve-formatting", false, "Write SRT and VTT cues left uncorrected exactly as read, keeping the original numbering, spacing, and line endings")
+	addRequestFlags(proofreadCmd)
+
+	mustRegisterCompletion(proofreadCmd, "checker", completeValues(checkerHunspell, checkerLLM))
+	registerTranslateCompletions(proofreadCmd, "provider", "model")
+}
+
+func runProofread(cmd *cobra.Command, args []string) error {
+	subtitlePath := args[0]
+	ctx := cmd.Context()
+
+	checkerName, _ := cmd.Flags().GetString("checker")
+	language, _ := cmd.Flags().GetString("language")
+	dictionary, _ := cmd.Flags().GetString("dictionary")
+	personal, _ := cmd.Flags().GetString("personal-dictionary")
+	reportPath, _ := cmd.Flags().GetString("report")
+	provider, _ := cmd.Flags().GetString("provider")
+	model, _ := cmd.Flags().GetString("model")
+	apiKey, _ := cmd.Flags().GetString("api-key")
+	prompt, _ := cmd.Flags().GetString("prompt")
+	batchSize, _ := cmd.Flags().GetInt("batch-size")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	outputPath, _ := cmd.Flags().GetString("output")
+	preserve, _ := cmd.Flags().GetBool("preserve-formatting")
+
+	if _, err := os.Stat(subtitlePath); os.IsNotExist(err) {
+		return inputErrorf("subtitle file not found: %s", subtitlePath)
+	}
+	if batchSize <= 0 {
+		return inputErrorf("batch-size must be positive, got %d", batchSize)
+	}
+	if concurrency <= 0 {
+		return inputErrorf("concurrency must be positive, got %d", concurrency)
+	}
+	if outputPath == "" {
+		outputPath = withNameFlag(subtitlePath, "proofread")
+	}
+	if reportPath == "" {
+		reportPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".diff"
+	}
+
+	display := startProgress()
+	defer display.Close()
+
+	var checker proofread.Checker
+	switch checkerName {
+	case checkerHunspell:
+		for _, name := range []string{"provider", "model", "api-key", "prompt"} {
+			if flagProvided(cmd, name) {
+				return inputErrorf("--%s requires --checker %s", name, checkerLLM)
+			}
+		}
+		if dictionary == "" {
+			dictionary = hunspellDictionary(language)
+		}
+		if dictionary == "" {
+			return inputErrorf("--checker %s needs --language or --dictionary", checkerHunspell)
+		}
+		checker = &proofread.Hunspell{
+			Dictionary: dictionary,
+			Personal:   expandHome(personal),
+			Unknown: func(index int, word string) {
+				logger.Warnw("Misspelled word has no suggestion", "entry", index+1, "word", word)
+			},
+		}
+	case checkerLLM:
+		if flagProvided(cmd, "dictionary") || flagProvided(cmd, "personal-dictionary") {
+			return inputErrorf("--dictionary and --personal-dictionary require --checker %s", checkerHunspell)
+		}
+		requests, err := newRequestSettings(cmd)
+		if err != nil {
+			return err
+		}
+		gen, err := newLLM(ctx, "proofread", provider, model, apiKey, requests, llmSettings{})
+		if err != nil {
+			return err
+		}
+		checker = &proofread.Grammar{
+			Generator:   gen,
+			Language:    language,
+			Prompt:      prompt,
+			BatchSize:   batchSize,
+			Concurrency: concurrency,
+			OnBatch:     display.Add,
+		}
+	default:
+		return inputErrorf("unsupported checker %q: use %s or %s", checkerName, checkerHunspell, checkerLLM)
+	}
+
+	subFile, err := subtitle.Open(subtitlePath)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, fmt.Errorf("failed to parse subtitle file: %w", err))
+	}
+	if preserve {
+		subtitle.PreserveFormatting(subFile)
+	}
+	entries := len(subFile.Subtitle().Entries)
+
+	logger.Infow("Proofreading subtitles", "subtitles", subtitlePath, "checker", checkerName, "entries", entries)
+	display.Stage("Proofreading", entries)
+	changes, err := proofread.Proofread(ctx, checker, subFile)
+	if err != nil {
+		return err
+	}
+	display.Done()
+
+	if err := subFile.Write(outputPath); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	var diff bytes.Buffer
+	if err := proofread.WriteDiff(&diff, subtitlePath, outputPath, changes); err != nil {
+		return err
+	}
+	if err := os.WriteFile(reportPath, diff.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	result := map[string]any{
+		"output":  absPath(outputPath),
+		"report":  absPath(reportPath),
+		"entries": entries,
+		"changed": len(changes),
+	}
+	report(result, func() {
+		fmt.Printf("Proofread subtitles: %s\n", absPath(outputPath))
+		fmt.Printf("  Changed: %d of %d cues\n", len(changes), entries)
+		fmt.Printf("  Report: %s\n", absPath(reportPath))
+	})
+	return nil
+}
+
+// regions of the dictionaries hunspell packages install for a language
+var hunspellRegions = map[string]string{
+	"en": "US", "de": "DE", "fr": "FR", "es": "ES", "it": "IT",
+	"pt": "BR", "nl": "NL", "ru": "RU", "pl": "PL", "sv": "SE",
+	"da": "DK", "nb": "NO", "fi": "FI", "cs": "CZ", "hu": "HU",
+	"ro": "RO", "tr": "TR", "uk": "UA", "el": "GR", "bg": "BG",
+}
+
+// hunspell dictionary for a language name or code: en -> en_US, and
+// pt-PT -> pt_PT
+func hunspellDictionary(language string) string {
+	code := video.ShortLanguageCode(language)
+	if code == "" {
+		return ""
+	}
+	if strings.ContainsAny(code, "-_") {
+		lang, region, _ := strings.Cut(strings.ReplaceAll(code, "-", "_"), "_")
+		return strings.ToLower(lang) + "_" + strings.ToUpper(region)
+	}
+	if region, ok := hunspellRegions[code]; ok {
+		return code + "_" + region
+	}
+	return code
+}
@@ -0,0 +1,22 @@
+package cli
+
+import "testing"
+
+func TestHunspellDictionary(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{"", ""},
+		{"en", "en_US"},
+		{"german", "de_DE"},
+		{"pt-PT", "pt_PT"},
+		{"en_gb", "en_GB"},
+		{"eo", "eo"},
+	}
+	for _, tt := range tests {
+		if got := hunspellDictionary(tt.language); got != tt.want {
+			t.Errorf("hunspellDictionary(%q) = %q, want %q", tt.language, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/review"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/video"
+	"github.com/spf13/cobra"
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render [subtitle_file]",
+	Short: "Render the subtitles shown at a time into a preview image",
+	Long: `Render the subtitles shown at --at into a PNG image, styled as they are
+when burned in: drawn by ffmpeg's libass renderer over the video frame at
+that time, or over a black canvas without --video. Use it to check fonts,
+positions, and colors without burning in the whole video.
+
+The canvas is the script resolution (PlayResX and PlayResY) of an ASS file,
+else 1920x1080; --size overrides it. With a Matroska --video, the fonts
+attached to it are used as they are when it plays.
+
+--at takes hh:mm:ss[.ms], mm:ss, seconds, or a duration such as 5m12s.
+The image is written to -o, by default next to the subtitle file.
+
+Examples:
+  lipi render subs.ass --at 00:05:12 -o frame.png
+  lipi render subs.ass --at 5:12.5 --video episode.mkv
+  lipi render subs.srt --at 90 --size 1280x720 --fonts-dir ./fonts`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSubtitleFiles,
+	RunE:              runRender,
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+
+	renderCmd.Flags().
+		String("at", "", "Time to render the subtitles at (hh:mm:ss[.ms], mm:ss, seconds, or 5m12s)")
+	renderCmd.Flags().
+		String("video", "", "Video to draw the subtitles over, at the frame of --at (default: a black canvas)")
+	renderCmd.Flags().
+		String("size", "", "Canvas size without --video, as WIDTHxHEIGHT (default: the script resolution, else 1920x1080)")
+	renderCmd.Flags().
+		String("fonts-dir", "", "Directory of more fonts for the renderer")
+
+	_ = renderCmd.MarkFlagRequired("at")
+}
+
+// render as reported by --json
+type renderReport struct {
+	Output string `json:"output"`
+	At     string `json:"at"`
+	// text of the cues shown at the time
+	Cues []string `json:"cues"`
+}
+
+func runRender(cmd *cobra.Command, args []string) error {
+	subtitlePath := args[0]
+	atFlag, _ := cmd.Flags().GetString("at")
+	videoPath, _ := cmd.Flags().GetString("video")
+	size, _ := cmd.Flags().GetString("size")
+	fontsDir, _ := cmd.Flags().GetString("fonts-dir")
+	outputPath, _ := cmd.Flags().GetString("output")
+
+	at, err := parseTimestamp(atFlag)
+	if err != nil {
+		return inputErrorf("invalid --at: %v", err)
+	}
+	if size != "" && videoPath != "" {
+		return inputErrorf("--size sets the canvas without --video; the video frame keeps its own size")
+	}
+	if _, err := os.Stat(subtitlePath); os.IsNotExist(err) {
+		return inputErrorf("subtitle file not found: %s", subtitlePath)
+	}
+	if videoPath != "" {
+		if _, err := os.Stat(videoPath); os.IsNotExist(err) {
+			return inputErrorf("video file not found: %s", videoPath)
+		}
+	}
+	if outputPath == "" {
+		outputPath = strings.TrimSuffix(subtitlePath, filepath.Ext(subtitlePath)) + ".png"
+	}
+
+	f, err := subtitle.Open(subtitlePath)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, fmt.Errorf("failed to parse subtitle file: %w", err))
+	}
+	opts := video.RenderOptions{VideoPath: videoPath, FontsDir: fontsDir}
+	if size != "" {
+		if opts.Width, opts.Height, err = parseSize(size); err != nil {
+			return inputErrorf("invalid --size: %v", err)
+		}
+	} else if ass, ok := f.(*subtitle.ASSFile); ok {
+		opts.Width, opts.Height = ass.PlayRes()
+	}
+
+	cues := cuesAt(f.Subtitle(), at)
+	if len(cues) == 0 {
+		logger.Warnw("No cue is shown at this time", "at", review.FormatTimestamp(at))
+	}
+
+	logger.Infow("Rendering subtitles", "subtitles", subtitlePath, "at", review.FormatTimestamp(at), "output", outputPath)
+	if err := video.NewProcessor("").RenderFrame(cmd.Context(), subtitlePath, outputPath, at, opts); err != nil {
+		return fmt.Errorf("render failed: %w", err)
+	}
+
+	report(renderReport{
+		Output: absPath(outputPath),
+		At:     review.FormatTimestamp(at),
+		Cues:   cues,
+	}, func() {
+		fmt.Printf("Rendered %d cues at %s: %s\n", len(cues), review.FormatTimestamp(at), absPath(outputPath))
+	})
+	return nil
+}
+
+// the text of the cues on screen at the time
+func cuesAt(sub *subtitle.Subtitle, at time.Duration) []string {
+	cues := []string{}
+	for _, e := range sub.Entries {
+		if e.StartTime <= at && at < e.EndTime {
+			cues = append(cues, e.Text)
+		}
+	}
+	return cues
+}
+
+// parses hh:mm:ss[.ms], mm:ss[.ms], plain seconds, or a Go duration
+func parseTimestamp(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty time")
+	}
+	if d, err := time.ParseDuration(s); err == nil && strings.ContainsAny(s, "hms") {
+		if d < 0 {
+			return 0, fmt.Errorf("negative time %q", s)
+		}
+		return d, nil
+	}
+	parts := strings.Split(strings.Replace(s, ",", ".", 1), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("%q is not hh:mm:ss", s)
+	}
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil || math.IsNaN(seconds) || seconds < 0 || seconds > math.MaxInt32 ||
+		len(parts) > 1 && seconds >= 60 {
+		return 0, fmt.Errorf("%q is not hh:mm:ss", s)
+	}
+	d := time.Duration(math.Round(seconds*1000)) * time.Millisecond
+	for i, unit := range []time.Duration{time.Minute, time.Hour} {
+		at := len(parts) - 2 - i
+		if at < 0 {
+			break
+		}
+		n, err := strconv.Atoi(parts[at])
+		if err != nil || n < 0 || i == 0 && len(parts) == 3 && n >= 60 {
+			return 0, fmt.Errorf("%q is not hh:mm:ss", s)
+		}
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
+
+// parses WIDTHxHEIGHT
+func parseSize(s string) (int, int, error) {
+	w, h, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	width, errW := strconv.Atoi(w)
+	height, errH := strconv.Atoi(h)
+	if !ok || errW != nil || errH != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("%q is not WIDTHxHEIGHT", s)
+	}
+	return width, height, nil
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "00:05:12", want: 5*time.Minute + 12*time.Second},
+		{in: "1:02:03.250", want: time.Hour + 2*time.Minute + 3250*time.Millisecond},
+		{in: "5:12.5", want: 5*time.Minute + 12500*time.Millisecond},
+		{in: "00:00:01,5", want: 1500 * time.Millisecond},
+		{in: "90", want: 90 * time.Second},
+		{in: "5m12s", want: 5*time.Minute + 12*time.Second},
+		{in: "", wantErr: true},
+		{in: "5:75", wantErr: true},
+		{in: "1:60:00", wantErr: true},
+		{in: "-3", wantErr: true},
+		{in: "soon", wantErr: true},
+		{in: "1:2:3:4", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimestamp(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseTimestamp(%q) = %v, %v, want %v (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	if w, h, err := parseSize("1280X720"); err != nil || w != 1280 || h != 720 {
+		t.Errorf("parseSize() = %d, %d, %v", w, h, err)
+	}
+	for _, in := range []string{"1280", "x720", "0x720", "wide"} {
+		if _, _, err := parseSize(in); err == nil {
+			t.Errorf("parseSize(%q) succeeded", in)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/httpclient"
+	"github.com/mgpai22/lipi/internal/llm"
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/middleware"
+	"github.com/mgpai22/lipi/internal/transcribe"
+	"github.com/mgpai22/lipi/internal/translate"
+	"github.com/spf13/cobra"
+)
+
+// how long providers get to release connections and uploads once a run ends
+const providerShutdownTimeout = 15 * time.Second
+
+// shuts a provider down with its own deadline, so cleanup still happens
+// after the run's context was cancelled; failures are only logged
+func shutdownProvider(log *logging.Logger, shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), providerShutdownTimeout)
+	defer cancel()
+	if err := shutdown(ctx); err != nil && log != nil {
+		log.Warnw("Failed to shut down provider", "error", err)
+	}
+}
+
+// providers that call out to a hosted API
+var remoteProviders = map[string]bool{
+	"gemini":     true,
+	"openai":     true,
+	"anthropic":  true,
+	"groq":       true,
+	"elevenlabs": true,
+}
+
+// fails fast when offline mode is on and provider needs the network;
+// local and mock providers pass
+func refuseOfflineProvider(provider string) error {
+	if !remoteProviders[provider] {
+		return nil
+	}
+	return httpclient.Refuse(fmt.Sprintf("use the %s provider (only local providers work offline)", provider))
+}
+
+// how provider requests are retried, rate limited, and cached; shared by
+// all files of a run
+type requestSettings struct {
+	retries   int
+	rateLimit *middleware.RateLimiter
+	cache     *middleware.Cache
+	dump      *middleware.Dump
+}
+
+func addRequestFlags(cmd *cobra.Command) {
+	cmd.Flags().
+		Int("retries", 2, "Retry failed provider requests this many times with exponential backoff")
+	cmd.Flags().
+		Int("rate-limit", 0, "Maximum provider requests per minute across all workers (0: unlimited)")
+	cmd.Flags().
+		String("cache-dir", "", "Directory to cache provider results in, so re-runs with the same settings skip finished requests")
+	cmd.Flags().
+		String("debug-dump", "", "Directory to save every provider prompt, raw response, and parse outcome in, one JSON file per request")
+}
+
+func newRequestSettings(cmd *cobra.Command) (requestSettings, error) {
+	retries, _ := cmd.Flags().GetInt("retries")
+	rateLimit, _ := cmd.Flags().GetInt("rate-limit")
+	cacheDir, _ := cmd.Flags().GetString("cache-dir")
+	dumpDir, _ := cmd.Flags().GetString("debug-dump")
+
+	if retries < 0 {
+		return requestSettings{}, inputErrorf("retries must not be negative, got %d", retries)
+	}
+	if rateLimit < 0 {
+		return requestSettings{}, inputErrorf("rate-limit must not be negative, got %d", rateLimit)
+	}
+
+	settings := requestSettings{
+		retries:   retries,
+		rateLimit: middleware.NewRateLimiter(rateLimit),
+	}
+	if cacheDir != "" {
+		settings.cache = middleware.NewCache(expandHome(cacheDir))
+	}
+	if dumpDir != "" {
+		settings.dump = middleware.NewDump(expandHome(dumpDir))
+	}
+	return settings, nil
+}
+
+func (r requestSettings) retryPolicy() middleware.RetryPolicy {
+	return middleware.RetryPolicy{
+		Attempts:  r.retries,
+		Retryable: retryableError,
+	}
+}
+
+func (r requestSettings) applyTranscribe(opts *transcribe.Options, log *logging.Logger) {
+	opts.Retry = r.retryPolicy()
+	opts.RateLimit = r.rateLimit
+	opts.Cache = r.cache
+	opts.Dump = r.dump
+	opts.Logger = log
+}
+
+func (r requestSettings) applyTranslate(opts *translate.Options, log *logging.Logger) {
+	opts.Retry = r.retryPolicy()
+	opts.RateLimit = r.rateLimit
+	opts.Cache = r.cache
+	opts.Dump = r.dump
+	opts.Logger = log
+}
+
+func (r requestSettings) applyLLM(opts *llm.Options) {
+	opts.Retry = r.retryPolicy()
+	opts.RateLimit = r.rateLimit
+	opts.Dump = r.dump
+}
+
+// whether another attempt could succeed: not for bad input, rejected keys,
+// or requests the provider refused outright
+func retryableError(err error) bool {
+	if !middleware.DefaultRetryable(err) {
+		return false
+	}
+	switch errorKind(err) {
+	case errs.KindInput, errs.KindAuth:
+		return false
+	}
+	status := errs.ProviderStatus(err)
+	switch {
+	case status == http.StatusTooManyRequests, status == http.StatusRequestTimeout:
+		return true
+	case status >= 400 && status < 500:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+func TestRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"network", errors.New("connection reset"), true},
+		{"canceled", fmt.Errorf("chunk 1: %w", context.Canceled), false},
+		{"missing key", missingAPIKeyError("GEMINI_API_KEY"), false},
+		{"rate limited", &openai.Error{StatusCode: 429}, true},
+		{"server error", &openai.Error{StatusCode: 503}, true},
+		{"bad request", &openai.Error{StatusCode: 400}, false},
+		{"unauthorized", &openai.Error{StatusCode: 401}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryableError(tt.err); got != tt.want {
+				t.Errorf("retryableError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/glossary"
+	"github.com/mgpai22/lipi/internal/review"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/translate"
+	"github.com/spf13/cobra"
+)
+
+var reviewCmd = &cobra.Command{
+	Use:   "review [subtitle_file]",
+	Short: "Step through subtitles to check and fix them interactively",
+	Long: `Open a subtitle file in an interactive review session that steps
+through the cues one at a time. For each cue you can play the matching audio
+from --media (requires ffplay), edit the text or timing, and, with
+--target-language, ask the provider for a fresh translation of just that cue.
+
+Retranslation reads the original text from --source, or from the file the
+translated name was derived from (video.ja.srt -> video.srt) when it exists;
+otherwise the cue's current text is retranslated.
+
+Edits are written back to the subtitle file (or to --output) when you save
+with "w" or confirm on quit. Type "h" in the session for the command list.
+
+Examples:
+  lipi review video.srt --media video.mp4
+  lipi review video.ja.srt --media video.mp4 --target-language ja
+  lipi review episode.es.vtt -t es --source episode.vtt --provider anthropic`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSubtitleFiles,
+	RunE:              runReview,
+}
+
+func init() {
+	rootCmd.AddCommand(reviewCmd)
+
+	reviewCmd.Flags().
+		StringP("media", "m", "", "Audio or video file the subtitles belong to, for cue playback")
+	reviewCmd.Flags().
+		StringP("target-language", "t", "", "Language of the subtitles, enables retranslating single cues")
+	reviewCmd.Flags().
+		String("source", "", "Original subtitles to retranslate from (default: guessed from the file name)")
+	reviewCmd.Flags().
+		StringP("api-key", "k", "", "API key (or set GEMINI_API_KEY/OPENAI_API_KEY/ANTHROPIC_API_KEY env var)")
+	reviewCmd.Flags().
+		String("model", "", "Model to use for translation (provider-specific, uses sensible defaults)")
+	reviewCmd.Flags().
+		Bool("model-override", false, "Allow any custom model, bypassing provider model validation")
+	reviewCmd.Flags().
+		String("provider", "gemini", "Translation provider (gemini, openai, anthropic)")
+	reviewCmd.Flags().
+		String("glossary", "", "File of terms to translate consistently (\"term\" or \"term = translation\" per line)")
+	reviewCmd.Flags().
+		String("prompt", "", "Additional instructions for the translation model")
+
+	mustRegisterCompletion(reviewCmd, "media", completeMediaFiles)
+	registerTranslateCompletions(reviewCmd, "provider", "model")
+}
+
+func runReview(cmd *cobra.Command, args []string) error {
+	subtitlePath := args[0]
+	ctx := cmd.Context()
+
+	mediaPath, _ := cmd.Flags().GetString("media")
+	targetLang, _ := cmd.Flags().GetString("target-language")
+	sourcePath, _ := cmd.Flags().GetString("source")
+	outputPath, _ := cmd.Flags().GetString("output")
+
+	if _, err := os.Stat(subtitlePath); os.IsNotExist(err) {
+		return inputErrorf("subtitle file not found: %s", subtitlePath)
+	}
+	subFile, err := subtitle.Open(subtitlePath)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, fmt.Errorf("failed to parse subtitle file: %w", err))
+	}
+	if outputPath == "" {
+		outputPath = subtitlePath
+	}
+
+	var opts review.Options
+	if mediaPath != "" {
+		if _, err := os.Stat(mediaPath); os.IsNotExist(err) {
+			return inputErrorf("media file not found: %s", mediaPath)
+		}
+		player, err := review.FFplay(mediaPath)
+		if err != nil {
+			return err
+		}
+		opts.Play = player
+	}
+
+	if targetLang != "" {
+		if sourcePath == "" {
+			sourcePath = guessSourceSubtitles(subtitlePath, targetLang)
+		}
+		retranslate, shutdown, err := newCueRetranslator(ctx, cmd, subFile, sourcePath)
+		if err != nil {
+			return err
+		}
+		defer shutdownProvider(logger, shutdown)
+		opts.Retranslate = retranslate
+	}
+
+	session := review.NewSession(subFile, outputPath, os.Stdin, os.Stdout, opts)
+	return session.Run(ctx)
+}
+
+// builds the retranslate callback: the cue's text in the source file, or
+// its current text without one, is sent to the provider on its own. The
+// returned shutdown releases the translator once the session ends.
+func newCueRetranslator(
+	ctx context.Context,
+	cmd *cobra.Command,
+	subFile subtitle.File,
+	sourcePath string,
+) (review.Retranslator, func(context.Context) error, error) {
+	targetLang, _ := cmd.Flags().GetString("target-language")
+	apiKey, _ := cmd.Flags().GetString("api-key")
+	model, _ := cmd.Flags().GetString("model")
+	modelOverride, _ := cmd.Flags().GetBool("model-override")
+	providerStr, _ := cmd.Flags().GetString("provider")
+	inputLang, _ := cmd.Flags().GetString("language")
+	glossaryPath, _ := cmd.Flags().GetString("glossary")
+	prompt, _ := cmd.Flags().GetString("prompt")
+
+	cfg := &translateConfig{
+		targetLang:    targetLang,
+		inputLang:     inputLang,
+		provider:      translate.Provider(providerStr),
+		apiKey:        apiKey,
+		model:         model,
+		modelOverride: modelOverride,
+		concurrency:   1,
+		batchSize:     1,
+		prompt:        prompt,
+	}
+	if glossaryPath != "" {
+		terms, err := glossary.Load(expandHome(glossaryPath))
+		if err != nil {
+			return nil, nil, errs.Wrap(errs.KindInput, err)
+		}
+		cfg.glossary = terms
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var source []subtitle.Entry
+	if sourcePath != "" {
+		sourceFile, err := subtitle.Open(sourcePath)
+		if err != nil {
+			return nil, nil, errs.Wrap(errs.KindInput, fmt.Errorf("failed to parse source subtitles: %w", err))
+		}
+		source = sourceFile.Subtitle().Entries
+		if len(source) != len(subFile.Subtitle().Entries) {
+			return nil, nil, inputErrorf(
+				"source subtitles %s have %d cues, %d expected",
+				sourcePath,
+				len(source),
+				len(subFile.Subtitle().Entries),
+			)
+		}
+	}
+
+	translator, err := translate.Factory(ctx, cfg.provider, cfg.apiKey, translate.Options{
+		InputLanguage:  cfg.inputLang,
+		TargetLanguage: cfg.targetLang,
+		Model:          cfg.model,
+		Prompt:         cfg.prompt,
+		Glossary:       cfg.glossary,
+		BatchSize:      cfg.batchSize,
+		Usage:          runUsage,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create translator: %w", err)
+	}
+
+	retranslate := func(ctx context.Context, index int) (string, error) {
+		text := subFile.Subtitle().Entries[index].Text
+		if source != nil {
+			text = source[index].Text
+		}
+		results, err := translator.Translate(ctx, []translate.TranslationItem{
+			{Index: index, Text: text},
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(results) == 0 {
+			return "", fmt.Errorf("provider returned no translation")
+		}
+		return results[0].Text, nil
+	}
+	shutdown := func(ctx context.Context) error {
+		return translate.Shutdown(ctx, translator)
+	}
+	return retranslate, shutdown, nil
+}
+
+// original subtitles for a translated file named like translate writes
+// them (video.ja.srt for video.srt), if present
+func guessSourceSubtitles(subtitlePath, targetLang string) string {
+	ext := filepath.Ext(subtitlePath)
+	base := strings.TrimSuffix(subtitlePath, ext)
+	suffix := "." + targetLang
+	if !strings.HasSuffix(strings.ToLower(base), strings.ToLower(suffix)) {
+		return ""
+	}
+	candidate := base[:len(base)-len(suffix)] + ext
+	if _, err := os.Stat(candidate); err != nil {
+		return ""
+	}
+	return candidate
+}
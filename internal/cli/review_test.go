@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGuessSourceSubtitles(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "video.srt")
+	if err := os.WriteFile(original, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path       string
+		targetLang string
+		want       string
+	}{
+		{filepath.Join(dir, "video.ja.srt"), "ja", original},
+		{filepath.Join(dir, "video.JA.srt"), "ja", original},
+		{filepath.Join(dir, "video.es.srt"), "ja", ""},
+		{filepath.Join(dir, "other.ja.srt"), "ja", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.path), func(t *testing.T) {
+			if got := guessSourceSubtitles(tt.path, tt.targetLang); got != tt.want {
+				t.Errorf("guessSourceSubtitles(%q, %q) = %q, want %q", tt.path, tt.targetLang, got, tt.want)
+			}
+		})
+	}
+}
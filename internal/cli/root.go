@@ -1,13 +1,36 @@
 package cli
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/mgpai22/lipi/internal/config"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/ffmpeg"
+	"github.com/mgpai22/lipi/internal/fonts"
+	"github.com/mgpai22/lipi/internal/httpclient"
 	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/source"
+	"github.com/mgpai22/lipi/internal/subtitle"
 	"github.com/spf13/cobra"
 )
 
 var (
-	verbose bool
-	logger  *logging.Logger
+	verbose            bool
+	configPath         string
+	profile            string
+	proxyURL           string
+	insecureSkipVerify bool
+	offlineMode        bool
+	ffmpegRelease      ffmpeg.Release
+	lineEndings        string
+	writeBOM           bool
+	logger             *logging.Logger
 )
 
 var rootCmd = &cobra.Command{
@@ -17,19 +40,158 @@ var rootCmd = &cobra.Command{
 subtitles for video files.
 
 It supports multiple transcription providers and subtitle formats.`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		logger = logging.NewLogger(verbose)
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(cmd); err != nil {
+			return errs.Wrap(errs.KindInput, err)
+		}
+		client, err := httpclient.New(proxyURL)
+		if err != nil {
+			return errs.Wrap(errs.KindInput, err)
+		}
+		vcrMode := os.Getenv(httpclient.VCREnv)
+		if vcrMode != "" {
+			if client, err = httpclient.WithVCR(client, vcrMode, vcrDir()); err != nil {
+				return errs.Wrap(errs.KindInput, err)
+			}
+		}
+		httpclient.SetDefault(client)
+		if err := ffmpeg.SetRelease(ffmpegRelease); err != nil {
+			return errs.Wrap(errs.KindInput, err)
+		}
+		ffmpeg.SetInsecureSkipVerify(insecureSkipVerify)
+		source.SetInsecureSkipVerify(insecureSkipVerify)
+		fonts.SetInsecureSkipVerify(insecureSkipVerify)
+		if err := subtitle.SetOutputStyle(subtitle.OutputStyle{
+			LineEndings: subtitle.LineEndings(strings.ToLower(lineEndings)),
+			BOM:         writeBOM,
+		}); err != nil {
+			return errs.Wrap(errs.KindInput, err)
+		}
+		httpclient.SetOffline(offlineMode)
+		// --json keeps stdout for the result document, and piped
+		// subtitles keep it for the data
+		logOutput := os.Stdout
+		if jsonOutput || writesSubtitlesToStdout(cmd, args) {
+			logOutput = os.Stderr
+		}
+		logger = logging.NewLogger(verbose, logOutput)
+		if vcrMode != "" {
+			logger.Warnw("Provider requests go through the VCR", "mode", vcrMode, "dir", vcrDir())
+		}
+		if runNotify, err = notifyTargets(cmd); err != nil {
+			return err
+		}
+		runArgs = args
+		return openLogFile(cmd, args)
 	},
 }
 
+// Execute runs the root command. The first Ctrl-C (or SIGTERM) cancels the
+// command's context so ffmpeg and provider calls stop and temp directories
+// are removed on the way out; a second one exits immediately.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	go func() {
+		<-signals
+		_, _ = fmt.Fprintln(
+			os.Stderr,
+			"Interrupted, cleaning up (press Ctrl-C again to quit immediately)",
+		)
+		cancel()
+		<-signals
+		os.Exit(130)
+	}()
+
+	tagUsageErrors(rootCmd)
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	notifyCompletion(cmd, err)
+	closeLogFile(cmd, err)
+	if jsonOutput {
+		if writeErr := writeJSONReport(os.Stdout, cmd, err); writeErr != nil &&
+			err == nil {
+			err = writeErr
+		}
+	}
+	return err
+}
+
+// directory of VCR recordings: $LIPI_VCR_DIR, or vcr in the cache dir
+func vcrDir() string {
+	if dir := os.Getenv(httpclient.VCRDirEnv); dir != "" {
+		return dir
+	}
+	return filepath.Join(config.CacheDir(), "vcr")
+}
+
+// reports whether the command writes subtitle data to stdout
+func writesSubtitlesToStdout(cmd *cobra.Command, args []string) bool {
+	if cmd != translateCmd || len(args) == 0 {
+		return false
+	}
+	output, _ := cmd.Flags().GetString("output")
+	return translateToStdout(args[0], output)
+}
+
+// marks flag and argument errors from cobra as input errors
+func tagUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return errs.Wrap(errs.KindInput, err)
+	})
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			return errs.Wrap(errs.KindInput, validate(cmd, args))
+		}
+	}
+	for _, child := range cmd.Commands() {
+		tagUsageErrors(child)
+	}
 }
 
 func init() {
 	rootCmd.PersistentFlags().
 		BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().
+		BoolVar(&jsonOutput, "json", false, "Print a JSON result on stdout and send logs to stderr")
+	rootCmd.PersistentFlags().
+		StringVar(&logFilePath, "log-file", "", "Also write logs to this file as JSON lines")
+	rootCmd.PersistentFlags().
+		IntVar(&logMaxSize, "log-max-size", 100, "Rotate --log-file once it reaches this many megabytes (0: never)")
+	rootCmd.PersistentFlags().
+		IntVar(&logMaxBackups, "log-max-backups", 5, "Rotated log files to keep")
+	rootCmd.PersistentFlags().
+		StringVar(&configPath, "config", "", "Config file (default: ~/.config/lipi/config.yaml)")
+	rootCmd.PersistentFlags().
+		StringVarP(&profile, "profile", "P", "", "Named profile from the config file")
+	rootCmd.PersistentFlags().
+		StringVar(&proxyURL, "proxy", "", "Proxy URL for provider requests and downloads (default: HTTPS_PROXY/HTTP_PROXY)")
+	rootCmd.PersistentFlags().
+		BoolVar(&offlineMode, "offline", false, "Refuse all network access: no downloads, remote media, or hosted providers")
+	rootCmd.PersistentFlags().
+		StringVar(&ffmpegRelease.BaseURL, "ffmpeg-mirror", "", "Mirror of the ffbinaries releases to download ffmpeg from")
+	rootCmd.PersistentFlags().
+		StringVar(&ffmpegRelease.Version, "ffmpeg-version", "", "ffbinaries release of ffmpeg to download (default: the pinned one)")
+	rootCmd.PersistentFlags().
+		StringVar(&ffmpegRelease.URL, "ffmpeg-url", "", "Archive (.zip, .tar.gz, .tar.xz) with ffmpeg and ffprobe to download instead")
+	rootCmd.PersistentFlags().
+		StringVar(&ffmpegRelease.SHA256, "ffmpeg-sha256", "", "SHA-256 checksum of the --ffmpeg-url archive")
+	rootCmd.PersistentFlags().
+		BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "Use downloaded ffmpeg and yt-dlp builds and fonts even without a matching checksum")
+	rootCmd.PersistentFlags().
+		StringVar(&lineEndings, "line-endings", "", "Line endings of written subtitle files: lf or crlf (default: lf, or as read with --preserve-formatting)")
+	rootCmd.PersistentFlags().
+		BoolVar(&writeBOM, "bom", false, "Start written subtitle files with a UTF-8 byte order mark")
 	rootCmd.PersistentFlags().StringP("output", "o", "", "Output file path")
 	rootCmd.PersistentFlags().
 		StringP("language", "l", "", "Language code (e.g., en, es, fr)")
+
+	mustRegisterCompletion(rootCmd, "line-endings", completeValues(
+		string(subtitle.LineEndingsLF),
+		string(subtitle.LineEndingsCRLF),
+	))
 }
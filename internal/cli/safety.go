@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Gemini harm categories by their --safety name
+var safetyCategories = map[string]string{
+	"harassment":        "HARM_CATEGORY_HARASSMENT",
+	"hate-speech":       "HARM_CATEGORY_HATE_SPEECH",
+	"sexually-explicit": "HARM_CATEGORY_SEXUALLY_EXPLICIT",
+	"dangerous-content": "HARM_CATEGORY_DANGEROUS_CONTENT",
+	"civic-integrity":   "HARM_CATEGORY_CIVIC_INTEGRITY",
+}
+
+// Gemini block thresholds by their --safety name
+var safetyThresholds = map[string]string{
+	"off":       "OFF",
+	"none":      "BLOCK_NONE",
+	"only-high": "BLOCK_ONLY_HIGH",
+	"medium":    "BLOCK_MEDIUM_AND_ABOVE",
+	"low":       "BLOCK_LOW_AND_ABOVE",
+}
+
+// registers --safety with value completion
+func addSafetyFlag(cmd *cobra.Command) {
+	cmd.Flags().
+		StringSlice("safety", nil, "Gemini safety filter thresholds: off, none, only-high, medium, or low for every category, or category=threshold for one of harassment, hate-speech, sexually-explicit, dangerous-content, civic-integrity (default: API defaults)")
+	mustRegisterCompletion(cmd, "safety", completeValues("off", "none", "only-high", "medium", "low"))
+}
+
+// reads --safety into the API's block threshold by harm category; a bare
+// threshold sets every category, and later entries override earlier ones
+func newSafetyThresholds(cmd *cobra.Command, provider string) (map[string]string, error) {
+	entries, _ := cmd.Flags().GetStringSlice("safety")
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	if provider != "gemini" {
+		return nil, inputErrorf("--safety requires the gemini provider, got %s", provider)
+	}
+	thresholds := make(map[string]string)
+	for _, entry := range entries {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			name, value = "", name
+		}
+		threshold, ok := safetyThresholds[strings.TrimSpace(value)]
+		if !ok {
+			return nil, inputErrorf(
+				"invalid --safety %q: use off, none, only-high, medium, or low", entry,
+			)
+		}
+		if name == "" {
+			for _, category := range safetyCategories {
+				thresholds[category] = threshold
+			}
+			continue
+		}
+		category, ok := safetyCategories[strings.TrimSpace(name)]
+		if !ok {
+			return nil, inputErrorf(
+				"invalid --safety category %q: use harassment, hate-speech, sexually-explicit, dangerous-content, or civic-integrity",
+				name,
+			)
+		}
+		thresholds[category] = threshold
+	}
+	return thresholds, nil
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestNewSafetyThresholds(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		safety   string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "unset",
+			provider: "gemini",
+		},
+		{
+			name:     "every category",
+			provider: "gemini",
+			safety:   "none",
+			want: map[string]string{
+				"HARM_CATEGORY_HARASSMENT":        "BLOCK_NONE",
+				"HARM_CATEGORY_HATE_SPEECH":       "BLOCK_NONE",
+				"HARM_CATEGORY_SEXUALLY_EXPLICIT": "BLOCK_NONE",
+				"HARM_CATEGORY_DANGEROUS_CONTENT": "BLOCK_NONE",
+				"HARM_CATEGORY_CIVIC_INTEGRITY":   "BLOCK_NONE",
+			},
+		},
+		{
+			name:     "later entries override",
+			provider: "gemini",
+			safety:   "only-high,hate-speech=low",
+			want: map[string]string{
+				"HARM_CATEGORY_HARASSMENT":        "BLOCK_ONLY_HIGH",
+				"HARM_CATEGORY_HATE_SPEECH":       "BLOCK_LOW_AND_ABOVE",
+				"HARM_CATEGORY_SEXUALLY_EXPLICIT": "BLOCK_ONLY_HIGH",
+				"HARM_CATEGORY_DANGEROUS_CONTENT": "BLOCK_ONLY_HIGH",
+				"HARM_CATEGORY_CIVIC_INTEGRITY":   "BLOCK_ONLY_HIGH",
+			},
+		},
+		{
+			name:     "one category",
+			provider: "gemini",
+			safety:   "sexually-explicit=off",
+			want:     map[string]string{"HARM_CATEGORY_SEXUALLY_EXPLICIT": "OFF"},
+		},
+		{
+			name:     "unknown threshold",
+			provider: "gemini",
+			safety:   "harassment=some",
+			wantErr:  true,
+		},
+		{
+			name:     "unknown category",
+			provider: "gemini",
+			safety:   "violence=none",
+			wantErr:  true,
+		},
+		{
+			name:     "not gemini",
+			provider: "openai",
+			safety:   "none",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "translate"}
+			addSafetyFlag(cmd)
+			if tt.safety != "" {
+				if err := cmd.Flags().Set("safety", tt.safety); err != nil {
+					t.Fatalf("failed to set --safety: %v", err)
+				}
+			}
+
+			got, err := newSafetyThresholds(cmd, tt.provider)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newSafetyThresholds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "--safety") {
+				t.Errorf("error %q does not name the flag", err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("newSafetyThresholds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLLMSettings(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   string
+		flags      map[string]string
+		wantSafety bool
+		wantErr    bool
+	}{
+		{"gemini", "gemini", map[string]string{"safety": "none", "thinking-budget": "0"}, true, false},
+		{"safety ignored by anthropic", "anthropic", map[string]string{"safety": "none", "temperature": "0.5"}, false, false},
+		{"invalid safety", "anthropic", map[string]string{"safety": "everything"}, false, true},
+		{"temperature out of range", "anthropic", map[string]string{"temperature": "1.5"}, false, true},
+		{"thinking budget on openai", "openai", map[string]string{"thinking-budget": "0"}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "summarize"}
+			cmd.Flags().Float64("temperature", 0, "")
+			addDecodingFlags(cmd)
+			addSafetyFlag(cmd)
+			for name, value := range tt.flags {
+				if err := cmd.Flags().Set(name, value); err != nil {
+					t.Fatalf("failed to set --%s: %v", name, err)
+				}
+			}
+
+			got, err := newLLMSettings(cmd, tt.provider)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newLLMSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got.safety != nil) != tt.wantSafety {
+				t.Errorf("safety = %v, want set %v", got.safety, tt.wantSafety)
+			}
+		})
+	}
+}
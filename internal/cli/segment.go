@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/hls"
+	"github.com/mgpai22/lipi/internal/source"
+	"github.com/spf13/cobra"
+)
+
+var segmentCmd = &cobra.Command{
+	Use:   "segment [subtitle_file]",
+	Short: "Cut subtitles into segmented WebVTT with an HLS playlist",
+	Long: `Cut a subtitle file into WebVTT segments and write the m3u8 media playlist
+that lists them, ready to be added as the SUBTITLES rendition of an HLS
+stream:
+
+  video.m3u8         the subtitle playlist
+  video_00000.vtt    the first --segment-duration seconds of cues
+  video_00001.vtt    ...
+
+Segments are written next to the playlist. A cue that spans segments is
+repeated in each of them. Cues keep their timestamps, and each segment maps
+cue time 0 to the MPEG-TS timestamp --mpegts in its X-TIMESTAMP-MAP; set it
+to the timestamp the packaged video starts at so the captions stay in sync.
+
+Pass "-" to read subtitles from stdin, along with -o.
+
+Examples:
+  lipi segment subs.vtt --target hls --segment-duration 6
+  lipi segment episode.srt -o stream/subs/en.m3u8
+  lipi segment subs.vtt --mpegts 900000`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSubtitleFiles,
+	RunE:              runSegment,
+}
+
+func init() {
+	rootCmd.AddCommand(segmentCmd)
+
+	segmentCmd.Flags().
+		String("target", "hls", "Streaming format to segment for (hls)")
+	segmentCmd.Flags().
+		Float64("segment-duration", 6, "Seconds of subtitles in each segment; match the video segments")
+	segmentCmd.Flags().
+		Int64("mpegts", 0, "MPEG-TS timestamp (90 kHz) that cue time 0 maps to in each segment")
+
+	mustRegisterCompletion(segmentCmd, "target", completeValues("hls"))
+}
+
+// segmenting as reported by --json
+type segmentReport struct {
+	Playlist string `json:"playlist"`
+	Segments int    `json:"segments"`
+	Entries  int    `json:"entries"`
+}
+
+func runSegment(cmd *cobra.Command, args []string) error {
+	subtitlePath := args[0]
+	target, _ := cmd.Flags().GetString("target")
+	seconds, _ := cmd.Flags().GetFloat64("segment-duration")
+	mpegts, _ := cmd.Flags().GetInt64("mpegts")
+	playlistPath, _ := cmd.Flags().GetString("output")
+
+	if !strings.EqualFold(target, "hls") {
+		return inputErrorf("unsupported target %q: use hls", target)
+	}
+	if seconds <= 0 {
+		return inputErrorf("segment duration must be positive, got %g", seconds)
+	}
+	if mpegts < 0 {
+		return inputErrorf("mpegts must not be negative, got %d", mpegts)
+	}
+
+	if subtitlePath == source.Stdin {
+		if playlistPath == "" || playlistPath == source.Stdin {
+			return inputErrorf("-o with the playlist path is required when reading from stdin")
+		}
+	} else if _, err := os.Stat(subtitlePath); os.IsNotExist(err) {
+		return inputErrorf("subtitle file not found: %s", subtitlePath)
+	}
+	if playlistPath == "" {
+		playlistPath = strings.TrimSuffix(subtitlePath, filepath.Ext(subtitlePath)) + ".m3u8"
+	}
+
+	subFile, err := openSubtitles(subtitlePath)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, fmt.Errorf("failed to parse subtitle file: %w", err))
+	}
+	sub := subFile.Subtitle()
+	if len(sub.Entries) == 0 {
+		return inputErrorf("subtitle file contains no entries")
+	}
+
+	name := strings.TrimSuffix(filepath.Base(playlistPath), filepath.Ext(playlistPath))
+	segments, err := hls.Split(sub, hls.Options{
+		SegmentDuration: time.Duration(seconds * float64(time.Second)),
+		MPEGTS:          mpegts,
+		Name:            name,
+	})
+	if err != nil {
+		return errs.Wrap(errs.KindInput, err)
+	}
+
+	dir := filepath.Dir(playlistPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	for _, s := range segments {
+		if err := os.WriteFile(filepath.Join(dir, s.Name), s.Data, 0644); err != nil {
+			return fmt.Errorf("failed to write segment: %w", err)
+		}
+	}
+	if err := os.WriteFile(playlistPath, hls.Playlist(segments), 0644); err != nil {
+		return fmt.Errorf("failed to write playlist: %w", err)
+	}
+
+	report(segmentReport{
+		Playlist: playlistPath,
+		Segments: len(segments),
+		Entries:  len(sub.Entries),
+	}, func() {
+		fmt.Printf("Wrote %d segments of %d cues to %s\n", len(segments), len(sub.Entries), playlistPath)
+	})
+	return nil
+}
@@ -0,0 +1,313 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/config"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/jobs"
+	"github.com/mgpai22/lipi/internal/server"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/transcribe"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run lipi as an HTTP captioning service",
+	Long: `Run an HTTP API that accepts media uploads or URLs, queues them for
+subtitle generation, and serves the finished subtitles.
+
+Endpoints:
+  POST /v1/jobs                submit a job (multipart: "file" or "url",
+                               optional "format", "language", "transcript_language")
+  GET  /v1/jobs                list jobs
+  GET  /v1/jobs/{id}           poll job status
+  GET  /v1/jobs/{id}/subtitle  download the finished subtitle
+
+A browser UI for uploading media, following job progress, previewing cues
+against the uploaded audio/video, and downloading results is served at /.
+
+With --worker-token, 'lipi worker' processes on this or other machines pull
+queued jobs from the server and run ffmpeg and the provider calls
+themselves, next to the --workers this process runs (0 for none):
+  POST /v1/worker/claim                claim the next queued job
+  POST /v1/worker/jobs/{id}/heartbeat  renew the job's lease, report progress
+  POST /v1/worker/jobs/{id}/complete   upload the finished subtitle
+  POST /v1/worker/jobs/{id}/fail       report an error
+A job whose worker stops reporting for two minutes goes back to the queue.
+
+With --grpc-addr, the same jobs are also served over gRPC (service
+lipi.v1.JobService in api/lipi/v1/lipi.proto): SubmitJob streams an upload
+or sends a URL, StreamProgress follows a job until it finishes, and
+GetResult returns the subtitle.
+
+With --tokens-file, every /v1/jobs request and gRPC call needs an API token
+("Authorization: Bearer <token>"), each token only sees its own jobs, and a
+token's daily_audio_minutes and daily_tokens quotas refuse new jobs (429)
+once used up for the day (UTC). GET /v1/usage shows a token's usage today;
+the web UI signs in with a token. The worker token also works as an API
+token without quotas or job isolation. The file lists:
+  tokens:
+    - name: alice
+      token: <at least 16 characters>
+      daily_audio_minutes: 120
+      daily_tokens: 2000000
+
+GET /healthz answers 200 while the process serves, and GET /readyz 200
+while it accepts jobs (503 while draining or when the job store is
+unreachable). On SIGTERM or Ctrl-C the server drains: new jobs are refused,
+running ones get --drain-timeout to finish, and anything still running then
+goes back to the queue. Every flag can be set from the environment
+(LIPI_ADDR, LIPI_WORKERS, ...), and LIPI_CACHE_DIR moves all caches and the
+default --data-dir, for containers.
+
+Jobs are stored in an SQLite database under --data-dir and survive restarts;
+queued and interrupted jobs resume when the server starts again. Manage them
+with 'lipi jobs': a job retried or cancelled there is picked up or stopped by
+the running server. Generation flags (provider, model, chunking, etc.) set
+the defaults for every job.
+
+A submitted URL is only fetched from a public address: loopback,
+link-local, and private ones, such as cloud metadata endpoints, are refused
+unless --allow-private-urls is given.
+
+Examples:
+  lipi serve --addr :8080 --workers 2
+  lipi serve --workers 0 --worker-token "$TOKEN"
+  lipi serve --grpc-addr :9090
+  lipi serve --tokens-file tokens.yaml
+  curl -F file=@episode.mkv -F format=vtt http://localhost:8080/v1/jobs`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	addGenerateFlags(serveCmd)
+	serveCmd.Flags().
+		String("addr", ":8080", "Address to listen on")
+	serveCmd.Flags().
+		String("grpc-addr", "", "Also serve the gRPC API on this address, e.g. :9090")
+	serveCmd.Flags().
+		String("data-dir", "", "Directory for uploads, results, and the job store (default: user cache dir)")
+	serveCmd.Flags().
+		Int("workers", 1, "Number of jobs processed at the same time by this process (0: only remote workers)")
+	serveCmd.Flags().
+		String("worker-token", "", "Let 'lipi worker' processes with this token pull jobs (or set "+workerTokenEnv+")")
+	serveCmd.Flags().
+		String("tokens-file", "", "YAML file of API tokens with daily quotas; without it the API is open")
+	serveCmd.Flags().
+		Int("queue-size", 100, "Maximum number of jobs waiting in the queue")
+	serveCmd.Flags().
+		Duration("drain-timeout", 30*time.Second, "On SIGTERM, how long running jobs may take to finish before they are requeued")
+	serveCmd.Flags().
+		Bool("allow-private-urls", false, "Fetch submitted URLs of loopback, link-local, and private addresses")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	addr, _ := cmd.Flags().GetString("addr")
+	grpcAddr, _ := cmd.Flags().GetString("grpc-addr")
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	workers, _ := cmd.Flags().GetInt("workers")
+	queueSize, _ := cmd.Flags().GetInt("queue-size")
+	drainTimeout, _ := cmd.Flags().GetDuration("drain-timeout")
+	tokensFile, _ := cmd.Flags().GetString("tokens-file")
+	allowPrivateURLs, _ := cmd.Flags().GetBool("allow-private-urls")
+	workerToken := workerToken(cmd)
+
+	if workers < 0 || (workers == 0 && workerToken == "") {
+		return inputErrorf("workers must be positive, or 0 with --worker-token, got %d", workers)
+	}
+	if queueSize <= 0 {
+		return inputErrorf("queue size must be positive, got %d", queueSize)
+	}
+	if drainTimeout < 0 {
+		return inputErrorf("drain-timeout cannot be negative, got %s", drainTimeout)
+	}
+
+	var tokens []server.Token
+	if tokensFile != "" {
+		var err error
+		if tokens, err = server.LoadTokens(expandHome(tokensFile)); err != nil {
+			return errs.Wrap(errs.KindInput, err)
+		}
+	}
+
+	cfg, err := newGenerateConfig(cmd)
+	if err != nil {
+		return err
+	}
+	// concurrent jobs share one request budget, as the files of a batch do
+	cfg.limiter = transcribe.NewLimiter(batchRequestBudget(cfg))
+	cfg.publicURLsOnly = !allowPrivateURLs
+
+	dataDir = resolveDataDir(dataDir)
+	store, err := openJobStore(dataDir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+	queue := jobs.NewQueue(store, newJobRunner(cfg, store), workers, queueSize)
+
+	srv := server.New(
+		server.Config{
+			Addr:           addr,
+			MaxUploadBytes: cfg.maxInputBytes,
+			WorkerToken:    workerToken,
+			Tokens:         tokens,
+			// checked again when a job fetches the URL
+			AllowPrivateURLs: allowPrivateURLs,
+		},
+		store,
+		queue,
+	)
+
+	// SIGTERM or Ctrl-C cancels cmd.Context(), which starts the drain;
+	// runCtx interrupts what is left once it times out
+	runCtx, stopRun := context.WithCancel(context.WithoutCancel(cmd.Context()))
+	defer stopRun()
+	serveCtx, stopServe := context.WithCancel(runCtx)
+	defer stopServe()
+
+	queueDone := make(chan error, 1)
+	go func() {
+		queueDone <- queue.Run(runCtx)
+		stopServe()
+	}()
+	go drainOnSignal(cmd.Context(), serveCtx, queue, drainTimeout, stopRun)
+
+	grpcDone := make(chan error, 1)
+	if grpcAddr != "" {
+		go func() {
+			err := srv.ListenAndServeGRPC(serveCtx, grpcAddr)
+			if err != nil {
+				stopRun()
+			}
+			grpcDone <- err
+		}()
+	} else {
+		grpcDone <- nil
+	}
+
+	logger.Infow("Serving",
+		"addr", addr,
+		"grpc_addr", grpcAddr,
+		"data_dir", dataDir,
+		"workers", workers,
+		"remote_workers", workerToken != "",
+	)
+
+	err = srv.ListenAndServe(serveCtx)
+	stopRun()
+	if grpcErr := <-grpcDone; grpcErr != nil && (err == nil || errors.Is(err, http.ErrServerClosed)) {
+		err = grpcErr
+	}
+	if queueErr := <-queueDone; queueErr != nil && err == nil {
+		err = queueErr
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// once ctx is cancelled, drains queue: the API keeps answering, /readyz
+// reports 503, and running jobs get until timeout to finish before
+// interrupt requeues them. Returns early when serveCtx ends first.
+func drainOnSignal(
+	ctx, serveCtx context.Context,
+	queue *jobs.Queue,
+	timeout time.Duration,
+	interrupt context.CancelFunc,
+) {
+	select {
+	case <-ctx.Done():
+	case <-serveCtx.Done():
+		return
+	}
+	logger.Infow("Draining: finishing running jobs", "timeout", timeout)
+	queue.Drain()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-serveCtx.Done():
+	case <-timer.C:
+		logger.Warnw("Drain timed out; running jobs go back to the queue", "timeout", timeout)
+		interrupt()
+	}
+}
+
+// the --data-dir of serve, batch, and jobs: dir, or server in lipi's cache
+// dir
+func resolveDataDir(dir string) string {
+	if dir != "" {
+		return expandHome(dir)
+	}
+	return filepath.Join(config.CacheDir(), "server")
+}
+
+// the job store shared by serve, batch, and jobs
+func openJobStore(dataDir string) (*jobs.SQLiteStore, error) {
+	return jobs.NewSQLiteStore(filepath.Join(dataDir, "jobs"))
+}
+
+// the settings of a job, the defaults with its overrides applied, and the
+// file name of its subtitles
+func jobGenerateConfig(defaults *generateConfig, jobName string, opts jobs.Options) (generateConfig, string) {
+	cfg := *defaults
+	if opts.Format != "" {
+		cfg.format = subtitle.Format(strings.ToLower(opts.Format))
+	}
+	if opts.Language != "" {
+		cfg.language = opts.Language
+	}
+	if opts.TranscriptLanguage != "" {
+		cfg.transcriptLang = opts.TranscriptLanguage
+	}
+
+	name := strings.TrimSuffix(filepath.Base(jobName), filepath.Ext(jobName))
+	if name == "" || name == "." {
+		name = "subtitles"
+	}
+	return cfg, name + subtitle.GetExtensionForFormat(cfg.format)
+}
+
+// runs jobs through the generate pipeline, applying per-job overrides to
+// the server defaults, and records their chunk progress in store
+func newJobRunner(defaults *generateConfig, store jobs.Store) jobs.Runner {
+	return func(ctx context.Context, job *jobs.Job) (*jobs.Result, error) {
+		cfg, name := jobGenerateConfig(defaults, job.Name, job.Options)
+		outputPath := filepath.Join(job.Dir, name)
+
+		log := logger.With("job", job.ID)
+		cfg.onChunk = func(done, total int) {
+			if err := store.SaveProgress(job.ID, done, total); err != nil {
+				log.Warnw("Failed to record job progress", "error", err)
+			}
+		}
+		result, err := generateSubtitles(ctx, &cfg, job.Source, outputPath, log)
+		if err != nil {
+			log.Errorw("Job failed", "error", err)
+			return nil, err
+		}
+
+		log.Infow("Job complete",
+			"output", result.Output,
+			"entries", result.Entries,
+		)
+		return &jobs.Result{
+			Output:       result.Output,
+			Entries:      result.Entries,
+			MediaSeconds: result.Duration.Seconds(),
+			Usage:        result.Usage,
+		}, nil
+	}
+}
@@ -0,0 +1,365 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/llm"
+	"github.com/mgpai22/lipi/internal/source"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/summarize"
+	"github.com/mgpai22/lipi/internal/translate"
+	"github.com/spf13/cobra"
+)
+
+var summarizeCmd = &cobra.Command{
+	Use:   "summarize [media_file|subtitle_file]",
+	Short: "Write a summary, key points, and chapters from a transcript",
+	Long: `Summarize a recording with a language model: a title, a summary, the key
+points, and YouTube-style chapter markers (timestamped titles), written as
+markdown. With --chapters only the chapters are written, as "0:00 Title"
+lines for a video description or, with --chapters-format ffmetadata, as an
+ffmpeg metadata file to mux into the video. With --index a keyword and
+topic index is written instead: the topics discussed and the people,
+organizations, places, works, and terms mentioned, with the timestamps where
+they come up, as JSON or markdown.
+
+The input may be a subtitle file, or a media file: its subtitles are reused
+when they already exist where 'lipi generate' would write them, and are
+generated first otherwise, with the same flags as generate.
+
+YouTube only shows chapters when there are at least three, the first at
+0:00, each at least ten seconds long; chapters that break these rules are
+dropped.
+
+Examples:
+  lipi summarize lecture.srt
+  lipi summarize talk.mp4 --summary-language english
+  lipi summarize episode.mp3 --chapters
+  lipi summarize meeting.srt --index --index-format markdown
+  lipi summarize video.mkv --chapters --chapters-format ffmetadata --summary-provider anthropic`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(
+		cmd *cobra.Command,
+		args []string,
+		toComplete string,
+	) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return append(audio.MediaExtensions(), "srt", "vtt", "ass", "ssa"), cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: runSummarize,
+}
+
+func init() {
+	rootCmd.AddCommand(summarizeCmd)
+
+	addGenerateFlags(summarizeCmd)
+	summarizeCmd.Flags().
+		Bool("chapters", false, "Write only the chapter markers")
+	summarizeCmd.Flags().
+		String("chapters-format", "youtube", "Chapters file format for --chapters (youtube, ffmetadata)")
+	summarizeCmd.Flags().
+		Bool("index", false, "Write a timestamped index of topics and names instead of a summary")
+	summarizeCmd.Flags().
+		String("index-format", "json", "Index file format for --index (json, markdown)")
+	summarizeCmd.Flags().
+		String("summary-provider", "gemini", "Language model provider for the summary (gemini, openai, anthropic)")
+	summarizeCmd.Flags().
+		String("summary-model", "", "Model to use for the summary (provider-specific, uses sensible defaults)")
+	summarizeCmd.Flags().
+		String("summary-language", "", "Language to write the summary in (default: the transcript's)")
+	summarizeCmd.Flags().
+		String("summary-prompt", "", "Additional instructions for the summary model")
+
+	summarizeCmd.MarkFlagsMutuallyExclusive("chapters", "index")
+	mustRegisterCompletion(summarizeCmd, "chapters-format", completeValues("youtube", "ffmetadata"))
+	mustRegisterCompletion(summarizeCmd, "index-format", completeValues("json", "markdown"))
+	registerTranslateCompletions(summarizeCmd, "summary-provider", "summary-model")
+}
+
+// summary as reported by --json
+type summarizeReport struct {
+	Output    string          `json:"output"`
+	Subtitles string          `json:"subtitles"`
+	Title     string          `json:"title,omitempty"`
+	Summary   string          `json:"summary,omitempty"`
+	KeyPoints []string        `json:"key_points,omitempty"`
+	Chapters  []chapterReport `json:"chapters,omitempty"`
+	Index     int             `json:"index_entries,omitempty"`
+}
+
+type chapterReport struct {
+	Start        string  `json:"start"`
+	StartSeconds float64 `json:"start_seconds"`
+	Title        string  `json:"title"`
+}
+
+func runSummarize(cmd *cobra.Command, args []string) error {
+	input := args[0]
+	ctx := cmd.Context()
+
+	chaptersOnly, _ := cmd.Flags().GetBool("chapters")
+	chaptersFormatStr, _ := cmd.Flags().GetString("chapters-format")
+	index, _ := cmd.Flags().GetBool("index")
+	indexFormatStr, _ := cmd.Flags().GetString("index-format")
+	provider, _ := cmd.Flags().GetString("summary-provider")
+	model, _ := cmd.Flags().GetString("summary-model")
+	language, _ := cmd.Flags().GetString("summary-language")
+	prompt, _ := cmd.Flags().GetString("summary-prompt")
+	outputPath, _ := cmd.Flags().GetString("output")
+
+	chaptersFormat, err := summarize.ParseChapterFormat(chaptersFormatStr)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, err)
+	}
+	if flagProvided(cmd, "chapters-format") && !chaptersOnly {
+		return inputErrorf("--chapters-format requires --chapters")
+	}
+	indexFormat, err := summarize.ParseIndexFormat(indexFormatStr)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, err)
+	}
+	if flagProvided(cmd, "index-format") && !index {
+		return inputErrorf("--index-format requires --index")
+	}
+	if !isSubtitlePath(input) && !source.IsRemote(input) {
+		if _, err := os.Stat(input); os.IsNotExist(err) {
+			return inputErrorf("file not found: %s", input)
+		}
+		if !audio.IsMediaFile(input) {
+			return inputErrorf(
+				"unsupported file type: %s (expected a subtitle, audio, or video file)",
+				filepath.Ext(input),
+			)
+		}
+	}
+	requests, err := newRequestSettings(cmd)
+	if err != nil {
+		return err
+	}
+	settings, err := newLLMSettings(cmd, provider)
+	if err != nil {
+		return err
+	}
+	// the transcription key also works for the summary on the same provider
+	var apiKey string
+	if transcribeProvider, _ := cmd.Flags().GetString("provider"); transcribeProvider == provider {
+		apiKey, _ = cmd.Flags().GetString("api-key")
+	}
+	operation := "summarize"
+	if index {
+		operation = "index"
+	}
+	// fail on a missing key before spending a transcription on it
+	gen, err := newLLM(ctx, operation, provider, model, apiKey, requests, settings)
+	if err != nil {
+		return err
+	}
+
+	subtitlePath, err := summarySubtitles(ctx, cmd, input)
+	if err != nil {
+		return err
+	}
+	file, err := subtitle.Open(subtitlePath)
+	if err != nil {
+		return errs.Wrap(errs.KindInput, fmt.Errorf("failed to parse subtitle file: %w", err))
+	}
+	sub := file.Subtitle()
+
+	opts := summarize.Options{
+		Language:     language,
+		ChaptersOnly: chaptersOnly,
+		Prompt:       prompt,
+	}
+	base := strings.TrimSuffix(subtitlePath, filepath.Ext(subtitlePath))
+	result := summarizeReport{Subtitles: absPath(subtitlePath)}
+	var buf bytes.Buffer
+	if index {
+		logger.Infow("Indexing transcript", "subtitles", subtitlePath, "entries", len(sub.Entries))
+		entries, err := summarize.Index(ctx, gen, sub, opts)
+		if err != nil {
+			return err
+		}
+		if outputPath == "" {
+			outputPath = base + indexFormat.Extension()
+		}
+		if err := summarize.WriteIndex(&buf, entries, indexFormat); err != nil {
+			return err
+		}
+		result.Index = len(entries)
+	} else {
+		logger.Infow("Summarizing transcript", "subtitles", subtitlePath, "entries", len(sub.Entries))
+		summary, err := summarize.Summarize(ctx, gen, sub, opts)
+		if err != nil {
+			return err
+		}
+		if chaptersOnly && len(summary.Chapters) == 0 {
+			return fmt.Errorf("the model found no usable chapters: YouTube needs at least three, ten seconds apart")
+		}
+		if chaptersOnly {
+			if outputPath == "" {
+				outputPath = base + chaptersFormat.Extension()
+			}
+			err = summarize.WriteChapters(&buf, summary.Chapters, chaptersFormat, subtitleDuration(sub))
+		} else {
+			if outputPath == "" {
+				outputPath = base + ".summary.md"
+			}
+			err = summarize.WriteMarkdown(&buf, summary)
+		}
+		if err != nil {
+			return err
+		}
+		result.Title = summary.Title
+		result.Summary = summary.Summary
+		result.KeyPoints = summary.KeyPoints
+		result.Chapters = make([]chapterReport, len(summary.Chapters))
+		for i, c := range summary.Chapters {
+			result.Chapters[i] = chapterReport{
+				Start:        summarize.FormatTimestamp(c.Start),
+				StartSeconds: c.Start.Seconds(),
+				Title:        c.Title,
+			}
+		}
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+	result.Output = absPath(outputPath)
+
+	report(result, func() {
+		if index {
+			fmt.Printf("Index written: %s\n", result.Output)
+			fmt.Printf("  Entries: %d\n", result.Index)
+			return
+		}
+		fmt.Printf("Summary written: %s\n", result.Output)
+		if result.Title != "" {
+			fmt.Printf("  Title: %s\n", result.Title)
+		}
+		fmt.Printf("  Chapters: %d\n", len(result.Chapters))
+	})
+	return nil
+}
+
+// the subtitles to summarize: the input itself, the existing subtitles of
+// a media file, or ones generated for it now
+func summarySubtitles(ctx context.Context, cmd *cobra.Command, input string) (string, error) {
+	if isSubtitlePath(input) {
+		if _, err := os.Stat(input); os.IsNotExist(err) {
+			return "", inputErrorf("subtitle file not found: %s", input)
+		}
+		return input, nil
+	}
+
+	cfg, err := newGenerateConfig(cmd)
+	if err != nil {
+		return "", err
+	}
+	if !source.IsRemote(input) {
+		existing := cfg.outputPathFor(&source.Media{
+			Path: input,
+			Name: strings.TrimSuffix(input, filepath.Ext(input)),
+		})
+		if _, err := os.Stat(existing); err == nil {
+			logger.Infow("Reusing existing subtitles", "subtitles", existing)
+			return existing, nil
+		}
+	}
+
+	cfg.progress = startProgress()
+	defer cfg.progress.Close()
+	result, err := generateSubtitles(ctx, cfg, input, "", logger)
+	if err != nil {
+		return "", err
+	}
+	return result.Output, nil
+}
+
+func isSubtitlePath(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".srt", ".vtt", ".ass", ".ssa":
+		return true
+	}
+	return false
+}
+
+// sampling and safety settings of language model requests
+type llmSettings struct {
+	decoding decodingSettings
+	safety   map[string]string
+}
+
+// reads --temperature, --top-p, --thinking-budget, and --safety for the
+// language model of provider; providers other than gemini ignore --safety,
+// as translation fallback models do
+func newLLMSettings(cmd *cobra.Command, provider string) (llmSettings, error) {
+	decoding, err := newDecodingSettings(cmd, provider, translateDecodingLimits(translate.Provider(provider)))
+	if err != nil {
+		return llmSettings{}, err
+	}
+	safety, err := newSafetyThresholds(cmd, llm.ProviderGemini)
+	if err != nil {
+		return llmSettings{}, err
+	}
+	if provider != llm.ProviderGemini {
+		safety = nil
+	}
+	return llmSettings{decoding: decoding, safety: safety}, nil
+}
+
+func (s llmSettings) apply(opts *llm.Options) {
+	opts.Temperature = s.decoding.temperature
+	opts.TopP = s.decoding.topP
+	opts.ThinkingBudget = s.decoding.thinkingBudget
+	opts.SafetyThresholds = s.safety
+}
+
+// a language model client for operation, with apiKey or the provider's
+// configured key and the run's request and sampling settings
+func newLLM(
+	ctx context.Context,
+	operation, provider, model, apiKey string,
+	requests requestSettings,
+	settings llmSettings,
+) (llm.Generator, error) {
+	if err := refuseOfflineProvider(provider); err != nil {
+		return nil, err
+	}
+	envVar, ok := providerKeyEnv[provider]
+	if !ok {
+		return nil, inputErrorf("unsupported provider %q: use gemini, openai, or anthropic", provider)
+	}
+	if apiKey == "" {
+		apiKey = lookupAPIKey(provider, envVar)
+	}
+	if apiKey == "" {
+		return nil, missingAPIKeyError(envVar)
+	}
+
+	opts := llm.Options{
+		Model:     model,
+		Operation: operation,
+		Usage:     runUsage.Child(),
+	}
+	requests.applyLLM(&opts)
+	settings.apply(&opts)
+	return llm.New(ctx, provider, apiKey, opts)
+}
+
+// the end of the last cue, used when a total duration is needed
+func subtitleDuration(sub *subtitle.Subtitle) time.Duration {
+	if len(sub.Entries) == 0 {
+		return 0
+	}
+	return sub.Entries[len(sub.Entries)-1].EndTime
+}
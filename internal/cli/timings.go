@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/review"
+	"github.com/mgpai22/lipi/internal/transcribe"
+)
+
+// chunks listed as the slowest after a run with --timings
+const slowestChunks = 5
+
+// chunk timings as reported by --json
+type timingsReport struct {
+	UploadSeconds    float64             `json:"upload_seconds"`
+	InferenceSeconds float64             `json:"inference_seconds"`
+	ParseSeconds     float64             `json:"parse_seconds"`
+	WaitingSeconds   float64             `json:"waiting_seconds"`
+	Chunks           []chunkTimingReport `json:"chunks"`
+}
+
+type chunkTimingReport struct {
+	Index            int     `json:"index"`
+	StartSeconds     float64 `json:"start_seconds"`
+	UploadSeconds    float64 `json:"upload_seconds"`
+	InferenceSeconds float64 `json:"inference_seconds"`
+	ParseSeconds     float64 `json:"parse_seconds"`
+	WaitingSeconds   float64 `json:"waiting_seconds"`
+	TotalSeconds     float64 `json:"total_seconds"`
+}
+
+func newTimingsReport(chunks []transcribe.ChunkTiming) *timingsReport {
+	if len(chunks) == 0 {
+		return nil
+	}
+	r := &timingsReport{Chunks: make([]chunkTimingReport, len(chunks))}
+	for i, c := range chunks {
+		r.UploadSeconds += c.Upload.Seconds()
+		r.InferenceSeconds += c.Inference.Seconds()
+		r.ParseSeconds += c.Parse.Seconds()
+		r.WaitingSeconds += c.Waiting().Seconds()
+		r.Chunks[i] = chunkTimingReport{
+			Index:            c.Index,
+			StartSeconds:     c.Start.Seconds(),
+			UploadSeconds:    c.Upload.Seconds(),
+			InferenceSeconds: c.Inference.Seconds(),
+			ParseSeconds:     c.Parse.Seconds(),
+			WaitingSeconds:   c.Waiting().Seconds(),
+			TotalSeconds:     c.Total.Seconds(),
+		}
+	}
+	return r
+}
+
+// a phase of the chunk requests, summed over the chunks
+type timingPhase struct {
+	name  string
+	total time.Duration
+}
+
+// the phases in report order, and the one that took longest
+func timingPhases(chunks []transcribe.ChunkTiming) ([]timingPhase, timingPhase) {
+	phases := []timingPhase{{name: "upload"}, {name: "inference"}, {name: "parse"}, {name: "waiting"}}
+	for _, c := range chunks {
+		phases[0].total += c.Upload
+		phases[1].total += c.Inference
+		phases[2].total += c.Parse
+		phases[3].total += c.Waiting()
+	}
+	slowest := phases[0]
+	for _, p := range phases[1:] {
+		if p.total > slowest.total {
+			slowest = p
+		}
+	}
+	return phases, slowest
+}
+
+// what to try when most of the time went to a phase
+var timingHints = map[string]string{
+	"upload":    "Most time went to uploading audio; a smaller --chunk-format such as opus sends less of it",
+	"inference": "Most time went to inference; a higher --concurrency runs more chunks at once, and a shorter --chunk-duration spreads the audio over more of them",
+	"waiting":   "Most time went to waiting on rate limits and retries; a lower --concurrency or --rate-limit avoids them",
+}
+
+// prints where the chunks' time went, the slowest chunks, and a hint for
+// the phase that took longest
+func printTimings(w io.Writer, chunks []transcribe.ChunkTiming) {
+	if len(chunks) == 0 {
+		return
+	}
+	phases, slowest := timingPhases(chunks)
+	var sum time.Duration
+	for _, p := range phases {
+		sum += p.total
+	}
+
+	_, _ = fmt.Fprintf(w, "  Chunk timings (%d chunks, per chunk on average):\n", len(chunks))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, p := range phases {
+		share := 0.0
+		if sum > 0 {
+			share = 100 * float64(p.total) / float64(sum)
+		}
+		_, _ = fmt.Fprintf(tw, "    %s\t%s\t%3.0f%%\n", p.name, roundTiming(p.total/time.Duration(len(chunks))), share)
+	}
+	_ = tw.Flush()
+
+	byTotal := append([]transcribe.ChunkTiming(nil), chunks...)
+	sort.SliceStable(byTotal, func(i, j int) bool { return byTotal[i].Total > byTotal[j].Total })
+	if len(byTotal) > slowestChunks {
+		byTotal = byTotal[:slowestChunks]
+	}
+	_, _ = fmt.Fprintln(w, "  Slowest chunks:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range byTotal {
+		_, _ = fmt.Fprintf(tw, "    #%d\tat %s\t%s\t(upload %s, inference %s, parse %s, waiting %s)\n",
+			c.Index,
+			review.FormatTimestamp(c.Start),
+			roundTiming(c.Total),
+			roundTiming(c.Upload),
+			roundTiming(c.Inference),
+			roundTiming(c.Parse),
+			roundTiming(c.Waiting()),
+		)
+	}
+	_ = tw.Flush()
+
+	if hint, ok := timingHints[slowest.name]; ok && slowest.total > 0 {
+		_, _ = fmt.Fprintf(w, "  %s\n", hint)
+	}
+}
+
+// rounds a timing to tenths of a second, or to seconds from a minute
+func roundTiming(d time.Duration) time.Duration {
+	if d >= time.Minute {
+		return d.Round(time.Second)
+	}
+	return d.Round(100 * time.Millisecond)
+}
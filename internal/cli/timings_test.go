@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/transcribe"
+)
+
+func TestPrintTimings(t *testing.T) {
+	var chunks []transcribe.ChunkTiming
+	for i := range 7 {
+		inference := time.Duration(10+i) * time.Second
+		chunks = append(chunks, transcribe.ChunkTiming{
+			Index:     i,
+			Start:     time.Duration(i) * time.Minute,
+			Upload:    time.Second,
+			Inference: inference,
+			Parse:     100 * time.Millisecond,
+			Total:     inference + 2*time.Second,
+		})
+	}
+
+	var buf bytes.Buffer
+	printTimings(&buf, chunks)
+	out := buf.String()
+	for _, want := range []string{
+		"Chunk timings (7 chunks",
+		"inference  13s",
+		"#6  at 00:06:00.000  18s  (upload 1s, inference 16s, parse 100ms, waiting 900ms)",
+		"Most time went to inference",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	// only the slowest five are listed
+	if strings.Contains(out, "#1  at") {
+		t.Errorf("output lists a fast chunk:\n%s", out)
+	}
+
+	report := newTimingsReport(chunks)
+	if len(report.Chunks) != 7 || report.UploadSeconds != 7 {
+		t.Errorf("report = %+v", report)
+	}
+	if newTimingsReport(nil) != nil {
+		t.Error("report of no chunks is not nil")
+	}
+}
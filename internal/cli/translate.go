@@ -1,19 +1,32 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/mgpai22/lipi/internal/dubbing"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/glossary"
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/progress"
+	"github.com/mgpai22/lipi/internal/project"
+	"github.com/mgpai22/lipi/internal/source"
 	"github.com/mgpai22/lipi/internal/subtitle"
 	"github.com/mgpai22/lipi/internal/translate"
+	"github.com/mgpai22/lipi/internal/usage"
+	"github.com/mgpai22/lipi/internal/video"
 	"github.com/spf13/cobra"
 )
 
 var translateCmd = &cobra.Command{
-	Use:   "translate [subtitle_file]",
+	Use:   "translate [subtitle_file|dir|glob]...",
 	Short: "Translate subtitles to another language using AI",
 	Long: `Translate an existing subtitle file to another language using AI.
 
@@ -23,95 +36,467 @@ formatting is preserved - only the dialogue text is translated.
 The --overlay flag creates bilingual subtitles with the translated text
 first, followed by the original text on the next line.
 
+--learning-mode writes an ASS track for language learners instead: each
+cue stacks the original line, its reading, and the translation, in the
+Original, Reading, and Translation styles. --reading picks romanization
+(romaji, pinyin, ...) or kana, which spells Japanese kanji out in hiragana
+like furigana.
+
+--format dubbing-script writes a voice-over script instead of subtitles:
+one row per cue with its timing, speaker, original and translated text,
+syllable counts, and the syllable budget the cue's duration allows at
+--syllable-rate, flagging lines that run over. The script is CSV, or an
+XLSX workbook when -o ends in .xlsx.
+
+A series keeps its names and decisions in a project file, lipi.project.yaml,
+found in the folder of the subtitles or above (or named by --project). Its
+characters, phrases, and --honorifics decision for the target language are
+added to the glossary and instructions of every translation, and each
+translation adds the names and recurring phrases it settled on, so later
+episodes render them the same way.
+
+Several files, a directory, or a glob pattern translate every subtitle file
+they match in one run, named as a single file would be (--output-dir puts
+them in another folder). --concurrency is then a limit on requests in flight
+shared by all files, --jobs sets how many files are translated at a time,
+and a failed file does not stop the others; a per-file summary is printed
+at the end. Files that are the translation of another matched file are left
+out, so a rerun into the same folder does not translate its own output.
+
+Pass "-" to read subtitles from stdin; the translation is then written to
+stdout unless -o names a file. "-o -" writes to stdout for any input.
+
 Examples:
   lipi translate video.srt --target-language japanese
   lipi translate video.ass --target-language ja --overlay
-  lipi translate video.vtt -l english --target-language spanish -o translated.vtt`,
-	Args: cobra.ExactArgs(1),
-	RunE: runTranslate,
+  lipi translate video.vtt -l english --target-language spanish -o translated.vtt
+  lipi translate video.srt -t german --format dubbing-script -o video.de.xlsx
+  lipi translate anime.ja.srt -t english --learning-mode --reading kana
+  lipi translate ep02.ja.srt -t en --project ../lipi.project.yaml --honorifics keep
+  lipi translate "./subs/*.srt" -t spanish --output-dir ./subs/es/ --skip-existing
+  cat in.srt | lipi translate - -t es > out.srt`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeSubtitleFiles,
+	RunE:              runTranslate,
 }
 
 func init() {
 	rootCmd.AddCommand(translateCmd)
 
-	translateCmd.Flags().
-		StringP("target-language", "t", "", "Target language for translation (required)")
+	addTranslateFlags(translateCmd)
 	translateCmd.Flags().
 		Bool("overlay", false, "Overlay translated text with original (bilingual subtitles)")
 	translateCmd.Flags().
-		StringP("api-key", "k", "", "API key (or set GEMINI_API_KEY/OPENAI_API_KEY/ANTHROPIC_API_KEY env var)")
+		Bool("localize", false, "Convert units, dates, numbers, and currency to the target language's conventions, and warn about entries that look unconverted")
 	translateCmd.Flags().
-		String("model", "", "Model to use for translation (provider-specific, uses sensible defaults)")
+		Bool("preserve-formatting", false, "Write SRT and VTT entries left untouched exactly as read, keeping the original numbering, spacing, and line endings")
 	translateCmd.Flags().
-		Bool("model-override", false, "Allow any custom model, bypassing provider model validation")
+		Bool("strict-indices", false, "Re-map by order, or resend, batches whose results do not match their entries one to one")
 	translateCmd.Flags().
-		String("provider", "gemini", "Translation provider (gemini, openai, anthropic)")
+		Bool("forced-narrative", false, "Translate and keep only the entries tagged with a language other than the main one (--language, or the one spoken longest), as a forced track")
+	translateCmd.Flags().
+		Bool("learning-mode", false, "Write an ASS track of three-line cues: original, reading, and translation")
+	translateCmd.Flags().
+		String("reading", string(translate.ReadingRomanization), "Reading line of --learning-mode: romanization or kana (furigana for Japanese)")
+	translateCmd.Flags().
+		String("format", "", "Output format: the input's subtitle format by default, or dubbing-script for a voice-over script (CSV, or XLSX for -o *.xlsx)")
+	translateCmd.Flags().
+		Float64("syllable-rate", dubbing.DefaultRate, "Syllables per second a line may take in a dubbing script")
+	translateCmd.Flags().
+		Int("max-line-length", 42, "Maximum characters per line of a translated entry")
+	translateCmd.Flags().
+		Int("max-lines", 2, "Maximum lines per translated entry; longer entries are split into consecutive entries")
 	translateCmd.Flags().
-		Int("concurrency", 3, "Number of parallel translation workers")
+		Bool("keep-long-entries", false, "Do not split translated entries that exceed --max-lines")
 	translateCmd.Flags().
+		String("project", "", "Project file of the series, created if missing (default: "+project.FileName+" in the subtitles' folder or above)")
+	translateCmd.Flags().
+		Bool("no-project", false, "Translate without reading or updating a project file")
+	translateCmd.Flags().
+		String("honorifics", "", "How honorifics such as -san are translated: keep, drop, or adapt; recorded in the project")
+	translateCmd.Flags().
+		Bool("skip-existing", false, "With several files, skip those whose translation already exists")
+	translateCmd.Flags().
+		IntP("jobs", "j", 1, "With several files, number of files to translate at the same time")
+	translateCmd.Flags().
+		BoolP("recursive", "r", false, "Descend into subdirectories of directory arguments")
+	translateCmd.Flags().
+		Bool("fail-fast", false, "With several files, stop after the first failure")
+
+	addOutputNamingFlags(translateCmd)
+	addPreviewFlag(translateCmd)
+	addNotifyFlags(translateCmd)
+
+	mustRegisterCompletion(translateCmd, "format", completeValues(formatDubbingScript))
+	mustRegisterCompletion(translateCmd, "honorifics", completeValues(
+		string(project.HonorificsKeep),
+		string(project.HonorificsDrop),
+		string(project.HonorificsAdapt),
+	))
+	mustRegisterCompletion(translateCmd, "reading", completeValues(
+		string(translate.ReadingRomanization),
+		string(translate.ReadingKana),
+	))
+}
+
+// registers the flags shared by translate and bench-translate
+func addTranslateFlags(cmd *cobra.Command) {
+	cmd.Flags().
+		StringP("target-language", "t", "", "Target language for translation (required)")
+	cmd.Flags().
+		StringP("api-key", "k", "", "API key (or set GEMINI_API_KEY/OPENAI_API_KEY/ANTHROPIC_API_KEY env var)")
+	cmd.Flags().
+		String("model", "", "Model to use for translation (provider-specific, uses sensible defaults)")
+	cmd.Flags().
+		Bool("model-override", false, "Allow any custom model, bypassing provider model validation")
+	cmd.Flags().
+		String("provider", "gemini", "Translation provider (gemini, openai, anthropic)")
+	cmd.Flags().
+		String("concurrency", "auto", "Number of parallel translation workers, or auto to size it from the request count and provider limits")
+	cmd.Flags().
 		Int("batch-size", 50, "Number of subtitle entries per API request")
+	cmd.Flags().
+		String("glossary", "", "File of terms to translate consistently (\"term\" or \"term = translation\" per line)")
+	cmd.Flags().
+		String("prompt", "", "Additional instructions for the translation model")
+	cmd.Flags().
+		StringSlice("model-fallback", nil, "Models a batch moves to, in order, when it keeps failing on --model (model, or provider:model for another provider)")
+	cmd.Flags().
+		Float64("temperature", 0, "Sampling temperature for translation (gemini, openai: 0-2, anthropic: 0-1; default: provider default)")
+	addDecodingFlags(cmd)
+	addSafetyFlag(cmd)
+	addRequestFlags(cmd)
+
+	_ = cmd.MarkFlagRequired("target-language")
+	registerTranslateCompletions(cmd, "provider", "model")
+}
 
-	_ = translateCmd.MarkFlagRequired("target-language")
+// --format value for a dubbing script
+const formatDubbingScript = "dubbing-script"
+
+// validated settings for translating subtitle files
+type translateConfig struct {
+	targetLang    string
+	inputLang     string
+	provider      translate.Provider
+	apiKey        string
+	model         string
+	modelOverride bool
+	concurrency   int // concurrencyAuto to size it per file
+	batchSize     int
+	overlay       bool
+	dubbingScript bool              // write a dubbing script instead of subtitles
+	syllableRate  float64           // speaking rate the script budgets for
+	learning      bool              // write a three-line learning track instead of subtitles
+	forced        bool              // keep only entries in other languages than the main one
+	localize      bool              // localize units, dates, numbers, and currency
+	strictIndices bool              // repair or resend batches whose results do not match their entries
+	preserve      bool              // keep the numbering, spacing, and line endings of SRT and VTT input
+	maxLineLength int               // characters per line a translated entry wraps to
+	maxLines      int               // lines past which a translated entry is split; 0 keeps it whole
+	limiter       translate.Limiter // when set, bounds requests in flight across the files of a batch
+	project       string            // project file; empty to look for one next to the subtitles
+	noProject     bool              // neither read nor update a project file
+	honorifics    project.Honorifics
+	reading       translate.Reading
+	glossary      glossary.Glossary
+	prompt        string
+	decoding      decodingSettings
+	safety        map[string]string // Gemini block threshold by harm category
+	modelFallback []string          // --model-fallback entries, resolved into fallbacks by validate
+	fallbacks     []translate.FallbackModel
+	requests      requestSettings
+	output        outputNamer
+	progress      *progress.Display
+}
+
+// outcome of translating a single subtitle file
+type translateResult struct {
+	Output       string
+	Entries      int
+	OverBudget   int         // dubbing script lines longer than their cue allows
+	Unlocalized  int         // entries whose units or dates look unconverted
+	Untranslated []int       // numbers of the entries no result answered, from 1
+	Project      string      // project file the translation shared terms with
+	ProjectTerms int         // terms this translation added to the project
+	Usage        usage.Usage // provider usage of this file alone
+}
+
+// translate result as reported by --json
+type translateReport struct {
+	Output         string `json:"output"`
+	Entries        int    `json:"entries"`
+	TargetLanguage string `json:"target_language"`
+	Overlay        bool   `json:"overlay"`
+	Learning       bool   `json:"learning"`
+	OverBudget     *int   `json:"over_budget,omitempty"`
+	Unlocalized    *int   `json:"unlocalized,omitempty"`
+	Untranslated   []int  `json:"untranslated,omitempty"`
+	Project        string `json:"project,omitempty"`
+	ProjectTerms   *int   `json:"project_terms,omitempty"`
+}
+
+func newTranslateReport(
+	cfg *translateConfig,
+	result *translateResult,
+) translateReport {
+	r := translateReport{
+		Output:         absPath(result.Output),
+		Entries:        result.Entries,
+		TargetLanguage: cfg.targetLang,
+		Overlay:        cfg.overlay,
+		Learning:       cfg.learning,
+		Untranslated:   result.Untranslated,
+	}
+	if cfg.dubbingScript {
+		r.OverBudget = &result.OverBudget
+	}
+	if cfg.localize {
+		r.Unlocalized = &result.Unlocalized
+	}
+	if result.Project != "" {
+		r.Project = absPath(result.Project)
+		r.ProjectTerms = &result.ProjectTerms
+	}
+	return r
 }
 
 func runTranslate(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 || isTranslateBatchArg(args[0]) {
+		return runTranslateBatch(cmd, args)
+	}
+	for _, name := range []string{"jobs", "skip-existing", "recursive", "fail-fast"} {
+		if flagProvided(cmd, name) {
+			return inputErrorf("--%s requires several subtitle files, a directory, or a glob", name)
+		}
+	}
+
 	subtitlePath := args[0]
-	ctx := context.Background()
+	ctx := cmd.Context()
+
+	targetLang, _ := cmd.Flags().GetString("target-language")
+	overlay, _ := cmd.Flags().GetBool("overlay")
+	outputPath, _ := cmd.Flags().GetString("output")
+
+	if subtitlePath != source.Stdin {
+		if _, err := os.Stat(subtitlePath); os.IsNotExist(err) {
+			return inputErrorf("subtitle file not found: %s", subtitlePath)
+		}
+
+		ext := strings.ToLower(filepath.Ext(subtitlePath))
+		if ext != ".srt" && ext != ".vtt" && ext != ".ass" && ext != ".ssa" {
+			return inputErrorf(
+				"unsupported subtitle format %q: use .srt, .vtt, .ass, or .ssa",
+				ext,
+			)
+		}
+	}
+	if err := checkTranslateModeFlags(cmd, outputPath); err != nil {
+		return err
+	}
+	preview, _ := cmd.Flags().GetInt("preview")
+	if preview < 0 {
+		return inputErrorf("preview must not be negative, got %d", preview)
+	}
+	toStdout := translateToStdout(subtitlePath, outputPath)
+	if toStdout && jsonOutput {
+		return inputErrorf("--json cannot be used while writing subtitles to stdout")
+	}
+
+	cfg, err := newTranslateConfig(cmd)
+	if err != nil {
+		return err
+	}
+	cfg.progress = startProgress()
+	defer cfg.progress.Close()
+
+	result, err := translateSubtitles(ctx, cfg, subtitlePath, outputPath, logger)
+	if err != nil {
+		return err
+	}
+
+	if toStdout {
+		return nil
+	}
+	if cfg.dubbingScript {
+		report(newTranslateReport(cfg, result), func() {
+			fmt.Printf("Dubbing script written: %s\n", absPath(result.Output))
+			fmt.Printf("  Entries: %d\n", result.Entries)
+			fmt.Printf("  Over budget: %d\n", result.OverBudget)
+			if cfg.localize {
+				fmt.Printf("  Unlocalized: %d\n", result.Unlocalized)
+			}
+			printUntranslated(result.Untranslated)
+		})
+		return nil
+	}
+	report(newTranslateReport(cfg, result), func() {
+		fmt.Printf("Subtitles translated successfully: %s\n", absPath(result.Output))
+		fmt.Printf("  Entries: %d\n", result.Entries)
+		fmt.Printf("  Target language: %s\n", targetLang)
+		if overlay {
+			fmt.Printf("  Mode: bilingual overlay\n")
+		}
+		if cfg.learning {
+			fmt.Printf("  Mode: learning (%s)\n", cfg.reading)
+		}
+		if cfg.localize {
+			fmt.Printf("  Unlocalized: %d\n", result.Unlocalized)
+		}
+		printUntranslated(result.Untranslated)
+		if result.Project != "" {
+			fmt.Printf("  Project: %s (%d new terms)\n", absPath(result.Project), result.ProjectTerms)
+		}
+		printPreview(os.Stdout, result.Output, preview)
+	})
+
+	return nil
+}
+
+// checks the flags that choose what translate writes
+func checkTranslateModeFlags(cmd *cobra.Command, outputPath string) error {
+	format, _ := cmd.Flags().GetString("format")
+	if format != "" && format != formatDubbingScript {
+		return inputErrorf("unsupported format %q: use %s, or leave it unset for subtitles", format, formatDubbingScript)
+	}
+	if flagProvided(cmd, "syllable-rate") && format != formatDubbingScript {
+		return inputErrorf("--syllable-rate requires --format %s", formatDubbingScript)
+	}
+	learning, _ := cmd.Flags().GetBool("learning-mode")
+	if flagProvided(cmd, "reading") && !learning {
+		return inputErrorf("--reading requires --learning-mode")
+	}
+	if learning && outputPath != "" && outputPath != source.Stdin &&
+		subtitle.GetFormatFromExtension(outputPath) != subtitle.FormatASS {
+		return inputErrorf("--learning-mode writes ASS: -o must end in .ass, got %s", outputPath)
+	}
+	return nil
+}
 
+// reads and validates the translation flags on cmd
+func newTranslateConfig(cmd *cobra.Command) (*translateConfig, error) {
 	targetLang, _ := cmd.Flags().GetString("target-language")
 	overlay, _ := cmd.Flags().GetBool("overlay")
 	apiKey, _ := cmd.Flags().GetString("api-key")
 	model, _ := cmd.Flags().GetString("model")
 	modelOverride, _ := cmd.Flags().GetBool("model-override")
 	providerStr, _ := cmd.Flags().GetString("provider")
-	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	concurrencyStr, _ := cmd.Flags().GetString("concurrency")
 	batchSize, _ := cmd.Flags().GetInt("batch-size")
-	outputPath, _ := cmd.Flags().GetString("output")
 	inputLang, _ := cmd.Flags().GetString("language")
+	glossaryPath, _ := cmd.Flags().GetString("glossary")
+	prompt, _ := cmd.Flags().GetString("prompt")
+	format, _ := cmd.Flags().GetString("format")
+	syllableRate, _ := cmd.Flags().GetFloat64("syllable-rate")
+	modelFallback, _ := cmd.Flags().GetStringSlice("model-fallback")
+	learning, _ := cmd.Flags().GetBool("learning-mode")
+	reading, _ := cmd.Flags().GetString("reading")
+	forcedNarrative, _ := cmd.Flags().GetBool("forced-narrative")
+	localize, _ := cmd.Flags().GetBool("localize")
+	strictIndices, _ := cmd.Flags().GetBool("strict-indices")
+	preserve, _ := cmd.Flags().GetBool("preserve-formatting")
+	maxLineLength, _ := cmd.Flags().GetInt("max-line-length")
+	maxLines, _ := cmd.Flags().GetInt("max-lines")
+	keepLong, _ := cmd.Flags().GetBool("keep-long-entries")
+	projectPath, _ := cmd.Flags().GetString("project")
+	noProject, _ := cmd.Flags().GetBool("no-project")
+	honorifics, _ := cmd.Flags().GetString("honorifics")
 
-	if _, err := os.Stat(subtitlePath); os.IsNotExist(err) {
-		return fmt.Errorf("subtitle file not found: %s", subtitlePath)
+	concurrency, err := parseConcurrency(concurrencyStr)
+	if err != nil {
+		return nil, err
 	}
-
-	ext := strings.ToLower(filepath.Ext(subtitlePath))
-	if ext != ".srt" && ext != ".vtt" && ext != ".ass" && ext != ".ssa" {
-		return fmt.Errorf(
-			"unsupported subtitle format %q: use .srt, .vtt, .ass, or .ssa",
-			ext,
-		)
+	cfg := &translateConfig{
+		targetLang:    targetLang,
+		inputLang:     inputLang,
+		provider:      translate.Provider(providerStr),
+		apiKey:        apiKey,
+		model:         model,
+		modelOverride: modelOverride,
+		concurrency:   concurrency,
+		batchSize:     batchSize,
+		overlay:       overlay,
+		dubbingScript: format == formatDubbingScript,
+		learning:      learning,
+		forced:        forcedNarrative,
+		localize:      localize,
+		strictIndices: strictIndices,
+		preserve:      preserve,
+		maxLineLength: maxLineLength,
+		project:       expandHome(projectPath),
+		noProject:     noProject,
+		honorifics:    project.Honorifics(honorifics),
+		syllableRate:  syllableRate,
+		prompt:        prompt,
+		modelFallback: modelFallback,
+	}
+	if learning {
+		cfg.reading = translate.Reading(reading)
+	}
+	if !keepLong {
+		cfg.maxLines = maxLines
 	}
+	if cfg.output, err = newOutputNamer(cmd); err != nil {
+		return nil, err
+	}
+	// a forced track is flagged in templated names and the sidecar
+	cfg.output.forced = cfg.output.forced || forcedNarrative
+	if cfg.requests, err = newRequestSettings(cmd); err != nil {
+		return nil, err
+	}
+	if cfg.decoding, err = newDecodingSettings(cmd, providerStr, translateDecodingLimits(cfg.provider)); err != nil {
+		return nil, err
+	}
+	if cfg.safety, err = newSafetyThresholds(cmd, providerStr); err != nil {
+		return nil, err
+	}
+	if glossaryPath != "" {
+		if cfg.glossary, err = glossary.Load(expandHome(glossaryPath)); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
 
-	if targetLang == "" {
-		return fmt.Errorf("target language is required")
+// checks the settings and fills the API key from the environment
+func (c *translateConfig) validate() error {
+	if c.targetLang == "" {
+		return inputErrorf("target language is required")
 	}
 
-	if inputLang != "" &&
+	if c.inputLang != "" &&
 		strings.EqualFold(
-			strings.TrimSpace(inputLang),
-			strings.TrimSpace(targetLang),
+			strings.TrimSpace(c.inputLang),
+			strings.TrimSpace(c.targetLang),
 		) {
-		return fmt.Errorf(
+		return inputErrorf(
 			"input language %q and target language %q cannot be the same",
-			inputLang,
-			targetLang,
+			c.inputLang,
+			c.targetLang,
 		)
 	}
 
-	provider := translate.Provider(providerStr)
+	if err := refuseOfflineProvider(string(c.provider)); err != nil {
+		return err
+	}
 
-	if apiKey == "" {
-		switch provider {
+	if c.apiKey == "" {
+		switch c.provider {
 		case translate.ProviderGemini:
-			apiKey = os.Getenv("GEMINI_API_KEY")
+			c.apiKey = lookupAPIKey("gemini", "GEMINI_API_KEY")
 		case translate.ProviderOpenAI:
-			apiKey = os.Getenv("OPENAI_API_KEY")
+			c.apiKey = lookupAPIKey("openai", "OPENAI_API_KEY")
 		case translate.ProviderAnthropic:
-			apiKey = os.Getenv("ANTHROPIC_API_KEY")
+			c.apiKey = lookupAPIKey("anthropic", "ANTHROPIC_API_KEY")
 		}
 	}
-	if apiKey == "" {
+	// the mock provider reads fixtures and needs no key
+	if c.apiKey == "" && c.provider != translate.ProviderMock {
 		var envVar string
-		switch provider {
+		switch c.provider {
 		case translate.ProviderGemini:
 			envVar = "GEMINI_API_KEY"
 		case translate.ProviderOpenAI:
@@ -121,108 +506,280 @@ func runTranslate(cmd *cobra.Command, args []string) error {
 		default:
 			envVar = "API_KEY"
 		}
-		return fmt.Errorf(
-			"API key is required: use --api-key flag or set %s environment variable",
-			envVar,
-		)
+		return missingAPIKeyError(envVar)
 	}
 
-	if model != "" && !modelOverride {
-		switch provider {
-		case translate.ProviderGemini:
-			if !isValidGeminiModel(model) {
-				return fmt.Errorf(
-					"unsupported Gemini model %q: valid models are gemini-3-pro-preview, gemini-3-flash-preview, gemini-2.5-pro, gemini-2.5-flash, gemini-2.5-flash-lite (use --model-override to bypass)",
-					model,
-				)
-			}
-		case translate.ProviderOpenAI:
-			if !isValidOpenAIModel(model) {
-				return fmt.Errorf(
-					"unsupported OpenAI model %q: valid models are o1, o3-mini, o1-pro, o3, gpt-5, gpt-5-nano, gpt-5-mini, gpt-5-pro, gpt-5.1, gpt-5.2, gpt-5.2-pro (use --model-override to bypass)",
-					model,
-				)
-			}
-		case translate.ProviderAnthropic:
-			if !isValidAnthropicModel(model) {
-				return fmt.Errorf(
-					"unsupported Anthropic model %q: valid models are claude-haiku-4-5, claude-sonnet-4-5, claude-opus-4-5 (use --model-override to bypass)",
-					model,
-				)
-			}
+	if c.model != "" && !c.modelOverride {
+		if err := checkTranslationModel(c.provider, c.model); err != nil {
+			return err
 		}
 	}
+	fallbacks, err := translationFallbacks(c)
+	if err != nil {
+		return err
+	}
+	c.fallbacks = fallbacks
 
-	if concurrency <= 0 {
-		return fmt.Errorf("concurrency must be positive, got %d", concurrency)
+	if c.concurrency < 0 {
+		return inputErrorf("concurrency must be positive, got %d", c.concurrency)
 	}
-	if batchSize <= 0 {
-		return fmt.Errorf("batch-size must be positive, got %d", batchSize)
+	if c.dubbingScript && c.overlay {
+		return inputErrorf("--overlay cannot be used with a dubbing script")
 	}
-
-	if outputPath == "" {
-		baseName := strings.TrimSuffix(subtitlePath, filepath.Ext(subtitlePath))
-		if overlay {
-			outputPath = fmt.Sprintf(
-				"%s.%s.overlay%s",
-				baseName,
-				targetLang,
-				ext,
-			)
-		} else {
-			outputPath = fmt.Sprintf("%s.%s%s", baseName, targetLang, ext)
+	if c.learning {
+		if c.overlay {
+			return inputErrorf("--overlay cannot be used with --learning-mode")
+		}
+		if c.dubbingScript {
+			return inputErrorf("--learning-mode cannot be used with a dubbing script")
 		}
+		switch c.reading {
+		case translate.ReadingRomanization, translate.ReadingKana:
+		default:
+			return inputErrorf("unsupported reading %q: use %s or %s", c.reading, translate.ReadingRomanization, translate.ReadingKana)
+		}
+	}
+	if c.dubbingScript && c.syllableRate <= 0 {
+		return inputErrorf("syllable-rate must be positive, got %g", c.syllableRate)
+	}
+	if c.batchSize <= 0 {
+		return inputErrorf("batch-size must be positive, got %d", c.batchSize)
+	}
+	if err := c.honorifics.Validate(); err != nil {
+		return errs.Wrap(errs.KindInput, err)
+	}
+	if c.noProject && (c.project != "" || c.honorifics != "") {
+		return inputErrorf("--no-project cannot be used with --project or --honorifics")
+	}
+	if c.maxLines < 0 || (c.maxLines > 0 && c.maxLineLength <= 0) {
+		return inputErrorf(
+			"max line length and max lines must be positive, got %d and %d",
+			c.maxLineLength,
+			c.maxLines,
+		)
+	}
+
+	return nil
+}
+
+// default output path: video.srt -> video.ja.srt (or video.ja.overlay.srt)
+// builds the settings for translating subtitles that a generate run just
+// produced, inheriting its language, concurrency, glossary, and (for the
+// same provider) API key and safety thresholds
+func newFollowUpTranslateConfig(
+	cfg *generateConfig,
+	targetLang, provider, model string,
+	overlay bool,
+) (*translateConfig, error) {
+	tcfg := &translateConfig{
+		targetLang:  targetLang,
+		inputLang:   cfg.language,
+		provider:    translate.Provider(provider),
+		model:       model,
+		concurrency: cfg.concurrency,
+		batchSize:   translate.DefaultBatchSize,
+		overlay:     overlay,
+		glossary:    cfg.glossary,
+		requests:    cfg.requests,
+		output:      cfg.output,
+		// translations wrap to the same lines as the transcript
+		maxLineLength: cfg.generator.MaxCharsPerLine,
+		maxLines:      cfg.generator.MaxLinesPerSub,
+	}
+	// the transcription key also works for translation on the same provider
+	if string(tcfg.provider) == string(cfg.provider) {
+		tcfg.apiKey = cfg.apiKey
+		tcfg.safety = cfg.safety
+	}
+	if err := tcfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid translation settings: %w", err)
 	}
+	return tcfg, nil
+}
+
+// translated subtitles go to stdout for "-o -", and by default when the
+// input is read from stdin
+func translateToStdout(subtitlePath, outputPath string) bool {
+	return outputPath == source.Stdin ||
+		(outputPath == "" && subtitlePath == source.Stdin)
+}
+
+// opens a subtitle file, or reads subtitles from stdin for "-" and detects
+// their format from the content
+func openSubtitles(path string) (subtitle.File, error) {
+	if path != source.Stdin {
+		return subtitle.Open(path)
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return subtitle.Read(bytes.NewReader(data), subtitle.DetectFormat(data))
+}
+
+func translateOutputPath(subtitlePath, targetLang string, overlay bool) string {
+	ext := filepath.Ext(subtitlePath)
+	baseName := strings.TrimSuffix(subtitlePath, ext)
+	if overlay {
+		return fmt.Sprintf("%s.%s.overlay%s", baseName, targetLang, ext)
+	}
+	return fmt.Sprintf("%s.%s%s", baseName, targetLang, ext)
+}
+
+// default output path with --output-dir and --output-template applied.
+// basename overrides the name of the subtitle file for the Basename field;
+// without it, language and flag suffixes are dropped from the file name, so
+// Movie.en.srt is translated to Movie.es.srt either way.
+func (c *translateConfig) outputPathFor(subtitlePath, basename string) string {
+	if basename == "" {
+		basename = stripSubtitleSuffixes(
+			strings.TrimSuffix(filepath.Base(subtitlePath), filepath.Ext(subtitlePath)),
+		)
+	}
+	defaultPath := translateOutputPath(subtitlePath, c.targetLang, c.overlay)
+	if c.forced {
+		defaultPath = withNameFlag(defaultPath, "forced")
+	}
+	return c.output.path(
+		defaultPath,
+		outputNameData{
+			Basename: basename,
+			Lang:     c.targetLang,
+			Format:   formatField(subtitlePath),
+		},
+	)
+}
+
+// where a translation of subtitlePath goes without -o: a dubbing script
+// or learning track is named after the subtitles it would have written
+func (c *translateConfig) defaultOutputPath(subtitlePath string) string {
+	outputPath := c.outputPathFor(subtitlePath, "")
+	if c.dubbingScript {
+		outputPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".dubbing.csv"
+	}
+	if c.learning {
+		outputPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".learn.ass"
+	}
+	return outputPath
+}
 
-	logger.Infow("Starting subtitle translation",
+// translates one subtitle file. outputPath may be empty to derive it from
+// the input and target language.
+func translateSubtitles(
+	ctx context.Context,
+	cfg *translateConfig,
+	subtitlePath, outputPath string,
+	log *logging.Logger,
+) (*translateResult, error) {
+	started := time.Now()
+	if translateToStdout(subtitlePath, outputPath) {
+		outputPath = source.Stdin
+	} else if outputPath == "" {
+		outputPath = cfg.defaultOutputPath(subtitlePath)
+	}
+
+	log.Infow("Starting subtitle translation",
 		"input", subtitlePath,
 		"output", outputPath,
-		"target_language", targetLang,
-		"input_language", inputLang,
-		"overlay", overlay,
-		"model", model,
+		"target_language", cfg.targetLang,
+		"input_language", cfg.inputLang,
+		"overlay", cfg.overlay,
+		"learning", cfg.learning,
+		"model", cfg.model,
 	)
 
-	logger.Infow("Parsing subtitle file")
-	subFile, err := subtitle.Open(subtitlePath)
+	log.Infow("Parsing subtitle file")
+	subFile, err := openSubtitles(subtitlePath)
 	if err != nil {
-		return fmt.Errorf("failed to parse subtitle file: %w", err)
+		return nil, fmt.Errorf("failed to parse subtitle file: %w", err)
+	}
+	if cfg.preserve {
+		subtitle.PreserveFormatting(subFile)
 	}
 
-	sub := subFile.Subtitle()
-	if len(sub.Entries) == 0 {
-		return fmt.Errorf("subtitle file contains no entries")
+	if len(subFile.Subtitle().Entries) == 0 {
+		return nil, inputErrorf("subtitle file contains no entries")
 	}
+	if cfg.forced {
+		if err := keepForcedEntries(subFile, cfg.inputLang, log); err != nil {
+			return nil, err
+		}
+	}
+	sub := subFile.Subtitle()
 
-	logger.Infow("Parsed subtitle file",
+	log.Infow("Parsed subtitle file",
 		"entries", len(sub.Entries),
 		"format", subFile.Format(),
 	)
 
+	proj, err := openTranslateProject(cfg, subtitlePath, log)
+	if err != nil {
+		return nil, err
+	}
+
+	meter := runUsage.Child()
 	opts := translate.Options{
-		InputLanguage:  inputLang,
-		TargetLanguage: targetLang,
-		Model:          model,
-		BatchSize:      batchSize,
+		InputLanguage:    cfg.inputLang,
+		TargetLanguage:   cfg.targetLang,
+		Model:            cfg.model,
+		Prompt:           proj.prompt(cfg.prompt),
+		Glossary:         proj.glossary(cfg.glossary),
+		Reading:          cfg.reading,
+		Localize:         cfg.localize,
+		BatchSize:        cfg.batchSize,
+		StrictIndices:    cfg.strictIndices,
+		Temperature:      cfg.decoding.temperature,
+		TopP:             cfg.decoding.topP,
+		ThinkingBudget:   cfg.decoding.thinkingBudget,
+		SafetyThresholds: cfg.safety,
+		Fallbacks:        cfg.fallbacks,
+		Usage:            meter,
+	}
+	cfg.requests.applyTranslate(&opts, log)
+	opts.Limiter = cfg.limiter
+	if cfg.progress != nil {
+		opts.OnProgress = cfg.progress.Add
 	}
 
-	translator, err := translate.Factory(ctx, provider, apiKey, opts)
+	translator, err := translate.Factory(ctx, cfg.provider, cfg.apiKey, opts)
 	if err != nil {
-		return fmt.Errorf("failed to create translator: %w", err)
+		return nil, fmt.Errorf("failed to create translator: %w", err)
 	}
+	defer shutdownProvider(log, func(ctx context.Context) error {
+		return translate.Shutdown(ctx, translator)
+	})
 
-	items := make([]translate.TranslationItem, len(sub.Entries))
+	// entries tagged as already in the target language are kept as they are
+	items := make([]translate.TranslationItem, 0, len(sub.Entries))
 	for i, entry := range sub.Entries {
-		items[i] = translate.TranslationItem{
+		if sameLanguage(entry.Language, cfg.targetLang) {
+			continue
+		}
+		items = append(items, translate.TranslationItem{
 			Index: i,
 			Text:  entry.Text,
-		}
+		})
+	}
+	if skipped := len(sub.Entries) - len(items); skipped > 0 {
+		log.Infow("Keeping entries already in the target language",
+			"entries", skipped,
+		)
 	}
 
-	logger.Infow("Translating subtitles",
+	concurrency := cfg.concurrency
+	if concurrency == concurrencyAuto {
+		requests := (len(items) + cfg.batchSize - 1) / cfg.batchSize
+		concurrency = autoConcurrency(
+			requests,
+			translationConcurrencyLimit(cfg.provider),
+		)
+	}
+
+	log.Infow("Translating subtitles",
 		"items", len(items),
 		"concurrency", concurrency,
 	)
+	cfg.progress.Stage("Translating to "+cfg.targetLang, len(items))
 
 	var results []translate.TranslationResult
 	if concurrentTranslator, ok := translator.(translate.ConcurrentTranslator); ok {
@@ -231,35 +788,77 @@ func runTranslate(cmd *cobra.Command, args []string) error {
 			items,
 			concurrency,
 		)
-	} else {
+	} else if len(items) > 0 {
 		results, err = translator.Translate(ctx, items)
 	}
 	if err != nil {
-		return fmt.Errorf("translation failed: %w", err)
+		return nil, fmt.Errorf("translation failed: %w", err)
 	}
 
-	logger.Infow("Translation complete",
+	log.Infow("Translation complete",
 		"results", len(results),
 	)
+	untranslated := reportUntranslated(items, results, log)
+	unlocalized := 0
+	if cfg.localize {
+		unlocalized = checkLocalized(sub, results, log)
+	}
+	var projectPath string
+	projectTerms := 0
+	if proj != nil {
+		if projectTerms, err = proj.record(ctx, cfg, subtitlePath, sub, results, log); err != nil {
+			return nil, err
+		}
+		projectPath = proj.path
+	}
+
+	if cfg.learning {
+		if err := writeLearningTrack(cfg, sub, results, outputPath, log); err != nil {
+			return nil, err
+		}
+		if err := writeTranslateSidecar(cfg, subtitlePath, outputPath, started, meter); err != nil {
+			return nil, err
+		}
+		cfg.progress.Done()
+		return &translateResult{
+			Output:       outputPath,
+			Entries:      len(sub.Entries),
+			Unlocalized:  unlocalized,
+			Untranslated: untranslated,
+			Project:      projectPath,
+			ProjectTerms: projectTerms,
+			Usage:        meter.Usage(),
+		}, nil
+	}
 
 	assFile, isASS := subFile.(*subtitle.ASSFile)
+	vttFile, isVTT := subFile.(*subtitle.VTTFile)
+	translatedTag := ""
+	if !cfg.overlay && video.LanguageCode(cfg.targetLang) != "" {
+		translatedTag = video.ShortLanguageCode(cfg.targetLang)
+	}
+	// SetText rewrites the entries of SRT and VTT files in place
+	originals := make([]string, len(sub.Entries))
+	for i, entry := range sub.Entries {
+		originals[i] = entry.Text
+	}
 
 	for _, result := range results {
 		if result.Index < 0 || result.Index >= len(sub.Entries) {
-			logger.Warnw("Skipping invalid result index",
+			log.Warnw("Skipping invalid result index",
 				"index", result.Index,
 				"max", len(sub.Entries)-1,
 			)
 			continue
 		}
 
-		if overlay {
+		if cfg.overlay {
 			if isASS {
 				if err := assFile.SetTextWithOverlay(
 					result.Index,
 					result.Text,
 				); err != nil {
-					return fmt.Errorf(
+					return nil, fmt.Errorf(
 						"failed to set overlay text for entry %d: %w",
 						result.Index,
 						err,
@@ -273,7 +872,7 @@ func runTranslate(cmd *cobra.Command, args []string) error {
 					result.Index,
 					overlayText,
 				); err != nil {
-					return fmt.Errorf(
+					return nil, fmt.Errorf(
 						"failed to set overlay text for entry %d: %w",
 						result.Index,
 						err,
@@ -283,27 +882,298 @@ func runTranslate(cmd *cobra.Command, args []string) error {
 		} else {
 			// replace with translation
 			if err := subFile.SetText(result.Index, result.Text); err != nil {
-				return fmt.Errorf(
+				return nil, fmt.Errorf(
 					"failed to set text for entry %d: %w",
 					result.Index,
 					err,
 				)
 			}
 		}
+
+		// a tagged entry now holds the translation, or both languages when
+		// overlaid
+		if isVTT && sub.Entries[result.Index].Language != "" {
+			if err := vttFile.SetLanguage(result.Index, translatedTag); err != nil {
+				return nil, fmt.Errorf(
+					"failed to set language for entry %d: %w",
+					result.Index,
+					err,
+				)
+			}
+		}
 	}
 
-	logger.Infow("Writing output file")
-	if err := subFile.Write(outputPath); err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
+	if cfg.dubbingScript {
+		overBudget, err := writeDubbingScript(cfg, subFile.Subtitle(), originals, outputPath)
+		if err != nil {
+			return nil, err
+		}
+		cfg.progress.Done()
+		return &translateResult{
+			Output:       outputPath,
+			Entries:      len(sub.Entries),
+			OverBudget:   overBudget,
+			Unlocalized:  unlocalized,
+			Untranslated: untranslated,
+			Project:      projectPath,
+			ProjectTerms: projectTerms,
+			Usage:        meter.Usage(),
+		}, nil
 	}
 
-	absOutput, _ := filepath.Abs(outputPath)
-	fmt.Printf("Subtitles translated successfully: %s\n", absOutput)
-	fmt.Printf("  Entries: %d\n", len(sub.Entries))
-	fmt.Printf("  Target language: %s\n", targetLang)
-	if overlay {
-		fmt.Printf("  Mode: bilingual overlay\n")
+	// an overlay holds both languages, so its entries already run longer
+	entries := len(sub.Entries)
+	if !cfg.overlay {
+		split, err := subtitle.SplitLong(subFile, cfg.maxLineLength, cfg.maxLines)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split long entries: %w", err)
+		}
+		if split > 0 {
+			entries = len(subFile.Subtitle().Entries)
+			log.Infow("Split long entries",
+				"split", split,
+				"entries", entries,
+			)
+		}
+	}
+
+	log.Infow("Writing output file")
+	cfg.progress.Stage("Writing subtitles", 0)
+	if outputPath == source.Stdin {
+		err = subFile.Encode(os.Stdout)
+	} else {
+		err = subFile.Write(outputPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := writeTranslateSidecar(cfg, subtitlePath, outputPath, started, meter); err != nil {
+		return nil, err
+	}
+	cfg.progress.Done()
+
+	return &translateResult{
+		Output:       outputPath,
+		Entries:      entries,
+		Unlocalized:  unlocalized,
+		Untranslated: untranslated,
+		Project:      projectPath,
+		ProjectTerms: projectTerms,
+		Usage:        meter.Usage(),
+	}, nil
+}
+
+// warns about each item no result answered, with its text, and returns
+// their entry numbers
+func reportUntranslated(
+	items []translate.TranslationItem,
+	results []translate.TranslationResult,
+	log *logging.Logger,
+) []int {
+	var entries []int
+	for _, item := range translate.Untranslated(items, results) {
+		entries = append(entries, item.Index+1)
+		log.Warnw("Entry left untranslated",
+			"entry", item.Index+1,
+			"text", item.Text,
+		)
+	}
+	return entries
+}
+
+// prints the entries left untranslated, if any
+func printUntranslated(entries []int) {
+	if len(entries) == 0 {
+		return
+	}
+	numbers := make([]string, len(entries))
+	for i, entry := range entries {
+		numbers[i] = strconv.Itoa(entry)
+	}
+	fmt.Printf("  Untranslated: %d (entries %s)\n", len(entries), strings.Join(numbers, ", "))
+}
+
+// warns about each translation that still carries the imperial units or
+// month-first dates of its original, and returns how many do
+func checkLocalized(
+	sub *subtitle.Subtitle,
+	results []translate.TranslationResult,
+	log *logging.Logger,
+) int {
+	flagged := 0
+	for _, result := range results {
+		if result.Index < 0 || result.Index >= len(sub.Entries) {
+			continue
+		}
+		found := translate.Unlocalized(sub.Entries[result.Index].Text, result.Text)
+		if len(found) == 0 {
+			continue
+		}
+		flagged++
+		log.Warnw("Translation may not be localized",
+			"entry", result.Index+1,
+			"found", strings.Join(found, ", "),
+			"translation", result.Text,
+		)
+	}
+	return flagged
+}
+
+// records where translated subtitles came from next to outputPath, unless
+// they went to stdout
+func writeTranslateSidecar(
+	cfg *translateConfig,
+	subtitlePath, outputPath string,
+	started time.Time,
+	meter *usage.Meter,
+) error {
+	if outputPath == source.Stdin {
+		return nil
+	}
+	sourceFile := subtitlePath
+	if sourceFile == source.Stdin {
+		sourceFile = ""
+	}
+	return cfg.output.writeSidecar(outputPath, sourceFile, subtitleSidecar{
+		Source:   subtitlePath,
+		Language: cfg.targetLang,
+		Provider: string(cfg.provider),
+		Model:    cfg.model,
+		Started:  started,
+		Usage:    meter.Usage(),
+	})
+}
+
+// writes the entries of sub with the readings and translations of results
+// as a learning track; entries without a result keep only their original
+func writeLearningTrack(
+	cfg *translateConfig,
+	sub *subtitle.Subtitle,
+	results []translate.TranslationResult,
+	outputPath string,
+	log *logging.Logger,
+) error {
+	cues := make([]subtitle.LearningCue, len(sub.Entries))
+	for i, entry := range sub.Entries {
+		cues[i] = subtitle.LearningCue{
+			StartTime: entry.StartTime,
+			EndTime:   entry.EndTime,
+			Speaker:   entry.Speaker,
+			Original:  entry.Text,
+		}
+	}
+	for _, result := range results {
+		if result.Index < 0 || result.Index >= len(cues) {
+			log.Warnw("Skipping invalid result index",
+				"index", result.Index,
+				"max", len(cues)-1,
+			)
+			continue
+		}
+		cues[result.Index].Reading = result.Reading
+		cues[result.Index].Translation = result.Text
 	}
 
+	log.Infow("Writing learning track")
+	cfg.progress.Stage("Writing subtitles", 0)
+	w := subtitle.NewLearningWriter()
+	var err error
+	if outputPath == source.Stdin {
+		err = w.Encode(cues, os.Stdout)
+	} else {
+		err = w.Write(cues, outputPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write learning track: %w", err)
+	}
 	return nil
 }
+
+// checks that provider offers model as a translation model
+func checkTranslationModel(provider translate.Provider, model string) error {
+	switch provider {
+	case translate.ProviderGemini:
+		if !isValidGeminiModel(model) {
+			return inputErrorf(
+				"unsupported Gemini model %q: valid models are gemini-3-pro-preview, gemini-3-flash-preview, gemini-2.5-pro, gemini-2.5-flash, gemini-2.5-flash-lite (use --model-override to bypass)",
+				model,
+			)
+		}
+	case translate.ProviderOpenAI:
+		if !isValidOpenAIModel(model) {
+			return inputErrorf(
+				"unsupported OpenAI model %q: valid models are o1, o3-mini, o1-pro, o3, gpt-5, gpt-5-nano, gpt-5-mini, gpt-5-pro, gpt-5.1, gpt-5.2, gpt-5.2-pro (use --model-override to bypass)",
+				model,
+			)
+		}
+	case translate.ProviderAnthropic:
+		if !isValidAnthropicModel(model) {
+			return inputErrorf(
+				"unsupported Anthropic model %q: valid models are claude-haiku-4-5, claude-sonnet-4-5, claude-opus-4-5 (use --model-override to bypass)",
+				model,
+			)
+		}
+	}
+	return nil
+}
+
+// what provider accepts for the decoding flags; Anthropic caps the
+// temperature at 1 and only Gemini has a thinking budget
+func translateDecodingLimits(provider translate.Provider) decodingLimits {
+	switch provider {
+	case translate.ProviderGemini:
+		return decodingLimits{maxTemperature: 2, topP: true, thinking: true}
+	case translate.ProviderAnthropic:
+		return decodingLimits{maxTemperature: 1, topP: true}
+	}
+	return decodingLimits{maxTemperature: 2, topP: true}
+}
+
+// reports whether an entry's language tag names the same language as lang
+func sameLanguage(tag, lang string) bool {
+	code := video.LanguageCode(tag)
+	return code != "" && code == video.LanguageCode(lang)
+}
+
+// writes the translated entries of sub, with their original text, as a
+// dubbing script, and returns how many lines run over their budget
+func writeDubbingScript(
+	cfg *translateConfig,
+	sub *subtitle.Subtitle,
+	originals []string,
+	outputPath string,
+) (int, error) {
+	cues := make([]dubbing.Cue, len(sub.Entries))
+	for i, entry := range sub.Entries {
+		cues[i] = dubbing.Cue{
+			Start:       entry.StartTime,
+			End:         entry.EndTime,
+			Speaker:     entry.Speaker,
+			Original:    originals[i],
+			Translation: entry.Text,
+		}
+	}
+	lines := dubbing.Lines(cues, dubbing.Options{Rate: cfg.syllableRate})
+	over := 0
+	for _, line := range lines {
+		if line.Over() {
+			over++
+		}
+	}
+
+	cfg.progress.Stage("Writing dubbing script", 0)
+	var buf bytes.Buffer
+	if err := dubbing.Write(&buf, dubbing.FormatForPath(outputPath), lines); err != nil {
+		return 0, fmt.Errorf("failed to encode dubbing script: %w", err)
+	}
+	var err error
+	if outputPath == source.Stdin {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = os.WriteFile(outputPath, buf.Bytes(), 0644)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write dubbing script: %w", err)
+	}
+	return over, nil
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/dubbing"
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/translate"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+func TestTranslateToStdout(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+		want   bool
+	}{
+		{"-", "", true},
+		{"-", "-", true},
+		{"video.srt", "-", true},
+		{"-", "out.srt", false},
+		{"video.srt", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := translateToStdout(tt.input, tt.output); got != tt.want {
+			t.Errorf("translateToStdout(%q, %q) = %v, want %v", tt.input, tt.output, got, tt.want)
+		}
+	}
+}
+
+// translates testdata/episode.srt with the mock provider and compares the
+// result with testdata/golden; texts missing from the fixture are tagged
+func TestTranslateGolden(t *testing.T) {
+	t.Setenv(translate.MockTranslationsEnv, filepath.Join("testdata", "translations.json"))
+
+	tests := []struct {
+		name     string
+		input    string
+		overlay  bool
+		dubbing  bool
+		learning bool
+	}{
+		{"episode.es.srt", "episode.srt", false, false, false},
+		{"episode.overlay.es.srt", "episode.srt", true, false, false},
+		{"episode.es.dubbing.csv", "episode.srt", false, true, false},
+		{"episode.es.learn.ass", "episode.srt", false, false, true},
+		// entries tagged as Spanish are kept, translated ones are retagged
+		{"mixed.es.vtt", "mixed.vtt", false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &translateConfig{
+				targetLang: "es",
+				provider:   translate.ProviderMock,
+				batchSize:  2,
+				overlay:    tt.overlay,
+
+				dubbingScript: tt.dubbing,
+				syllableRate:  dubbing.DefaultRate,
+				learning:      tt.learning,
+				maxLineLength: 42,
+				maxLines:      2,
+			}
+			if tt.learning {
+				cfg.reading = translate.ReadingRomanization
+			}
+			if err := cfg.validate(); err != nil {
+				t.Fatalf("validate() error = %v", err)
+			}
+			output := filepath.Join(t.TempDir(), tt.name)
+			log := logging.NewLogger(false, io.Discard)
+			if _, err := translateSubtitles(context.Background(), cfg, filepath.Join("testdata", tt.input), output, log); err != nil {
+				t.Fatalf("translateSubtitles() error = %v", err)
+			}
+
+			got, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatalf("failed to read output: %v", err)
+			}
+			golden := filepath.Join("testdata", "golden", tt.name)
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatalf("failed to update golden file: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("output differs from golden file\n--- got ---\n%s\n--- want ---\n%s", got, want)
+			}
+		})
+	}
+}
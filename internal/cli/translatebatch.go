@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/source"
+	"github.com/mgpai22/lipi/internal/translate"
+	"github.com/spf13/cobra"
+)
+
+// reports whether a translate argument names many subtitle files: a
+// directory or a glob pattern
+func isTranslateBatchArg(arg string) bool {
+	if arg == source.Stdin {
+		return false
+	}
+	if strings.ContainsAny(arg, "*?[") {
+		return true
+	}
+	info, err := os.Stat(arg)
+	return err == nil && info.IsDir()
+}
+
+// translates every subtitle file matched by args with the same settings,
+// sharing one request budget across the files
+func runTranslateBatch(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	if output, _ := cmd.Flags().GetString("output"); output != "" {
+		return inputErrorf(
+			"-o is not supported with several subtitle files: use --output-dir or --output-template",
+		)
+	}
+	for _, arg := range args {
+		if arg == source.Stdin {
+			return inputErrorf("stdin cannot be translated along with other subtitle files")
+		}
+	}
+	if flagProvided(cmd, "preview") {
+		return inputErrorf("--preview requires a single subtitle file")
+	}
+
+	skipExisting, _ := cmd.Flags().GetBool("skip-existing")
+	workers, _ := cmd.Flags().GetInt("jobs")
+	recursive, _ := cmd.Flags().GetBool("recursive")
+	failFast, _ := cmd.Flags().GetBool("fail-fast")
+	if workers <= 0 {
+		return inputErrorf("jobs must be positive, got %d", workers)
+	}
+	if err := checkTranslateModeFlags(cmd, ""); err != nil {
+		return err
+	}
+
+	cfg, err := newTranslateConfig(cmd)
+	if err != nil {
+		return err
+	}
+	items, err := translateBatchItems(cfg, args, recursive)
+	if err != nil {
+		return err
+	}
+
+	workers = min(workers, len(items))
+	budget := cfg.concurrency
+	if budget == concurrencyAuto {
+		budget = translationConcurrencyLimit(cfg.provider)
+	}
+	fileCfg := *cfg
+	fileCfg.limiter = translate.NewLimiter(budget)
+	fileCfg.progress = nil
+
+	logger.Infow("Starting translation batch",
+		"files", len(items),
+		"jobs", workers,
+		"target_language", cfg.targetLang,
+		"requests_in_flight", budget,
+	)
+	runTranslateBatchItems(ctx, &fileCfg, items, batchOptions{
+		jobs:         workers,
+		skipExisting: skipExisting,
+		failFast:     failFast,
+		stage:        "Translating subtitles",
+	})
+
+	if err := printBatchSummary(items); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf(
+			"batch interrupted: rerun with --skip-existing to resume: %w",
+			err,
+		)
+	}
+	return nil
+}
+
+// the subtitle files matched by args with their translation's path,
+// leaving out files that are the translation of another one, as a rerun
+// into the same folder would match them
+func translateBatchItems(cfg *translateConfig, args []string, recursive bool) ([]batchItem, error) {
+	inputs, err := expandPaths(args, recursive, isSubtitlePath)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make(map[string]bool, len(inputs))
+	for _, input := range inputs {
+		outputs[absPath(cfg.defaultOutputPath(input))] = true
+	}
+	var items []batchItem
+	for _, input := range inputs {
+		if outputs[absPath(input)] {
+			continue
+		}
+		items = append(items, batchItem{
+			Input:  input,
+			Output: cfg.defaultOutputPath(input),
+		})
+	}
+	if len(items) == 0 {
+		return nil, inputErrorf("no subtitle files matched %s", strings.Join(args, " "))
+	}
+	return items, nil
+}
+
+// translates each item with cfg, recording the outcome in the item. A
+// failed file does not stop the others unless failFast is set.
+func runTranslateBatchItems(ctx context.Context, cfg *translateConfig, items []batchItem, opts batchOptions) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	display := startProgress()
+	defer display.Close()
+	display.Stage(opts.stage, len(items))
+
+	indexChan := make(chan int)
+	var wg sync.WaitGroup
+	for range min(opts.jobs, len(items)) {
+		wg.Go(func() {
+			for i := range indexChan {
+				item := &items[i]
+				log := logger.With("file", filepath.Base(item.Input))
+
+				fileCfg := *cfg
+				start := time.Now()
+				result, err := translateSubtitles(ctx, &fileCfg, item.Input, item.Output, log)
+				item.Elapsed = time.Since(start)
+				display.Add(1)
+				if err != nil && ctx.Err() != nil {
+					// stopped by Ctrl-C or --fail-fast, not by this file
+					item.Status = batchSkipped
+					item.Err = ctx.Err()
+					continue
+				}
+				if err != nil {
+					item.Status = batchFailed
+					item.Err = err
+					log.Errorw("File failed", "error", err)
+					if opts.failFast {
+						cancel()
+					}
+					continue
+				}
+				item.Status = batchSucceeded
+				item.Entries = result.Entries
+			}
+		})
+	}
+
+	for i := range items {
+		if opts.skipExisting {
+			if _, err := os.Stat(items[i].Output); err == nil {
+				items[i].Status = batchSkipped
+				logger.Infow("Skipping existing output",
+					"output", items[i].Output,
+				)
+				display.Add(1)
+				continue
+			}
+		}
+		if ctx.Err() != nil {
+			items[i].Status = batchSkipped
+			items[i].Err = ctx.Err()
+			continue
+		}
+		indexChan <- i
+	}
+	close(indexChan)
+	wg.Wait()
+	display.Done()
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/translate"
+)
+
+func TestTranslateBatch(t *testing.T) {
+	t.Setenv(translate.MockTranslationsEnv, filepath.Join("testdata", "translations.json"))
+	prevLogger := logger
+	t.Cleanup(func() { logger = prevLogger })
+	logger = logging.NewLogger(false, io.Discard)
+
+	data, err := os.ReadFile(filepath.Join("testdata", "episode.srt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	// ep02.es.srt is the translation of ep02.srt from an earlier run
+	for _, name := range []string{"ep01.srt", "ep02.srt", "ep02.es.srt", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &translateConfig{
+		targetLang: "es",
+		provider:   translate.ProviderMock,
+		batchSize:  2,
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+	items, err := translateBatchItems(cfg, []string{filepath.Join(dir, "*")}, false)
+	if err != nil {
+		t.Fatalf("translateBatchItems() error = %v", err)
+	}
+	if len(items) != 2 ||
+		items[0].Output != filepath.Join(dir, "ep01.es.srt") ||
+		items[1].Output != filepath.Join(dir, "ep02.es.srt") {
+		t.Fatalf("items = %+v", items)
+	}
+
+	cfg.limiter = translate.NewLimiter(1)
+	runTranslateBatchItems(context.Background(), cfg, items, batchOptions{jobs: 2, skipExisting: true})
+	if items[0].Status != batchSucceeded || items[0].Entries == 0 || items[0].Err != nil {
+		t.Errorf("ep01 = %+v, want translated", items[0])
+	}
+	if items[1].Status != batchSkipped || items[1].Err != nil {
+		t.Errorf("ep02 = %+v, want skipped for its existing output", items[1])
+	}
+	if _, err := os.Stat(items[0].Output); err != nil {
+		t.Errorf("translation not written: %v", err)
+	}
+}
+
+func TestIsTranslateBatchArg(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "ep01.srt")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		arg  string
+		want bool
+	}{
+		{file, false},
+		{"-", false},
+		{dir, true},
+		{filepath.Join(dir, "*.srt"), true},
+	}
+	for _, tt := range tests {
+		if got := isTranslateBatchArg(tt.arg); got != tt.want {
+			t.Errorf("isTranslateBatchArg(%q) = %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
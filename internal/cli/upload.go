@@ -0,0 +1,244 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mgpai22/lipi/internal/credentials"
+	"github.com/mgpai22/lipi/internal/httpclient"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/video"
+	"github.com/mgpai22/lipi/internal/youtube"
+	"github.com/spf13/cobra"
+)
+
+// credential store entry holding the YouTube refresh token
+const youtubeCredential = "youtube"
+
+var uploadCaptionsCmd = &cobra.Command{
+	Use:   "upload-captions [video_id] [subtitle_file]",
+	Short: "Upload subtitles to a YouTube video as a caption track",
+	Long: `Upload a subtitle file to one of your YouTube videos as a caption track,
+without going through YouTube Studio.
+
+The video may be given as an ID or a watch, youtu.be, or shorts URL. SRT and
+WebVTT files are uploaded as is; ASS files are converted to SRT first.
+
+Uploads use the YouTube Data API with an OAuth client of the "TVs and
+Limited Input devices" type from your Google Cloud project, given with
+--client-id and --client-secret (or YOUTUBE_CLIENT_ID and
+YOUTUBE_CLIENT_SECRET). The first upload prints a code to enter at
+google.com/device; the refresh token is then kept in the OS keychain (or the
+encrypted credentials file) for later runs. Remove it with
+"lipi auth delete youtube".
+
+Examples:
+  lipi upload-captions dQw4w9WgXcQ video.es.srt --language es
+  lipi upload-captions "https://youtu.be/dQw4w9WgXcQ" video.fr.vtt -l fr --name "Français"
+  lipi upload-captions dQw4w9WgXcQ video.es.srt -l es --replace --draft`,
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(
+		cmd *cobra.Command,
+		args []string,
+		toComplete string,
+	) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) != 1 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeSubtitleFiles(cmd, nil, toComplete)
+	},
+	RunE: runUploadCaptions,
+}
+
+func init() {
+	rootCmd.AddCommand(uploadCaptionsCmd)
+
+	uploadCaptionsCmd.Flags().
+		String("name", "", "Track name shown in the YouTube player (default: none)")
+	uploadCaptionsCmd.Flags().
+		Bool("draft", false, "Upload the track as a draft that viewers do not see")
+	uploadCaptionsCmd.Flags().
+		Bool("replace", false, "Replace the video's existing track with the same language and name instead of adding another")
+	uploadCaptionsCmd.Flags().
+		String("client-id", "", "OAuth client ID (or set YOUTUBE_CLIENT_ID)")
+	uploadCaptionsCmd.Flags().
+		String("client-secret", "", "OAuth client secret (or set YOUTUBE_CLIENT_SECRET)")
+	uploadCaptionsCmd.Flags().
+		String("store", "auto", "Where to keep the refresh token (auto, keychain, file)")
+	mustRegisterCompletion(uploadCaptionsCmd, "store", completeValues("auto", "keychain", "file"))
+}
+
+// caption upload as reported by --json
+type uploadCaptionsReport struct {
+	VideoID   string `json:"video_id"`
+	CaptionID string `json:"caption_id"`
+	Language  string `json:"language"`
+	Name      string `json:"name,omitempty"`
+	Draft     bool   `json:"draft"`
+	Replaced  bool   `json:"replaced"`
+}
+
+func runUploadCaptions(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	language, _ := cmd.Flags().GetString("language")
+	name, _ := cmd.Flags().GetString("name")
+	draft, _ := cmd.Flags().GetBool("draft")
+	replace, _ := cmd.Flags().GetBool("replace")
+	clientID, _ := cmd.Flags().GetString("client-id")
+	clientSecret, _ := cmd.Flags().GetString("client-secret")
+
+	videoID, err := youtube.ParseVideoID(args[0])
+	if err != nil {
+		return err
+	}
+	if language == "" {
+		return inputErrorf("--language is required: YouTube needs the caption language")
+	}
+	language = video.ShortLanguageCode(language)
+	data, err := captionData(args[1])
+	if err != nil {
+		return err
+	}
+
+	if err := httpclient.Refuse("upload captions to YouTube"); err != nil {
+		return err
+	}
+	if clientID == "" {
+		clientID = os.Getenv("YOUTUBE_CLIENT_ID")
+	}
+	if clientSecret == "" {
+		clientSecret = os.Getenv("YOUTUBE_CLIENT_SECRET")
+	}
+	if clientID == "" || clientSecret == "" {
+		return inputErrorf(
+			"set --client-id and --client-secret (or YOUTUBE_CLIENT_ID and YOUTUBE_CLIENT_SECRET) to an OAuth client of the \"TVs and Limited Input devices\" type",
+		)
+	}
+
+	client := youtube.New(nil, clientID, clientSecret)
+	token, err := youtubeAccessToken(ctx, cmd, client)
+	if err != nil {
+		return err
+	}
+
+	upload := youtube.Upload{
+		VideoID:  videoID,
+		Language: language,
+		Name:     name,
+		Draft:    draft,
+		Data:     data,
+	}
+	var existing *youtube.Caption
+	if replace {
+		tracks, err := client.ListCaptions(ctx, token, videoID)
+		if err != nil {
+			return err
+		}
+		for i := range tracks {
+			if tracks[i].Language == language && tracks[i].Name == name {
+				existing = &tracks[i]
+				break
+			}
+		}
+	}
+
+	var caption youtube.Caption
+	if existing != nil {
+		logger.Infow("Replacing caption track", "video", videoID, "caption", existing.ID)
+		caption, err = client.UpdateCaption(ctx, token, existing.ID, upload)
+	} else {
+		logger.Infow("Uploading caption track", "video", videoID, "language", language)
+		caption, err = client.UploadCaption(ctx, token, upload)
+	}
+	if err != nil {
+		return err
+	}
+
+	report(uploadCaptionsReport{
+		VideoID:   videoID,
+		CaptionID: caption.ID,
+		Language:  language,
+		Name:      name,
+		Draft:     draft,
+		Replaced:  existing != nil,
+	}, func() {
+		verb := "Uploaded"
+		if existing != nil {
+			verb = "Replaced"
+		}
+		fmt.Printf("%s %s captions on https://www.youtube.com/watch?v=%s\n", verb, language, videoID)
+		if draft {
+			fmt.Println("  The track is a draft: publish it in YouTube Studio")
+		}
+	})
+	return nil
+}
+
+// the file to upload; YouTube reads SRT and WebVTT but not ASS
+func captionData(path string) ([]byte, error) {
+	file, err := subtitle.Open(path)
+	if err != nil {
+		return nil, inputErrorf("failed to read subtitles: %v", err)
+	}
+	if len(file.Subtitle().Entries) == 0 {
+		return nil, inputErrorf("subtitle file contains no entries")
+	}
+	if file.Format() != subtitle.FormatASS {
+		return os.ReadFile(path)
+	}
+
+	writer, err := subtitle.NewWriter(subtitle.FormatSRT)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := writer.Encode(file.Subtitle(), &buf); err != nil {
+		return nil, fmt.Errorf("failed to convert subtitles to SRT: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// an access token from the stored refresh token, or from a new device
+// authorization when there is none or it was revoked
+func youtubeAccessToken(ctx context.Context, cmd *cobra.Command, client *youtube.Client) (string, error) {
+	if refresh := credentials.Lookup(youtubeCredential, credentialPassphrase(false)); refresh != "" {
+		token, err := client.Refresh(ctx, refresh)
+		if err == nil {
+			return token.AccessToken, nil
+		}
+		if !errors.Is(err, youtube.ErrInvalidGrant) {
+			return "", err
+		}
+		logger.Warnw("Stored YouTube authorization is no longer valid; authorizing again")
+	}
+
+	code, err := client.RequestDeviceCode(ctx)
+	if err != nil {
+		return "", err
+	}
+	_, _ = fmt.Fprintf(os.Stderr,
+		"To let lipi upload captions, visit %s and enter the code %s\n",
+		code.VerificationURL,
+		code.UserCode,
+	)
+	token, err := client.PollToken(ctx, code)
+	if err != nil {
+		return "", err
+	}
+
+	if token.RefreshToken == "" {
+		return token.AccessToken, nil
+	}
+	// a failure to store the token only means authorizing again next time
+	store, err := credentialStore(cmd, true)
+	if err == nil {
+		err = store.Set(youtubeCredential, token.RefreshToken)
+	}
+	if err != nil {
+		logger.Warnw("Failed to store the YouTube refresh token", "error", err)
+	}
+	return token.AccessToken, nil
+}
@@ -17,9 +17,15 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version information",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("lipi %s\n", Version)
-		fmt.Printf("  commit: %s\n", Commit)
-		fmt.Printf("  built:  %s\n", BuildDate)
+		report(map[string]string{
+			"version": Version,
+			"commit":  Commit,
+			"built":   BuildDate,
+		}, func() {
+			fmt.Printf("lipi %s\n", Version)
+			fmt.Printf("  commit: %s\n", Commit)
+			fmt.Printf("  built:  %s\n", BuildDate)
+		})
 	},
 }
 
@@ -0,0 +1,456 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [directory]",
+	Short: "Watch a directory and subtitle new media files automatically",
+	Long: `Watch a drop folder and generate subtitles for every audio or video file
+that appears in it. Subtitles are written next to each file.
+
+A file is processed once it has stopped growing for --settle, so large copies
+and downloads are not picked up half-written. Files that already have
+subtitles are skipped. Use --translate-to to also write a translated copy.
+
+--on-complete controls what happens to a media file once it is done:
+  none    leave everything in place (default)
+  move    move the media and its subtitles into --done-dir
+  delete  remove the media file and keep the subtitles
+
+The watcher runs until interrupted with Ctrl-C.
+
+Examples:
+  lipi watch /media/incoming
+  lipi watch /media/incoming --on-complete move --done-dir /media/library
+  lipi watch ./drop --recursive --translate-to spanish`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeDirectories,
+	RunE:              runWatch,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	addGenerateFlags(watchCmd)
+	addOutputNamingFlags(watchCmd)
+	watchCmd.Flags().
+		BoolP("recursive", "r", false, "Also watch subdirectories")
+	watchCmd.Flags().
+		Duration("settle", 5*time.Second, "How long a file must stop changing before it is processed")
+	watchCmd.Flags().
+		String("on-complete", "none", "Action after a file is subtitled (none, move, delete)")
+	watchCmd.Flags().
+		String("done-dir", "", "Destination for --on-complete move (default: <directory>/done)")
+	watchCmd.Flags().
+		Bool("process-existing", true, "Process media already in the directory when the watcher starts")
+	watchCmd.Flags().
+		String("translate-to", "", "Also translate the generated subtitles to this language")
+	watchCmd.Flags().
+		String("translate-provider", "gemini", "Translation provider (gemini, openai, anthropic)")
+	watchCmd.Flags().
+		String("translate-model", "", "Model to use for translation (provider-specific, uses sensible defaults)")
+	watchCmd.Flags().
+		Bool("overlay", false, "Write bilingual translated subtitles (translated + original)")
+
+	mustRegisterCompletion(watchCmd, "on-complete", completeValues("none", "move", "delete"))
+	registerTranslateCompletions(watchCmd, "translate-provider", "translate-model")
+}
+
+// what to do with a media file after it has been subtitled
+type completeAction string
+
+const (
+	completeNone   completeAction = "none"
+	completeMove   completeAction = "move"
+	completeDelete completeAction = "delete"
+)
+
+// settings for a watch session
+type watchOptions struct {
+	root       string
+	recursive  bool
+	settle     time.Duration
+	onComplete completeAction
+	doneDir    string
+	generate   *generateConfig
+	translate  *translateConfig
+}
+
+// a file that has changed recently and is waiting to settle
+type pendingFile struct {
+	size    int64
+	changed time.Time
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	root := filepath.Clean(args[0])
+
+	info, err := os.Stat(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return inputErrorf("directory not found: %s", root)
+		}
+		return err
+	}
+	if !info.IsDir() {
+		return inputErrorf("not a directory: %s", root)
+	}
+
+	if output, _ := cmd.Flags().GetString("output"); output != "" {
+		return inputErrorf(
+			"--output is not supported by watch: subtitles are written next to each input",
+		)
+	}
+
+	recursive, _ := cmd.Flags().GetBool("recursive")
+	settle, _ := cmd.Flags().GetDuration("settle")
+	onComplete, _ := cmd.Flags().GetString("on-complete")
+	doneDir, _ := cmd.Flags().GetString("done-dir")
+	processExisting, _ := cmd.Flags().GetBool("process-existing")
+	translateTo, _ := cmd.Flags().GetString("translate-to")
+	translateProvider, _ := cmd.Flags().GetString("translate-provider")
+	translateModel, _ := cmd.Flags().GetString("translate-model")
+	overlay, _ := cmd.Flags().GetBool("overlay")
+
+	if settle < 0 {
+		return inputErrorf("settle must not be negative, got %s", settle)
+	}
+
+	action := completeAction(strings.ToLower(onComplete))
+	switch action {
+	case completeNone, completeMove, completeDelete:
+	default:
+		return inputErrorf(
+			"unsupported on-complete action %q: use none, move, or delete",
+			onComplete,
+		)
+	}
+	if doneDir == "" {
+		doneDir = filepath.Join(root, "done")
+	}
+
+	cfg, err := newGenerateConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	opts := &watchOptions{
+		root:       root,
+		recursive:  recursive,
+		settle:     settle,
+		onComplete: action,
+		doneDir:    filepath.Clean(doneDir),
+		generate:   cfg,
+	}
+
+	if translateTo != "" {
+		tcfg, err := newFollowUpTranslateConfig(
+			cfg,
+			translateTo,
+			translateProvider,
+			translateModel,
+			overlay,
+		)
+		if err != nil {
+			return err
+		}
+		opts.translate = tcfg
+	}
+
+	ctx := cmd.Context()
+
+	return watchDirectory(ctx, opts, processExisting)
+}
+
+// runs the event loop until ctx is cancelled. Files are handed to a single
+// worker one at a time; each one already uses --concurrency workers.
+func watchDirectory(
+	ctx context.Context,
+	opts *watchOptions,
+	processExisting bool,
+) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer func() {
+		_ = watcher.Close()
+	}()
+
+	pending := make(map[string]*pendingFile)
+	err = walkWatchTree(opts, opts.root, func(path string, isDir bool) error {
+		if isDir {
+			return watcher.Add(path)
+		}
+		if processExisting && isWatchCandidate(opts, path) {
+			pending[path] = &pendingFile{size: -1}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", opts.root, err)
+	}
+
+	logger.Infow("Watching for media files",
+		"directory", opts.root,
+		"recursive", opts.recursive,
+		"on_complete", string(opts.onComplete),
+	)
+
+	work := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for path := range work {
+			processWatchedFile(ctx, opts, path)
+		}
+	}()
+	defer func() {
+		close(work)
+		<-done
+	}()
+
+	ticker := time.NewTicker(watchPollInterval(opts.settle))
+	defer ticker.Stop()
+
+	var queue []string
+	for {
+		var send chan<- string
+		var next string
+		if len(queue) > 0 {
+			send = work
+			next = queue[0]
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Infow("Stopping watcher")
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			path := filepath.Clean(event.Name)
+
+			switch {
+			case event.Has(fsnotify.Create) || event.Has(fsnotify.Write):
+				if info, err := os.Stat(path); err == nil && info.IsDir() {
+					if event.Has(fsnotify.Create) && opts.recursive {
+						addWatchTree(watcher, opts, path, pending)
+					}
+					continue
+				}
+				if isWatchCandidate(opts, path) {
+					pending[path] = &pendingFile{size: -1, changed: time.Now()}
+				}
+			case event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename):
+				delete(pending, path)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Warnw("File watcher error", "error", err)
+
+		case now := <-ticker.C:
+			for path, p := range pending {
+				info, err := os.Stat(path)
+				if err != nil {
+					delete(pending, path)
+					continue
+				}
+				if p.settled(info.Size(), now, opts.settle) {
+					delete(pending, path)
+					queue = append(queue, path)
+				}
+			}
+
+		case send <- next:
+			queue = queue[1:]
+		}
+	}
+}
+
+// starts watching a directory created after startup, queueing any media
+// that was moved in along with it
+func addWatchTree(
+	watcher *fsnotify.Watcher,
+	opts *watchOptions,
+	dir string,
+	pending map[string]*pendingFile,
+) {
+	err := walkWatchTree(opts, dir, func(path string, isDir bool) error {
+		if isDir {
+			return watcher.Add(path)
+		}
+		if isWatchCandidate(opts, path) {
+			pending[path] = &pendingFile{size: -1, changed: time.Now()}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Warnw("Failed to watch directory", "directory", dir, "error", err)
+	}
+}
+
+// visits dir and, when recursive, its subdirectories, never entering the
+// done directory
+func walkWatchTree(
+	opts *watchOptions,
+	dir string,
+	visit func(path string, isDir bool) error,
+) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (!opts.recursive || isWithin(path, opts.doneDir)) {
+				return filepath.SkipDir
+			}
+			return visit(path, true)
+		}
+		return visit(path, false)
+	})
+}
+
+// reports whether the file has stopped growing. The size is sampled at most
+// once per settle period, so a file must keep one size for a full period.
+func (p *pendingFile) settled(
+	size int64,
+	now time.Time,
+	settle time.Duration,
+) bool {
+	if now.Sub(p.changed) < settle {
+		return false
+	}
+	if size != p.size {
+		p.size = size
+		p.changed = now
+		return false
+	}
+	return true
+}
+
+func watchPollInterval(settle time.Duration) time.Duration {
+	return min(max(settle/2, 100*time.Millisecond), time.Second)
+}
+
+// media files outside the done directory that do not have subtitles yet
+func isWatchCandidate(opts *watchOptions, path string) bool {
+	if !audio.IsMediaFile(path) || isWithin(path, opts.doneDir) {
+		return false
+	}
+	if strings.HasPrefix(filepath.Base(path), ".") {
+		return false
+	}
+	_, err := os.Stat(batchOutputPath(path, opts.generate))
+	return os.IsNotExist(err)
+}
+
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." ||
+		(rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// generates (and optionally translates) subtitles for one settled file, then
+// applies the on-complete action. Failures are logged and the watcher
+// carries on with the next file.
+func processWatchedFile(
+	ctx context.Context,
+	opts *watchOptions,
+	mediaPath string,
+) {
+	if !isWatchCandidate(opts, mediaPath) {
+		return
+	}
+
+	log := logger.With("file", filepath.Base(mediaPath))
+
+	result, err := generateSubtitles(ctx, opts.generate, mediaPath, "", log)
+	if err != nil {
+		log.Errorw("Subtitle generation failed", "error", err)
+		return
+	}
+	outputs := []string{result.Output}
+	log.Infow("Subtitles generated",
+		"output", result.Output,
+		"entries", result.Entries,
+	)
+
+	if opts.translate != nil {
+		translated, err := translateSubtitles(
+			ctx,
+			opts.translate,
+			result.Output,
+			opts.translate.outputPathFor(result.Output, result.Basename),
+			log,
+		)
+		if err != nil {
+			log.Errorw("Subtitle translation failed", "error", err)
+		} else {
+			outputs = append(outputs, translated.Output)
+			log.Infow("Subtitles translated", "output", translated.Output)
+		}
+	}
+
+	if opts.generate.output.sidecar {
+		for _, output := range outputs {
+			outputs = append(outputs, sidecarPath(output))
+		}
+	}
+	if err := finishWatchedFile(opts, mediaPath, outputs); err != nil {
+		log.Errorw("On-complete action failed",
+			"action", string(opts.onComplete),
+			"error", err,
+		)
+	}
+}
+
+// applies the on-complete action to a processed media file and its outputs
+func finishWatchedFile(
+	opts *watchOptions,
+	mediaPath string,
+	outputs []string,
+) error {
+	switch opts.onComplete {
+	case completeMove:
+		rel, err := filepath.Rel(opts.root, filepath.Dir(mediaPath))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = "."
+		}
+		destDir := filepath.Join(opts.doneDir, rel)
+		if err := os.MkdirAll(destDir, 0755); err != nil {
+			return fmt.Errorf("failed to create done directory: %w", err)
+		}
+		for _, path := range append([]string{mediaPath}, outputs...) {
+			dest := filepath.Join(destDir, filepath.Base(path))
+			if err := os.Rename(path, dest); err != nil {
+				return fmt.Errorf("failed to move %s: %w", path, err)
+			}
+		}
+	case completeDelete:
+		if err := os.Remove(mediaPath); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", mediaPath, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+func TestPendingFileSettled(t *testing.T) {
+	settle := 5 * time.Second
+	start := time.Now()
+	p := &pendingFile{size: -1, changed: start}
+
+	if p.settled(100, start.Add(time.Second), settle) {
+		t.Error("file should not settle before the settle period")
+	}
+	if p.settled(100, start.Add(settle), settle) {
+		t.Error("first size sample should not settle the file")
+	}
+	if p.settled(200, start.Add(2*settle), settle) {
+		t.Error("growing file should not settle")
+	}
+	if !p.settled(200, start.Add(3*settle), settle) {
+		t.Error("file with a stable size should settle")
+	}
+}
+
+func TestIsWithin(t *testing.T) {
+	root := filepath.Join("media", "incoming")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(root, "done", "ep01.mkv"), true},
+		{root, true},
+		{filepath.Join("media", "other", "ep01.mkv"), false},
+		{filepath.Join("media", "incoming-old", "ep01.mkv"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isWithin(tt.path, root); got != tt.want {
+			t.Errorf("isWithin(%q, %q) = %v, want %v", tt.path, root, got, tt.want)
+		}
+	}
+}
+
+func TestIsWatchCandidate(t *testing.T) {
+	root := t.TempDir()
+	opts := &watchOptions{
+		root:     root,
+		doneDir:  filepath.Join(root, "done"),
+		generate: &generateConfig{format: subtitle.FormatSRT},
+	}
+
+	write := func(name string) string {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+		return path
+	}
+
+	fresh := write("ep01.mkv")
+	subtitled := write("ep02.mkv")
+	write("ep02.srt")
+	finished := write(filepath.Join("done", "ep00.mkv"))
+	notes := write("notes.txt")
+	hidden := write(".ep03.mkv")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{fresh, true},
+		{subtitled, false},
+		{finished, false},
+		{notes, false},
+		{hidden, false},
+	}
+
+	for _, tt := range tests {
+		if got := isWatchCandidate(opts, tt.path); got != tt.want {
+			t.Errorf("isWatchCandidate(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFinishWatchedFileMove(t *testing.T) {
+	root := t.TempDir()
+	opts := &watchOptions{
+		root:       root,
+		onComplete: completeMove,
+		doneDir:    filepath.Join(root, "done"),
+	}
+
+	media := filepath.Join(root, "show", "ep01.mkv")
+	subs := filepath.Join(root, "show", "ep01.srt")
+	if err := os.MkdirAll(filepath.Dir(media), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	for _, path := range []string{media, subs} {
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+	}
+
+	if err := finishWatchedFile(opts, media, []string{subs}); err != nil {
+		t.Fatalf("finishWatchedFile error: %v", err)
+	}
+
+	for _, name := range []string{"ep01.mkv", "ep01.srt"} {
+		if _, err := os.Stat(filepath.Join(opts.doneDir, "show", name)); err != nil {
+			t.Errorf("expected %s in done directory: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(media); !os.IsNotExist(err) {
+		t.Error("media file should have been moved")
+	}
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/mgpai22/lipi/internal/audio"
+)
+
+// creates the per-run scratch directory under workDir (or the system temp
+// dir when empty) and returns a cleanup func that honors keep
+func newWorkDir(workDir string, keep bool) (string, func(), error) {
+	if workDir != "" {
+		if err := os.MkdirAll(workDir, 0755); err != nil {
+			return "", nil, fmt.Errorf(
+				"failed to create work directory: %w",
+				err,
+			)
+		}
+	}
+
+	tempDir, err := os.MkdirTemp(workDir, "lipi-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	cleanup := func() {
+		if keep {
+			logger.Infow("Keeping intermediate files", "work_dir", tempDir)
+			return
+		}
+		_ = os.RemoveAll(tempDir)
+	}
+
+	return tempDir, cleanup, nil
+}
+
+// estimates the space a generate run needs from the source duration and
+// fails early when the work directory's volume cannot hold it
+func checkWorkspace(
+	ctx context.Context,
+	mediaPath, workDir, chunkFormat string,
+	isolateVoice bool,
+) error {
+	duration, err := audio.GetDuration(ctx, mediaPath)
+	if err != nil {
+		// the real failure surfaces with a better message during extraction
+		return nil
+	}
+
+	need := audio.EstimateWorkspace(duration, chunkFormat, isolateVoice)
+	warn, err := audio.CheckDiskSpace(workDir, need)
+	if err != nil {
+		return err
+	}
+	if warn {
+		logger.Warnw("Work directory is nearly full",
+			"work_dir", workDir,
+			"estimated", audio.FormatBytes(uint64(need)),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/httpclient"
+	"github.com/mgpai22/lipi/internal/jobs"
+	"github.com/mgpai22/lipi/internal/server"
+	"github.com/mgpai22/lipi/internal/transcribe"
+	"github.com/mgpai22/lipi/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+// environment variable with the token of serve's worker endpoints
+const workerTokenEnv = "LIPI_WORKER_TOKEN"
+
+// how often a worker renews the lease on its job between chunks; well
+// inside jobs.DefaultLease
+const workerHeartbeat = 30 * time.Second
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Process jobs from a lipi server's queue",
+	Long: `Pull queued jobs from a 'lipi serve' started with --worker-token and
+process them on this machine: the media is downloaded from the server (or
+from the submitted URL), ffmpeg and the provider calls run here, and the
+finished subtitles are uploaded back. Run workers on several machines to
+caption a large library in parallel.
+
+Generation flags (provider, model, chunking, etc.) set this worker's
+defaults; a job's format and language overrides apply as they do on the
+server. Provider keys are read on this machine. The worker keeps its job's
+lease with a heartbeat and stops work on a job cancelled with 'lipi jobs
+cancel'. A job interrupted by stopping the worker goes back to the queue
+once its lease runs out.
+
+Examples:
+  lipi worker --server http://captions.internal:8080 --worker-token "$TOKEN"
+  LIPI_WORKER_TOKEN=... lipi worker --server http://10.0.0.5:8080 --jobs 2 --provider gemini`,
+	Args: cobra.NoArgs,
+	RunE: runWorker,
+}
+
+func init() {
+	rootCmd.AddCommand(workerCmd)
+
+	addGenerateFlags(workerCmd)
+	workerCmd.Flags().
+		String("server", "", "Base URL of the lipi server (required)")
+	workerCmd.Flags().
+		String("worker-token", "", "Token the server was started with (or set "+workerTokenEnv+")")
+	workerCmd.Flags().
+		String("name", "", "Name of this worker in the server's job list (default: host name)")
+	workerCmd.Flags().
+		IntP("jobs", "j", 1, "Number of jobs processed at the same time")
+	workerCmd.Flags().
+		Duration("poll-interval", 5*time.Second, "How long to wait before asking again when the queue is empty")
+	workerCmd.Flags().
+		Bool("allow-private-urls", false, "Fetch job URLs of loopback, link-local, and private addresses")
+
+	_ = workerCmd.MarkFlagRequired("server")
+}
+
+// --worker-token, or the environment variable
+func workerToken(cmd *cobra.Command) string {
+	token, _ := cmd.Flags().GetString("worker-token")
+	if token == "" {
+		token = os.Getenv(workerTokenEnv)
+	}
+	return token
+}
+
+func runWorker(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	serverURL, _ := cmd.Flags().GetString("server")
+	name, _ := cmd.Flags().GetString("name")
+	workers, _ := cmd.Flags().GetInt("jobs")
+	pollInterval, _ := cmd.Flags().GetDuration("poll-interval")
+	allowPrivateURLs, _ := cmd.Flags().GetBool("allow-private-urls")
+	token := workerToken(cmd)
+
+	if token == "" {
+		return inputErrorf("a worker token is required: use --worker-token or set %s", workerTokenEnv)
+	}
+	if workers <= 0 {
+		return inputErrorf("jobs must be positive, got %d", workers)
+	}
+	if pollInterval <= 0 {
+		return inputErrorf("poll-interval must be positive, got %s", pollInterval)
+	}
+	if name == "" {
+		if name, _ = os.Hostname(); name == "" {
+			name = "worker"
+		}
+	}
+
+	cfg, err := newGenerateConfig(cmd)
+	if err != nil {
+		return err
+	}
+	// concurrent jobs share one request budget, as the files of a batch do
+	cfg.limiter = transcribe.NewLimiter(batchRequestBudget(cfg))
+	cfg.publicURLsOnly = !allowPrivateURLs
+
+	client := &server.WorkerClient{
+		BaseURL: serverURL,
+		Token:   token,
+		Worker:  name,
+		HTTP:    httpclient.Default(),
+	}
+	logger.Infow("Worker started", "server", serverURL, "worker", name, "jobs", workers)
+
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errList []error
+	for range workers {
+		wg.Go(func() {
+			if err := workerLoop(ctx, client, cfg, pollInterval); err != nil {
+				mu.Lock()
+				errList = append(errList, err)
+				mu.Unlock()
+				stop()
+			}
+		})
+	}
+	wg.Wait()
+	return errors.Join(errList...)
+}
+
+// claims and runs jobs until ctx is cancelled, or the server rejects the
+// token
+func workerLoop(ctx context.Context, client *server.WorkerClient, cfg *generateConfig, pollInterval time.Duration) error {
+	for ctx.Err() == nil {
+		job, err := client.Claim(ctx)
+		if errs.KindOf(err) == errs.KindAuth {
+			return err
+		}
+		if err != nil && ctx.Err() == nil {
+			logger.Warnw("Failed to claim a job", "error", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		runRemoteJob(ctx, client, cfg, job)
+	}
+	return nil
+}
+
+// generates subtitles for a claimed job and reports the outcome
+func runRemoteJob(ctx context.Context, client *server.WorkerClient, defaults *generateConfig, job *server.WorkerJob) {
+	log := logger.With("job", job.ID)
+	log.Infow("Job claimed", "name", job.Name)
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var done, total atomic.Int64
+	heartbeat := func() {
+		err := client.Heartbeat(ctx, job.ID, int(done.Load()), int(total.Load()))
+		if errors.Is(err, jobs.ErrCancelled) || errors.Is(err, jobs.ErrState) {
+			cancel(err)
+		} else if err != nil && ctx.Err() == nil {
+			log.Warnw("Failed to renew the job lease", "error", err)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(workerHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-jobCtx.Done():
+				return
+			case <-ticker.C:
+				heartbeat()
+			}
+		}
+	}()
+
+	dir, err := os.MkdirTemp(defaults.workDir, "lipi-worker-")
+	if err != nil {
+		reportRemoteFailure(ctx, client, job, fmt.Errorf("failed to create temp directory: %w", err), usage.Usage{})
+		return
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	cfg, name := jobGenerateConfig(defaults, job.Name, job.Options)
+	cfg.onChunk = func(d, t int) {
+		done.Store(int64(d))
+		total.Store(int64(t))
+		heartbeat()
+	}
+
+	result, err := func() (*generateResult, error) {
+		input, err := client.Media(jobCtx, job, dir)
+		if err != nil {
+			return nil, err
+		}
+		return generateSubtitles(jobCtx, &cfg, input, filepath.Join(dir, name), log)
+	}()
+	if cause := context.Cause(jobCtx); errors.Is(cause, jobs.ErrCancelled) {
+		log.Infow("Job cancelled")
+		return
+	} else if errors.Is(cause, jobs.ErrState) {
+		log.Warnw("Job was given to another worker", "error", cause)
+		return
+	}
+	if ctx.Err() != nil {
+		// stopping; the server requeues the job when its lease runs out
+		return
+	}
+	if err != nil {
+		log.Errorw("Job failed", "error", err)
+		reportRemoteFailure(ctx, client, job, err, usage.Usage{})
+		return
+	}
+
+	complete := &jobs.Result{
+		Output:       result.Output,
+		Entries:      result.Entries,
+		MediaSeconds: result.Duration.Seconds(),
+		Usage:        result.Usage,
+	}
+	if err := client.Complete(ctx, job.ID, complete); err != nil {
+		log.Errorw("Failed to upload the subtitle", "error", err)
+		return
+	}
+	log.Infow("Job complete", "entries", result.Entries)
+}
+
+func reportRemoteFailure(ctx context.Context, client *server.WorkerClient, job *server.WorkerJob, err error, used usage.Usage) {
+	if reportErr := client.Fail(ctx, job.ID, err, errs.Code(err), used); reportErr != nil {
+		logger.Warnw("Failed to report the job failure", "job", job.ID, "error", reportErr)
+	}
+}
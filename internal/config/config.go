@@ -0,0 +1,284 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds defaults read from config.yaml. Top-level scalar keys apply to
+// every command with a matching flag; a top-level map named after a command
+// (generate, translate, ...) overrides them for that command only.
+//
+//	provider: gemini
+//	concurrency: 4
+//	api_keys:
+//	  gemini: ...
+//	notify:
+//	  slack: https://hooks.slack.com/services/...
+//	generate:
+//	  format: vtt
+//	translate:
+//	  provider: anthropic
+//	profiles:
+//	  anime-jp:
+//	    model: gemini-2.5-pro
+//	    language: ja
+//	    glossary: ~/lipi/anime.txt
+//
+// A profile has the same shape as the top level and, once selected with
+// UseProfile, takes precedence over it.
+type Config struct {
+	Path     string
+	base     *section
+	profiles map[string]*section
+	profile  *section
+	apiKeys  map[string]string
+	notify   map[string][]string
+}
+
+// flag values that apply globally and per command
+type section struct {
+	global   map[string]string
+	commands map[string]map[string]string
+}
+
+func newSection() *section {
+	return &section{
+		global:   map[string]string{},
+		commands: map[string]map[string]string{},
+	}
+}
+
+// location of the user config file, usually ~/.config/lipi/config.yaml
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil || dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "lipi", "config.yaml")
+}
+
+// Load reads the config at path. A missing file yields an empty config
+// unless required is set, as it is for an explicit --config.
+func Load(path string, required bool) (*Config, error) {
+	cfg := &Config{
+		Path:     path,
+		base:     newSection(),
+		profiles: map[string]*section{},
+		apiKeys:  map[string]string{},
+		notify:   map[string][]string{},
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !required {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	for key, value := range raw {
+		key = normalizeKey(key)
+		switch key {
+		case "api-keys":
+			keys, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("api_keys must be a map of provider to key")
+			}
+			flat, err := flatten(keys)
+			if err != nil {
+				return nil, fmt.Errorf("invalid api_keys: %w", err)
+			}
+			for provider, apiKey := range flat {
+				cfg.apiKeys[provider] = apiKey
+			}
+		case "notify":
+			targets, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("notify must be a map of target kind to URLs")
+			}
+			flat, err := flatten(targets)
+			if err != nil {
+				return nil, fmt.Errorf("invalid notify: %w", err)
+			}
+			for kind, urls := range flat {
+				for _, u := range strings.Split(urls, ",") {
+					if u = strings.TrimSpace(u); u != "" {
+						cfg.notify[kind] = append(cfg.notify[kind], u)
+					}
+				}
+			}
+		case "profiles":
+			profiles, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("profiles must be a map of name to settings")
+			}
+			for name, body := range profiles {
+				m, ok := body.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("profile %q must be a map", name)
+				}
+				sec := newSection()
+				if err := sec.load(m); err != nil {
+					return nil, fmt.Errorf("invalid profile %q: %w", name, err)
+				}
+				cfg.profiles[name] = sec
+			}
+		default:
+			if err := cfg.base.load(map[string]any{key: value}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// adds scalar keys as global values and maps as command sections
+func (s *section) load(raw map[string]any) error {
+	for key, value := range raw {
+		key = normalizeKey(key)
+		if m, ok := value.(map[string]any); ok {
+			flat, err := flatten(m)
+			if err != nil {
+				return fmt.Errorf("invalid config section %q: %w", key, err)
+			}
+			s.commands[key] = flat
+			continue
+		}
+		v, err := scalar(value)
+		if err != nil {
+			return fmt.Errorf("invalid config value for %q: %w", key, err)
+		}
+		s.global[key] = v
+	}
+	return nil
+}
+
+func (s *section) lookup(command, flag string) (string, bool) {
+	if values, ok := s.commands[command]; ok {
+		if v, ok := values[flag]; ok {
+			return v, true
+		}
+	}
+	v, ok := s.global[flag]
+	return v, ok
+}
+
+// selects a named profile; an empty name falls back to the config's own
+// "profile" key, if any
+func (c *Config) UseProfile(name string) error {
+	if name == "" {
+		name = c.base.global["profile"]
+	}
+	if name == "" {
+		return nil
+	}
+	p, ok := c.profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q (available: %s)", name, c.profileNames())
+	}
+	c.profile = p
+	return nil
+}
+
+// sorted profile names, for error messages and listings
+func (c *Config) Profiles() []string {
+	names := make([]string, 0, len(c.profiles))
+	for name := range c.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Config) profileNames() string {
+	names := c.Profiles()
+	if len(names) == 0 {
+		return "none defined"
+	}
+	return strings.Join(names, ", ")
+}
+
+// value for a flag on command. The active profile wins over the top level,
+// and a command's own section wins over global values within each.
+func (c *Config) Lookup(command, flag string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	if c.profile != nil {
+		if v, ok := c.profile.lookup(command, flag); ok {
+			return v, true
+		}
+	}
+	return c.base.lookup(command, flag)
+}
+
+// configured API key for a provider, if any
+func (c *Config) APIKey(provider string) string {
+	if c == nil {
+		return ""
+	}
+	return c.apiKeys[strings.ToLower(provider)]
+}
+
+// notification URLs by target kind (webhook, slack, discord, ntfy)
+func (c *Config) Notify() map[string][]string {
+	if c == nil {
+		return nil
+	}
+	return c.notify
+}
+
+func flatten(m map[string]any) (map[string]string, error) {
+	out := make(map[string]string, len(m))
+	for key, value := range m {
+		s, err := scalar(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		out[normalizeKey(key)] = s
+	}
+	return out, nil
+}
+
+// converts a YAML value to the string form pflag accepts
+func scalar(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, int, int64, float64:
+		return fmt.Sprint(v), nil
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			s, err := scalar(item)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+// accepts snake_case keys for kebab-case flags
+func normalizeKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "_", "-")
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const sampleConfig = `
+provider: gemini
+concurrency: 4
+api_keys:
+  gemini: gem-key
+  Anthropic: ant-key
+generate:
+  format: vtt
+  chunk_duration: 2
+  isolate-voice: true
+translate:
+  provider: anthropic
+`
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLookup(t *testing.T) {
+	cfg, err := Load(writeConfig(t, sampleConfig), true)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	tests := []struct {
+		command, flag string
+		want          string
+		ok            bool
+	}{
+		{"generate", "format", "vtt", true},
+		{"generate", "chunk-duration", "2", true},
+		{"generate", "isolate-voice", "true", true},
+		{"generate", "provider", "gemini", true},
+		{"translate", "provider", "anthropic", true},
+		{"translate", "concurrency", "4", true},
+		{"translate", "format", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := cfg.Lookup(tt.command, tt.flag)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf(
+				"Lookup(%q, %q) = %q, %v, want %q, %v",
+				tt.command,
+				tt.flag,
+				got,
+				ok,
+				tt.want,
+				tt.ok,
+			)
+		}
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	cfg, err := Load(writeConfig(t, sampleConfig), true)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if got := cfg.APIKey("gemini"); got != "gem-key" {
+		t.Errorf("APIKey(gemini) = %q, want gem-key", got)
+	}
+	if got := cfg.APIKey("anthropic"); got != "ant-key" {
+		t.Errorf("APIKey(anthropic) = %q, want ant-key", got)
+	}
+	if got := cfg.APIKey("openai"); got != "" {
+		t.Errorf("APIKey(openai) = %q, want empty", got)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `
+notify:
+  slack: https://hooks.slack.com/services/T/B/X
+  webhook:
+    - https://a.example/hook
+    - https://b.example/hook
+`), true)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	notify := cfg.Notify()
+	if got := notify["slack"]; len(got) != 1 || got[0] != "https://hooks.slack.com/services/T/B/X" {
+		t.Errorf("slack = %v", got)
+	}
+	if got := notify["webhook"]; len(got) != 2 || got[1] != "https://b.example/hook" {
+		t.Errorf("webhook = %v", got)
+	}
+	if _, ok := cfg.Lookup("generate", "slack"); ok {
+		t.Error("notify targets should not become flag values")
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.yaml")
+
+	cfg, err := Load(path, false)
+	if err != nil {
+		t.Fatalf("optional config should not fail: %v", err)
+	}
+	if _, ok := cfg.Lookup("generate", "format"); ok {
+		t.Error("empty config should have no values")
+	}
+
+	if _, err := Load(path, true); err == nil {
+		t.Error("expected error for missing required config")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	if _, err := Load(writeConfig(t, "provider: [unclosed"), true); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+const profileConfig = `
+model: gemini-2.5-flash
+profile: podcast
+profiles:
+  anime-jp:
+    model: gemini-2.5-pro
+    language: ja
+    translate:
+      provider: anthropic
+  podcast:
+    format: vtt
+`
+
+func TestUseProfile(t *testing.T) {
+	cfg, err := Load(writeConfig(t, profileConfig), true)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if err := cfg.UseProfile("anime-jp"); err != nil {
+		t.Fatalf("UseProfile error: %v", err)
+	}
+
+	tests := []struct {
+		command, flag, want string
+	}{
+		{"generate", "model", "gemini-2.5-pro"},
+		{"generate", "language", "ja"},
+		{"translate", "provider", "anthropic"},
+	}
+	for _, tt := range tests {
+		if got, _ := cfg.Lookup(tt.command, tt.flag); got != tt.want {
+			t.Errorf(
+				"Lookup(%q, %q) = %q, want %q",
+				tt.command,
+				tt.flag,
+				got,
+				tt.want,
+			)
+		}
+	}
+	if _, ok := cfg.Lookup("generate", "format"); ok {
+		t.Error("values from other profiles should not apply")
+	}
+}
+
+func TestUseProfileDefault(t *testing.T) {
+	cfg, err := Load(writeConfig(t, profileConfig), true)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if err := cfg.UseProfile(""); err != nil {
+		t.Fatalf("UseProfile error: %v", err)
+	}
+	if got, _ := cfg.Lookup("generate", "format"); got != "vtt" {
+		t.Errorf("expected default profile format vtt, got %q", got)
+	}
+	if got, _ := cfg.Lookup("generate", "model"); got != "gemini-2.5-flash" {
+		t.Errorf("expected top-level model, got %q", got)
+	}
+}
+
+func TestUseProfileUnknown(t *testing.T) {
+	cfg, err := Load(writeConfig(t, profileConfig), true)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if err := cfg.UseProfile("missing"); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// CacheDirEnv moves every lipi cache (ffmpeg, yt-dlp, models, server data)
+// to one directory, e.g. a volume in a container
+const CacheDirEnv = "LIPI_CACHE_DIR"
+
+// CacheDir is the directory lipi keeps its caches in: $LIPI_CACHE_DIR, or
+// lipi in the user cache dir. When the user cache dir is unknown or not
+// writable, as for a container user without a home directory, it falls
+// back to lipi in the temp dir.
+func CacheDir() string {
+	if dir := os.Getenv(CacheDirEnv); dir != "" {
+		return dir
+	}
+	if dir, err := os.UserCacheDir(); err == nil && dir != "" {
+		path := filepath.Join(dir, "lipi")
+		if os.MkdirAll(path, 0755) == nil {
+			return path
+		}
+	}
+	return filepath.Join(os.TempDir(), "lipi")
+}
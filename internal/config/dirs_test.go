@@ -0,0 +1,30 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCacheDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(CacheDirEnv, dir)
+	if got := CacheDir(); got != dir {
+		t.Errorf("expected %s from %s, got %s", dir, CacheDirEnv, got)
+	}
+
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG_CACHE_HOME only applies on linux")
+	}
+	t.Setenv(CacheDirEnv, "")
+	// a file where the cache dir should be, as good as a read-only home
+	blocked := filepath.Join(t.TempDir(), "cache")
+	if err := os.WriteFile(blocked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_CACHE_HOME", blocked)
+	if got, want := CacheDir(), filepath.Join(os.TempDir(), "lipi"); got != want {
+		t.Errorf("expected fallback %s, got %s", want, got)
+	}
+}
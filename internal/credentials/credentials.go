@@ -0,0 +1,55 @@
+package credentials
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotFound is returned when no key is stored for a provider
+var ErrNotFound = errors.New("no key stored")
+
+// Store keeps provider API keys outside of shell history and plain
+// environment variables
+type Store interface {
+	// Name identifies the backend in messages, e.g. "macOS keychain"
+	Name() string
+	Get(provider string) (string, error)
+	Set(provider, key string) error
+	Delete(provider string) error
+}
+
+// Passphrase returns the passphrase protecting the encrypted file store
+type Passphrase func() (string, error)
+
+// DefaultFilePath is where the encrypted file store lives next to the
+// config file
+func DefaultFilePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil || dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "lipi", "credentials.enc")
+}
+
+// Lookup returns the stored key for provider from the OS keychain, or from
+// the encrypted file when it exists. Any failure yields an empty key so
+// callers can fall through to their missing-key error.
+func Lookup(provider string, passphrase Passphrase) string {
+	if keychain, ok := Keychain(); ok {
+		if key, err := keychain.Get(provider); err == nil {
+			return key
+		}
+	}
+
+	path := DefaultFilePath()
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	key, err := EncryptedFile(path, passphrase).Get(provider)
+	if err != nil {
+		return ""
+	}
+	return key
+}
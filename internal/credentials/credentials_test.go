@@ -0,0 +1,140 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func init() {
+	kdfIterations = 1000
+}
+
+func fixedPassphrase(p string) Passphrase {
+	return func() (string, error) { return p, nil }
+}
+
+func TestEncryptedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	store := EncryptedFile(path, fixedPassphrase("correct horse"))
+
+	if _, err := store.Get("gemini"); err == nil {
+		t.Fatalf("Get() on a missing file succeeded")
+	}
+	if err := store.Set("gemini", "g-key"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Set("openai", "o-key"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	key, err := store.Get("gemini")
+	if err != nil || key != "g-key" {
+		t.Errorf("Get(gemini) = %q, %v; want g-key", key, err)
+	}
+	if _, err := store.Get("anthropic"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(anthropic) error = %v, want ErrNotFound", err)
+	}
+
+	wrong := EncryptedFile(path, fixedPassphrase("battery staple"))
+	if _, err := wrong.Get("gemini"); err == nil {
+		t.Error("Get() with the wrong passphrase succeeded")
+	}
+
+	if err := store.Delete("gemini"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("gemini"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(gemini) after Delete error = %v, want ErrNotFound", err)
+	}
+	if key, _ := store.Get("openai"); key != "o-key" {
+		t.Errorf("Get(openai) after Delete = %q, want o-key", key)
+	}
+}
+
+// the exit status of a fake keychain tool
+type exitCode int
+
+func (e exitCode) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e exitCode) ExitCode() int { return int(e) }
+
+func TestCommandKeychain(t *testing.T) {
+	tests := []struct {
+		goos      string
+		wantSet   []string
+		wantStdin string
+		notFound  error // what lookup fails with when nothing is stored
+		locked    error // and when the keychain cannot be read
+	}{
+		{
+			goos:      "darwin",
+			wantSet:   []string{"add-generic-password", "-U", "-s", "lipi", "-a", "gemini", "-l", "lipi gemini API key", "-w"},
+			wantStdin: "secret\nsecret\n",
+			notFound:  exitCode(44),
+			locked:    exitCode(51),
+		},
+		{
+			goos:      "linux",
+			wantSet:   []string{"store", "--label", "lipi gemini API key", "service", "lipi", "provider", "gemini"},
+			wantStdin: "secret",
+			notFound:  exitCode(1),
+			locked:    fmt.Errorf("%w: %s", exitCode(1), "Cannot autolaunch D-Bus without X11 $DISPLAY"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			stored := map[string]string{}
+			var lastArgs []string
+			var lastStdin string
+			var lookupErr error
+			k := &commandKeychain{
+				name: "test keychain",
+				goos: tt.goos,
+				tool: "tool",
+				run: func(stdin, name string, args ...string) (string, error) {
+					lastArgs, lastStdin = args, stdin
+					switch args[0] {
+					case "add-generic-password":
+						stored["gemini"], _, _ = strings.Cut(stdin, "\n")
+					case "store":
+						stored["gemini"] = stdin
+					case "find-generic-password", "lookup":
+						if lookupErr != nil {
+							return "", lookupErr
+						}
+						if key, ok := stored["gemini"]; ok {
+							return key, nil
+						}
+						return "", tt.notFound
+					}
+					return "", nil
+				},
+			}
+
+			if _, err := k.Get("gemini"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() before Set error = %v, want ErrNotFound", err)
+			}
+			if err := k.Set("gemini", "secret"); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if !slices.Equal(lastArgs, tt.wantSet) {
+				t.Errorf("Set() args = %v, want %v", lastArgs, tt.wantSet)
+			}
+			if lastStdin != tt.wantStdin {
+				t.Errorf("Set() stdin = %q, want %q", lastStdin, tt.wantStdin)
+			}
+			if key, err := k.Get("gemini"); err != nil || key != "secret" {
+				t.Errorf("Get() = %q, %v; want secret", key, err)
+			}
+
+			lookupErr = tt.locked
+			if _, err := k.Get("gemini"); err == nil || errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() of a locked keychain error = %v, want it returned", err)
+			}
+		})
+	}
+}
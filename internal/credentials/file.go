@@ -0,0 +1,177 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// file layout: magic, salt, GCM nonce, then the sealed JSON map of
+// provider to key
+var fileMagic = []byte("LIPICRED1")
+
+const (
+	saltSize = 16
+	keySize  = 32
+)
+
+// PBKDF2 rounds; a variable so tests can keep key derivation cheap
+var kdfIterations = 600_000
+
+// store in a file encrypted with AES-GCM under a passphrase-derived key
+type fileStore struct {
+	path       string
+	passphrase Passphrase
+}
+
+// EncryptedFile returns a store kept in the file at path, for systems
+// without a usable keychain
+func EncryptedFile(path string, passphrase Passphrase) Store {
+	return &fileStore{path: path, passphrase: passphrase}
+}
+
+func (f *fileStore) Name() string {
+	return "encrypted file " + f.path
+}
+
+func (f *fileStore) Get(provider string) (string, error) {
+	keys, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	key, ok := keys[provider]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return key, nil
+}
+
+func (f *fileStore) Set(provider, key string) error {
+	keys, err := f.load()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if keys == nil {
+		keys = map[string]string{}
+	}
+	keys[provider] = key
+	return f.save(keys)
+}
+
+func (f *fileStore) Delete(provider string) error {
+	keys, err := f.load()
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if _, ok := keys[provider]; !ok {
+		return ErrNotFound
+	}
+	delete(keys, provider)
+	return f.save(keys)
+}
+
+// reads and decrypts the file; a missing file is reported as
+// os.ErrNotExist
+func (f *fileStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(data, fileMagic) {
+		return nil, fmt.Errorf("%s is not a lipi credentials file", f.path)
+	}
+	data = data[len(fileMagic):]
+	if len(data) < saltSize {
+		return nil, fmt.Errorf("%s is truncated", f.path)
+	}
+	salt, data := data[:saltSize], data[saltSize:]
+
+	aead, err := f.cipher(salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("%s is truncated", f.path)
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, fileMagic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: wrong passphrase or corrupted file", f.path)
+	}
+
+	var keys map[string]string
+	if err := json.Unmarshal(plain, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", f.path, err)
+	}
+	return keys, nil
+}
+
+// encrypts keys under a fresh salt and nonce and replaces the file
+func (f *fileStore) save(keys map[string]string) error {
+	plain, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := f.cipher(salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := append([]byte{}, fileMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	out = aead.Seal(out, nonce, plain, fileMagic)
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0600); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	return nil
+}
+
+func (f *fileStore) cipher(salt []byte) (cipher.AEAD, error) {
+	passphrase, err := f.passphrase()
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return nil, errors.New("credentials passphrase is empty")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, kdfIterations, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,142 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// service name the keys are stored under in the keychain
+const service = "lipi"
+
+// runs a keychain tool with optional stdin and returns its trimmed stdout
+type runner func(stdin, name string, args ...string) (string, error)
+
+// keychain backed by the platform's command-line tool: security on macOS,
+// secret-tool (libsecret) on Linux
+type commandKeychain struct {
+	name string
+	goos string
+	tool string
+	run  runner
+}
+
+// Keychain returns the OS keychain store, if the platform has one whose
+// command-line tool is installed
+func Keychain() (Store, bool) {
+	var name, tool string
+	switch runtime.GOOS {
+	case "darwin":
+		name, tool = "macOS keychain", "security"
+	case "linux", "freebsd", "openbsd":
+		name, tool = "Secret Service keyring", "secret-tool"
+	default:
+		return nil, false
+	}
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return nil, false
+	}
+	return &commandKeychain{
+		name: name,
+		goos: runtime.GOOS,
+		tool: path,
+		run:  runCommand,
+	}, true
+}
+
+func (k *commandKeychain) Name() string {
+	return k.name
+}
+
+func (k *commandKeychain) Get(provider string) (string, error) {
+	var key string
+	var err error
+	if k.goos == "darwin" {
+		key, err = k.run("", k.tool,
+			"find-generic-password", "-s", service, "-a", provider, "-w")
+	} else {
+		key, err = k.run("", k.tool,
+			"lookup", "service", service, "provider", provider)
+	}
+	if k.notFound(err) || (err == nil && key == "") {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read from %s: %w", k.name, err)
+	}
+	return key, nil
+}
+
+// reports whether a failed lookup means nothing is stored: security exits
+// with errSecItemNotFound (44), secret-tool with 1 and no error message.
+// Other failures, such as a locked keychain, are returned as they are.
+func (k *commandKeychain) notFound(err error) bool {
+	var exitErr interface{ ExitCode() int }
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	if k.goos == "darwin" {
+		return exitErr.ExitCode() == 44
+	}
+	// runCommand wraps the exit error with stderr when there is any
+	return exitErr.ExitCode() == 1 && errors.Unwrap(err) == nil
+}
+
+func (k *commandKeychain) Set(provider, key string) error {
+	var err error
+	if k.goos == "darwin" {
+		// -U updates an existing item instead of failing. A trailing -w
+		// prompts for the key and its retype, which are read from stdin to
+		// keep the key off the command line.
+		_, err = k.run(key+"\n"+key+"\n", k.tool,
+			"add-generic-password", "-U", "-s", service, "-a", provider,
+			"-l", "lipi "+provider+" API key", "-w")
+	} else {
+		// secret-tool reads the secret from stdin, keeping it off the
+		// command line
+		_, err = k.run(key, k.tool,
+			"store", "--label", "lipi "+provider+" API key",
+			"service", service, "provider", provider)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write to %s: %w", k.name, err)
+	}
+	return nil
+}
+
+func (k *commandKeychain) Delete(provider string) error {
+	if _, err := k.Get(provider); err != nil {
+		return err
+	}
+	var err error
+	if k.goos == "darwin" {
+		_, err = k.run("", k.tool,
+			"delete-generic-password", "-s", service, "-a", provider)
+	} else {
+		_, err = k.run("", k.tool,
+			"clear", "service", service, "provider", provider)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete from %s: %w", k.name, err)
+	}
+	return nil
+}
+
+func runCommand(stdin, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,163 @@
+package dubbing
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a dubbing script file type
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+// FormatForPath picks the file type from the output extension, CSV unless
+// it is .xlsx
+func FormatForPath(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".xlsx") {
+		return FormatXLSX
+	}
+	return FormatCSV
+}
+
+// Cue is a subtitle entry with its translation
+type Cue struct {
+	Start       time.Duration
+	End         time.Duration
+	Speaker     string
+	Original    string
+	Translation string
+}
+
+// markup and sound descriptions in cue text, which are not spoken
+var unspoken = regexp.MustCompile(`\{[^}]*\}|<[^>]*>|\[[^\]]*\]|\\[Nnh]|[♪♫]`)
+
+// SpeechText is the part of a cue's text a voice says: ASS override tags,
+// HTML tags, bracketed sound descriptions, and music notes are dropped
+func SpeechText(text string) string {
+	return strings.Join(strings.Fields(unspoken.ReplaceAllString(text, " ")), " ")
+}
+
+// DefaultRate is a comfortable speaking rate for voice-over, in syllables
+// per second
+const DefaultRate = 6.0
+
+type Options struct {
+	Rate float64 // syllables per second a line may take (default 6)
+}
+
+// Line is a script row: a cue with its timing budget
+type Line struct {
+	Cue
+	Number              int
+	Duration            time.Duration
+	OriginalSyllables   int
+	TranslatedSyllables int
+	Budget              int     // syllables that fit in the cue at the speaking rate
+	Rate                float64 // syllables per second the translation needs
+}
+
+// Over reports whether the translation needs more syllables than fit
+func (l Line) Over() bool {
+	return l.TranslatedSyllables > l.Budget
+}
+
+// Lines works out the timing budget of each cue
+func Lines(cues []Cue, opts Options) []Line {
+	rate := opts.Rate
+	if rate <= 0 {
+		rate = DefaultRate
+	}
+	lines := make([]Line, len(cues))
+	for i, cue := range cues {
+		duration := max(cue.End-cue.Start, 0)
+		line := Line{
+			Cue:                 cue,
+			Number:              i + 1,
+			Duration:            duration,
+			OriginalSyllables:   Syllables(cue.Original),
+			TranslatedSyllables: Syllables(cue.Translation),
+			Budget:              int(math.Floor(duration.Seconds() * rate)),
+		}
+		if duration > 0 {
+			line.Rate = float64(line.TranslatedSyllables) / duration.Seconds()
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+// script columns, in order
+var header = []string{
+	"#", "Start", "End", "Duration (s)", "Speaker", "Original", "Translation",
+	"Original syllables", "Translation syllables", "Syllable budget", "Syllables/s", "Status",
+}
+
+// cell values of a line; numeric cells are marked so spreadsheets get numbers
+func (l Line) cells() []cell {
+	status := "OK"
+	if l.Over() {
+		status = fmt.Sprintf("Over by %d", l.TranslatedSyllables-l.Budget)
+	}
+	return []cell{
+		{value: strconv.Itoa(l.Number), number: true},
+		{value: timecode(l.Start)},
+		{value: timecode(l.End)},
+		{value: strconv.FormatFloat(l.Duration.Seconds(), 'f', 3, 64), number: true},
+		{value: l.Speaker},
+		{value: l.Original},
+		{value: l.Translation},
+		{value: strconv.Itoa(l.OriginalSyllables), number: true},
+		{value: strconv.Itoa(l.TranslatedSyllables), number: true},
+		{value: strconv.Itoa(l.Budget), number: true},
+		{value: strconv.FormatFloat(l.Rate, 'f', 1, 64), number: true},
+		{value: status},
+	}
+}
+
+type cell struct {
+	value  string
+	number bool
+}
+
+// HH:MM:SS.mmm
+func timecode(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// Write encodes lines as a script in format
+func Write(w io.Writer, format Format, lines []Line) error {
+	if format == FormatXLSX {
+		return writeXLSX(w, lines)
+	}
+	return writeCSV(w, lines)
+}
+
+func writeCSV(w io.Writer, lines []Line) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, line := range lines {
+		cells := line.cells()
+		record := make([]string, len(cells))
+		for i, c := range cells {
+			record[i] = c.value
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,211 @@
+package dubbing
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyllables(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"Hello world", 3},
+		{"¿Dónde está la biblioteca?", 9},
+		{"Здравствуйте", 3},
+		{"こんにちは", 5},
+		{"你好", 2},
+		{"नमस्ते", 4},
+		{"It's 2024!", 5},
+		{"", 0},
+		{"...", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := Syllables(tt.text); got != tt.want {
+				t.Errorf("Syllables(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpeechText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Hello\nworld", "Hello world"},
+		{`{\i1}Hola{\i0}\Nmundo`, "Hola mundo"},
+		{"<i>Run!</i> [door slams]", "Run!"},
+		{"♪ La la la ♪", "La la la"},
+		{"[MUSIC]", ""},
+	}
+	for _, tt := range tests {
+		if got := SpeechText(tt.text); got != tt.want {
+			t.Errorf("SpeechText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func testLines() []Line {
+	return Lines([]Cue{
+		{Start: time.Second, End: 2500 * time.Millisecond, Speaker: "Ana", Original: "Hello world", Translation: "Hola, ¿qué tal?"},
+		{Start: 3 * time.Second, End: 3500 * time.Millisecond, Original: "Go!", Translation: "¡Vámonos ya, rápido!"},
+	}, Options{})
+}
+
+func TestLines(t *testing.T) {
+	lines := testLines()
+	if first := lines[0]; first.Budget != 9 || first.TranslatedSyllables != 4 || first.Over() {
+		t.Errorf("line 1 = %+v", first)
+	}
+	if second := lines[1]; second.Budget != 3 || !second.Over() || second.Rate != 14 {
+		t.Errorf("line 2 = %+v", second)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatCSV, testLines()); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("script is not valid CSV: %v", err)
+	}
+	if len(records) != 3 || len(records[0]) != len(header) {
+		t.Fatalf("records = %q", records)
+	}
+	want := []string{"1", "00:00:01.000", "00:00:02.500", "1.500", "Ana", "Hello world", "Hola, ¿qué tal?", "3", "4", "9", "2.7", "OK"}
+	if strings.Join(records[1], "|") != strings.Join(want, "|") {
+		t.Errorf("row 1 = %q, want %q", records[1], want)
+	}
+	if records[2][11] != "Over by 4" {
+		t.Errorf("row 2 status = %q", records[2][11])
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatXLSX, testLines()); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("workbook is not a zip: %v", err)
+	}
+	var sheet string
+	for _, f := range zr.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, _ := f.Open()
+			data, _ := io.ReadAll(rc)
+			sheet = string(data)
+		}
+	}
+	for _, want := range []string{
+		`<c r="L1" s="1" t="inlineStr"><is><t xml:space="preserve">Status</t></is></c>`,
+		`<c r="A2"><v>1</v></c>`,
+		`<c r="G2" s="2" t="inlineStr"><is><t xml:space="preserve">Hola, ¿qué tal?</t></is></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet missing %s:\n%s", want, sheet)
+		}
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for col, want := range map[int]string{0: "A", 11: "L", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(col); got != want {
+			t.Errorf("columnName(%d) = %q, want %q", col, got, want)
+		}
+	}
+}
+
+func TestTempoFor(t *testing.T) {
+	tests := []struct {
+		n, slot int
+		want    float64
+	}{
+		{100, 200, 1},
+		{200, 200, 1},
+		{240, 200, 1.2},
+		{400, 200, 1.5},
+		{100, 0, 1},
+	}
+	for _, tt := range tests {
+		if got := tempoFor(tt.n, tt.slot, 1.5); got != tt.want {
+			t.Errorf("tempoFor(%d, %d) = %g, want %g", tt.n, tt.slot, got, tt.want)
+		}
+	}
+}
+
+func TestAtempoFilter(t *testing.T) {
+	for tempo, want := range map[float64]string{
+		1.25: "atempo=1.2500",
+		2:    "atempo=2.0000",
+		3:    "atempo=2,atempo=1.5000",
+		5:    "atempo=2,atempo=2,atempo=1.2500",
+	} {
+		if got := atempoFilter(tempo); got != want {
+			t.Errorf("atempoFilter(%g) = %q, want %q", tempo, got, want)
+		}
+	}
+}
+
+func TestCut(t *testing.T) {
+	pcm := make([]byte, 4000)
+	for i := 0; i < len(pcm); i += 2 {
+		binary.LittleEndian.PutUint16(pcm[i:], 1000)
+	}
+	got := cut(pcm, 1500)
+	if len(got) != 3000 {
+		t.Fatalf("len = %d, want 3000", len(got))
+	}
+	first := int16(binary.LittleEndian.Uint16(got[0:]))
+	last := int16(binary.LittleEndian.Uint16(got[len(got)-2:]))
+	if first != 1000 || last >= 100 {
+		t.Errorf("first = %d, last = %d: want the end faded out", first, last)
+	}
+}
+
+func TestWriteTrack(t *testing.T) {
+	clip := func(values ...int16) []byte {
+		pcm := make([]byte, 2*len(values))
+		for i, v := range values {
+			binary.LittleEndian.PutUint16(pcm[2*i:], uint16(v))
+		}
+		return pcm
+	}
+	var buf bytes.Buffer
+	err := writeTrack(&buf, []placedClip{
+		{start: 1, pcm: clip(1, 2)},
+		{start: 2, pcm: clip(3)}, // overlaps the first: pushed back
+		{start: 6, pcm: clip(4, 5, 6)},
+	}, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if string(data[:4]) != "RIFF" || binary.LittleEndian.Uint32(data[40:]) != 16 {
+		t.Fatalf("bad header: %v", data[:44])
+	}
+	var got []int16
+	for i := 44; i < len(data); i += 2 {
+		got = append(got, int16(binary.LittleEndian.Uint16(data[i:])))
+	}
+	want := []int16{0, 1, 2, 3, 0, 0, 4, 5}
+	if len(got) != len(want) {
+		t.Fatalf("samples = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("samples = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package dubbing
+
+import (
+	"strings"
+	"unicode"
+)
+
+// vowels of the alphabetic scripts, where a run of vowels is one syllable
+const vowels = "aeiouyàáâãäåæèéêëìíîïòóôõöøùúûüýÿœāēīōūăĕĭŏŭąęįųěůőű" +
+	"аеёиоуыэюяіїє" +
+	"αεηιουωάέήίόύώϊϋΐΰ"
+
+// Syllables estimates how many syllables it takes to say text. Alphabetic
+// words count their vowel groups; Chinese, Japanese, and Korean count one
+// per character; other scripts count their letters, halved for Arabic and
+// Hebrew, which leave vowels unwritten. Digits count one each.
+func Syllables(text string) int {
+	total := 0
+	for _, word := range strings.FieldsFunc(text, isSeparator) {
+		total += wordSyllables(word)
+	}
+	return total
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r) && r != '\'' && r != '’'
+}
+
+func wordSyllables(word string) int {
+	var groups, syllabic, abjad, letters, digits int
+	inVowel := false
+	for _, r := range strings.ToLower(word) {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+			inVowel = false
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			syllabic++
+			inVowel = false
+		case strings.ContainsRune(vowels, r):
+			if !inVowel {
+				groups++
+			}
+			inVowel = true
+		case unicode.In(r, unicode.Arabic, unicode.Hebrew):
+			abjad++
+			inVowel = false
+		case unicode.IsLetter(r):
+			letters++
+			inVowel = false
+		default:
+			inVowel = false
+		}
+	}
+
+	count := digits + syllabic
+	switch {
+	case groups > 0:
+		count += groups
+	case abjad > 0:
+		count += (abjad + 1) / 2
+	case !isAlphabetic(word):
+		// abugidas such as Devanagari: each consonant carries a vowel
+		count += letters
+	}
+	if count == 0 && groups+abjad+letters > 0 {
+		count = 1
+	}
+	return count
+}
+
+// words written in Latin, Cyrillic, or Greek letters
+func isAlphabetic(word string) bool {
+	for _, r := range word {
+		if unicode.In(r, unicode.Latin, unicode.Cyrillic, unicode.Greek) {
+			return true
+		}
+	}
+	return false
+}
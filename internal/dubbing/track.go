@@ -0,0 +1,229 @@
+package dubbing
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	ffmpegbin "github.com/mgpai22/lipi/internal/ffmpeg"
+	"github.com/mgpai22/lipi/internal/tts"
+)
+
+// SampleRate of the dub track, the rate speech models return
+const SampleRate = 24000
+
+// DefaultMaxSpeedup is how much faster than synthesized a clip may play to
+// fit its cue before it is cut
+const DefaultMaxSpeedup = 1.5
+
+// length of the fade where a clip is cut
+const fadeOut = 50 * time.Millisecond
+
+// TrackClip is synthesized speech for the cue starting at Start
+type TrackClip struct {
+	Path  string
+	Start time.Duration
+}
+
+type TrackOptions struct {
+	MaxSpeedup float64 // default 1.5
+	OnClip     func()  // called after each clip is fitted
+}
+
+// TrackStats counts the clips that did not fit their cue as synthesized
+type TrackStats struct {
+	SpedUp  int // played faster to fit
+	Trimmed int // still too long at the maximum speed-up, and cut
+}
+
+// WriteTrack lays clips out on a mono WAV track of length at outputPath.
+// Each clip may run until the next one starts; a longer clip is sped up,
+// by at most MaxSpeedup, and whatever still overruns is faded out and cut.
+// Clips must be sorted by start.
+func WriteTrack(
+	ctx context.Context,
+	clips []TrackClip,
+	length time.Duration,
+	outputPath string,
+	opts TrackOptions,
+) (TrackStats, error) {
+	maxSpeedup := opts.MaxSpeedup
+	if maxSpeedup < 1 {
+		maxSpeedup = DefaultMaxSpeedup
+	}
+
+	var stats TrackStats
+	placed := make([]placedClip, 0, len(clips))
+	for i, clip := range clips {
+		end := length
+		if i+1 < len(clips) {
+			end = min(clips[i+1].Start, length)
+		}
+		slot := samples(end - clip.Start)
+
+		pcm, err := decode(ctx, clip.Path, nil, "")
+		if err != nil {
+			return stats, err
+		}
+		if tempo := tempoFor(len(pcm)/2, slot, maxSpeedup); tempo > 1 {
+			stats.SpedUp++
+			if pcm, err = decode(ctx, "", pcm, atempoFilter(tempo)); err != nil {
+				return stats, err
+			}
+		}
+		if len(pcm)/2 > slot {
+			stats.Trimmed++
+			pcm = cut(pcm, slot)
+		}
+		placed = append(placed, placedClip{start: samples(clip.Start), pcm: pcm})
+		if opts.OnClip != nil {
+			opts.OnClip()
+		}
+	}
+
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return stats, fmt.Errorf("failed to create dub track: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	if err := writeTrack(w, placed, samples(length)); err != nil {
+		_ = f.Close()
+		return stats, fmt.Errorf("failed to write dub track: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return stats, fmt.Errorf("failed to write dub track: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return stats, fmt.Errorf("failed to write dub track: %w", err)
+	}
+	return stats, nil
+}
+
+// samples of the track in d
+func samples(d time.Duration) int {
+	return max(int(d.Seconds()*SampleRate), 0)
+}
+
+// playback speed that fits n samples into slot, capped at maxSpeedup; 1
+// when they fit already
+func tempoFor(n, slot int, maxSpeedup float64) float64 {
+	if n <= slot || slot <= 0 {
+		return 1
+	}
+	return min(float64(n)/float64(slot), maxSpeedup)
+}
+
+// atempo filters for tempo, chained since one filter goes at most 2x
+func atempoFilter(tempo float64) string {
+	var filters []string
+	for tempo > 2 {
+		filters = append(filters, "atempo=2")
+		tempo /= 2
+	}
+	filters = append(filters, "atempo="+strconv.FormatFloat(tempo, 'f', 4, 64))
+	return strings.Join(filters, ",")
+}
+
+// decodes the file at path, or raw track samples when path is empty, to
+// raw track samples through filter
+func decode(ctx context.Context, path string, raw []byte, filter string) ([]byte, error) {
+	ffmpegPath, err := ffmpegbin.FFmpegPath()
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"-v", "error"}
+	if path == "" {
+		args = append(args, "-f", "s16le", "-ar", strconv.Itoa(SampleRate), "-ac", "1", "-i", "pipe:0")
+	} else {
+		args = append(args, "-i", path)
+	}
+	if filter != "" {
+		args = append(args, "-af", filter)
+	}
+	args = append(args, "-ac", "1", "-ar", strconv.Itoa(SampleRate), "-f", "s16le", "pipe:1")
+
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+	if path == "" {
+		cmd.Stdin = bytes.NewReader(raw)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, errs.Wrap(errs.KindFFmpeg, fmt.Errorf(
+			"ffmpeg speech decoding failed: %w (%s)",
+			err,
+			strings.TrimSpace(stderr.String()),
+		))
+	}
+	return stdout.Bytes(), nil
+}
+
+// cuts pcm to n samples, fading out the end
+func cut(pcm []byte, n int) []byte {
+	pcm = pcm[:n*2]
+	fade := min(samples(fadeOut), n)
+	for i := 0; i < fade; i++ {
+		at := (n - fade + i) * 2
+		sample := int16(binary.LittleEndian.Uint16(pcm[at:]))
+		sample = int16(int(sample) * (fade - i) / (fade + 1))
+		binary.LittleEndian.PutUint16(pcm[at:], uint16(sample))
+	}
+	return pcm
+}
+
+// clip samples starting at a sample offset of the track
+type placedClip struct {
+	start int
+	pcm   []byte
+}
+
+// writes a WAV track of total samples with the clips in place and silence
+// between them. A clip that starts before the previous one ends is pushed
+// back.
+func writeTrack(w io.Writer, clips []placedClip, total int) error {
+	if _, err := w.Write(tts.WAVHeader(total*2, SampleRate, 1)); err != nil {
+		return err
+	}
+	pos := 0
+	for _, clip := range clips {
+		if clip.start > pos {
+			if err := writeSilence(w, min(clip.start, total)-pos); err != nil {
+				return err
+			}
+			pos = min(clip.start, total)
+		}
+		n := min(len(clip.pcm)/2, total-pos)
+		if n <= 0 {
+			continue
+		}
+		if _, err := w.Write(clip.pcm[:n*2]); err != nil {
+			return err
+		}
+		pos += n
+	}
+	return writeSilence(w, total-pos)
+}
+
+func writeSilence(w io.Writer, n int) error {
+	zeros := make([]byte, 4096)
+	for left := n * 2; left > 0; left -= len(zeros) {
+		if _, err := w.Write(zeros[:min(left, len(zeros))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package errs
+
+import (
+	"context"
+	"errors"
+)
+
+// Kind classifies a failure so scripts and the server can react to it
+// without matching error strings.
+type Kind int
+
+const (
+	KindUnknown     Kind = iota
+	KindInput            // bad arguments, missing or unsupported files
+	KindAuth             // missing or rejected API key
+	KindRateLimit        // provider rate limit or quota exhausted
+	KindPartial          // some items of a batch failed
+	KindFFmpeg           // ffmpeg or ffprobe failed
+	KindInterrupted      // cancelled by Ctrl-C or shutdown
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindInput:
+		return "input"
+	case KindAuth:
+		return "auth"
+	case KindRateLimit:
+		return "rate_limit"
+	case KindPartial:
+		return "partial"
+	case KindFFmpeg:
+		return "ffmpeg"
+	case KindInterrupted:
+		return "interrupted"
+	default:
+		return "unknown"
+	}
+}
+
+// Error attaches a Kind to an underlying error
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap tags err with kind. An error that already carries a kind keeps it,
+// so the most specific classification wins.
+func Wrap(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// KindOf reports the kind of err. Cancellation is always KindInterrupted,
+// even when it surfaces through an ffmpeg or provider call; untagged
+// ErrAuth and ErrRateLimited provider errors map to their kinds.
+func KindOf(err error) Kind {
+	if err == nil {
+		return KindUnknown
+	}
+	if errors.Is(err, context.Canceled) {
+		return KindInterrupted
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	switch {
+	case errors.Is(err, ErrAuth):
+		return KindAuth
+	case errors.Is(err, ErrRateLimited):
+		return KindRateLimit
+	}
+	return KindUnknown
+}
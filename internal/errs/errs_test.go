@@ -0,0 +1,84 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestKindOf(t *testing.T) {
+	base := errors.New("boom")
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{"nil", nil, KindUnknown},
+		{"plain", base, KindUnknown},
+		{"tagged", Wrap(KindFFmpeg, base), KindFFmpeg},
+		{"wrapped tag", fmt.Errorf("extract: %w", Wrap(KindFFmpeg, base)), KindFFmpeg},
+		{"inner kind wins", Wrap(KindInput, Wrap(KindAuth, base)), KindAuth},
+		{"canceled", Wrap(KindFFmpeg, context.Canceled), KindInterrupted},
+		{"auth class", fmt.Errorf("translate: %w", Mark(ErrAuth, base)), KindAuth},
+		{"rate limit class", Mark(ErrRateLimited, base), KindRateLimit},
+		{"filtered class", Mark(ErrContentFiltered, base), KindUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.want {
+				t.Errorf("KindOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapKeepsMessage(t *testing.T) {
+	err := Wrap(KindInput, errors.New("file not found: a.mp4"))
+	if err.Error() != "file not found: a.mp4" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if Wrap(KindInput, nil) != nil {
+		t.Error("Wrap(nil) should be nil")
+	}
+}
+
+func TestMarkKeepsMessage(t *testing.T) {
+	base := errors.New("no text in Gemini response")
+	err := fmt.Errorf("batch 2 failed: %w", Mark(ErrParse, base))
+	if err.Error() != "batch 2 failed: no text in Gemini response" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if !errors.Is(err, ErrParse) || !errors.Is(err, base) {
+		t.Error("expected both the class and the cause in the chain")
+	}
+	if Mark(ErrParse, nil) != nil {
+		t.Error("Mark(nil) should be nil")
+	}
+}
+
+func TestCode(t *testing.T) {
+	base := errors.New("boom")
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"plain", base, "unknown"},
+		{"kind", Wrap(KindFFmpeg, base), "ffmpeg"},
+		{"auth", Mark(ErrAuth, base), "auth"},
+		{"filtered", Mark(ErrContentFiltered, base), "content_filtered"},
+		{"truncated", Mark(ErrResponseTruncated, base), "response_truncated"},
+		{"parse", fmt.Errorf("chunk 1 failed: %w", Mark(ErrParse, base)), "parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Code(tt.err); got != tt.want {
+				t.Errorf("Code() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package errs
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+	"google.golang.org/genai"
+)
+
+// classes of provider failures, returned by transcribers and translators so
+// callers can branch with errors.Is instead of matching messages
+var (
+	ErrRateLimited       = errors.New("rate limited by provider")
+	ErrAuth              = errors.New("API key rejected by provider")
+	ErrContentFiltered   = errors.New("response blocked by provider content filter")
+	ErrResponseTruncated = errors.New("provider response truncated")
+	ErrParse             = errors.New("provider response could not be parsed")
+)
+
+// an error in one of the provider classes; the message stays that of err
+type classified struct {
+	class error
+	err   error
+}
+
+func (c *classified) Error() string {
+	return c.err.Error()
+}
+
+func (c *classified) Unwrap() []error {
+	return []error{c.err, c.class}
+}
+
+// Mark tags err with class, one of the Err* values above. The message is
+// unchanged.
+func Mark(class, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classified{class: class, err: err}
+}
+
+// Classify marks provider SDK errors by their HTTP status, so rejected keys
+// and rate limits are recognizable wherever they surface. Other errors are
+// returned as is.
+func Classify(err error) error {
+	switch ProviderStatus(err) {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return Mark(ErrAuth, err)
+	case http.StatusTooManyRequests:
+		return Mark(ErrRateLimited, err)
+	}
+	return err
+}
+
+// ProviderStatus is the HTTP status code carried by a Gemini, OpenAI, or
+// Anthropic API error, or 0
+func ProviderStatus(err error) int {
+	var geminiErr genai.APIError
+	if errors.As(err, &geminiErr) {
+		return geminiErr.Code
+	}
+	var geminiPtrErr *genai.APIError
+	if errors.As(err, &geminiPtrErr) {
+		return geminiPtrErr.Code
+	}
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return openaiErr.StatusCode
+	}
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		return anthropicErr.StatusCode
+	}
+	return 0
+}
+
+// Code names the failure class of err for APIs and logs: the provider class
+// when there is one, otherwise its Kind
+func Code(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrContentFiltered):
+		return "content_filtered"
+	case errors.Is(err, ErrResponseTruncated):
+		return "response_truncated"
+	case errors.Is(err, ErrParse):
+		return "parse"
+	}
+	return KindOf(err).String()
+}
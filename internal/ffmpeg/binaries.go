@@ -2,6 +2,9 @@ package ffmpeg
 
 import (
 	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -13,6 +16,10 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/mgpai22/lipi/internal/config"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/httpclient"
 )
 
 const (
@@ -34,6 +41,7 @@ var (
 func Ensure() (BinaryPaths, error) {
 	ensureOnce.Do(func() {
 		ensurePath, ensureErr = ensure()
+		ensureErr = errs.Wrap(errs.KindFFmpeg, ensureErr)
 	})
 	return ensurePath, ensureErr
 }
@@ -54,12 +62,27 @@ func FFprobePath() (string, error) {
 	return paths.FFprobe, nil
 }
 
-func ensure() (BinaryPaths, error) {
-	paths := BinaryPaths{}
+// Source says where the ffmpeg binaries come from
+type Source string
+
+const (
+	SourceEnv   Source = "env"   // LIPI_FFMPEG_PATH or LIPI_FFPROBE_PATH
+	SourcePath  Source = "path"  // found on PATH
+	SourceCache Source = "cache" // downloaded or extracted into the cache dir
+)
+
+// Locate finds the binaries Ensure would use without installing anything.
+// ok is false when they still have to be downloaded or extracted, in which
+// case paths are where they will be put.
+func Locate() (paths BinaryPaths, source Source, ok bool) {
 	ffmpegPath := os.Getenv("LIPI_FFMPEG_PATH")
 	ffprobePath := os.Getenv("LIPI_FFPROBE_PATH")
+	source = SourcePath
+	if ffmpegPath != "" || ffprobePath != "" {
+		source = SourceEnv
+	}
 	if ffmpegPath != "" && ffprobePath != "" {
-		return BinaryPaths{FFmpeg: ffmpegPath, FFprobe: ffprobePath}, nil
+		return BinaryPaths{FFmpeg: ffmpegPath, FFprobe: ffprobePath}, source, true
 	}
 
 	if ffmpegPath == "" {
@@ -72,41 +95,152 @@ func ensure() (BinaryPaths, error) {
 			ffprobePath = found
 		}
 	}
-
 	if ffmpegPath != "" && ffprobePath != "" {
-		paths.FFmpeg = ffmpegPath
-		paths.FFprobe = ffprobePath
-		return paths, nil
+		return BinaryPaths{FFmpeg: ffmpegPath, FFprobe: ffprobePath}, source, true
 	}
 
-	assetNames, err := assetsForPlatform(runtime.GOOS, runtime.GOARCH)
-	if err != nil {
-		return BinaryPaths{}, err
-	}
+	paths = cachedPaths(InstallDir())
+	return paths, SourceCache, binariesExist(paths.FFmpeg, paths.FFprobe)
+}
 
-	cacheDir, err := os.UserCacheDir()
-	if err != nil || cacheDir == "" {
-		cacheDir = os.TempDir()
-	}
-	installDir := filepath.Join(
-		cacheDir,
-		"lipi",
-		"ffmpeg",
-		ffmpegReleaseVersion,
+// InstallDir is the cache directory the bundled or downloaded binaries are
+// kept in, one per pinned ffmpeg version and platform
+func InstallDir() string {
+	return filepath.Join(
+		cacheRoot(),
+		release().label(),
 		runtime.GOOS,
 		runtime.GOARCH,
 	)
+}
+
+func cacheRoot() string {
+	return filepath.Join(config.CacheDir(), "ffmpeg")
+}
+
+func cachedPaths(installDir string) BinaryPaths {
 	exeSuffix := executableSuffix()
-	ffmpegPath = filepath.Join(installDir, "ffmpeg"+exeSuffix)
-	ffprobePath = filepath.Join(installDir, "ffprobe"+exeSuffix)
+	return BinaryPaths{
+		FFmpeg:  filepath.Join(installDir, "ffmpeg"+exeSuffix),
+		FFprobe: filepath.Join(installDir, "ffprobe"+exeSuffix),
+	}
+}
 
-	if binariesExist(ffmpegPath, ffprobePath) {
-		return BinaryPaths{FFmpeg: ffmpegPath, FFprobe: ffprobePath}, nil
+func ensure() (BinaryPaths, error) {
+	if paths, _, ok := Locate(); ok {
+		return paths, nil
+	}
+	return install(InstallDir(), false)
+}
+
+// Install puts the pinned ffmpeg build into the cache directory even when
+// ffmpeg is on PATH, so it can be provisioned ahead of time. With force an
+// existing install is replaced. Builds of other versions are removed.
+func Install(force bool) (BinaryPaths, error) {
+	paths, err := install(InstallDir(), force)
+	if err != nil {
+		return BinaryPaths{}, errs.Wrap(errs.KindFFmpeg, err)
+	}
+	if err := pruneVersions(); err != nil {
+		return BinaryPaths{}, errs.Wrap(errs.KindFFmpeg, err)
+	}
+	return paths, nil
+}
+
+// Remove deletes every cached ffmpeg build
+func Remove() error {
+	if err := os.RemoveAll(cacheRoot()); err != nil {
+		return errs.Wrap(
+			errs.KindFFmpeg,
+			fmt.Errorf("remove ffmpeg cache: %w", err),
+		)
+	}
+	return nil
+}
+
+// removes the cached builds of releases other than the configured one
+func pruneVersions() error {
+	entries, err := os.ReadDir(cacheRoot())
+	if err != nil {
+		return fmt.Errorf("read ffmpeg cache dir: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.Name() == release().label() {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(cacheRoot(), entry.Name())); err != nil {
+			return fmt.Errorf("remove ffmpeg %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Version is the first line of a binary's -version output
+func Version(ctx context.Context, path string) (string, error) {
+	out, err := exec.CommandContext(ctx, path, "-version").Output()
+	if err != nil {
+		return "", errs.Wrap(
+			errs.KindFFmpeg,
+			fmt.Errorf("run %s: %w", path, err),
+		)
 	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	return strings.TrimSpace(line), nil
+}
 
-	if err := os.MkdirAll(installDir, 0o755); err != nil {
+// installs the configured build into installDir, or replaces it with force.
+// Concurrent installs, in this process or others, wait on a lock file
+// next to installDir; binaries are unpacked into a staging directory and
+// renamed into place, so a reader never sees a partial install.
+func install(installDir string, force bool) (BinaryPaths, error) {
+	paths := cachedPaths(installDir)
+	if !force && binariesExist(paths.FFmpeg, paths.FFprobe) {
+		return paths, nil
+	}
+
+	parent := filepath.Dir(installDir)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
 		return BinaryPaths{}, fmt.Errorf("create ffmpeg cache dir: %w", err)
 	}
+	unlock, err := lockFile(installDir + ".lock")
+	if err != nil {
+		return BinaryPaths{}, err
+	}
+	defer unlock()
+
+	// another process may have finished the install while we waited
+	if !force && binariesExist(paths.FFmpeg, paths.FFprobe) {
+		return paths, nil
+	}
+
+	stageDir, err := os.MkdirTemp(parent, filepath.Base(installDir)+".tmp-*")
+	if err != nil {
+		return BinaryPaths{}, fmt.Errorf("create ffmpeg staging dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(stageDir) }()
+
+	if _, err := unpack(stageDir); err != nil {
+		return BinaryPaths{}, err
+	}
+	// a leftover partial install or the one being replaced
+	if err := os.RemoveAll(installDir); err != nil {
+		return BinaryPaths{}, fmt.Errorf("remove ffmpeg install: %w", err)
+	}
+	if err := os.Rename(stageDir, installDir); err != nil {
+		return BinaryPaths{}, fmt.Errorf("install ffmpeg: %w", err)
+	}
+	return paths, nil
+}
+
+// extracts the bundled build, or downloads it, into installDir
+func unpack(installDir string) (BinaryPaths, error) {
+	assetNames, err := release().assets(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return BinaryPaths{}, err
+	}
+
+	paths := cachedPaths(installDir)
+	ffmpegPath, ffprobePath := paths.FFmpeg, paths.FFprobe
 
 	embeddedUsed := false
 	for _, assetName := range assetNames {
@@ -135,6 +269,11 @@ func ensure() (BinaryPaths, error) {
 		return BinaryPaths{FFmpeg: ffmpegPath, FFprobe: ffprobePath}, nil
 	}
 
+	if err := httpclient.Refuse(
+		"download ffmpeg (install it, set LIPI_FFMPEG_PATH and LIPI_FFPROBE_PATH, or run `lipi ffmpeg install` beforehand)",
+	); err != nil {
+		return BinaryPaths{}, err
+	}
 	for _, assetName := range assetNames {
 		if err := downloadAndExtract(assetName, installDir); err != nil {
 			return BinaryPaths{}, err
@@ -164,33 +303,34 @@ func assetsForPlatform(goos, goarch string) ([]string, error) {
 	switch {
 	case goos == "linux" && goarch == "amd64":
 		suffix = "linux-64"
+	case goos == "linux" && goarch == "386":
+		suffix = "linux-32"
 	case goos == "linux" && goarch == "arm64":
 		suffix = "linux-arm-64"
-	case goos == "darwin" && goarch == "amd64":
+	case goos == "linux" && goarch == "arm":
+		suffix = "linux-armhf-32"
+	// there are no native Apple Silicon or Windows on ARM builds; the x86-64
+	// ones run under Rosetta 2 and Windows' x64 emulation
+	case goos == "darwin" && (goarch == "amd64" || goarch == "arm64"):
 		suffix = "macos-64"
-	case goos == "windows" && goarch == "amd64":
+	case goos == "windows" && (goarch == "amd64" || goarch == "arm64"):
 		suffix = "win-64"
 	default:
 		return nil, fmt.Errorf(
-			"unsupported platform for bundled ffmpeg: %s/%s",
+			"unsupported platform for bundled ffmpeg: %s/%s (install ffmpeg or set LIPI_FFMPEG_PATH and LIPI_FFPROBE_PATH)",
 			goos,
 			goarch,
 		)
 	}
 	return []string{
-		"ffmpeg-" + ffmpegReleaseVersion + "-" + suffix + ".zip",
-		"ffprobe-" + ffmpegReleaseVersion + "-" + suffix + ".zip",
+		"ffmpeg-" + release().Version + "-" + suffix + ".zip",
+		"ffprobe-" + release().Version + "-" + suffix + ".zip",
 	}, nil
 }
 
 func downloadAndExtract(assetName, installDir string) error {
-	url := fmt.Sprintf(
-		"%s/v%s/%s",
-		ffmpegReleaseBaseURL,
-		ffmpegReleaseVersion,
-		assetName,
-	)
-	client := &http.Client{Timeout: 5 * time.Minute}
+	url := release().assetURL(assetName)
+	client := httpclient.WithTimeout(httpclient.Default(), 5*time.Minute)
 	resp, err := client.Get(url)
 	if err != nil {
 		return fmt.Errorf("download ffmpeg bundle: %w", err)
@@ -207,7 +347,7 @@ func downloadAndExtract(assetName, installDir string) error {
 		)
 	}
 
-	return extractArchiveFromReader(assetName, resp.Body, installDir)
+	return extractArchiveFromReader(assetName, resp.Body, installDir, true)
 }
 
 func extractEmbedded(assetName, installDir string) (bool, error) {
@@ -221,6 +361,7 @@ func extractEmbedded(assetName, installDir string) (bool, error) {
 		assetName,
 		reader,
 		installDir,
+		false,
 	); err != nil {
 		return true, err
 	}
@@ -231,13 +372,15 @@ func extractArchiveFromReader(
 	assetName string,
 	reader io.Reader,
 	installDir string,
+	downloaded bool,
 ) error {
-	tmpFile, err := os.CreateTemp("", "lipi-ffmpeg-*.zip")
+	tmpFile, err := os.CreateTemp("", "lipi-ffmpeg-*")
 	if err != nil {
 		return fmt.Errorf("create temp archive: %w", err)
 	}
 	archivePath := tmpFile.Name()
-	if _, err := io.Copy(tmpFile, reader); err != nil {
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmpFile, hash), reader); err != nil {
 		_ = tmpFile.Close()
 		_ = os.Remove(archivePath)
 		return fmt.Errorf("write archive: %w", err)
@@ -248,13 +391,23 @@ func extractArchiveFromReader(
 	}
 	defer func() { _ = os.Remove(archivePath) }()
 
-	if err := extractArchive(archivePath, installDir); err != nil {
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if err := verifyChecksum(assetName, sum, downloaded); err != nil {
+		return fmt.Errorf("verify %s: %w", assetName, err)
+	}
+
+	if err := extractArchive(assetName, archivePath, installDir); err != nil {
 		return fmt.Errorf("extract %s: %w", assetName, err)
 	}
 	return nil
 }
 
-func extractArchive(archivePath, installDir string) error {
+func extractArchive(assetName, archivePath, installDir string) error {
+	switch archiveFormat(assetName) {
+	case "tar.gz", "tar.xz":
+		return extractTar(archivePath, archiveFormat(assetName), installDir)
+	}
+
 	zipReader, err := zip.OpenReader(archivePath)
 	if err != nil {
 		return fmt.Errorf("open ffmpeg archive: %w", err)
@@ -288,6 +441,10 @@ func extractZipFile(file *zip.File, dest string) error {
 	}
 	defer func() { _ = reader.Close() }()
 
+	return writeBinary(reader, dest)
+}
+
+func writeBinary(reader io.Reader, dest string) error {
 	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
 		return fmt.Errorf("create ffmpeg output dir: %w", err)
 	}
@@ -332,3 +489,16 @@ func executableSuffix() string {
 	}
 	return ""
 }
+
+// path of ffplay, used for interactive playback only. Unlike ffmpeg and
+// ffprobe it is never bundled or downloaded.
+func FFplayPath() (string, error) {
+	path, err := exec.LookPath("ffplay")
+	if err != nil {
+		return "", errs.Wrap(
+			errs.KindFFmpeg,
+			errors.New("ffplay not found on PATH: install FFmpeg to play cues"),
+		)
+	}
+	return path, nil
+}
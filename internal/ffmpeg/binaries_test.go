@@ -0,0 +1,234 @@
+package ffmpeg
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAssetsForPlatform(t *testing.T) {
+	tests := []struct {
+		goos, goarch string
+		want         string
+		wantErr      bool
+	}{
+		{"linux", "amd64", "ffmpeg-6.1-linux-64.zip", false},
+		{"linux", "386", "ffmpeg-6.1-linux-32.zip", false},
+		{"linux", "arm64", "ffmpeg-6.1-linux-arm-64.zip", false},
+		{"linux", "arm", "ffmpeg-6.1-linux-armhf-32.zip", false},
+		{"darwin", "amd64", "ffmpeg-6.1-macos-64.zip", false},
+		{"darwin", "arm64", "ffmpeg-6.1-macos-64.zip", false},
+		{"windows", "amd64", "ffmpeg-6.1-win-64.zip", false},
+		{"windows", "arm64", "ffmpeg-6.1-win-64.zip", false},
+		{"freebsd", "amd64", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos+"/"+tt.goarch, func(t *testing.T) {
+			assets, err := assetsForPlatform(tt.goos, tt.goarch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("assetsForPlatform() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(assets) != 2 || assets[0] != tt.want {
+				t.Errorf("assetsForPlatform() = %v, want %s and its ffprobe", assets, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	const pinned = "ffmpeg-test.zip"
+	sum := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	original := checksumFile
+	checksumFile = "# comment\n" + sum + "  " + pinned + "\n"
+	defer func() { checksumFile = original }()
+
+	tests := []struct {
+		name     string
+		asset    string
+		sum      string
+		required bool
+		skip     bool
+		wantErr  bool
+	}{
+		{"match", pinned, sum, true, false, false},
+		{"mismatch", pinned, "00", true, false, true},
+		{"mismatch skipped", pinned, "00", true, true, false},
+		{"unpinned download", "other.zip", sum, true, false, true},
+		{"unpinned download skipped", "other.zip", sum, true, true, false},
+		{"unpinned embedded", "other.zip", sum, false, false, false},
+		{"mismatch embedded", pinned, "00", false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetInsecureSkipVerify(tt.skip)
+			defer SetInsecureSkipVerify(false)
+			err := verifyChecksum(tt.asset, tt.sum, tt.required)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLocate(t *testing.T) {
+	t.Setenv("LIPI_FFMPEG_PATH", "/opt/ffmpeg")
+	t.Setenv("LIPI_FFPROBE_PATH", "/opt/ffprobe")
+	paths, source, ok := Locate()
+	if !ok || source != SourceEnv || paths.FFmpeg != "/opt/ffmpeg" || paths.FFprobe != "/opt/ffprobe" {
+		t.Errorf("Locate() = %+v, %q, %v, want env paths", paths, source, ok)
+	}
+
+	t.Setenv("LIPI_FFMPEG_PATH", "")
+	t.Setenv("LIPI_FFPROBE_PATH", "")
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	paths, source, ok = Locate()
+	if ok || source != SourceCache {
+		t.Errorf("Locate() = %q, %v, want a missing cache install", source, ok)
+	}
+	if filepath.Dir(paths.FFmpeg) != InstallDir() {
+		t.Errorf("FFmpeg = %q, want it in %q", paths.FFmpeg, InstallDir())
+	}
+}
+
+func TestReleaseAssets(t *testing.T) {
+	defer func() { _ = SetRelease(Release{}) }()
+
+	if err := SetRelease(Release{BaseURL: "https://mirror.local/ffbinaries/", Version: "7.0"}); err != nil {
+		t.Fatal(err)
+	}
+	assets, err := release().assets("linux", "amd64")
+	if err != nil || assets[0] != "ffmpeg-7.0-linux-64.zip" {
+		t.Fatalf("assets = %v, %v", assets, err)
+	}
+	if got := release().assetURL(assets[0]); got != "https://mirror.local/ffbinaries/v7.0/ffmpeg-7.0-linux-64.zip" {
+		t.Errorf("assetURL = %q", got)
+	}
+
+	url := "https://mirror.local/ffmpeg-{version}-amd64-static.tar.xz"
+	if err := SetRelease(Release{URL: url, Version: "7.0", SHA256: "ABC"}); err != nil {
+		t.Fatal(err)
+	}
+	assets, err = release().assets("freebsd", "amd64")
+	if err != nil || len(assets) != 1 || assets[0] != "ffmpeg-7.0-amd64-static.tar.xz" {
+		t.Fatalf("assets = %v, %v", assets, err)
+	}
+	if checksums()[assets[0]] != "abc" {
+		t.Error("expected the --ffmpeg-sha256 pin for the archive")
+	}
+	if !strings.HasPrefix(release().label(), "url-") {
+		t.Errorf("label = %q, want a per-URL cache dir", release().label())
+	}
+
+	if err := SetRelease(Release{URL: "https://mirror.local/ffmpeg.7z"}); err == nil {
+		t.Error("expected an error for an unsupported archive")
+	}
+}
+
+// a .tar.gz holding fake ffmpeg and ffprobe binaries and another file
+func writeTarGz(t *testing.T, archive string) {
+	t.Helper()
+	file, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{"ffmpeg-7.0-static/ffmpeg", "ffmpeg-7.0-static/ffprobe", "ffmpeg-7.0-static/readme.txt"} {
+		body := []byte("binary " + name)
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = tw.Close()
+	_ = gz.Close()
+	_ = file.Close()
+}
+
+func TestExtractTarGz(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "ffmpeg.tar.gz")
+	writeTarGz(t, archive)
+
+	installDir := t.TempDir()
+	if err := extractArchive("ffmpeg.tar.gz", archive, installDir); err != nil {
+		t.Fatal(err)
+	}
+	paths := cachedPaths(installDir)
+	if !binariesExist(paths.FFmpeg, paths.FFprobe) {
+		t.Error("expected ffmpeg and ffprobe to be extracted")
+	}
+	if fileExists(filepath.Join(installDir, "readme.txt")) {
+		t.Error("unexpected extra file extracted")
+	}
+}
+
+func TestInstallConcurrent(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "ffmpeg.tar.gz")
+	writeTarGz(t, archive)
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+
+	var downloads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	if err := SetRelease(Release{URL: server.URL + "/ffmpeg.tar.gz", SHA256: hex.EncodeToString(sum[:])}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = SetRelease(Release{}) }()
+
+	installDir := filepath.Join(t.TempDir(), "ffmpeg", "linux")
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for range 4 {
+		wg.Go(func() {
+			_, err := install(installDir, false)
+			errs <- err
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if downloads.Load() != 1 {
+		t.Errorf("downloads = %d, want 1", downloads.Load())
+	}
+	paths := cachedPaths(installDir)
+	if !binariesExist(paths.FFmpeg, paths.FFprobe) {
+		t.Error("expected ffmpeg and ffprobe to be installed")
+	}
+	entries, _ := os.ReadDir(filepath.Dir(installDir))
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Errorf("staging dir %s left behind", entry.Name())
+		}
+	}
+}
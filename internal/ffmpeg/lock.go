@@ -0,0 +1,24 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os"
+)
+
+// lockFile takes an exclusive advisory lock on path, creating it, and
+// blocks until it is free, so concurrent lipi processes install the same
+// build only once. The returned function releases it.
+func lockFile(path string) (func(), error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open ffmpeg install lock: %w", err)
+	}
+	if err := lock(file); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("lock ffmpeg install: %w", err)
+	}
+	return func() {
+		_ = unlock(file)
+		_ = file.Close()
+	}, nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package ffmpeg
+
+import "os"
+
+// file locking is unavailable on this platform; installs still land
+// atomically, but concurrent processes may both download
+func lock(file *os.File) error {
+	return nil
+}
+
+func unlock(file *os.File) error {
+	return nil
+}
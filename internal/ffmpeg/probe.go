@@ -0,0 +1,264 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+// single stream reported by ffprobe
+type Stream struct {
+	Index      int
+	CodecType  string // audio, video, subtitle, data, attachment
+	CodecName  string
+	Language   string
+	Title      string
+	Filename   string // name of an attachment, such as a font
+	BitRate    int64
+	SampleRate int
+	Channels   int
+	Width      int
+	Height     int
+	FrameRate  float64
+}
+
+// everything lipi needs to know about a media file, from one ffprobe call
+type ProbeResult struct {
+	Path       string
+	FormatName string
+	Duration   time.Duration
+	BitRate    int64
+	Size       int64
+	Streams    []Stream
+}
+
+// streams of the given codec type (audio, video, subtitle)
+func (p *ProbeResult) StreamsOfType(codecType string) []Stream {
+	var streams []Stream
+	for _, s := range p.Streams {
+		if s.CodecType == codecType {
+			streams = append(streams, s)
+		}
+	}
+	return streams
+}
+
+func (p *ProbeResult) HasAudio() bool {
+	return len(p.StreamsOfType("audio")) > 0
+}
+
+func (p *ProbeResult) HasVideo() bool {
+	return len(p.StreamsOfType("video")) > 0
+}
+
+// raw JSON output from ffprobe
+type ffprobeOutput struct {
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		BitRate    string `json:"bit_rate"`
+		Size       string `json:"size"`
+	} `json:"format"`
+	Streams []struct {
+		Index        int               `json:"index"`
+		CodecType    string            `json:"codec_type"`
+		CodecName    string            `json:"codec_name"`
+		BitRate      string            `json:"bit_rate"`
+		SampleRate   string            `json:"sample_rate"`
+		Channels     int               `json:"channels"`
+		Width        int               `json:"width"`
+		Height       int               `json:"height"`
+		AvgFrameRate string            `json:"avg_frame_rate"`
+		Tags         map[string]string `json:"tags"`
+	} `json:"streams"`
+}
+
+type probeCacheKey struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// number of probe results kept, enough for the files of a batch without
+// growing for the life of a long-running server
+const probeCacheSize = 256
+
+var probeCache = newProbeLRU(probeCacheSize)
+
+// Probe runs ffprobe once per file version and caches the result, so repeated
+// duration and stream queries on the same file are free. The cache is keyed by
+// path, size, and modification time, so rewritten files are probed again, and
+// holds the most recently used results only.
+func Probe(ctx context.Context, path string) (*ProbeResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("file not found: %s", path)
+		}
+		return nil, err
+	}
+
+	key := probeCacheKey{path: path, size: info.Size(), modTime: info.ModTime()}
+
+	if cached, ok := probeCache.get(key); ok {
+		return cached, nil
+	}
+
+	result, err := runProbe(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	probeCache.add(key, result)
+
+	return result, nil
+}
+
+// least recently used cache of probe results, safe for concurrent use
+type probeLRU struct {
+	mu      sync.Mutex
+	max     int
+	order   *list.List // most recently used first
+	entries map[probeCacheKey]*list.Element
+}
+
+type probeEntry struct {
+	key    probeCacheKey
+	result *ProbeResult
+}
+
+func newProbeLRU(max int) *probeLRU {
+	return &probeLRU{
+		max:     max,
+		order:   list.New(),
+		entries: make(map[probeCacheKey]*list.Element),
+	}
+}
+
+func (c *probeLRU) get(key probeCacheKey) (*ProbeResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*probeEntry).result, true
+}
+
+func (c *probeLRU) add(key probeCacheKey, result *ProbeResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*probeEntry).result = result
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&probeEntry{key: key, result: result})
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*probeEntry).key)
+	}
+}
+
+func runProbe(ctx context.Context, path string) (*ProbeResult, error) {
+	ffprobePath, err := FFprobePath()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, ffprobePath,
+		"-v", "quiet",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		path,
+	)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	if err := cmd.Run(); err != nil {
+		return nil, errs.Wrap(
+			errs.KindFFmpeg,
+			fmt.Errorf("ffprobe failed: %w", err),
+		)
+	}
+
+	return parseProbeOutput(path, out.Bytes())
+}
+
+func parseProbeOutput(path string, data []byte) (*ProbeResult, error) {
+	var probe ffprobeOutput
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	result := &ProbeResult{
+		Path:       path,
+		FormatName: probe.Format.FormatName,
+		BitRate:    parseInt(probe.Format.BitRate),
+		Size:       parseInt(probe.Format.Size),
+	}
+
+	if probe.Format.Duration != "" {
+		seconds, err := strconv.ParseFloat(probe.Format.Duration, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse duration: %w", err)
+		}
+		result.Duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	for _, s := range probe.Streams {
+		result.Streams = append(result.Streams, Stream{
+			Index:      s.Index,
+			CodecType:  s.CodecType,
+			CodecName:  s.CodecName,
+			Language:   s.Tags["language"],
+			Title:      s.Tags["title"],
+			Filename:   s.Tags["filename"],
+			BitRate:    parseInt(s.BitRate),
+			SampleRate: int(parseInt(s.SampleRate)),
+			Channels:   s.Channels,
+			Width:      s.Width,
+			Height:     s.Height,
+			FrameRate:  parseFrameRate(s.AvgFrameRate),
+		})
+	}
+
+	return result, nil
+}
+
+func parseInt(s string) int64 {
+	n, _ := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	return n
+}
+
+// parses ffprobe's rational frame rates such as "30000/1001"
+func parseFrameRate(s string) float64 {
+	num, den, ok := strings.Cut(s, "/")
+	if !ok {
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
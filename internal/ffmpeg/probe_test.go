@@ -0,0 +1,133 @@
+package ffmpeg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseProbeOutput(t *testing.T) {
+	data := []byte(`{
+		"streams": [
+			{
+				"index": 0,
+				"codec_type": "video",
+				"codec_name": "h264",
+				"width": 1920,
+				"height": 1080,
+				"avg_frame_rate": "30000/1001"
+			},
+			{
+				"index": 1,
+				"codec_type": "audio",
+				"codec_name": "aac",
+				"sample_rate": "48000",
+				"channels": 2,
+				"bit_rate": "128000",
+				"tags": {"language": "jpn"}
+			}
+		],
+		"format": {
+			"format_name": "mov,mp4,m4a,3gp,3g2,mj2",
+			"duration": "62.500000",
+			"bit_rate": "2500000",
+			"size": "19531250"
+		}
+	}`)
+
+	probe, err := parseProbeOutput("video.mp4", data)
+	if err != nil {
+		t.Fatalf("parseProbeOutput error: %v", err)
+	}
+
+	if probe.Duration != 62500*time.Millisecond {
+		t.Errorf("expected duration 62.5s, got %v", probe.Duration)
+	}
+	if probe.BitRate != 2500000 {
+		t.Errorf("expected bit rate 2500000, got %d", probe.BitRate)
+	}
+	if !probe.HasAudio() || !probe.HasVideo() {
+		t.Errorf("expected both audio and video streams")
+	}
+
+	audioStreams := probe.StreamsOfType("audio")
+	if len(audioStreams) != 1 {
+		t.Fatalf("expected 1 audio stream, got %d", len(audioStreams))
+	}
+	if audioStreams[0].Language != "jpn" {
+		t.Errorf("expected language jpn, got %q", audioStreams[0].Language)
+	}
+	if audioStreams[0].SampleRate != 48000 {
+		t.Errorf("expected sample rate 48000, got %d", audioStreams[0].SampleRate)
+	}
+
+	videoStreams := probe.StreamsOfType("video")
+	if got := videoStreams[0].FrameRate; got < 29.96 || got > 29.98 {
+		t.Errorf("expected ~29.97 fps, got %f", got)
+	}
+}
+
+func TestParseProbeOutputNoAudio(t *testing.T) {
+	data := []byte(`{
+		"streams": [{"index": 0, "codec_type": "video", "codec_name": "vp9"}],
+		"format": {"duration": "10.0"}
+	}`)
+
+	probe, err := parseProbeOutput("silent.webm", data)
+	if err != nil {
+		t.Fatalf("parseProbeOutput error: %v", err)
+	}
+	if probe.HasAudio() {
+		t.Error("expected no audio streams")
+	}
+}
+
+func TestParseFrameRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"25/1", 25},
+		{"0/0", 0},
+		{"24", 24},
+		{"", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseFrameRate(tt.in); got != tt.want {
+			t.Errorf("parseFrameRate(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestProbeLRU(t *testing.T) {
+	cache := newProbeLRU(2)
+	key := func(path string) probeCacheKey {
+		return probeCacheKey{path: path, size: 1, modTime: time.Unix(0, 0)}
+	}
+
+	cache.add(key("a"), &ProbeResult{Path: "a"})
+	cache.add(key("b"), &ProbeResult{Path: "b"})
+	if _, ok := cache.get(key("a")); !ok {
+		t.Fatal("a should be cached")
+	}
+	// b is now the least recently used and makes room for c
+	cache.add(key("c"), &ProbeResult{Path: "c"})
+
+	if _, ok := cache.get(key("b")); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, path := range []string{"a", "c"} {
+		if got, ok := cache.get(key(path)); !ok || got.Path != path {
+			t.Errorf("get(%s) = %v, %v", path, got, ok)
+		}
+	}
+	if cache.order.Len() != 2 || len(cache.entries) != 2 {
+		t.Errorf("cache holds %d/%d entries, want 2", cache.order.Len(), len(cache.entries))
+	}
+
+	// a rewritten file has a new key, so the old result is not returned
+	rewritten := probeCacheKey{path: "a", size: 2, modTime: time.Unix(1, 0)}
+	if _, ok := cache.get(rewritten); ok {
+		t.Error("a rewritten file should miss the cache")
+	}
+}
@@ -0,0 +1,112 @@
+package ffmpeg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Release says where ffmpeg builds are downloaded from. Empty fields keep
+// the pinned ffbinaries release.
+type Release struct {
+	// BaseURL replaces the ffbinaries release URL, for internal mirrors
+	// laid out like it: BaseURL/vVERSION/ffmpeg-VERSION-PLATFORM.zip
+	BaseURL string
+	// Version is the ffbinaries release to download
+	Version string
+	// URL is a single archive holding both ffmpeg and ffprobe, such as a
+	// BtbN or johnvansickle build (.zip, .tar.gz, or .tar.xz). {version} in
+	// it is replaced by Version. It takes precedence over BaseURL.
+	URL string
+	// SHA256 pins the checksum of the archive at URL
+	SHA256 string
+}
+
+var (
+	releaseMu sync.RWMutex
+	current   Release
+)
+
+// SetRelease changes where builds are downloaded from. It must be called
+// before the first download.
+func SetRelease(r Release) error {
+	if r.URL != "" {
+		u, err := url.Parse(r.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid ffmpeg URL %q", r.URL)
+		}
+		if archiveFormat(path.Base(u.Path)) == "" {
+			return fmt.Errorf(
+				"unsupported ffmpeg archive %q: expected .zip, .tar.gz, or .tar.xz",
+				path.Base(u.Path),
+			)
+		}
+	}
+	r.SHA256 = strings.ToLower(strings.TrimSpace(r.SHA256))
+	releaseMu.Lock()
+	defer releaseMu.Unlock()
+	current = r
+	return nil
+}
+
+func release() Release {
+	releaseMu.RLock()
+	defer releaseMu.RUnlock()
+	r := current
+	if r.BaseURL == "" {
+		r.BaseURL = ffmpegReleaseBaseURL
+	}
+	r.BaseURL = strings.TrimSuffix(r.BaseURL, "/")
+	if r.Version == "" {
+		r.Version = ffmpegReleaseVersion
+	}
+	r.URL = strings.ReplaceAll(r.URL, "{version}", r.Version)
+	return r
+}
+
+// names the cache directory of the release, so switching releases never
+// reuses binaries of another one
+func (r Release) label() string {
+	if r.URL == "" {
+		return r.Version
+	}
+	sum := sha256.Sum256([]byte(r.URL))
+	return "url-" + hex.EncodeToString(sum[:6])
+}
+
+// archives to download for a platform
+func (r Release) assets(goos, goarch string) ([]string, error) {
+	if r.URL != "" {
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ffmpeg URL %q", r.URL)
+		}
+		return []string{path.Base(u.Path)}, nil
+	}
+	return assetsForPlatform(goos, goarch)
+}
+
+func (r Release) assetURL(assetName string) string {
+	if r.URL != "" {
+		return r.URL
+	}
+	return fmt.Sprintf("%s/v%s/%s", r.BaseURL, r.Version, assetName)
+}
+
+// archive format by file name: "zip", "tar.gz", "tar.xz", or ""
+func archiveFormat(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return "zip"
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(name, ".tar.xz"), strings.HasSuffix(name, ".txz"):
+		return "tar.xz"
+	}
+	return ""
+}
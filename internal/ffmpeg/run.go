@@ -0,0 +1,47 @@
+package ffmpeg
+
+import (
+	"context"
+	"os/exec"
+)
+
+// RunContext runs a command compiled by ffmpeg-go, killing it when ctx is
+// cancelled. ffmpeg-go's own Run ignores cancellation, which would leave
+// ffmpeg writing into a temp directory that is being removed.
+func RunContext(ctx context.Context, cmd *exec.Cmd) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = cmd.Process.Kill()
+	})
+	defer stop()
+
+	err := cmd.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// TolerantInputArgs are the input options that have ffmpeg drop corrupt
+// packets, regenerate missing timestamps, and decode past errors instead
+// of aborting, for damaged recordings such as those of a crashed OBS
+// session or a truncated MP4. What cannot be decoded is skipped; pair them
+// with ResyncAudioFilter on the output to keep the rest in time.
+func TolerantInputArgs() map[string]any {
+	return map[string]any{
+		"fflags":     "+discardcorrupt+genpts",
+		"err_detect": "ignore_err",
+		// ffmpeg fails a run once this share of packets failed to decode
+		"max_error_rate": "1",
+	}
+}
+
+// ResyncAudioFilter fills the gaps skipped audio leaves with silence, so
+// speech after a damaged part keeps its time in the recording
+const ResyncAudioFilter = "aresample=async=1:first_pts=0"
@@ -0,0 +1,41 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestRunContextKillsOnCancel(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not available")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = RunContext(ctx, exec.Command(sleep, "10"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RunContext() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("process was not killed promptly (ran %s)", elapsed)
+	}
+}
+
+func TestRunContextCancelledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cmd := exec.Command("does-not-need-to-exist")
+	if err := RunContext(ctx, cmd); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunContext() error = %v, want canceled", err)
+	}
+	if cmd.Process != nil {
+		t.Error("command should not have been started")
+	}
+}
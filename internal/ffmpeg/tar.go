@@ -0,0 +1,86 @@
+package ffmpeg
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// extracts ffmpeg and ffprobe from a .tar.gz or .tar.xz archive, wherever
+// they are in it. Go has no xz decoder, so .tar.xz needs xz on PATH.
+func extractTar(archivePath, format, installDir string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("open ffmpeg archive: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var (
+		reader io.Reader
+		wait   = func() error { return nil }
+	)
+	switch format {
+	case "tar.gz":
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("open ffmpeg archive: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+		reader = gz
+	case "tar.xz":
+		xzPath, err := exec.LookPath("xz")
+		if err != nil {
+			return errors.New("xz not found on PATH: it is needed to unpack .tar.xz ffmpeg builds")
+		}
+		cmd := exec.Command(xzPath, "-dc")
+		cmd.Stdin = file
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return fmt.Errorf("start xz: %w", err)
+		}
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("start xz: %w", err)
+		}
+		defer func() { _ = cmd.Process.Kill() }()
+		reader = stdout
+		wait = cmd.Wait
+	default:
+		return fmt.Errorf("unsupported ffmpeg archive format %q", format)
+	}
+
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read ffmpeg archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := filepath.Base(header.Name)
+		var dest string
+		switch {
+		case isFFmpegBinary(name):
+			dest = filepath.Join(installDir, "ffmpeg"+executableSuffix())
+		case isFFprobeBinary(name):
+			dest = filepath.Join(installDir, "ffprobe"+executableSuffix())
+		default:
+			continue
+		}
+		if err := writeBinary(tr, dest); err != nil {
+			return err
+		}
+	}
+	if err := wait(); err != nil {
+		return fmt.Errorf("decompress ffmpeg archive: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package fonts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"unicode"
+)
+
+// Charset is the set of characters a font has glyphs for
+type Charset struct {
+	ranges [][2]rune // sorted, not overlapping
+}
+
+// Has reports whether the font has a glyph for r
+func (c *Charset) Has(r rune) bool {
+	i := sort.Search(len(c.ranges), func(i int) bool { return c.ranges[i][1] >= r })
+	return i < len(c.ranges) && c.ranges[i][0] <= r
+}
+
+// Missing returns the distinct characters of text the font has no glyph
+// for, in order of first use. Spaces and control and format characters
+// are not drawn, so they are never missing.
+func (c *Charset) Missing(text string) []rune {
+	var missing []rune
+	seen := make(map[rune]bool)
+	for _, r := range text {
+		if seen[r] || unicode.IsSpace(r) || unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		seen[r] = true
+		if !c.Has(r) {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
+
+func (c *Charset) add(first, last rune) {
+	c.ranges = append(c.ranges, [2]rune{first, last})
+}
+
+// sorts and merges the ranges added
+func (c *Charset) normalize() {
+	sort.Slice(c.ranges, func(i, j int) bool { return c.ranges[i][0] < c.ranges[j][0] })
+	merged := c.ranges[:0]
+	for _, r := range c.ranges {
+		if n := len(merged); n > 0 && r[0] <= merged[n-1][1]+1 {
+			merged[n-1][1] = max(merged[n-1][1], r[1])
+			continue
+		}
+		merged = append(merged, r)
+	}
+	c.ranges = merged
+}
+
+// Coverage reads the characters f has glyphs for from its cmap table
+func Coverage(f Font) (*Charset, error) {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font: %w", err)
+	}
+	offsets, err := faces(data)
+	if err != nil {
+		return nil, err
+	}
+	if f.Index >= len(offsets) {
+		return nil, fmt.Errorf("font collection %s has no font %d", f.Path, f.Index)
+	}
+	t, err := table(data, offsets[f.Index], "cmap")
+	if err != nil {
+		return nil, err
+	}
+	return parseCmap(t)
+}
+
+// the characters of every Unicode subtable of a cmap table in format 4
+// or 12
+func parseCmap(t []byte) (*Charset, error) {
+	if len(t) < 4 {
+		return nil, errors.New("truncated cmap table")
+	}
+	c := &Charset{}
+	count := int(binary.BigEndian.Uint16(t[2:]))
+	for i := range count {
+		record := 4 + 8*i
+		if len(t) < record+8 {
+			return nil, errors.New("truncated cmap table")
+		}
+		platform := binary.BigEndian.Uint16(t[record:])
+		encoding := binary.BigEndian.Uint16(t[record+2:])
+		offset := int(binary.BigEndian.Uint32(t[record+4:]))
+		if platform != 0 && !(platform == 3 && (encoding == 1 || encoding == 10)) {
+			continue
+		}
+		if len(t) < offset+2 {
+			continue
+		}
+		switch binary.BigEndian.Uint16(t[offset:]) {
+		case 4:
+			cmapFormat4(c, t[offset:])
+		case 12:
+			cmapFormat12(c, t[offset:])
+		}
+	}
+	c.normalize()
+	return c, nil
+}
+
+// segments of the 16-bit format: runs of characters, with a glyph array
+// for those that are not mapped by a plain offset
+func cmapFormat4(c *Charset, sub []byte) {
+	if len(sub) < 14 {
+		return
+	}
+	segments := int(binary.BigEndian.Uint16(sub[6:])) / 2
+	ends := 14
+	starts := ends + 2*segments + 2
+	rangeOffsets := starts + 4*segments
+	if len(sub) < rangeOffsets+2*segments {
+		return
+	}
+	for i := range segments {
+		end := rune(binary.BigEndian.Uint16(sub[ends+2*i:]))
+		start := rune(binary.BigEndian.Uint16(sub[starts+2*i:]))
+		rangeOffset := int(binary.BigEndian.Uint16(sub[rangeOffsets+2*i:]))
+		if start > end || start == 0xFFFF {
+			continue
+		}
+		if rangeOffset == 0 {
+			c.add(start, end)
+			continue
+		}
+		for r := start; r <= end; r++ {
+			at := rangeOffsets + 2*i + rangeOffset + 2*int(r-start)
+			if at+2 <= len(sub) && binary.BigEndian.Uint16(sub[at:]) != 0 {
+				c.add(r, r)
+			}
+		}
+	}
+}
+
+// groups of the 32-bit format: runs of consecutive characters
+func cmapFormat12(c *Charset, sub []byte) {
+	if len(sub) < 16 {
+		return
+	}
+	groups := int(binary.BigEndian.Uint32(sub[12:]))
+	for i := range groups {
+		group := 16 + 12*i
+		if len(sub) < group+12 {
+			return
+		}
+		start := rune(binary.BigEndian.Uint32(sub[group:]))
+		end := rune(binary.BigEndian.Uint32(sub[group+4:]))
+		if start <= end {
+			c.add(start, end)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package fonts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// a cmap table with a format 4 subtable mapping A-Z by offset and, through
+// the glyph array, a and c but not b, and a format 12 subtable for kana
+func testCmap() []byte {
+	u16 := binary.BigEndian.AppendUint16
+	u32 := binary.BigEndian.AppendUint32
+
+	var f4 []byte
+	// segments: A-Z, a-c, and the closing 0xFFFF
+	f4 = u16(f4, 4)
+	f4 = u16(f4, 0) // length, unchecked
+	f4 = u16(f4, 0)
+	f4 = u16(f4, 6) // segCountX2
+	f4 = u16(u16(u16(f4, 0), 0), 0)
+	for _, end := range []uint16{'Z', 'c', 0xFFFF} {
+		f4 = u16(f4, end)
+	}
+	f4 = u16(f4, 0)
+	for _, start := range []uint16{'A', 'a', 0xFFFF} {
+		f4 = u16(f4, start)
+	}
+	for _, delta := range []uint16{1, 0, 1} {
+		f4 = u16(f4, delta)
+	}
+	// the second segment's glyphs start right after the range offsets: 2
+	// bytes away from its own entry plus the third entry
+	for _, offset := range []uint16{0, 4, 0} {
+		f4 = u16(f4, offset)
+	}
+	for _, glyph := range []uint16{40, 0, 42} {
+		f4 = u16(f4, glyph)
+	}
+
+	var f12 []byte
+	f12 = u16(u16(f12, 12), 0)
+	f12 = u32(u32(f12, 0), 0)
+	f12 = u32(f12, 1)
+	f12 = u32(u32(u32(f12, 0x3041), 0x3096), 100)
+
+	var cmap []byte
+	cmap = u16(u16(cmap, 0), 2)
+	cmap = u32(u16(u16(cmap, 3), 1), 4+16)
+	cmap = u32(u16(u16(cmap, 3), 10), uint32(4+16+len(f4)))
+	cmap = append(append(cmap, f4...), f12...)
+	return cmap
+}
+
+func TestCoverage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.ttf")
+	data := testSFNT(0, "cmap", testCmap(), "name", testNameTable(testName{3, 1, 1, "Test"}))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Coverage(Font{Path: path})
+	if err != nil {
+		t.Fatalf("Coverage() error = %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want []rune
+	}{
+		{"HELLO", nil},
+		{"ac", nil},
+		{"abc bb", []rune{'b'}},
+		{"あいう\u200b", nil},
+		{"日本ごは", []rune{'日', '本'}},
+	}
+	for _, tt := range tests {
+		if got := c.Missing(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Missing(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestReplacementFor(t *testing.T) {
+	tests := []struct {
+		chars []rune
+		want  string
+	}{
+		{[]rune("日本語です"), "Noto Sans CJK JP"},
+		{[]rune("日本"), "Noto Sans CJK SC"},
+		{[]rune("한국어"), "Noto Sans CJK KR"},
+		{[]rune("Ωé"), "Noto Sans"},
+	}
+	for _, tt := range tests {
+		if got := ReplacementFor(tt.chars); got.Family != tt.want {
+			t.Errorf("ReplacementFor(%q) = %s, want %s", string(tt.chars), got.Family, tt.want)
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte("font data"))
+	}))
+	defer server.Close()
+
+	sum := sha256.Sum256([]byte("font data"))
+	pinned := hex.EncodeToString(sum[:])
+	tests := []struct {
+		name    string
+		sha256  string
+		skip    bool
+		wantErr bool
+	}{
+		{"pinned", pinned, false, false},
+		{"mismatch", strings.Repeat("0", 64), false, true},
+		{"mismatch skipped", strings.Repeat("0", 64), true, false},
+		{"unpinned", "", false, true},
+		{"unpinned skipped", "", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetInsecureSkipVerify(tt.skip)
+			defer SetInsecureSkipVerify(false)
+			requests = 0
+			dir := t.TempDir()
+			r := Replacement{Family: "Test Sans", URL: server.URL + "/fonts/TestSans-Regular.ttf", SHA256: tt.sha256}
+			for range 2 {
+				path, err := Download(context.Background(), r, dir)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Download() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if tt.wantErr {
+					if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+						t.Errorf("refused download left %d files behind", len(entries))
+					}
+					return
+				}
+				if path != filepath.Join(dir, "TestSans-Regular.ttf") {
+					t.Errorf("Download() = %s", path)
+				}
+			}
+			if requests != 1 {
+				t.Errorf("downloaded %d times, want once", requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package fonts
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/mgpai22/lipi/internal/httpclient"
+)
+
+// Replacement is an open-license (SIL OFL) Noto font to install when the
+// font a subtitle names is missing or lacks glyphs for its text
+type Replacement struct {
+	Family string // the family name the font answers to
+	URL    string
+	SHA256 string // checksum the download must match
+}
+
+// tagged releases the fonts are downloaded from, so the files only change
+// along with their pinned checksums
+const (
+	notoCJKBaseURL = "https://github.com/notofonts/noto-cjk/raw/Sans2.004/Sans/OTF"
+	notoBaseURL    = "https://github.com/notofonts/notofonts.github.io/raw/noto-monthly-release-2024.12.01/fonts"
+)
+
+//go:embed checksums.txt
+var checksumFile string
+
+var skipVerify atomic.Bool
+
+// SetInsecureSkipVerify turns off checksum verification of downloaded
+// fonts, for fonts that are not pinned yet
+func SetInsecureSkipVerify(skip bool) {
+	skipVerify.Store(skip)
+}
+
+// the replacement family downloaded from url, with the checksum pinned
+// for its file name
+func noto(family, url string) Replacement {
+	return Replacement{Family: family, URL: url, SHA256: checksums()[path.Base(url)]}
+}
+
+// pinned SHA-256 checksums by file name
+func checksums() map[string]string {
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(checksumFile))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return sums
+}
+
+// Noto fonts by the scripts they cover, checked in order: Japanese before
+// Chinese, as Japanese text mixes kana with Han characters
+var replacements = []struct {
+	scripts []*unicode.RangeTable
+	Replacement
+}{
+	{[]*unicode.RangeTable{unicode.Hiragana, unicode.Katakana}, noto(
+		"Noto Sans CJK JP", notoCJKBaseURL+"/Japanese/NotoSansCJKjp-Regular.otf",
+	)},
+	{[]*unicode.RangeTable{unicode.Hangul}, noto(
+		"Noto Sans CJK KR", notoCJKBaseURL+"/Korean/NotoSansCJKkr-Regular.otf",
+	)},
+	{[]*unicode.RangeTable{unicode.Han, unicode.Bopomofo}, noto(
+		"Noto Sans CJK SC", notoCJKBaseURL+"/SimplifiedChinese/NotoSansCJKsc-Regular.otf",
+	)},
+	{[]*unicode.RangeTable{unicode.Arabic}, noto(
+		"Noto Sans Arabic", notoBaseURL+"/NotoSansArabic/hinted/ttf/NotoSansArabic-Regular.ttf",
+	)},
+	{[]*unicode.RangeTable{unicode.Hebrew}, noto(
+		"Noto Sans Hebrew", notoBaseURL+"/NotoSansHebrew/hinted/ttf/NotoSansHebrew-Regular.ttf",
+	)},
+	{[]*unicode.RangeTable{unicode.Devanagari}, noto(
+		"Noto Sans Devanagari", notoBaseURL+"/NotoSansDevanagari/hinted/ttf/NotoSansDevanagari-Regular.ttf",
+	)},
+	{[]*unicode.RangeTable{unicode.Thai}, noto(
+		"Noto Sans Thai", notoBaseURL+"/NotoSansThai/hinted/ttf/NotoSansThai-Regular.ttf",
+	)},
+}
+
+// Latin, Greek, and Cyrillic, and anything no other replacement covers
+var notoSans = noto("Noto Sans", notoBaseURL+"/NotoSans/hinted/ttf/NotoSans-Regular.ttf")
+
+// ReplacementFor picks the Noto font for the script of chars
+func ReplacementFor(chars []rune) Replacement {
+	for _, r := range replacements {
+		for _, c := range chars {
+			if unicode.In(c, r.scripts...) {
+				return r.Replacement
+			}
+		}
+	}
+	return notoSans
+}
+
+// UserDir is the directory of the current user's fonts, where players
+// and ffmpeg's subtitle renderer find them without installing
+func UserDir() string {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "Windows", "Fonts")
+	case "darwin":
+		return filepath.Join(home, "Library", "Fonts")
+	}
+	return filepath.Join(home, ".local", "share", "fonts")
+}
+
+// Download saves the font file of r into dir, unless it is there already,
+// and returns its path. The file must match r.SHA256 before it is put in
+// place; without a checksum it is refused unless verification is skipped.
+func Download(ctx context.Context, r Replacement, dir string) (string, error) {
+	dest := filepath.Join(dir, path.Base(r.URL))
+	if info, err := os.Stat(dest); err == nil && info.Size() > 0 {
+		return dest, nil
+	}
+	if r.SHA256 == "" && !skipVerify.Load() {
+		return "", fmt.Errorf(
+			"no pinned checksum for %s; install %s yourself or pass --insecure-skip-verify",
+			path.Base(r.URL),
+			r.Family,
+		)
+	}
+	if err := httpclient.Refuse("download " + r.Family); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create fonts directory: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	client := httpclient.WithTimeout(httpclient.Default(), 10*time.Minute)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", r.Family, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s: unexpected status %s", r.Family, resp.Status)
+	}
+
+	// written under a temp name, so an interrupted download never looks
+	// like an installed font
+	out, err := os.CreateTemp(dir, "lipi-font-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create font file: %w", err)
+	}
+	tmpPath := out.Name()
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, hash), resp.Body); err != nil {
+		_ = out.Close()
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to download %s: %w", r.Family, err)
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to write font file: %w", err)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); r.SHA256 != "" && sum != strings.ToLower(r.SHA256) && !skipVerify.Load() {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf(
+			"checksum mismatch for %s: got sha256 %s, want %s; the download may be corrupt or tampered with",
+			path.Base(r.URL),
+			sum,
+			r.SHA256,
+		)
+	}
+	if err := os.Rename(tmpPath, dest); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to install font file: %w", err)
+	}
+	return dest, nil
+}
@@ -0,0 +1,269 @@
+package fonts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+)
+
+// Font is a font in a file and the names subtitles may refer to it by
+type Font struct {
+	Path  string
+	Index int // of the font in a collection, 0 otherwise
+	// family, full, and PostScript names, as libass matches them
+	Names []string
+	Style string // the subfamily, such as Regular or Bold
+}
+
+// name table IDs libass matches a font name against
+var matchedNameIDs = map[uint16]bool{
+	1:  true, // family
+	4:  true, // full name
+	6:  true, // PostScript name
+	16: true, // typographic family
+}
+
+// SystemDirs are the directories fonts are installed into on this system
+func SystemDirs() []string {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		dirs := []string{filepath.Join(os.Getenv("WINDIR"), "Fonts")}
+		if local := os.Getenv("LOCALAPPDATA"); local != "" {
+			dirs = append(dirs, filepath.Join(local, "Microsoft", "Windows", "Fonts"))
+		}
+		return dirs
+	case "darwin":
+		return []string{
+			"/System/Library/Fonts",
+			"/Library/Fonts",
+			filepath.Join(home, "Library", "Fonts"),
+		}
+	}
+	return []string{
+		"/usr/share/fonts",
+		"/usr/local/share/fonts",
+		filepath.Join(home, ".local", "share", "fonts"),
+		filepath.Join(home, ".fonts"),
+	}
+}
+
+// IsFontFile reports whether path has the extension of a font file Scan
+// reads
+func IsFontFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ttf", ".otf", ".ttc", ".otc":
+		return true
+	}
+	return false
+}
+
+// Scan reads the names of the fonts in dirs and their subdirectories, one
+// Font for each font of a collection. Missing directories and unreadable
+// files are skipped.
+func Scan(dirs []string) []Font {
+	var found []Font
+	for _, dir := range dirs {
+		_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !IsFontFile(path) {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			offsets, err := faces(data)
+			if err != nil {
+				return nil
+			}
+			for i, offset := range offsets {
+				if names, style, err := fontNames(data, offset); err == nil && len(names) > 0 {
+					found = append(found, Font{Path: path, Index: i, Names: names, Style: style})
+				}
+			}
+			return nil
+		})
+	}
+	return found
+}
+
+// Find returns the font of fonts that answers to name, ignoring case,
+// preferring the regular face of a family
+func Find(fonts []Font, name string) (Font, bool) {
+	var best Font
+	found := false
+	for _, f := range fonts {
+		if !f.answersTo(name) {
+			continue
+		}
+		if !found || !isRegular(best.Style) && isRegular(f.Style) {
+			best, found = f, true
+		}
+	}
+	return best, found
+}
+
+func (f Font) answersTo(name string) bool {
+	for _, n := range f.Names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRegular(style string) bool {
+	switch strings.ToLower(style) {
+	case "regular", "book", "normal", "roman":
+		return true
+	}
+	return false
+}
+
+// Names reads the names of a TrueType or OpenType font, or of every font
+// in a collection
+func Names(data []byte) ([]string, error) {
+	offsets, err := faces(data)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, offset := range offsets {
+		fontNames, _, err := fontNames(data, offset)
+		if err != nil {
+			return nil, err
+		}
+		names = appendNew(names, fontNames...)
+	}
+	return names, nil
+}
+
+// offsets of the table directories of the fonts in data: one for a font
+// file, one per font for a collection
+func faces(data []byte) ([]int, error) {
+	if len(data) < 12 {
+		return nil, errors.New("not a font file")
+	}
+	if string(data[:4]) != "ttcf" {
+		return []int{0}, nil
+	}
+	count := int(binary.BigEndian.Uint32(data[8:12]))
+	if len(data) < 12+4*count {
+		return nil, errors.New("truncated font collection")
+	}
+	offsets := make([]int, count)
+	for i := range offsets {
+		offsets[i] = int(binary.BigEndian.Uint32(data[12+4*i:]))
+	}
+	return offsets, nil
+}
+
+// the table tag of the font whose table directory starts at offset
+func table(data []byte, offset int, tag string) ([]byte, error) {
+	if len(data) < offset+12 {
+		return nil, errors.New("truncated font")
+	}
+	tables := int(binary.BigEndian.Uint16(data[offset+4:]))
+	for i := range tables {
+		record := offset + 12 + 16*i
+		if len(data) < record+16 {
+			return nil, errors.New("truncated table directory")
+		}
+		if string(data[record:record+4]) != tag {
+			continue
+		}
+		start := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if start+length > len(data) {
+			return nil, fmt.Errorf("truncated %s table", tag)
+		}
+		return data[start : start+length], nil
+	}
+	return nil, fmt.Errorf("font has no %s table", tag)
+}
+
+// names and style of the font whose table directory starts at offset
+func fontNames(data []byte, offset int) ([]string, string, error) {
+	t, err := table(data, offset, "name")
+	if err != nil {
+		return nil, "", err
+	}
+	return nameTable(t)
+}
+
+// the matched names of a name table, in the order they appear, and the
+// subfamily
+func nameTable(table []byte) (names []string, style string, err error) {
+	if len(table) < 6 {
+		return nil, "", errors.New("truncated name table")
+	}
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	storage := int(binary.BigEndian.Uint16(table[4:]))
+	for i := range count {
+		record := 6 + 12*i
+		if len(table) < record+12 {
+			return nil, "", fmt.Errorf("truncated name record %d", i)
+		}
+		platform := binary.BigEndian.Uint16(table[record:])
+		encoding := binary.BigEndian.Uint16(table[record+2:])
+		id := binary.BigEndian.Uint16(table[record+6:])
+		length := int(binary.BigEndian.Uint16(table[record+8:]))
+		start := storage + int(binary.BigEndian.Uint16(table[record+10:]))
+		if !matchedNameIDs[id] && id != 2 || start+length > len(table) {
+			continue
+		}
+		raw := table[start : start+length]
+
+		var name string
+		switch {
+		case platform == 0, platform == 3 && (encoding == 0 || encoding == 1 || encoding == 10):
+			name = decodeUTF16(raw)
+		case platform == 1 && encoding == 0:
+			// Mac Roman; names are ASCII in practice
+			name = string(raw)
+		default:
+			continue
+		}
+		switch name = strings.TrimSpace(name); {
+		case name == "":
+		case id == 2:
+			if style == "" {
+				style = name
+			}
+		default:
+			names = appendNew(names, name)
+		}
+	}
+	return names, style, nil
+}
+
+func decodeUTF16(raw []byte) string {
+	units := make([]uint16, len(raw)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(raw[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// appends the names not in list yet, ignoring case
+func appendNew(list []string, names ...string) []string {
+	for _, name := range names {
+		seen := false
+		for _, have := range list {
+			if strings.EqualFold(have, name) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			list = append(list, name)
+		}
+	}
+	return list
+}
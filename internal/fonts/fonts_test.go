@@ -0,0 +1,137 @@
+package fonts
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+type testName struct {
+	platform, encoding, id uint16
+	value                  string
+}
+
+// a font file with nothing but a name table, starting at offset within
+// its file
+func testFont(offset int, names ...testName) []byte {
+	return testSFNT(offset, "name", testNameTable(names...))
+}
+
+func testNameTable(names ...testName) []byte {
+	var records, storage []byte
+	for _, n := range names {
+		var raw []byte
+		if n.platform == 1 {
+			raw = []byte(n.value)
+		} else {
+			for _, u := range utf16.Encode([]rune(n.value)) {
+				raw = binary.BigEndian.AppendUint16(raw, u)
+			}
+		}
+		for _, v := range []uint16{n.platform, n.encoding, 0x409, n.id, uint16(len(raw)), uint16(len(storage))} {
+			records = binary.BigEndian.AppendUint16(records, v)
+		}
+		storage = append(storage, raw...)
+	}
+	var table []byte
+	for _, v := range []uint16{0, uint16(len(names)), uint16(6 + len(records))} {
+		table = binary.BigEndian.AppendUint16(table, v)
+	}
+	return append(append(table, records...), storage...)
+}
+
+// a font file of the tables, given as tag and table pairs, starting at
+// offset within its file
+func testSFNT(offset int, tables ...any) []byte {
+	count := len(tables) / 2
+	font := []byte{0, 1, 0, 0}
+	for _, v := range []uint16{uint16(count), 16, 0, 0} {
+		font = binary.BigEndian.AppendUint16(font, v)
+	}
+	at := offset + 12 + 16*count
+	var body []byte
+	for i := 0; i < len(tables); i += 2 {
+		table := tables[i+1].([]byte)
+		font = append(font, tables[i].(string)...)
+		font = binary.BigEndian.AppendUint32(font, 0)
+		font = binary.BigEndian.AppendUint32(font, uint32(at+len(body)))
+		font = binary.BigEndian.AppendUint32(font, uint32(len(table)))
+		body = append(body, table...)
+	}
+	return append(font, body...)
+}
+
+func TestNames(t *testing.T) {
+	font := testFont(0,
+		testName{3, 1, 1, "Noto Sans JP"},
+		testName{3, 1, 2, "Regular"},
+		testName{3, 1, 4, "Noto Sans JP Regular"},
+		testName{1, 0, 1, "Noto Sans JP"},
+		testName{3, 1, 6, "NotoSansJP-Regular"},
+	)
+	want := []string{"Noto Sans JP", "Noto Sans JP Regular", "NotoSansJP-Regular"}
+	got, err := Names(font)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %q, %v, want %q", got, err, want)
+	}
+
+	// a collection of two fonts
+	var collection []byte
+	collection = append(collection, "ttcf"...)
+	collection = binary.BigEndian.AppendUint32(collection, 0x10000)
+	collection = binary.BigEndian.AppendUint32(collection, 2)
+	first := 12 + 8
+	a := testFont(first, testName{3, 1, 1, "Gothic"})
+	collection = binary.BigEndian.AppendUint32(collection, uint32(first))
+	collection = binary.BigEndian.AppendUint32(collection, uint32(first+len(a)))
+	collection = append(collection, a...)
+	collection = append(collection, testFont(first+len(a), testName{3, 1, 1, "PGothic"})...)
+	got, err = Names(collection)
+	if err != nil || !reflect.DeepEqual(got, []string{"Gothic", "PGothic"}) {
+		t.Errorf("Names(collection) = %q, %v", got, err)
+	}
+
+	if _, err := Names([]byte("not a font at all")); err == nil {
+		t.Error("Names() of a text file succeeded")
+	}
+}
+
+func TestScanAndFind(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "noto"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "noto", "NotoSansJP.otf")
+	if err := os.WriteFile(path, testFont(0, testName{3, 1, 1, "Noto Sans JP"}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("hi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	found := Scan([]string{dir, filepath.Join(dir, "missing")})
+	if len(found) != 1 || found[0].Path != path {
+		t.Fatalf("Scan() = %+v", found)
+	}
+	if f, ok := Find(found, "noto sans jp"); !ok || f.Path != path {
+		t.Errorf("Find() = %+v, %v", f, ok)
+	}
+	if _, ok := Find(found, "Arial"); ok {
+		t.Error("Find() found a font that is not there")
+	}
+
+	family := []Font{
+		{Path: "Sans-Bold.ttf", Names: []string{"Sans", "Sans Bold"}, Style: "Bold"},
+		{Path: "Sans.ttf", Names: []string{"Sans"}, Style: "Regular"},
+		{Path: "Sans-Italic.ttf", Names: []string{"Sans", "Sans Italic"}, Style: "Italic"},
+	}
+	if f, _ := Find(family, "sans"); f.Path != "Sans.ttf" {
+		t.Errorf("Find() = %s, want the regular face", f.Path)
+	}
+	if f, _ := Find(family, "Sans Italic"); f.Path != "Sans-Italic.ttf" {
+		t.Errorf("Find() = %s, want the face named", f.Path)
+	}
+}
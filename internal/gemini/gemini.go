@@ -0,0 +1,117 @@
+package gemini
+
+import (
+	"fmt"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"google.golang.org/genai"
+)
+
+// Settings are the sampling and safety settings of a Gemini request; nil
+// and unset fields keep the API defaults
+type Settings struct {
+	Temperature    *float64
+	TopP           *float64
+	ThinkingBudget *int // tokens the model may spend thinking
+
+	// block thresholds by harm category, such as
+	// HARM_CATEGORY_HATE_SPEECH: BLOCK_NONE
+	SafetyThresholds map[string]string
+}
+
+// Config returns the request config for s, nil for the API defaults;
+// relaxed turns off the safety filters left at their defaults
+func (s Settings) Config(relaxed bool) *genai.GenerateContentConfig {
+	safety := SafetySettings(s.SafetyThresholds, relaxed)
+	if s.Temperature == nil && s.TopP == nil && s.ThinkingBudget == nil && safety == nil {
+		return nil
+	}
+	config := &genai.GenerateContentConfig{SafetySettings: safety}
+	if s.Temperature != nil {
+		config.Temperature = genai.Ptr(float32(*s.Temperature))
+	}
+	if s.TopP != nil {
+		config.TopP = genai.Ptr(float32(*s.TopP))
+	}
+	if s.ThinkingBudget != nil {
+		config.ThinkingConfig = &genai.ThinkingConfig{
+			ThinkingBudget: genai.Ptr(int32(*s.ThinkingBudget)),
+		}
+	}
+	return config
+}
+
+// HarmCategories are the harm categories whose block threshold the Gemini
+// API lets callers adjust
+var HarmCategories = []genai.HarmCategory{
+	genai.HarmCategoryHarassment,
+	genai.HarmCategoryHateSpeech,
+	genai.HarmCategorySexuallyExplicit,
+	genai.HarmCategoryDangerousContent,
+	genai.HarmCategoryCivicIntegrity,
+}
+
+// SafetySettings returns the safety settings of a request: the configured
+// thresholds and, when relaxed, BLOCK_NONE for the categories left unset
+func SafetySettings(thresholds map[string]string, relaxed bool) []*genai.SafetySetting {
+	var settings []*genai.SafetySetting
+	for _, category := range HarmCategories {
+		threshold, ok := thresholds[string(category)]
+		if !ok {
+			if !relaxed {
+				continue
+			}
+			threshold = string(genai.HarmBlockThresholdBlockNone)
+		}
+		settings = append(settings, &genai.SafetySetting{
+			Category:  category,
+			Threshold: genai.HarmBlockThreshold(threshold),
+		})
+	}
+	return settings
+}
+
+// Blocked reports a prompt or response that Gemini's safety filters
+// blocked, as an errs.ErrContentFiltered error
+func Blocked(result *genai.GenerateContentResponse) error {
+	if result == nil {
+		return nil
+	}
+	if feedback := result.PromptFeedback; feedback != nil && feedback.BlockReason != "" {
+		return errs.Mark(
+			errs.ErrContentFiltered,
+			fmt.Errorf("prompt blocked by Gemini: %s", feedback.BlockReason),
+		)
+	}
+	if len(result.Candidates) == 0 || result.Candidates[0] == nil {
+		return nil
+	}
+	switch reason := result.Candidates[0].FinishReason; reason {
+	case genai.FinishReasonSafety,
+		genai.FinishReasonBlocklist,
+		genai.FinishReasonProhibitedContent,
+		genai.FinishReasonSPII,
+		genai.FinishReasonRecitation:
+		return errs.Mark(
+			errs.ErrContentFiltered,
+			fmt.Errorf("response blocked by Gemini: %s", reason),
+		)
+	}
+	return nil
+}
+
+// Truncated reports whether the response was cut off at the output token
+// limit
+func Truncated(result *genai.GenerateContentResponse) bool {
+	return result != nil && len(result.Candidates) > 0 && result.Candidates[0] != nil &&
+		result.Candidates[0].FinishReason == genai.FinishReasonMaxTokens
+}
+
+// ParseClass returns why a response could not be parsed: cut off at the
+// output token limit, or malformed
+func ParseClass(result *genai.GenerateContentResponse) error {
+	if Truncated(result) {
+		return errs.ErrResponseTruncated
+	}
+	return errs.ErrParse
+}
@@ -0,0 +1,89 @@
+package gemini
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"google.golang.org/genai"
+)
+
+func TestSafetySettings(t *testing.T) {
+	thresholds := map[string]string{"HARM_CATEGORY_HATE_SPEECH": "BLOCK_ONLY_HIGH"}
+	if got := SafetySettings(nil, false); got != nil {
+		t.Errorf("unset thresholds = %v, want API defaults", got)
+	}
+
+	got := SafetySettings(thresholds, false)
+	if len(got) != 1 || got[0].Category != genai.HarmCategoryHateSpeech ||
+		got[0].Threshold != genai.HarmBlockThresholdBlockOnlyHigh {
+		t.Errorf("configured thresholds = %+v", got)
+	}
+
+	// a relaxed request keeps configured thresholds and turns the rest off
+	got = SafetySettings(thresholds, true)
+	if len(got) != len(HarmCategories) {
+		t.Fatalf("relaxed settings cover %d categories, want %d", len(got), len(HarmCategories))
+	}
+	for _, s := range got {
+		want := genai.HarmBlockThresholdBlockNone
+		if s.Category == genai.HarmCategoryHateSpeech {
+			want = genai.HarmBlockThresholdBlockOnlyHigh
+		}
+		if s.Threshold != want {
+			t.Errorf("%s threshold = %s, want %s", s.Category, s.Threshold, want)
+		}
+	}
+}
+
+func TestSettingsConfig(t *testing.T) {
+	if got := (Settings{}).Config(false); got != nil {
+		t.Errorf("unset settings = %+v, want API defaults", got)
+	}
+
+	temperature, budget := 0.2, 0
+	got := Settings{Temperature: &temperature, ThinkingBudget: &budget}.Config(false)
+	if got == nil || got.Temperature == nil || *got.Temperature != 0.2 || got.TopP != nil ||
+		got.ThinkingConfig == nil || *got.ThinkingConfig.ThinkingBudget != 0 || got.SafetySettings != nil {
+		t.Errorf("config = %+v", got)
+	}
+
+	if got := (Settings{}).Config(true); got == nil || len(got.SafetySettings) != len(HarmCategories) {
+		t.Errorf("relaxed config = %+v, want every filter off", got)
+	}
+}
+
+func TestBlockedAndParseClass(t *testing.T) {
+	candidate := func(reason genai.FinishReason) *genai.GenerateContentResponse {
+		return &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{FinishReason: reason}}}
+	}
+	tests := []struct {
+		name        string
+		result      *genai.GenerateContentResponse
+		wantBlocked bool
+		wantClass   error
+	}{
+		{"nil", nil, false, errs.ErrParse},
+		{"stop", candidate(genai.FinishReasonStop), false, errs.ErrParse},
+		{"safety", candidate(genai.FinishReasonSafety), true, errs.ErrParse},
+		{"recitation", candidate(genai.FinishReasonRecitation), true, errs.ErrParse},
+		{"max tokens", candidate(genai.FinishReasonMaxTokens), false, errs.ErrResponseTruncated},
+		{"prompt blocked", &genai.GenerateContentResponse{
+			PromptFeedback: &genai.GenerateContentResponsePromptFeedback{BlockReason: genai.BlockedReasonSafety},
+		}, true, errs.ErrParse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Blocked(tt.result)
+			if (err != nil) != tt.wantBlocked {
+				t.Fatalf("Blocked() = %v, want blocked %v", err, tt.wantBlocked)
+			}
+			if err != nil && !errors.Is(err, errs.ErrContentFiltered) {
+				t.Errorf("Blocked() = %v, want a content filter error", err)
+			}
+			if got := ParseClass(tt.result); got != tt.wantClass {
+				t.Errorf("ParseClass() = %v, want %v", got, tt.wantClass)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package glossary
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// single glossary term with an optional fixed translation
+type Entry struct {
+	Term        string
+	Translation string
+}
+
+// Glossary lists names and terms that must be spelled and translated
+// consistently
+type Glossary []Entry
+
+// reads a glossary file
+func Load(path string) (Glossary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open glossary: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	g, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse glossary %s: %w", path, err)
+	}
+	return g, nil
+}
+
+// Parse reads one entry per line. A line is either a bare term, which is
+// kept as-is in translations, or "term = translation". Blank lines and lines
+// starting with # are ignored.
+func Parse(r io.Reader) (Glossary, error) {
+	var g Glossary
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		term, translation, _ := strings.Cut(line, "=")
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return nil, fmt.Errorf("empty term in line %q", line)
+		}
+		g = append(g, Entry{
+			Term:        term,
+			Translation: strings.TrimSpace(translation),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// source-language terms, in file order
+func (g Glossary) Terms() []string {
+	terms := make([]string, len(g))
+	for i, e := range g {
+		terms[i] = e.Term
+	}
+	return terms
+}
@@ -0,0 +1,40 @@
+package glossary
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	input := `# characters
+Naruto
+Konoha = Hidden Leaf Village
+
+  Rasengan =  Spiralling Sphere
+`
+	g, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	want := Glossary{
+		{Term: "Naruto"},
+		{Term: "Konoha", Translation: "Hidden Leaf Village"},
+		{Term: "Rasengan", Translation: "Spiralling Sphere"},
+	}
+	if !slices.Equal(g, want) {
+		t.Errorf("Parse() = %+v, want %+v", g, want)
+	}
+
+	terms := g.Terms()
+	if !slices.Equal(terms, []string{"Naruto", "Konoha", "Rasengan"}) {
+		t.Errorf("Terms() = %v", terms)
+	}
+}
+
+func TestParseEmptyTerm(t *testing.T) {
+	if _, err := Parse(strings.NewReader("= translation\n")); err == nil {
+		t.Error("expected error for missing term")
+	}
+}
@@ -0,0 +1,95 @@
+package hls
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// Options control how subtitles are cut into segments
+type Options struct {
+	// length of each segment; the last one ends with the last cue
+	SegmentDuration time.Duration
+	// 90 kHz MPEG-TS timestamp of the start of the media, which the
+	// X-TIMESTAMP-MAP of each segment maps to cue time 0
+	MPEGTS int64
+	// segment files are named Name_00000.vtt, Name_00001.vtt, and so on
+	Name string
+}
+
+// Segment is one WebVTT file of a subtitle playlist
+type Segment struct {
+	Name     string
+	Duration time.Duration
+	Data     []byte
+}
+
+// Split cuts sub into WebVTT segments of opts.SegmentDuration. A cue is
+// repeated in every segment it overlaps, as HLS requires, and keeps its
+// timestamps, so each segment is read against the same X-TIMESTAMP-MAP.
+// Segments without cues are kept so the playlist covers the whole media.
+func Split(sub *subtitle.Subtitle, opts Options) ([]Segment, error) {
+	if opts.SegmentDuration <= 0 {
+		return nil, fmt.Errorf("segment duration must be positive, got %s", opts.SegmentDuration)
+	}
+
+	var end time.Duration
+	for _, entry := range sub.Entries {
+		end = max(end, entry.EndTime)
+	}
+	count := max(int((end+opts.SegmentDuration-1)/opts.SegmentDuration), 1)
+
+	writer := &subtitle.VTTWriter{Header: []string{
+		fmt.Sprintf("X-TIMESTAMP-MAP=MPEGTS:%d,LOCAL:00:00:00.000", opts.MPEGTS),
+	}}
+	segments := make([]Segment, count)
+	for i := range segments {
+		start := time.Duration(i) * opts.SegmentDuration
+		stop := start + opts.SegmentDuration
+		if i == count-1 && end > start {
+			stop = end
+		}
+
+		part := &subtitle.Subtitle{Format: string(subtitle.FormatVTT)}
+		for _, entry := range sub.Entries {
+			if entry.StartTime < stop && entry.EndTime > start {
+				part.Entries = append(part.Entries, entry)
+			}
+		}
+		var buf bytes.Buffer
+		if err := writer.Encode(part, &buf); err != nil {
+			return nil, fmt.Errorf("failed to encode segment %d: %w", i, err)
+		}
+		segments[i] = Segment{
+			Name:     fmt.Sprintf("%s_%05d.vtt", opts.Name, i),
+			Duration: stop - start,
+			Data:     buf.Bytes(),
+		}
+	}
+	return segments, nil
+}
+
+// Playlist is the VOD media playlist of segments, for the SUBTITLES
+// rendition of a master playlist
+func Playlist(segments []Segment) []byte {
+	target := 1
+	for _, s := range segments {
+		target = max(target, int(math.Round(s.Duration.Seconds())))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("#EXTM3U\n")
+	sb.WriteString("#EXT-X-VERSION:3\n")
+	fmt.Fprintf(&sb, "#EXT-X-TARGETDURATION:%d\n", target)
+	sb.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	sb.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	for _, s := range segments {
+		fmt.Fprintf(&sb, "#EXTINF:%.3f,\n%s\n", s.Duration.Seconds(), s.Name)
+	}
+	sb.WriteString("#EXT-X-ENDLIST\n")
+	return []byte(sb.String())
+}
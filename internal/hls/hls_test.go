@@ -0,0 +1,75 @@
+package hls
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+func TestSplit(t *testing.T) {
+	sub := &subtitle.Subtitle{Entries: []subtitle.Entry{
+		{Index: 1, StartTime: time.Second, EndTime: 3 * time.Second, Text: "First"},
+		{Index: 2, StartTime: 5 * time.Second, EndTime: 7 * time.Second, Text: "Across"},
+		{Index: 3, StartTime: 20 * time.Second, EndTime: 21500 * time.Millisecond, Text: "Last"},
+	}}
+	segments, err := Split(sub, Options{SegmentDuration: 6 * time.Second, MPEGTS: 900000, Name: "subs"})
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		duration time.Duration
+		cues     []string
+	}{
+		{"subs_00000.vtt", 6 * time.Second, []string{"First", "Across"}},
+		{"subs_00001.vtt", 6 * time.Second, []string{"Across"}},
+		{"subs_00002.vtt", 6 * time.Second, nil},
+		{"subs_00003.vtt", 3500 * time.Millisecond, []string{"Last"}},
+	}
+	if len(segments) != len(tests) {
+		t.Fatalf("got %d segments, want %d", len(segments), len(tests))
+	}
+	for i, tt := range tests {
+		s := segments[i]
+		data := string(s.Data)
+		if s.Name != tt.name || s.Duration != tt.duration {
+			t.Errorf("segment %d = %s for %s, want %s for %s", i, s.Name, s.Duration, tt.name, tt.duration)
+		}
+		if !strings.HasPrefix(data, "WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:900000,LOCAL:00:00:00.000\n\n") {
+			t.Errorf("segment %d header = %q", i, data)
+		}
+		if got := strings.Count(data, " --> "); got != len(tt.cues) {
+			t.Errorf("segment %d has %d cues, want %d:\n%s", i, got, len(tt.cues), data)
+		}
+		for _, cue := range tt.cues {
+			if !strings.Contains(data, cue) {
+				t.Errorf("segment %d is missing %q:\n%s", i, cue, data)
+			}
+		}
+	}
+	// cues keep their timestamps
+	if !strings.Contains(string(segments[1].Data), "00:00:05.000 --> 00:00:07.000") {
+		t.Errorf("segment 1 = %q", segments[1].Data)
+	}
+
+	want := "#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-TARGETDURATION:6\n" +
+		"#EXT-X-MEDIA-SEQUENCE:0\n" +
+		"#EXT-X-PLAYLIST-TYPE:VOD\n" +
+		"#EXTINF:6.000,\nsubs_00000.vtt\n" +
+		"#EXTINF:6.000,\nsubs_00001.vtt\n" +
+		"#EXTINF:6.000,\nsubs_00002.vtt\n" +
+		"#EXTINF:3.500,\nsubs_00003.vtt\n" +
+		"#EXT-X-ENDLIST\n"
+	if got := string(Playlist(segments)); got != want {
+		t.Errorf("Playlist() = %q, want %q", got, want)
+	}
+
+	if _, err := Split(sub, Options{}); err == nil {
+		t.Error("Split() with no segment duration succeeded")
+	}
+}
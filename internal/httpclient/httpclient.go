@@ -0,0 +1,68 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	mu      sync.RWMutex
+	current = http.DefaultClient
+)
+
+// New returns a client for provider and download requests. With proxy set,
+// every request goes through it; otherwise HTTPS_PROXY, HTTP_PROXY, and
+// NO_PROXY apply as usual.
+func New(proxy string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", proxy)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// Default is the client set with SetDefault, or http.DefaultClient. Provider
+// constructors and downloaders use it unless they are given one. In offline
+// mode it refuses every request.
+func Default() *http.Client {
+	if Offline() {
+		return offlineClient
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// SetDefault makes c the client used for requests that are not given one;
+// nil restores http.DefaultClient
+func SetDefault(c *http.Client) {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	current = c
+}
+
+// Or returns c, or the default client when c is nil
+func Or(c *http.Client) *http.Client {
+	if c != nil {
+		return c
+	}
+	return Default()
+}
+
+// WithTimeout returns a copy of c that gives up on requests after timeout,
+// for downloads that would otherwise hang on a stalled connection
+func WithTimeout(c *http.Client, timeout time.Duration) *http.Client {
+	copied := *c
+	copied.Timeout = timeout
+	return &copied
+}
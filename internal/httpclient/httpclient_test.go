@@ -0,0 +1,74 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+func TestNewProxy(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxy   string
+		want    string
+		wantErr bool
+	}{
+		{"explicit", "http://proxy.local:3128", "http://proxy.local:3128", false},
+		{"no scheme", "proxy.local:3128", "", true},
+		{"garbage", "://", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := New(tt.proxy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			req, _ := http.NewRequest(http.MethodGet, "https://api.example.com", nil)
+			u, err := client.Transport.(*http.Transport).Proxy(req)
+			if err != nil || u == nil || u.String() != tt.want {
+				t.Errorf("proxy = %v (%v), want %s", u, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultAndOr(t *testing.T) {
+	custom := &http.Client{}
+	SetDefault(custom)
+	defer SetDefault(nil)
+
+	if Default() != custom || Or(nil) != custom {
+		t.Error("expected the custom client as default")
+	}
+	other := &http.Client{}
+	if Or(other) != other {
+		t.Error("Or should prefer a given client")
+	}
+	SetDefault(nil)
+	if Default() != http.DefaultClient {
+		t.Error("SetDefault(nil) should restore http.DefaultClient")
+	}
+}
+
+func TestOffline(t *testing.T) {
+	if Refuse("download") != nil {
+		t.Fatal("Refuse should pass when online")
+	}
+	SetOffline(true)
+	defer SetOffline(false)
+
+	err := Refuse("download ffmpeg")
+	if !errors.Is(err, ErrOffline) || errs.KindOf(err) != errs.KindInput {
+		t.Errorf("Refuse() = %v, want an input error wrapping ErrOffline", err)
+	}
+	_, err = Default().Get("https://example.com")
+	if !errors.Is(err, ErrOffline) {
+		t.Errorf("Get() error = %v, want ErrOffline", err)
+	}
+}
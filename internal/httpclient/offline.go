@@ -0,0 +1,48 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+// ErrOffline is returned for network access attempted in offline mode
+var ErrOffline = errors.New("network access is disabled by --offline")
+
+var offline atomic.Bool
+
+// SetOffline turns offline mode on or off. While it is on, the default
+// client refuses every request, and downloaders and remote providers fail
+// up front with Refuse.
+func SetOffline(on bool) {
+	offline.Store(on)
+}
+
+// Offline reports whether offline mode is on
+func Offline() bool {
+	return offline.Load()
+}
+
+// Refuse is the input error for an operation that needs the network, such
+// as "download ffmpeg", when offline mode is on, and nil otherwise
+func Refuse(operation string) error {
+	if !Offline() {
+		return nil
+	}
+	return errs.Wrap(errs.KindInput, fmt.Errorf("cannot %s: %w", operation, ErrOffline))
+}
+
+// fails every request; the transport of the default client when offline
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, errs.Wrap(
+		errs.KindInput,
+		fmt.Errorf("cannot reach %s: %w", req.URL.Host, ErrOffline),
+	)
+}
+
+var offlineClient = &http.Client{Transport: offlineTransport{}}
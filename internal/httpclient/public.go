@@ -0,0 +1,125 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned for requests to loopback, link-local,
+// private, or otherwise non-public addresses by a PublicOnly client
+var ErrPrivateAddress = errors.New("refusing to connect to a non-public address")
+
+// PublicOnly returns a copy of c that only connects to public addresses,
+// for fetching URLs given by API clients. The address is checked after DNS
+// resolution, when the connection is made, so a host resolving to a
+// private address, or one that changes its answer, is refused too;
+// redirects are checked the same way. Through a proxy, which may itself be
+// on a private address, the target host is resolved and checked before the
+// request instead.
+func PublicOnly(c *http.Client) *http.Client {
+	base := c.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	copied := *c
+	public := &publicTransport{base: base}
+	if t, ok := base.(*http.Transport); ok {
+		direct := t.Clone()
+		direct.Proxy = nil
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   checkDialAddress,
+		}
+		direct.DialContext = dialer.DialContext
+		public.direct = direct
+	}
+	copied.Transport = public
+	return &copied
+}
+
+// checks the resolved address a connection is about to be made to
+func checkDialAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+	}
+	if !IsPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// IsPublicAddr reports whether addr is a globally routable unicast address,
+// not loopback, link-local, private, shared (CGNAT), or unspecified
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	if addr.Is4() {
+		b := addr.As4()
+		// 0.0.0.0/8 "this network", and 100.64.0.0/10 shared by
+		// carrier-grade NAT
+		if b[0] == 0 || (b[0] == 100 && b[1]&0xc0 == 64) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckPublicHost resolves host and returns an ErrPrivateAddress error when
+// any of its addresses is not public, for URLs handed to tools that make
+// their own requests
+func CheckPublicHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !IsPublicAddr(addr) {
+			return fmt.Errorf("%w: %s", ErrPrivateAddress, addr)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !IsPublicAddr(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrPrivateAddress, host, addr)
+		}
+	}
+	return nil
+}
+
+// sends requests without a proxy through direct, which checks the address
+// it dials, and checks the target host of proxied requests up front, as
+// their connection goes to the proxy rather than the target
+type publicTransport struct {
+	base   http.RoundTripper
+	direct *http.Transport // nil when base is not an *http.Transport
+}
+
+func (t *publicTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.direct != nil {
+		proxied := false
+		if proxy := t.base.(*http.Transport).Proxy; proxy != nil {
+			proxyURL, err := proxy(req)
+			if err != nil {
+				return nil, err
+			}
+			proxied = proxyURL != nil
+		}
+		if !proxied {
+			return t.direct.RoundTrip(req)
+		}
+	}
+	if err := CheckPublicHost(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
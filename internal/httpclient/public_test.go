@@ -0,0 +1,97 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := IsPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("IsPublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestPublicOnlyRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := PublicOnly(&http.Client{Transport: &http.Transport{}})
+	resp, err := client.Get(server.URL)
+	if err == nil {
+		_ = resp.Body.Close()
+		t.Fatal("expected the loopback server to be refused")
+	}
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("error = %v, want ErrPrivateAddress", err)
+	}
+
+	if err := CheckPublicHost(context.Background(), "127.0.0.1"); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("CheckPublicHost(127.0.0.1) error = %v, want ErrPrivateAddress", err)
+	}
+	if err := CheckPublicHost(context.Background(), "localhost"); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("CheckPublicHost(localhost) error = %v, want ErrPrivateAddress", err)
+	}
+}
+
+func TestPublicOnlyThroughLoopbackProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		_, _ = io.WriteString(w, "proxied")
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := PublicOnly(&http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}})
+	resp, err := client.Get("http://93.184.216.34/video.mp4")
+	if err != nil {
+		t.Fatalf("public URL through a loopback proxy: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "proxied" {
+		t.Errorf("body = %q, want the proxy's response", body)
+	}
+
+	resp, err = client.Get("http://127.0.0.1:1/video.mp4")
+	if err == nil {
+		_ = resp.Body.Close()
+		t.Fatal("expected a loopback target to be refused through the proxy")
+	}
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("error = %v, want ErrPrivateAddress", err)
+	}
+	if len(proxied) != 1 || proxied[0] != "http://93.184.216.34/video.mp4" {
+		t.Errorf("proxy saw %q, want only the public URL", proxied)
+	}
+}
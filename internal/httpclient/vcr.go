@@ -0,0 +1,257 @@
+package httpclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+// VCREnv turns on recording or replaying provider HTTP interactions, for
+// developing against captured responses without spending API credits
+const (
+	VCREnv    = "LIPI_VCR"
+	VCRDirEnv = "LIPI_VCR_DIR"
+)
+
+// VCR modes
+const (
+	VCRRecord = "record"
+	VCRReplay = "replay"
+)
+
+// ErrNotRecorded is returned in replay mode for a request that was never
+// recorded
+var ErrNotRecorded = errors.New("no recorded response")
+
+// responses larger than this, such as binary downloads, pass through
+// without being recorded
+const maxRecordedBody = 16 << 20
+
+// query parameters that carry credentials, left out of recordings and of
+// the key requests are matched by
+var secretParams = []string{"key", "api_key", "apikey", "access_token", "token"}
+
+// WithVCR returns a copy of c that records its requests and responses to
+// dir, or answers them from dir without touching the network. Requests
+// match by method, URL without credentials, and body; a request sent
+// several times, as when polling, replays the responses in the order they
+// were recorded, then repeats the last one.
+func WithVCR(c *http.Client, mode, dir string) (*http.Client, error) {
+	if mode != VCRRecord && mode != VCRReplay {
+		return nil, fmt.Errorf("invalid %s %q (use %s or %s)", VCREnv, mode, VCRRecord, VCRReplay)
+	}
+	if mode == VCRRecord {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create VCR directory: %w", err)
+		}
+	}
+	next := c.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	copied := *c
+	copied.Transport = &vcrTransport{
+		mode:  mode,
+		dir:   dir,
+		next:  next,
+		calls: make(map[string]int),
+	}
+	return &copied, nil
+}
+
+type vcrTransport struct {
+	mode string
+	dir  string
+	next http.RoundTripper
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// one recorded request and its response, as stored in <dir>/<key>-<n>.json
+type interaction struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Status     int         `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 []byte      `json:"body_base64,omitempty"`
+}
+
+func (t *vcrTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	url := redactURL(req)
+	key := interactionKey(req, url, body)
+
+	t.mu.Lock()
+	n := t.calls[key]
+	t.calls[key]++
+	t.mu.Unlock()
+
+	if t.mode == VCRReplay {
+		return t.replay(req, url, key, n)
+	}
+
+	forwarded := req.Clone(req.Context())
+	forwarded.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := t.next.RoundTrip(forwarded)
+	if err != nil {
+		return nil, err
+	}
+	return t.record(resp, req.Method, url, t.path(key, n))
+}
+
+func (t *vcrTransport) path(key string, n int) string {
+	return filepath.Join(t.dir, fmt.Sprintf("%s-%d.json", key, n))
+}
+
+func (t *vcrTransport) record(resp *http.Response, method, url, path string) (*http.Response, error) {
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRecordedBody+1))
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	if len(data) > maxRecordedBody {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+		return resp, nil
+	}
+	_ = resp.Body.Close()
+
+	rec := interaction{Method: method, URL: url, Status: resp.StatusCode, Header: resp.Header.Clone()}
+	rec.Header.Del("Set-Cookie")
+	rec.Header.Del("Content-Length")
+	if utf8.Valid(data) {
+		rec.Body = string(data)
+	} else {
+		rec.BodyBase64 = data
+	}
+	encoded, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode recording: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, encoded, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write recording: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, fmt.Errorf("failed to write recording: %w", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	return resp, nil
+}
+
+func (t *vcrTransport) replay(req *http.Request, url, key string, n int) (*http.Response, error) {
+	var data []byte
+	for i := n; i >= 0; i-- {
+		var err error
+		data, err = os.ReadFile(t.path(key, i))
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read recording: %w", err)
+		}
+	}
+	if data == nil {
+		return nil, errs.Wrap(errs.KindInput, fmt.Errorf(
+			"%w for %s %s in %s (record it with %s=%s)",
+			ErrNotRecorded,
+			req.Method,
+			url,
+			t.dir,
+			VCREnv,
+			VCRRecord,
+		))
+	}
+
+	var rec interaction
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to parse recording %s: %w", t.path(key, n), err)
+	}
+	body := rec.BodyBase64
+	if body == nil {
+		body = []byte(rec.Body)
+	}
+	header := rec.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// reads req's body without consuming it for the caller
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err == nil {
+			defer func() { _ = body.Close() }()
+			return io.ReadAll(body)
+		}
+	}
+	data, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// req's URL without credentials in its query
+func redactURL(req *http.Request) string {
+	u := *req.URL
+	query := u.Query()
+	for _, param := range secretParams {
+		query.Del(param)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// identifies a request by method, redacted URL, and body. Multipart
+// boundaries are random, so they are left out.
+func interactionKey(req *http.Request, url string, body []byte) string {
+	if _, params, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err == nil &&
+		params["boundary"] != "" {
+		body = bytes.ReplaceAll(body, []byte(params["boundary"]), []byte("boundary"))
+	}
+	h := sha256.New()
+	_, _ = io.WriteString(h, req.Method+" "+url+"\n")
+	_, _ = h.Write(body)
+	sum := hex.EncodeToString(h.Sum(nil))[:16]
+	return strings.ToLower(req.Method) + "-" + req.URL.Hostname() + "-" + sum
+}
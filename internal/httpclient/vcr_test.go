@@ -0,0 +1,115 @@
+package httpclient
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestVCRRecordAndReplay(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"call": %d, "echo": %q}`, n, body)
+	}))
+	dir := t.TempDir()
+
+	recorder, err := WithVCR(upstream.Client(), VCRRecord, dir)
+	if err != nil {
+		t.Fatalf("WithVCR error: %v", err)
+	}
+	post := func(c *http.Client, body string) string {
+		t.Helper()
+		resp, err := c.Post(upstream.URL+"/v1/generate?key=secret123", "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Post error: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		data, _ := io.ReadAll(resp.Body)
+		return string(data)
+	}
+	first := post(recorder, "hello")
+	second := post(recorder, "hello")
+	other := post(recorder, "bye")
+	upstream.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 3 {
+		t.Fatalf("expected 3 recordings, got %v", files)
+	}
+	for _, file := range files {
+		if data, _ := os.ReadFile(file); bytes.Contains(data, []byte("secret123")) {
+			t.Errorf("recording %s contains the API key", file)
+		}
+	}
+
+	player, err := WithVCR(&http.Client{}, VCRReplay, dir)
+	if err != nil {
+		t.Fatalf("WithVCR error: %v", err)
+	}
+	// repeated requests replay in order, then the last one repeats
+	for i, want := range []string{first, second, second} {
+		if got := post(player, "hello"); got != want {
+			t.Errorf("replay %d = %s, want %s", i, got, want)
+		}
+	}
+	if got := post(player, "bye"); got != other {
+		t.Errorf("replay = %s, want %s", got, other)
+	}
+
+	_, err = player.Post(upstream.URL+"/v1/other", "text/plain", strings.NewReader("x"))
+	if !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("expected ErrNotRecorded, got %v", err)
+	}
+}
+
+func TestVCRIgnoresMultipartBoundary(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "transcript")
+	}))
+	dir := t.TempDir()
+
+	upload := func(c *http.Client) (*http.Response, error) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "chunk.mp3")
+		_, _ = fw.Write([]byte("audio"))
+		_ = mw.Close()
+		return c.Post(upstream.URL+"/v1/audio", mw.FormDataContentType(), &body)
+	}
+
+	recorder, _ := WithVCR(upstream.Client(), VCRRecord, dir)
+	resp, err := upload(recorder)
+	if err != nil {
+		t.Fatalf("record error: %v", err)
+	}
+	_ = resp.Body.Close()
+	upstream.Close()
+
+	player, _ := WithVCR(&http.Client{}, VCRReplay, dir)
+	resp, err = upload(player)
+	if err != nil {
+		t.Fatalf("replay error: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if data, _ := io.ReadAll(resp.Body); string(data) != "transcript" {
+		t.Errorf("replayed body = %q", data)
+	}
+}
+
+func TestWithVCRInvalidMode(t *testing.T) {
+	if _, err := WithVCR(&http.Client{}, "rewind", t.TempDir()); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
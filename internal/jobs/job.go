@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/usage"
+)
+
+// represents lifecycle state of a job
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// reports whether the job will not change state again, unless retried
+func (s Status) Done() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCancelled
+}
+
+// per-job overrides of the server's generation defaults
+type Options struct {
+	Format             string `json:"format,omitempty"`
+	Language           string `json:"language,omitempty"`
+	TranscriptLanguage string `json:"transcript_language,omitempty"`
+}
+
+// single subtitle generation request
+type Job struct {
+	ID     string `json:"id"`
+	Status Status `json:"status"`
+	// Batch is the ID of the batch run the job is a file of; empty for
+	// server jobs
+	Batch string `json:"batch,omitempty"`
+	// Name is the uploaded file name or submitted URL, for display
+	Name string `json:"name"`
+	// Source is the local path or URL handed to the generator
+	Source string `json:"source"`
+	// Dir holds the job's input and output files
+	Dir     string  `json:"dir"`
+	Options Options `json:"options"`
+	// Owner names the API token that submitted the job; empty without
+	// token auth
+	Owner string `json:"owner,omitempty"`
+
+	Output  string `json:"output,omitempty"`
+	Entries int    `json:"entries,omitempty"`
+	// MediaSeconds is the length of the transcribed media
+	MediaSeconds float64 `json:"media_seconds,omitempty"`
+	Error        string  `json:"error,omitempty"`
+	// ErrorKind classifies Error, such as "auth" or "content_filtered"
+	ErrorKind string `json:"error_kind,omitempty"`
+
+	// Worker names the remote worker that claimed the job; empty when the
+	// server runs it
+	Worker string `json:"worker,omitempty"`
+
+	Chunks     int `json:"chunks,omitempty"`
+	ChunksDone int `json:"chunks_done,omitempty"`
+	// Usage is what the provider billed, summed over every attempt
+	Usage usage.Usage `json:"usage"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// outcome reported by a Runner
+type Result struct {
+	Output       string
+	Entries      int
+	MediaSeconds float64
+	Usage        usage.Usage
+}
+
+// generates a random hex job ID
+func NewID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// checks that id looks like one produced by NewID, so it is safe to use
+// as a path component
+func ValidID(id string) bool {
+	if len(id) != 16 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
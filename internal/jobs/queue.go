@@ -0,0 +1,299 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+var ErrQueueFull = errors.New("job queue is full")
+
+// ErrDraining is returned for jobs submitted after Drain
+var ErrDraining = errors.New("server is shutting down")
+
+// runs a single job, returning the subtitle it produced
+type Runner func(ctx context.Context, job *Job) (*Result, error)
+
+// how often a running queue checks the store for jobs requeued or
+// cancelled by another process
+const defaultPollInterval = 2 * time.Second
+
+// Queue feeds submitted jobs to a fixed number of workers and records every
+// state change in the store.
+type Queue struct {
+	store        Store
+	runner       Runner
+	workers      int
+	pending      chan string
+	pollInterval time.Duration
+	lease        time.Duration // how long a remote worker may go without reporting
+
+	running   atomic.Bool
+	drainOnce sync.Once
+	drained   chan struct{} // closed by Drain
+
+	mu       sync.Mutex
+	enqueued map[string]bool  // jobs in pending, being processed, or claimed
+	leases   map[string]lease // jobs claimed by remote workers
+}
+
+// creates a queue holding up to size waiting jobs, run by workers local
+// workers; with none, only remote workers calling Claim process jobs
+func NewQueue(store Store, runner Runner, workers, size int) *Queue {
+	return &Queue{
+		store:        store,
+		runner:       runner,
+		workers:      max(workers, 0),
+		pending:      make(chan string, max(size, 1)),
+		pollInterval: defaultPollInterval,
+		lease:        DefaultLease,
+		drained:      make(chan struct{}),
+		enqueued:     make(map[string]bool),
+		leases:       make(map[string]lease),
+	}
+}
+
+// Drain stops the queue taking work: Submit and Claim refuse new jobs, and
+// Run returns once the jobs its workers are running have finished. Queued
+// jobs stay in the store for the next run.
+func (q *Queue) Drain() {
+	q.drainOnce.Do(func() { close(q.drained) })
+}
+
+// reports whether Drain was called
+func (q *Queue) Draining() bool {
+	select {
+	case <-q.drained:
+		return true
+	default:
+		return false
+	}
+}
+
+// Ready reports why the queue cannot take jobs, if it cannot: it is not
+// running, draining, or its store is unreachable
+func (q *Queue) Ready() error {
+	if q.Draining() {
+		return ErrDraining
+	}
+	if !q.running.Load() {
+		return errors.New("job queue is not running")
+	}
+	if err := q.store.Ping(); err != nil {
+		return fmt.Errorf("job store unavailable: %w", err)
+	}
+	return nil
+}
+
+// persists a new job and queues it for processing
+func (q *Queue) Submit(job *Job) error {
+	if job.ID == "" {
+		job.ID = NewID()
+	}
+	job.Status = StatusQueued
+	job.CreatedAt = time.Now().UTC()
+
+	if q.Draining() {
+		return ErrDraining
+	}
+	if len(q.pending) == cap(q.pending) {
+		return ErrQueueFull
+	}
+	if err := q.store.Save(job); err != nil {
+		return err
+	}
+
+	if !q.enqueue(job.ID) {
+		job.Status = StatusFailed
+		job.Error = ErrQueueFull.Error()
+		_ = q.store.Save(job)
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// adds id to pending unless it is already there or running, reporting
+// false when the queue is full
+func (q *Queue) enqueue(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.enqueued[id] {
+		return true
+	}
+	select {
+	case q.pending <- id:
+		q.enqueued[id] = true
+		return true
+	default:
+		return false
+	}
+}
+
+// Run requeues unfinished jobs from a previous run, then processes jobs
+// until ctx is cancelled, or until Drain once running jobs are done. Jobs
+// interrupted by shutdown go back to queued. Jobs requeued in the store
+// while it runs, as by Retry, are picked up; the files of batch runs are
+// left to the batch.
+func (q *Queue) Run(ctx context.Context) error {
+	existing, err := q.store.List()
+	if err != nil {
+		return fmt.Errorf("failed to load jobs: %w", err)
+	}
+	q.running.Store(true)
+	defer q.running.Store(false)
+
+	var recovered []string
+	for i := len(existing) - 1; i >= 0; i-- {
+		if !existing[i].Status.Done() && existing[i].Batch == "" {
+			recovered = append(recovered, existing[i].ID)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		// oldest first, waiting for room as the workers take jobs
+		for _, id := range recovered {
+			for !q.enqueue(id) {
+				select {
+				case <-time.After(q.pollInterval):
+				case <-ctx.Done():
+					return
+				case <-q.drained:
+					return
+				}
+			}
+		}
+
+		ticker := time.NewTicker(q.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.drained:
+				return
+			case <-ticker.C:
+				q.expireLeases()
+				q.pollQueued()
+			}
+		}
+	})
+
+	for range q.workers {
+		wg.Go(func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-q.drained:
+					return
+				case id := <-q.pending:
+					if q.Draining() {
+						// still queued in the store for the next run
+						q.release(id)
+						return
+					}
+					q.process(ctx, id)
+					q.mu.Lock()
+					delete(q.enqueued, id)
+					q.mu.Unlock()
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// queues the jobs another process put back in the store, oldest first,
+// as many as fit
+func (q *Queue) pollQueued() {
+	all, err := q.store.List()
+	if err != nil {
+		return
+	}
+	for i := len(all) - 1; i >= 0; i-- {
+		if all[i].Status == StatusQueued && all[i].Batch == "" && !q.enqueue(all[i].ID) {
+			return
+		}
+	}
+}
+
+func (q *Queue) process(ctx context.Context, id string) {
+	job, err := q.store.Get(id)
+	if err != nil || job.Status.Done() {
+		return
+	}
+
+	started := time.Now().UTC()
+	job.Status = StatusRunning
+	job.Worker = ""
+	job.StartedAt = &started
+	job.Error = ""
+	job.ErrorKind = ""
+	if err := q.store.Save(job); err != nil {
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go q.watchCancel(jobCtx, id, cancel)
+
+	result, err := q.runner(jobCtx, job)
+	if result != nil {
+		job.Usage = job.Usage.Add(result.Usage)
+	}
+	if current, getErr := q.store.Get(id); getErr == nil {
+		if current.Status == StatusCancelled {
+			// cancelled while running; keep the cancellation
+			current.Usage = job.Usage
+			_ = q.store.Save(current)
+			return
+		}
+		job.Chunks, job.ChunksDone = current.Chunks, current.ChunksDone
+	}
+	if err != nil && ctx.Err() != nil {
+		// shutting down; leave the job for the next run
+		job.Status = StatusQueued
+		job.StartedAt = nil
+		_ = q.store.Save(job)
+		return
+	}
+
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		job.ErrorKind = errs.Code(err)
+	} else {
+		job.Status = StatusSucceeded
+		job.Output = result.Output
+		job.Entries = result.Entries
+		job.MediaSeconds = result.MediaSeconds
+	}
+	_ = q.store.Save(job)
+}
+
+// cancels a running job when the store says it was cancelled
+func (q *Queue) watchCancel(ctx context.Context, id string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if job, err := q.store.Get(id); err == nil && job.Status == StatusCancelled {
+				cancel()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueueRunsJobs(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore error: %v", err)
+	}
+
+	runner := func(ctx context.Context, job *Job) (*Result, error) {
+		if job.Name == "bad.mp3" {
+			return nil, errors.New("transcription failed")
+		}
+		return &Result{Output: job.Name + ".srt", Entries: 3}, nil
+	}
+	queue := NewQueue(store, runner, 2, 10)
+
+	good := &Job{Name: "good.mp3"}
+	bad := &Job{Name: "bad.mp3"}
+	for _, job := range []*Job{good, bad} {
+		if err := queue.Submit(job); err != nil {
+			t.Fatalf("Submit error: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- queue.Run(ctx)
+	}()
+
+	waitForStatus(t, store, good.ID, StatusSucceeded)
+	waitForStatus(t, store, bad.ID, StatusFailed)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	got, _ := store.Get(good.ID)
+	if got.Output != "good.mp3.srt" || got.Entries != 3 {
+		t.Errorf("unexpected result %+v", got)
+	}
+	got, _ = store.Get(bad.ID)
+	if got.Error != "transcription failed" {
+		t.Errorf("expected recorded error, got %q", got.Error)
+	}
+}
+
+func TestQueueResumesUnfinishedJobs(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore error: %v", err)
+	}
+
+	interrupted := &Job{ID: NewID(), Status: StatusRunning, CreatedAt: time.Now()}
+	if err := store.Save(interrupted); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	runner := func(ctx context.Context, job *Job) (*Result, error) {
+		return &Result{Output: "out.srt"}, nil
+	}
+	queue := NewQueue(store, runner, 1, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = queue.Run(ctx)
+	}()
+
+	waitForStatus(t, store, interrupted.ID, StatusSucceeded)
+}
+
+func TestQueueSubmitFull(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore error: %v", err)
+	}
+	queue := NewQueue(store, nil, 1, 1)
+
+	if err := queue.Submit(&Job{Name: "one.mp3"}); err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+	if err := queue.Submit(&Job{Name: "two.mp3"}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+}
+
+func waitForStatus(t *testing.T, store Store, id string, want Status) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := store.Get(id)
+		if err == nil && job.Status == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not reach status %s", id, want)
+}
+
+func TestQueuePicksUpRetriedAndCancelledJobs(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore error: %v", err)
+	}
+
+	failed := &Job{ID: NewID(), Status: StatusFailed, Name: "retry.mp3", CreatedAt: time.Now()}
+	if err := store.Save(failed); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	stopped := make(chan struct{})
+	runner := func(ctx context.Context, job *Job) (*Result, error) {
+		if job.Name == "slow.mp3" {
+			<-ctx.Done()
+			close(stopped)
+			return nil, ctx.Err()
+		}
+		return &Result{Output: "out.srt"}, nil
+	}
+	queue := NewQueue(store, runner, 2, 10)
+	queue.pollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = queue.Run(ctx)
+	}()
+
+	slow := &Job{Name: "slow.mp3"}
+	if err := queue.Submit(slow); err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+	waitForStatus(t, store, slow.ID, StatusRunning)
+	if _, err := Cancel(store, slow.ID); err != nil {
+		t.Fatalf("Cancel error: %v", err)
+	}
+
+	if _, err := Retry(store, failed.ID); err != nil {
+		t.Fatalf("Retry error: %v", err)
+	}
+	waitForStatus(t, store, failed.ID, StatusSucceeded)
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled job kept running")
+	}
+	// the cancelled job stays cancelled once its runner returns
+	time.Sleep(50 * time.Millisecond)
+	if got, _ := store.Get(slow.ID); got.Status != StatusCancelled {
+		t.Errorf("cancelled job status = %s", got.Status)
+	}
+}
+
+func TestQueueDrainFinishesRunningJobs(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore error: %v", err)
+	}
+
+	release := make(chan struct{})
+	runner := func(ctx context.Context, job *Job) (*Result, error) {
+		<-release
+		return &Result{Output: "out.srt"}, nil
+	}
+	queue := NewQueue(store, runner, 1, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- queue.Run(ctx)
+	}()
+
+	running := &Job{Name: "running.mp3"}
+	if err := queue.Submit(running); err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+	waitForStatus(t, store, running.ID, StatusRunning)
+	if err := queue.Ready(); err != nil {
+		t.Fatalf("expected a ready queue, got %v", err)
+	}
+	waiting := &Job{Name: "waiting.mp3"}
+	if err := queue.Submit(waiting); err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+
+	queue.Drain()
+	if err := queue.Ready(); !errors.Is(err, ErrDraining) {
+		t.Errorf("expected ErrDraining from Ready, got %v", err)
+	}
+	if err := queue.Submit(&Job{Name: "late.mp3"}); !errors.Is(err, ErrDraining) {
+		t.Errorf("expected ErrDraining from Submit, got %v", err)
+	}
+
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the drain")
+	}
+
+	got, _ := store.Get(running.ID)
+	if got.Status != StatusSucceeded {
+		t.Errorf("expected the running job to finish, got %s", got.Status)
+	}
+	got, _ = store.Get(waiting.ID)
+	if got.Status != StatusQueued {
+		t.Errorf("expected the waiting job to stay queued, got %s", got.Status)
+	}
+}
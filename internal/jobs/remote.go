@@ -0,0 +1,186 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/usage"
+)
+
+// DefaultLease is how long a remote worker holds a job without reporting
+// before the job goes back to the queue
+const DefaultLease = 2 * time.Minute
+
+// ErrCancelled is returned to a remote worker whose job was cancelled
+var ErrCancelled = errors.New("job cancelled")
+
+// a job claimed by a remote worker
+type lease struct {
+	worker string
+	until  time.Time
+}
+
+// Claim hands the next queued job to a remote worker, marking it running,
+// or returns nil when nothing is waiting. The worker must report within
+// the lease, through Heartbeat, Complete, or Fail, or the job is requeued.
+func (q *Queue) Claim(worker string) (*Job, error) {
+	if q.Draining() {
+		return nil, nil
+	}
+	for {
+		var id string
+		select {
+		case id = <-q.pending:
+		default:
+			return nil, nil
+		}
+
+		job, err := q.store.Get(id)
+		if err != nil || job.Status.Done() {
+			q.release(id)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+			continue
+		}
+
+		started := time.Now().UTC()
+		job.Status = StatusRunning
+		job.Worker = worker
+		job.StartedAt = &started
+		job.Error = ""
+		job.ErrorKind = ""
+		if err := q.store.Save(job); err != nil {
+			q.release(id)
+			return nil, err
+		}
+		q.mu.Lock()
+		q.leases[id] = lease{worker: worker, until: time.Now().Add(q.lease)}
+		q.mu.Unlock()
+		return job, nil
+	}
+}
+
+// Heartbeat renews a remote worker's lease on a job and records its chunk
+// progress. It returns ErrCancelled once the job is cancelled, so the
+// worker can stop.
+func (q *Queue) Heartbeat(id, worker string, done, total int) error {
+	if err := q.renew(id, worker); err != nil {
+		return err
+	}
+	job, err := q.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if job.Status == StatusCancelled {
+		q.dropLease(id)
+		return ErrCancelled
+	}
+	if total > 0 {
+		return q.store.SaveProgress(id, done, total)
+	}
+	return nil
+}
+
+// Complete records the subtitle a remote worker produced for a job
+func (q *Queue) Complete(id, worker string, result *Result) error {
+	return q.finishRemote(id, worker, result.Usage, func(job *Job) {
+		job.Status = StatusSucceeded
+		job.Output = result.Output
+		job.Entries = result.Entries
+		job.MediaSeconds = result.MediaSeconds
+	})
+}
+
+// Fail records that a remote worker could not process a job; kind
+// classifies the error as errs.Code does
+func (q *Queue) Fail(id, worker, message, kind string, used usage.Usage) error {
+	return q.finishRemote(id, worker, used, func(job *Job) {
+		job.Status = StatusFailed
+		job.Error = message
+		job.ErrorKind = kind
+	})
+}
+
+func (q *Queue) finishRemote(id, worker string, used usage.Usage, update func(*Job)) error {
+	if err := q.renew(id, worker); err != nil {
+		return err
+	}
+	defer q.dropLease(id)
+
+	job, err := q.store.Get(id)
+	if err != nil {
+		return err
+	}
+	job.Usage = job.Usage.Add(used)
+	if job.Status == StatusCancelled {
+		_ = q.store.Save(job)
+		return ErrCancelled
+	}
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	update(job)
+	return q.store.Save(job)
+}
+
+// Holds reports, as an ErrState error, when worker does not hold the lease
+// on job id, and extends the lease when it does
+func (q *Queue) Holds(id, worker string) error {
+	return q.renew(id, worker)
+}
+
+// extends the lease worker holds on id
+func (q *Queue) renew(id, worker string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	l, ok := q.leases[id]
+	if !ok || l.worker != worker {
+		return fmt.Errorf("%w: job %s is not claimed by worker %q", ErrState, id, worker)
+	}
+	l.until = time.Now().Add(q.lease)
+	q.leases[id] = l
+	return nil
+}
+
+func (q *Queue) dropLease(id string) {
+	q.mu.Lock()
+	delete(q.leases, id)
+	q.mu.Unlock()
+	q.release(id)
+}
+
+// forgets id was queued, so it can be enqueued again
+func (q *Queue) release(id string) {
+	q.mu.Lock()
+	delete(q.enqueued, id)
+	q.mu.Unlock()
+}
+
+// requeues the jobs of remote workers that stopped reporting
+func (q *Queue) expireLeases() {
+	now := time.Now()
+	var expired []string
+	q.mu.Lock()
+	for id, l := range q.leases {
+		if now.After(l.until) {
+			expired = append(expired, id)
+			delete(q.leases, id)
+			delete(q.enqueued, id)
+		}
+	}
+	q.mu.Unlock()
+
+	for _, id := range expired {
+		job, err := q.store.Get(id)
+		if err != nil || job.Status != StatusRunning {
+			continue
+		}
+		job.Status = StatusQueued
+		job.Worker = ""
+		job.StartedAt = nil
+		if err := q.store.Save(job); err == nil {
+			q.enqueue(id)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/usage"
+)
+
+func newRemoteQueue(t *testing.T) (*Queue, Store) {
+	t.Helper()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore error: %v", err)
+	}
+	return NewQueue(store, nil, 0, 10), store
+}
+
+func TestClaimAndComplete(t *testing.T) {
+	queue, store := newRemoteQueue(t)
+
+	if job, err := queue.Claim("box-1"); err != nil || job != nil {
+		t.Fatalf("expected nothing to claim, got %+v, %v", job, err)
+	}
+
+	submitted := &Job{Name: "episode.mp3"}
+	if err := queue.Submit(submitted); err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+	job, err := queue.Claim("box-1")
+	if err != nil || job == nil || job.ID != submitted.ID {
+		t.Fatalf("expected to claim %s, got %+v, %v", submitted.ID, job, err)
+	}
+	if job.Status != StatusRunning || job.Worker != "box-1" {
+		t.Errorf("unexpected claimed job %+v", job)
+	}
+	if again, _ := queue.Claim("box-2"); again != nil {
+		t.Fatalf("claimed job handed out twice")
+	}
+
+	if err := queue.Heartbeat(job.ID, "box-2", 1, 4); !errors.Is(err, ErrState) {
+		t.Errorf("expected ErrState for another worker, got %v", err)
+	}
+	if err := queue.Heartbeat(job.ID, "box-1", 1, 4); err != nil {
+		t.Fatalf("Heartbeat error: %v", err)
+	}
+	got, _ := store.Get(job.ID)
+	if got.ChunksDone != 1 || got.Chunks != 4 {
+		t.Errorf("expected progress 1/4, got %d/%d", got.ChunksDone, got.Chunks)
+	}
+
+	result := &Result{Output: "episode.srt", Entries: 12, Usage: usage.Usage{Requests: 4}}
+	if err := queue.Complete(job.ID, "box-1", result); err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+	got, _ = store.Get(job.ID)
+	if got.Status != StatusSucceeded || got.Output != "episode.srt" || got.Entries != 12 {
+		t.Errorf("unexpected completed job %+v", got)
+	}
+	if got.Usage.Requests != 4 || got.FinishedAt == nil {
+		t.Errorf("expected usage and finish time, got %+v", got)
+	}
+	if err := queue.Complete(job.ID, "box-1", result); !errors.Is(err, ErrState) {
+		t.Errorf("expected ErrState completing twice, got %v", err)
+	}
+}
+
+func TestHeartbeatReportsCancellation(t *testing.T) {
+	queue, store := newRemoteQueue(t)
+
+	submitted := &Job{Name: "episode.mp3"}
+	if err := queue.Submit(submitted); err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+	job, _ := queue.Claim("box-1")
+	if _, err := Cancel(store, job.ID); err != nil {
+		t.Fatalf("Cancel error: %v", err)
+	}
+
+	if err := queue.Heartbeat(job.ID, "box-1", 0, 0); !errors.Is(err, ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", err)
+	}
+	got, _ := store.Get(job.ID)
+	if got.Status != StatusCancelled {
+		t.Errorf("expected cancelled job, got %s", got.Status)
+	}
+}
+
+func TestExpiredLeaseRequeuesJob(t *testing.T) {
+	queue, store := newRemoteQueue(t)
+	queue.lease = 0
+
+	submitted := &Job{Name: "episode.mp3"}
+	if err := queue.Submit(submitted); err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+	if _, err := queue.Claim("box-1"); err != nil {
+		t.Fatalf("Claim error: %v", err)
+	}
+
+	queue.expireLeases()
+	got, _ := store.Get(submitted.ID)
+	if got.Status != StatusQueued || got.Worker != "" {
+		t.Fatalf("expected requeued job, got %+v", got)
+	}
+	if err := queue.Fail(submitted.ID, "box-1", "late", "", usage.Usage{}); !errors.Is(err, ErrState) {
+		t.Errorf("expected ErrState from the expired worker, got %v", err)
+	}
+
+	job, err := queue.Claim("box-2")
+	if err != nil || job == nil || job.Worker != "box-2" {
+		t.Fatalf("expected box-2 to claim the requeued job, got %+v, %v", job, err)
+	}
+}
@@ -0,0 +1,347 @@
+package jobs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/usage"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS jobs (
+	id            TEXT PRIMARY KEY,
+	batch         TEXT NOT NULL DEFAULT '',
+	status        TEXT NOT NULL,
+	name          TEXT NOT NULL DEFAULT '',
+	source        TEXT NOT NULL DEFAULT '',
+	dir           TEXT NOT NULL DEFAULT '',
+	options       TEXT NOT NULL DEFAULT '{}',
+	output        TEXT NOT NULL DEFAULT '',
+	entries       INTEGER NOT NULL DEFAULT 0,
+	error         TEXT NOT NULL DEFAULT '',
+	error_kind    TEXT NOT NULL DEFAULT '',
+	chunks        INTEGER NOT NULL DEFAULT 0,
+	chunks_done   INTEGER NOT NULL DEFAULT 0,
+	requests      INTEGER NOT NULL DEFAULT 0,
+	input_tokens  INTEGER NOT NULL DEFAULT 0,
+	output_tokens INTEGER NOT NULL DEFAULT 0,
+	audio_seconds REAL NOT NULL DEFAULT 0,
+	created_at    INTEGER NOT NULL,
+	started_at    INTEGER,
+	finished_at   INTEGER,
+	worker        TEXT NOT NULL DEFAULT '',
+	owner         TEXT NOT NULL DEFAULT '',
+	media_seconds REAL NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS jobs_status ON jobs (status);
+CREATE INDEX IF NOT EXISTS jobs_batch ON jobs (batch);
+`
+
+const jobColumns = `id, batch, status, name, source, dir, options, output, entries,
+	error, error_kind, chunks, chunks_done, requests, input_tokens,
+	output_tokens, audio_seconds, created_at, started_at, finished_at, worker,
+	owner, media_seconds`
+
+// columns added after the first version of the schema, added to older
+// databases when they are opened
+var addedColumns = []struct{ name, definition string }{
+	{"worker", "TEXT NOT NULL DEFAULT ''"},
+	{"owner", "TEXT NOT NULL DEFAULT ''"},
+	{"media_seconds", "REAL NOT NULL DEFAULT 0"},
+}
+
+// SQLiteStore keeps jobs in <dir>/jobs.db, with each job's input and output
+// files in <dir>/<id>. Several processes can share the database, so 'lipi
+// jobs' can retry or cancel the jobs of a running server or batch.
+type SQLiteStore struct {
+	dir string
+	db  *sql.DB
+}
+
+// opens the store in dir, creating it if needed. Jobs a FileStore left in
+// dir are imported the first time.
+func NewSQLiteStore(dir string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job store: %w", err)
+	}
+	path := filepath.Join(dir, "jobs.db")
+	_, statErr := os.Stat(path)
+	created := os.IsNotExist(statErr)
+
+	db, err := sql.Open(
+		"sqlite",
+		"file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job store: %w", err)
+	}
+	// one connection serializes this process's writes instead of having
+	// them wait on each other's locks
+	db.SetMaxOpenConns(1)
+
+	s := &SQLiteStore{dir: dir, db: db}
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create job store: %w", err)
+	}
+	if err := s.migrate(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	if created {
+		if err := s.importFiles(); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// adds the columns an older database lacks
+func (s *SQLiteStore) migrate() error {
+	rows, err := s.db.Query(`SELECT name FROM pragma_table_info('jobs')`)
+	if err != nil {
+		return fmt.Errorf("failed to read job store schema: %w", err)
+	}
+	have := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to read job store schema: %w", err)
+		}
+		have[name] = true
+	}
+	_ = rows.Close()
+
+	for _, col := range addedColumns {
+		if have[col.name] {
+			continue
+		}
+		if _, err := s.db.Exec(`ALTER TABLE jobs ADD COLUMN ` + col.name + ` ` + col.definition); err != nil {
+			return fmt.Errorf("failed to upgrade job store: %w", err)
+		}
+	}
+	return nil
+}
+
+// copies the job.json files of a FileStore in the same directory
+func (s *SQLiteStore) importFiles() error {
+	files := &FileStore{dir: s.dir}
+	existing, err := files.List()
+	if err != nil {
+		return err
+	}
+	for _, job := range existing {
+		if err := s.Save(job); err != nil {
+			return fmt.Errorf("failed to import job %s: %w", job.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) Ping() error {
+	return s.db.Ping()
+}
+
+// directory for a job's files
+func (s *SQLiteStore) JobDir(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+func (s *SQLiteStore) Save(job *Job) error {
+	if !ValidID(job.ID) {
+		return fmt.Errorf("invalid job ID %q", job.ID)
+	}
+	options, err := json.Marshal(job.Options)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+
+	_, err = s.db.Exec(`INSERT INTO jobs (`+jobColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			batch = excluded.batch,
+			status = excluded.status,
+			name = excluded.name,
+			source = excluded.source,
+			dir = excluded.dir,
+			options = excluded.options,
+			output = excluded.output,
+			entries = excluded.entries,
+			error = excluded.error,
+			error_kind = excluded.error_kind,
+			chunks = excluded.chunks,
+			chunks_done = excluded.chunks_done,
+			requests = excluded.requests,
+			input_tokens = excluded.input_tokens,
+			output_tokens = excluded.output_tokens,
+			audio_seconds = excluded.audio_seconds,
+			created_at = excluded.created_at,
+			started_at = excluded.started_at,
+			finished_at = excluded.finished_at,
+			worker = excluded.worker,
+			owner = excluded.owner,
+			media_seconds = excluded.media_seconds`,
+		job.ID,
+		job.Batch,
+		string(job.Status),
+		job.Name,
+		job.Source,
+		job.Dir,
+		string(options),
+		job.Output,
+		job.Entries,
+		job.Error,
+		job.ErrorKind,
+		job.Chunks,
+		job.ChunksDone,
+		job.Usage.Requests,
+		job.Usage.InputTokens,
+		job.Usage.OutputTokens,
+		job.Usage.AudioSeconds,
+		job.CreatedAt.UnixNano(),
+		nullableTime(job.StartedAt),
+		nullableTime(job.FinishedAt),
+		job.Worker,
+		job.Owner,
+		job.MediaSeconds,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) SaveProgress(id string, done, total int) error {
+	res, err := s.db.Exec(
+		`UPDATE jobs SET chunks_done = ?, chunks = ? WHERE id = ?`,
+		done, total, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Get(id string) (*Job, error) {
+	if !ValidID(id) {
+		return nil, ErrNotFound
+	}
+	job, err := scanJob(s.db.QueryRow(`SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return job, err
+}
+
+// lists all jobs, newest first
+func (s *SQLiteStore) List() ([]*Job, error) {
+	rows, err := s.db.Query(`SELECT ` + jobColumns + ` FROM jobs ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job store: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var jobs []*Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read job store: %w", err)
+	}
+	return jobs, nil
+}
+
+// a *sql.Row or *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row scanner) (*Job, error) {
+	var (
+		job               Job
+		status, options   string
+		created           int64
+		started, finished sql.NullInt64
+		requests, in, out int64
+		audioSeconds      float64
+	)
+	err := row.Scan(
+		&job.ID,
+		&job.Batch,
+		&status,
+		&job.Name,
+		&job.Source,
+		&job.Dir,
+		&options,
+		&job.Output,
+		&job.Entries,
+		&job.Error,
+		&job.ErrorKind,
+		&job.Chunks,
+		&job.ChunksDone,
+		&requests,
+		&in,
+		&out,
+		&audioSeconds,
+		&created,
+		&started,
+		&finished,
+		&job.Worker,
+		&job.Owner,
+		&job.MediaSeconds,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read job: %w", err)
+	}
+	if err := json.Unmarshal([]byte(options), &job.Options); err != nil {
+		return nil, fmt.Errorf("failed to decode job %s: %w", job.ID, err)
+	}
+	job.Status = Status(status)
+	job.Usage = usage.Usage{
+		Requests:     requests,
+		InputTokens:  in,
+		OutputTokens: out,
+		AudioSeconds: audioSeconds,
+	}
+	job.CreatedAt = time.Unix(0, created).UTC()
+	job.StartedAt = timeFromNull(started)
+	job.FinishedAt = timeFromNull(finished)
+	return &job, nil
+}
+
+func nullableTime(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: true}
+}
+
+func timeFromNull(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.Unix(0, v.Int64).UTC()
+	return &t
+}
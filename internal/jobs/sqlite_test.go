@@ -0,0 +1,136 @@
+package jobs
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/usage"
+)
+
+func TestSQLiteStoreRoundTrip(t *testing.T) {
+	store, err := NewSQLiteStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore error: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	started := time.Now().UTC().Add(-30 * time.Second)
+	older := &Job{
+		ID:           NewID(),
+		Status:       StatusSucceeded,
+		Batch:        NewID(),
+		Name:         "ep01.mkv",
+		Options:      Options{Format: "vtt"},
+		Owner:        "alice",
+		Entries:      12,
+		MediaSeconds: 1425.5,
+		Usage:        usage.Usage{Requests: 2, InputTokens: 100, OutputTokens: 40},
+		CreatedAt:    time.Now().Add(-time.Minute),
+		StartedAt:    &started,
+	}
+	newer := &Job{ID: NewID(), Status: StatusQueued, CreatedAt: time.Now()}
+	for _, job := range []*Job{older, newer} {
+		if err := store.Save(job); err != nil {
+			t.Fatalf("Save error: %v", err)
+		}
+	}
+	if err := store.SaveProgress(newer.ID, 3, 8); err != nil {
+		t.Fatalf("SaveProgress error: %v", err)
+	}
+
+	got, err := store.Get(older.ID)
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if got.Name != "ep01.mkv" || got.Options.Format != "vtt" || got.Batch != older.Batch ||
+		got.Entries != 12 || got.Usage != older.Usage || got.Owner != "alice" ||
+		got.MediaSeconds != 1425.5 {
+		t.Errorf("unexpected job %+v", got)
+	}
+	if got.StartedAt == nil || !got.StartedAt.Equal(started) || got.FinishedAt != nil {
+		t.Errorf("times = %v, %v", got.StartedAt, got.FinishedAt)
+	}
+
+	list, err := store.List()
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != newer.ID {
+		t.Fatalf("expected newest job first, got %+v", list)
+	}
+	if list[0].ChunksDone != 3 || list[0].Chunks != 8 || list[0].Status != StatusQueued {
+		t.Errorf("progress = %d/%d, status %s", list[0].ChunksDone, list[0].Chunks, list[0].Status)
+	}
+
+	if err := store.SaveProgress(NewID(), 1, 2); err != ErrNotFound {
+		t.Errorf("SaveProgress of a missing job error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSQLiteStoreImportsFileStore(t *testing.T) {
+	dir := t.TempDir()
+	files, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore error: %v", err)
+	}
+	job := &Job{ID: NewID(), Status: StatusFailed, Name: "old.mp3", CreatedAt: time.Now()}
+	if err := files.Save(job); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	store, err := NewSQLiteStore(dir)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore error: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	got, err := store.Get(job.ID)
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if got.Name != "old.mp3" || got.Status != StatusFailed {
+		t.Errorf("unexpected job %+v", got)
+	}
+	if store.JobDir(job.ID) != filepath.Join(dir, job.ID) {
+		t.Errorf("JobDir() = %s", store.JobDir(job.ID))
+	}
+}
+
+func TestCancelAndRetry(t *testing.T) {
+	store, err := NewSQLiteStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore error: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	job := &Job{ID: NewID(), Status: StatusQueued, CreatedAt: time.Now()}
+	if err := store.Save(job); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	if _, err := Retry(store, job.ID); !errors.Is(err, ErrState) {
+		t.Errorf("Retry of a queued job error = %v, want ErrState", err)
+	}
+	cancelled, err := Cancel(store, job.ID)
+	if err != nil {
+		t.Fatalf("Cancel error: %v", err)
+	}
+	if cancelled.Status != StatusCancelled || cancelled.FinishedAt == nil {
+		t.Errorf("cancelled job %+v", cancelled)
+	}
+	if _, err := Cancel(store, job.ID); !errors.Is(err, ErrState) {
+		t.Errorf("second Cancel error = %v, want ErrState", err)
+	}
+
+	retried, err := Retry(store, job.ID)
+	if err != nil {
+		t.Fatalf("Retry error: %v", err)
+	}
+	if retried.Status != StatusQueued || retried.FinishedAt != nil || retried.Error != "" {
+		t.Errorf("retried job %+v", retried)
+	}
+	if _, err := Cancel(store, NewID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Cancel of a missing job error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,208 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("job not found")
+	// ErrState is returned for a retry or cancel the job's status does not
+	// allow
+	ErrState = errors.New("invalid job state")
+)
+
+// interface for persisting jobs
+type Store interface {
+	Save(job *Job) error
+	Get(id string) (*Job, error)
+	List() ([]*Job, error)
+	// records how many of a job's chunks are transcribed, leaving the rest
+	// of the job as it is stored
+	SaveProgress(id string, done, total int) error
+	// directory for a job's input and output files
+	JobDir(id string) string
+	// reports whether the store can be read
+	Ping() error
+}
+
+// marks a queued or running job cancelled. A queue running the job stops
+// it the next time it checks the store.
+func Cancel(store Store, id string) (*Job, error) {
+	job, err := store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status.Done() {
+		return nil, fmt.Errorf("%w: job %s is already %s", ErrState, id, job.Status)
+	}
+	finished := time.Now().UTC()
+	job.Status = StatusCancelled
+	job.FinishedAt = &finished
+	job.Error = "cancelled"
+	job.ErrorKind = ""
+	if err := store.Save(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// puts a failed or cancelled job back in the queue, keeping the usage of
+// its earlier attempts
+func Retry(store Store, id string) (*Job, error) {
+	job, err := store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != StatusFailed && job.Status != StatusCancelled {
+		return nil, fmt.Errorf("%w: job %s is %s, only failed or cancelled jobs can be retried", ErrState, id, job.Status)
+	}
+	job.Status = StatusQueued
+	job.Error = ""
+	job.ErrorKind = ""
+	job.StartedAt = nil
+	job.FinishedAt = nil
+	job.Chunks, job.ChunksDone = 0, 0
+	if err := store.Save(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// FileStore keeps each job as <dir>/<id>/job.json, next to the job's input
+// and output files, so a restarted server picks up where it left off.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job store: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// directory for a job's files
+func (s *FileStore) JobDir(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+func (s *FileStore) Ping() error {
+	_, err := os.Stat(s.dir)
+	return err
+}
+
+func (s *FileStore) Save(job *Job) error {
+	if !ValidID(job.ID) {
+		return fmt.Errorf("invalid job ID %q", job.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(job)
+}
+
+func (s *FileStore) SaveProgress(id string, done, total int) error {
+	if !ValidID(id) {
+		return ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := s.read(id)
+	if err != nil {
+		return err
+	}
+	job.ChunksDone, job.Chunks = done, total
+	return s.write(job)
+}
+
+func (s *FileStore) Get(id string) (*Job, error) {
+	if !ValidID(id) {
+		return nil, ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read(id)
+}
+
+// lists all jobs, newest first
+func (s *FileStore) List() ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job store: %w", err)
+	}
+
+	var jobs []*Job
+	for _, entry := range entries {
+		if !entry.IsDir() || !ValidID(entry.Name()) {
+			continue
+		}
+		job, err := s.read(entry.Name())
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+func (s *FileStore) read(id string) (*Job, error) {
+	data, err := os.ReadFile(filepath.Join(s.JobDir(id), "job.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read job: %w", err)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job %s: %w", id, err)
+	}
+	return &job, nil
+}
+
+func (s *FileStore) write(job *Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+
+	dir := s.JobDir(job.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create job directory: %w", err)
+	}
+
+	path := filepath.Join(dir, "job.json")
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	return nil
+}
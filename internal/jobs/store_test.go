@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore error: %v", err)
+	}
+
+	older := &Job{
+		ID:        NewID(),
+		Status:    StatusSucceeded,
+		Name:      "ep01.mkv",
+		Options:   Options{Format: "vtt"},
+		CreatedAt: time.Now().Add(-time.Minute),
+	}
+	newer := &Job{ID: NewID(), Status: StatusQueued, CreatedAt: time.Now()}
+	for _, job := range []*Job{older, newer} {
+		if err := store.Save(job); err != nil {
+			t.Fatalf("Save error: %v", err)
+		}
+	}
+
+	got, err := store.Get(older.ID)
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if got.Name != "ep01.mkv" || got.Options.Format != "vtt" {
+		t.Errorf("unexpected job %+v", got)
+	}
+
+	list, err := store.List()
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != newer.ID {
+		t.Errorf("expected newest job first, got %+v", list)
+	}
+}
+
+func TestFileStoreGetRejectsInvalidID(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore error: %v", err)
+	}
+
+	for _, id := range []string{"../etc", "missing", "0123456789abcdef"} {
+		if _, err := store.Get(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) error = %v, want ErrNotFound", id, err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/middleware"
+	"github.com/mgpai22/lipi/internal/translate"
+	"github.com/mgpai22/lipi/internal/usage"
+)
+
+// Generator answers a prompt with text. It is how features beyond
+// translation (summaries, chapters, and the like) use the language model
+// providers.
+type Generator interface {
+	Generate(ctx context.Context, prompt string) (string, error)
+}
+
+// GeneratorFunc adapts a function to Generator
+type GeneratorFunc func(ctx context.Context, prompt string) (string, error)
+
+func (f GeneratorFunc) Generate(ctx context.Context, prompt string) (string, error) {
+	return f(ctx, prompt)
+}
+
+// Vision is a Generator that also reads images sent with the prompt
+type Vision interface {
+	Generator
+	GenerateVision(ctx context.Context, prompt string, images []Image) (string, error)
+}
+
+// Image is a picture sent to the model with a prompt
+type Image = translate.Image
+
+// provider names, the same as the translation providers, whose clients the
+// generators use
+const (
+	ProviderGemini    = string(translate.ProviderGemini)
+	ProviderOpenAI    = string(translate.ProviderOpenAI)
+	ProviderAnthropic = string(translate.ProviderAnthropic)
+)
+
+// default output token limit where the provider needs one
+const defaultMaxTokens = 8192
+
+type Options struct {
+	Model      string
+	MaxTokens  int          // output token limit (default 8192)
+	Usage      *usage.Meter // when set, records tokens sent to the provider
+	HTTPClient *http.Client // when set, used for provider requests
+
+	// sampling settings; nil keeps the provider default
+	Temperature    *float64
+	TopP           *float64
+	ThinkingBudget *int // tokens a Gemini model may spend thinking
+
+	// Gemini block thresholds by harm category, such as
+	// HARM_CATEGORY_HATE_SPEECH: BLOCK_NONE; unset categories keep the
+	// API default
+	SafetyThresholds map[string]string
+
+	// Operation names the requests in dump files, e.g. "summarize"
+	Operation string
+	Retry     middleware.RetryPolicy  // retries failed requests; zero Attempts disables it
+	RateLimit *middleware.RateLimiter // spaces requests to stay under a per-minute quota
+	Dump      *middleware.Dump        // saves each request's prompt, raw response, and outcome
+}
+
+// a provider request: the response text and the SDK response for dumps
+type generateFunc func(ctx context.Context, prompt string, images []Image) (string, any, error)
+
+// generator runs a provider request with the requested middleware
+type generator struct {
+	provider string
+	model    string
+	opts     Options
+	call     generateFunc
+}
+
+// New returns the Generator for provider
+func New(ctx context.Context, provider, apiKey string, opts Options) (Generator, error) {
+	return newGenerator(ctx, provider, apiKey, opts)
+}
+
+// NewVision returns the Vision for provider; only gemini reads images
+func NewVision(ctx context.Context, provider, apiKey string, opts Options) (Vision, error) {
+	if provider != ProviderGemini {
+		return nil, errs.Wrap(errs.KindInput, fmt.Errorf(
+			"provider %q cannot read images: use gemini", provider,
+		))
+	}
+	return newGenerator(ctx, provider, apiKey, opts)
+}
+
+func newGenerator(ctx context.Context, provider, apiKey string, opts Options) (*generator, error) {
+	if apiKey == "" {
+		return nil, errs.Wrap(errs.KindAuth, errors.New("API key is required"))
+	}
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = defaultMaxTokens
+	}
+	if opts.Operation == "" {
+		opts.Operation = "generate"
+	}
+
+	t, err := newTranslator(ctx, provider, apiKey, opts)
+	if err != nil {
+		return nil, err
+	}
+	call := func(ctx context.Context, prompt string, images []Image) (string, any, error) {
+		return t.Generate(ctx, prompt, images, opts.MaxTokens)
+	}
+	return &generator{provider: provider, model: t.Model(), opts: opts, call: call}, nil
+}
+
+func (g *generator) Generate(ctx context.Context, prompt string) (string, error) {
+	return g.generate(ctx, prompt, nil)
+}
+
+func (g *generator) GenerateVision(ctx context.Context, prompt string, images []Image) (string, error) {
+	return g.generate(ctx, prompt, images)
+}
+
+func (g *generator) generate(ctx context.Context, prompt string, images []Image) (string, error) {
+	input := fmt.Sprintf("%d characters", len(prompt))
+	if len(images) > 0 {
+		input += fmt.Sprintf(", %d images", len(images))
+	}
+	return middleware.Retry(ctx, g.opts.Retry, func(ctx context.Context) (string, error) {
+		if err := g.opts.RateLimit.Wait(ctx); err != nil {
+			return "", err
+		}
+		start := time.Now()
+		text, response, err := g.call(ctx, prompt, images)
+		if err == nil && strings.TrimSpace(text) == "" {
+			err = errs.Mark(errs.ErrParse, fmt.Errorf("no text in %s response", g.provider))
+		}
+		g.opts.Dump.Record(middleware.Exchange{
+			Operation: g.opts.Operation,
+			Provider:  g.provider,
+			Model:     g.model,
+			Input:     input,
+			Prompt:    prompt,
+		}, start, response, err)
+		return text, err
+	})
+}
+
+// DecodeJSON parses the JSON object in a model's answer into v, ignoring
+// code fences and any prose around it
+func DecodeJSON(text string, v any) error {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return errs.Mark(errs.ErrParse, fmt.Errorf("no JSON object in response: %s", truncate(text, 200)))
+	}
+	if err := json.Unmarshal([]byte(text[start:end+1]), v); err != nil {
+		return errs.Mark(errs.ErrParse, fmt.Errorf(
+			"failed to parse JSON response: %w (response: %s)", err, truncate(text, 200),
+		))
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/middleware"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{"plain", `{"summary":"ok"}`, "ok", false},
+		{"fenced", "```json\n{\"summary\":\"ok\"}\n```", "ok", false},
+		{"with prose", "Here it is:\n{\"summary\":\"ok\"}\nHope this helps.", "ok", false},
+		{"no object", "Sorry, I can't.", "", true},
+		{"broken", `{"summary":`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got struct {
+				Summary string `json:"summary"`
+			}
+			err := DecodeJSON(tt.text, &got)
+			if (err != nil) != tt.wantErr || got.Summary != tt.want {
+				t.Errorf("DecodeJSON(%q) = %+v, %v", tt.text, got, err)
+			}
+			if err != nil && !errors.Is(err, errs.ErrParse) {
+				t.Errorf("error %v is not a parse error", err)
+			}
+		})
+	}
+}
+
+func TestGenerateRetriesEmptyAnswers(t *testing.T) {
+	calls := 0
+	g := &generator{
+		provider: "test",
+		opts: Options{
+			Operation: "summarize",
+			Retry:     middleware.RetryPolicy{Attempts: 2, Backoff: time.Millisecond},
+		},
+		call: func(ctx context.Context, prompt string, _ []Image) (string, any, error) {
+			calls++
+			if calls == 1 {
+				return "  ", nil, nil
+			}
+			return "answer to " + prompt, nil, nil
+		},
+	}
+
+	got, err := g.Generate(context.Background(), "question")
+	if err != nil || got != "answer to question" || calls != 2 {
+		t.Errorf("Generate() = %q, %v after %d calls", got, err, calls)
+	}
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	if _, err := New(context.Background(), "mistral", "key", Options{}); errs.KindOf(err) != errs.KindInput {
+		t.Errorf("New(mistral) error = %v, want input error", err)
+	}
+	if _, err := New(context.Background(), ProviderOpenAI, "", Options{}); errs.KindOf(err) != errs.KindAuth {
+		t.Errorf("New() without key error = %v, want auth error", err)
+	}
+	if _, err := NewVision(context.Background(), ProviderAnthropic, "key", Options{}); errs.KindOf(err) != errs.KindInput {
+		t.Errorf("NewVision(anthropic) error = %v, want input error", err)
+	}
+}
+
+// serves requests with a handler instead of the network
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (h handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	h.handler.ServeHTTP(rec, req)
+	return rec.Result(), nil
+}
+
+func TestGeminiSendsSettings(t *testing.T) {
+	var body string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"a summary"}]}}]}`))
+	})
+	temperature, budget := 0.2, 0
+	g, err := New(context.Background(), ProviderGemini, "key", Options{
+		HTTPClient:       &http.Client{Transport: handlerTransport{handler}},
+		MaxTokens:        100,
+		Temperature:      &temperature,
+		ThinkingBudget:   &budget,
+		SafetyThresholds: map[string]string{"HARM_CATEGORY_HATE_SPEECH": "BLOCK_NONE"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := g.Generate(context.Background(), "summarize this")
+	if err != nil || got != "a summary" {
+		t.Fatalf("Generate() = %q, %v", got, err)
+	}
+	for _, want := range []string{
+		`"maxOutputTokens":100`,
+		`"temperature":0.2`,
+		`"thinkingBudget":0`,
+		`{"category":"HARM_CATEGORY_HATE_SPEECH","threshold":"BLOCK_NONE"}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("request does not carry %s: %s", want, body)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/translate"
+)
+
+// the translator of provider, whose client, default model, and sampling and
+// safety settings answer the prompts
+func newTranslator(ctx context.Context, provider, apiKey string, opts Options) (translate.TextGenerator, error) {
+	topts := translate.Options{
+		Model:            opts.Model,
+		Usage:            opts.Usage,
+		HTTPClient:       opts.HTTPClient,
+		Temperature:      opts.Temperature,
+		TopP:             opts.TopP,
+		ThinkingBudget:   opts.ThinkingBudget,
+		SafetyThresholds: opts.SafetyThresholds,
+	}
+	switch provider {
+	case ProviderGemini:
+		return translate.NewGeminiTranslator(ctx, apiKey, topts)
+	case ProviderOpenAI:
+		return translate.NewOpenAITranslator(ctx, apiKey, topts)
+	case ProviderAnthropic:
+		return translate.NewAnthropicTranslator(ctx, apiKey, topts)
+	}
+	return nil, errs.Wrap(errs.KindInput, fmt.Errorf(
+		"unsupported provider %q: use gemini, openai, or anthropic", provider,
+	))
+}
@@ -1,7 +1,8 @@
 package logging
 
 import (
-	"os"
+	"io"
+	"sync"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -9,12 +10,31 @@ import (
 
 type Logger struct {
 	*zap.SugaredLogger
+	level    zap.AtomicLevel
+	warnings *warningLog
 }
 
-func NewLogger(verbose bool) *Logger {
-	level := zapcore.InfoLevel
+// messages logged at warn level or above, kept for --json results
+type warningLog struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (w *warningLog) record(entry zapcore.Entry) error {
+	if entry.Level < zapcore.WarnLevel {
+		return nil
+	}
+	w.mu.Lock()
+	w.messages = append(w.messages, entry.Message)
+	w.mu.Unlock()
+	return nil
+}
+
+// creates a console logger writing to out
+func NewLogger(verbose bool, out io.Writer) *Logger {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
 	if verbose {
-		level = zapcore.DebugLevel
+		level.SetLevel(zapcore.DebugLevel)
 	}
 
 	encoderConfig := zapcore.EncoderConfig{
@@ -34,14 +54,64 @@ func NewLogger(verbose bool) *Logger {
 
 	core := zapcore.NewCore(
 		zapcore.NewConsoleEncoder(encoderConfig),
-		zapcore.AddSync(os.Stdout),
+		zapcore.AddSync(out),
+		level,
+	)
+
+	warnings := &warningLog{}
+	zapLogger := zap.New(core, zap.Hooks(warnings.record))
+	return &Logger{zapLogger.Sugar(), level, warnings}
+}
+
+// creates a logger writing JSON lines to w, for log files that are parsed
+// later; debug messages are included when verbose
+func NewJSONLogger(verbose bool, w io.Writer) *Logger {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	if verbose {
+		level.SetLevel(zapcore.DebugLevel)
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "timestamp"
+	encoderConfig.MessageKey = "message"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderConfig.EncodeDuration = zapcore.SecondsDurationEncoder
+
+	core := zapcore.NewCore(
+		zapcore.NewJSONEncoder(encoderConfig),
+		zapcore.AddSync(w),
 		level,
 	)
 
-	zapLogger := zap.New(core)
-	return &Logger{zapLogger.Sugar()}
+	warnings := &warningLog{}
+	zapLogger := zap.New(core, zap.Hooks(warnings.record))
+	return &Logger{zapLogger.Sugar(), level, warnings}
+}
+
+// Tee returns a logger that writes every entry to both l and other. Quiet
+// and Warnings keep working on l, so a log file keeps every message while
+// a progress display silences the console.
+func (l *Logger) Tee(other *Logger) *Logger {
+	core := zapcore.NewTee(l.Desugar().Core(), other.Desugar().Core())
+	return &Logger{zap.New(core).Sugar(), l.level, l.warnings}
 }
 
 func (l *Logger) With(args ...interface{}) *Logger {
-	return &Logger{l.SugaredLogger.With(args...)}
+	return &Logger{l.SugaredLogger.With(args...), l.level, l.warnings}
+}
+
+// limits output to warnings and errors, e.g. while a progress display owns
+// the terminal
+func (l *Logger) Quiet() {
+	l.level.SetLevel(zapcore.WarnLevel)
+}
+
+// warning messages logged so far by this logger and its children
+func (l *Logger) Warnings() []string {
+	if l == nil || l.warnings == nil {
+		return nil
+	}
+	l.warnings.mu.Lock()
+	defer l.warnings.mu.Unlock()
+	return append([]string(nil), l.warnings.messages...)
 }
@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "lipi.log")
+	f, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for file, content := range want {
+		data, err := os.ReadFile(file)
+		if err != nil || string(data) != content {
+			t.Errorf("%s = %q (%v), want %q", filepath.Base(file), data, err, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("expected only two backups to be kept")
+	}
+}
+
+func TestTeeWritesJSON(t *testing.T) {
+	var console, file strings.Builder
+	log := NewLogger(false, &console)
+	log = log.Tee(NewJSONLogger(false, &file))
+	log.Quiet()
+
+	log.Infow("Processing", "chunk", 3)
+	log.Warnw("Slow response")
+
+	if strings.Contains(console.String(), "Processing") {
+		t.Error("quiet console should not show info messages")
+	}
+	lines := strings.Split(strings.TrimSpace(file.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log file has %d lines, want 2: %q", len(lines), file.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["message"] != "Processing" || entry["chunk"] != float64(3) {
+		t.Errorf("entry = %v", entry)
+	}
+	if got := log.Warnings(); len(got) != 1 || got[0] != "Slow response" {
+		t.Errorf("Warnings() = %v", got)
+	}
+}
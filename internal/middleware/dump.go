@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+// Dump saves every provider exchange to a directory, one JSON file per
+// request, so a bad result can be traced to the prompt and raw response
+// behind it after the run. It is safe for concurrent use.
+type Dump struct {
+	dir string
+	seq atomic.Int64
+}
+
+// NewDump returns a dump written to dir
+func NewDump(dir string) *Dump {
+	return &Dump{dir: dir}
+}
+
+// Exchange is one provider request as saved by Dump
+type Exchange struct {
+	Time      time.Time       `json:"time"`
+	Elapsed   string          `json:"elapsed"`
+	Operation string          `json:"operation"` // transcribe or translate
+	Provider  string          `json:"provider"`
+	Model     string          `json:"model,omitempty"`
+	Input     string          `json:"input"` // audio chunk or entry range
+	Prompt    string          `json:"prompt,omitempty"`
+	Response  json.RawMessage `json:"response,omitempty"`
+	Outcome   string          `json:"outcome"` // ok, or the error code of the failure
+	Error     string          `json:"error,omitempty"`
+}
+
+// rawJSONer is implemented by OpenAI and Anthropic SDK responses, which
+// keep the body as received
+type rawJSONer interface {
+	RawJSON() string
+}
+
+// Record saves a request that started at start. response is the provider's
+// reply as returned by its SDK, or nil; err is the request or parse error.
+// Failures to write are ignored, the dump being a diagnostic aid.
+func (d *Dump) Record(e Exchange, start time.Time, response any, err error) {
+	if d == nil {
+		return
+	}
+	e.Time = start
+	e.Elapsed = time.Since(start).String()
+	e.Response = rawResponse(response)
+	e.Outcome = "ok"
+	if err != nil {
+		e.Outcome = errs.Code(err)
+		e.Error = err.Error()
+	}
+
+	data, marshalErr := json.MarshalIndent(e, "", "  ")
+	if marshalErr != nil {
+		return
+	}
+	if os.MkdirAll(d.dir, 0o755) != nil {
+		return
+	}
+	name := fmt.Sprintf("%06d-%s-%s.json", d.seq.Add(1), e.Operation, e.Provider)
+	_ = os.WriteFile(filepath.Join(d.dir, name), data, 0o644)
+}
+
+func rawResponse(response any) json.RawMessage {
+	if v := reflect.ValueOf(response); !v.IsValid() ||
+		(v.Kind() == reflect.Pointer && v.IsNil()) {
+		return nil
+	}
+	switch r := response.(type) {
+	case rawJSONer:
+		if raw := strings.TrimSpace(r.RawJSON()); json.Valid([]byte(raw)) {
+			return json.RawMessage(raw)
+		}
+		return nil
+	}
+	raw, err := json.Marshal(response)
+	if err != nil || string(raw) == "null" {
+		return nil
+	}
+	return raw
+}
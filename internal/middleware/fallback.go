@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+// Fallback sends a request to each of calls in turn until one succeeds or
+// fails with an error that another model cannot fix. onFallback, when set,
+// is called with the position of the next call before moving on to it.
+// The error of the last call tried is returned.
+func Fallback[T any](
+	ctx context.Context,
+	calls []func(context.Context) (T, error),
+	onFallback func(next int, err error),
+) (T, error) {
+	var (
+		result T
+		err    error
+	)
+	for i, call := range calls {
+		if i > 0 {
+			if !DefaultFallback(err) || ctx.Err() != nil {
+				break
+			}
+			if onFallback != nil {
+				onFallback(i, err)
+			}
+		}
+		if result, err = call(ctx); err == nil {
+			return result, nil
+		}
+	}
+	return result, err
+}
+
+// DefaultFallback moves a request on after anything but cancellation and
+// input or auth errors. Rate limits, content filters, and responses that
+// could not be parsed tend to differ between models.
+func DefaultFallback(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	switch errs.KindOf(err) {
+	case errs.KindInput, errs.KindAuth, errs.KindInterrupted:
+		return false
+	}
+	return true
+}
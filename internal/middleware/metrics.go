@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics counts the requests made through a metrics middleware. It is safe
+// for concurrent use and can be shared by several providers.
+type Metrics struct {
+	requests atomic.Int64
+	failures atomic.Int64
+	retries  atomic.Int64
+	latency  atomic.Int64 // nanoseconds
+}
+
+// snapshot of Metrics
+type MetricsSnapshot struct {
+	Requests int64
+	Failures int64
+	Retries  int64
+	Latency  time.Duration // total time spent in requests
+}
+
+// Observe records one request that started at start and ended with err
+func (m *Metrics) Observe(start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.requests.Add(1)
+	if err != nil {
+		m.failures.Add(1)
+	}
+	m.latency.Add(int64(time.Since(start)))
+}
+
+// Retry records a retried request
+func (m *Metrics) Retry() {
+	if m != nil {
+		m.retries.Add(1)
+	}
+}
+
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	if m == nil {
+		return MetricsSnapshot{}
+	}
+	return MetricsSnapshot{
+		Requests: m.requests.Load(),
+		Failures: m.failures.Load(),
+		Retries:  m.retries.Load(),
+		Latency:  time.Duration(m.latency.Load()),
+	}
+}
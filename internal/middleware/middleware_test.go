@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+func TestRetry(t *testing.T) {
+	transient := errors.New("503 unavailable")
+	tests := []struct {
+		name      string
+		attempts  int
+		failures  int
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{"succeeds first time", 2, 0, transient, 1, false},
+		{"recovers", 2, 2, transient, 3, false},
+		{"runs out of attempts", 2, 5, transient, 3, true},
+		{"disabled", 0, 1, transient, 1, true},
+		{"not retryable", 2, 1, errs.Wrap(errs.KindAuth, errors.New("bad key")), 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			policy := RetryPolicy{Attempts: tt.attempts, Backoff: time.Millisecond}
+			got, err := Retry(context.Background(), policy, func(context.Context) (int, error) {
+				calls++
+				if calls <= tt.failures {
+					return 0, tt.err
+				}
+				return 42, nil
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Retry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != 42 {
+				t.Errorf("Retry() = %d, want 42", got)
+			}
+		})
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	_, err := Retry(ctx, RetryPolicy{Attempts: 5, Backoff: time.Hour}, func(context.Context) (int, error) {
+		calls++
+		cancel()
+		return 0, errors.New("boom")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Retry() = %v after %d calls, want the first error after 1 call", err, calls)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	filtered := errs.Mark(errs.ErrContentFiltered, errors.New("blocked"))
+	tests := []struct {
+		name      string
+		errs      []error // error of each call, nil for success
+		wantCalls int
+		wantErr   bool
+	}{
+		{"primary succeeds", []error{nil, nil}, 1, false},
+		{"falls back", []error{filtered, nil}, 2, false},
+		{"all fail", []error{filtered, errors.New("503 unavailable")}, 2, true},
+		{"auth stops", []error{errs.Wrap(errs.KindAuth, errors.New("bad key")), nil}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, fallbacks := 0, 0
+			funcs := make([]func(context.Context) (int, error), len(tt.errs))
+			for i, err := range tt.errs {
+				funcs[i] = func(context.Context) (int, error) {
+					calls++
+					return i, err
+				}
+			}
+			got, err := Fallback(context.Background(), funcs, func(next int, err error) {
+				fallbacks++
+			})
+			if calls != tt.wantCalls || fallbacks != tt.wantCalls-1 {
+				t.Errorf("calls = %d, fallbacks = %d, want %d calls", calls, fallbacks, tt.wantCalls)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Fallback() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != calls-1 {
+				t.Errorf("Fallback() = %d, want the result of call %d", got, calls-1)
+			}
+		})
+	}
+}
+
+func TestRateLimiterSpacesRequests(t *testing.T) {
+	if err := NewRateLimiter(0).Wait(context.Background()); err != nil {
+		t.Fatalf("nil limiter Wait() error = %v", err)
+	}
+
+	limiter := NewRateLimiter(600) // one every 100ms
+	start := time.Now()
+	for range 3 {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+		t.Errorf("three requests took %v, want at least 200ms", elapsed)
+	}
+}
+
+func TestCache(t *testing.T) {
+	cache := NewCache(filepath.Join(t.TempDir(), "cache"))
+	key := Key("transcribe", "gemini", "chunk")
+
+	var got []string
+	if cache.Get(key, &got) {
+		t.Fatal("Get() on an empty cache hit")
+	}
+	if err := cache.Put(key, []string{"a", "b"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if !cache.Get(key, &got) || len(got) != 2 || got[1] != "b" {
+		t.Errorf("Get() = %v, want [a b]", got)
+	}
+	if Key("a", "bc") == Key("ab", "c") {
+		t.Error("Key() does not separate its parts")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	var m Metrics
+	m.Observe(time.Now(), nil)
+	m.Observe(time.Now(), errors.New("boom"))
+	m.Retry()
+
+	got := m.Snapshot()
+	if got.Requests != 2 || got.Failures != 1 || got.Retries != 1 {
+		t.Errorf("Snapshot() = %+v, want 2 requests, 1 failure, 1 retry", got)
+	}
+
+	var nilMetrics *Metrics
+	nilMetrics.Observe(time.Now(), nil)
+	nilMetrics.Retry()
+}
+
+func TestDump(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dump")
+	dump := NewDump(dir)
+
+	type reply struct {
+		Text string `json:"text"`
+	}
+	var noReply *reply
+	dump.Record(Exchange{Operation: "translate", Provider: "gemini", Input: "entries 1-2"},
+		time.Now(), reply{Text: "hola"}, nil)
+	dump.Record(Exchange{Operation: "transcribe", Provider: "openai", Input: "chunk.mp3"},
+		time.Now(), noReply, errs.Wrap(errs.KindRateLimit, errors.New("429 quota exhausted")))
+
+	read := func(name string) Exchange {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		var e Exchange
+		if err := json.Unmarshal(data, &e); err != nil {
+			t.Fatalf("failed to decode %s: %v", name, err)
+		}
+		return e
+	}
+
+	ok := read("000001-translate-gemini.json")
+	var got reply
+	_ = json.Unmarshal(ok.Response, &got)
+	if ok.Outcome != "ok" || got.Text != "hola" {
+		t.Errorf("first exchange = %+v, want ok with the response", ok)
+	}
+	failed := read("000002-transcribe-openai.json")
+	if failed.Outcome != "rate_limit" ||
+		failed.Error == "" || failed.Response != nil {
+		t.Errorf("second exchange = %+v, want the error and no response", failed)
+	}
+
+	var nilDump *Dump
+	nilDump.Record(Exchange{}, time.Now(), nil, nil)
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+// default wait before the first retry
+const DefaultBackoff = time.Second
+
+// RetryPolicy says how often and after which errors a failed request is
+// sent again
+type RetryPolicy struct {
+	Attempts int           // retries after the first failure; 0 disables retrying
+	Backoff  time.Duration // wait before the first retry, doubled for each later one
+	// Retryable reports whether err is worth another attempt. When nil,
+	// everything but cancellation, input, and auth errors is retried.
+	Retryable func(err error) bool
+	// OnRetry, when set, is called before each retry
+	OnRetry func(attempt int, err error)
+}
+
+// Retry calls call until it succeeds, fails with an error that is not
+// retryable, or runs out of attempts
+func Retry[T any](
+	ctx context.Context,
+	policy RetryPolicy,
+	call func(context.Context) (T, error),
+) (T, error) {
+	backoff := policy.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+
+	for attempt := 0; ; attempt++ {
+		result, err := call(ctx)
+		if err == nil || attempt >= policy.Attempts || !retryable(err) {
+			return result, err
+		}
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt+1, err)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// DefaultRetryable retries everything except cancellation and errors that
+// another attempt cannot fix
+func DefaultRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	// the same request would be filtered again
+	if errors.Is(err, errs.ErrContentFiltered) {
+		return false
+	}
+	switch errs.KindOf(err) {
+	case errs.KindInput, errs.KindAuth, errs.KindInterrupted:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/config"
+)
+
+// Model is a model ID as accepted by --model, with the provider's display
+// name when it has one
+type Model struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name,omitempty"`
+}
+
+// queries a provider's model-list API and returns the models lipi can use
+// for transcription or translation, sorted by ID
+func List(ctx context.Context, provider, apiKey string) ([]Model, error) {
+	var (
+		all []Model
+		err error
+	)
+	switch provider {
+	case "gemini":
+		all, err = listGemini(ctx, apiKey)
+	case "openai":
+		all, err = listOpenAI(ctx, apiKey)
+	case "anthropic":
+		all, err = listAnthropic(ctx, apiKey)
+	default:
+		return nil, fmt.Errorf(
+			"unsupported provider %q: use gemini, openai, or anthropic",
+			provider,
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s models: %w", provider, err)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].ID < all[j].ID
+	})
+	return all, nil
+}
+
+// text generation models from OpenAI's list, which also includes image,
+// embedding, speech, and realtime models
+func isOpenAITextModel(id string) bool {
+	for _, skip := range []string{
+		"audio", "realtime", "tts", "transcribe", "image", "search", "embedding",
+	} {
+		if strings.Contains(id, skip) {
+			return false
+		}
+	}
+	if strings.HasPrefix(id, "gpt-") {
+		return true
+	}
+	// o1, o3-mini, o4-mini, ...
+	return len(id) >= 2 && id[0] == 'o' && id[1] >= '0' && id[1] <= '9'
+}
+
+// cached model list written by the last successful `lipi models` run
+type cacheFile struct {
+	Updated time.Time `json:"updated"`
+	Models  []Model   `json:"models"`
+}
+
+// location of the cached model list for a provider
+func CachePath(provider string) string {
+	return filepath.Join(config.CacheDir(), "models", provider+".json")
+}
+
+// SaveCache records a provider's live model list so validation can accept
+// models released after this build.
+func SaveCache(provider string, models []Model) error {
+	path := CachePath(provider)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create model cache: %w", err)
+	}
+
+	data, err := json.MarshalIndent(cacheFile{
+		Updated: time.Now().UTC(),
+		Models:  models,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode model cache: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write model cache: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write model cache: %w", err)
+	}
+	return nil
+}
+
+// cached models for a provider; a missing cache is not an error
+func LoadCache(provider string) ([]Model, error) {
+	data, err := os.ReadFile(CachePath(provider))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read model cache: %w", err)
+	}
+
+	var cache cacheFile
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to decode model cache: %w", err)
+	}
+	return cache.Models, nil
+}
+
+// reports whether the cached list for provider contains id
+func Cached(provider, id string) bool {
+	cached, err := LoadCache(provider)
+	if err != nil {
+		return false
+	}
+	for _, m := range cached {
+		if m.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package models
+
+import "testing"
+
+func TestIsOpenAITextModel(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"gpt-5", true},
+		{"gpt-5.2-pro", true},
+		{"o3-mini", true},
+		{"o4-mini", true},
+		{"gpt-4o-audio-preview", false},
+		{"gpt-4o-realtime-preview", false},
+		{"gpt-4o-mini-tts", false},
+		{"gpt-image-1", false},
+		{"text-embedding-3-small", false},
+		{"dall-e-3", false},
+		{"omni-moderation-latest", false},
+	}
+
+	for _, tt := range tests {
+		if got := isOpenAITextModel(tt.id); got != tt.want {
+			t.Errorf("isOpenAITextModel(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestCacheRoundTrip(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	if Cached("gemini", "gemini-9-pro") {
+		t.Fatal("empty cache should not contain any model")
+	}
+
+	live := []Model{
+		{ID: "gemini-2.5-flash", DisplayName: "Gemini 2.5 Flash"},
+		{ID: "gemini-9-pro"},
+	}
+	if err := SaveCache("gemini", live); err != nil {
+		t.Fatalf("SaveCache() error = %v", err)
+	}
+
+	got, err := LoadCache("gemini")
+	if err != nil {
+		t.Fatalf("LoadCache() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != live[0] {
+		t.Errorf("LoadCache() = %+v, want %+v", got, live)
+	}
+	if !Cached("gemini", "gemini-9-pro") {
+		t.Error("cached model should be found")
+	}
+	if Cached("anthropic", "gemini-9-pro") {
+		t.Error("caches should be per provider")
+	}
+}
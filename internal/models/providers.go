@@ -0,0 +1,79 @@
+package models
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/mgpai22/lipi/internal/httpclient"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"google.golang.org/genai"
+)
+
+func listGemini(ctx context.Context, apiKey string) ([]Model, error) {
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:     apiKey,
+		HTTPClient: httpclient.Default(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Model
+	for m, err := range client.Models.All(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		// only models that can take audio/text prompts
+		if !slices.Contains(m.SupportedActions, "generateContent") {
+			continue
+		}
+		id := strings.TrimPrefix(m.Name, "models/")
+		if !strings.HasPrefix(id, "gemini-") {
+			continue
+		}
+		out = append(out, Model{ID: id, DisplayName: m.DisplayName})
+	}
+	return out, nil
+}
+
+func listOpenAI(ctx context.Context, apiKey string) ([]Model, error) {
+	client := openai.NewClient(
+		option.WithAPIKey(apiKey),
+		option.WithHTTPClient(httpclient.Default()),
+	)
+
+	var out []Model
+	iter := client.Models.ListAutoPaging(ctx)
+	for iter.Next() {
+		m := iter.Current()
+		if m.ID == "whisper-1" || isOpenAITextModel(m.ID) {
+			out = append(out, Model{ID: m.ID})
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func listAnthropic(ctx context.Context, apiKey string) ([]Model, error) {
+	client := anthropic.NewClient(
+		anthropicoption.WithAPIKey(apiKey),
+		anthropicoption.WithHTTPClient(httpclient.Default()),
+	)
+
+	var out []Model
+	iter := client.Models.ListAutoPaging(ctx, anthropic.ModelListParams{})
+	for iter.Next() {
+		m := iter.Current()
+		out = append(out, Model{ID: m.ID, DisplayName: m.DisplayName})
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,132 @@
+package nle
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// version written; Final Cut Pro 10.5 and later import it
+const fcpxmlVersion = "1.9"
+
+type fcpxml struct {
+	XMLName   xml.Name   `xml:"fcpxml"`
+	Version   string     `xml:"version,attr"`
+	Resources fcpFormats `xml:"resources"`
+	Event     fcpEvent   `xml:"library>event"`
+}
+
+type fcpFormats struct {
+	Format struct {
+		ID            string `xml:"id,attr"`
+		Name          string `xml:"name,attr,omitempty"`
+		FrameDuration string `xml:"frameDuration,attr"`
+	} `xml:"format"`
+}
+
+type fcpEvent struct {
+	Name    string     `xml:"name,attr"`
+	Project fcpProject `xml:"project"`
+}
+
+type fcpProject struct {
+	Name     string      `xml:"name,attr"`
+	Sequence fcpSequence `xml:"sequence"`
+}
+
+type fcpSequence struct {
+	Format   string `xml:"format,attr"`
+	Duration string `xml:"duration,attr"`
+	TCStart  string `xml:"tcStart,attr"`
+	TCFormat string `xml:"tcFormat,attr"`
+	Gap      fcpGap `xml:"spine>gap"`
+}
+
+// markers sit on a gap spanning the sequence, which the editor's media is
+// then placed over
+type fcpGap struct {
+	Name     string      `xml:"name,attr"`
+	Offset   string      `xml:"offset,attr"`
+	Duration string      `xml:"duration,attr"`
+	Start    string      `xml:"start,attr"`
+	Markers  []fcpMarker `xml:"marker"`
+}
+
+type fcpMarker struct {
+	Start    string `xml:"start,attr"`
+	Duration string `xml:"duration,attr"`
+	Value    string `xml:"value,attr"`
+}
+
+// a frame count as FCPXML writes times: a multiple of the frame duration
+func (r Rate) fcpTime(frames int64) string {
+	if frames == 0 {
+		return "0s"
+	}
+	return fmt.Sprintf("%d/%ds", frames*r.Den, r.Num)
+}
+
+// duration of one frame, e.g. "1001/30000s"
+func (r Rate) frameDuration() string {
+	return fmt.Sprintf("%d/%ds", r.Den, r.Num)
+}
+
+// Final Cut Pro XML project with one marker per cue
+func writeFCPXML(w io.Writer, sub *subtitle.Subtitle, opts Options) error {
+	rate := opts.rate()
+	title := opts.Title
+	if title == "" {
+		title = "lipi"
+	}
+
+	var end int64
+	markers := make([]fcpMarker, len(sub.Entries))
+	for i, entry := range sub.Entries {
+		start := rate.frames(entry.StartTime)
+		length := max(rate.frames(entry.EndTime)-start, 1)
+		end = max(end, start+length)
+		markers[i] = fcpMarker{
+			Start:    rate.fcpTime(start),
+			Duration: rate.fcpTime(length),
+			Value:    oneLine(entry.Text),
+		}
+	}
+	// a marker must lie inside its clip, so the gap gets a frame to spare
+	duration := rate.fcpTime(end + 1)
+
+	doc := fcpxml{Version: fcpxmlVersion}
+	doc.Resources.Format.ID = "r1"
+	doc.Resources.Format.FrameDuration = rate.frameDuration()
+	doc.Event = fcpEvent{
+		Name: title,
+		Project: fcpProject{
+			Name: title,
+			Sequence: fcpSequence{
+				Format:   "r1",
+				Duration: duration,
+				TCStart:  "0s",
+				TCFormat: "NDF",
+				Gap: fcpGap{
+					Name:     "Transcript",
+					Offset:   "0s",
+					Duration: duration,
+					Start:    "0s",
+					Markers:  markers,
+				},
+			},
+		},
+	}
+
+	if _, err := io.WriteString(w, xml.Header+"<!DOCTYPE fcpxml>\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,137 @@
+package nle
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// Format is an editor file format
+type Format string
+
+const (
+	FormatAudacity Format = "audacity" // label track, .txt
+	FormatPremiere Format = "premiere" // marker list, .csv
+	FormatFCPXML   Format = "fcpxml"   // Final Cut Pro XML, .fcpxml
+)
+
+// ParseFormat validates an --to value
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatAudacity, FormatPremiere, FormatFCPXML:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported export format %q: use audacity, premiere, or fcpxml", s)
+}
+
+// Extension is the file extension the editor expects, with the dot
+func (f Format) Extension() string {
+	switch f {
+	case FormatPremiere:
+		return ".markers.csv"
+	case FormatFCPXML:
+		return ".fcpxml"
+	}
+	return ".labels.txt"
+}
+
+// Rate is a video frame rate as a fraction, e.g. 30000/1001 for 29.97
+type Rate struct {
+	Num, Den int64
+}
+
+// common rates by the names editors show
+var namedRates = map[string]Rate{
+	"23.976": {24000, 1001},
+	"23.98":  {24000, 1001},
+	"29.97":  {30000, 1001},
+	"59.94":  {60000, 1001},
+}
+
+// ParseRate reads a frame rate such as 25, 29.97, or 30000/1001
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	if rate, ok := namedRates[s]; ok {
+		return rate, nil
+	}
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, err1 := strconv.ParseInt(num, 10, 64)
+		d, err2 := strconv.ParseInt(den, 10, 64)
+		if err1 == nil && err2 == nil && n > 0 && d > 0 {
+			return Rate{n, d}, nil
+		}
+	} else if n, err := strconv.ParseInt(s, 10, 64); err == nil && n > 0 {
+		return Rate{n, 1}, nil
+	}
+	return Rate{}, fmt.Errorf("invalid frame rate %q: use e.g. 24, 25, 29.97, or 30000/1001", s)
+}
+
+// frames counts whole frames in d
+func (r Rate) frames(d time.Duration) int64 {
+	return int64(math.Round(d.Seconds() * float64(r.Num) / float64(r.Den)))
+}
+
+// frames per second of timecode, 30 for 29.97
+func (r Rate) timecodeBase() int64 {
+	return (r.Num + r.Den - 1) / r.Den
+}
+
+// non-drop-frame timecode, HH:MM:SS:FF
+func (r Rate) timecode(d time.Duration) string {
+	frames := r.frames(d)
+	base := r.timecodeBase()
+	ff := frames % base
+	total := frames / base
+	return fmt.Sprintf("%02d:%02d:%02d:%02d", total/3600, total/60%60, total%60, ff)
+}
+
+// Options apply to the formats that count frames
+type Options struct {
+	Rate  Rate   // frame rate of the timeline; zero means 25
+	Title string // project or sequence name
+}
+
+func (o Options) rate() Rate {
+	if o.Rate.Num <= 0 || o.Rate.Den <= 0 {
+		return Rate{25, 1}
+	}
+	return o.Rate
+}
+
+// Write encodes the cues of sub in format to w
+func Write(w io.Writer, format Format, sub *subtitle.Subtitle, opts Options) error {
+	switch format {
+	case FormatAudacity:
+		return writeAudacity(w, sub)
+	case FormatPremiere:
+		return writePremiere(w, sub, opts)
+	case FormatFCPXML:
+		return writeFCPXML(w, sub, opts)
+	}
+	return fmt.Errorf("unsupported export format %q", format)
+}
+
+// cue text on one line, as labels and markers hold
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Audacity label track: start, end, and label separated by tabs, times in
+// seconds
+func writeAudacity(w io.Writer, sub *subtitle.Subtitle) error {
+	var sb strings.Builder
+	for _, entry := range sub.Entries {
+		fmt.Fprintf(&sb, "%.6f\t%.6f\t%s\n",
+			entry.StartTime.Seconds(),
+			entry.EndTime.Seconds(),
+			oneLine(entry.Text),
+		)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
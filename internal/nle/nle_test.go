@@ -0,0 +1,116 @@
+package nle
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+func testSubtitle() *subtitle.Subtitle {
+	return &subtitle.Subtitle{Entries: []subtitle.Entry{
+		{Index: 1, StartTime: 1500 * time.Millisecond, EndTime: 3 * time.Second, Text: "Hello,\nworld"},
+		{Index: 2, StartTime: 61 * time.Second, EndTime: 62*time.Second + 500*time.Millisecond, Text: "Tom & \"Jerry\""},
+	}}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Rate
+		wantErr bool
+	}{
+		{"25", Rate{25, 1}, false},
+		{"29.97", Rate{30000, 1001}, false},
+		{"24000/1001", Rate{24000, 1001}, false},
+		{"0", Rate{}, true},
+		{"25.5", Rate{}, true},
+		{"30/0", Rate{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseRate(tt.input)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseRate(%q) = %v, %v", tt.input, got, err)
+			}
+		})
+	}
+}
+
+func TestTimecode(t *testing.T) {
+	tests := []struct {
+		rate Rate
+		d    time.Duration
+		want string
+	}{
+		{Rate{25, 1}, 1500 * time.Millisecond, "00:00:01:13"},
+		{Rate{25, 1}, time.Hour + 2*time.Minute + 3*time.Second, "01:02:03:00"},
+		{Rate{30000, 1001}, 1001 * time.Millisecond, "00:00:01:00"},
+		{Rate{24, 1}, 0, "00:00:00:00"},
+	}
+	for _, tt := range tests {
+		if got := tt.rate.timecode(tt.d); got != tt.want {
+			t.Errorf("%v.timecode(%v) = %q, want %q", tt.rate, tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestWriteAudacity(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatAudacity, testSubtitle(), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "1.500000\t3.000000\tHello, world\n61.000000\t62.500000\tTom & \"Jerry\"\n"
+	if buf.String() != want {
+		t.Errorf("Write(audacity) = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWritePremiere(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatPremiere, testSubtitle(), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0][0] != "Marker Name" {
+		t.Fatalf("records = %q", records)
+	}
+	want := []string{"Tom & \"Jerry\"", "", "00:01:01:00", "00:01:02:13", "00:00:01:13", "Comment"}
+	if strings.Join(records[2], "|") != strings.Join(want, "|") {
+		t.Errorf("records[2] = %q, want %q", records[2], want)
+	}
+}
+
+func TestWriteFCPXML(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{Rate: Rate{30000, 1001}, Title: "Interview"}
+	if err := Write(&buf, FormatFCPXML, testSubtitle(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc fcpxml
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, buf.String())
+	}
+	if doc.Resources.Format.FrameDuration != "1001/30000s" || doc.Event.Project.Name != "Interview" {
+		t.Errorf("doc = %+v", doc)
+	}
+	gap := doc.Event.Project.Sequence.Gap
+	if len(gap.Markers) != 2 {
+		t.Fatalf("markers = %+v", gap.Markers)
+	}
+	// 1.5s is 45 frames at 29.97, each 1001/30000s long
+	if m := gap.Markers[0]; m.Start != "45045/30000s" || m.Value != "Hello, world" {
+		t.Errorf("markers[0] = %+v", m)
+	}
+	if gap.Markers[1].Value != "Tom & \"Jerry\"" {
+		t.Errorf("markers[1] = %+v", gap.Markers[1])
+	}
+}
@@ -0,0 +1,34 @@
+package nle
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// Premiere Pro marker list, with the columns of its own marker export and
+// timecodes at the timeline's frame rate
+func writePremiere(w io.Writer, sub *subtitle.Subtitle, opts Options) error {
+	rate := opts.rate()
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"Marker Name", "Description", "In", "Out", "Duration", "Marker Type",
+	}); err != nil {
+		return err
+	}
+	for _, entry := range sub.Entries {
+		if err := cw.Write([]string{
+			oneLine(entry.Text),
+			"",
+			rate.timecode(entry.StartTime),
+			rate.timecode(entry.EndTime),
+			rate.timecode(entry.EndTime - entry.StartTime),
+			"Comment",
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/httpclient"
+)
+
+// Kind is the service a target URL belongs to, which decides the body
+type Kind string
+
+const (
+	KindWebhook Kind = "webhook" // the payload as JSON
+	KindSlack   Kind = "slack"   // Slack incoming webhook
+	KindDiscord Kind = "discord" // Discord channel webhook
+	KindNtfy    Kind = "ntfy"    // ntfy topic URL
+)
+
+// how long one target may take to accept a notification
+const sendTimeout = 10 * time.Second
+
+// Target is a URL to notify
+type Target struct {
+	Kind Kind
+	URL  string
+}
+
+// ParseTarget validates a target of the named kind
+func ParseTarget(kind, rawURL string) (Target, error) {
+	k := Kind(strings.ToLower(strings.TrimSpace(kind)))
+	switch k {
+	case KindWebhook, KindSlack, KindDiscord, KindNtfy:
+	default:
+		return Target{}, fmt.Errorf(
+			"unsupported notification target %q: use webhook, slack, discord, or ntfy",
+			kind,
+		)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Target{}, fmt.Errorf("invalid %s URL %q", k, rawURL)
+	}
+	return Target{Kind: k, URL: rawURL}, nil
+}
+
+// Message describes a finished run. Webhooks receive Payload; chat
+// targets get Title and Text.
+type Message struct {
+	Title   string
+	Text    string
+	Failed  bool
+	Payload any
+}
+
+// Send posts msg to every target and returns the errors of those that
+// failed, joined
+func Send(ctx context.Context, client *http.Client, targets []Target, msg Message) error {
+	client = httpclient.WithTimeout(httpclient.Or(client), sendTimeout)
+	var errs []error
+	for _, t := range targets {
+		if err := send(ctx, client, t, msg); err != nil {
+			errs = append(errs, fmt.Errorf("failed to notify %s: %w", t.Kind, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func send(ctx context.Context, client *http.Client, t Target, msg Message) error {
+	req, err := newRequest(ctx, t, msg)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// the request body each kind of target expects
+func newRequest(ctx context.Context, t Target, msg Message) (*http.Request, error) {
+	summary := msg.Title
+	if msg.Text != "" {
+		summary += "\n" + msg.Text
+	}
+
+	var (
+		body        []byte
+		contentType = "application/json"
+		err         error
+	)
+	switch t.Kind {
+	case KindSlack:
+		body, err = json.Marshal(map[string]string{"text": summary})
+	case KindDiscord:
+		body, err = json.Marshal(map[string]string{"content": summary})
+	case KindNtfy:
+		body, contentType = []byte(msg.Text), "text/plain; charset=utf-8"
+	default:
+		body, err = json.Marshal(msg.Payload)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if t.Kind == KindNtfy {
+		req.Header.Set("Title", msg.Title)
+		if msg.Failed {
+			req.Header.Set("Priority", "high")
+			req.Header.Set("Tags", "warning")
+		} else {
+			req.Header.Set("Tags", "white_check_mark")
+		}
+	}
+	return req, nil
+}
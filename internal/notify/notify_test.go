@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendBodies(t *testing.T) {
+	type request struct {
+		path, body, title string
+	}
+	received := make(chan request, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- request{r.URL.Path, string(body), r.Header.Get("Title")}
+	}))
+	defer server.Close()
+
+	var targets []Target
+	for _, kind := range []string{"webhook", "slack", "discord", "ntfy"} {
+		target, err := ParseTarget(kind, server.URL+"/"+kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		targets = append(targets, target)
+	}
+	msg := Message{
+		Title:   "lipi generate failed",
+		Text:    "Error: boom",
+		Failed:  true,
+		Payload: map[string]any{"ok": false},
+	}
+	if err := Send(context.Background(), server.Client(), targets, msg); err != nil {
+		t.Fatal(err)
+	}
+	close(received)
+
+	want := map[string]string{
+		"/webhook": `{"ok":false}`,
+		"/slack":   `{"text":"lipi generate failed\nError: boom"}`,
+		"/discord": `{"content":"lipi generate failed\nError: boom"}`,
+		"/ntfy":    "Error: boom",
+	}
+	for r := range received {
+		if r.body != want[r.path] {
+			t.Errorf("%s body = %q, want %q", r.path, r.body, want[r.path])
+		}
+		if r.path == "/ntfy" && r.title != msg.Title {
+			t.Errorf("ntfy Title = %q, want %q", r.title, msg.Title)
+		}
+	}
+}
+
+func TestSendReportsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	err := Send(context.Background(), server.Client(), []Target{{Kind: KindSlack, URL: server.URL}}, Message{Title: "done"})
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Send() error = %v, want the 404 status", err)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		kind, url string
+		wantErr   bool
+	}{
+		{"slack", "https://hooks.slack.com/services/x", false},
+		{"NTFY", "https://ntfy.sh/lipi", false},
+		{"email", "https://example.com", true},
+		{"webhook", "ftp://example.com", true},
+		{"webhook", "not a url", true},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParseTarget(tt.kind, tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("ParseTarget(%q, %q) error = %v, wantErr %v", tt.kind, tt.url, err, tt.wantErr)
+		}
+	}
+}
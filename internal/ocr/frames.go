@@ -0,0 +1,103 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	ffmpegbin "github.com/mgpai22/lipi/internal/ffmpeg"
+)
+
+// Frame is a still sampled from the video
+type Frame struct {
+	At   time.Duration
+	Path string // JPEG file
+}
+
+type SampleOptions struct {
+	Interval time.Duration // time between frames (default 1s)
+	Region   float64       // bottom part of the picture to keep, 0-1 (default 0.3; 1 keeps it all)
+	Width    int           // frames are scaled down to this width (default 960)
+}
+
+func (o SampleOptions) withDefaults() SampleOptions {
+	if o.Interval <= 0 {
+		o.Interval = time.Second
+	}
+	if o.Region <= 0 || o.Region > 1 {
+		o.Region = 0.3
+	}
+	if o.Width <= 0 {
+		o.Width = 960
+	}
+	return o
+}
+
+// Sample writes a frame of videoPath to dir every Interval, cropped to the
+// band where subtitles are burned in
+func Sample(ctx context.Context, videoPath, dir string, opts SampleOptions) ([]Frame, error) {
+	opts = opts.withDefaults()
+	ffmpegPath, err := ffmpegbin.FFmpegPath()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, ffmpegPath, sampleArgs(videoPath, dir, opts)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, errs.Wrap(errs.KindFFmpeg, fmt.Errorf(
+			"ffmpeg frame sampling failed: %w (%s)",
+			err,
+			strings.TrimSpace(string(out)),
+		))
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "frame-*.jpg"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list frames: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, errs.Wrap(errs.KindInput, fmt.Errorf("no video frames in %s", videoPath))
+	}
+	sort.Strings(paths)
+	frames := make([]Frame, len(paths))
+	for i, path := range paths {
+		frames[i] = Frame{At: time.Duration(i) * opts.Interval, Path: path}
+	}
+	return frames, nil
+}
+
+// ffmpeg arguments that sample, crop, and scale the frames into dir
+func sampleArgs(videoPath, dir string, opts SampleOptions) []string {
+	filters := []string{fmt.Sprintf("fps=%g", 1/opts.Interval.Seconds())}
+	if opts.Region < 1 {
+		// an even band height keeps the JPEG encoder happy with any source
+		band := fmt.Sprintf("trunc(ih*%g/2)*2", opts.Region)
+		filters = append(filters, fmt.Sprintf("crop=iw:%s:0:ih-%s", band, band))
+	}
+	filters = append(filters, fmt.Sprintf("scale='min(%d,iw)':-2", opts.Width))
+	return []string{
+		"-v", "error",
+		"-i", videoPath,
+		"-map", "0:v:0",
+		"-vf", strings.Join(filters, ","),
+		"-q:v", "4",
+		filepath.Join(dir, "frame-%06d.jpg"),
+	}
+}
+
+// reads a frame's JPEG for the model
+func (f Frame) data() ([]byte, error) {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read frame: %w", err)
+	}
+	return data, nil
+}
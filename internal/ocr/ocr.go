@@ -0,0 +1,206 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mgpai22/lipi/internal/llm"
+	"github.com/mgpai22/lipi/internal/pool"
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+type ReadOptions struct {
+	BatchSize   int         // frames per request (default 20)
+	Concurrency int         // requests in flight (default 1)
+	Language    string      // language of the subtitles, when known
+	OnBatch     func(n int) // when set, called with the frame count of each batch read
+}
+
+// model answer
+type readResponse struct {
+	Frames []struct {
+		Frame int    `json:"frame"`
+		Text  string `json:"text"`
+	} `json:"frames"`
+}
+
+// Read asks vision for the subtitle text burned into each frame, a batch
+// of frames per request. texts[i] is the text of frames[i], empty when it
+// shows none.
+func Read(ctx context.Context, vision llm.Vision, frames []Frame, opts ReadOptions) ([]string, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 20
+	}
+	var batches [][]int // frame indexes
+	for start := 0; start < len(frames); start += opts.BatchSize {
+		batch := make([]int, 0, opts.BatchSize)
+		for i := start; i < min(start+opts.BatchSize, len(frames)); i++ {
+			batch = append(batch, i)
+		}
+		batches = append(batches, batch)
+	}
+
+	texts := make([]string, len(frames))
+	_, err := pool.Run(ctx, batches, opts.Concurrency, func(ctx context.Context, batch []int) (struct{}, error) {
+		images := make([]llm.Image, len(batch))
+		for i, index := range batch {
+			data, err := frames[index].data()
+			if err != nil {
+				return struct{}{}, err
+			}
+			images[i] = llm.Image{Data: data, MIMEType: "image/jpeg"}
+		}
+		answer, err := vision.GenerateVision(ctx, buildReadPrompt(len(batch), opts.Language), images)
+		if err != nil {
+			return struct{}{}, fmt.Errorf("failed to read frames at %s: %w", frames[batch[0]].At, err)
+		}
+		var resp readResponse
+		if err := llm.DecodeJSON(answer, &resp); err != nil {
+			return struct{}{}, fmt.Errorf("failed to read frames at %s: %w", frames[batch[0]].At, err)
+		}
+		// each batch writes only its own frames
+		for _, f := range resp.Frames {
+			if f.Frame >= 1 && f.Frame <= len(batch) {
+				texts[batch[f.Frame-1]] = cleanText(f.Text)
+			}
+		}
+		if opts.OnBatch != nil {
+			opts.OnBatch(len(batch))
+		}
+		return struct{}{}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return texts, nil
+}
+
+func buildReadPrompt(n int, language string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "These %d images are frames from a video, in order, numbered from 1. ", n)
+	sb.WriteString("Read the subtitle text burned into each frame exactly as shown, keeping its line breaks. ")
+	sb.WriteString("Ignore on-screen text that is not a subtitle, such as signs, logos, credits, and watermarks.\n")
+	if language != "" {
+		fmt.Fprintf(&sb, "The subtitles are in %s.\n", language)
+	}
+	sb.WriteString("\nRespond with only a JSON object in this format, with an empty text for frames without subtitles:\n")
+	sb.WriteString(`{"frames": [{"frame": 1, "text": "..."}]}`)
+	return sb.String()
+}
+
+// trims each line and drops blank ones
+func cleanText(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// readings of the same subtitle whose letters differ by at most this
+// fraction, as OCR of a line over several frames does
+const maxDifference = 0.25
+
+// Build turns the text read from consecutive frames into cues. A cue lasts
+// while the frames show the same text, allowing for small reading
+// differences and a single frame read as blank, and ends interval after
+// its last frame.
+func Build(frames []Frame, texts []string, interval time.Duration) *subtitle.Subtitle {
+	keys := make([]string, len(texts))
+	for i, text := range texts {
+		keys[i] = normalize(text)
+	}
+	// one missed frame inside a subtitle does not split it
+	for i := 1; i+1 < len(keys); i++ {
+		if keys[i] == "" && keys[i-1] != "" && similar(keys[i-1], keys[i+1]) {
+			keys[i] = keys[i-1]
+		}
+	}
+
+	sub := &subtitle.Subtitle{Format: string(subtitle.FormatSRT)}
+	for i := 0; i < len(frames); {
+		if keys[i] == "" {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(frames) && keys[j] != "" && similar(keys[i], keys[j]) {
+			j++
+		}
+		end := frames[j-1].At + interval
+		if j < len(frames) && end > frames[j].At {
+			end = frames[j].At
+		}
+		sub.Entries = append(sub.Entries, subtitle.Entry{
+			Index:     len(sub.Entries) + 1,
+			StartTime: frames[i].At,
+			EndTime:   end,
+			Text:      commonest(texts[i:j]),
+		})
+		i = j
+	}
+	return sub
+}
+
+// the letters and digits of text, lowercased, for comparing readings
+func normalize(text string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func similar(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	return float64(editDistance(ra, rb)) <= maxDifference*float64(longest)
+}
+
+// Levenshtein distance
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// the reading seen most often, the earliest of those on a tie
+func commonest(texts []string) string {
+	counts := make(map[string]int)
+	for _, text := range texts {
+		if text != "" {
+			counts[text]++
+		}
+	}
+	best := ""
+	for _, text := range texts {
+		if counts[text] > counts[best] {
+			best = text
+		}
+	}
+	return best
+}
@@ -0,0 +1,108 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/llm"
+)
+
+// fake vision model that reads each frame's file content as its subtitle
+type fileVision struct {
+	mu     sync.Mutex
+	calls  int
+	images int
+}
+
+func (v *fileVision) Generate(ctx context.Context, prompt string) (string, error) {
+	return "", fmt.Errorf("no images")
+}
+
+func (v *fileVision) GenerateVision(ctx context.Context, prompt string, images []llm.Image) (string, error) {
+	v.mu.Lock()
+	v.calls++
+	v.images += len(images)
+	v.mu.Unlock()
+	var parts []string
+	for i, image := range images {
+		parts = append(parts, fmt.Sprintf(`{"frame": %d, "text": %q}`, i+1, image.Data))
+	}
+	return `{"frames": [` + strings.Join(parts, ",") + `]}`, nil
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	var frames []Frame
+	for i, text := range []string{"", "Hello", " Hello \n\n there ", "", "Bye"} {
+		path := filepath.Join(dir, fmt.Sprintf("frame-%06d.jpg", i+1))
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, Frame{At: time.Duration(i) * time.Second, Path: path})
+	}
+
+	vision := &fileVision{}
+	texts, err := Read(context.Background(), vision, frames, ReadOptions{BatchSize: 2, Concurrency: 2})
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	want := []string{"", "Hello", "Hello\nthere", "", "Bye"}
+	if strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("Read() = %q, want %q", texts, want)
+	}
+	if vision.calls != 3 || vision.images != 5 {
+		t.Errorf("calls = %d with %d images, want 3 with 5", vision.calls, vision.images)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	texts := []string{
+		"", "Where are you going?", "Where are you goinq?", "", "Where are you going?",
+		"Home.", "", "", "I'II be back", "I'll be back", "I'll be back",
+	}
+	frames := make([]Frame, len(texts))
+	for i := range frames {
+		frames[i] = Frame{At: time.Duration(i) * time.Second}
+	}
+
+	sub := Build(frames, texts, time.Second)
+	type cue struct {
+		start, end int
+		text       string
+	}
+	want := []cue{
+		{1, 5, "Where are you going?"},
+		{5, 6, "Home."},
+		{8, 11, "I'll be back"},
+	}
+	if len(sub.Entries) != len(want) {
+		t.Fatalf("Build() = %+v, want %d cues", sub.Entries, len(want))
+	}
+	for i, w := range want {
+		e := sub.Entries[i]
+		if e.StartTime != time.Duration(w.start)*time.Second ||
+			e.EndTime != time.Duration(w.end)*time.Second || e.Text != w.text {
+			t.Errorf("cue %d = %v-%v %q, want %+v", i, e.StartTime, e.EndTime, e.Text, w)
+		}
+	}
+	if texts[3] != "" {
+		t.Error("Build() modified its input")
+	}
+}
+
+func TestSampleArgs(t *testing.T) {
+	args := strings.Join(sampleArgs("in.mkv", "out", SampleOptions{Interval: 500 * time.Millisecond}.withDefaults()), " ")
+	if !strings.Contains(args, "fps=2,crop=iw:trunc(ih*0.3/2)*2:0:ih-trunc(ih*0.3/2)*2,scale='min(960,iw)':-2") {
+		t.Errorf("sampleArgs() = %s", args)
+	}
+	args = strings.Join(sampleArgs("in.mkv", "out", SampleOptions{Region: 1}.withDefaults()), " ")
+	if strings.Contains(args, "crop") || !strings.Contains(args, "fps=1,") {
+		t.Errorf("sampleArgs() for the full frame = %s", args)
+	}
+}
@@ -0,0 +1,290 @@
+package opensubtitles
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/httpclient"
+)
+
+const apiURL = "https://api.opensubtitles.com/api/v1"
+
+// largest subtitle file downloaded
+const maxSubtitleBytes = 16 << 20
+
+// Client searches and downloads subtitles through the opensubtitles.com REST
+// API with a consumer API key
+type Client struct {
+	http      *http.Client
+	apiKey    string
+	userAgent string
+	token     string // set by Login
+
+	// endpoint, replaced in tests and by Login for VIP accounts
+	apiURL string
+}
+
+// New returns a client for apiKey. The API requires a user agent naming the
+// application, e.g. "lipi v1.2.0". A nil httpClient uses the default client.
+func New(httpClient *http.Client, apiKey, userAgent string) *Client {
+	return &Client{
+		http:      httpclient.Or(httpClient),
+		apiKey:    apiKey,
+		userAgent: userAgent,
+		apiURL:    apiURL,
+	}
+}
+
+// Subtitle is a search result
+type Subtitle struct {
+	ID                string
+	FileID            int64
+	FileName          string
+	Language          string
+	Release           string
+	Title             string
+	Year              int
+	IMDbID            int64
+	Downloads         int
+	HashMatch         bool // found by the file's hash, so timed for this release
+	Trusted           bool
+	HearingImpaired   bool
+	MachineTranslated bool
+}
+
+// Query selects subtitles by file hash and, as a fallback, by name
+type Query struct {
+	Hash      string
+	Name      string   // release or file name without extension
+	Languages []string // ISO 639-1 codes, or regional ones like pt-BR
+}
+
+type searchResponse struct {
+	Data []struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Language          string `json:"language"`
+			Release           string `json:"release"`
+			DownloadCount     int    `json:"download_count"`
+			HearingImpaired   bool   `json:"hearing_impaired"`
+			MachineTranslated bool   `json:"machine_translated"`
+			AITranslated      bool   `json:"ai_translated"`
+			FromTrusted       bool   `json:"from_trusted"`
+			MovieHashMatch    bool   `json:"moviehash_match"`
+			FeatureDetails    struct {
+				Title  string `json:"title"`
+				Year   int    `json:"year"`
+				IMDbID int64  `json:"imdb_id"`
+			} `json:"feature_details"`
+			Files []struct {
+				FileID   int64  `json:"file_id"`
+				FileName string `json:"file_name"`
+			} `json:"files"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// Search returns matching subtitles, best first: hash matches, then human
+// translations, then trusted uploaders, then the most downloaded
+func (c *Client) Search(ctx context.Context, q Query) ([]Subtitle, error) {
+	// the API redirects requests whose parameters are not sorted and
+	// lowercased; Encode sorts them by key
+	params := url.Values{}
+	if len(q.Languages) > 0 {
+		langs := make([]string, len(q.Languages))
+		for i, lang := range q.Languages {
+			langs[i] = strings.ToLower(strings.TrimSpace(lang))
+		}
+		sort.Strings(langs)
+		params.Set("languages", strings.Join(langs, ","))
+	}
+	if q.Hash != "" {
+		params.Set("moviehash", strings.ToLower(q.Hash))
+	}
+	if q.Name != "" {
+		params.Set("query", strings.ToLower(q.Name))
+	}
+
+	query := strings.ReplaceAll(params.Encode(), "%2C", ",")
+	req, err := c.newRequest(ctx, http.MethodGet, "/subtitles?"+query, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp searchResponse
+	if err := c.do(req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to search OpenSubtitles: %w", err)
+	}
+
+	var results []Subtitle
+	for _, item := range resp.Data {
+		a := item.Attributes
+		if len(a.Files) == 0 {
+			continue
+		}
+		results = append(results, Subtitle{
+			ID:                item.ID,
+			FileID:            a.Files[0].FileID,
+			FileName:          a.Files[0].FileName,
+			Language:          a.Language,
+			Release:           a.Release,
+			Title:             a.FeatureDetails.Title,
+			Year:              a.FeatureDetails.Year,
+			IMDbID:            a.FeatureDetails.IMDbID,
+			Downloads:         a.DownloadCount,
+			HashMatch:         a.MovieHashMatch,
+			Trusted:           a.FromTrusted,
+			HearingImpaired:   a.HearingImpaired,
+			MachineTranslated: a.MachineTranslated || a.AITranslated,
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch {
+		case a.HashMatch != b.HashMatch:
+			return a.HashMatch
+		case a.MachineTranslated != b.MachineTranslated:
+			return !a.MachineTranslated
+		case a.Trusted != b.Trusted:
+			return a.Trusted
+		}
+		return a.Downloads > b.Downloads
+	})
+	return results, nil
+}
+
+// Login signs in to an opensubtitles.com account, which raises the daily
+// download quota over the anonymous one
+func (c *Client) Login(ctx context.Context, username, password string) error {
+	body, err := json.Marshal(map[string]string{"username": username, "password": password})
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, "/login", body)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Token   string `json:"token"`
+		BaseURL string `json:"base_url"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		return fmt.Errorf("failed to sign in to OpenSubtitles: %w", err)
+	}
+	c.token = resp.Token
+	// VIP accounts are served from their own host
+	if resp.BaseURL != "" && c.apiURL == apiURL {
+		c.apiURL = "https://" + strings.TrimPrefix(resp.BaseURL, "https://") + "/api/v1"
+	}
+	return nil
+}
+
+// Download fetches the subtitle file fileID as SRT. remaining is the number
+// of downloads left in the current quota window.
+func (c *Client) Download(ctx context.Context, fileID int64) (data []byte, remaining int, err error) {
+	body, err := json.Marshal(map[string]any{"file_id": fileID, "sub_format": "srt"})
+	if err != nil {
+		return nil, 0, err
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, "/download", body)
+	if err != nil {
+		return nil, 0, err
+	}
+	var resp struct {
+		Link      string `json:"link"`
+		Remaining int    `json:"remaining"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		return nil, 0, fmt.Errorf("failed to download subtitles: %w", err)
+	}
+	if resp.Link == "" {
+		return nil, 0, errors.New("failed to download subtitles: no download link returned")
+	}
+
+	fileReq, err := http.NewRequestWithContext(ctx, http.MethodGet, resp.Link, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to download subtitles: %w", err)
+	}
+	fileResp, err := c.http.Do(fileReq)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to download subtitles: %w", err)
+	}
+	defer func() { _ = fileResp.Body.Close() }()
+	if fileResp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("failed to download subtitles: unexpected status %s", fileResp.Status)
+	}
+	data, err = io.ReadAll(io.LimitReader(fileResp.Body, maxSubtitleBytes))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to download subtitles: %w", err)
+	}
+	return data, resp.Remaining, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.apiURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Api-Key", c.apiKey)
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// error body of the REST API
+type apiError struct {
+	Message string   `json:"message"`
+	Errors  []string `json:"errors"`
+}
+
+// sends req and decodes a JSON response into out, mapping failures to
+// error kinds
+func (c *Client) do(req *http.Request, out any) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return json.Unmarshal(body, out)
+	}
+
+	message := resp.Status
+	var apiErr apiError
+	if json.Unmarshal(body, &apiErr) == nil {
+		if apiErr.Message != "" {
+			message = fmt.Sprintf("%s: %s", resp.Status, apiErr.Message)
+		} else if len(apiErr.Errors) > 0 {
+			message = fmt.Sprintf("%s: %s", resp.Status, strings.Join(apiErr.Errors, "; "))
+		}
+	}
+	err = errors.New(message)
+	switch resp.StatusCode {
+	// 406 is the answer once the daily download quota is used up
+	case http.StatusTooManyRequests, http.StatusNotAcceptable:
+		return errs.Wrap(errs.KindRateLimit, err)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errs.Wrap(errs.KindAuth, err)
+	case http.StatusBadRequest, http.StatusNotFound:
+		return errs.Wrap(errs.KindInput, err)
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+package opensubtitles
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+// bytes read from each end of the file
+const hashChunkSize = 64 << 10
+
+// FileHash is the OpenSubtitles hash of a media file, which identifies a
+// release regardless of its name
+type FileHash struct {
+	Hash string // 16 hex digits
+	Size int64
+}
+
+// Hash computes the OpenSubtitles hash of path: the file size plus the sum
+// of the first and last 64 KiB read as little-endian 64-bit words
+func Hash(path string) (FileHash, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return FileHash{}, errs.Wrap(errs.KindInput, fmt.Errorf("media file not found: %s", path))
+		}
+		return FileHash{}, fmt.Errorf("failed to open media file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return FileHash{}, fmt.Errorf("failed to read media file: %w", err)
+	}
+	size := info.Size()
+	if size < hashChunkSize {
+		return FileHash{}, errs.Wrap(errs.KindInput, fmt.Errorf(
+			"media file is too small to hash: %d bytes, need at least %d", size, hashChunkSize,
+		))
+	}
+
+	sum := uint64(size)
+	buf := make([]byte, hashChunkSize)
+	for _, offset := range []int64{0, size - hashChunkSize} {
+		if _, err := f.ReadAt(buf, offset); err != nil && err != io.EOF {
+			return FileHash{}, fmt.Errorf("failed to read media file: %w", err)
+		}
+		for i := 0; i < len(buf); i += 8 {
+			sum += binary.LittleEndian.Uint64(buf[i:])
+		}
+	}
+	return FileHash{Hash: fmt.Sprintf("%016x", sum), Size: size}, nil
+}
@@ -0,0 +1,201 @@
+package opensubtitles
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+func TestHash(t *testing.T) {
+	data := make([]byte, 3*hashChunkSize)
+	// one word in the first chunk, one in the last, and one in the middle
+	// that the hash skips
+	data[0] = 1
+	data[len(data)-8] = 2
+	data[hashChunkSize+8] = 0xff
+	path := filepath.Join(t.TempDir(), "movie.mkv")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Hash(path)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	// 3*65536 + 1 + 2
+	if got.Hash != "0000000000030003" || got.Size != int64(len(data)) {
+		t.Errorf("Hash() = %+v", got)
+	}
+
+	small := filepath.Join(t.TempDir(), "small.mkv")
+	if err := os.WriteFile(small, []byte("tiny"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Hash(small); errs.KindOf(err) != errs.KindInput {
+		t.Errorf("Hash(small) error = %v, want input error", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Api-Key") != "key" || r.Header.Get("User-Agent") != "lipi test" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		if got := r.URL.RawQuery; got != "languages=en,pt-br&moviehash=8e245d9679d31e12" {
+			t.Errorf("query = %q", got)
+		}
+		_, _ = io.WriteString(w, `{"data":[
+			{"id":"1","attributes":{"language":"en","download_count":900,"files":[{"file_id":11,"file_name":"popular.srt"}]}},
+			{"id":"2","attributes":{"language":"en","download_count":5,"moviehash_match":true,"ai_translated":true,"files":[{"file_id":22}]}},
+			{"id":"3","attributes":{"language":"en","download_count":10,"moviehash_match":true,"files":[{"file_id":33}]}},
+			{"id":"4","attributes":{"language":"en","files":[]}}
+		]}`)
+	}))
+	defer server.Close()
+	client := New(server.Client(), "key", "lipi test")
+	client.apiURL = server.URL
+
+	results, err := client.Search(context.Background(), Query{
+		Hash:      "8E245D9679D31E12",
+		Languages: []string{"pt-BR", "en"},
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	if strings.Join(ids, ",") != "3,2,1" {
+		t.Errorf("Search() order = %v, want 3,2,1", ids)
+	}
+}
+
+func TestDownloadQuota(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotAcceptable)
+		_, _ = io.WriteString(w, `{"message":"You have downloaded your allowed 5 subtitles for 24h"}`)
+	}))
+	defer server.Close()
+	client := New(server.Client(), "key", "lipi test")
+	client.apiURL = server.URL
+
+	_, _, err := client.Download(context.Background(), 11)
+	if errs.KindOf(err) != errs.KindRateLimit || !strings.Contains(err.Error(), "allowed 5") {
+		t.Errorf("Download() error = %v, want rate limit", err)
+	}
+}
+
+// XML-RPC answers by method name
+func rpcServer(t *testing.T, answers map[string]string, calls *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var call struct {
+			Method string `xml:"methodName"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&call); err != nil {
+			t.Errorf("request is not XML-RPC: %v", err)
+		}
+		*calls = append(*calls, call.Method)
+		_, _ = io.WriteString(w, `<?xml version="1.0"?><methodResponse><params><param><value>`+
+			answers[call.Method]+`</value></param></params></methodResponse>`)
+	}))
+}
+
+func member(name, value string) string {
+	return "<member><name>" + name + "</name><value>" + value + "</value></member>"
+}
+
+func TestUpload(t *testing.T) {
+	ok := member("status", "<string>200 OK</string>")
+	tests := []struct {
+		name      string
+		try       string
+		imdbID    string
+		wantCalls string
+		want      UploadResult
+		wantKind  errs.Kind
+	}{
+		{
+			name:      "new subtitles for a known release",
+			try:       ok + member("alreadyindb", "<int>0</int>") + member("data", "<array><data><value><struct>"+member("IDMovieImdb", "<string>133093</string>")+"</struct></value></data></array>"),
+			wantCalls: "LogIn,TryUploadSubtitles,UploadSubtitles,LogOut",
+			want:      UploadResult{URL: "https://www.opensubtitles.org/subtitles/1"},
+		},
+		{
+			name:      "duplicate",
+			try:       ok + member("alreadyindb", "<int>1</int>"),
+			wantCalls: "LogIn,TryUploadSubtitles,LogOut",
+			want:      UploadResult{Duplicate: true},
+		},
+		{
+			name:      "unknown release without an IMDb ID",
+			try:       ok + member("alreadyindb", "<int>0</int>"),
+			wantCalls: "LogIn,TryUploadSubtitles,LogOut",
+			wantKind:  errs.KindInput,
+		},
+		{
+			name:      "unknown release with an IMDb ID",
+			try:       ok + member("alreadyindb", "<int>0</int>"),
+			imdbID:    "tt0133093",
+			wantCalls: "LogIn,TryUploadSubtitles,UploadSubtitles,LogOut",
+			want:      UploadResult{URL: "https://www.opensubtitles.org/subtitles/1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			server := rpcServer(t, map[string]string{
+				"LogIn":              "<struct>" + ok + member("token", "<string>tok</string>") + "</struct>",
+				"TryUploadSubtitles": "<struct>" + tt.try + "</struct>",
+				"UploadSubtitles":    "<struct>" + ok + member("data", "https://www.opensubtitles.org/subtitles/1") + "</struct>",
+				"LogOut":             "<struct>" + ok + "</struct>",
+			}, &calls)
+			defer server.Close()
+			uploader := NewUploader(server.Client(), "lipi test")
+			uploader.rpcURL = server.URL
+
+			got, err := uploader.Upload(context.Background(), "user", "pass", Contribution{
+				Media:    FileHash{Hash: "8e245d9679d31e12", Size: 1 << 30},
+				FileName: "movie.en.srt",
+				Data:     []byte("1\n00:00:01,000 --> 00:00:02,000\nHi\n"),
+				Language: "eng",
+				IMDbID:   tt.imdbID,
+			})
+			if strings.Join(calls, ",") != tt.wantCalls {
+				t.Errorf("calls = %v, want %s", calls, tt.wantCalls)
+			}
+			if tt.wantKind != errs.KindUnknown {
+				if errs.KindOf(err) != tt.wantKind {
+					t.Errorf("Upload() error = %v, want kind %s", err, tt.wantKind)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Upload() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadBadLogin(t *testing.T) {
+	var calls []string
+	server := rpcServer(t, map[string]string{
+		"LogIn": "<struct>" + member("status", "<string>401 Unauthorized</string>") + "</struct>",
+	}, &calls)
+	defer server.Close()
+	uploader := NewUploader(server.Client(), "lipi test")
+	uploader.rpcURL = server.URL
+
+	_, err := uploader.Upload(context.Background(), "user", "wrong", Contribution{})
+	if errs.KindOf(err) != errs.KindAuth {
+		t.Errorf("Upload() error = %v, want auth error", err)
+	}
+}
@@ -0,0 +1,206 @@
+package opensubtitles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/httpclient"
+)
+
+// the REST API has no upload endpoint; uploads still go through the
+// opensubtitles.org XML-RPC API
+const rpcURL = "https://api.opensubtitles.org/xml-rpc"
+
+// Uploader adds subtitles to opensubtitles.org with an account's username
+// and password
+type Uploader struct {
+	http      *http.Client
+	userAgent string
+
+	// endpoint, replaced in tests
+	rpcURL string
+}
+
+// NewUploader returns an uploader; userAgent must be one registered with
+// OpenSubtitles. A nil httpClient uses the default client.
+func NewUploader(httpClient *http.Client, userAgent string) *Uploader {
+	return &Uploader{
+		http:      httpclient.Or(httpClient),
+		userAgent: userAgent,
+		rpcURL:    rpcURL,
+	}
+}
+
+// Contribution is a subtitle file for a release identified by its hash
+type Contribution struct {
+	Media       FileHash
+	MediaName   string // file name of the release
+	FileName    string // file name of the subtitles
+	Data        []byte
+	Language    string // ISO 639-2/B, e.g. "eng" or "ger"
+	IMDbID      string // required unless OpenSubtitles knows the hash
+	Release     string
+	Comment     string
+	MachineMade bool // the text is a machine transcription or translation
+}
+
+// UploadResult says where the subtitles ended up
+type UploadResult struct {
+	URL       string
+	Duplicate bool // OpenSubtitles already had this exact file
+}
+
+// Upload signs in, checks that the subtitles are new, and uploads them
+func (u *Uploader) Upload(ctx context.Context, username, password string, c Contribution) (UploadResult, error) {
+	login, err := u.call(ctx, "LogIn", username, password, "en", u.userAgent)
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("failed to sign in to OpenSubtitles: %w", err)
+	}
+	token, _ := login["token"].(string)
+	defer func() {
+		_, _ = u.call(context.WithoutCancel(ctx), "LogOut", token)
+	}()
+
+	sum := md5.Sum(c.Data)
+	cd := map[string]any{
+		"subhash":       hex.EncodeToString(sum[:]),
+		"subfilename":   c.FileName,
+		"moviehash":     c.Media.Hash,
+		"moviebytesize": strconv.FormatInt(c.Media.Size, 10),
+		"moviefilename": c.MediaName,
+	}
+	try, err := u.call(ctx, "TryUploadSubtitles", token, map[string]any{"cd1": cd})
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("failed to check for existing subtitles: %w", err)
+	}
+	if number(try["alreadyindb"]) == 1 {
+		return UploadResult{Duplicate: true}, nil
+	}
+
+	imdbID := strings.TrimPrefix(strings.ToLower(c.IMDbID), "tt")
+	if imdbID == "" {
+		imdbID = knownIMDbID(try["data"])
+	}
+	if imdbID == "" {
+		return UploadResult{}, errs.Wrap(errs.KindInput, errors.New(
+			"OpenSubtitles does not know this release: give its IMDb ID with --imdb-id",
+		))
+	}
+
+	content, err := gzipBase64(c.Data)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	cd["subcontent"] = content
+	baseinfo := map[string]any{
+		"idmovieimdb":      imdbID,
+		"sublanguageid":    c.Language,
+		"moviereleasename": c.Release,
+		"subauthorcomment": c.Comment,
+	}
+	if c.MachineMade {
+		baseinfo["automatictranslation"] = "1"
+	}
+	uploaded, err := u.call(ctx, "UploadSubtitles", token, map[string]any{
+		"baseinfo": baseinfo,
+		"cd1":      cd,
+	})
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("failed to upload subtitles: %w", err)
+	}
+	url, _ := uploaded["data"].(string)
+	return UploadResult{URL: url}, nil
+}
+
+// calls method and returns its struct result, failing on a status other
+// than "200 OK"
+func (u *Uploader) call(ctx context.Context, method string, params ...any) (map[string]any, error) {
+	body, err := encodeCall(method, params...)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	req.Header.Set("User-Agent", u.userAgent)
+	resp, err := u.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	value, err := decodeResponse(data)
+	if err != nil {
+		return nil, err
+	}
+	result, _ := value.(map[string]any)
+	status, _ := result["status"].(string)
+	if !strings.HasPrefix(status, "200") {
+		err := fmt.Errorf("OpenSubtitles returned %q", status)
+		switch {
+		case strings.HasPrefix(status, "401"), strings.HasPrefix(status, "411"), strings.HasPrefix(status, "414"):
+			return nil, errs.Wrap(errs.KindAuth, err)
+		case strings.HasPrefix(status, "429"):
+			return nil, errs.Wrap(errs.KindRateLimit, err)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// the IMDb ID TryUploadSubtitles reports for a known hash, if any
+func knownIMDbID(data any) string {
+	matches, _ := data.([]any)
+	for _, match := range matches {
+		if m, ok := match.(map[string]any); ok {
+			if id, _ := m["IDMovieImdb"].(string); id != "" {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// XML-RPC flags arrive as ints or numeric strings
+func number(v any) int {
+	switch v := v.(type) {
+	case int:
+		return v
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
+
+func gzipBase64(data []byte) (string, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,145 @@
+package opensubtitles
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the subset of XML-RPC the opensubtitles.org upload API uses
+
+func encodeCall(method string, params ...any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString("<methodCall><methodName>")
+	if err := xml.EscapeText(&buf, []byte(method)); err != nil {
+		return nil, err
+	}
+	buf.WriteString("</methodName><params>")
+	for _, param := range params {
+		buf.WriteString("<param>")
+		if err := encodeValue(&buf, param); err != nil {
+			return nil, err
+		}
+		buf.WriteString("</param>")
+	}
+	buf.WriteString("</params></methodCall>")
+	return buf.Bytes(), nil
+}
+
+func encodeValue(buf *bytes.Buffer, v any) error {
+	buf.WriteString("<value>")
+	switch v := v.(type) {
+	case string:
+		buf.WriteString("<string>")
+		if err := xml.EscapeText(buf, []byte(v)); err != nil {
+			return err
+		}
+		buf.WriteString("</string>")
+	case int:
+		fmt.Fprintf(buf, "<int>%d</int>", v)
+	case bool:
+		b := 0
+		if v {
+			b = 1
+		}
+		fmt.Fprintf(buf, "<boolean>%d</boolean>", b)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteString("<struct>")
+		for _, k := range keys {
+			buf.WriteString("<member><name>")
+			if err := xml.EscapeText(buf, []byte(k)); err != nil {
+				return err
+			}
+			buf.WriteString("</name>")
+			if err := encodeValue(buf, v[k]); err != nil {
+				return err
+			}
+			buf.WriteString("</member>")
+		}
+		buf.WriteString("</struct>")
+	default:
+		return fmt.Errorf("unsupported XML-RPC value %T", v)
+	}
+	buf.WriteString("</value>")
+	return nil
+}
+
+type rpcValue struct {
+	Text    string      `xml:",chardata"`
+	String  *string     `xml:"string"`
+	Int     *string     `xml:"int"`
+	I4      *string     `xml:"i4"`
+	Boolean *string     `xml:"boolean"`
+	Double  *string     `xml:"double"`
+	Struct  *[]rpcField `xml:"struct>member"`
+	Array   *[]rpcValue `xml:"array>data>value"`
+}
+
+type rpcField struct {
+	Name  string   `xml:"name"`
+	Value rpcValue `xml:"value"`
+}
+
+type rpcResponse struct {
+	Params []rpcValue `xml:"params>param>value"`
+	Fault  *rpcValue  `xml:"fault>value"`
+}
+
+// decodes a methodResponse into its first parameter: structs become
+// map[string]any, arrays []any, and scalars string, int, bool, or float64
+func decodeResponse(data []byte) (any, error) {
+	var resp rpcResponse
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("invalid XML-RPC response: %w", err)
+	}
+	if resp.Fault != nil {
+		fault, _ := resp.Fault.decode().(map[string]any)
+		return nil, fmt.Errorf("XML-RPC fault: %v", fault["faultString"])
+	}
+	if len(resp.Params) == 0 {
+		return nil, nil
+	}
+	return resp.Params[0].decode(), nil
+}
+
+func (v rpcValue) decode() any {
+	switch {
+	case v.String != nil:
+		return *v.String
+	case v.Int != nil, v.I4 != nil:
+		s := v.Int
+		if s == nil {
+			s = v.I4
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(*s))
+		return n
+	case v.Boolean != nil:
+		return strings.TrimSpace(*v.Boolean) == "1"
+	case v.Double != nil:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(*v.Double), 64)
+		return f
+	case v.Struct != nil:
+		m := make(map[string]any, len(*v.Struct))
+		for _, field := range *v.Struct {
+			m[field.Name] = field.Value.decode()
+		}
+		return m
+	case v.Array != nil:
+		items := make([]any, len(*v.Array))
+		for i, item := range *v.Array {
+			items[i] = item.decode()
+		}
+		return items
+	}
+	// a value without a type element is a string
+	return v.Text
+}
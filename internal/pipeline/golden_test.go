@@ -0,0 +1,65 @@
+package pipeline
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/transcribe"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+// compares got with testdata/golden/name, rewriting it with -update
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s differs from golden file\n--- got ---\n%s\n--- want ---\n%s", name, got, want)
+	}
+}
+
+// runs the mock transcript through the default generator and each writer
+func TestGoldenOutputs(t *testing.T) {
+	mock, err := transcribe.NewMockTranscriber(filepath.Join("testdata", "transcript.json"), transcribe.Options{})
+	if err != nil {
+		t.Fatalf("NewMockTranscriber() error = %v", err)
+	}
+
+	for _, format := range []subtitle.Format{subtitle.FormatSRT, subtitle.FormatVTT, subtitle.FormatASS} {
+		t.Run(string(format), func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "out."+string(format))
+			p := &Pipeline{
+				Transcribe: NewStage(StageTranscribe, func(ctx context.Context, s *State) error {
+					result, err := mock.Transcribe(ctx, s.AudioPath)
+					s.Transcript = result
+					return err
+				}),
+				Generate: GenerateStage{Language: "en", Format: format},
+				Write:    WriteStage{Format: format},
+			}
+			if err := p.Run(context.Background(), &State{AudioPath: "audio.wav", OutputPath: output}); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			got, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatalf("failed to read output: %v", err)
+			}
+			assertGolden(t, "transcript."+string(format), got)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/progress"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/transcribe"
+)
+
+// stage names passed to hooks
+const (
+	StageExtract    = "extract"
+	StageChunk      = "chunk"
+	StageTranscribe = "transcribe"
+	StageGenerate   = "generate"
+	StageWrite      = "write"
+)
+
+// data handed from stage to stage during one run. Callers fill in the
+// input fields; each stage sets the fields it produces.
+type State struct {
+	// inputs
+	MediaPath  string // local audio or video file
+	WorkDir    string // scratch directory for audio and chunks
+	OutputPath string // where WriteStage puts the subtitles
+	// MediaReader, when set, is read instead of MediaPath, so streamed media
+	// such as uploads never has to be staged on disk
+	MediaReader io.Reader
+
+	// where stages report; Log defaults to a discarding logger and a nil
+	// Progress draws nothing
+	Log      *logging.Logger
+	Progress *progress.Display
+
+	AudioPath   string        // set by ExtractStage
+	Offset      time.Duration // where AudioPath starts in the media, set by ExtractStage
+	Duration    time.Duration // set by ChunkStage
+	Chunks      int           // set by ChunkStage
+	Concurrency int           // set by ChunkStage
+	Transcript  *transcribe.Result
+	Subtitle    *subtitle.Subtitle
+}
+
+// one step of a run
+type Stage interface {
+	Name() string
+	Run(ctx context.Context, s *State) error
+}
+
+type stageFunc struct {
+	name string
+	run  func(ctx context.Context, s *State) error
+}
+
+func (f stageFunc) Name() string { return f.name }
+
+func (f stageFunc) Run(ctx context.Context, s *State) error { return f.run(ctx, s) }
+
+// NewStage returns a stage that calls run, for replacing a default stage
+// without declaring a type
+func NewStage(name string, run func(ctx context.Context, s *State) error) Stage {
+	return stageFunc{name: name, run: run}
+}
+
+// called with the stage name before or after a stage runs; an error stops
+// the run
+type Hook func(ctx context.Context, stage string, s *State) error
+
+// Pipeline turns a media file into a subtitle file. Any stage may be
+// replaced; a nil stage is skipped.
+type Pipeline struct {
+	Extract    Stage
+	Chunk      Stage
+	Transcribe Stage
+	Generate   Stage
+	Write      Stage
+
+	Before []Hook
+	After  []Hook
+}
+
+// runs the stages in order, calling the hooks around each one
+func (p *Pipeline) Run(ctx context.Context, s *State) error {
+	if s.Log == nil {
+		s.Log = logging.NewLogger(false, io.Discard)
+	}
+
+	for _, stage := range []Stage{p.Extract, p.Chunk, p.Transcribe, p.Generate, p.Write} {
+		if stage == nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := runHooks(ctx, p.Before, stage.Name(), s); err != nil {
+			return err
+		}
+		if err := stage.Run(ctx, s); err != nil {
+			return err
+		}
+		if err := runHooks(ctx, p.After, stage.Name(), s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown releases what the stages hold, such as the transcriber of the
+// transcribe stage, by calling Shutdown on each stage that implements
+// transcribe.Shutdowner. Every such stage is shut down even when one fails.
+func (p *Pipeline) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, stage := range []Stage{p.Extract, p.Chunk, p.Transcribe, p.Generate, p.Write} {
+		if s, ok := stage.(transcribe.Shutdowner); ok {
+			if err := s.Shutdown(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func runHooks(ctx context.Context, hooks []Hook, stage string, s *State) error {
+	for _, hook := range hooks {
+		if err := hook(ctx, stage, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/transcribe"
+)
+
+func TestPipelineRunOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) Stage {
+		return NewStage(name, func(ctx context.Context, s *State) error {
+			calls = append(calls, name)
+			return nil
+		})
+	}
+	hook := func(when string) Hook {
+		return func(ctx context.Context, stage string, s *State) error {
+			calls = append(calls, when+" "+stage)
+			return nil
+		}
+	}
+
+	p := &Pipeline{
+		Extract:  record(StageExtract),
+		Generate: record(StageGenerate),
+		Before:   []Hook{hook("before")},
+		After:    []Hook{hook("after")},
+	}
+	if err := p.Run(context.Background(), &State{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := []string{
+		"before extract", "extract", "after extract",
+		"before generate", "generate", "after generate",
+	}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestPipelineRunStopsOnError(t *testing.T) {
+	boom := errors.New("boom")
+	ran := false
+
+	tests := []struct {
+		name string
+		p    *Pipeline
+	}{
+		{
+			name: "stage",
+			p: &Pipeline{
+				Extract: NewStage(StageExtract, func(ctx context.Context, s *State) error {
+					return boom
+				}),
+			},
+		},
+		{
+			name: "before hook",
+			p: &Pipeline{
+				Extract: NewStage(StageExtract, func(ctx context.Context, s *State) error {
+					ran = true
+					return nil
+				}),
+				Before: []Hook{func(ctx context.Context, stage string, s *State) error {
+					return boom
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran = false
+			tt.p.Write = NewStage(StageWrite, func(ctx context.Context, s *State) error {
+				t.Error("write stage ran after a failure")
+				return nil
+			})
+			if err := tt.p.Run(context.Background(), &State{}); !errors.Is(err, boom) {
+				t.Errorf("Run() error = %v, want boom", err)
+			}
+			if ran {
+				t.Error("stage ran after its before hook failed")
+			}
+		})
+	}
+}
+
+// transcriber that records being shut down
+type closingTranscriber struct {
+	shutdown bool
+}
+
+func (c *closingTranscriber) Transcribe(context.Context, string) (*transcribe.Result, error) {
+	return &transcribe.Result{}, nil
+}
+
+func (c *closingTranscriber) Shutdown(context.Context) error {
+	c.shutdown = true
+	return nil
+}
+
+func TestPipelineShutdownReleasesTranscriber(t *testing.T) {
+	transcriber := &closingTranscriber{}
+	p := &Pipeline{
+		Extract:    NewStage(StageExtract, func(context.Context, *State) error { return nil }),
+		Transcribe: TranscribeStage{Transcriber: transcriber},
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if !transcriber.shutdown {
+		t.Error("expected the transcriber to be shut down")
+	}
+}
+
+func TestChunkStageConcurrency(t *testing.T) {
+	tests := []struct {
+		name  string
+		stage ChunkStage
+		want  int
+	}{
+		{name: "auto", stage: ChunkStage{}, want: 10},
+		{name: "auto capped", stage: ChunkStage{MaxConcurrency: 4}, want: 4},
+		{name: "explicit", stage: ChunkStage{Concurrency: 3}, want: 3},
+		{name: "more than chunks", stage: ChunkStage{Concurrency: 20}, want: 10},
+	}
+
+	s := &State{}
+	if err := (&Pipeline{}).Run(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stage.concurrency(10, s); got != tt.want {
+				t.Errorf("concurrency(10) = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateAndWriteStages(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "out.srt")
+	s := &State{
+		OutputPath: output,
+		Transcript: &transcribe.Result{Segments: []subtitle.Segment{
+			{StartTime: 0, EndTime: 2 * time.Second, Text: "Hello there"},
+		}},
+	}
+	p := &Pipeline{
+		Generate: GenerateStage{Language: "en", Format: subtitle.FormatSRT},
+		Write:    WriteStage{Format: subtitle.FormatSRT},
+	}
+	if err := p.Run(context.Background(), s); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(s.Subtitle.Entries) != 1 || s.Subtitle.Language != "en" {
+		t.Errorf("Subtitle = %+v, want one English entry", s.Subtitle)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if !strings.Contains(string(data), "Hello there") {
+		t.Errorf("output = %q, want the transcript text", data)
+	}
+}
+
+func TestGenerateStageOffset(t *testing.T) {
+	s := &State{
+		Offset: 10 * time.Minute,
+		Transcript: &transcribe.Result{Segments: []subtitle.Segment{
+			{StartTime: time.Second, EndTime: 3 * time.Second, Text: "From the sample"},
+		}},
+	}
+	if err := (GenerateStage{Format: subtitle.FormatSRT}).Run(context.Background(), s); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if e := s.Subtitle.Entries[0]; e.StartTime != 10*time.Minute+time.Second {
+		t.Errorf("entry starts at %s, want at its place in the media", e.StartTime)
+	}
+	if s.Transcript.Segments[0].StartTime != time.Second {
+		t.Error("the transcript was changed")
+	}
+}
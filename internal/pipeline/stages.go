@@ -0,0 +1,355 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/transcribe"
+	"github.com/mgpai22/lipi/internal/video"
+)
+
+// extracts the audio track of a video, or recompresses an audio file, into
+// the format sent to the provider, optionally keeping only the vocals
+type ExtractStage struct {
+	Format       string // audio.FormatMP3, audio.FormatOpus, ...
+	IsolateVoice bool
+	Separator    string
+	// binary of the separator, see audio.IsolationOptions.Executable
+	SeparatorPath string
+	// decode damaged media past its errors, see ffmpeg.TolerantInputArgs
+	IgnoreDecodeErrors bool
+	// excerpt of the media to transcribe: from Start, for Length when
+	// set; the whole media when both are zero
+	Start, Length time.Duration
+}
+
+func (ExtractStage) Name() string { return StageExtract }
+
+func (e ExtractStage) Run(ctx context.Context, s *State) error {
+	compressionOpts := audio.DefaultCompressionOptions()
+	if e.Format != "" {
+		compressionOpts.Format = e.Format
+	}
+	compressionOpts.IgnoreDecodeErrors = e.IgnoreDecodeErrors
+	compressionOpts.Start = e.Start
+	compressionOpts.Length = e.Length
+	audioExt := audio.ExtensionForFormat(compressionOpts.Format)
+	audioPath := filepath.Join(s.WorkDir, "audio"+audioExt)
+
+	if s.MediaReader != nil {
+		s.Log.Infow("Compressing streamed media for transcription")
+		s.Progress.Stage("Compressing audio", 0)
+
+		if err := audio.CompressReader(
+			ctx,
+			s.MediaReader,
+			audioPath,
+			compressionOpts,
+		); err != nil {
+			return fmt.Errorf("failed to compress audio: %w", err)
+		}
+	} else if audio.IsVideoFile(s.MediaPath) {
+		s.Log.Infow("Extracting audio from video")
+		s.Progress.Stage("Extracting audio", 0)
+
+		processor := video.NewProcessor(s.WorkDir)
+		extractOpts := video.ExtractAudioOptions{
+			Format:     compressionOpts.Format,
+			SampleRate: compressionOpts.SampleRate,
+			Channels:   compressionOpts.Channels,
+			Bitrate:    compressionOpts.Bitrate,
+
+			IgnoreDecodeErrors: compressionOpts.IgnoreDecodeErrors,
+			Start:              compressionOpts.Start,
+			Length:             compressionOpts.Length,
+		}
+		if err := processor.ExtractAudio(
+			ctx,
+			s.MediaPath,
+			audioPath,
+			extractOpts,
+		); err != nil {
+			return fmt.Errorf("failed to extract audio: %w", err)
+		}
+	} else {
+		s.Log.Infow("Compressing audio for transcription")
+		s.Progress.Stage("Compressing audio", 0)
+
+		if err := audio.CompressAudio(
+			ctx,
+			s.MediaPath,
+			audioPath,
+			compressionOpts,
+		); err != nil {
+			return fmt.Errorf("failed to compress audio: %w", err)
+		}
+	}
+
+	if e.IsolateVoice {
+		s.Log.Infow("Isolating voice",
+			"separator", e.Separator,
+		)
+		s.Progress.Stage("Isolating voice", 0)
+
+		vocalsPath, err := audio.IsolateVoice(
+			ctx,
+			audioPath,
+			filepath.Join(s.WorkDir, "stems"),
+			audio.IsolationOptions{Separator: e.Separator, Executable: e.SeparatorPath},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to isolate voice: %w", err)
+		}
+
+		// the stem is of the excerpt already
+		compressionOpts.Start, compressionOpts.Length = 0, 0
+		audioPath = filepath.Join(s.WorkDir, "vocals"+audioExt)
+		if err := audio.CompressAudio(
+			ctx,
+			vocalsPath,
+			audioPath,
+			compressionOpts,
+		); err != nil {
+			return fmt.Errorf("failed to compress vocal stem: %w", err)
+		}
+	}
+
+	s.AudioPath = audioPath
+	s.Offset = e.Start
+	return nil
+}
+
+// measures the prepared audio and plans how it is chunked. The chunks
+// themselves are cut by TranscribeStage, so cutting can overlap with
+// provider requests.
+type ChunkStage struct {
+	ChunkDuration time.Duration
+	// parallel requests; 0 sizes it to the chunk count, up to
+	// MaxConcurrency when that is set
+	Concurrency    int
+	MaxConcurrency int
+}
+
+func (ChunkStage) Name() string { return StageChunk }
+
+func (c ChunkStage) Run(ctx context.Context, s *State) error {
+	duration, err := audio.GetDuration(ctx, s.AudioPath)
+	if err != nil {
+		return fmt.Errorf("failed to get audio duration: %w", err)
+	}
+	s.Log.Infow("Audio prepared",
+		"duration", duration.String(),
+	)
+
+	chunks := audio.ChunkCount(duration, c.ChunkDuration)
+	if chunks == 0 {
+		return fmt.Errorf("failed to split audio: no chunks were created")
+	}
+
+	s.Duration = duration
+	s.Chunks = chunks
+	s.Concurrency = c.concurrency(chunks, s)
+	return nil
+}
+
+func (c ChunkStage) concurrency(chunks int, s *State) int {
+	if c.Concurrency <= 0 {
+		limit := chunks
+		if c.MaxConcurrency > 0 {
+			limit = min(chunks, c.MaxConcurrency)
+		}
+		return max(limit, 1)
+	}
+	if c.Concurrency > chunks {
+		s.Log.Infow(
+			"Requested concurrency exceeds number of chunks; capping concurrency",
+			"requested_concurrency",
+			c.Concurrency,
+			"chunk_count",
+			chunks,
+			"effective_concurrency",
+			chunks,
+		)
+		return chunks
+	}
+	return c.Concurrency
+}
+
+// cuts the audio into chunks and transcribes them
+type TranscribeStage struct {
+	Transcriber   transcribe.Transcriber
+	ChunkDuration time.Duration
+}
+
+func (TranscribeStage) Name() string { return StageTranscribe }
+
+// releases the transcriber
+func (t TranscribeStage) Shutdown(ctx context.Context) error {
+	return transcribe.Shutdown(ctx, t.Transcriber)
+}
+
+func (t TranscribeStage) Run(ctx context.Context, s *State) error {
+	s.Progress.Stage("Transcribing", s.Chunks)
+
+	result, err := transcribeAudio(
+		ctx,
+		t.Transcriber,
+		s.AudioPath,
+		filepath.Join(s.WorkDir, "chunks"),
+		t.ChunkDuration,
+		s.Concurrency,
+	)
+	if err != nil {
+		return err
+	}
+	s.Log.Infow("Transcription complete",
+		"segments", len(result.Segments),
+	)
+
+	s.Transcript = result
+	return nil
+}
+
+// cuts audioPath into chunks and transcribes them, overlapping ffmpeg work
+// with provider latency when the transcriber can consume a chunk stream
+func transcribeAudio(
+	ctx context.Context,
+	transcriber transcribe.Transcriber,
+	audioPath, chunkDir string,
+	chunkDur time.Duration,
+	concurrency int,
+) (*transcribe.Result, error) {
+	streaming, ok := transcriber.(transcribe.StreamingTranscriber)
+	if !ok {
+		chunks, err := audio.ChunkAudio(ctx, audioPath, chunkDur, chunkDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split audio: %w", err)
+		}
+		if len(chunks) == 0 {
+			return nil, fmt.Errorf(
+				"failed to split audio: no chunks were created",
+			)
+		}
+
+		var result *transcribe.Result
+		if concurrent, ok := transcriber.(transcribe.ConcurrentTranscriber); ok {
+			result, err = concurrent.TranscribeWithChunks(
+				ctx,
+				chunks,
+				concurrency,
+			)
+		} else {
+			result, err = transcriber.Transcribe(ctx, audioPath)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transcription failed: %w", err)
+		}
+		return result, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chunkChan := make(chan audio.ChunkInfo)
+	chunkErr := make(chan error, 1)
+	go func() {
+		err := audio.ChunkAudioStream(
+			ctx,
+			audioPath,
+			chunkDur,
+			chunkDir,
+			0,
+			chunkChan,
+		)
+		if err != nil {
+			// stop transcription of already-cut chunks; the job cannot finish
+			cancel()
+		}
+		chunkErr <- err
+	}()
+
+	result, err := streaming.TranscribeStream(ctx, chunkChan, concurrency)
+	if err != nil {
+		// unblock the chunker if transcription gave up early
+		cancel()
+	}
+	if splitErr := <-chunkErr; splitErr != nil &&
+		!errors.Is(splitErr, context.Canceled) {
+		return nil, fmt.Errorf("failed to split audio: %w", splitErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("transcription failed: %w", err)
+	}
+	if len(result.Segments) == 0 && result.Duration == 0 {
+		return nil, fmt.Errorf(
+			"failed to split audio: no chunks were created",
+		)
+	}
+
+	return result, nil
+}
+
+// turns transcript segments into subtitle entries
+type GenerateStage struct {
+	Generator subtitle.Generator // defaults to subtitle.NewDefaultGenerator
+	Language  string
+	Format    subtitle.Format
+}
+
+func (GenerateStage) Name() string { return StageGenerate }
+
+func (g GenerateStage) Run(ctx context.Context, s *State) error {
+	generator := g.Generator
+	if generator == nil {
+		generator = subtitle.NewDefaultGenerator()
+	}
+	segments := s.Transcript.Segments
+	if s.Offset > 0 {
+		// timed from the start of an excerpt, so moved to its place in the
+		// media
+		segments = make([]subtitle.Segment, len(s.Transcript.Segments))
+		for i, seg := range s.Transcript.Segments {
+			seg.StartTime += s.Offset
+			seg.EndTime += s.Offset
+			segments[i] = seg
+		}
+	}
+	subs, err := generator.Generate(segments)
+	if err != nil {
+		return fmt.Errorf("failed to generate subtitles: %w", err)
+	}
+	subs.Language = g.Language
+	subs.Format = string(g.Format)
+
+	s.Subtitle = subs
+	return nil
+}
+
+// writes the subtitles to State.OutputPath
+type WriteStage struct {
+	Format subtitle.Format
+	Writer subtitle.Writer // defaults to the writer for Format
+}
+
+func (WriteStage) Name() string { return StageWrite }
+
+func (w WriteStage) Run(ctx context.Context, s *State) error {
+	s.Progress.Stage("Writing subtitles", 0)
+
+	writer := w.Writer
+	if writer == nil {
+		var err error
+		if writer, err = subtitle.NewWriter(w.Format); err != nil {
+			return fmt.Errorf("failed to create subtitle writer: %w", err)
+		}
+	}
+	if err := writer.Write(s.Subtitle, s.OutputPath); err != nil {
+		return fmt.Errorf("failed to write subtitles: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package podcast
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/httpclient"
+	"github.com/mgpai22/lipi/internal/source"
+	"golang.org/x/net/html/charset"
+)
+
+// largest feed document accepted
+const maxFeedBytes = 32 << 20
+
+// Feed is a podcast RSS feed
+type Feed struct {
+	Title    string
+	Episodes []Episode // newest first
+}
+
+// Episode is a feed item with an audio or video enclosure
+type Episode struct {
+	GUID      string
+	Title     string
+	Published time.Time // zero when the feed gives no parseable date
+	MediaURL  string
+	MediaType string
+	Duration  string // itunes:duration as given, e.g. "1:02:03"
+}
+
+type rss struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type rssItem struct {
+	GUID      string `xml:"guid"`
+	Title     string `xml:"title"`
+	PubDate   string `xml:"pubDate"`
+	Duration  string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	Enclosure struct {
+		URL  string `xml:"url,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"enclosure"`
+}
+
+// Load reads a feed from an http(s) URL or a local file
+func Load(ctx context.Context, client *http.Client, location string) (*Feed, error) {
+	var r io.Reader
+	if source.IsURL(location) {
+		if err := httpclient.Refuse("download podcast feeds"); err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+		if err != nil {
+			return nil, errs.Wrap(errs.KindInput, fmt.Errorf("invalid feed URL: %w", err))
+		}
+		resp, err := httpclient.Or(client).Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download feed: %w", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to download feed: unexpected status %s", resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(location)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, errs.Wrap(errs.KindInput, fmt.Errorf("feed not found: %s", location))
+			}
+			return nil, fmt.Errorf("failed to open feed: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+	return Parse(io.LimitReader(r, maxFeedBytes))
+}
+
+// Parse decodes an RSS feed, keeping the items that have a media enclosure
+func Parse(r io.Reader) (*Feed, error) {
+	var doc rss
+	decoder := xml.NewDecoder(r)
+	// feeds still come in ISO-8859-1 and Windows code pages
+	decoder.CharsetReader = charset.NewReaderLabel
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, errs.Wrap(errs.KindInput, fmt.Errorf("failed to parse feed: %w", err))
+	}
+
+	feed := &Feed{Title: strings.TrimSpace(doc.Channel.Title)}
+	for _, item := range doc.Channel.Items {
+		if item.Enclosure.URL == "" {
+			continue
+		}
+		episode := Episode{
+			GUID:      strings.TrimSpace(item.GUID),
+			Title:     strings.TrimSpace(item.Title),
+			Published: parseDate(item.PubDate),
+			MediaURL:  strings.TrimSpace(item.Enclosure.URL),
+			MediaType: item.Enclosure.Type,
+			Duration:  strings.TrimSpace(item.Duration),
+		}
+		if episode.GUID == "" {
+			episode.GUID = episode.MediaURL
+		}
+		feed.Episodes = append(feed.Episodes, episode)
+	}
+	if len(feed.Episodes) == 0 {
+		return nil, errs.Wrap(errs.KindInput, errors.New("feed has no episodes with media enclosures"))
+	}
+
+	// undated episodes keep their feed order after the dated ones
+	sort.SliceStable(feed.Episodes, func(i, j int) bool {
+		a, b := feed.Episodes[i].Published, feed.Episodes[j].Published
+		if a.IsZero() || b.IsZero() {
+			return !a.IsZero() && b.IsZero()
+		}
+		return a.After(b)
+	})
+	return feed, nil
+}
+
+// RFC 822 dates as feeds actually write them
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+}
+
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Latest returns the n newest episodes, or all of them when n is 0
+func (f *Feed) Latest(n int) []Episode {
+	if n <= 0 || n >= len(f.Episodes) {
+		return f.Episodes
+	}
+	return f.Episodes[:n]
+}
+
+// characters that are not allowed or awkward in file names
+var unsafeNameChars = strings.NewReplacer(
+	"/", "-", "\\", "-", ":", " -", "*", "", "?", "", "\"", "'",
+	"<", "", ">", "", "|", "-", "\n", " ", "\r", " ", "\t", " ",
+)
+
+// Basename is a file name for the episode without extension: its date and
+// title, e.g. "2024-05-01 Episode 12 - Guests"
+func (e Episode) Basename() string {
+	title := strings.Join(strings.Fields(unsafeNameChars.Replace(e.Title)), " ")
+	title = strings.Trim(title, ". ")
+	if runes := []rune(title); len(runes) > 120 {
+		title = strings.TrimSpace(string(runes[:120]))
+	}
+	if title == "" {
+		title = "episode"
+	}
+	if e.Published.IsZero() {
+		return title
+	}
+	return e.Published.Format("2006-01-02") + " " + title
+}
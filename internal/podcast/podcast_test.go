@@ -0,0 +1,111 @@
+package podcast
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+const testFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+<channel>
+  <title>Test Show</title>
+  <item>
+    <title>Episode 1: Pilot</title>
+    <guid>ep1</guid>
+    <pubDate>Mon, 01 Jan 2024 10:00:00 +0000</pubDate>
+    <enclosure url="https://cdn.example.com/ep1.mp3" type="audio/mpeg" length="1"/>
+  </item>
+  <item>
+    <title>Announcement</title>
+    <pubDate>Tue, 02 Jan 2024 10:00:00 +0000</pubDate>
+  </item>
+  <item>
+    <title>Episode 2: Guests / Q&amp;A?</title>
+    <pubDate>Mon, 8 Jan 2024 10:00:00 GMT</pubDate>
+    <itunes:duration>1:02:03</itunes:duration>
+    <enclosure url="https://cdn.example.com/ep2.mp3" type="audio/mpeg" length="1"/>
+  </item>
+  <item>
+    <title>Bonus</title>
+    <enclosure url="https://cdn.example.com/bonus.mp3" type="audio/mpeg" length="1"/>
+  </item>
+</channel>
+</rss>`
+
+func TestParse(t *testing.T) {
+	feed, err := Parse(strings.NewReader(testFeed))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if feed.Title != "Test Show" || len(feed.Episodes) != 3 {
+		t.Fatalf("Parse() = %q with %d episodes, want 3 with media", feed.Title, len(feed.Episodes))
+	}
+
+	var titles []string
+	for _, e := range feed.Episodes {
+		titles = append(titles, e.Title)
+	}
+	if got := strings.Join(titles, "|"); got != "Episode 2: Guests / Q&A?|Episode 1: Pilot|Bonus" {
+		t.Errorf("episode order = %s, want newest first and undated last", got)
+	}
+	newest := feed.Episodes[0]
+	if newest.Duration != "1:02:03" || newest.GUID != newest.MediaURL {
+		t.Errorf("newest = %+v", newest)
+	}
+	if got := len(feed.Latest(2)); got != 2 {
+		t.Errorf("Latest(2) returned %d episodes", got)
+	}
+	if got := len(feed.Latest(0)); got != 3 {
+		t.Errorf("Latest(0) returned %d episodes, want all", got)
+	}
+}
+
+func TestParseLatin1(t *testing.T) {
+	feed := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><rss><channel><title>Caf\xe9</title>" +
+		"<item><title>Un</title><enclosure url=\"https://example.com/1.mp3\"/></item></channel></rss>"
+	got, err := Parse(strings.NewReader(feed))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got.Title != "Café" {
+		t.Errorf("Title = %q, want Café", got.Title)
+	}
+}
+
+func TestEpisodeBasename(t *testing.T) {
+	published := time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		episode Episode
+		want    string
+	}{
+		{Episode{Title: "Episode 2: Guests / Q&A?", Published: published}, "2024-01-08 Episode 2 - Guests - Q&A"},
+		{Episode{Title: "  Bonus  "}, "Bonus"},
+		{Episode{Title: "..."}, "episode"},
+	}
+	for _, tt := range tests {
+		if got := tt.episode.Basename(); got != tt.want {
+			t.Errorf("Basename(%q) = %q, want %q", tt.episode.Title, got, tt.want)
+		}
+	}
+}
+
+func TestTranscriptJSON(t *testing.T) {
+	data, err := TranscriptJSON(&subtitle.Subtitle{Entries: []subtitle.Entry{
+		{Index: 1, StartTime: 1500 * time.Millisecond, EndTime: 3 * time.Second, Text: "Hello"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got transcript
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != "1.0.0" || len(got.Segments) != 1 ||
+		got.Segments[0].StartTime != 1.5 || got.Segments[0].Body != "Hello" {
+		t.Errorf("TranscriptJSON() = %s", data)
+	}
+}
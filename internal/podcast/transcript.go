@@ -0,0 +1,62 @@
+package podcast
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// TranscriptType is the MIME type of a JSON transcript, for the type
+// attribute of <podcast:transcript>
+const TranscriptType = "application/json"
+
+// JSON transcript as specified by the Podcasting 2.0 namespace
+type transcript struct {
+	Version  string              `json:"version"`
+	Segments []transcriptSegment `json:"segments"`
+}
+
+type transcriptSegment struct {
+	Speaker   string  `json:"speaker,omitempty"`
+	StartTime float64 `json:"startTime"`
+	EndTime   float64 `json:"endTime"`
+	Body      string  `json:"body"`
+}
+
+// TranscriptJSON encodes subtitle entries as a Podcasting 2.0 JSON
+// transcript, one segment per entry
+func TranscriptJSON(sub *subtitle.Subtitle) ([]byte, error) {
+	doc := transcript{
+		Version:  "1.0.0",
+		Segments: make([]transcriptSegment, len(sub.Entries)),
+	}
+	for i, entry := range sub.Entries {
+		doc.Segments[i] = transcriptSegment{
+			Speaker:   entry.Speaker,
+			StartTime: seconds(entry.StartTime.Seconds()),
+			EndTime:   seconds(entry.EndTime.Seconds()),
+			Body:      entry.Text,
+		}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// WriteTranscript writes the JSON transcript of sub to path
+func WriteTranscript(path string, sub *subtitle.Subtitle) error {
+	data, err := TranscriptJSON(sub)
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
+
+// times are written to the millisecond
+func seconds(s float64) float64 {
+	return math.Round(s*1000) / 1000
+}
@@ -0,0 +1,138 @@
+package pool
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Result is the value produced for one item
+type Result[T any] struct {
+	Index int // position of the item in the input, or its arrival order for Stream
+	Value T
+}
+
+// Run calls fn for each item, with up to workers calls in flight, and
+// returns the values of the items that finished, in input order.
+//
+// The first error cancels the remaining work and is returned together with
+// the results finished so far. Errors that other workers see only because
+// of that cancellation never replace it. If ctx is cancelled, ctx.Err() is
+// returned with the partial results.
+func Run[In, Out any](
+	ctx context.Context,
+	items []In,
+	workers int,
+	fn func(context.Context, In) (Out, error),
+) ([]Result[Out], error) {
+	if len(items) == 0 {
+		return nil, ctx.Err()
+	}
+
+	feedCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// feed work in a separate goroutine so enqueueing stops promptly once
+	// the run is cancelled
+	work := make(chan In)
+	go func() {
+		defer close(work)
+		for _, item := range items {
+			select {
+			case <-feedCtx.Done():
+				return
+			case work <- item:
+			}
+		}
+	}()
+
+	return Stream(ctx, work, min(max(workers, 1), len(items)), fn)
+}
+
+// Stream is Run for items that arrive on a channel, such as chunks that are
+// still being cut. It returns once items is closed and all started work has
+// finished, or as soon as workers stop after an error or cancellation.
+func Stream[In, Out any](
+	ctx context.Context,
+	items <-chan In,
+	workers int,
+	fn func(context.Context, In) (Out, error),
+) ([]Result[Out], error) {
+	workers = max(workers, 1)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		results  []Result[Out]
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		// stop the other workers from starting more work
+		cancel()
+	}
+
+	type job struct {
+		index int
+		item  In
+	}
+	jobs := make(chan job)
+
+	// numbers items in arrival order, so results can be ordered even when
+	// several workers receive at once
+	go func() {
+		defer close(jobs)
+		for index := 0; ; index++ {
+			select {
+			case <-runCtx.Done():
+				return
+			case item, ok := <-items:
+				if !ok {
+					return
+				}
+				select {
+				case <-runCtx.Done():
+					return
+				case jobs <- job{index: index, item: item}:
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for j := range jobs {
+				// if cancellation won the race with receiving work, stop
+				// promptly to avoid starting more requests
+				if runCtx.Err() != nil {
+					return
+				}
+
+				value, err := fn(runCtx, j.item)
+				if err != nil {
+					fail(err)
+					return
+				}
+				mu.Lock()
+				results = append(results, Result[Out]{Index: j.index, Value: value})
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, k int) bool {
+		return results[i].Index < results[k].Index
+	})
+	if firstErr != nil {
+		return results, firstErr
+	}
+	return results, ctx.Err()
+}
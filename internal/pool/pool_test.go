@@ -0,0 +1,98 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunKeepsInputOrder(t *testing.T) {
+	items := []int{5, 4, 3, 2, 1, 0}
+	// later items finish first
+	results, err := Run(context.Background(), items, 3, func(ctx context.Context, n int) (string, error) {
+		time.Sleep(time.Duration(n) * time.Millisecond)
+		return fmt.Sprint(n), nil
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("Run() returned %d results, want %d", len(results), len(items))
+	}
+	for i, r := range results {
+		if r.Index != i || r.Value != fmt.Sprint(items[i]) {
+			t.Errorf("results[%d] = %+v, want index %d value %d", i, r, i, items[i])
+		}
+	}
+}
+
+func TestRunBoundsWorkers(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	items := make([]int, 12)
+	_, err := Run(context.Background(), items, 3, func(ctx context.Context, _ int) (int, error) {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		inFlight.Add(-1)
+		return 0, nil
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if peak.Load() > 3 {
+		t.Errorf("peak workers = %d, want at most 3", peak.Load())
+	}
+}
+
+func TestRunReturnsFirstErrorAndPartialResults(t *testing.T) {
+	boom := errors.New("boom")
+	items := []int{0, 1, 2, 3}
+	firstDone := make(chan struct{})
+	results, err := Run(context.Background(), items, 2, func(ctx context.Context, n int) (int, error) {
+		switch n {
+		case 0:
+			defer close(firstDone)
+		case 1:
+			<-firstDone
+			return 0, boom
+		case 2, 3:
+			// in flight when item 1 fails; sees only the cancellation
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return n, nil
+	})
+	if !errors.Is(err, boom) {
+		t.Errorf("Run() error = %v, want boom rather than the cancellation", err)
+	}
+	if len(results) != 1 || results[0].Value != 0 {
+		t.Errorf("partial results = %+v, want item 0", results)
+	}
+}
+
+func TestStreamReportsCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	items := make(chan int)
+	go func() {
+		items <- 1
+		cancel()
+	}()
+
+	results, err := Stream(ctx, items, 2, func(ctx context.Context, n int) (int, error) {
+		return n, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Stream() error = %v, want context.Canceled", err)
+	}
+	if len(results) > 1 {
+		t.Errorf("results = %+v, want at most the item sent before cancelling", results)
+	}
+}
@@ -0,0 +1,200 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	barWidth       = 24
+	redrawInterval = 150 * time.Millisecond
+)
+
+var spinner = []string{"|", "/", "-", "\\"}
+
+// Display draws one live status line for the current stage of a run,
+//
+//	/ Transcribing [==========>             ] 12/24  41s
+//
+// and leaves a summary line behind for each finished stage. A nil Display
+// draws nothing, so callers can report progress unconditionally.
+type Display struct {
+	mu      sync.Mutex
+	w       io.Writer
+	name    string
+	total   int
+	done    int
+	started time.Time
+	frame   int
+	stop    chan struct{}
+	stopped sync.WaitGroup
+	closed  sync.Once
+}
+
+// creates a display that redraws to w, normally a terminal's stderr
+func New(w io.Writer) *Display {
+	d := &Display{w: w, stop: make(chan struct{})}
+	d.stopped.Go(d.loop)
+	return d
+}
+
+// reports whether f is an interactive terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// finishes the current stage, if any, and starts the next. A total of 0
+// shows a spinner instead of a bar.
+func (d *Display) Stage(name string, total int) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.finish()
+	d.name = name
+	d.total = total
+	d.done = 0
+	d.started = time.Now()
+	d.draw()
+}
+
+// advances the current stage by n steps
+func (d *Display) Add(n int) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.done += n
+	d.draw()
+}
+
+// finishes the current stage
+func (d *Display) Done() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.finish()
+}
+
+// stops redrawing and clears an unfinished stage's line
+func (d *Display) Close() {
+	if d == nil {
+		return
+	}
+	d.closed.Do(func() {
+		close(d.stop)
+	})
+	d.stopped.Wait()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.name != "" {
+		_, _ = fmt.Fprint(d.w, "\r\033[K")
+		d.name = ""
+	}
+}
+
+// keeps the spinner and elapsed time moving between updates
+func (d *Display) loop() {
+	ticker := time.NewTicker(redrawInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			d.frame++
+			d.draw()
+			d.mu.Unlock()
+		}
+	}
+}
+
+func (d *Display) draw() {
+	if d.name == "" {
+		return
+	}
+	line := renderLine(
+		d.name,
+		d.done,
+		d.total,
+		time.Since(d.started),
+		d.frame,
+	)
+	_, _ = fmt.Fprint(d.w, "\r\033[K"+line)
+}
+
+func (d *Display) finish() {
+	if d.name == "" {
+		return
+	}
+	line := renderDone(d.name, d.done, d.total, time.Since(d.started))
+	_, _ = fmt.Fprint(d.w, "\r\033[K"+line+"\n")
+	d.name = ""
+}
+
+// status line for a stage in progress
+func renderLine(name string, done, total int, elapsed time.Duration, frame int) string {
+	if total <= 0 {
+		return fmt.Sprintf(
+			"%s %s  %s",
+			spinner[frame%len(spinner)],
+			name,
+			formatElapsed(elapsed),
+		)
+	}
+
+	done = min(done, total)
+	filled := done * barWidth / total
+	bar := strings.Repeat("=", filled)
+	if filled < barWidth {
+		bar += ">" + strings.Repeat(" ", barWidth-filled-1)
+	}
+	return fmt.Sprintf(
+		"%s %s [%s] %d/%d  %s",
+		spinner[frame%len(spinner)],
+		name,
+		bar,
+		done,
+		total,
+		formatElapsed(elapsed),
+	)
+}
+
+// summary line left behind for a finished stage
+func renderDone(name string, done, total int, elapsed time.Duration) string {
+	if total <= 0 {
+		return fmt.Sprintf("* %s (%s)", name, formatElapsed(elapsed))
+	}
+	return fmt.Sprintf(
+		"* %s %d/%d (%s)",
+		name,
+		min(done, total),
+		total,
+		formatElapsed(elapsed),
+	)
+}
+
+func formatElapsed(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(100 * time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,67 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		done  int
+		total int
+		want  string
+	}{
+		{"spinner", 0, 0, "| Extracting audio  1.5s"},
+		{"empty bar", 0, 4, "| Extracting audio [>                       ] 0/4  1.5s"},
+		{"half", 2, 4, "| Extracting audio [============>           ] 2/4  1.5s"},
+		{"full", 4, 4, "| Extracting audio [========================] 4/4  1.5s"},
+		{"overflow", 9, 4, "| Extracting audio [========================] 4/4  1.5s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderLine("Extracting audio", tt.done, tt.total, 1500*time.Millisecond, 0)
+			if got != tt.want {
+				t.Errorf("renderLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderDone(t *testing.T) {
+	if got := renderDone("Transcribing", 3, 3, 75*time.Second); got != "* Transcribing 3/3 (1m15s)" {
+		t.Errorf("renderDone() = %q", got)
+	}
+	if got := renderDone("Writing subtitles", 0, 0, 200*time.Millisecond); got != "* Writing subtitles (200ms)" {
+		t.Errorf("renderDone() = %q", got)
+	}
+}
+
+func TestDisplayStages(t *testing.T) {
+	var buf bytes.Buffer
+	d := New(&buf)
+	d.Stage("Transcribing", 2)
+	d.Add(1)
+	d.Add(1)
+	d.Stage("Writing subtitles", 0)
+	d.Done()
+	d.Close()
+
+	out := buf.String()
+	for _, want := range []string{"* Transcribing 2/2 (", "* Writing subtitles ("} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%q", want, out)
+		}
+	}
+}
+
+func TestNilDisplay(t *testing.T) {
+	var d *Display
+	d.Stage("Transcribing", 2)
+	d.Add(1)
+	d.Done()
+	d.Close()
+}
@@ -0,0 +1,92 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/llm"
+)
+
+// Terms are names and phrases found in a translation, with the rendering
+// it used for each
+type Terms struct {
+	Characters map[string]string
+	Phrases    map[string]string
+}
+
+// Pair is a cue's original text and its translation
+type Pair struct {
+	Original    string `json:"original"`
+	Translation string `json:"translation"`
+}
+
+// model answer
+type extractResponse struct {
+	Characters []extractedTerm `json:"characters"`
+	Phrases    []extractedTerm `json:"phrases"`
+}
+
+type extractedTerm struct {
+	Source      string `json:"source"`
+	Translation string `json:"translation"`
+}
+
+// Extract asks gen for the character names and recurring phrases of a
+// translated episode that are not among known, with the renderings the
+// translation gave them
+func Extract(ctx context.Context, gen llm.Generator, pairs []Pair, targetLang string, known []string) (Terms, error) {
+	terms := Terms{Characters: map[string]string{}, Phrases: map[string]string{}}
+	if len(pairs) == 0 {
+		return terms, nil
+	}
+	prompt, err := buildExtractPrompt(pairs, targetLang, known)
+	if err != nil {
+		return terms, err
+	}
+	answer, err := gen.Generate(ctx, prompt)
+	if err != nil {
+		return terms, fmt.Errorf("failed to extract project terms: %w", err)
+	}
+	var resp extractResponse
+	if err := llm.DecodeJSON(answer, &resp); err != nil {
+		return terms, fmt.Errorf("failed to extract project terms: %w", err)
+	}
+	for _, t := range resp.Characters {
+		addTerm(terms.Characters, t)
+	}
+	for _, t := range resp.Phrases {
+		addTerm(terms.Phrases, t)
+	}
+	return terms, nil
+}
+
+func addTerm(dst map[string]string, t extractedTerm) {
+	source, translation := strings.TrimSpace(t.Source), strings.TrimSpace(t.Translation)
+	if source != "" && translation != "" {
+		dst[source] = translation
+	}
+}
+
+func buildExtractPrompt(pairs []Pair, targetLang string, known []string) (string, error) {
+	data, err := json.Marshal(pairs)
+	if err != nil {
+		return "", fmt.Errorf("failed to build project prompt: %w", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "These are subtitle lines of an episode of a series with their translation to %s. ", targetLang)
+	sb.WriteString("List what later episodes must translate the same way:\n")
+	sb.WriteString("- characters: names of people, creatures, and groups, as written in the original, with the rendering the translation used.\n")
+	sb.WriteString("- phrases: recurring catchphrases, titles, named techniques, places, and invented terms, with their rendering.\n")
+	sb.WriteString("Leave out ordinary words and phrases that any translator would render the same way.\n")
+	if len(known) > 0 {
+		fmt.Fprintf(&sb, "These are already known and must not be listed: %s\n", strings.Join(known, ", "))
+	}
+	sb.WriteString("\nLines:\n")
+	sb.Write(data)
+	sb.WriteString("\n\nRespond with only a JSON object in this format:\n")
+	sb.WriteString(`{"characters": [{"source": "...", "translation": "..."}], "phrases": [{"source": "...", "translation": "..."}]}`)
+	return sb.String(), nil
+}
@@ -0,0 +1,228 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/mgpai22/lipi/internal/glossary"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of a project file, looked up from the folder of the
+// subtitles being translated upwards
+const FileName = "lipi.project.yaml"
+
+// how honorifics such as -san and -sama are carried into a translation
+type Honorifics string
+
+const (
+	// keep them as in the original
+	HonorificsKeep Honorifics = "keep"
+	// leave them out
+	HonorificsDrop Honorifics = "drop"
+	// replace them with the target language's forms of address
+	HonorificsAdapt Honorifics = "adapt"
+)
+
+// Project is the translation memory of a series, shared by its episodes:
+// the names, phrases, and decisions each target language has settled on.
+//
+//	name: My Series
+//	languages:
+//	  en:
+//	    honorifics: keep
+//	    characters:
+//	      炭治郎: Tanjiro
+//	    phrases:
+//	      全集中の呼吸: Total Concentration Breathing
+//	    episodes:
+//	      - ep01.ja.srt
+type Project struct {
+	Name      string               `yaml:"name,omitempty"`
+	Languages map[string]*Language `yaml:"languages,omitempty"`
+}
+
+// Language is what translations into one language have settled on
+type Language struct {
+	Honorifics Honorifics        `yaml:"honorifics,omitempty"`
+	Characters map[string]string `yaml:"characters,omitempty"` // name -> rendering
+	Phrases    map[string]string `yaml:"phrases,omitempty"`    // phrase -> rendering
+	Episodes   []string          `yaml:"episodes,omitempty"`   // subtitle files translated so far
+}
+
+// Find returns the project file in dir or the closest folder above it, or
+// "" when there is none
+func Find(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// reads a project file
+func Load(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project: %w", err)
+	}
+	var p Project
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse project %s: %w", path, err)
+	}
+	for lang, l := range p.Languages {
+		if l == nil {
+			continue
+		}
+		if err := l.Honorifics.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid project %s: %s: %w", path, lang, err)
+		}
+	}
+	return &p, nil
+}
+
+// writes the project to path, replacing the file only once it is complete
+func (p *Project) Save(path string) error {
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode project: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write project: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write project: %w", err)
+	}
+	return nil
+}
+
+// the settings of lang, added to the project when it has none yet
+func (p *Project) Language(lang string) *Language {
+	if p.Languages == nil {
+		p.Languages = map[string]*Language{}
+	}
+	l := p.Languages[lang]
+	if l == nil {
+		l = &Language{}
+		p.Languages[lang] = l
+	}
+	return l
+}
+
+// serializes updates of project files by the runs of one process
+var updateMu sync.Mutex
+
+// Update loads the project at path, or starts an empty one when the file
+// does not exist, applies fn, and saves the result. Concurrent updates
+// within the process are applied one after another, each to the file the
+// last one saved.
+func Update(path string, fn func(*Project) error) error {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+
+	p, err := Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		p, err = &Project{}, nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := fn(p); err != nil {
+		return err
+	}
+	return p.Save(path)
+}
+
+// Glossary lists the characters and then the phrases with their
+// renderings, each sorted, for the translation prompt
+func (l *Language) Glossary() glossary.Glossary {
+	var g glossary.Glossary
+	for _, terms := range []map[string]string{l.Characters, l.Phrases} {
+		keys := make([]string, 0, len(terms))
+		for term := range terms {
+			keys = append(keys, term)
+		}
+		sort.Strings(keys)
+		for _, term := range keys {
+			g = append(g, glossary.Entry{Term: term, Translation: terms[term]})
+		}
+	}
+	return g
+}
+
+// Learn adds the terms not known yet and returns how many it added.
+// Renderings already in the project, decided earlier or edited by hand,
+// are never replaced.
+func (l *Language) Learn(terms Terms) int {
+	added := 0
+	add := func(dst *map[string]string, src map[string]string) {
+		for term, rendering := range src {
+			if term == "" || rendering == "" || l.knows(term) {
+				continue
+			}
+			if *dst == nil {
+				*dst = map[string]string{}
+			}
+			(*dst)[term] = rendering
+			added++
+		}
+	}
+	add(&l.Characters, terms.Characters)
+	add(&l.Phrases, terms.Phrases)
+	return added
+}
+
+func (l *Language) knows(term string) bool {
+	_, character := l.Characters[term]
+	_, phrase := l.Phrases[term]
+	return character || phrase
+}
+
+// records a translated episode once
+func (l *Language) AddEpisode(name string) {
+	for _, e := range l.Episodes {
+		if e == name {
+			return
+		}
+	}
+	l.Episodes = append(l.Episodes, name)
+}
+
+// Validate accepts the known honorifics settings and the empty one
+func (h Honorifics) Validate() error {
+	switch h {
+	case "", HonorificsKeep, HonorificsDrop, HonorificsAdapt:
+		return nil
+	}
+	return fmt.Errorf("unsupported honorifics %q: use %s, %s, or %s", h, HonorificsKeep, HonorificsDrop, HonorificsAdapt)
+}
+
+// Instruction is the translation prompt's rule for honorifics, empty when
+// no decision was made
+func (h Honorifics) Instruction() string {
+	switch h {
+	case HonorificsKeep:
+		return "Keep honorifics such as -san, -kun, -chan, -sama, and -senpai attached to names as in the original."
+	case HonorificsDrop:
+		return "Leave honorifics such as -san, -kun, -chan, and -sama out of the translation."
+	case HonorificsAdapt:
+		return "Render honorifics such as -san and -sama with the target language's own forms of address, or leave them out where it has none."
+	}
+	return ""
+}
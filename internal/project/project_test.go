@@ -0,0 +1,115 @@
+package project
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/glossary"
+	"github.com/mgpai22/lipi/internal/llm"
+)
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	episode := filepath.Join(root, "season1", "ep01")
+	if err := os.MkdirAll(episode, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := Find(episode); got != "" {
+		t.Errorf("Find() without a project = %q", got)
+	}
+	path := filepath.Join(root, FileName)
+	if err := os.WriteFile(path, []byte("name: Show\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := Find(episode); got != path {
+		t.Errorf("Find() = %q, want %q", got, path)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	err := Update(path, func(p *Project) error {
+		l := p.Language("en")
+		l.Honorifics = HonorificsKeep
+		l.Characters = map[string]string{"炭治郎": "Tanjiro"}
+		l.AddEpisode("ep01.ja.srt")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	err = Update(path, func(p *Project) error {
+		l := p.Language("en")
+		// a hand-edited rendering is not replaced
+		if added := l.Learn(Terms{
+			Characters: map[string]string{"炭治郎": "Tanjirou", "禰豆子": "Nezuko"},
+			Phrases:    map[string]string{"全集中": "Total Concentration", "": "empty"},
+		}); added != 2 {
+			t.Errorf("Learn() = %d, want 2", added)
+		}
+		l.AddEpisode("ep01.ja.srt")
+		l.AddEpisode("ep02.ja.srt")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	l := p.Languages["en"]
+	if l.Honorifics != HonorificsKeep || !reflect.DeepEqual(l.Episodes, []string{"ep01.ja.srt", "ep02.ja.srt"}) {
+		t.Errorf("language = %+v", l)
+	}
+	want := glossary.Glossary{
+		{Term: "炭治郎", Translation: "Tanjiro"},
+		{Term: "禰豆子", Translation: "Nezuko"},
+		{Term: "全集中", Translation: "Total Concentration"},
+	}
+	if got := l.Glossary(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Glossary() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadRejectsHonorifics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte("languages:\n  en:\n    honorifics: sometimes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "sometimes") {
+		t.Errorf("Load() error = %v, want unsupported honorifics", err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	var prompt string
+	gen := llm.GeneratorFunc(func(ctx context.Context, p string) (string, error) {
+		prompt = p
+		return "```json\n" + `{
+			"characters": [{"source": "炭治郎", "translation": "Tanjiro"}, {"source": " ", "translation": "nobody"}],
+			"phrases": [{"source": "全集中", "translation": " Total Concentration "}]
+		}` + "\n```", nil
+	})
+	terms, err := Extract(context.Background(), gen, []Pair{{Original: "炭治郎!", Translation: "Tanjiro!"}}, "English", []string{"鬼"})
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	want := Terms{
+		Characters: map[string]string{"炭治郎": "Tanjiro"},
+		Phrases:    map[string]string{"全集中": "Total Concentration"},
+	}
+	if !reflect.DeepEqual(terms, want) {
+		t.Errorf("Extract() = %+v, want %+v", terms, want)
+	}
+	for _, part := range []string{"translation to English", "must not be listed: 鬼", `"original":"炭治郎!"`} {
+		if !strings.Contains(prompt, part) {
+			t.Errorf("prompt lacks %q:\n%s", part, prompt)
+		}
+	}
+}
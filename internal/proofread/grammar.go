@@ -0,0 +1,109 @@
+package proofread
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/llm"
+	"github.com/mgpai22/lipi/internal/pool"
+)
+
+// Grammar corrects spelling, grammar, and punctuation with a language
+// model, a batch of cues per request, leaving wording and meaning alone
+type Grammar struct {
+	Generator   llm.Generator
+	Language    string      // language of the subtitles, when known
+	Prompt      string      // additional instructions
+	BatchSize   int         // cues per request (default 50)
+	Concurrency int         // requests in flight (default 1)
+	OnBatch     func(n int) // when set, called with the cue count of each batch checked
+}
+
+// model answer
+type grammarResponse struct {
+	Cues []struct {
+		Index int    `json:"index"`
+		Text  string `json:"text"`
+	} `json:"cues"`
+}
+
+func (g *Grammar) Correct(ctx context.Context, texts []string) ([]string, error) {
+	batchSize := g.BatchSize
+	if batchSize <= 0 {
+		batchSize = 50
+	}
+	var batches [][]int // cue indexes
+	for start := 0; start < len(texts); start += batchSize {
+		batch := make([]int, 0, batchSize)
+		for i := start; i < min(start+batchSize, len(texts)); i++ {
+			batch = append(batch, i)
+		}
+		batches = append(batches, batch)
+	}
+
+	corrected := make([]string, len(texts))
+	copy(corrected, texts)
+	_, err := pool.Run(ctx, batches, g.Concurrency, func(ctx context.Context, batch []int) (struct{}, error) {
+		prompt, err := g.buildPrompt(texts, batch)
+		if err != nil {
+			return struct{}{}, err
+		}
+		answer, err := g.Generator.Generate(ctx, prompt)
+		if err != nil {
+			return struct{}{}, fmt.Errorf("failed to proofread cues %d-%d: %w", batch[0]+1, batch[len(batch)-1]+1, err)
+		}
+		var resp grammarResponse
+		if err := llm.DecodeJSON(answer, &resp); err != nil {
+			return struct{}{}, fmt.Errorf("failed to proofread cues %d-%d: %w", batch[0]+1, batch[len(batch)-1]+1, err)
+		}
+		// each batch writes only its own cues; blank answers keep the cue
+		first, last := batch[0], batch[len(batch)-1]
+		for _, cue := range resp.Cues {
+			if cue.Index >= first && cue.Index <= last && strings.TrimSpace(cue.Text) != "" {
+				corrected[cue.Index] = cue.Text
+			}
+		}
+		if g.OnBatch != nil {
+			g.OnBatch(len(batch))
+		}
+		return struct{}{}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return corrected, nil
+}
+
+func (g *Grammar) buildPrompt(texts []string, batch []int) (string, error) {
+	type cue struct {
+		Index int    `json:"index"`
+		Text  string `json:"text"`
+	}
+	cues := make([]cue, len(batch))
+	for i, index := range batch {
+		cues[i] = cue{Index: index, Text: texts[index]}
+	}
+	data, err := json.Marshal(cues)
+	if err != nil {
+		return "", fmt.Errorf("failed to build proofreading prompt: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Proofread these subtitle cues. Fix spelling, grammar, and punctuation mistakes only:\n")
+	sb.WriteString("1. Do not reword, shorten, or restyle a line that is already correct; colloquial speech stays colloquial.\n")
+	sb.WriteString("2. Keep names, line breaks, and formatting tags such as {\\i1} or <i> as they are.\n")
+	sb.WriteString("3. Return every cue with its index, unchanged when it needs no fix.\n")
+	if g.Language != "" {
+		fmt.Fprintf(&sb, "The subtitles are in %s.\n", g.Language)
+	}
+	if g.Prompt != "" {
+		fmt.Fprintf(&sb, "Additional instructions: %s\n", g.Prompt)
+	}
+	sb.WriteString("\nCues:\n")
+	sb.Write(data)
+	sb.WriteString("\n\nRespond with only a JSON object in this format:\n")
+	sb.WriteString(`{"cues": [{"index": 0, "text": "..."}]}`)
+	return sb.String(), nil
+}
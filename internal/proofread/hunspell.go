@@ -0,0 +1,188 @@
+package proofread
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Hunspell corrects misspelled words with the hunspell spell checker and
+// its dictionaries, replacing each with its first suggestion. It checks
+// spelling only, word by word.
+type Hunspell struct {
+	Executable string // default "hunspell"
+	Dictionary string // dictionary name or path, e.g. en_US
+	Personal   string // when set, file of extra words to accept
+	// Unknown, when set, is called for each misspelled word that has no
+	// suggestion and is left as is
+	Unknown func(index int, word string)
+}
+
+// a word hunspell does not accept
+type misspelling struct {
+	word        string
+	suggestions []string
+}
+
+// override blocks of ASS and tags of SRT and WebVTT, which are not words
+var markupRegex = regexp.MustCompile(`\{[^}]*\}|<[^>]*>`)
+
+func (h *Hunspell) Correct(ctx context.Context, texts []string) ([]string, error) {
+	exe := h.Executable
+	if exe == "" {
+		exe = "hunspell"
+	}
+	resolved, err := exec.LookPath(exe)
+	if err != nil {
+		return nil, fmt.Errorf("spell checker %q not found: install hunspell and a dictionary for the language", exe)
+	}
+
+	// markup is blanked out, keeping every word at its byte offset
+	var lines, masked []string
+	var input strings.Builder
+	for _, text := range texts {
+		for _, line := range strings.Split(text, "\n") {
+			mask := markupRegex.ReplaceAllStringFunc(line, func(tag string) string {
+				return strings.Repeat(" ", len(tag))
+			})
+			lines = append(lines, line)
+			masked = append(masked, mask)
+			// "^" keeps a line from being read as a hunspell command
+			input.WriteString("^" + mask + "\n")
+		}
+	}
+
+	args := []string{"-a", "-i", "utf-8"}
+	if h.Dictionary != "" {
+		args = append(args, "-d", h.Dictionary)
+	}
+	if h.Personal != "" {
+		args = append(args, "-p", h.Personal)
+	}
+	cmd := exec.CommandContext(ctx, resolved, args...)
+	cmd.Stdin = strings.NewReader(input.String())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("spell check failed: %w (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+	found, err := parseHunspell(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) != len(lines) {
+		return nil, fmt.Errorf("spell checker answered %d lines, %d expected", len(found), len(lines))
+	}
+
+	corrected := make([]string, len(texts))
+	n := 0
+	for i, text := range texts {
+		parts := strings.Split(text, "\n")
+		for j := range parts {
+			parts[j] = h.fix(i, lines[n], masked[n], found[n])
+			n++
+		}
+		corrected[i] = strings.Join(parts, "\n")
+	}
+	return corrected, nil
+}
+
+// line with each misspelling replaced by its first suggestion, found in
+// order in the masked line
+func (h *Hunspell) fix(index int, line, mask string, found []misspelling) string {
+	var sb strings.Builder
+	last, from := 0, 0
+	for _, m := range found {
+		at := findWord(mask, m.word, from)
+		if at < 0 {
+			continue
+		}
+		from = at + len(m.word)
+		if len(m.suggestions) == 0 {
+			if h.Unknown != nil {
+				h.Unknown(index, m.word)
+			}
+			continue
+		}
+		sb.WriteString(line[last:at])
+		sb.WriteString(m.suggestions[0])
+		last = from
+	}
+	sb.WriteString(line[last:])
+	return sb.String()
+}
+
+// offset of the first whole-word occurrence of word in s at or after from,
+// or -1
+func findWord(s, word string, from int) int {
+	for from <= len(s) {
+		i := strings.Index(s[from:], word)
+		if i < 0 {
+			return -1
+		}
+		at := from + i
+		before, _ := utf8.DecodeLastRuneInString(s[:at])
+		after, _ := utf8.DecodeRuneInString(s[at+len(word):])
+		if !isWordRune(before) && !isWordRune(after) {
+			return at
+		}
+		from = at + 1
+	}
+	return -1
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// misspellings of each input line from the output of "hunspell -a", in
+// which every line's results end with a blank line
+func parseHunspell(out []byte) ([][]misspelling, error) {
+	var lines [][]misspelling
+	var cur []misspelling
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			lines = append(lines, cur)
+			cur = nil
+		// "& word count offset: suggestion, ..." or, when it guessed the
+		// suggestions, "? ..."
+		case strings.HasPrefix(line, "& "), strings.HasPrefix(line, "? "):
+			head, list, ok := strings.Cut(line[2:], ": ")
+			fields := strings.Fields(head)
+			if !ok || len(fields) < 1 {
+				return nil, fmt.Errorf("unexpected spell checker output %q", line)
+			}
+			m := misspelling{word: fields[0]}
+			for _, s := range strings.Split(list, ", ") {
+				if s = strings.TrimSpace(s); s != "" {
+					m.suggestions = append(m.suggestions, s)
+				}
+			}
+			cur = append(cur, m)
+		// "# word offset": no suggestions
+		case strings.HasPrefix(line, "# "):
+			fields := strings.Fields(line[2:])
+			if len(fields) < 1 {
+				return nil, fmt.Errorf("unexpected spell checker output %q", line)
+			}
+			cur = append(cur, misspelling{word: fields[0]})
+		}
+		// "@(#)" is the version banner; "*", "+ root", and "-" mark
+		// accepted words
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read spell checker output: %w", err)
+	}
+	return lines, nil
+}
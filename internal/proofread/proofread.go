@@ -0,0 +1,95 @@
+package proofread
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// Checker returns the corrected text of each cue; corrected[i] is texts[i]
+// when nothing needed fixing
+type Checker interface {
+	Correct(ctx context.Context, texts []string) ([]string, error)
+}
+
+// Change is a cue whose text the checker corrected
+type Change struct {
+	Index     int // entry index in the file
+	StartTime time.Duration
+	EndTime   time.Duration
+	Original  string
+	Corrected string
+}
+
+// leading override blocks of an ASS dialogue, such as {\an8}
+var leadingTagsRegex = regexp.MustCompile(`^(\{[^}]*\})+`)
+
+// Proofread runs checker over the cues of f, writes the corrections back
+// into it, and returns the cues it changed
+func Proofread(ctx context.Context, checker Checker, f subtitle.File) ([]Change, error) {
+	entries := f.Subtitle().Entries
+	texts := make([]string, len(entries))
+	for i, entry := range entries {
+		texts[i] = entry.Text
+	}
+	corrected, err := checker.Correct(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(corrected) != len(texts) {
+		return nil, fmt.Errorf("checker returned %d cues, %d expected", len(corrected), len(texts))
+	}
+
+	_, isASS := f.(*subtitle.ASSFile)
+	var changes []Change
+	for i, text := range corrected {
+		if text == texts[i] {
+			continue
+		}
+		set := text
+		// ASS keeps a dialogue's leading tags and adds them back itself
+		if leading := leadingTagsRegex.FindString(texts[i]); isASS && leading != "" {
+			set = strings.TrimPrefix(text, leading)
+		}
+		if err := f.SetText(i, set); err != nil {
+			return nil, fmt.Errorf("failed to set text for entry %d: %w", i, err)
+		}
+		changes = append(changes, Change{
+			Index:     i,
+			StartTime: entries[i].StartTime,
+			EndTime:   entries[i].EndTime,
+			Original:  texts[i],
+			Corrected: text,
+		})
+	}
+	return changes, nil
+}
+
+// WriteDiff writes changes as a unified diff from the original file to the
+// corrected one, with a hunk per cue led by its number and timing
+func WriteDiff(w io.Writer, original, corrected string, changes []Change) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", original, corrected)
+	for _, c := range changes {
+		fmt.Fprintf(&sb, "@@ cue %d %s --> %s @@\n", c.Index+1, formatTime(c.StartTime), formatTime(c.EndTime))
+		for _, line := range strings.Split(c.Original, "\n") {
+			sb.WriteString("-" + line + "\n")
+		}
+		for _, line := range strings.Split(c.Corrected, "\n") {
+			sb.WriteString("+" + line + "\n")
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// H:MM:SS.mmm
+func formatTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
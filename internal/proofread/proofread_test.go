@@ -0,0 +1,170 @@
+package proofread
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/llm"
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+func TestParseHunspell(t *testing.T) {
+	out := "@(#) International Ispell Version 3.2.06 (but really Hunspell 1.7.2)\n" +
+		"*\n& teh 3 0: the, ten, tea\n*\n\n" +
+		"\n" +
+		"# Zorblax 4\n+ run\n\n"
+	got, err := parseHunspell([]byte(out))
+	if err != nil {
+		t.Fatalf("parseHunspell() error = %v", err)
+	}
+	want := [][]misspelling{
+		{{word: "teh", suggestions: []string{"the", "ten", "tea"}}},
+		nil,
+		{{word: "Zorblax"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseHunspell() = %+v, want %+v", got, want)
+	}
+}
+
+func TestHunspellFix(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		found []misspelling
+		want  string
+	}{
+		{
+			"first suggestion",
+			"I saw teh cat",
+			[]misspelling{{word: "teh", suggestions: []string{"the", "ten"}}},
+			"I saw the cat",
+		},
+		{
+			"whole words only",
+			"tehran teh",
+			[]misspelling{{word: "teh", suggestions: []string{"the"}}},
+			"tehran the",
+		},
+		{
+			"markup untouched",
+			"{\\i1}recieve{\\i0} <i>recieve</i>",
+			[]misspelling{
+				{word: "recieve", suggestions: []string{"receive"}},
+				{word: "recieve", suggestions: []string{"receive"}},
+			},
+			"{\\i1}receive{\\i0} <i>receive</i>",
+		},
+		{
+			"no suggestion kept",
+			"Zorblax is here",
+			[]misspelling{{word: "Zorblax"}},
+			"Zorblax is here",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unknown []string
+			h := &Hunspell{Unknown: func(index int, word string) { unknown = append(unknown, word) }}
+			mask := markupRegex.ReplaceAllStringFunc(tt.line, func(tag string) string {
+				return strings.Repeat(" ", len(tag))
+			})
+			if got := h.fix(0, tt.line, mask, tt.found); got != tt.want {
+				t.Errorf("fix() = %q, want %q", got, tt.want)
+			}
+			if tt.name == "no suggestion kept" && len(unknown) != 1 {
+				t.Errorf("unknown words = %v, want [Zorblax]", unknown)
+			}
+		})
+	}
+}
+
+func TestGrammar(t *testing.T) {
+	var prompts []string
+	gen := llm.GeneratorFunc(func(ctx context.Context, prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		if strings.Contains(prompt, `"index":0`) {
+			return `{"cues": [{"index": 0, "text": "They're here."}, {"index": 1, "text": "Its fine."}]}`, nil
+		}
+		// an index from another batch and a blank text are ignored
+		return "```json\n" + `{"cues": [{"index": 0, "text": "wrong"}, {"index": 2, "text": " "}]}` + "\n```", nil
+	})
+	g := &Grammar{Generator: gen, Language: "English", BatchSize: 2}
+	got, err := g.Correct(context.Background(), []string{"Their here.", "Its fine.", "Go home"})
+	if err != nil {
+		t.Fatalf("Correct() error = %v", err)
+	}
+	want := []string{"They're here.", "Its fine.", "Go home"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Correct() = %q, want %q", got, want)
+	}
+	if len(prompts) != 2 || !strings.Contains(prompts[0], "The subtitles are in English.") {
+		t.Errorf("prompts = %q", prompts)
+	}
+}
+
+// corrects a fixed word
+type replacer struct{ from, to string }
+
+func (r replacer) Correct(ctx context.Context, texts []string) ([]string, error) {
+	out := make([]string, len(texts))
+	for i, text := range texts {
+		out[i] = strings.ReplaceAll(text, r.from, r.to)
+	}
+	return out, nil
+}
+
+func TestProofread(t *testing.T) {
+	content := `[Script Info]
+ScriptType: v4.00+
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:00:01.00,0:00:02.50,Default,,0,0,0,,{\an8}teh sign
+Dialogue: 0,0:00:03.00,0:00:04.00,Default,,0,0,0,,Fine
+Dialogue: 0,0:01:05.00,0:01:07.00,Default,,0,0,0,,teh end\Nof teh show
+`
+	file, err := subtitle.Read(strings.NewReader(content), subtitle.FormatASS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := Proofread(context.Background(), replacer{"teh", "the"}, file)
+	if err != nil {
+		t.Fatalf("Proofread() error = %v", err)
+	}
+	if len(changes) != 2 || changes[0].Index != 0 || changes[1].Index != 2 {
+		t.Fatalf("changes = %+v", changes)
+	}
+
+	var out bytes.Buffer
+	if err := file.Encode(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`,,{\an8}the sign`, `,,the end\Nof the show`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	var diff bytes.Buffer
+	if err := WriteDiff(&diff, "show.ass", "show.proofread.ass", changes); err != nil {
+		t.Fatal(err)
+	}
+	want := `--- show.ass
++++ show.proofread.ass
+@@ cue 1 0:00:01.000 --> 0:00:02.500 @@
+-{\an8}teh sign
++{\an8}the sign
+@@ cue 3 0:01:05.000 --> 0:01:07.000 @@
+-teh end
+-of teh show
++the end
++of the show
+`
+	if diff.String() != want {
+		t.Errorf("WriteDiff() =\n%s\nwant\n%s", diff.String(), want)
+	}
+}
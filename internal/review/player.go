@@ -0,0 +1,33 @@
+package review
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"time"
+
+	ffmpegbin "github.com/mgpai22/lipi/internal/ffmpeg"
+)
+
+// plays cues from media with ffplay, audio only
+func FFplay(media string) (Player, error) {
+	path, err := ffmpegbin.FFplayPath()
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, start, end time.Duration) error {
+		cmd := exec.Command(path,
+			"-nodisp",
+			"-autoexit",
+			"-loglevel", "error",
+			"-ss", seconds(start),
+			"-t", seconds(end-start),
+			media,
+		)
+		return ffmpegbin.RunContext(ctx, cmd)
+	}, nil
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
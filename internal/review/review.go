@@ -0,0 +1,314 @@
+package review
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// plays the media between start and end
+type Player func(ctx context.Context, start, end time.Duration) error
+
+// returns a fresh translation for the cue at index
+type Retranslator func(ctx context.Context, index int) (string, error)
+
+type Options struct {
+	// Play, when set, enables the play command
+	Play Player
+	// Retranslate, when set, enables the retranslate command
+	Retranslate Retranslator
+}
+
+// Session steps through the cues of a subtitle file, reading commands from
+// in and printing to out. Edits are kept in memory until saved.
+type Session struct {
+	file  subtitle.File
+	path  string
+	in    *bufio.Scanner
+	out   io.Writer
+	opts  Options
+	cur   int
+	dirty bool
+}
+
+func NewSession(
+	file subtitle.File,
+	path string,
+	in io.Reader,
+	out io.Writer,
+	opts Options,
+) *Session {
+	return &Session{
+		file: file,
+		path: path,
+		in:   bufio.NewScanner(in),
+		out:  out,
+		opts: opts,
+	}
+}
+
+const help = `Commands:
+  n, <enter>        next cue
+  p                 previous cue
+  g <number>        go to cue
+  a                 play the cue's audio
+  e                 edit text (end with a line containing only ".")
+  t <start> <end>   set timing, e.g. t 00:01:02.500 00:01:04.000
+  s <offset>        shift start and end, e.g. s 250ms or s -1.5s
+  r                 re-request the cue's translation
+  w                 save
+  q                 quit
+  h                 show this help`
+
+// runs until the user quits or input ends. Unsaved edits are saved on quit
+// only after confirmation.
+func (s *Session) Run(ctx context.Context) error {
+	entries := s.entries()
+	if len(entries) == 0 {
+		return errors.New("subtitle file contains no entries")
+	}
+
+	s.printf("%d cues in %s; h for help\n", len(entries), s.path)
+	s.show()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line, ok := s.prompt("> ")
+		if !ok {
+			return s.quit()
+		}
+
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		arg = strings.TrimSpace(arg)
+		switch cmd {
+		case "", "n":
+			s.move(s.cur + 1)
+		case "p":
+			s.move(s.cur - 1)
+		case "g":
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				s.printf("usage: g <number>\n")
+				continue
+			}
+			s.move(n - 1)
+		case "a":
+			s.play(ctx)
+		case "e":
+			s.edit()
+		case "t":
+			s.setTiming(arg)
+		case "s":
+			s.shift(arg)
+		case "r":
+			s.retranslate(ctx)
+		case "w":
+			if err := s.save(); err != nil {
+				return err
+			}
+		case "q":
+			return s.quit()
+		case "h", "?":
+			s.printf("%s\n", help)
+		default:
+			s.printf("unknown command %q; h for help\n", cmd)
+		}
+	}
+}
+
+func (s *Session) entries() []subtitle.Entry {
+	return s.file.Subtitle().Entries
+}
+
+func (s *Session) printf(format string, args ...any) {
+	_, _ = fmt.Fprintf(s.out, format, args...)
+}
+
+func (s *Session) prompt(p string) (string, bool) {
+	s.printf("%s", p)
+	if !s.in.Scan() {
+		return "", false
+	}
+	return s.in.Text(), true
+}
+
+func (s *Session) show() {
+	entries := s.entries()
+	e := entries[s.cur]
+	s.printf("\n[%d/%d] %s --> %s\n%s\n",
+		s.cur+1,
+		len(entries),
+		FormatTimestamp(e.StartTime),
+		FormatTimestamp(e.EndTime),
+		e.Text,
+	)
+}
+
+func (s *Session) move(i int) {
+	if i < 0 || i >= len(s.entries()) {
+		s.printf("no cue %d\n", i+1)
+		return
+	}
+	s.cur = i
+	s.show()
+}
+
+func (s *Session) play(ctx context.Context) {
+	if s.opts.Play == nil {
+		s.printf("playback needs --media\n")
+		return
+	}
+	e := s.entries()[s.cur]
+	if err := s.opts.Play(ctx, e.StartTime, e.EndTime); err != nil {
+		s.printf("playback failed: %v\n", err)
+	}
+}
+
+func (s *Session) edit() {
+	s.printf("new text, end with a line containing only \".\":\n")
+	var lines []string
+	for {
+		line, ok := s.prompt("")
+		if !ok || line == "." {
+			break
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		s.printf("unchanged\n")
+		return
+	}
+	s.setText(strings.Join(lines, "\n"))
+}
+
+func (s *Session) setText(text string) {
+	if err := s.file.SetText(s.cur, text); err != nil {
+		s.printf("edit failed: %v\n", err)
+		return
+	}
+	s.dirty = true
+	s.show()
+}
+
+func (s *Session) setTiming(arg string) {
+	fields := strings.Fields(arg)
+	if len(fields) != 2 {
+		s.printf("usage: t <start> <end>\n")
+		return
+	}
+	start, err := ParseTimestamp(fields[0])
+	if err != nil {
+		s.printf("%v\n", err)
+		return
+	}
+	end, err := ParseTimestamp(fields[1])
+	if err != nil {
+		s.printf("%v\n", err)
+		return
+	}
+	s.retime(start, end)
+}
+
+func (s *Session) shift(arg string) {
+	offset, err := time.ParseDuration(arg)
+	if err != nil {
+		s.printf("usage: s <offset>, e.g. s 250ms or s -1.5s\n")
+		return
+	}
+	e := s.entries()[s.cur]
+	s.retime(e.StartTime+offset, e.EndTime+offset)
+}
+
+func (s *Session) retime(start, end time.Duration) {
+	if start < 0 || end <= start {
+		s.printf("invalid timing: end must be after start, and start not negative\n")
+		return
+	}
+	if err := s.file.SetTiming(s.cur, start, end); err != nil {
+		s.printf("retiming failed: %v\n", err)
+		return
+	}
+	s.dirty = true
+	s.show()
+}
+
+func (s *Session) retranslate(ctx context.Context) {
+	if s.opts.Retranslate == nil {
+		s.printf("retranslation needs --target-language\n")
+		return
+	}
+	text, err := s.opts.Retranslate(ctx, s.cur)
+	if err != nil {
+		s.printf("retranslation failed: %v\n", err)
+		return
+	}
+	s.printf("suggested:\n%s\n", text)
+	if answer, _ := s.prompt("accept? [y/N] "); strings.EqualFold(strings.TrimSpace(answer), "y") {
+		s.setText(text)
+	}
+}
+
+func (s *Session) save() error {
+	if err := s.file.Write(s.path); err != nil {
+		return fmt.Errorf("failed to save %s: %w", s.path, err)
+	}
+	s.dirty = false
+	s.printf("saved %s\n", s.path)
+	return nil
+}
+
+func (s *Session) quit() error {
+	if !s.dirty {
+		return nil
+	}
+	answer, ok := s.prompt("save changes? [Y/n] ")
+	if ok && strings.EqualFold(strings.TrimSpace(answer), "n") {
+		return nil
+	}
+	return s.save()
+}
+
+// parses HH:MM:SS.mmm, MM:SS.mmm, or plain seconds; a comma may separate
+// the milliseconds as in SRT
+func ParseTimestamp(value string) (time.Duration, error) {
+	v := strings.ReplaceAll(strings.TrimSpace(value), ",", ".")
+	parts := strings.Split(v, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	var whole int
+	for _, part := range parts[:len(parts)-1] {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid timestamp %q", value)
+		}
+		whole = whole*60 + n
+	}
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	d := time.Duration(whole)*time.Minute +
+		time.Duration(seconds*float64(time.Second))
+	return d.Round(time.Millisecond), nil
+}
+
+// formats d as HH:MM:SS.mmm
+func FormatTimestamp(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d:%02d.%03d",
+		int(d.Hours()),
+		int(d.Minutes())%60,
+		int(d.Seconds())%60,
+		int(d.Milliseconds())%1000,
+	)
+}
@@ -0,0 +1,123 @@
+package review
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"00:01:02.500", time.Minute + 2500*time.Millisecond, false},
+		{"01:00:00,250", time.Hour + 250*time.Millisecond, false},
+		{"2:03.1", 2*time.Minute + 3100*time.Millisecond, false},
+		{"4.25", 4250 * time.Millisecond, false},
+		{"1:2:3:4", 0, true},
+		{"-1", 0, true},
+		{"ab:00", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseTimestamp(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTimestamp(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTimestamp(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.srt")
+	content := "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n" +
+		"2\n00:00:03,000 --> 00:00:04,000\nWrold\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := subtitle.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var played []time.Duration
+	opts := Options{
+		Play: func(ctx context.Context, start, end time.Duration) error {
+			played = append(played, start, end)
+			return nil
+		},
+		Retranslate: func(ctx context.Context, index int) (string, error) {
+			return "Bonjour", nil
+		},
+	}
+	// fix cue 2's text and shift it, retranslate cue 1, then quit and save
+	input := strings.Join([]string{
+		"n", "a", "e", "World", ".", "s 500ms",
+		"g 1", "r", "y",
+		"q", "y",
+	}, "\n") + "\n"
+
+	var out bytes.Buffer
+	session := NewSession(file, path, strings.NewReader(input), &out, opts)
+	if err := session.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v\noutput:\n%s", err, out.String())
+	}
+
+	if len(played) != 2 || played[0] != 3*time.Second || played[1] != 4*time.Second {
+		t.Errorf("played %v, want cue 2 (3s-4s)", played)
+	}
+
+	saved, err := subtitle.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := saved.Subtitle().Entries
+	if entries[0].Text != "Bonjour" {
+		t.Errorf("cue 1 = %q, want retranslated text", entries[0].Text)
+	}
+	if entries[1].Text != "World" {
+		t.Errorf("cue 2 = %q, want edited text", entries[1].Text)
+	}
+	if entries[1].StartTime != 3500*time.Millisecond || entries[1].EndTime != 4500*time.Millisecond {
+		t.Errorf("cue 2 timing = %v --> %v, want shifted by 500ms", entries[1].StartTime, entries[1].EndTime)
+	}
+}
+
+func TestSessionQuitWithoutSaving(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.srt")
+	content := "1\n00:00:01,000 --> 00:00:02,000\nHello\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := subtitle.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := "e\nChanged\n.\nq\nn\n"
+	var out bytes.Buffer
+	session := NewSession(file, path, strings.NewReader(input), &out, Options{})
+	if err := session.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("file changed after declining to save:\n%s", data)
+	}
+}
@@ -0,0 +1,325 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	ffmpegbin "github.com/mgpai22/lipi/internal/ffmpeg"
+	"github.com/mgpai22/lipi/internal/jobs"
+	"github.com/mgpai22/lipi/internal/source"
+	"gopkg.in/yaml.v3"
+)
+
+// Token is an API token of the HTTP and gRPC APIs with its daily quota.
+// Quotas count the jobs a token submitted since midnight UTC and are
+// checked when a job is submitted, so the job that crosses a limit still
+// runs to the end. An uploaded file counts from its submission.
+type Token struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+	// DailyAudioMinutes caps the minutes of media transcribed; 0 is
+	// unlimited
+	DailyAudioMinutes float64 `yaml:"daily_audio_minutes"`
+	// DailyTokens caps the provider tokens spent, input plus output; 0 is
+	// unlimited
+	DailyTokens int64 `yaml:"daily_tokens"`
+}
+
+// name of the cookie the web UI signs in with
+const sessionCookie = "lipi_token"
+
+// errQuota is returned for jobs submitted after a token's quota is used up
+var errQuota = errors.New("daily quota exceeded")
+
+// LoadTokens reads the API tokens file:
+//
+//	tokens:
+//	  - name: alice
+//	    token: 3f6c...
+//	    daily_audio_minutes: 120
+//	    daily_tokens: 2000000
+func LoadTokens(path string) ([]Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokens file: %w", err)
+	}
+	var file struct {
+		Tokens []Token `yaml:"tokens"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tokens file %s: %w", path, err)
+	}
+	if len(file.Tokens) == 0 {
+		return nil, fmt.Errorf("tokens file %s lists no tokens", path)
+	}
+
+	names := make(map[string]bool)
+	secrets := make(map[string]bool)
+	for i, tok := range file.Tokens {
+		switch {
+		case tok.Name == "":
+			return nil, fmt.Errorf("token %d in %s has no name", i+1, path)
+		case names[tok.Name]:
+			return nil, fmt.Errorf("token name %q appears twice in %s", tok.Name, path)
+		case len(tok.Token) < 16:
+			return nil, fmt.Errorf("token %q in %s must be at least 16 characters", tok.Name, path)
+		case strings.ContainsFunc(tok.Token, unsafeTokenRune):
+			return nil, fmt.Errorf("token %q in %s may only use printable ASCII without spaces, quotes, commas, semicolons, or backslashes", tok.Name, path)
+		case secrets[tok.Token]:
+			return nil, fmt.Errorf("token %q in %s reuses another token's secret", tok.Name, path)
+		case tok.DailyAudioMinutes < 0 || tok.DailyTokens < 0:
+			return nil, fmt.Errorf("token %q in %s has a negative quota", tok.Name, path)
+		}
+		names[tok.Name] = true
+		secrets[tok.Token] = true
+	}
+	return file.Tokens, nil
+}
+
+// reports runes a token cannot use, as they do not survive the web UI's
+// cookie
+func unsafeTokenRune(r rune) bool {
+	return r <= ' ' || r >= 0x7f || strings.ContainsRune("\"\\,;", r)
+}
+
+// indexes tokens by the hash of their secret, so looking one up does not
+// compare secrets byte by byte
+func indexTokens(tokens []Token) map[[32]byte]*Token {
+	index := make(map[[32]byte]*Token, len(tokens))
+	for i := range tokens {
+		index[sha256.Sum256([]byte(tokens[i].Token))] = &tokens[i]
+	}
+	return index
+}
+
+// the token behind secret. A nil token with ok means unrestricted access:
+// the API has no tokens, or secret is the worker token.
+func (s *Server) authorize(secret string) (tok *Token, ok bool) {
+	if len(s.tokens) == 0 {
+		return nil, true
+	}
+	if secret == "" {
+		return nil, false
+	}
+	if s.cfg.WorkerToken != "" &&
+		subtle.ConstantTimeCompare([]byte(secret), []byte(s.cfg.WorkerToken)) == 1 {
+		return nil, true
+	}
+	tok, ok = s.tokens[sha256.Sum256([]byte(secret))]
+	return tok, ok
+}
+
+// the secret of a request: its bearer token, or the web UI's cookie
+func requestSecret(r *http.Request) string {
+	if secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return secret
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+type tokenKey struct{}
+
+// the token a request was authorized with, nil for unrestricted access
+func tokenFrom(ctx context.Context) *Token {
+	tok, _ := ctx.Value(tokenKey{}).(*Token)
+	return tok
+}
+
+// rejects requests without a valid API token when the server has tokens
+func (s *Server) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tok, ok := s.authorize(requestSecret(r))
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="lipi"`)
+			writeError(w, http.StatusUnauthorized, "a valid API token is required")
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), tokenKey{}, tok)))
+	}
+}
+
+// reports whether tok may see job: its own jobs, or all of them when
+// unrestricted
+func visible(tok *Token, job *jobs.Job) bool {
+	return tok == nil || job.Owner == tok.Name
+}
+
+// what a token used today, as returned by GET /v1/usage
+type usageResponse struct {
+	Name              string  `json:"name"`
+	Day               string  `json:"day"`
+	Jobs              int     `json:"jobs"`
+	AudioMinutes      float64 `json:"audio_minutes"`
+	Tokens            int64   `json:"tokens"`
+	DailyAudioMinutes float64 `json:"daily_audio_minutes,omitempty"`
+	DailyTokens       int64   `json:"daily_tokens,omitempty"`
+}
+
+// sums the jobs tok submitted since midnight UTC
+func (s *Server) dailyUsage(tok *Token) (usageResponse, error) {
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	resp := usageResponse{
+		Name:              tok.Name,
+		Day:               day.Format(time.DateOnly),
+		DailyAudioMinutes: tok.DailyAudioMinutes,
+		DailyTokens:       tok.DailyTokens,
+	}
+	all, err := s.store.List()
+	if err != nil {
+		return resp, err
+	}
+	s.reservedMu.Lock()
+	defer s.reservedMu.Unlock()
+	for _, job := range all {
+		if job.Status.Done() {
+			delete(s.reserved, job.ID)
+		}
+		if job.Owner != tok.Name || job.CreatedAt.Before(day) {
+			continue
+		}
+		resp.Jobs++
+		resp.AudioMinutes += job.MediaSeconds / 60
+		if job.MediaSeconds == 0 {
+			resp.AudioMinutes += s.reserved[job.ID] / 60
+		}
+		resp.Tokens += job.Usage.InputTokens + job.Usage.OutputTokens
+	}
+	return resp, nil
+}
+
+// returns an errQuota error once tok has used up a daily limit
+func (s *Server) checkQuota(tok *Token) error {
+	if tok == nil || (tok.DailyAudioMinutes == 0 && tok.DailyTokens == 0) {
+		return nil
+	}
+	used, err := s.dailyUsage(tok)
+	if err != nil {
+		return err
+	}
+	if tok.DailyAudioMinutes > 0 && used.AudioMinutes >= tok.DailyAudioMinutes {
+		return fmt.Errorf(
+			"%w: %.1f of %g audio minutes used today",
+			errQuota,
+			used.AudioMinutes,
+			tok.DailyAudioMinutes,
+		)
+	}
+	if tok.DailyTokens > 0 && used.Tokens >= tok.DailyTokens {
+		return fmt.Errorf(
+			"%w: %d of %d tokens used today",
+			errQuota,
+			used.Tokens,
+			tok.DailyTokens,
+		)
+	}
+	return nil
+}
+
+// submits job for tok once its quota allows. The check and the submit
+// happen under tok's lock, and the job reserves the length of an uploaded
+// file until it records its own, so concurrent submissions cannot all
+// pass the check.
+func (s *Server) submit(ctx context.Context, tok *Token, job *jobs.Job) error {
+	if tok == nil || (tok.DailyAudioMinutes == 0 && tok.DailyTokens == 0) {
+		return s.queue.Submit(job)
+	}
+	var seconds float64
+	if tok.DailyAudioMinutes > 0 && !source.IsURL(job.Source) {
+		if length, err := s.mediaLength(ctx, job.Source); err == nil {
+			seconds = length.Seconds()
+		}
+	}
+
+	mu := s.quotaMu[tok.Name]
+	mu.Lock()
+	defer mu.Unlock()
+	if err := s.checkQuota(tok); err != nil {
+		return err
+	}
+	s.reservedMu.Lock()
+	s.reserved[job.ID] = seconds
+	s.reservedMu.Unlock()
+	if err := s.queue.Submit(job); err != nil {
+		s.reservedMu.Lock()
+		delete(s.reserved, job.ID)
+		s.reservedMu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// the length of an uploaded file when ffprobe is installed; it is never
+// downloaded for this, so without it jobs count once they finish
+func probeLength(ctx context.Context, path string) (time.Duration, error) {
+	if _, _, ok := ffmpegbin.Locate(); !ok {
+		return 0, errors.New("ffprobe is not installed")
+	}
+	result, err := ffmpegbin.Probe(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	return result.Duration, nil
+}
+
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	tok := tokenFrom(r.Context())
+	if tok == nil {
+		writeError(w, http.StatusNotFound, "usage is only tracked for API tokens")
+		return
+	}
+	used, err := s.dailyUsage(tok)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, used)
+}
+
+// signs the web UI in: POST {"token": "..."} sets the session cookie,
+// DELETE clears it
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	cookie := &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	}
+	if r.Method == http.MethodDelete {
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if len(s.tokens) == 0 {
+		writeError(w, http.StatusNotFound, "this server does not use API tokens")
+		return
+	}
+	if _, ok := s.authorize(strings.TrimSpace(req.Token)); !ok {
+		writeError(w, http.StatusUnauthorized, "unknown API token")
+		return
+	}
+	cookie.Value = strings.TrimSpace(req.Token)
+	cookie.MaxAge = int((30 * 24 * time.Hour).Seconds())
+	http.SetCookie(w, cookie)
+	w.WriteHeader(http.StatusNoContent)
+}
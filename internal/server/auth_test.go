@@ -0,0 +1,279 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	lipiv1 "github.com/mgpai22/lipi/api/lipi/v1"
+	"github.com/mgpai22/lipi/internal/jobs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var testTokens = []Token{
+	{Name: "alice", Token: "alice-0123456789abcdef", DailyAudioMinutes: 10},
+	{Name: "bob", Token: "bob-0123456789abcdef"},
+}
+
+func newAuthServer(t *testing.T) (*httptest.Server, *Server, *jobs.FileStore) {
+	t.Helper()
+	store, queue := newTestQueue(t)
+	srv := New(
+		Config{MaxUploadBytes: 1024, WorkerToken: "worker-secret", Tokens: testTokens},
+		store,
+		queue,
+	)
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts, srv, store
+}
+
+// sends req with secret as its bearer token
+func doAs(t *testing.T, req *http.Request, secret string) *http.Response {
+	t.Helper()
+	if secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s error: %v", req.Method, req.URL, err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func submitAs(t *testing.T, url, secret string) *http.Response {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "episode.mp3")
+	_, _ = fw.Write([]byte("audio"))
+	_ = mw.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, url+"/v1/jobs", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return doAs(t, req, secret)
+}
+
+func TestLoadTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "valid",
+			content: "tokens:\n  - name: alice\n    token: 0123456789abcdef\n    daily_audio_minutes: 60\n",
+		},
+		{name: "empty", content: "tokens: []\n", wantErr: "lists no tokens"},
+		{name: "no name", content: "tokens:\n  - token: 0123456789abcdef\n", wantErr: "has no name"},
+		{name: "short", content: "tokens:\n  - name: a\n    token: short\n", wantErr: "at least 16"},
+		{name: "unsafe", content: "tokens:\n  - name: a\n    token: \"0123456789 abcdef\"\n", wantErr: "printable ASCII"},
+		{
+			name:    "duplicate name",
+			content: "tokens:\n  - name: a\n    token: 0123456789abcdef\n  - name: a\n    token: fedcba9876543210\n",
+			wantErr: "appears twice",
+		},
+		{
+			name:    "reused secret",
+			content: "tokens:\n  - name: a\n    token: 0123456789abcdef\n  - name: b\n    token: 0123456789abcdef\n",
+			wantErr: "reuses",
+		},
+		{
+			name:    "negative quota",
+			content: "tokens:\n  - name: a\n    token: 0123456789abcdef\n    daily_tokens: -1\n",
+			wantErr: "negative quota",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tokens.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			tokens, err := LoadTokens(path)
+			if tt.wantErr == "" {
+				if err != nil || len(tokens) != 1 || tokens[0].DailyAudioMinutes != 60 {
+					t.Fatalf("LoadTokens = %+v, %v", tokens, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTokenAuthAndIsolation(t *testing.T) {
+	ts, _, _ := newAuthServer(t)
+
+	if resp := submitAs(t, ts.URL, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", resp.StatusCode)
+	}
+	if resp := submitAs(t, ts.URL, "not-a-real-token-at-all"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an unknown token, got %d", resp.StatusCode)
+	}
+
+	resp := submitAs(t, ts.URL, testTokens[0].Token)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+	var job jobResponse
+	_ = json.NewDecoder(resp.Body).Decode(&job)
+
+	list := func(secret string) int {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/jobs", nil)
+		var body struct {
+			Jobs []jobResponse `json:"jobs"`
+		}
+		_ = json.NewDecoder(doAs(t, req, secret).Body).Decode(&body)
+		return len(body.Jobs)
+	}
+	if n := list(testTokens[0].Token); n != 1 {
+		t.Errorf("expected alice to see her job, got %d jobs", n)
+	}
+	if n := list(testTokens[1].Token); n != 0 {
+		t.Errorf("expected bob to see no jobs, got %d", n)
+	}
+	if n := list("worker-secret"); n != 1 {
+		t.Errorf("expected the worker token to see every job, got %d", n)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/jobs/"+job.ID, nil)
+	if resp := doAs(t, req, testTokens[1].Token); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for another token's job, got %d", resp.StatusCode)
+	}
+}
+
+func TestDailyQuota(t *testing.T) {
+	ts, _, store := newAuthServer(t)
+
+	// a job of today that used up alice's 10 minutes, and one of yesterday
+	// that does not count
+	for _, created := range []time.Time{time.Now(), time.Now().Add(-48 * time.Hour)} {
+		err := store.Save(&jobs.Job{
+			ID:           jobs.NewID(),
+			Status:       jobs.StatusSucceeded,
+			Owner:        "alice",
+			MediaSeconds: 600,
+			CreatedAt:    created,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp := submitAs(t, ts.URL, testTokens[0].Token)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 over quota, got %d", resp.StatusCode)
+	}
+	if resp := submitAs(t, ts.URL, testTokens[1].Token); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected bob's unlimited token to submit, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/usage", nil)
+	var used usageResponse
+	_ = json.NewDecoder(doAs(t, req, testTokens[0].Token).Body).Decode(&used)
+	if used.Name != "alice" || used.Jobs != 1 || used.AudioMinutes != 10 || used.DailyAudioMinutes != 10 {
+		t.Errorf("unexpected usage %+v", used)
+	}
+}
+
+func TestQuotaConcurrentSubmissions(t *testing.T) {
+	store, err := jobs.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a queue that is not run, so the jobs stay unfinished
+	queue := jobs.NewQueue(store, nil, 1, 10)
+	srv := New(Config{MaxUploadBytes: 1024, Tokens: testTokens}, store, queue)
+	srv.mediaLength = func(context.Context, string) (time.Duration, error) {
+		return 6 * time.Minute, nil
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+
+	// alice's 10 minutes admit a job at 0 and at 6 minutes used
+	statuses := make(chan int, 6)
+	var wg sync.WaitGroup
+	for range cap(statuses) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- submitAs(t, ts.URL, testTokens[0].Token).StatusCode
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := map[int]int{}
+	for code := range statuses {
+		counts[code]++
+	}
+	if counts[http.StatusAccepted] != 2 || counts[http.StatusTooManyRequests] != 4 {
+		t.Errorf("expected 2 accepted and 4 over quota, got %v", counts)
+	}
+	all, _ := store.List()
+	if len(all) != 2 {
+		t.Errorf("expected only the 2 accepted jobs saved, got %d", len(all))
+	}
+}
+
+func TestSessionCookie(t *testing.T) {
+	ts, _, _ := newAuthServer(t)
+
+	post := func(token string) *http.Response {
+		body := strings.NewReader(`{"token": "` + token + `"}`)
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/session", body)
+		return doAs(t, req, "")
+	}
+	if resp := post("wrong-token-wrong-token"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an unknown token, got %d", resp.StatusCode)
+	}
+
+	resp := post(testTokens[1].Token)
+	if resp.StatusCode != http.StatusNoContent || len(resp.Cookies()) != 1 {
+		t.Fatalf("expected a session cookie, got %d %v", resp.StatusCode, resp.Cookies())
+	}
+	cookie := resp.Cookies()[0]
+	if !cookie.HttpOnly || cookie.Value != testTokens[1].Token {
+		t.Errorf("unexpected cookie %+v", cookie)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/jobs", nil)
+	req.AddCookie(cookie)
+	if resp := doAs(t, req, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the cookie to authorize, got %d", resp.StatusCode)
+	}
+}
+
+func TestGRPCTokenAuth(t *testing.T) {
+	_, srv, _ := newAuthServer(t)
+	client := dialGRPC(t, srv)
+
+	_, err := client.GetJob(context.Background(), &lipiv1.GetJobRequest{Id: jobs.NewID()})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(
+		context.Background(),
+		"authorization", "Bearer "+testTokens[1].Token,
+	)
+	_, err = client.GetJob(ctx, &lipiv1.GetJobRequest{Id: jobs.NewID()})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound with a token, got %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/jobs"
+	"github.com/mgpai22/lipi/internal/source"
+	"github.com/mgpai22/lipi/internal/usage"
+)
+
+// WorkerClient pulls jobs from a server's worker endpoints. Reports about
+// a job return jobs.ErrCancelled once it is cancelled, and jobs.ErrState
+// when the server no longer considers it this worker's.
+type WorkerClient struct {
+	BaseURL string // e.g. http://captions.internal:8080
+	Token   string
+	Worker  string // name of this worker in the server's job list
+	HTTP    *http.Client
+}
+
+// claims the next queued job, or returns nil when none is waiting
+func (c *WorkerClient) Claim(ctx context.Context) (*WorkerJob, error) {
+	resp, err := c.post(ctx, "/v1/worker/claim", workerRequest{Worker: c.Worker})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if err := responseError(resp); err != nil {
+		return nil, err
+	}
+	var job WorkerJob
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		return nil, fmt.Errorf("failed to decode claimed job: %w", err)
+	}
+	return &job, nil
+}
+
+// renews the lease on a job, reporting its chunk progress
+func (c *WorkerClient) Heartbeat(ctx context.Context, id string, done, total int) error {
+	return c.report(ctx, "/v1/worker/jobs/"+id+"/heartbeat", workerRequest{
+		Worker:     c.Worker,
+		ChunksDone: done,
+		Chunks:     total,
+	})
+}
+
+// reports that a job failed with err, classified by kind
+func (c *WorkerClient) Fail(ctx context.Context, id string, err error, kind string, used usage.Usage) error {
+	return c.report(ctx, "/v1/worker/jobs/"+id+"/fail", workerRequest{
+		Worker:    c.Worker,
+		Error:     err.Error(),
+		ErrorKind: kind,
+		Usage:     used,
+	})
+}
+
+// uploads the subtitle at result.Output, a local path, as a job's result
+func (c *WorkerClient) Complete(ctx context.Context, id string, result *jobs.Result) error {
+	path := result.Output
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open subtitle: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	usageJSON, err := json.Marshal(result.Usage)
+	if err != nil {
+		return err
+	}
+
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		err := form.WriteField("worker", c.Worker)
+		if err == nil {
+			err = form.WriteField("entries", strconv.Itoa(result.Entries))
+		}
+		if err == nil {
+			err = form.WriteField("media_seconds", strconv.FormatFloat(result.MediaSeconds, 'f', -1, 64))
+		}
+		if err == nil {
+			err = form.WriteField("usage", string(usageJSON))
+		}
+		if err == nil {
+			var part io.Writer
+			if part, err = form.CreateFormFile("subtitle", filepath.Base(path)); err == nil {
+				_, err = io.Copy(part, file)
+			}
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		_ = writer.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/v1/worker/jobs/"+id+"/complete"), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return responseError(resp)
+}
+
+// the media of a claimed job: its URL, or the upload downloaded into dir
+func (c *WorkerClient) Media(ctx context.Context, job *WorkerJob, dir string) (string, error) {
+	if source.IsURL(job.MediaURL) {
+		return job.MediaURL, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url(job.MediaURL), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err := responseError(resp); err != nil {
+		return "", fmt.Errorf("failed to download media: %w", err)
+	}
+
+	name := filepath.Base(job.Name)
+	if name == "." || name == string(filepath.Separator) || name == "" {
+		return "", errors.New("claimed job has no media name")
+	}
+	path := filepath.Join(dir, name)
+	out, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to download media: %w", err)
+	}
+	defer func() { _ = out.Close() }()
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to download media: %w", err)
+	}
+	return path, nil
+}
+
+func (c *WorkerClient) report(ctx context.Context, path string, body workerRequest) error {
+	resp, err := c.post(ctx, path, body)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return responseError(resp)
+}
+
+func (c *WorkerClient) post(ctx context.Context, path string, body workerRequest) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url(path), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req)
+}
+
+func (c *WorkerClient) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach server: %w", err)
+	}
+	return resp, nil
+}
+
+func (c *WorkerClient) url(path string) string {
+	return strings.TrimSuffix(c.BaseURL, "/") + path
+}
+
+// the error a server response stands for, nil for success
+func responseError(resp *http.Response) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body)
+	msg := body.Error
+	if msg == "" {
+		msg = resp.Status
+	}
+	switch resp.StatusCode {
+	case http.StatusGone:
+		return jobs.ErrCancelled
+	case http.StatusConflict:
+		return fmt.Errorf("%w: %s", jobs.ErrState, msg)
+	case http.StatusUnauthorized:
+		return errs.Wrap(errs.KindAuth, fmt.Errorf("server rejected the worker token: %s", msg))
+	default:
+		return fmt.Errorf("server returned %d: %s", resp.StatusCode, msg)
+	}
+}
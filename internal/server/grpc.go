@@ -0,0 +1,338 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	lipiv1 "github.com/mgpai22/lipi/api/lipi/v1"
+	"github.com/mgpai22/lipi/internal/jobs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// how often StreamProgress looks for changes to the job it follows
+var progressInterval = 500 * time.Millisecond
+
+// the JobService of api/lipi/v1, over the same store and queue as the
+// REST API
+type grpcService struct {
+	lipiv1.UnimplementedJobServiceServer
+	s *Server
+}
+
+// GRPCServer returns a gRPC server with the JobService registered, and
+// server reflection for tools such as grpcurl. With API tokens, calls need
+// one as "authorization: Bearer <token>" metadata.
+func (s *Server) GRPCServer() *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(s.unaryAuth),
+		grpc.StreamInterceptor(s.streamAuth),
+	)
+	lipiv1.RegisterJobServiceServer(srv, &grpcService{s: s})
+	reflection.Register(srv)
+	return srv
+}
+
+// serves the gRPC API on addr until ctx is cancelled, then gives open
+// calls the REST API's grace period to finish
+func (s *Server) ListenAndServeGRPC(ctx context.Context, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	srv := s.GRPCServer()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- srv.Serve(lis)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(10 * time.Second):
+			srv.Stop()
+		}
+		return nil
+	}
+}
+
+// adds the token of a call's metadata to ctx, as requireToken does for
+// HTTP requests
+func (s *Server) authContext(ctx context.Context) (context.Context, error) {
+	var secret string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			secret, _ = strings.CutPrefix(values[0], "Bearer ")
+		}
+	}
+	tok, ok := s.authorize(secret)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "a valid API token is required")
+	}
+	return context.WithValue(ctx, tokenKey{}, tok), nil
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamAuth(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authContext(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authStream{ServerStream: stream, ctx: ctx})
+}
+
+// a server stream carrying the context authContext built
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a *authStream) Context() context.Context {
+	return a.ctx
+}
+
+func (g *grpcService) SubmitJob(stream lipiv1.JobService_SubmitJobServer) error {
+	tok := tokenFrom(stream.Context())
+	if err := g.s.checkQuota(tok); err != nil {
+		if errors.Is(err, errQuota) {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "a job spec is required")
+	}
+	if err != nil {
+		return err
+	}
+	spec := first.GetSpec()
+	if spec == nil {
+		return status.Error(codes.InvalidArgument, "the first message must carry the job spec")
+	}
+
+	job := &jobs.Job{ID: jobs.NewID()}
+	job.Dir = g.s.store.JobDir(job.ID)
+	if tok != nil {
+		job.Owner = tok.Name
+	}
+	if err := g.readSpec(stream.Context(), spec, &chunkReader{stream: stream}, job); err != nil {
+		_ = os.RemoveAll(job.Dir)
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return status.Error(codes.ResourceExhausted, "upload too large")
+		}
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := g.s.submit(stream.Context(), tok, job); err != nil {
+		if errors.Is(err, errQuota) {
+			_ = os.RemoveAll(job.Dir)
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		if errors.Is(err, jobs.ErrQueueFull) || errors.Is(err, jobs.ErrDraining) {
+			_ = os.RemoveAll(job.Dir)
+			return status.Error(codes.Unavailable, err.Error())
+		}
+		_ = os.RemoveAll(job.Dir)
+		return status.Error(codes.Internal, err.Error())
+	}
+	return stream.SendAndClose(newProtoJob(job))
+}
+
+// fills in job from a submission, streaming uploaded media from media
+func (g *grpcService) readSpec(ctx context.Context, spec *lipiv1.JobSpec, media io.Reader, job *jobs.Job) error {
+	opts := spec.GetOptions()
+	if value := strings.TrimSpace(opts.GetFormat()); value != "" {
+		format, err := parseFormat(value)
+		if err != nil {
+			return err
+		}
+		job.Options.Format = format
+	}
+	job.Options.Language = strings.TrimSpace(opts.GetLanguage())
+	job.Options.TranscriptLanguage = strings.TrimSpace(opts.GetTranscriptLanguage())
+
+	switch m := spec.GetMedia().(type) {
+	case *lipiv1.JobSpec_Url:
+		if err := g.s.checkURL(ctx, m.Url); err != nil {
+			return err
+		}
+		if n, err := media.Read(make([]byte, 1)); n > 0 || (err != nil && err != io.EOF) {
+			if err != nil {
+				return err
+			}
+			return errors.New("media chunks cannot be sent with a url")
+		}
+		job.Name = m.Url
+		job.Source = m.Url
+		return nil
+	case *lipiv1.JobSpec_FileName:
+		return g.s.saveUpload(m.FileName, media, job)
+	default:
+		return errors.New("a url or file_name is required")
+	}
+}
+
+// reads the chunk messages following a job spec
+type chunkReader struct {
+	stream lipiv1.JobService_SubmitJobServer
+	buf    []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		if req.GetSpec() != nil {
+			return 0, errors.New("only the first message may carry the job spec")
+		}
+		r.buf = req.GetChunk()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (g *grpcService) GetJob(ctx context.Context, req *lipiv1.GetJobRequest) (*lipiv1.Job, error) {
+	job, err := g.lookup(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return newProtoJob(job), nil
+}
+
+func (g *grpcService) StreamProgress(req *lipiv1.StreamProgressRequest, stream lipiv1.JobService_StreamProgressServer) error {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	var last *lipiv1.Job
+	for {
+		job, err := g.lookup(stream.Context(), req.GetId())
+		if err != nil {
+			return err
+		}
+		if msg := newProtoJob(job); !proto.Equal(msg, last) {
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+			last = msg
+		}
+		if job.Status.Done() {
+			return nil
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *grpcService) GetResult(ctx context.Context, req *lipiv1.GetResultRequest) (*lipiv1.Result, error) {
+	job, err := g.lookup(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != jobs.StatusSucceeded || job.Output == "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "job is %s; subtitle not available", job.Status)
+	}
+
+	data, err := os.ReadFile(job.Output)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read subtitle: %v", err)
+	}
+	return &lipiv1.Result{
+		Job:      newProtoJob(job),
+		FileName: filepath.Base(job.Output),
+		Subtitle: data,
+	}, nil
+}
+
+// the job with id, hiding the files of batch runs and other tokens' jobs
+// as the REST API does
+func (g *grpcService) lookup(ctx context.Context, id string) (*jobs.Job, error) {
+	job, err := g.s.store.Get(id)
+	if err == nil && (job.Batch != "" || !visible(tokenFrom(ctx), job)) {
+		err = jobs.ErrNotFound
+	}
+	if errors.Is(err, jobs.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return job, nil
+}
+
+var protoStatuses = map[jobs.Status]lipiv1.JobStatus{
+	jobs.StatusQueued:    lipiv1.JobStatus_JOB_STATUS_QUEUED,
+	jobs.StatusRunning:   lipiv1.JobStatus_JOB_STATUS_RUNNING,
+	jobs.StatusSucceeded: lipiv1.JobStatus_JOB_STATUS_SUCCEEDED,
+	jobs.StatusFailed:    lipiv1.JobStatus_JOB_STATUS_FAILED,
+	jobs.StatusCancelled: lipiv1.JobStatus_JOB_STATUS_CANCELLED,
+}
+
+// job as returned by the gRPC API, without server-local paths
+func newProtoJob(job *jobs.Job) *lipiv1.Job {
+	msg := &lipiv1.Job{
+		Id:     job.ID,
+		Status: protoStatuses[job.Status],
+		Name:   job.Name,
+		Options: &lipiv1.JobOptions{
+			Format:             job.Options.Format,
+			Language:           job.Options.Language,
+			TranscriptLanguage: job.Options.TranscriptLanguage,
+		},
+		Entries:    int32(job.Entries),
+		Error:      job.Error,
+		ErrorKind:  job.ErrorKind,
+		Chunks:     int32(job.Chunks),
+		ChunksDone: int32(job.ChunksDone),
+		Worker:     job.Worker,
+		CreatedAt:  timestamppb.New(job.CreatedAt),
+	}
+	if job.StartedAt != nil {
+		msg.StartedAt = timestamppb.New(*job.StartedAt)
+	}
+	if job.FinishedAt != nil {
+		msg.FinishedAt = timestamppb.New(*job.FinishedAt)
+	}
+	return msg
+}
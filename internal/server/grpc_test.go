@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	lipiv1 "github.com/mgpai22/lipi/api/lipi/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestGRPCClient(t *testing.T) lipiv1.JobServiceClient {
+	t.Helper()
+	progressInterval = 10 * time.Millisecond
+	store, queue := newTestQueue(t)
+	return dialGRPC(t, New(Config{MaxUploadBytes: 1024}, store, queue))
+}
+
+// serves s over an in-memory listener and returns a client of it
+func dialGRPC(t *testing.T, s *Server) lipiv1.JobServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := s.GRPCServer()
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return lipiv1.NewJobServiceClient(conn)
+}
+
+func TestGRPCSubmitStreamAndResult(t *testing.T) {
+	client := newTestGRPCClient(t)
+	ctx := context.Background()
+
+	upload, err := client.SubmitJob(ctx)
+	if err != nil {
+		t.Fatalf("SubmitJob error: %v", err)
+	}
+	messages := []*lipiv1.SubmitJobRequest{
+		{Payload: &lipiv1.SubmitJobRequest_Spec{Spec: &lipiv1.JobSpec{
+			Media:   &lipiv1.JobSpec_FileName{FileName: "episode.mp3"},
+			Options: &lipiv1.JobOptions{Format: "SRT"},
+		}}},
+		{Payload: &lipiv1.SubmitJobRequest_Chunk{Chunk: []byte("au")}},
+		{Payload: &lipiv1.SubmitJobRequest_Chunk{Chunk: []byte("dio")}},
+	}
+	for _, msg := range messages {
+		if err := upload.Send(msg); err != nil {
+			t.Fatalf("Send error: %v", err)
+		}
+	}
+	job, err := upload.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv error: %v", err)
+	}
+	if job.GetOptions().GetFormat() != "srt" || job.GetName() != "episode.mp3" {
+		t.Errorf("unexpected submitted job %+v", job)
+	}
+
+	progress, err := client.StreamProgress(ctx, &lipiv1.StreamProgressRequest{Id: job.GetId()})
+	if err != nil {
+		t.Fatalf("StreamProgress error: %v", err)
+	}
+	var last *lipiv1.Job
+	for {
+		msg, err := progress.Recv()
+		if err != nil {
+			break
+		}
+		last = msg
+	}
+	if last.GetStatus() != lipiv1.JobStatus_JOB_STATUS_SUCCEEDED {
+		t.Fatalf("expected the stream to end with a succeeded job, got %v", last.GetStatus())
+	}
+
+	result, err := client.GetResult(ctx, &lipiv1.GetResultRequest{Id: job.GetId()})
+	if err != nil {
+		t.Fatalf("GetResult error: %v", err)
+	}
+	if result.GetFileName() != "out.srt" || len(result.GetSubtitle()) == 0 {
+		t.Errorf("unexpected result %q (%d bytes)", result.GetFileName(), len(result.GetSubtitle()))
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	client := newTestGRPCClient(t)
+	ctx := context.Background()
+
+	_, err := client.GetJob(ctx, &lipiv1.GetJobRequest{Id: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+
+	upload, err := client.SubmitJob(ctx)
+	if err != nil {
+		t.Fatalf("SubmitJob error: %v", err)
+	}
+	_ = upload.Send(&lipiv1.SubmitJobRequest{Payload: &lipiv1.SubmitJobRequest_Spec{Spec: &lipiv1.JobSpec{
+		Media: &lipiv1.JobSpec_FileName{FileName: "notes.txt"},
+	}}})
+	if _, err := upload.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a non-media upload, got %v", err)
+	}
+
+	upload, err = client.SubmitJob(ctx)
+	if err != nil {
+		t.Fatalf("SubmitJob error: %v", err)
+	}
+	_ = upload.Send(&lipiv1.SubmitJobRequest{Payload: &lipiv1.SubmitJobRequest_Spec{Spec: &lipiv1.JobSpec{
+		Media: &lipiv1.JobSpec_FileName{FileName: "episode.mp3"},
+	}}})
+	_ = upload.Send(&lipiv1.SubmitJobRequest{Payload: &lipiv1.SubmitJobRequest_Chunk{Chunk: make([]byte, 2048)}})
+	if _, err := upload.CloseAndRecv(); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected ResourceExhausted for an oversized upload, got %v", err)
+	}
+}
@@ -0,0 +1,456 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/jobs"
+	"github.com/mgpai22/lipi/internal/source"
+)
+
+// settings for the HTTP API
+type Config struct {
+	Addr string
+	// MaxUploadBytes caps the size of an uploaded media file
+	MaxUploadBytes int64
+	// WorkerToken, when set, enables the remote worker endpoints for
+	// clients sending it as a bearer token
+	WorkerToken string
+	// Tokens, when set, are required by the job API (see Token); the
+	// worker token then also works as one without quota that sees every
+	// job
+	Tokens []Token
+	// AllowPrivateURLs accepts submitted URLs of loopback, link-local, and
+	// private addresses, which are refused by default
+	AllowPrivateURLs bool
+}
+
+// Server exposes the job queue over a small REST API:
+//
+//	POST /v1/jobs               submit media (multipart "file" or "url" field)
+//	GET  /v1/jobs               list jobs
+//	GET  /v1/jobs/{id}          job status
+//	GET  /v1/jobs/{id}/subtitle download the finished subtitle
+//	GET  /v1/jobs/{id}/cues     subtitle cues as JSON, for the preview player
+//	GET  /v1/jobs/{id}/media    uploaded media, for the preview player
+//	GET  /v1/usage              the API token's usage and quota today
+//	POST /v1/session            sign the web UI in with an API token
+//	GET  /healthz               liveness: 200 while the process serves
+//	GET  /readyz                readiness: 503 while draining or without a store
+//
+// an embedded web UI at /, and with a worker token the endpoints remote
+// workers pull jobs from (see registerWorkerRoutes).
+type Server struct {
+	cfg    Config
+	store  jobs.Store
+	queue  *jobs.Queue
+	tokens map[[32]byte]*Token
+
+	// by token name, held from a quota check to the submit it allowed
+	quotaMu map[string]*sync.Mutex
+	// media seconds of unfinished jobs by ID, counted against quotas
+	// until the jobs record their own
+	reservedMu sync.Mutex
+	reserved   map[string]float64
+	// length of an uploaded file, reserved when its job is submitted
+	mediaLength func(ctx context.Context, path string) (time.Duration, error)
+}
+
+func New(cfg Config, store jobs.Store, queue *jobs.Queue) *Server {
+	if cfg.MaxUploadBytes <= 0 {
+		cfg.MaxUploadBytes = source.DefaultMaxBytes
+	}
+	s := &Server{
+		cfg:         cfg,
+		store:       store,
+		queue:       queue,
+		tokens:      indexTokens(cfg.Tokens),
+		quotaMu:     make(map[string]*sync.Mutex, len(cfg.Tokens)),
+		reserved:    make(map[string]float64),
+		mediaLength: probeLength,
+	}
+	for _, tok := range cfg.Tokens {
+		s.quotaMu[tok.Name] = &sync.Mutex{}
+	}
+	return s
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/jobs", s.requireToken(s.handleSubmit))
+	mux.HandleFunc("GET /v1/jobs", s.requireToken(s.handleList))
+	mux.HandleFunc("GET /v1/jobs/{id}", s.requireToken(s.handleGet))
+	mux.HandleFunc("GET /v1/jobs/{id}/subtitle", s.requireToken(s.handleDownload))
+	mux.HandleFunc("GET /v1/jobs/{id}/cues", s.requireToken(s.handleCues))
+	mux.HandleFunc("GET /v1/jobs/{id}/media", s.requireToken(s.handleMedia))
+	mux.HandleFunc("GET /v1/usage", s.requireToken(s.handleUsage))
+	mux.HandleFunc("POST /v1/session", s.handleSession)
+	mux.HandleFunc("DELETE /v1/session", s.handleSession)
+	if s.cfg.WorkerToken != "" {
+		s.registerWorkerRoutes(mux)
+	}
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("GET /readyz", s.handleReady)
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.Handle("GET /static/", staticHandler())
+	return mux
+}
+
+// serves until ctx is cancelled, then drains open requests
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.cfg.Addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(
+			context.Background(),
+			10*time.Second,
+		)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// job as returned by the API, without server-local paths
+type jobResponse struct {
+	ID         string       `json:"id"`
+	Status     jobs.Status  `json:"status"`
+	Name       string       `json:"name"`
+	Options    jobs.Options `json:"options"`
+	Entries    int          `json:"entries,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	ErrorKind  string       `json:"error_kind,omitempty"`
+	Chunks     int          `json:"chunks,omitempty"`
+	ChunksDone int          `json:"chunks_done,omitempty"`
+	Worker     string       `json:"worker,omitempty"`
+	Subtitle   string       `json:"subtitle_url,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+}
+
+func newJobResponse(job *jobs.Job) jobResponse {
+	resp := jobResponse{
+		ID:         job.ID,
+		Status:     job.Status,
+		Name:       job.Name,
+		Options:    job.Options,
+		Entries:    job.Entries,
+		Error:      job.Error,
+		ErrorKind:  job.ErrorKind,
+		Chunks:     job.Chunks,
+		ChunksDone: job.ChunksDone,
+		Worker:     job.Worker,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if job.Status == jobs.StatusSucceeded {
+		resp.Subtitle = "/v1/jobs/" + job.ID + "/subtitle"
+	}
+	return resp
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	tok := tokenFrom(r.Context())
+	if err := s.checkQuota(tok); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errQuota) {
+			status = http.StatusTooManyRequests
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+
+	job := &jobs.Job{ID: jobs.NewID()}
+	job.Dir = s.store.JobDir(job.ID)
+	if tok != nil {
+		job.Owner = tok.Name
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxUploadBytes+1<<20)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "expected multipart/form-data")
+		return
+	}
+
+	if err := s.readSubmission(r.Context(), reader, job); err != nil {
+		_ = os.RemoveAll(job.Dir)
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "upload too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.submit(r.Context(), tok, job); err != nil {
+		if errors.Is(err, errQuota) {
+			_ = os.RemoveAll(job.Dir)
+			writeError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		if errors.Is(err, jobs.ErrQueueFull) || errors.Is(err, jobs.ErrDraining) {
+			_ = os.RemoveAll(job.Dir)
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		_ = os.RemoveAll(job.Dir)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", "/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, newJobResponse(job))
+}
+
+// errOneInput is returned for submissions with more than one input
+var errOneInput = errors.New(`only one "file" or "url" may be submitted`)
+
+// reads the multipart form, streaming any uploaded file into the job dir
+func (s *Server) readSubmission(ctx context.Context, reader *multipart.Reader, job *jobs.Job) error {
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch part.FormName() {
+		case "file":
+			if err := s.saveUpload(part.FileName(), part, job); err != nil {
+				return err
+			}
+		case "url":
+			if job.Source != "" {
+				return errOneInput
+			}
+			value, err := readField(part)
+			if err != nil {
+				return err
+			}
+			if err := s.checkURL(ctx, value); err != nil {
+				return err
+			}
+			job.Name = value
+			job.Source = value
+		case "format":
+			value, err := readField(part)
+			if err != nil {
+				return err
+			}
+			if job.Options.Format, err = parseFormat(value); err != nil {
+				return err
+			}
+		case "language":
+			if job.Options.Language, err = readField(part); err != nil {
+				return err
+			}
+		case "transcript_language":
+			if job.Options.TranscriptLanguage, err = readField(part); err != nil {
+				return err
+			}
+		}
+		_ = part.Close()
+	}
+
+	if job.Source == "" {
+		return errors.New(`a "file" upload or "url" field is required`)
+	}
+	return nil
+}
+
+// returns an error unless value is an http(s) URL that the server may
+// fetch: of a public address, unless private ones are allowed
+func (s *Server) checkURL(ctx context.Context, value string) error {
+	if !source.IsURL(value) {
+		return fmt.Errorf("invalid url %q", value)
+	}
+	if s.cfg.AllowPrivateURLs {
+		return nil
+	}
+	if err := source.CheckPublicURL(ctx, value); err != nil {
+		return fmt.Errorf("url %q is not allowed: %w", value, err)
+	}
+	return nil
+}
+
+// the format option of a submission, lowercased
+func parseFormat(value string) (string, error) {
+	switch strings.ToLower(value) {
+	case "srt", "vtt", "ass":
+		return strings.ToLower(value), nil
+	default:
+		return "", fmt.Errorf("unsupported format %q: use srt, vtt, or ass", value)
+	}
+}
+
+// streams an uploaded media file named fileName from r into the job dir
+func (s *Server) saveUpload(fileName string, r io.Reader, job *jobs.Job) error {
+	if job.Source != "" {
+		return errOneInput
+	}
+
+	name := filepath.Base(fileName)
+	if name == "." || name == string(filepath.Separator) || name == "" {
+		return errors.New("uploaded file has no name")
+	}
+	if !audio.IsMediaFile(name) {
+		return fmt.Errorf(
+			"unsupported file type: %s (expected audio or video file)",
+			filepath.Ext(name),
+		)
+	}
+
+	inputDir := filepath.Join(job.Dir, "input")
+	if err := os.MkdirAll(inputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create job directory: %w", err)
+	}
+
+	path := filepath.Join(inputDir, name)
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create upload: %w", err)
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	n, err := io.Copy(out, io.LimitReader(r, s.cfg.MaxUploadBytes+1))
+	if err != nil {
+		return err
+	}
+	if n > s.cfg.MaxUploadBytes {
+		return &http.MaxBytesError{Limit: s.cfg.MaxUploadBytes}
+	}
+
+	job.Name = name
+	job.Source = path
+	return nil
+}
+
+func readField(part *multipart.Part) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(part, 4096))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ready while the queue runs, is not draining, and can reach its store
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if err := s.queue.Ready(); err != nil {
+		status := "unavailable"
+		if errors.Is(err, jobs.ErrDraining) {
+			status = "draining"
+		}
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"status": status,
+			"error":  err.Error(),
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	all, err := s.store.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := make([]jobResponse, 0, len(all))
+	for _, job := range all {
+		// files of batch runs share the store but not the API
+		if job.Batch == "" && visible(tokenFrom(r.Context()), job) {
+			resp = append(resp, newJobResponse(job))
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"jobs": resp})
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, newJobResponse(job))
+}
+
+func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	if job.Status != jobs.StatusSucceeded || job.Output == "" {
+		writeError(
+			w,
+			http.StatusConflict,
+			fmt.Sprintf("job is %s; subtitle not available", job.Status),
+		)
+		return
+	}
+
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf("attachment; filename=%q", filepath.Base(job.Output)),
+	)
+	http.ServeFile(w, r, job.Output)
+}
+
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (*jobs.Job, bool) {
+	job, err := s.store.Get(r.PathValue("id"))
+	if err == nil && (job.Batch != "" || !visible(tokenFrom(r.Context()), job)) {
+		err = jobs.ErrNotFound
+	}
+	if errors.Is(err, jobs.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	return job, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,299 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/jobs"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *jobs.FileStore) {
+	t.Helper()
+	store, queue := newTestQueue(t)
+	srv := New(Config{MaxUploadBytes: 1024}, store, queue)
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts, store
+}
+
+// a running queue whose jobs write a one-cue subtitle
+func newTestQueue(t *testing.T) (*jobs.FileStore, *jobs.Queue) {
+	t.Helper()
+	store, err := jobs.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore error: %v", err)
+	}
+
+	runner := func(ctx context.Context, job *jobs.Job) (*jobs.Result, error) {
+		output := filepath.Join(job.Dir, "out.srt")
+		data := []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n")
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return nil, err
+		}
+		return &jobs.Result{Output: output, Entries: 1}, nil
+	}
+	queue := jobs.NewQueue(store, runner, 1, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() {
+		_ = queue.Run(ctx)
+	}()
+	return store, queue
+}
+
+func submit(t *testing.T, url string, fields map[string]string, file []byte) *http.Response {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		_ = mw.WriteField(k, v)
+	}
+	if file != nil {
+		fw, err := mw.CreateFormFile("file", "episode.mp3")
+		if err != nil {
+			t.Fatalf("CreateFormFile error: %v", err)
+		}
+		_, _ = fw.Write(file)
+	}
+	_ = mw.Close()
+
+	resp, err := http.Post(url+"/v1/jobs", mw.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("POST error: %v", err)
+	}
+	return resp
+}
+
+func TestSubmitAndDownload(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	resp := submit(t, ts.URL, map[string]string{"format": "srt"}, []byte("audio"))
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+
+	var job jobResponse
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if job.Name != "episode.mp3" || job.Status != jobs.StatusQueued {
+		t.Errorf("unexpected job %+v", job)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != jobs.StatusSucceeded {
+		if time.Now().After(deadline) {
+			t.Fatalf("job did not finish, last status %s", job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		r, err := http.Get(ts.URL + "/v1/jobs/" + job.ID)
+		if err != nil {
+			t.Fatalf("GET error: %v", err)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&job)
+		_ = r.Body.Close()
+	}
+
+	r, err := http.Get(ts.URL + job.Subtitle)
+	if err != nil {
+		t.Fatalf("download error: %v", err)
+	}
+	defer func() { _ = r.Body.Close() }()
+	data, _ := io.ReadAll(r.Body)
+	if r.StatusCode != http.StatusOK || !bytes.Contains(data, []byte("hello")) {
+		t.Errorf("unexpected download %d %q", r.StatusCode, data)
+	}
+}
+
+func TestSubmitValidation(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		file   []byte
+		want   int
+	}{
+		{"no input", nil, nil, http.StatusBadRequest},
+		{"bad format", map[string]string{"format": "txt"}, []byte("a"), http.StatusBadRequest},
+		{"bad url", map[string]string{"url": "not a url"}, nil, http.StatusBadRequest},
+		{"loopback url", map[string]string{"url": "http://127.0.0.1:8080/admin"}, nil, http.StatusBadRequest},
+		{"metadata url", map[string]string{"url": "http://169.254.169.254/latest/meta-data/"}, nil, http.StatusBadRequest},
+		{"private url", map[string]string{"url": "http://[fd00::1]/episode.mp3"}, nil, http.StatusBadRequest},
+		{"too large", nil, make([]byte, 2048), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := submit(t, ts.URL, tt.fields, tt.file)
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestSubmitFileThenURL(t *testing.T) {
+	ts, store := newTestServer(t)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "episode.mp3")
+	if err != nil {
+		t.Fatalf("CreateFormFile error: %v", err)
+	}
+	_, _ = fw.Write([]byte("audio"))
+	_ = mw.WriteField("url", "https://example.com/episode.mp3")
+	_ = mw.Close()
+
+	resp, err := http.Post(ts.URL+"/v1/jobs", mw.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("POST error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a url after an upload to be refused, got %d", resp.StatusCode)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(store.JobDir("x"))); len(entries) != 0 {
+		t.Errorf("expected the upload to be removed, found %d job dirs", len(entries))
+	}
+}
+
+func TestSubmitAllowPrivateURLs(t *testing.T) {
+	store, queue := newTestQueue(t)
+	srv := New(Config{MaxUploadBytes: 1024, AllowPrivateURLs: true}, store, queue)
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+
+	resp := submit(t, ts.URL, map[string]string{"url": "http://10.0.0.5/episode.mp3"}, nil)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("expected a private url to be accepted, got %d", resp.StatusCode)
+	}
+}
+
+func TestGetUnknownJob(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	for _, path := range []string{"/v1/jobs/0123456789abcdef", "/v1/jobs/nope/subtitle"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET error: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: expected 404, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestWebUI(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	for _, path := range []string{"/", "/static/app.js", "/static/style.css"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET error: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s: expected 200, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestCuesAndMedia(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	resp := submit(t, ts.URL, nil, []byte("audio"))
+	var job jobResponse
+	_ = json.NewDecoder(resp.Body).Decode(&job)
+	_ = resp.Body.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	var body struct {
+		Cues []cue `json:"cues"`
+	}
+	for {
+		r, err := http.Get(ts.URL + "/v1/jobs/" + job.ID + "/cues")
+		if err != nil {
+			t.Fatalf("GET error: %v", err)
+		}
+		status := r.StatusCode
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_ = r.Body.Close()
+		if status == http.StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cues not available, last status %d", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(body.Cues) != 1 || body.Cues[0].Text != "hello" || body.Cues[0].End != 1 {
+		t.Errorf("unexpected cues %+v", body.Cues)
+	}
+
+	r, err := http.Get(ts.URL + "/v1/jobs/" + job.ID + "/media")
+	if err != nil {
+		t.Fatalf("GET error: %v", err)
+	}
+	data, _ := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	if r.StatusCode != http.StatusOK || string(data) != "audio" {
+		t.Errorf("unexpected media response %d %q", r.StatusCode, data)
+	}
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	store, queue := newTestQueue(t)
+	ts := httptest.NewServer(New(Config{}, store, queue).Handler())
+	t.Cleanup(ts.Close)
+
+	get := func(path string) int {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s error: %v", path, err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := get("/healthz"); code != http.StatusOK {
+		t.Errorf("expected healthz 200, got %d", code)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for get("/readyz") != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("server never became ready")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	queue.Drain()
+	if code := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected readyz 503 while draining, got %d", code)
+	}
+	if code := get("/healthz"); code != http.StatusOK {
+		t.Errorf("expected healthz 200 while draining, got %d", code)
+	}
+	resp := submit(t, ts.URL, nil, []byte("audio"))
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 for a job submitted while draining, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/jobs"
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// serves the embedded single-page UI
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	http.ServeFileFS(w, r, webFiles, "web/index.html")
+}
+
+func staticHandler() http.Handler {
+	static, _ := fs.Sub(webFiles, "web")
+	return http.StripPrefix("/static/", http.FileServerFS(static))
+}
+
+// single subtitle cue for the preview player
+type cue struct {
+	Index int     `json:"index"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+func (s *Server) handleCues(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	if job.Status != jobs.StatusSucceeded || job.Output == "" {
+		writeError(
+			w,
+			http.StatusConflict,
+			"job is "+string(job.Status)+"; subtitle not available",
+		)
+		return
+	}
+
+	subFile, err := subtitle.Open(job.Output)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	entries := subFile.Subtitle().Entries
+	cues := make([]cue, len(entries))
+	for i, entry := range entries {
+		cues[i] = cue{
+			Index: entry.Index,
+			Start: entry.StartTime.Seconds(),
+			End:   entry.EndTime.Seconds(),
+			Text:  entry.Text,
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"cues": cues})
+}
+
+// streams an uploaded input back for preview; URL jobs have no local copy
+func (s *Server) handleMedia(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	rel, err := filepath.Rel(job.Dir, job.Source)
+	if err != nil || strings.HasPrefix(rel, "..") || filepath.IsAbs(rel) {
+		writeError(w, http.StatusNotFound, "media is not stored for this job")
+		return
+	}
+	http.ServeFile(w, r, job.Source)
+}
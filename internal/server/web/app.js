@@ -0,0 +1,280 @@
+"use strict";
+
+const form = document.getElementById("submit-form");
+const fileInput = document.getElementById("file");
+const fileName = document.getElementById("file-name");
+const dropZone = document.getElementById("drop-zone");
+const urlInput = document.getElementById("url");
+const submitButton = document.getElementById("submit-button");
+const submitError = document.getElementById("submit-error");
+const uploadProgress = document.getElementById("upload-progress");
+const jobsBody = document.querySelector("#jobs tbody");
+const noJobs = document.getElementById("no-jobs");
+const preview = document.getElementById("preview");
+const previewName = document.getElementById("preview-name");
+const player = document.getElementById("player");
+const currentCue = document.getElementById("current-cue");
+const cueList = document.getElementById("cues");
+const signIn = document.getElementById("sign-in");
+const signInForm = document.getElementById("sign-in-form");
+const signInError = document.getElementById("sign-in-error");
+const signOut = document.getElementById("sign-out");
+const usage = document.getElementById("usage");
+
+let cues = [];
+
+fileInput.addEventListener("change", () => {
+  fileName.textContent = fileInput.files.length ? fileInput.files[0].name : "";
+});
+
+["dragenter", "dragover"].forEach((type) =>
+  dropZone.addEventListener(type, () => dropZone.classList.add("active")),
+);
+["dragleave", "drop"].forEach((type) =>
+  dropZone.addEventListener(type, () => dropZone.classList.remove("active")),
+);
+
+form.addEventListener("submit", (event) => {
+  event.preventDefault();
+  submitError.hidden = true;
+
+  const data = new FormData();
+  for (const field of ["format", "language", "transcript_language"]) {
+    const value = form.elements[field].value.trim();
+    if (value) data.append(field, value);
+  }
+  if (fileInput.files.length) {
+    data.append("file", fileInput.files[0]);
+  } else if (urlInput.value.trim()) {
+    data.append("url", urlInput.value.trim());
+  } else {
+    showError("Choose a file or enter a URL.");
+    return;
+  }
+
+  // XMLHttpRequest rather than fetch so upload progress is visible
+  const xhr = new XMLHttpRequest();
+  xhr.open("POST", "/v1/jobs");
+  xhr.upload.addEventListener("progress", (e) => {
+    if (e.lengthComputable) uploadProgress.value = (e.loaded / e.total) * 100;
+  });
+  xhr.addEventListener("load", () => {
+    resetForm();
+    if (xhr.status === 401) {
+      showSignIn();
+      return;
+    }
+    if (xhr.status !== 202) {
+      showError(parseError(xhr.responseText) || `Upload failed (${xhr.status})`);
+      return;
+    }
+    form.reset();
+    fileName.textContent = "";
+    refreshJobs();
+  });
+  xhr.addEventListener("error", () => {
+    resetForm();
+    showError("Upload failed: network error");
+  });
+
+  submitButton.disabled = true;
+  uploadProgress.hidden = false;
+  uploadProgress.value = 0;
+  xhr.send(data);
+});
+
+function resetForm() {
+  submitButton.disabled = false;
+  uploadProgress.hidden = true;
+}
+
+function showError(message) {
+  submitError.textContent = message;
+  submitError.hidden = false;
+}
+
+function parseError(text) {
+  try {
+    return JSON.parse(text).error;
+  } catch {
+    return "";
+  }
+}
+
+// the server keeps the token in an HttpOnly cookie, which also covers the
+// download links and the preview player
+signInForm.addEventListener("submit", async (event) => {
+  event.preventDefault();
+  signInError.hidden = true;
+  const resp = await fetch("/v1/session", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ token: signInForm.elements.token.value }),
+  });
+  if (!resp.ok) {
+    signInError.textContent =
+      parseError(await resp.text()) || `Sign in failed (${resp.status})`;
+    signInError.hidden = false;
+    return;
+  }
+  signInForm.reset();
+  signIn.hidden = true;
+  signOut.hidden = false;
+  refreshJobs();
+});
+
+signOut.addEventListener("click", async () => {
+  await fetch("/v1/session", { method: "DELETE" });
+  showSignIn();
+});
+
+function showSignIn() {
+  signIn.hidden = false;
+  signOut.hidden = true;
+  usage.hidden = true;
+  jobsBody.replaceChildren();
+  noJobs.hidden = false;
+}
+
+async function refreshJobs() {
+  let jobs = [];
+  try {
+    const resp = await fetch("/v1/jobs");
+    if (resp.status === 401) {
+      showSignIn();
+      return;
+    }
+    jobs = (await resp.json()).jobs || [];
+  } catch {
+    return;
+  }
+
+  noJobs.hidden = jobs.length > 0;
+  jobsBody.replaceChildren(...jobs.map(jobRow));
+  refreshUsage();
+}
+
+// today's usage of the signed-in token; the request fails without tokens
+async function refreshUsage() {
+  try {
+    const resp = await fetch("/v1/usage");
+    if (!resp.ok) return;
+    const used = await resp.json();
+    let text = `${used.name}: ${used.audio_minutes.toFixed(1)}`;
+    if (used.daily_audio_minutes) text += ` / ${used.daily_audio_minutes}`;
+    text += " min";
+    text += `, ${used.tokens.toLocaleString()}`;
+    if (used.daily_tokens) text += ` / ${used.daily_tokens.toLocaleString()}`;
+    text += " tokens today";
+    usage.textContent = text;
+    usage.hidden = false;
+    signOut.hidden = false;
+  } catch {
+    // usage is informational only
+  }
+}
+
+function jobRow(job) {
+  const row = document.createElement("tr");
+
+  const name = document.createElement("td");
+  name.textContent = job.name;
+  if (job.error) name.title = job.error;
+
+  const status = document.createElement("td");
+  status.textContent = job.status;
+  if (job.status === "running" && job.chunks) {
+    status.textContent += ` (${job.chunks_done || 0}/${job.chunks})`;
+  }
+  status.className = `status status-${job.status}`;
+
+  const created = document.createElement("td");
+  created.textContent = new Date(job.created_at).toLocaleString();
+
+  const elapsed = document.createElement("td");
+  elapsed.textContent = formatElapsed(job);
+
+  const actions = document.createElement("td");
+  actions.className = "actions";
+  if (job.subtitle_url) {
+    const previewButton = document.createElement("button");
+    previewButton.className = "secondary";
+    previewButton.textContent = "Preview";
+    previewButton.addEventListener("click", () => openPreview(job));
+
+    const download = document.createElement("a");
+    download.className = "button";
+    download.href = job.subtitle_url;
+    download.textContent = "Download";
+
+    actions.append(previewButton, " ", download);
+  } else if (job.error) {
+    actions.textContent = job.error;
+    actions.className = "actions error";
+  }
+
+  row.append(name, status, created, elapsed, actions);
+  return row;
+}
+
+function formatElapsed(job) {
+  if (!job.started_at) return "";
+  const start = new Date(job.started_at);
+  const end = job.finished_at ? new Date(job.finished_at) : new Date();
+  const seconds = Math.max(0, Math.round((end - start) / 1000));
+  return seconds < 60
+    ? `${seconds}s`
+    : `${Math.floor(seconds / 60)}m ${seconds % 60}s`;
+}
+
+async function openPreview(job) {
+  const resp = await fetch(`/v1/jobs/${job.id}/cues`);
+  if (!resp.ok) {
+    showError(parseError(await resp.text()) || "Failed to load cues");
+    return;
+  }
+  cues = (await resp.json()).cues || [];
+
+  previewName.textContent = job.name;
+  player.src = `/v1/jobs/${job.id}/media`;
+  currentCue.textContent = "";
+  cueList.replaceChildren(...cues.map(cueItem));
+  preview.hidden = false;
+  preview.scrollIntoView({ behavior: "smooth" });
+}
+
+function cueItem(cue, i) {
+  const item = document.createElement("li");
+  const time = document.createElement("span");
+  time.className = "time";
+  time.textContent = formatTime(cue.start);
+  item.append(time, cue.text);
+  item.addEventListener("click", () => {
+    player.currentTime = cue.start;
+    player.play();
+  });
+  item.dataset.index = i;
+  return item;
+}
+
+function formatTime(seconds) {
+  const m = Math.floor(seconds / 60);
+  const s = Math.floor(seconds % 60);
+  return `${m}:${String(s).padStart(2, "0")}`;
+}
+
+player.addEventListener("timeupdate", () => {
+  const t = player.currentTime;
+  const index = cues.findIndex((c) => t >= c.start && t < c.end);
+  currentCue.textContent = index >= 0 ? cues[index].text : "";
+  for (const item of cueList.children) {
+    item.classList.toggle("active", Number(item.dataset.index) === index);
+  }
+});
+
+player.addEventListener("error", () => {
+  currentCue.textContent = "Media preview is only available for uploaded files.";
+});
+
+refreshJobs();
+setInterval(refreshJobs, 2000);
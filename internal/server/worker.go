@@ -0,0 +1,237 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/jobs"
+	"github.com/mgpai22/lipi/internal/source"
+	"github.com/mgpai22/lipi/internal/usage"
+)
+
+// job handed to a remote worker by POST /v1/worker/claim
+type WorkerJob struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Options jobs.Options `json:"options"`
+	// MediaURL is the submitted URL, or the path on the server of the
+	// uploaded media
+	MediaURL string `json:"media_url"`
+}
+
+// body of the claim, heartbeat, and fail requests of a remote worker
+type workerRequest struct {
+	Worker     string      `json:"worker"`
+	ChunksDone int         `json:"chunks_done,omitempty"`
+	Chunks     int         `json:"chunks,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	ErrorKind  string      `json:"error_kind,omitempty"`
+	Usage      usage.Usage `json:"usage"`
+}
+
+// registers the remote worker endpoints, all behind the worker token:
+//
+//	POST /v1/worker/claim                claim the next queued job
+//	POST /v1/worker/jobs/{id}/heartbeat  renew the lease, report progress
+//	POST /v1/worker/jobs/{id}/complete   upload the subtitle (multipart)
+//	POST /v1/worker/jobs/{id}/fail       report an error
+func (s *Server) registerWorkerRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /v1/worker/claim", s.requireWorker(s.handleClaim))
+	mux.HandleFunc("POST /v1/worker/jobs/{id}/heartbeat", s.requireWorker(s.handleHeartbeat))
+	mux.HandleFunc("POST /v1/worker/jobs/{id}/complete", s.requireWorker(s.handleComplete))
+	mux.HandleFunc("POST /v1/worker/jobs/{id}/fail", s.requireWorker(s.handleFail))
+}
+
+// rejects requests without the worker token as a bearer token
+func (s *Server) requireWorker(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.WorkerToken)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid worker token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleClaim(w http.ResponseWriter, r *http.Request) {
+	req, ok := readWorkerRequest(w, r)
+	if !ok {
+		return
+	}
+	job, err := s.queue.Claim(req.Worker)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if job == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	claim := WorkerJob{ID: job.ID, Name: job.Name, Options: job.Options, MediaURL: job.Source}
+	if !source.IsURL(job.Source) {
+		claim.MediaURL = "/v1/jobs/" + job.ID + "/media"
+	}
+	writeJSON(w, http.StatusOK, claim)
+}
+
+func (s *Server) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	req, ok := readWorkerRequest(w, r)
+	if !ok {
+		return
+	}
+	err := s.queue.Heartbeat(r.PathValue("id"), req.Worker, req.ChunksDone, req.Chunks)
+	writeWorkerResult(w, err)
+}
+
+func (s *Server) handleFail(w http.ResponseWriter, r *http.Request) {
+	req, ok := readWorkerRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.Error == "" {
+		req.Error = "remote worker failed"
+	}
+	err := s.queue.Fail(r.PathValue("id"), req.Worker, req.Error, req.ErrorKind, req.Usage)
+	writeWorkerResult(w, err)
+}
+
+// reads the multipart fields "worker", "entries", "media_seconds", and
+// "usage" (JSON) and the "subtitle" file, written into the job's directory
+func (s *Server) handleComplete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	job, err := s.store.Get(id)
+	if err != nil {
+		writeWorkerResult(w, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxUploadBytes+1<<20)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "expected multipart/form-data")
+		return
+	}
+
+	var worker, output string
+	result := &jobs.Result{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		switch part.FormName() {
+		case "worker":
+			worker, err = readField(part)
+		case "entries":
+			var value string
+			if value, err = readField(part); err == nil {
+				result.Entries, err = strconv.Atoi(value)
+			}
+		case "media_seconds":
+			var value string
+			if value, err = readField(part); err == nil {
+				result.MediaSeconds, err = strconv.ParseFloat(value, 64)
+			}
+		case "usage":
+			var value string
+			if value, err = readField(part); err == nil {
+				err = json.Unmarshal([]byte(value), &result.Usage)
+			}
+		case "subtitle":
+			// the worker field comes first, so only the job's holder
+			// overwrites its output
+			if err := s.queue.Holds(id, worker); err != nil {
+				_ = part.Close()
+				writeWorkerResult(w, err)
+				return
+			}
+			output, err = saveSubtitle(part.FileName(), part, job)
+		}
+		_ = part.Close()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if output == "" {
+		writeError(w, http.StatusBadRequest, `a "subtitle" file is required`)
+		return
+	}
+
+	result.Output = output
+	writeWorkerResult(w, s.queue.Complete(id, worker, result))
+}
+
+// writes a worker's subtitle into the job directory, named after the
+// job's input with the uploaded file's extension
+func saveSubtitle(fileName string, r io.Reader, job *jobs.Job) (string, error) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	switch ext {
+	case ".srt", ".vtt", ".ass":
+	default:
+		return "", fmt.Errorf("unsupported subtitle file %q: use srt, vtt, or ass", fileName)
+	}
+	name := strings.TrimSuffix(filepath.Base(job.Name), filepath.Ext(job.Name))
+	if name == "" || name == "." {
+		name = "subtitles"
+	}
+	if err := os.MkdirAll(job.Dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create job directory: %w", err)
+	}
+
+	path := filepath.Join(job.Dir, name+ext)
+	out, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to save subtitle: %w", err)
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+	if _, err := io.Copy(out, r); err != nil {
+		return "", fmt.Errorf("failed to save subtitle: %w", err)
+	}
+	return path, nil
+}
+
+func readWorkerRequest(w http.ResponseWriter, r *http.Request) (workerRequest, bool) {
+	var req workerRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return req, false
+	}
+	if req.Worker == "" {
+		writeError(w, http.StatusBadRequest, "worker name is required")
+		return req, false
+	}
+	return req, true
+}
+
+// answers a worker's report: 410 once the job is cancelled, 409 when the
+// worker no longer holds it
+func writeWorkerResult(w http.ResponseWriter, err error) {
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, jobs.ErrCancelled):
+		writeError(w, http.StatusGone, err.Error())
+	case errors.Is(err, jobs.ErrState), errors.Is(err, jobs.ErrNotFound):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
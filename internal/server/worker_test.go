@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/jobs"
+	"github.com/mgpai22/lipi/internal/usage"
+)
+
+// a server without local workers, so jobs wait for remote ones
+func newWorkerServer(t *testing.T) (*httptest.Server, *jobs.FileStore) {
+	t.Helper()
+	store, err := jobs.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore error: %v", err)
+	}
+	queue := jobs.NewQueue(store, nil, 0, 10)
+
+	srv := New(Config{MaxUploadBytes: 1024, WorkerToken: "secret"}, store, queue)
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts, store
+}
+
+func TestWorkerRejectsBadToken(t *testing.T) {
+	ts, _ := newWorkerServer(t)
+
+	client := &WorkerClient{BaseURL: ts.URL, Token: "wrong", Worker: "box-1"}
+	_, err := client.Claim(context.Background())
+	if errs.KindOf(err) != errs.KindAuth {
+		t.Fatalf("expected auth error, got %v", err)
+	}
+}
+
+func TestWorkerClaimAndComplete(t *testing.T) {
+	ts, store := newWorkerServer(t)
+	ctx := context.Background()
+	client := &WorkerClient{BaseURL: ts.URL, Token: "secret", Worker: "box-1"}
+
+	if job, err := client.Claim(ctx); err != nil || job != nil {
+		t.Fatalf("expected no job, got %+v, %v", job, err)
+	}
+
+	resp := submit(t, ts.URL, map[string]string{"format": "vtt"}, []byte("audio"))
+	_ = resp.Body.Close()
+
+	job, err := client.Claim(ctx)
+	if err != nil || job == nil {
+		t.Fatalf("Claim error: %v", err)
+	}
+	if job.Options.Format != "vtt" || job.MediaURL != "/v1/jobs/"+job.ID+"/media" {
+		t.Errorf("unexpected claimed job %+v", job)
+	}
+
+	dir := t.TempDir()
+	media, err := client.Media(ctx, job, dir)
+	if err != nil {
+		t.Fatalf("Media error: %v", err)
+	}
+	if data, _ := os.ReadFile(media); string(data) != "audio" {
+		t.Errorf("unexpected media %q", data)
+	}
+
+	if err := client.Heartbeat(ctx, job.ID, 1, 2); err != nil {
+		t.Fatalf("Heartbeat error: %v", err)
+	}
+	subtitle := filepath.Join(dir, "episode.vtt")
+	if err := os.WriteFile(subtitle, []byte("WEBVTT\n\n00:00.000 --> 00:01.000\nhello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Complete(ctx, job.ID, &jobs.Result{
+		Output:       subtitle,
+		Entries:      1,
+		MediaSeconds: 90,
+		Usage:        usage.Usage{Requests: 2},
+	}); err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+
+	got, _ := store.Get(job.ID)
+	if got.Status != jobs.StatusSucceeded || got.Worker != "box-1" || got.Usage.Requests != 2 || got.MediaSeconds != 90 {
+		t.Errorf("unexpected finished job %+v", got)
+	}
+	if filepath.Dir(got.Output) != got.Dir || filepath.Ext(got.Output) != ".vtt" {
+		t.Errorf("expected the subtitle in the job directory, got %s", got.Output)
+	}
+}
+
+func TestWorkerCancelledJob(t *testing.T) {
+	ts, store := newWorkerServer(t)
+	ctx := context.Background()
+	client := &WorkerClient{BaseURL: ts.URL, Token: "secret", Worker: "box-1"}
+
+	resp := submit(t, ts.URL, nil, []byte("audio"))
+	_ = resp.Body.Close()
+	job, err := client.Claim(ctx)
+	if err != nil || job == nil {
+		t.Fatalf("Claim error: %v", err)
+	}
+
+	other := &WorkerClient{BaseURL: ts.URL, Token: "secret", Worker: "box-2"}
+	if err := other.Heartbeat(ctx, job.ID, 0, 0); !errors.Is(err, jobs.ErrState) {
+		t.Errorf("expected ErrState for another worker, got %v", err)
+	}
+
+	if _, err := jobs.Cancel(store, job.ID); err != nil {
+		t.Fatalf("Cancel error: %v", err)
+	}
+	if err := client.Heartbeat(ctx, job.ID, 0, 0); !errors.Is(err, jobs.ErrCancelled) {
+		t.Errorf("expected ErrCancelled, got %v", err)
+	}
+}
@@ -0,0 +1,437 @@
+package source
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/httpclient"
+)
+
+// stdin marker accepted in place of a file path
+const Stdin = "-"
+
+// DefaultMaxBytes caps remote and streamed input (4 GiB)
+const DefaultMaxBytes int64 = 4 << 30
+
+// locally staged media input
+type Media struct {
+	// Path is a local file that ffmpeg can read
+	Path string
+	// Name is a base name (without extension) used to derive output paths
+	Name string
+	// Remote is true when the input was downloaded or read from stdin
+	Remote bool
+}
+
+// settings for fetching remote or streamed input
+type Options struct {
+	MaxBytes int64         // Maximum bytes to accept (0 = DefaultMaxBytes)
+	Retries  int           // Attempts after a dropped connection or server error
+	Timeout  time.Duration // Per-request timeout (0 = none)
+	// Format overrides the detected container extension (e.g. "mp3", "mkv")
+	Format string
+	// UseYTDLP forces yt-dlp for URLs that are not recognized streaming sites
+	UseYTDLP bool
+	Stdin    io.Reader
+	// HTTPClient is used for downloads; nil uses the default client
+	HTTPClient *http.Client
+	// Proxy is handed to yt-dlp, which makes its own requests
+	Proxy string
+	// PublicOnly refuses URLs of loopback, link-local, and private
+	// addresses, for URLs submitted to a server
+	PublicOnly bool
+}
+
+// defaults for fetching remote input
+func DefaultOptions() Options {
+	return Options{
+		MaxBytes: DefaultMaxBytes,
+		Retries:  3,
+		Stdin:    os.Stdin,
+	}
+}
+
+// checks if input is an http(s) URL
+func IsURL(input string) bool {
+	u, err := url.Parse(input)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// CheckPublicURL returns an error unless rawURL's host resolves only to
+// public addresses
+func CheckPublicURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	return httpclient.CheckPublicHost(ctx, u.Hostname())
+}
+
+// checks if input should be read from stdin
+func IsStdin(input string) bool {
+	return input == Stdin
+}
+
+// checks if input is a URL or stdin rather than a local file
+func IsRemote(input string) bool {
+	return IsURL(input) || IsStdin(input)
+}
+
+// Resolve stages input into destDir when it is a URL or stdin, and returns
+// local files unchanged.
+func Resolve(
+	ctx context.Context,
+	input, destDir string,
+	opts Options,
+) (*Media, error) {
+	if IsURL(input) {
+		if err := httpclient.Refuse("fetch " + input); err != nil {
+			return nil, err
+		}
+		// downloads are checked again as they connect; yt-dlp makes its
+		// own requests, so this is all it gets
+		if opts.PublicOnly {
+			if err := CheckPublicURL(ctx, input); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := CheckFormat(opts.Format); err != nil {
+		return nil, err
+	}
+	switch {
+	case IsStdin(input):
+		return readStdin(destDir, opts)
+	case IsURL(input) && (opts.UseYTDLP || IsStreamingSite(input)):
+		return fetchWithYTDLP(ctx, input, destDir, opts)
+	case IsURL(input):
+		return download(ctx, input, destDir, opts)
+	default:
+		if _, err := os.Stat(input); os.IsNotExist(err) {
+			return nil, fmt.Errorf("file not found: %s", input)
+		}
+		return &Media{
+			Path: input,
+			Name: strings.TrimSuffix(input, filepath.Ext(input)),
+		}, nil
+	}
+}
+
+func readStdin(destDir string, opts Options) (*Media, error) {
+	reader := opts.Stdin
+	if reader == nil {
+		reader = os.Stdin
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create input directory: %w", err)
+	}
+
+	buffered := bufio.NewReaderSize(reader, 512)
+	ext := formatExtension(opts.Format)
+	if ext == "" {
+		head, _ := buffered.Peek(512)
+		ext = extensionForContentType(http.DetectContentType(head))
+	}
+
+	destPath := filepath.Join(destDir, "stdin"+ext)
+	out, err := os.Create(destPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create input file: %w", err)
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	if _, err := copyLimited(out, buffered, maxBytes(opts), 0); err != nil {
+		return nil, fmt.Errorf("failed to read stdin: %w", err)
+	}
+
+	return &Media{Path: destPath, Name: "stdin", Remote: true}, nil
+}
+
+func download(
+	ctx context.Context,
+	rawURL, destDir string,
+	opts Options,
+) (*Media, error) {
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create input directory: %w", err)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+
+	name := strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+	if name == "" || name == "." || name == "/" {
+		name = "download"
+	}
+
+	client := httpclient.WithTimeout(httpclient.Or(opts.HTTPClient), opts.Timeout)
+	if opts.PublicOnly {
+		client = httpclient.PublicOnly(client)
+	}
+	limit := maxBytes(opts)
+
+	var (
+		out      *os.File
+		destPath string
+		written  int64
+	)
+	defer func() {
+		if out != nil {
+			_ = out.Close()
+		}
+	}()
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		if written > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", written))
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if attempt < opts.Retries && ctx.Err() == nil {
+				if err := waitRetry(ctx, attempt); err != nil {
+					return nil, fmt.Errorf("download failed: %w", err)
+				}
+				continue
+			}
+			return nil, fmt.Errorf("download failed: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK &&
+			resp.StatusCode != http.StatusPartialContent {
+			_ = resp.Body.Close()
+			if retryableStatus(resp.StatusCode) && attempt < opts.Retries {
+				if err := waitRetry(ctx, attempt); err != nil {
+					return nil, fmt.Errorf("download failed: %w", err)
+				}
+				continue
+			}
+			return nil, fmt.Errorf(
+				"download failed: unexpected status %s",
+				resp.Status,
+			)
+		}
+
+		restart := written > 0 && resp.StatusCode == http.StatusOK
+		if resp.StatusCode == http.StatusPartialContent &&
+			rangeStart(resp.Header.Get("Content-Range")) != written {
+			// a range other than the one asked for; the bytes cannot be
+			// appended, so the download starts over
+			_ = resp.Body.Close()
+			if attempt >= opts.Retries {
+				return nil, fmt.Errorf(
+					"download failed: server sent range %q for a request from byte %d",
+					resp.Header.Get("Content-Range"),
+					written,
+				)
+			}
+			if err := resetDownload(out); err != nil {
+				return nil, err
+			}
+			written = 0
+			continue
+		}
+		if restart {
+			// server ignored the range request; start over
+			written = 0
+			if err := resetDownload(out); err != nil {
+				_ = resp.Body.Close()
+				return nil, err
+			}
+		}
+
+		if resp.ContentLength > 0 && written+resp.ContentLength > limit {
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf(
+				"remote file is %d bytes, exceeding the %d byte limit",
+				written+resp.ContentLength,
+				limit,
+			)
+		}
+
+		if out == nil {
+			ext := formatExtension(opts.Format)
+			// the path's extension only when it is a media one, not that of
+			// a script such as .php serving the file
+			if pathExt := strings.ToLower(path.Ext(u.Path)); ext == "" && audio.IsMediaFile(pathExt) {
+				ext = pathExt
+			}
+			if ext == "" {
+				ext = extensionForContentType(resp.Header.Get("Content-Type"))
+			}
+			destPath = filepath.Join(destDir, name+ext)
+			out, err = os.Create(destPath)
+			if err != nil {
+				_ = resp.Body.Close()
+				return nil, fmt.Errorf("failed to create input file: %w", err)
+			}
+		}
+
+		n, err := copyLimited(out, resp.Body, limit, written)
+		_ = resp.Body.Close()
+		written += n
+		if err == nil {
+			break
+		}
+		if errors.Is(err, errTooLarge) || ctx.Err() != nil ||
+			attempt >= opts.Retries {
+			return nil, fmt.Errorf("download failed: %w", err)
+		}
+	}
+
+	return &Media{Path: destPath, Name: name, Remote: true}, nil
+}
+
+var errTooLarge = errors.New("input exceeds size limit")
+
+// wait before the first retry of a download, doubled for each one after
+var retryBackoff = time.Second
+
+// waits before retry attempt+1, or until ctx is done
+func waitRetry(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(retryBackoff << min(attempt, 5))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reports whether a status may clear up on a later attempt
+func retryableStatus(code int) bool {
+	return code == http.StatusRequestTimeout ||
+		code == http.StatusTooManyRequests ||
+		code >= 500
+}
+
+// the first byte of a Content-Range of "bytes START-END/TOTAL", -1 when
+// it cannot be parsed
+func rangeStart(contentRange string) int64 {
+	var start, end int64
+	var total string
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%s", &start, &end, &total); err != nil {
+		return -1
+	}
+	return start
+}
+
+// empties a partial download so it can start over
+func resetDownload(out *os.File) error {
+	if out == nil {
+		return nil
+	}
+	if _, err := out.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind download: %w", err)
+	}
+	if err := out.Truncate(0); err != nil {
+		return fmt.Errorf("failed to reset download: %w", err)
+	}
+	return nil
+}
+
+// copies src to dst, failing once the total crosses limit
+func copyLimited(
+	dst io.Writer,
+	src io.Reader,
+	limit, already int64,
+) (int64, error) {
+	remaining := limit - already
+	n, err := io.Copy(dst, io.LimitReader(src, remaining+1))
+	if err != nil {
+		return n, err
+	}
+	if n > remaining {
+		return n, fmt.Errorf("%w of %d bytes", errTooLarge, limit)
+	}
+	return n, nil
+}
+
+func maxBytes(opts Options) int64 {
+	if opts.MaxBytes > 0 {
+		return opts.MaxBytes
+	}
+	return DefaultMaxBytes
+}
+
+// CheckFormat returns an error unless format, as given to --input-format,
+// is empty or the extension of a supported media file
+func CheckFormat(format string) error {
+	// a bare extension: nothing that could change the directory or name
+	// of the file it is given to
+	if ext := formatExtension(format); ext != "" && (filepath.Ext(ext) != ext || !audio.IsMediaFile(ext)) {
+		return fmt.Errorf(
+			"unsupported input format %q: use one of %s",
+			format,
+			strings.Join(audio.MediaExtensions(), ", "),
+		)
+	}
+	return nil
+}
+
+func formatExtension(format string) string {
+	format = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(format)), ".")
+	if format == "" {
+		return ""
+	}
+	return "." + format
+}
+
+// maps a MIME type to a container extension ffmpeg will recognize
+func extensionForContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	switch mediaType {
+	case "audio/mpeg", "audio/mp3":
+		return ".mp3"
+	case "audio/wav", "audio/wave", "audio/x-wav":
+		return ".wav"
+	case "audio/aac":
+		return ".aac"
+	case "audio/flac", "audio/x-flac":
+		return ".flac"
+	case "audio/ogg", "application/ogg":
+		return ".ogg"
+	case "audio/mp4", "audio/x-m4a":
+		return ".m4a"
+	case "video/mp4":
+		return ".mp4"
+	case "video/webm", "audio/webm":
+		return ".webm"
+	case "video/quicktime":
+		return ".mov"
+	case "video/x-msvideo", "video/avi":
+		return ".avi"
+	default:
+		// unknown containers are treated as video; ffmpeg probes the real
+		// format and audio-only inputs extract fine with -vn
+		return ".mkv"
+	}
+}
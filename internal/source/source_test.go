@@ -0,0 +1,333 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/httpclient"
+)
+
+func TestIsURL(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"https://example.com/episode.mp3", true},
+		{"http://example.com/video", true},
+		{"ftp://example.com/file.mp3", false},
+		{"video.mp4", false},
+		{"/tmp/video.mp4", false},
+		{"-", false},
+		{"https://", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := IsURL(tt.input); got != tt.want {
+				t.Errorf("IsURL(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsStreamingSite(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"https://www.youtube.com/watch?v=abc123", true},
+		{"https://youtu.be/abc123", true},
+		{"https://vimeo.com/12345", true},
+		{"https://m.youtube.com/watch?v=abc123", true},
+		{"https://example.com/episode.mp3", false},
+		{"https://notyoutube.com/watch", false},
+		{"video.mp4", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := IsStreamingSite(tt.input); got != tt.want {
+				t.Errorf(
+					"IsStreamingSite(%q) = %v, want %v",
+					tt.input,
+					got,
+					tt.want,
+				)
+			}
+		})
+	}
+}
+
+func TestResolveLocalFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "talk.mp3")
+	if err := os.WriteFile(path, []byte("audio"), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	media, err := Resolve(context.Background(), path, tmpDir, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Resolve error: %v", err)
+	}
+	if media.Path != path {
+		t.Errorf("expected path %q, got %q", path, media.Path)
+	}
+	if media.Name != filepath.Join(tmpDir, "talk") {
+		t.Errorf("unexpected name %q", media.Name)
+	}
+	if media.Remote {
+		t.Error("local file should not be marked remote")
+	}
+}
+
+func TestResolveStdin(t *testing.T) {
+	tmpDir := t.TempDir()
+	opts := DefaultOptions()
+	opts.Stdin = bytes.NewReader([]byte("ID3\x03\x00\x00\x00fake mp3 data"))
+	opts.Format = "mp3"
+
+	media, err := Resolve(context.Background(), Stdin, tmpDir, opts)
+	if err != nil {
+		t.Fatalf("Resolve error: %v", err)
+	}
+	if filepath.Ext(media.Path) != ".mp3" {
+		t.Errorf("expected .mp3 extension, got %q", media.Path)
+	}
+	data, err := os.ReadFile(media.Path)
+	if err != nil {
+		t.Fatalf("failed to read staged file: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("ID3")) {
+		t.Errorf("staged stdin content mismatch: %q", data)
+	}
+}
+
+func TestResolveStdinSizeLimit(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Stdin = bytes.NewReader(make([]byte, 2048))
+	opts.MaxBytes = 1024
+
+	if _, err := Resolve(
+		context.Background(),
+		Stdin,
+		t.TempDir(),
+		opts,
+	); err == nil {
+		t.Error("expected error for oversized stdin input")
+	}
+}
+
+func TestResolveURL(t *testing.T) {
+	payload := []byte("RIFF....WAVEfmt fake wav payload")
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "audio/wav")
+			_, _ = w.Write(payload)
+		}),
+	)
+	defer server.Close()
+
+	media, err := Resolve(
+		context.Background(),
+		server.URL+"/shows/episode",
+		t.TempDir(),
+		DefaultOptions(),
+	)
+	if err != nil {
+		t.Fatalf("Resolve error: %v", err)
+	}
+	if media.Name != "episode" {
+		t.Errorf("expected name 'episode', got %q", media.Name)
+	}
+	if filepath.Ext(media.Path) != ".wav" {
+		t.Errorf("expected .wav from content type, got %q", media.Path)
+	}
+	data, err := os.ReadFile(media.Path)
+	if err != nil {
+		t.Fatalf("failed to read download: %v", err)
+	}
+	if !bytes.Equal(data, payload) {
+		t.Errorf("downloaded content mismatch")
+	}
+}
+
+func TestResolveURLRejectsOversizedContentLength(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(make([]byte, 4096))
+		}),
+	)
+	defer server.Close()
+
+	opts := DefaultOptions()
+	opts.MaxBytes = 1024
+
+	if _, err := Resolve(
+		context.Background(),
+		server.URL+"/big.mp3",
+		t.TempDir(),
+		opts,
+	); err == nil {
+		t.Error("expected error for oversized download")
+	}
+}
+
+func TestResolveURLRetries(t *testing.T) {
+	retryBackoff = 0
+	defer func() { retryBackoff = time.Second }()
+	payload := []byte("0123456789abcdefghij")
+
+	tests := []struct {
+		name     string
+		handler  func(w http.ResponseWriter, r *http.Request, attempt int)
+		wantErr  bool
+		requests int
+	}{
+		{
+			name: "server error retried",
+			handler: func(w http.ResponseWriter, r *http.Request, attempt int) {
+				if attempt == 0 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				_, _ = w.Write(payload)
+			},
+			requests: 2,
+		},
+		{
+			name: "not found fails at once",
+			handler: func(w http.ResponseWriter, r *http.Request, attempt int) {
+				http.NotFound(w, r)
+			},
+			wantErr:  true,
+			requests: 1,
+		},
+		{
+			name: "wrong range starts over",
+			handler: func(w http.ResponseWriter, r *http.Request, attempt int) {
+				switch {
+				case attempt == 0:
+					// drops the connection halfway
+					w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+					_, _ = w.Write(payload[:10])
+				case r.Header.Get("Range") != "":
+					w.Header().Set("Content-Range", fmt.Sprintf("bytes 5-%d/%d", len(payload)-1, len(payload)))
+					w.WriteHeader(http.StatusPartialContent)
+					_, _ = w.Write(payload[5:])
+				default:
+					_, _ = w.Write(payload)
+				}
+			},
+			requests: 3,
+		},
+		{
+			name: "matching range resumes",
+			handler: func(w http.ResponseWriter, r *http.Request, attempt int) {
+				if attempt == 0 {
+					w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+					_, _ = w.Write(payload[:10])
+					return
+				}
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes 10-%d/%d", len(payload)-1, len(payload)))
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = w.Write(payload[10:])
+			},
+			requests: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(w, r, int(requests.Add(1))-1)
+			}))
+			defer server.Close()
+
+			media, err := Resolve(context.Background(), server.URL+"/episode.mp3", t.TempDir(), DefaultOptions())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := int(requests.Load()); got != tt.requests {
+				t.Errorf("made %d requests, want %d", got, tt.requests)
+			}
+			if tt.wantErr {
+				return
+			}
+			if data, _ := os.ReadFile(media.Path); !bytes.Equal(data, payload) {
+				t.Errorf("downloaded %q, want %q", data, payload)
+			}
+		})
+	}
+}
+
+func TestResolveURLExtension(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		_, _ = w.Write([]byte("ID3 fake mp3"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path, format, want string
+	}{
+		{"/media/episode.m4a", "", ".m4a"},
+		{"/download.php", "", ".mp3"},
+		{"/download.php", "ogg", ".ogg"},
+	}
+	for _, tt := range tests {
+		opts := DefaultOptions()
+		opts.Format = tt.format
+		media, err := Resolve(context.Background(), server.URL+tt.path, t.TempDir(), opts)
+		if err != nil {
+			t.Fatalf("Resolve(%s) error = %v", tt.path, err)
+		}
+		if got := filepath.Ext(media.Path); got != tt.want {
+			t.Errorf("Resolve(%s, %q) extension = %q, want %q", tt.path, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{"", false},
+		{"mp3", false},
+		{".MKV", false},
+		{"php", true},
+		{"../../etc/cron.d/x", true},
+		{"a/b.mp3", true},
+		{"mp3/", true},
+	}
+	for _, tt := range tests {
+		if err := CheckFormat(tt.format); (err != nil) != tt.wantErr {
+			t.Errorf("CheckFormat(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+		}
+	}
+}
+
+func TestResolveURLPublicOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ID3 fake mp3"))
+	}))
+	defer server.Close()
+
+	opts := DefaultOptions()
+	opts.PublicOnly = true
+	_, err := Resolve(context.Background(), server.URL+"/episode.mp3", t.TempDir(), opts)
+	if !errors.Is(err, httpclient.ErrPrivateAddress) {
+		t.Errorf("Resolve() of a loopback URL error = %v, want ErrPrivateAddress", err)
+	}
+}
@@ -0,0 +1,79 @@
+package source
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadYTDLP(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho yt-dlp\n")
+	digest := sha256.Sum256(binary)
+	good := hex.EncodeToString(digest[:])
+
+	tests := []struct {
+		name    string
+		sums    string
+		skip    bool
+		wantErr bool
+	}{
+		{"match", good + "  yt-dlp_linux\n", false, false},
+		{"match in binary mode", good + " *yt-dlp_linux\n", false, false},
+		{"mismatch", sha256Hex("other") + "  yt-dlp_linux\n", false, true},
+		{"mismatch skipped", sha256Hex("other") + "  yt-dlp_linux\n", true, false},
+		{"unpublished", good + "  yt-dlp_macos\n", false, true},
+		{"unpublished skipped", good + "  yt-dlp_macos\n", true, false},
+		{"missing sums", "", false, true},
+		{"malformed", "abc  yt-dlp_linux\n", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/SHA2-256SUMS":
+					if tt.sums == "" {
+						http.NotFound(w, r)
+						return
+					}
+					_, _ = w.Write([]byte(tt.sums))
+				case "/yt-dlp_linux":
+					_, _ = w.Write(binary)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+			original := ytdlpReleaseBaseURL
+			ytdlpReleaseBaseURL = server.URL
+			defer func() { ytdlpReleaseBaseURL = original }()
+			SetInsecureSkipVerify(tt.skip)
+			defer SetInsecureSkipVerify(false)
+
+			binPath := filepath.Join(t.TempDir(), "yt-dlp")
+			err := downloadYTDLP(server.Client(), "yt-dlp_linux", binPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("downloadYTDLP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			_, statErr := os.Stat(binPath)
+			if tt.wantErr && statErr == nil {
+				t.Error("rejected download was installed")
+			}
+			if !tt.wantErr && statErr != nil {
+				t.Errorf("download not installed: %v", statErr)
+			}
+			if entries, _ := os.ReadDir(filepath.Dir(binPath)); tt.wantErr && len(entries) > 0 {
+				t.Errorf("left behind %d files", len(entries))
+			}
+		})
+	}
+}
+
+func sha256Hex(s string) string {
+	digest := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(digest[:])
+}
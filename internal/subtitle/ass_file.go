@@ -3,6 +3,7 @@ package subtitle
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strconv"
@@ -38,6 +39,10 @@ func parseASSFile(path string) (*ASSFile, error) {
 		_ = file.Close()
 	}()
 
+	return parseASS(file)
+}
+
+func parseASS(r io.Reader) (*ASSFile, error) {
 	assFile := &ASSFile{
 		preEventsLines:        make([]string, 0),
 		dialogues:             make([]ASSDialogue, 0),
@@ -45,7 +50,7 @@ func parseASSFile(path string) (*ASSFile, error) {
 		textColumnIndex:       -1,
 	}
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	inEventsSection := false
 	lineNum := 0
 
@@ -209,6 +214,7 @@ func (f *ASSFile) Format() Format {
 
 func (f *ASSFile) Subtitle() *Subtitle {
 	entries := make([]Entry, len(f.dialogues))
+	nameIdx := f.column("name")
 
 	for i, d := range f.dialogues {
 		startTime, endTime := f.parseDialogueTimes(d)
@@ -221,6 +227,9 @@ func (f *ASSFile) Subtitle() *Subtitle {
 			EndTime:   endTime,
 			Text:      text,
 		}
+		if nameIdx >= 0 && nameIdx < len(d.FieldsBefore) {
+			entries[i].Speaker = strings.TrimSpace(d.FieldsBefore[nameIdx])
+		}
 	}
 
 	return &Subtitle{
@@ -229,9 +238,30 @@ func (f *ASSFile) Subtitle() *Subtitle {
 	}
 }
 
-func (f *ASSFile) parseDialogueTimes(
-	d ASSDialogue,
-) (time.Duration, time.Duration) {
+// PlayRes returns the script resolution of the [Script Info] section, the
+// canvas its positions and font sizes are given in; zero when not set
+func (f *ASSFile) PlayRes() (width, height int) {
+	for _, line := range f.preEventsLines {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n <= 0 {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "PlayResX":
+			width = n
+		case "PlayResY":
+			height = n
+		}
+	}
+	return width, height
+}
+
+// positions of the Start and End columns in the Format line, -1 if absent
+func (f *ASSFile) timeColumns() (int, int) {
 	startIdx := -1
 	endIdx := -1
 	for i, col := range f.formatColumns {
@@ -242,6 +272,23 @@ func (f *ASSFile) parseDialogueTimes(
 			endIdx = i
 		}
 	}
+	return startIdx, endIdx
+}
+
+// position of a column in the Format line, -1 if absent
+func (f *ASSFile) column(name string) int {
+	for i, col := range f.formatColumns {
+		if strings.EqualFold(col, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *ASSFile) parseDialogueTimes(
+	d ASSDialogue,
+) (time.Duration, time.Duration) {
+	startIdx, endIdx := f.timeColumns()
 
 	var startTime, endTime time.Duration
 
@@ -311,6 +358,27 @@ func (f *ASSFile) SetText(index int, text string) error {
 	return nil
 }
 
+func (f *ASSFile) SetTiming(index int, start, end time.Duration) error {
+	if index < 0 || index >= len(f.dialogues) {
+		return fmt.Errorf(
+			"index %d out of range (0-%d)",
+			index,
+			len(f.dialogues)-1,
+		)
+	}
+
+	startIdx, endIdx := f.timeColumns()
+	d := &f.dialogues[index]
+	if startIdx < 0 || startIdx >= len(d.FieldsBefore) ||
+		endIdx < 0 || endIdx >= len(d.FieldsBefore) {
+		return fmt.Errorf("dialogue %d has no Start/End fields", index)
+	}
+	d.FieldsBefore[startIdx] = formatASSTime(start)
+	d.FieldsBefore[endIdx] = formatASSTime(end)
+
+	return nil
+}
+
 func (f *ASSFile) SetTextWithOverlay(index int, translatedText string) error {
 	if index < 0 || index >= len(f.dialogues) {
 		return fmt.Errorf(
@@ -330,44 +398,26 @@ func (f *ASSFile) SetTextWithOverlay(index int, translatedText string) error {
 }
 
 func (f *ASSFile) Write(path string) error {
-	if err := ensureDir(path); err != nil {
-		return err
-	}
-
-	file, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("failed to create ASS file: %w", err)
-	}
-	defer func() {
-		_ = file.Close()
-	}()
+	return writeStyled(path, f.render())
+}
 
-	writer := bufio.NewWriter(file)
+func (f *ASSFile) Encode(w io.Writer) error {
+	return encodeStyled(w, f.render())
+}
 
+func (f *ASSFile) render() string {
+	var sb strings.Builder
 	for _, line := range f.preEventsLines {
-		if _, err := writer.WriteString(line + "\n"); err != nil {
-			return err
-		}
+		sb.WriteString(line + "\n")
 	}
-
-	if _, err := writer.WriteString(f.formatLine + "\n"); err != nil {
-		return err
-	}
-
+	sb.WriteString(f.formatLine + "\n")
 	for _, d := range f.dialogues {
-		line := f.buildDialogueLine(d)
-		if _, err := writer.WriteString(line + "\n"); err != nil {
-			return err
-		}
+		sb.WriteString(f.buildDialogueLine(d) + "\n")
 	}
-
 	for _, line := range f.nonDialogueEventLines {
-		if _, err := writer.WriteString(line + "\n"); err != nil {
-			return err
-		}
+		sb.WriteString(line + "\n")
 	}
-
-	return writer.Flush()
+	return sb.String()
 }
 
 func (f *ASSFile) buildDialogueLine(d ASSDialogue) string {
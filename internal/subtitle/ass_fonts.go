@@ -0,0 +1,161 @@
+package subtitle
+
+import (
+	"strings"
+)
+
+// FontUse is a font an ASS file names and the text drawn in it
+type FontUse struct {
+	Font string
+	Text string // of the dialogue lines, override tags removed
+}
+
+// Fonts returns the font names the file's styles and \fn override tags
+// use, in order of first use. A vertical-text @ prefix is dropped, as
+// players look the font up without it.
+func (f *ASSFile) Fonts() []string {
+	uses := f.FontUses()
+	names := make([]string, len(uses))
+	for i, use := range uses {
+		names[i] = use.Font
+	}
+	return names
+}
+
+// FontUses returns the fonts of Fonts with the text each one draws,
+// following the style of each line and its \fn and \r override tags. A
+// style no line uses draws no text.
+func (f *ASSFile) FontUses() []FontUse {
+	var uses []FontUse
+	text := make(map[string]*strings.Builder)
+	add := func(name, drawn string) {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+		if name == "" {
+			return
+		}
+		key := strings.ToLower(name)
+		sb, ok := text[key]
+		if !ok {
+			sb = &strings.Builder{}
+			text[key] = sb
+			uses = append(uses, FontUse{Font: name})
+		}
+		sb.WriteString(drawn)
+	}
+
+	styles := f.styleFonts()
+	for _, style := range styles {
+		add(style.font, "")
+	}
+	styleFont := func(name string) string {
+		for _, style := range styles {
+			if style.name == name {
+				return style.font
+			}
+		}
+		for _, style := range styles {
+			if style.name == "Default" {
+				return style.font
+			}
+		}
+		return ""
+	}
+
+	styleIdx := f.column("style")
+	for _, d := range f.dialogues {
+		lineStyle := ""
+		if styleIdx >= 0 && styleIdx < len(d.FieldsBefore) {
+			lineStyle = strings.TrimSpace(d.FieldsBefore[styleIdx])
+		}
+		font := styleFont(lineStyle)
+		rest := d.Text
+		for rest != "" {
+			if rest[0] == '{' {
+				if end := strings.IndexByte(rest, '}'); end >= 0 {
+					for _, tag := range strings.Split(rest[1:end], `\`)[1:] {
+						switch {
+						case strings.HasPrefix(tag, "fn"):
+							font = strings.TrimSpace(tag[2:])
+							if font == "" {
+								font = styleFont(lineStyle)
+							}
+							add(font, "")
+						case strings.HasPrefix(tag, "r"):
+							if name := strings.TrimSpace(tag[1:]); name != "" {
+								font = styleFont(name)
+							} else {
+								font = styleFont(lineStyle)
+							}
+						}
+					}
+					rest = rest[end+1:]
+					continue
+				}
+			}
+			next := strings.IndexByte(rest[1:], '{') + 1
+			if next == 0 {
+				next = len(rest)
+			}
+			add(font, assPlainText(rest[:next]))
+			rest = rest[next:]
+		}
+	}
+
+	for i := range uses {
+		uses[i].Text = text[strings.ToLower(uses[i].Font)].String()
+	}
+	return uses
+}
+
+type assStyleFont struct {
+	name, font string
+}
+
+// the font of each style in the [V4+ Styles] or [V4 Styles] section
+func (f *ASSFile) styleFonts() []assStyleFont {
+	var styles []assStyleFont
+	inStyles := false
+	nameColumn, fontColumn, columns := -1, -1, 0
+	for _, line := range f.preEventsLines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section := strings.ToLower(trimmed)
+			inStyles = section == "[v4+ styles]" || section == "[v4 styles]"
+			continue
+		}
+		if !inStyles {
+			continue
+		}
+		if format, ok := strings.CutPrefix(trimmed, "Format:"); ok {
+			cols := strings.Split(format, ",")
+			columns, nameColumn, fontColumn = len(cols), -1, -1
+			for i, col := range cols {
+				switch strings.ToLower(strings.TrimSpace(col)) {
+				case "name":
+					nameColumn = i
+				case "fontname":
+					fontColumn = i
+				}
+			}
+			continue
+		}
+		style, ok := strings.CutPrefix(trimmed, "Style:")
+		if !ok || nameColumn < 0 || fontColumn < 0 {
+			continue
+		}
+		fields := splitASSFields(strings.TrimSpace(style), columns)
+		if nameColumn < len(fields) && fontColumn < len(fields) {
+			styles = append(styles, assStyleFont{
+				name: strings.TrimSpace(fields[nameColumn]),
+				font: fields[fontColumn],
+			})
+		}
+	}
+	return styles
+}
+
+// dialogue text outside override blocks as drawn: line breaks and hard
+// spaces become spaces
+func assPlainText(text string) string {
+	return strings.NewReplacer(`\N`, " ", `\n`, " ", `\h`, " ").Replace(text)
+}
@@ -0,0 +1,42 @@
+package subtitle
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestASSFonts(t *testing.T) {
+	content := `[Script Info]
+ScriptType: v4.00+
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Noto Sans JP,20,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,2,10,10,10,1
+Style: Sign,@MS Gothic,20,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,2,10,10,10,1
+Style: Alt,noto sans jp,20,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,2,10,10,10,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:00:01.00,0:00:04.00,Default,,0,0,0,,{\b1\fnComic Sans MS}Hello{\fn} there
+Dialogue: 0,0:00:05.00,0:00:06.00,Sign,,0,0,0,,Not a tag: \fnArial
+Dialogue: 0,0:00:07.00,0:00:08.00,Default,,0,0,0,,{\fnComic Sans MS}{\r}
+`
+	f, err := parseASS(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Noto Sans JP", "MS Gothic", "Comic Sans MS"}
+	if got := f.Fonts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Fonts() = %q, want %q", got, want)
+	}
+
+	uses := []FontUse{
+		{Font: "Noto Sans JP", Text: " there"},
+		{Font: "MS Gothic", Text: "Not a tag: \\fnArial"},
+		{Font: "Comic Sans MS", Text: "Hello"},
+	}
+	if got := f.FontUses(); !reflect.DeepEqual(got, uses) {
+		t.Errorf("FontUses() = %q, want %q", got, uses)
+	}
+}
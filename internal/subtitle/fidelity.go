@@ -0,0 +1,203 @@
+package subtitle
+
+import (
+	"strconv"
+	"strings"
+)
+
+// lines of an SRT entry or VTT cue in the file it was read from, numbered
+// from 1
+type lineSpan struct {
+	first    int    // the index or identifier line, or the timing line without one
+	timing   int    // 0 when the entry has no timing line
+	last     int    // the last text line
+	settings string // what follows the timestamps on the timing line, such as VTT cue settings
+}
+
+// an entry as it was read
+type rawEntry struct {
+	entry    Entry
+	text     string // its lines, line endings included
+	gap      string // the blank lines, comments, and such up to the next entry
+	id       string // its index or identifier line, "" for a VTT cue without one
+	timing   string // its timing line
+	settings string
+	newline  string
+}
+
+// the text of a file as read, split around its entries, so the ones left
+// untouched can be written back as they were
+type rawSource struct {
+	header  string      // everything before the first entry
+	entries []*rawEntry // by index of the file's entries; nil for entries made since
+	newline string      // the line ending most lines of the file use
+}
+
+func newRawSource(content string, entries []Entry, spans []lineSpan) *rawSource {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	s := &rawSource{header: content, newline: "\n"}
+	if crlf := strings.Count(content, "\r\n"); crlf > 0 && 2*crlf >= strings.Count(content, "\n") {
+		s.newline = "\r\n"
+	}
+	if len(spans) == 0 {
+		return s
+	}
+
+	s.header = strings.Join(lines[:spans[0].first-1], "")
+	s.entries = make([]*rawEntry, len(entries))
+	for i, span := range spans {
+		next := len(lines)
+		if i+1 < len(spans) {
+			next = spans[i+1].first - 1
+		}
+		raw := &rawEntry{
+			entry:    entries[i],
+			text:     strings.Join(lines[span.first-1:span.last], ""),
+			gap:      strings.Join(lines[span.last:next], ""),
+			settings: span.settings,
+			newline:  s.newline,
+		}
+		if strings.HasSuffix(lines[span.first-1], "\r\n") {
+			raw.newline = "\r\n"
+		} else if strings.HasSuffix(lines[span.first-1], "\n") {
+			raw.newline = "\n"
+		}
+		if span.timing > span.first {
+			raw.id = strings.TrimRight(lines[span.first-1], "\r\n")
+		}
+		if span.timing > 0 {
+			raw.timing = strings.TrimRight(lines[span.timing-1], "\r\n")
+		}
+		s.entries[i] = raw
+	}
+	return s
+}
+
+// the raw entry of index, nil when there is none
+func (s *rawSource) entry(index int) *rawEntry {
+	if s == nil || index >= len(s.entries) {
+		return nil
+	}
+	return s.entries[index]
+}
+
+// follows a reshape: a piece that is an entry left whole keeps its raw
+// entry, split and merged ones are written anew
+func (s *rawSource) reshape(pieces []Piece, m IndexMap) {
+	if s == nil {
+		return
+	}
+	entries := make([]*rawEntry, len(pieces))
+	for i, p := range pieces {
+		if len(p.From) == 1 && len(m[p.From[0]]) == 1 {
+			entries[i] = s.entry(p.From[0])
+		}
+	}
+	s.entries = entries
+}
+
+// follows Keep
+func (s *rawSource) keep(indices []int) {
+	if s == nil {
+		return
+	}
+	entries := make([]*rawEntry, len(indices))
+	for i, index := range indices {
+		entries[i] = s.entry(index)
+	}
+	s.entries = entries
+}
+
+// writes entries as read where they are unchanged. A changed entry keeps
+// its original index or identifier, its cue settings, the blank lines
+// after it, and its line endings; an entry made by a split or merge
+// continues the numbering of the one before it.
+func (s *rawSource) render(format Format, entries []Entry) string {
+	var sb strings.Builder
+	sb.WriteString(s.header)
+	if format == FormatVTT && !strings.Contains(s.header, "WEBVTT") {
+		sb.WriteString("WEBVTT" + s.newline + s.newline)
+	}
+
+	number := 0
+	for i, entry := range entries {
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteString(s.newline)
+		}
+		raw := s.entry(i)
+		if raw != nil && sameEntry(entry, raw.entry) {
+			sb.WriteString(raw.text)
+			sb.WriteString(raw.gap)
+			number = raw.entry.Index
+			continue
+		}
+
+		nl, settings := s.newline, ""
+		if raw != nil {
+			nl, settings = raw.newline, raw.settings
+		}
+		switch {
+		case raw != nil && raw.id != "":
+			sb.WriteString(raw.id + nl)
+			number = raw.entry.Index
+		case format == FormatSRT:
+			number++
+			sb.WriteString(strconv.Itoa(number) + nl)
+		}
+
+		if raw != nil && raw.timing != "" &&
+			entry.StartTime == raw.entry.StartTime && entry.EndTime == raw.entry.EndTime {
+			sb.WriteString(raw.timing)
+		} else if format == FormatVTT {
+			sb.WriteString(formatVTTTime(entry.StartTime) + " --> " + formatVTTTime(entry.EndTime) + settings)
+		} else {
+			sb.WriteString(formatSRTTime(entry.StartTime) + " --> " + formatSRTTime(entry.EndTime) + settings)
+		}
+		sb.WriteString(nl)
+
+		text := srtText(entry)
+		if format == FormatVTT {
+			text = vttText(entry)
+		}
+		sb.WriteString(strings.ReplaceAll(text, "\n", nl))
+		if raw == nil {
+			sb.WriteString(nl + nl)
+			continue
+		}
+		if strings.HasSuffix(raw.text, "\n") {
+			sb.WriteString(nl)
+		}
+		sb.WriteString(raw.gap)
+	}
+	return sb.String()
+}
+
+// reports whether an entry is as it was read; its position may differ
+func sameEntry(a, b Entry) bool {
+	a.Index = b.Index
+	return a == b
+}
+
+func writePreserved(path string, s *rawSource, format Format, entries []Entry) error {
+	return writeStyled(path, s.render(format, entries))
+}
+
+// PreserveFormatting makes f write the entries left untouched exactly as
+// they were read, with their numbering, blank lines, and line endings, and
+// keep the numbering and line endings of the ones changed. It reports
+// whether f's format supports it: SRT and VTT files read by Open or Read
+// do; ASS files always keep their layout.
+func PreserveFormatting(f File) bool {
+	switch f := f.(type) {
+	case *SRTFile:
+		f.preserve = f.source != nil
+		return f.preserve
+	case *VTTFile:
+		f.preserve = f.source != nil
+		return f.preserve
+	}
+	return false
+}
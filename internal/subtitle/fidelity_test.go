@@ -0,0 +1,101 @@
+package subtitle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPreserveFormattingSRT(t *testing.T) {
+	content := "\ufeff7\r\n00:00:01,000 --> 00:00:02,000\r\nHello\r\n\r\n\r\n" +
+		"9\r\n00:00:03,000 --> 00:00:04,000 X1:10 X2:20\r\nHow are you?\r\nFine.\r\n\r\n" +
+		"12\r\n00:00:05,000 --> 00:00:06,000\r\nBye"
+	tests := []struct {
+		name   string
+		change func(f File) error
+		want   string
+	}{
+		{
+			"untouched",
+			func(f File) error { return nil },
+			content,
+		},
+		{
+			"changed entry keeps its number, coordinates, and line endings",
+			func(f File) error {
+				if err := f.SetText(1, "¿Cómo estás?\nBien."); err != nil {
+					return err
+				}
+				return f.SetTiming(1, 3500_000_000, 4000_000_000)
+			},
+			"\ufeff7\r\n00:00:01,000 --> 00:00:02,000\r\nHello\r\n\r\n\r\n" +
+				"9\r\n00:00:03,500 --> 00:00:04,000 X1:10 X2:20\r\n¿Cómo estás?\r\nBien.\r\n\r\n" +
+				"12\r\n00:00:05,000 --> 00:00:06,000\r\nBye",
+		},
+		{
+			"split entry continues the numbering",
+			func(f File) error {
+				_, err := f.Reshape([]Piece{
+					{From: []int{0}, StartTime: 1000_000_000, EndTime: 2000_000_000, Text: "Hello"},
+					{From: []int{1}, StartTime: 3000_000_000, EndTime: 3500_000_000, Text: "How are you?"},
+					{From: []int{1}, StartTime: 3500_000_000, EndTime: 4000_000_000, Text: "Fine."},
+					{From: []int{2}, StartTime: 5000_000_000, EndTime: 6000_000_000, Text: "Bye"},
+				})
+				return err
+			},
+			"\ufeff7\r\n00:00:01,000 --> 00:00:02,000\r\nHello\r\n\r\n\r\n" +
+				"8\r\n00:00:03,000 --> 00:00:03,500\r\nHow are you?\r\n\r\n" +
+				"9\r\n00:00:03,500 --> 00:00:04,000\r\nFine.\r\n\r\n" +
+				"12\r\n00:00:05,000 --> 00:00:06,000\r\nBye",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Read(strings.NewReader(content), FormatSRT)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !PreserveFormatting(f) {
+				t.Fatal("PreserveFormatting() = false")
+			}
+			if err := tt.change(f); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := f.Encode(&out); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("Encode() =\n%q\nwant\n%q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPreserveFormattingVTT(t *testing.T) {
+	content := "WEBVTT\nKind: captions\n\nNOTE kept as is\n\n" +
+		"intro\n00:01.000 --> 00:02.000 align:start\n<v Ana>Hello\n\n" +
+		"00:03.000 --> 00:04.000\n<lang fr>Bonjour</lang>\n\n" +
+		"00:05.000 --> 00:06.000\nBye\n"
+	f, err := Read(strings.NewReader(content), FormatVTT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	PreserveFormatting(f)
+	if err := f.SetText(0, "Hola"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Keep([]int{0, 2}); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := f.Encode(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := "WEBVTT\nKind: captions\n\nNOTE kept as is\n\n" +
+		"intro\n00:01.000 --> 00:02.000 align:start\n<v Ana>Hola\n\n" +
+		"00:05.000 --> 00:06.000\nBye\n"
+	if out.String() != want {
+		t.Errorf("Encode() =\n%q\nwant\n%q", out.String(), want)
+	}
+}
@@ -12,8 +12,19 @@ type DefaultGenerator struct {
 	MaxLinesPerSub  int
 	MinDuration     time.Duration
 	MaxDuration     time.Duration
+	// the most an entry too short to read may run on into the silence
+	// after it; 0 keeps the segment timings
+	StretchIntoSilence time.Duration
 }
 
+const (
+	// characters a viewer reads in a second
+	readingRate = 17
+	// the least silence kept between a stretched entry and the next one,
+	// about two frames
+	minCueGap = 80 * time.Millisecond
+)
+
 func NewDefaultGenerator() *DefaultGenerator {
 	return &DefaultGenerator{
 		MaxCharsPerLine: 42, // Standard subtitle line length
@@ -51,17 +62,53 @@ func (g *DefaultGenerator) Generate(segments []Segment) (*Subtitle, error) {
 				StartTime: seg.StartTime,
 				EndTime:   seg.EndTime,
 				Text:      g.formatText(text),
+				Speaker:   seg.Speaker,
+				Language:  seg.Language,
 			})
 			index++
 		}
 	}
 
+	if g.StretchIntoSilence > 0 {
+		g.stretch(entries)
+	}
+
 	return &Subtitle{
 		Entries: entries,
 		Format:  string(FormatSRT),
 	}, nil
 }
 
+// lets entries shown for less than their reading time, or MinDuration,
+// end later, into the silence before the next entry: by StretchIntoSilence
+// at most, never past MaxDuration, and minCueGap short of the next entry
+func (g *DefaultGenerator) stretch(entries []Entry) {
+	for i := range entries {
+		e := &entries[i]
+		need := max(readingTime(e.Text), g.MinDuration)
+		if g.MaxDuration > 0 {
+			need = min(need, g.MaxDuration)
+		}
+		short := need - (e.EndTime - e.StartTime)
+		if short <= 0 {
+			continue
+		}
+		end := e.EndTime + min(short, g.StretchIntoSilence)
+		if i+1 < len(entries) {
+			end = min(end, entries[i+1].StartTime-minCueGap)
+		}
+		if end > e.EndTime {
+			e.EndTime = end
+		}
+	}
+}
+
+// how long text takes to read at readingRate
+func readingTime(text string) time.Duration {
+	chars := utf8.RuneCountInString(strings.ReplaceAll(text, "\n", ""))
+	return time.Duration(chars) * time.Second / readingRate
+}
+
 func (g *DefaultGenerator) needsSplit(
 	text string,
 	duration time.Duration,
@@ -134,6 +181,8 @@ func (g *DefaultGenerator) splitSegment(seg Segment, startIndex int) []Entry {
 			StartTime: currentStart,
 			EndTime:   currentEnd,
 			Text:      g.formatText(splitText),
+			Speaker:   seg.Speaker,
+			Language:  seg.Language,
 		})
 
 		currentStart = currentEnd
@@ -0,0 +1,88 @@
+package subtitle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerateStretchIntoSilence(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name     string
+		stretch  time.Duration
+		segments []Segment
+		wantEnds []time.Duration
+	}{
+		{
+			name:    "off",
+			stretch: 0,
+			segments: []Segment{
+				{StartTime: 0, EndTime: 300 * ms, Text: "Yes."},
+				{StartTime: 5 * time.Second, EndTime: 6 * time.Second, Text: "Come in."},
+			},
+			wantEnds: []time.Duration{300 * ms, 6 * time.Second},
+		},
+		{
+			name:    "capped by the stretch",
+			stretch: 500 * ms,
+			segments: []Segment{
+				{StartTime: 0, EndTime: 300 * ms, Text: "Yes."},
+				{StartTime: 5 * time.Second, EndTime: 6 * time.Second, Text: "Come in."},
+			},
+			wantEnds: []time.Duration{800 * ms, 6 * time.Second},
+		},
+		{
+			name:    "up to the minimum duration",
+			stretch: 5 * time.Second,
+			segments: []Segment{
+				{StartTime: 0, EndTime: 300 * ms, Text: "Yes."},
+			},
+			wantEnds: []time.Duration{time.Second},
+		},
+		{
+			name:    "up to the reading time",
+			stretch: 5 * time.Second,
+			segments: []Segment{
+				// 34 characters take two seconds to read
+				{StartTime: 0, EndTime: 1500 * ms, Text: "I told you never to open the door."},
+			},
+			wantEnds: []time.Duration{2 * time.Second},
+		},
+		{
+			name:    "short of the next entry",
+			stretch: 5 * time.Second,
+			segments: []Segment{
+				{StartTime: 0, EndTime: 300 * ms, Text: "Yes."},
+				{StartTime: 600 * ms, EndTime: 2 * time.Second, Text: "Come in."},
+			},
+			wantEnds: []time.Duration{520 * ms, 2 * time.Second},
+		},
+		{
+			name:    "overlapping entries",
+			stretch: 5 * time.Second,
+			segments: []Segment{
+				{StartTime: 0, EndTime: 300 * ms, Text: "Yes."},
+				{StartTime: 200 * ms, EndTime: 2 * time.Second, Text: "Come in."},
+			},
+			wantEnds: []time.Duration{300 * ms, 2 * time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewDefaultGenerator()
+			g.StretchIntoSilence = tt.stretch
+			sub, err := g.Generate(tt.segments)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if len(sub.Entries) != len(tt.wantEnds) {
+				t.Fatalf("got %d entries, want %d", len(sub.Entries), len(tt.wantEnds))
+			}
+			for i, want := range tt.wantEnds {
+				if got := sub.Entries[i].EndTime; got != want {
+					t.Errorf("entry %d ends at %s, want %s", i+1, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package subtitle
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LearningCue is a cue of a language-learning track: the original line,
+// how it is read, and its translation
+type LearningCue struct {
+	StartTime   time.Duration
+	EndTime     time.Duration
+	Speaker     string
+	Original    string
+	Reading     string // romanization or kana; may be empty
+	Translation string
+}
+
+// styles of the three lines of a learning cue
+const (
+	LearningStyleOriginal    = "Original"
+	LearningStyleReading     = "Reading"
+	LearningStyleTranslation = "Translation"
+)
+
+// writes cues as an ASS track whose dialogues stack the original, its
+// reading, and the translation, each line in its own style so players and
+// editors can restyle or hide them separately
+type LearningWriter struct {
+	Title    string
+	FontName string
+	FontSize int
+}
+
+func NewLearningWriter() *LearningWriter {
+	return &LearningWriter{
+		Title:    "Lipi Learning Subtitles",
+		FontName: "Arial",
+		FontSize: 20,
+	}
+}
+
+// writes the cues to an ASS file
+func (w *LearningWriter) Write(cues []LearningCue, path string) error {
+	return writeStyled(path, w.render(cues))
+}
+
+// encodes the cues in ASS format to out
+func (w *LearningWriter) Encode(cues []LearningCue, out io.Writer) error {
+	return encodeStyled(out, w.render(cues))
+}
+
+// override blocks such as {\an8}
+var assOverrideRegex = regexp.MustCompile(`\{[^}]*\}`)
+
+func (w *LearningWriter) render(cues []LearningCue) string {
+	var sb strings.Builder
+
+	sb.WriteString("[Script Info]\n")
+	sb.WriteString(fmt.Sprintf("Title: %s\n", w.Title))
+	sb.WriteString("ScriptType: v4.00+\n")
+	sb.WriteString("Collisions: Normal\n")
+	sb.WriteString("PlayDepth: 0\n\n")
+
+	// the reading is smaller and the translation tinted, so the original
+	// stays the line read first
+	readingSize := w.FontSize * 7 / 10
+	sb.WriteString("[V4+ Styles]\n")
+	sb.WriteString(
+		"Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\n",
+	)
+	sb.WriteString(fmt.Sprintf(
+		"Style: %s,%s,%d,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,2,10,10,10,1\n",
+		LearningStyleOriginal, w.FontName, w.FontSize,
+	))
+	sb.WriteString(fmt.Sprintf(
+		"Style: %s,%s,%d,&H00C0C0C0,&H000000FF,&H00000000,&H00000000,0,1,0,0,100,100,0,0,1,1,1,2,10,10,10,1\n",
+		LearningStyleReading, w.FontName, readingSize,
+	))
+	sb.WriteString(fmt.Sprintf(
+		"Style: %s,%s,%d,&H0000E6FF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,2,10,10,10,1\n\n",
+		LearningStyleTranslation, w.FontName, w.FontSize,
+	))
+
+	sb.WriteString("[Events]\n")
+	sb.WriteString(
+		"Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n",
+	)
+	for _, cue := range cues {
+		sb.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,%s,%s,0,0,0,,%s\n",
+			formatASSTime(cue.StartTime),
+			formatASSTime(cue.EndTime),
+			LearningStyleOriginal,
+			strings.ReplaceAll(cue.Speaker, ",", ""),
+			learningText(cue)))
+	}
+
+	return sb.String()
+}
+
+// the lines of a cue, each reset to its style; an empty reading is left
+// out rather than leaving a blank line
+func learningText(cue LearningCue) string {
+	lines := []string{learningLine(cue.Original)}
+	if reading := learningLine(cue.Reading); reading != "" {
+		lines = append(lines, `{\r`+LearningStyleReading+`}`+reading)
+	}
+	if translation := learningLine(cue.Translation); translation != "" {
+		lines = append(lines, `{\r`+LearningStyleTranslation+`}`+translation)
+	}
+	return strings.Join(lines, `\N`)
+}
+
+// text of one line without the override tags an ASS source carried, which
+// would restyle the lines after it
+func learningLine(text string) string {
+	return escapeASSText(strings.TrimSpace(assOverrideRegex.ReplaceAllString(text, "")))
+}
@@ -0,0 +1,47 @@
+package subtitle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLearningWriter(t *testing.T) {
+	cues := []LearningCue{
+		{
+			EndTime:     2 * time.Second,
+			Speaker:     "Sato, Ken",
+			Original:    "{\\an8}こんにちは\n元気？",
+			Reading:     "konnichiwa\ngenki?",
+			Translation: "Hello\nHow are you?",
+		},
+		// an entry without a result keeps its original alone
+		{StartTime: 2 * time.Second, EndTime: 3 * time.Second, Original: "はい"},
+	}
+
+	var out bytes.Buffer
+	if err := NewLearningWriter().Encode(cues, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Style: Original,Arial,20,",
+		"Style: Reading,Arial,14,",
+		"Style: Translation,Arial,20,",
+		`Dialogue: 0,0:00:00.00,0:00:02.00,Original,Sato Ken,0,0,0,,こんにちは\N元気？\N{\rReading}konnichiwa\Ngenki?\N{\rTranslation}Hello\NHow are you?`,
+		`Dialogue: 0,0:00:02.00,0:00:03.00,Original,,0,0,0,,はい` + "\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	// the track reads back as one entry per cue
+	file, err := Read(&out, FormatASS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(file.Subtitle().Entries); n != 2 {
+		t.Errorf("entries = %d, want 2", n)
+	}
+}
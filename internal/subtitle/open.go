@@ -2,8 +2,10 @@ package subtitle
 
 import (
 	"fmt"
+	"io"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // parsed subtitle file that preserves format specific metadata
@@ -11,7 +13,14 @@ type File interface {
 	Format() Format
 	Subtitle() *Subtitle
 	SetText(index int, text string) error
+	SetTiming(index int, start, end time.Duration) error
+	// Reshape splits and merges entries, see Piece; indices passed to the
+	// other methods afterwards refer to the reshaped entries
+	Reshape(pieces []Piece) (IndexMap, error)
+	// Keep drops every entry but those at indices, given in ascending order
+	Keep(indices []int) error
 	Write(path string) error
+	Encode(w io.Writer) error
 }
 
 func Open(path string) (File, error) {
@@ -27,3 +36,31 @@ func Open(path string) (File, error) {
 		return nil, fmt.Errorf("unsupported subtitle format: %s", ext)
 	}
 }
+
+// Read parses subtitles in the given format from r, e.g. stdin
+func Read(r io.Reader, format Format) (File, error) {
+	switch format {
+	case FormatSRT:
+		return parseSRT(r)
+	case FormatVTT:
+		return parseVTT(r)
+	case FormatASS:
+		return parseASS(r)
+	default:
+		return nil, fmt.Errorf("unsupported subtitle format: %s", format)
+	}
+}
+
+// guesses the format of subtitle data without a file name: a WEBVTT
+// header means VTT, a [Script Info] section ASS/SSA, anything else SRT
+func DetectFormat(data []byte) Format {
+	text := strings.TrimLeft(string(data), "\ufeff \t\r\n")
+	switch {
+	case strings.HasPrefix(text, "WEBVTT"):
+		return FormatVTT
+	case strings.HasPrefix(strings.ToLower(text), "[script info]"):
+		return FormatASS
+	default:
+		return FormatSRT
+	}
+}
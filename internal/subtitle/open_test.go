@@ -276,6 +276,58 @@ Dialogue: 0,0:00:05.00,0:00:08.00,Italic,,0,0,0,,{\pos(100,200)}Tagged text
 	}
 }
 
+func TestSetTiming(t *testing.T) {
+	files := map[string]string{
+		"test.srt": "1\n00:00:01,000 --> 00:00:04,000\nHello\n",
+		"test.ass": `[Script Info]
+ScriptType: v4.00+
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:00:01.00,0:00:04.00,Default,,0,0,0,,Hello
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write test file: %v", err)
+			}
+			file, err := Open(path)
+			if err != nil {
+				t.Fatalf("failed to open %s: %v", name, err)
+			}
+
+			start, end := 1500*time.Millisecond, 4250*time.Millisecond
+			if err := file.SetTiming(0, start, end); err != nil {
+				t.Fatalf("SetTiming failed: %v", err)
+			}
+			if err := file.SetTiming(1, start, end); err == nil {
+				t.Error("expected error for out of range index")
+			}
+			if err := file.Write(path); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+
+			reopened, err := Open(path)
+			if err != nil {
+				t.Fatalf("failed to reopen %s: %v", name, err)
+			}
+			entry := reopened.Subtitle().Entries[0]
+			if entry.StartTime != start || entry.EndTime != end {
+				t.Errorf(
+					"timing = %v --> %v, want %v --> %v",
+					entry.StartTime,
+					entry.EndTime,
+					start,
+					end,
+				)
+			}
+		})
+	}
+}
+
 func TestExtractLeadingTags(t *testing.T) {
 	tests := []struct {
 		input       string
@@ -332,3 +384,105 @@ func TestOpenUnsupportedFormat(t *testing.T) {
 		t.Errorf("expected 'unsupported' in error, got: %v", err)
 	}
 }
+
+func TestReadAndEncode(t *testing.T) {
+	inputs := map[Format]string{
+		FormatSRT: "1\n00:00:01,000 --> 00:00:02,500\nHello\n",
+		FormatVTT: "\ufeffWEBVTT\n\n00:00:01.000 --> 00:00:02.500\nHello\n",
+		FormatASS: `[Script Info]
+ScriptType: v4.00+
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:00:01.00,0:00:02.50,Default,,0,0,0,,Hello
+`,
+	}
+
+	for want, content := range inputs {
+		t.Run(string(want), func(t *testing.T) {
+			format := DetectFormat([]byte(content))
+			if format != want {
+				t.Fatalf("DetectFormat() = %s, want %s", format, want)
+			}
+
+			file, err := Read(strings.NewReader(content), format)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if err := file.SetText(0, "Hola"); err != nil {
+				t.Fatalf("SetText() error = %v", err)
+			}
+
+			var out strings.Builder
+			if err := file.Encode(&out); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			reread, err := Read(strings.NewReader(out.String()), format)
+			if err != nil {
+				t.Fatalf("Read() of encoded output error = %v\n%s", err, out.String())
+			}
+			entries := reread.Subtitle().Entries
+			if len(entries) != 1 || entries[0].Text != "Hola" ||
+				entries[0].EndTime != 2500*time.Millisecond {
+				t.Errorf("round trip = %+v\n%s", entries, out.String())
+			}
+		})
+	}
+}
+
+func TestSpeakerRoundTrip(t *testing.T) {
+	sub := &Subtitle{Entries: []Entry{
+		{Index: 1, StartTime: time.Second, EndTime: 2 * time.Second, Text: "Hi, all", Speaker: "Ana"},
+		{Index: 2, StartTime: 3 * time.Second, EndTime: 4 * time.Second, Text: "Hello"},
+	}}
+
+	for _, format := range []Format{FormatVTT, FormatASS} {
+		t.Run(string(format), func(t *testing.T) {
+			writer, err := NewWriter(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			if err := writer.Encode(sub, &out); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			file, err := Read(strings.NewReader(out.String()), format)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			entries := file.Subtitle().Entries
+			if len(entries) != 2 ||
+				entries[0].Speaker != "Ana" || entries[0].Text != "Hi, all" ||
+				entries[1].Speaker != "" || entries[1].Text != "Hello" {
+				t.Errorf("round trip = %+v\n%s", entries, out.String())
+			}
+		})
+	}
+}
+
+func TestLanguageRoundTrip(t *testing.T) {
+	sub := &Subtitle{Entries: []Entry{
+		{Index: 1, StartTime: time.Second, EndTime: 2 * time.Second, Text: "Kya haal hai?\nSab theek", Speaker: "Ana", Language: "hi"},
+		{Index: 2, StartTime: 3 * time.Second, EndTime: 4 * time.Second, Text: "All good", Language: "en"},
+		{Index: 3, StartTime: 5 * time.Second, EndTime: 6 * time.Second, Text: "Hello"},
+	}}
+
+	var out strings.Builder
+	if err := (&VTTWriter{}).Encode(sub, &out); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "<v Ana><lang hi>Kya haal hai?\nSab theek</lang>") {
+		t.Errorf("Encode() = %s, want a language span inside the voice span", out.String())
+	}
+	file, err := Read(strings.NewReader(out.String()), FormatVTT)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	entries := file.Subtitle().Entries
+	if len(entries) != 3 ||
+		entries[0].Speaker != "Ana" || entries[0].Language != "hi" || entries[0].Text != "Kya haal hai?\nSab theek" ||
+		entries[1].Language != "en" || entries[1].Text != "All good" ||
+		entries[2].Language != "" || entries[2].Text != "Hello" {
+		t.Errorf("round trip = %+v\n%s", entries, out.String())
+	}
+}
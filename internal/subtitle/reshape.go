@@ -0,0 +1,208 @@
+package subtitle
+
+import (
+	"fmt"
+	"time"
+)
+
+// Piece is one entry of a reshaped file: the entries it replaces, with its
+// own timing and text. Several pieces with the same single entry in From
+// split that entry; one piece with several entries merges them.
+type Piece struct {
+	// From lists the indices of the entries replaced, ascending and
+	// consecutive
+	From      []int
+	StartTime time.Duration
+	EndTime   time.Duration
+	Text      string
+}
+
+// IndexMap maps the index of an entry before a reshape to the indices of
+// the entries made from it: several for a split entry, one shared with its
+// neighbours for merged entries
+type IndexMap [][]int
+
+// Indices returns the entries made from the entry at original, nil when
+// original is out of range
+func (m IndexMap) Indices(original int) []int {
+	if original < 0 || original >= len(m) {
+		return nil
+	}
+	return m[original]
+}
+
+// checks that pieces replace all n entries in order and maps each entry
+// to its pieces
+func newIndexMap(n int, pieces []Piece) (IndexMap, error) {
+	m := make(IndexMap, n)
+	next := 0
+	for i, p := range pieces {
+		if len(p.From) == 0 {
+			return nil, fmt.Errorf("piece %d replaces no entries", i)
+		}
+		for j := 1; j < len(p.From); j++ {
+			if p.From[j] != p.From[j-1]+1 {
+				return nil, fmt.Errorf("piece %d merges entries that are not consecutive", i)
+			}
+		}
+		// a split continues with the entry the previous piece came from
+		continued := len(p.From) == 1 && i > 0 &&
+			len(pieces[i-1].From) == 1 && pieces[i-1].From[0] == p.From[0]
+		if !continued {
+			if p.From[0] != next {
+				return nil, fmt.Errorf("piece %d starts at entry %d, want %d", i, p.From[0], next)
+			}
+			next = p.From[len(p.From)-1] + 1
+		}
+		if next > n {
+			return nil, fmt.Errorf("piece %d replaces entry %d of %d", i, next-1, n)
+		}
+		for _, from := range p.From {
+			m[from] = append(m[from], i)
+		}
+	}
+	if next != n {
+		return nil, fmt.Errorf("pieces replace %d of %d entries", next, n)
+	}
+	return m, nil
+}
+
+// entries made from pieces; the speaker and language of the first entry
+// a piece replaces carry over
+func reshapeEntries(entries []Entry, pieces []Piece) ([]Entry, IndexMap, error) {
+	m, err := newIndexMap(len(entries), pieces)
+	if err != nil {
+		return nil, nil, err
+	}
+	reshaped := make([]Entry, len(pieces))
+	for i, p := range pieces {
+		first := entries[p.From[0]]
+		reshaped[i] = Entry{
+			Index:     i + 1,
+			StartTime: p.StartTime,
+			EndTime:   p.EndTime,
+			Text:      p.Text,
+			Speaker:   first.Speaker,
+			Language:  first.Language,
+		}
+	}
+	return reshaped, m, nil
+}
+
+// Reshape splits and merges entries as pieces describe
+func (f *SRTFile) Reshape(pieces []Piece) (IndexMap, error) {
+	entries, m, err := reshapeEntries(f.entries, pieces)
+	if err != nil {
+		return nil, err
+	}
+	f.entries = entries
+	f.source.reshape(pieces, m)
+	return m, nil
+}
+
+// Reshape splits and merges cues as pieces describe
+func (f *VTTFile) Reshape(pieces []Piece) (IndexMap, error) {
+	entries, m, err := reshapeEntries(f.entries, pieces)
+	if err != nil {
+		return nil, err
+	}
+	f.entries = entries
+	f.source.reshape(pieces, m)
+	return m, nil
+}
+
+// Reshape splits and merges dialogues as pieces describe. Each piece keeps
+// the fields (layer, style, name, margins, effect) and leading override
+// tags of the first dialogue it replaces, so split halves stay styled and
+// positioned like the original, and SetTextWithOverlay overlays a piece
+// on its own text.
+func (f *ASSFile) Reshape(pieces []Piece) (IndexMap, error) {
+	m, err := newIndexMap(len(f.dialogues), pieces)
+	if err != nil {
+		return nil, err
+	}
+	startIdx, endIdx := f.timeColumns()
+
+	dialogues := make([]ASSDialogue, len(pieces))
+	for i, p := range pieces {
+		first := f.dialogues[p.From[0]]
+		fields := append([]string(nil), first.FieldsBefore...)
+		if startIdx >= 0 && startIdx < len(fields) {
+			fields[startIdx] = formatASSTime(p.StartTime)
+		}
+		if endIdx >= 0 && endIdx < len(fields) {
+			fields[endIdx] = formatASSTime(p.EndTime)
+		}
+		text := escapeASSText(p.Text)
+		dialogues[i] = ASSDialogue{
+			FieldsBefore:    fields,
+			Text:            first.LeadingTags + text,
+			LeadingTags:     first.LeadingTags,
+			TextWithoutTags: text,
+		}
+	}
+	f.dialogues = dialogues
+	return m, nil
+}
+
+// checks that indices ascend within n entries
+func checkKept(n int, indices []int) error {
+	for i, index := range indices {
+		if index < 0 || index >= n {
+			return fmt.Errorf("index %d out of range [0, %d]", index, n-1)
+		}
+		if i > 0 && index <= indices[i-1] {
+			return fmt.Errorf("indices must ascend, got %d after %d", index, indices[i-1])
+		}
+	}
+	return nil
+}
+
+// the entries at indices, renumbered
+func keepEntries(entries []Entry, indices []int) ([]Entry, error) {
+	if err := checkKept(len(entries), indices); err != nil {
+		return nil, err
+	}
+	kept := make([]Entry, len(indices))
+	for i, index := range indices {
+		kept[i] = entries[index]
+		kept[i].Index = i + 1
+	}
+	return kept, nil
+}
+
+// Keep drops every entry but those at indices
+func (f *SRTFile) Keep(indices []int) error {
+	entries, err := keepEntries(f.entries, indices)
+	if err != nil {
+		return err
+	}
+	f.entries = entries
+	f.source.keep(indices)
+	return nil
+}
+
+// Keep drops every cue but those at indices
+func (f *VTTFile) Keep(indices []int) error {
+	entries, err := keepEntries(f.entries, indices)
+	if err != nil {
+		return err
+	}
+	f.entries = entries
+	f.source.keep(indices)
+	return nil
+}
+
+// Keep drops every dialogue but those at indices; styles, comments, and
+// the other events stay
+func (f *ASSFile) Keep(indices []int) error {
+	if err := checkKept(len(f.dialogues), indices); err != nil {
+		return err
+	}
+	dialogues := make([]ASSDialogue, len(indices))
+	for i, index := range indices {
+		dialogues[i] = f.dialogues[index]
+	}
+	f.dialogues = dialogues
+	return nil
+}
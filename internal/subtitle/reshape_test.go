@@ -0,0 +1,157 @@
+package subtitle
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewIndexMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       int
+		pieces  [][]int
+		want    IndexMap
+		wantErr bool
+	}{
+		{"unchanged", 2, [][]int{{0}, {1}}, IndexMap{{0}, {1}}, false},
+		{"split", 2, [][]int{{0}, {0}, {1}}, IndexMap{{0, 1}, {2}}, false},
+		{"merge", 3, [][]int{{0, 1}, {2}}, IndexMap{{0}, {0}, {1}}, false},
+		{"split after merge", 3, [][]int{{0, 1}, {2}, {2}}, IndexMap{{0}, {0}, {1, 2}}, false},
+		{"missing entry", 3, [][]int{{0}, {2}}, nil, true},
+		{"not all replaced", 2, [][]int{{0}}, nil, true},
+		{"gap in merge", 3, [][]int{{0, 2}, {1}}, nil, true},
+		{"out of range", 1, [][]int{{0, 1}}, nil, true},
+		{"empty piece", 1, [][]int{{}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pieces := make([]Piece, len(tt.pieces))
+			for i, from := range tt.pieces {
+				pieces[i] = Piece{From: from}
+			}
+			got, err := newIndexMap(tt.n, pieces)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newIndexMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal[[]int]) {
+				t.Errorf("newIndexMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestASSReshapeKeepsIdentity(t *testing.T) {
+	content := `[Script Info]
+ScriptType: v4.00+
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 1,0:00:01.00,0:00:05.00,Sign,Alice,0,0,20,,{\an8}First half second half
+Dialogue: 0,0:00:06.00,0:00:07.00,Default,,0,0,0,,Short
+Dialogue: 0,0:00:07.00,0:00:08.00,Default,,0,0,0,,lines
+`
+	file, err := Read(strings.NewReader(content), FormatASS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assFile := file.(*ASSFile)
+
+	m, err := assFile.Reshape([]Piece{
+		{From: []int{0}, StartTime: time.Second, EndTime: 3 * time.Second, Text: "First half"},
+		{From: []int{0}, StartTime: 3 * time.Second, EndTime: 5 * time.Second, Text: "second half"},
+		{From: []int{1, 2}, StartTime: 6 * time.Second, EndTime: 8 * time.Second, Text: "Short lines"},
+	})
+	if err != nil {
+		t.Fatalf("Reshape() error = %v", err)
+	}
+	if got := m.Indices(2); !slices.Equal(got, []int{2}) {
+		t.Errorf("Indices(2) = %v, want [2]", got)
+	}
+
+	// indices of translations of the original entries reach every piece
+	for _, i := range m.Indices(0) {
+		if err := assFile.SetTextWithOverlay(i, "Mitad"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := assFile.Encode(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`Dialogue: 1,0:00:01.00,0:00:03.00,Sign,Alice,0,0,20,,{\an8}Mitad\NFirst half`,
+		`Dialogue: 1,0:00:03.00,0:00:05.00,Sign,Alice,0,0,20,,{\an8}Mitad\Nsecond half`,
+		`Dialogue: 0,0:00:06.00,0:00:08.00,Default,,0,0,0,,Short lines`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+	if entries := assFile.Subtitle().Entries; len(entries) != 3 || entries[1].Speaker != "Alice" {
+		t.Errorf("entries after reshape = %+v", entries)
+	}
+}
+
+func TestSRTReshape(t *testing.T) {
+	file, err := Read(strings.NewReader("1\n00:00:01,000 --> 00:00:02,000\nOne\n\n2\n00:00:02,000 --> 00:00:03,000\nTwo\n"), FormatSRT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Reshape([]Piece{
+		{From: []int{0, 1}, StartTime: time.Second, EndTime: 3 * time.Second, Text: "One two"},
+	}); err != nil {
+		t.Fatalf("Reshape() error = %v", err)
+	}
+	entries := file.Subtitle().Entries
+	if len(entries) != 1 || entries[0].Text != "One two" || entries[0].EndTime != 3*time.Second {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestKeep(t *testing.T) {
+	content := `[Script Info]
+ScriptType: v4.00+
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,One
+Comment: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,note
+Dialogue: 0,0:00:02.00,0:00:03.00,Sign,,0,0,0,,{\an8}Two
+Dialogue: 0,0:00:03.00,0:00:04.00,Default,,0,0,0,,Three
+`
+	file, err := Read(strings.NewReader(content), FormatASS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Keep([]int{2, 1}); err == nil {
+		t.Error("Keep() should reject indices out of order")
+	}
+	if err := file.Keep([]int{1}); err != nil {
+		t.Fatalf("Keep() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := file.Encode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(out.String(), "Dialogue:") != 1 ||
+		!strings.Contains(out.String(), `Sign,,0,0,0,,{\an8}Two`) ||
+		!strings.Contains(out.String(), "Comment:") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	srt, err := Read(strings.NewReader("1\n00:00:01,000 --> 00:00:02,000\nOne\n\n2\n00:00:02,000 --> 00:00:03,000\nTwo\n"), FormatSRT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srt.Keep([]int{1}); err != nil {
+		t.Fatal(err)
+	}
+	if entries := srt.Subtitle().Entries; len(entries) != 1 || entries[0].Text != "Two" || entries[0].Index != 1 {
+		t.Errorf("entries = %+v", entries)
+	}
+}
@@ -0,0 +1,82 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+)
+
+// how ASS output styles the lines of each speaker
+type SpeakerStyles string
+
+const (
+	// one style per speaker, each in its own color
+	SpeakerStylesColor SpeakerStyles = "color"
+	// colors, with speakers alternating between the left and right of the
+	// screen, as in conversations
+	SpeakerStylesSides SpeakerStyles = "sides"
+)
+
+// primary colours speakers take in turn, as ASS &HAABBGGRR values
+var speakerColors = []string{
+	"&H0000FFFF", // yellow
+	"&H00FFFF00", // cyan
+	"&H0080FF80", // light green
+	"&H00C080FF", // pink
+	"&H000080FF", // orange
+	"&H00FFA060", // light blue
+	"&H00FF80C0", // violet
+	"&H0080C0FF", // peach
+}
+
+// bottom-left and bottom-right numpad alignments of the sides mode
+var speakerAlignments = []int{1, 3}
+
+// speaker names as they appear in the Name field and as style names, in
+// order of first appearance
+func speakerNames(sub *Subtitle) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, entry := range sub.Entries {
+		name := assName(entry.Speaker)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// a speaker name without the commas that separate ASS fields
+func assName(speaker string) string {
+	return strings.TrimSpace(strings.ReplaceAll(speaker, ",", ""))
+}
+
+// Style lines of the speakers, each named after its speaker, and the
+// speakers that got one; a speaker named Default keeps the Default style
+func (w *ASSWriter) speakerStyleLines(names []string) (string, map[string]bool) {
+	var sb strings.Builder
+	styled := map[string]bool{}
+	for i, name := range names {
+		if name == "Default" {
+			continue
+		}
+		alignment, margin := 2, 10
+		if w.SpeakerStyles == SpeakerStylesSides {
+			alignment = speakerAlignments[i%len(speakerAlignments)]
+			margin = 40
+		}
+		fmt.Fprintf(&sb,
+			"Style: %s,%s,%d,%s,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,%d,%d,%d,10,1\n",
+			name,
+			w.FontName,
+			w.FontSize,
+			speakerColors[i%len(speakerColors)],
+			alignment,
+			margin,
+			margin,
+		)
+		styled[name] = true
+	}
+	return sb.String(), styled
+}
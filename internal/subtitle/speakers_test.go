@@ -0,0 +1,57 @@
+package subtitle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestASSWriterSpeakerStyles(t *testing.T) {
+	sub := &Subtitle{Entries: []Entry{
+		{EndTime: time.Second, Text: "Hi", Speaker: "Ana"},
+		{StartTime: time.Second, EndTime: 2 * time.Second, Text: "Hello", Speaker: "Raj, Jr."},
+		{StartTime: 2 * time.Second, EndTime: 3 * time.Second, Text: "(music)"},
+		{StartTime: 3 * time.Second, EndTime: 4 * time.Second, Text: "Bye", Speaker: "Ana"},
+	}}
+
+	tests := []struct {
+		styles SpeakerStyles
+		want   []string
+	}{
+		{"", []string{
+			`Dialogue: 0,0:00:00.00,0:00:01.00,Default,Ana,0,0,0,,Hi`,
+			`Dialogue: 0,0:00:01.00,0:00:02.00,Default,Raj Jr.,0,0,0,,Hello`,
+		}},
+		{SpeakerStylesColor, []string{
+			"Style: Ana,Arial,20,&H0000FFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,2,10,10,10,1\n",
+			"Style: Raj Jr.,Arial,20,&H00FFFF00,",
+			`Dialogue: 0,0:00:00.00,0:00:01.00,Ana,Ana,0,0,0,,Hi`,
+			`Dialogue: 0,0:00:01.00,0:00:02.00,Raj Jr.,Raj Jr.,0,0,0,,Hello`,
+			`Dialogue: 0,0:00:02.00,0:00:03.00,Default,,0,0,0,,(music)`,
+			`Dialogue: 0,0:00:03.00,0:00:04.00,Ana,Ana,0,0,0,,Bye`,
+		}},
+		{SpeakerStylesSides, []string{
+			",1,2,2,1,40,40,10,1\n",
+			"Style: Raj Jr.,Arial,20,&H00FFFF00,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,3,40,40,10,1\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.styles), func(t *testing.T) {
+			w := NewASSWriter()
+			w.SpeakerStyles = tt.styles
+			var out bytes.Buffer
+			if err := w.Encode(sub, &out); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output lacks %q:\n%s", want, out.String())
+				}
+			}
+			if tt.styles == "" && strings.Count(out.String(), "Style: ") != 1 {
+				t.Errorf("expected only the Default style:\n%s", out.String())
+			}
+		})
+	}
+}
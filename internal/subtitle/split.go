@@ -0,0 +1,176 @@
+package subtitle
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// override blocks of ASS and tags of WebVTT, which take no room on screen
+var markupRegex = regexp.MustCompile(`\{[^}]*\}|<[^>]*>`)
+
+// leading override blocks of an entry's text, such as {\an8}
+var leadingTagsRegex = regexp.MustCompile(`^(\{[^}]*\})+`)
+
+// SplitLong splits each entry of f whose text cannot be wrapped into
+// maxLines lines of maxChars into as few consecutive entries as fit,
+// dividing its time in proportion to their text and preferring to break
+// after punctuation. Leading override tags are repeated on every part;
+// tags within the text stay with their words. It returns how many
+// entries were split.
+func SplitLong(f File, maxChars, maxLines int) (int, error) {
+	if maxChars <= 0 || maxLines <= 0 {
+		return 0, nil
+	}
+	_, isASS := f.(*ASSFile)
+	entries := f.Subtitle().Entries
+
+	var pieces []Piece
+	split := 0
+	for i, entry := range entries {
+		leading := leadingTagsRegex.FindString(entry.Text)
+		words := strings.Fields(entry.Text[len(leading):])
+		// text without spaces, such as Japanese, has nowhere to split
+		if len(words) < 2 || fitsLines(words, maxChars, maxLines) {
+			text := entry.Text
+			if isASS {
+				text = text[len(leading):]
+			}
+			pieces = append(pieces, Piece{
+				From:      []int{i},
+				StartTime: entry.StartTime,
+				EndTime:   entry.EndTime,
+				Text:      text,
+			})
+			continue
+		}
+		split++
+
+		parts := splitWords(words, maxChars, maxLines)
+		total := 0
+		for _, part := range parts {
+			total += visibleLen(strings.Join(part, " "))
+		}
+		span := entry.EndTime - entry.StartTime
+		start, done := entry.StartTime, 0
+		for j, part := range parts {
+			done += visibleLen(strings.Join(part, " "))
+			end := entry.StartTime + time.Duration(int64(span)*int64(done)/int64(max(total, 1)))
+			if j == len(parts)-1 {
+				end = entry.EndTime
+			}
+			text := wrapWords(part, maxChars)
+			// ASS adds the dialogue's leading tags back itself
+			if !isASS {
+				text = leading + text
+			}
+			pieces = append(pieces, Piece{From: []int{i}, StartTime: start, EndTime: end, Text: text})
+			start = end
+		}
+	}
+	if split == 0 {
+		return 0, nil
+	}
+	if _, err := f.Reshape(pieces); err != nil {
+		return 0, err
+	}
+	return split, nil
+}
+
+// characters of text shown on screen
+func visibleLen(text string) int {
+	return utf8.RuneCountInString(markupRegex.ReplaceAllString(text, ""))
+}
+
+// whether words wrap into maxLines lines of maxChars; a word longer than
+// a line takes a line of its own
+func fitsLines(words []string, maxChars, maxLines int) bool {
+	return len(strings.Split(wrapWords(words, maxChars), "\n")) <= maxLines
+}
+
+// words wrapped greedily into lines of maxChars
+func wrapWords(words []string, maxChars int) string {
+	var sb strings.Builder
+	width := 0
+	for _, word := range words {
+		n := visibleLen(word)
+		switch {
+		case width == 0:
+		case width+1+n > maxChars:
+			sb.WriteString("\n")
+			width = 0
+		default:
+			sb.WriteString(" ")
+			width++
+		}
+		sb.WriteString(word)
+		width += n
+	}
+	return sb.String()
+}
+
+// words divided into the fewest parts that each fit, of similar length
+func splitWords(words []string, maxChars, maxLines int) [][]string {
+	for n := 2; n < len(words); n++ {
+		parts := cutWords(words, n, maxChars)
+		fit := true
+		for _, part := range parts {
+			if !fitsLines(part, maxChars, maxLines) {
+				fit = false
+				break
+			}
+		}
+		if fit {
+			return parts
+		}
+	}
+	parts := make([][]string, len(words))
+	for i, word := range words {
+		parts[i] = []string{word}
+	}
+	return parts
+}
+
+// words cut into n parts near equal length, preferring cuts after a word
+// ending a sentence or clause
+func cutWords(words []string, n, maxChars int) [][]string {
+	// ends[i] is the length of words[:i+1] joined with spaces
+	ends := make([]int, len(words))
+	for i, word := range words {
+		ends[i] = visibleLen(word)
+		if i > 0 {
+			ends[i] += ends[i-1] + 1
+		}
+	}
+	total := ends[len(words)-1]
+
+	var parts [][]string
+	from := 0
+	for k := 1; k < n; k++ {
+		target := total * k / n
+		best, bestScore := -1, 0
+		// leave at least one word for each part still to come
+		for i := from; i < len(words)-(n-k); i++ {
+			score := abs(ends[i] - target)
+			if endsClause(words[i]) {
+				score -= maxChars / 3
+			}
+			if best < 0 || score < bestScore {
+				best, bestScore = i, score
+			}
+		}
+		parts = append(parts, words[from:best+1])
+		from = best + 1
+	}
+	return append(parts, words[from:])
+}
+
+func endsClause(word string) bool {
+	word = strings.TrimRight(markupRegex.ReplaceAllString(word, ""), `"'»”’)`)
+	return strings.HasSuffix(word, ".") || strings.HasSuffix(word, ",") ||
+		strings.HasSuffix(word, "!") || strings.HasSuffix(word, "?") ||
+		strings.HasSuffix(word, ";") || strings.HasSuffix(word, ":") ||
+		strings.HasSuffix(word, "…") || strings.HasSuffix(word, "。") ||
+		strings.HasSuffix(word, "、")
+}
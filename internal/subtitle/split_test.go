@@ -0,0 +1,133 @@
+package subtitle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitLong(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxChars  int
+		wantSplit int
+		want      []Entry
+	}{
+		{
+			name:     "fits",
+			text:     "short enough\nto wrap",
+			maxChars: 20,
+			want:     []Entry{{StartTime: 0, EndTime: 21 * time.Second, Text: "short enough\nto wrap"}},
+		},
+		{
+			name:      "proportional timing",
+			text:      "aaaa bbbb cccc dddd",
+			maxChars:  4,
+			wantSplit: 1,
+			want: []Entry{
+				{StartTime: 0, EndTime: 10500 * time.Millisecond, Text: "aaaa\nbbbb"},
+				{StartTime: 10500 * time.Millisecond, EndTime: 21 * time.Second, Text: "cccc\ndddd"},
+			},
+		},
+		{
+			name:      "breaks after punctuation",
+			text:      "aaaaa, b cccc dddd ee",
+			maxChars:  9,
+			wantSplit: 1,
+			want: []Entry{
+				{StartTime: 0, EndTime: 6300 * time.Millisecond, Text: "aaaaa,"},
+				{StartTime: 6300 * time.Millisecond, EndTime: 21 * time.Second, Text: "b cccc\ndddd ee"},
+			},
+		},
+		{
+			name:      "leading tags repeated",
+			text:      "{\\an8}alpha beta gamma zeta",
+			maxChars:  5,
+			wantSplit: 1,
+			want: []Entry{
+				{StartTime: 0, EndTime: 10500 * time.Millisecond, Text: "{\\an8}alpha\nbeta"},
+				{StartTime: 10500 * time.Millisecond, EndTime: 21 * time.Second, Text: "{\\an8}gamma\nzeta"},
+			},
+		},
+		{
+			name:      "tags stay with their words",
+			text:      "<i>alpha beta gamma delta</i>",
+			maxChars:  5,
+			wantSplit: 1,
+			want: []Entry{
+				{StartTime: 0, EndTime: 10 * time.Second, Text: "<i>alpha\nbeta"},
+				{StartTime: 10 * time.Second, EndTime: 21 * time.Second, Text: "gamma\ndelta</i>"},
+			},
+		},
+		{
+			name:     "no spaces",
+			text:     "とても長い一行の字幕でどこにも空白がない",
+			maxChars: 5,
+			want:     []Entry{{StartTime: 0, EndTime: 21 * time.Second, Text: "とても長い一行の字幕でどこにも空白がない"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Read(strings.NewReader("1\n00:00:00,000 --> 00:00:21,000\n"+tt.text+"\n"), FormatSRT)
+			if err != nil {
+				t.Fatal(err)
+			}
+			split, err := SplitLong(file, tt.maxChars, 2)
+			if err != nil {
+				t.Fatalf("SplitLong() error = %v", err)
+			}
+			if split != tt.wantSplit {
+				t.Errorf("SplitLong() = %d, want %d", split, tt.wantSplit)
+			}
+			entries := file.Subtitle().Entries
+			if len(entries) != len(tt.want) {
+				t.Fatalf("entries = %+v, want %+v", entries, tt.want)
+			}
+			for i, want := range tt.want {
+				got := entries[i]
+				if got.StartTime != want.StartTime || got.EndTime != want.EndTime || got.Text != want.Text {
+					t.Errorf("entry %d = %v-%v %q, want %v-%v %q",
+						i, got.StartTime, got.EndTime, got.Text, want.StartTime, want.EndTime, want.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitLongASS(t *testing.T) {
+	content := `[Script Info]
+ScriptType: v4.00+
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 1,0:00:01.00,0:00:05.00,Sign,Alice,0,0,20,,{\an8}alpha {\i1}beta{\i0} gamma zeta
+Dialogue: 0,0:00:06.00,0:00:07.00,Default,,0,0,0,,{\an8}Short
+`
+	file, err := Read(strings.NewReader(content), FormatASS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	split, err := SplitLong(file, 5, 2)
+	if err != nil {
+		t.Fatalf("SplitLong() error = %v", err)
+	}
+	if split != 1 {
+		t.Errorf("SplitLong() = %d, want 1", split)
+	}
+
+	var out bytes.Buffer
+	if err := file.Encode(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`Dialogue: 1,0:00:01.00,0:00:03.00,Sign,Alice,0,0,20,,{\an8}alpha\N{\i1}beta{\i0}`,
+		`Dialogue: 1,0:00:03.00,0:00:05.00,Sign,Alice,0,0,20,,{\an8}gamma\Nzeta`,
+		`Dialogue: 0,0:00:06.00,0:00:07.00,Default,,0,0,0,,{\an8}Short`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -2,7 +2,9 @@ package subtitle
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strconv"
@@ -11,7 +13,9 @@ import (
 )
 
 type SRTFile struct {
-	entries []Entry
+	entries  []Entry
+	source   *rawSource // the file as read, for PreserveFormatting
+	preserve bool
 }
 
 func parseSRTFile(path string) (*SRTFile, error) {
@@ -23,14 +27,24 @@ func parseSRTFile(path string) (*SRTFile, error) {
 		_ = file.Close()
 	}()
 
+	return parseSRT(file)
+}
+
+func parseSRT(r io.Reader) (*SRTFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading SRT file: %w", err)
+	}
 	var entries []Entry
-	scanner := bufio.NewScanner(file)
+	var spans []lineSpan
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 
 	timestampRegex := regexp.MustCompile(
 		`(\d{2}):(\d{2}):(\d{2}),(\d{3})\s*-->\s*(\d{2}):(\d{2}):(\d{2}),(\d{3})`,
 	)
 
 	var currentEntry *Entry
+	var span lineSpan
 	var textLines []string
 	lineNum := 0
 
@@ -46,6 +60,7 @@ func parseSRTFile(path string) (*SRTFile, error) {
 			if currentEntry != nil && len(textLines) > 0 {
 				currentEntry.Text = strings.Join(textLines, "\n")
 				entries = append(entries, *currentEntry)
+				spans = append(spans, span)
 				currentEntry = nil
 				textLines = nil
 			}
@@ -56,6 +71,7 @@ func parseSRTFile(path string) (*SRTFile, error) {
 			index, err := strconv.Atoi(strings.TrimSpace(line))
 			if err == nil {
 				currentEntry = &Entry{Index: index}
+				span = lineSpan{first: lineNum}
 				continue
 			}
 		}
@@ -86,25 +102,29 @@ func parseSRTFile(path string) (*SRTFile, error) {
 				}
 				currentEntry.StartTime = startTime
 				currentEntry.EndTime = endTime
+				span.timing = lineNum
+				span.settings = line[strings.Index(line, matches[0])+len(matches[0]):]
 				continue
 			}
 		}
 
 		if currentEntry != nil {
 			textLines = append(textLines, line)
+			span.last = lineNum
 		}
 	}
 
 	if currentEntry != nil && len(textLines) > 0 {
 		currentEntry.Text = strings.Join(textLines, "\n")
 		entries = append(entries, *currentEntry)
+		spans = append(spans, span)
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("error reading SRT file: %w", err)
 	}
 
-	return &SRTFile{entries: entries}, nil
+	return &SRTFile{entries: entries, source: newRawSource(string(data), entries, spans)}, nil
 }
 
 func parseSRTTimestamp(
@@ -156,10 +176,33 @@ func (f *SRTFile) SetText(index int, text string) error {
 	return nil
 }
 
+func (f *SRTFile) SetTiming(index int, start, end time.Duration) error {
+	if index < 0 || index >= len(f.entries) {
+		return fmt.Errorf(
+			"index %d out of range (0-%d)",
+			index,
+			len(f.entries)-1,
+		)
+	}
+	f.entries[index].StartTime = start
+	f.entries[index].EndTime = end
+	return nil
+}
+
 func (f *SRTFile) Write(path string) error {
+	if f.preserve {
+		return writePreserved(path, f.source, FormatSRT, f.entries)
+	}
 	writer, err := NewWriter(FormatSRT)
 	if err != nil {
 		return err
 	}
 	return writer.Write(f.Subtitle(), path)
 }
+
+func (f *SRTFile) Encode(w io.Writer) error {
+	if f.preserve {
+		return encodeStyled(w, f.source.render(FormatSRT, f.entries))
+	}
+	return (&SRTWriter{}).Encode(f.Subtitle(), w)
+}
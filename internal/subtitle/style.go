@@ -0,0 +1,81 @@
+package subtitle
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// how the lines of written subtitle files end
+type LineEndings string
+
+const (
+	// Unix line endings, "\n"
+	LineEndingsLF LineEndings = "lf"
+	// Windows line endings, "\r\n", which some players and set-top boxes
+	// need
+	LineEndingsCRLF LineEndings = "crlf"
+)
+
+// OutputStyle is the byte-level layout of every subtitle file this package
+// writes. The zero value keeps each writer's own: "\n" line endings, or
+// those of the original with PreserveFormatting, and no byte order mark.
+type OutputStyle struct {
+	LineEndings LineEndings // empty keeps the writer's
+	BOM         bool        // start the file with the UTF-8 byte order mark
+}
+
+var (
+	styleMu sync.RWMutex
+	style   OutputStyle
+)
+
+// SetOutputStyle applies s to the files and streams written from then on
+func SetOutputStyle(s OutputStyle) error {
+	switch s.LineEndings {
+	case "", LineEndingsLF, LineEndingsCRLF:
+	default:
+		return fmt.Errorf("unsupported line endings %q: use %s or %s", s.LineEndings, LineEndingsLF, LineEndingsCRLF)
+	}
+	styleMu.Lock()
+	defer styleMu.Unlock()
+	style = s
+	return nil
+}
+
+func currentStyle() OutputStyle {
+	styleMu.RLock()
+	defer styleMu.RUnlock()
+	return style
+}
+
+// text with the output style applied
+func styled(text string) string {
+	s := currentStyle()
+	switch s.LineEndings {
+	case LineEndingsLF:
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	case LineEndingsCRLF:
+		text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+	}
+	if s.BOM && !strings.HasPrefix(text, "\ufeff") {
+		text = "\ufeff" + text
+	}
+	return text
+}
+
+// writes a rendered file to path in the output style
+func writeStyled(path, text string) error {
+	if err := ensureDir(path); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(styled(text)), 0644)
+}
+
+// writes a rendered file to w in the output style
+func encodeStyled(w io.Writer, text string) error {
+	_, err := io.WriteString(w, styled(text))
+	return err
+}
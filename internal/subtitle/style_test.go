@@ -0,0 +1,59 @@
+package subtitle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutputStyle(t *testing.T) {
+	t.Cleanup(func() { _ = SetOutputStyle(OutputStyle{}) })
+
+	sub := &Subtitle{Entries: []Entry{{StartTime: 0, EndTime: time.Second, Text: "Hello\nthere"}}}
+	tests := []struct {
+		name  string
+		style OutputStyle
+		want  string
+	}{
+		{"default", OutputStyle{}, "1\n00:00:00,000 --> 00:00:01,000\nHello\nthere\n\n"},
+		{"crlf", OutputStyle{LineEndings: LineEndingsCRLF}, "1\r\n00:00:00,000 --> 00:00:01,000\r\nHello\r\nthere\r\n\r\n"},
+		{"bom", OutputStyle{BOM: true}, "\ufeff1\n00:00:00,000 --> 00:00:01,000\nHello\nthere\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetOutputStyle(tt.style); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := (&SRTWriter{}).Encode(sub, &out); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("Encode() = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+
+	// a preserved CRLF file with its BOM is not given a second one, and
+	// lf converts it
+	if err := SetOutputStyle(OutputStyle{LineEndings: LineEndingsLF, BOM: true}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Read(strings.NewReader("\ufeff1\r\n00:00:00,000 --> 00:00:01,000\r\nHi\r\n"), FormatSRT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	PreserveFormatting(f)
+	var out bytes.Buffer
+	if err := f.Encode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\ufeff1\n00:00:00,000 --> 00:00:01,000\nHi\n"; out.String() != want {
+		t.Errorf("Encode() = %q, want %q", out.String(), want)
+	}
+
+	if err := SetOutputStyle(OutputStyle{LineEndings: "cr"}); err == nil {
+		t.Error("SetOutputStyle() accepted cr line endings")
+	}
+}
@@ -1,6 +1,7 @@
 package subtitle
 
 import (
+	"io"
 	"time"
 )
 
@@ -10,6 +11,8 @@ type Entry struct {
 	StartTime time.Duration
 	EndTime   time.Duration
 	Text      string
+	Speaker   string // who is talking, when transcribed with diarization
+	Language  string // language code of the text, when tagged per entry
 }
 
 // represents complete subtitle track
@@ -38,11 +41,14 @@ type Segment struct {
 	StartTime time.Duration
 	EndTime   time.Duration
 	Text      string
+	Speaker   string
+	Language  string
 }
 
-// interface for writing subtitles to files
+// interface for writing subtitles to files or streams
 type Writer interface {
 	Write(subtitle *Subtitle, path string) error
+	Encode(subtitle *Subtitle, w io.Writer) error
 }
 
 // interface for parsing subtitle files
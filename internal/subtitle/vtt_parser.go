@@ -2,7 +2,9 @@ package subtitle
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strconv"
@@ -11,7 +13,9 @@ import (
 )
 
 type VTTFile struct {
-	entries []Entry
+	entries  []Entry
+	source   *rawSource // the file as read, for PreserveFormatting
+	preserve bool
 }
 
 func parseVTTFile(path string) (*VTTFile, error) {
@@ -23,8 +27,17 @@ func parseVTTFile(path string) (*VTTFile, error) {
 		_ = file.Close()
 	}()
 
+	return parseVTT(file)
+}
+
+func parseVTT(r io.Reader) (*VTTFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading VTT file: %w", err)
+	}
 	var entries []Entry
-	scanner := bufio.NewScanner(file)
+	var spans []lineSpan
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 
 	timestampRegex := regexp.MustCompile(
 		`(\d{2}):(\d{2}):(\d{2})\.(\d{3})\s*-->\s*(\d{2}):(\d{2}):(\d{2})\.(\d{3})`,
@@ -34,10 +47,13 @@ func parseVTTFile(path string) (*VTTFile, error) {
 	)
 
 	var currentEntry *Entry
+	var span lineSpan
 	var textLines []string
 	lineNum := 0
 	headerParsed := false
 	entryIndex := 0
+	// line of a cue identifier, the line before a timing line outside a cue
+	identifierLine := 0
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -56,6 +72,7 @@ func parseVTTFile(path string) (*VTTFile, error) {
 
 		if strings.HasPrefix(strings.TrimSpace(line), "NOTE") {
 			for scanner.Scan() {
+				lineNum++
 				if strings.TrimSpace(scanner.Text()) == "" {
 					break
 				}
@@ -65,6 +82,7 @@ func parseVTTFile(path string) (*VTTFile, error) {
 
 		if strings.HasPrefix(strings.TrimSpace(line), "STYLE") {
 			for scanner.Scan() {
+				lineNum++
 				if strings.TrimSpace(scanner.Text()) == "" {
 					break
 				}
@@ -76,9 +94,11 @@ func parseVTTFile(path string) (*VTTFile, error) {
 			if currentEntry != nil && len(textLines) > 0 {
 				currentEntry.Text = strings.Join(textLines, "\n")
 				entries = append(entries, *currentEntry)
+				spans = append(spans, span)
 				currentEntry = nil
 				textLines = nil
 			}
+			identifierLine = 0
 			continue
 		}
 
@@ -87,6 +107,7 @@ func parseVTTFile(path string) (*VTTFile, error) {
 			if currentEntry != nil && len(textLines) > 0 {
 				currentEntry.Text = strings.Join(textLines, "\n")
 				entries = append(entries, *currentEntry)
+				spans = append(spans, span)
 				textLines = nil
 			}
 
@@ -117,6 +138,7 @@ func parseVTTFile(path string) (*VTTFile, error) {
 				StartTime: startTime,
 				EndTime:   endTime,
 			}
+			span = newCueSpan(lineNum, identifierLine, line, matches[0])
 			continue
 		}
 
@@ -125,6 +147,7 @@ func parseVTTFile(path string) (*VTTFile, error) {
 			if currentEntry != nil && len(textLines) > 0 {
 				currentEntry.Text = strings.Join(textLines, "\n")
 				entries = append(entries, *currentEntry)
+				spans = append(spans, span)
 				textLines = nil
 			}
 
@@ -155,24 +178,48 @@ func parseVTTFile(path string) (*VTTFile, error) {
 				StartTime: startTime,
 				EndTime:   endTime,
 			}
+			span = newCueSpan(lineNum, identifierLine, line, shortMatches[0])
 			continue
 		}
 
 		if currentEntry != nil {
 			textLines = append(textLines, line)
+			span.last = lineNum
+		} else {
+			identifierLine = lineNum
 		}
 	}
 
 	if currentEntry != nil && len(textLines) > 0 {
 		currentEntry.Text = strings.Join(textLines, "\n")
 		entries = append(entries, *currentEntry)
+		spans = append(spans, span)
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("error reading VTT file: %w", err)
 	}
 
-	return &VTTFile{entries: entries}, nil
+	for i := range entries {
+		entries[i].Speaker, entries[i].Text = splitVoice(entries[i].Text)
+		entries[i].Language, entries[i].Text = splitLang(entries[i].Text)
+	}
+
+	return &VTTFile{entries: entries, source: newRawSource(string(data), entries, spans)}, nil
+}
+
+// the lines of a cue starting at the timing line, and at its identifier
+// when that is the line right before
+func newCueSpan(timing, identifier int, line, timestamps string) lineSpan {
+	span := lineSpan{
+		first:    timing,
+		timing:   timing,
+		settings: line[strings.Index(line, timestamps)+len(timestamps):],
+	}
+	if identifier == timing-1 && identifier > 0 {
+		span.first = identifier
+	}
+	return span
 }
 
 func parseVTTTimestamp(
@@ -201,6 +248,31 @@ func parseVTTTimestamp(
 		time.Duration(ms)*time.Millisecond, nil
 }
 
+// a cue that opens with a voice span, "<v Speaker 1>text", gives its speaker
+var voiceRegex = regexp.MustCompile(`^<v(?:\.[^\s>]*)?[ \t]+([^>]+)>`)
+
+func splitVoice(text string) (string, string) {
+	match := voiceRegex.FindStringSubmatch(text)
+	if match == nil {
+		return "", text
+	}
+	text = strings.TrimSuffix(text[len(match[0]):], "</v>")
+	return strings.TrimSpace(match[1]), text
+}
+
+// a cue whose text is wrapped in a language span, "<lang hi>text</lang>",
+// gives its language
+var langRegex = regexp.MustCompile(`^<lang(?:\.[^\s>]*)?[ \t]+([^\s>]+)>`)
+
+func splitLang(text string) (string, string) {
+	match := langRegex.FindStringSubmatch(text)
+	if match == nil || !strings.HasSuffix(text, "</lang>") {
+		return "", text
+	}
+	text = strings.TrimSuffix(text[len(match[0]):], "</lang>")
+	return match[1], text
+}
+
 func (f *VTTFile) Format() Format {
 	return FormatVTT
 }
@@ -224,10 +296,47 @@ func (f *VTTFile) SetText(index int, text string) error {
 	return nil
 }
 
+// SetLanguage tags the language of an entry's text; an empty lang removes
+// the tag
+func (f *VTTFile) SetLanguage(index int, lang string) error {
+	if index < 0 || index >= len(f.entries) {
+		return fmt.Errorf(
+			"index %d out of range (0-%d)",
+			index,
+			len(f.entries)-1,
+		)
+	}
+	f.entries[index].Language = lang
+	return nil
+}
+
+func (f *VTTFile) SetTiming(index int, start, end time.Duration) error {
+	if index < 0 || index >= len(f.entries) {
+		return fmt.Errorf(
+			"index %d out of range (0-%d)",
+			index,
+			len(f.entries)-1,
+		)
+	}
+	f.entries[index].StartTime = start
+	f.entries[index].EndTime = end
+	return nil
+}
+
 func (f *VTTFile) Write(path string) error {
+	if f.preserve {
+		return writePreserved(path, f.source, FormatVTT, f.entries)
+	}
 	writer, err := NewWriter(FormatVTT)
 	if err != nil {
 		return err
 	}
 	return writer.Write(f.Subtitle(), path)
 }
+
+func (f *VTTFile) Encode(w io.Writer) error {
+	if f.preserve {
+		return encodeStyled(w, f.source.render(FormatVTT, f.entries))
+	}
+	return (&VTTWriter{}).Encode(f.Subtitle(), w)
+}
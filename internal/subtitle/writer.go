@@ -2,6 +2,7 @@ package subtitle
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,13 +13,19 @@ import (
 type SRTWriter struct{}
 
 // WebVTT format
-type VTTWriter struct{}
+type VTTWriter struct {
+	// lines after WEBVTT in the file header, such as the X-TIMESTAMP-MAP
+	// of an HLS segment
+	Header []string
+}
 
 // Advanced SubStation Alpha format
 type ASSWriter struct {
 	Title    string
 	FontName string
 	FontSize int
+	// when set, lines with a speaker take a style of their own
+	SpeakerStyles SpeakerStyles
 }
 
 func NewWriter(format Format) (Writer, error) {
@@ -28,22 +35,32 @@ func NewWriter(format Format) (Writer, error) {
 	case FormatVTT:
 		return &VTTWriter{}, nil
 	case FormatASS:
-		return &ASSWriter{
-			Title:    "Lipi Generated Subtitles",
-			FontName: "Arial",
-			FontSize: 20,
-		}, nil
+		return NewASSWriter(), nil
 	default:
 		return nil, fmt.Errorf("unsupported format: %s", format)
 	}
 }
 
+// ASS writer with the default title and font
+func NewASSWriter() *ASSWriter {
+	return &ASSWriter{
+		Title:    "Lipi Generated Subtitles",
+		FontName: "Arial",
+		FontSize: 20,
+	}
+}
+
 // writes the subtitle to an SRT file
 func (w *SRTWriter) Write(sub *Subtitle, path string) error {
-	if err := ensureDir(path); err != nil {
-		return err
-	}
+	return writeStyled(path, w.render(sub))
+}
 
+// encodes the subtitle in SRT format to out
+func (w *SRTWriter) Encode(sub *Subtitle, out io.Writer) error {
+	return encodeStyled(out, w.render(sub))
+}
+
+func (w *SRTWriter) render(sub *Subtitle) string {
 	var sb strings.Builder
 	for i, entry := range sub.Entries {
 		// index (1-based)
@@ -54,24 +71,40 @@ func (w *SRTWriter) Write(sub *Subtitle, path string) error {
 			formatSRTTime(entry.StartTime),
 			formatSRTTime(entry.EndTime)))
 
-		// text
-		sb.WriteString(entry.Text)
+		sb.WriteString(srtText(entry))
 		sb.WriteString("\n\n")
 	}
 
-	return os.WriteFile(path, []byte(sb.String()), 0644)
+	return sb.String()
+}
+
+// the text of an SRT entry, with the speaker as a voice span
+func srtText(entry Entry) string {
+	if entry.Speaker != "" {
+		return fmt.Sprintf("<v %s>%s", entry.Speaker, entry.Text)
+	}
+	return entry.Text
 }
 
 // writes the subtitle to a VTT file
 func (w *VTTWriter) Write(sub *Subtitle, path string) error {
-	if err := ensureDir(path); err != nil {
-		return err
-	}
+	return writeStyled(path, w.render(sub))
+}
 
+// encodes the subtitle in VTT format to out
+func (w *VTTWriter) Encode(sub *Subtitle, out io.Writer) error {
+	return encodeStyled(out, w.render(sub))
+}
+
+func (w *VTTWriter) render(sub *Subtitle) string {
 	var sb strings.Builder
 
 	// VTT header
-	sb.WriteString("WEBVTT\n\n")
+	sb.WriteString("WEBVTT\n")
+	for _, line := range w.Header {
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\n")
 
 	for i, entry := range sub.Entries {
 		// optional cue identifier
@@ -82,20 +115,37 @@ func (w *VTTWriter) Write(sub *Subtitle, path string) error {
 			formatVTTTime(entry.StartTime),
 			formatVTTTime(entry.EndTime)))
 
-		// text
-		sb.WriteString(entry.Text)
+		sb.WriteString(vttText(entry))
 		sb.WriteString("\n\n")
 	}
 
-	return os.WriteFile(path, []byte(sb.String()), 0644)
+	return sb.String()
+}
+
+// the text of a VTT cue, with the speaker as a voice span and its language
+// as a language span
+func vttText(entry Entry) string {
+	text := entry.Text
+	if entry.Language != "" {
+		text = fmt.Sprintf("<lang %s>%s</lang>", entry.Language, text)
+	}
+	if entry.Speaker != "" {
+		text = fmt.Sprintf("<v %s>%s", entry.Speaker, text)
+	}
+	return text
 }
 
 // writes the subtitle to an ASS file
 func (w *ASSWriter) Write(sub *Subtitle, path string) error {
-	if err := ensureDir(path); err != nil {
-		return err
-	}
+	return writeStyled(path, w.render(sub))
+}
 
+// encodes the subtitle in ASS format to out
+func (w *ASSWriter) Encode(sub *Subtitle, out io.Writer) error {
+	return encodeStyled(out, w.render(sub))
+}
+
+func (w *ASSWriter) render(sub *Subtitle) string {
 	var sb strings.Builder
 
 	// script info section
@@ -112,11 +162,18 @@ func (w *ASSWriter) Write(sub *Subtitle, path string) error {
 	)
 	sb.WriteString(
 		fmt.Sprintf(
-			"Style: Default,%s,%d,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,2,10,10,10,1\n\n",
+			"Style: Default,%s,%d,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,2,10,10,10,1\n",
 			w.FontName,
 			w.FontSize,
 		),
 	)
+	var styled map[string]bool
+	if w.SpeakerStyles != "" {
+		var lines string
+		lines, styled = w.speakerStyleLines(speakerNames(sub))
+		sb.WriteString(lines)
+	}
+	sb.WriteString("\n")
 
 	// events section
 	sb.WriteString("[Events]\n")
@@ -125,14 +182,21 @@ func (w *ASSWriter) Write(sub *Subtitle, path string) error {
 	)
 
 	for _, entry := range sub.Entries {
+		name := assName(entry.Speaker)
+		style := "Default"
+		if styled[name] {
+			style = name
+		}
 		// dialogue line
-		sb.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,Default,,0,0,0,,%s\n",
+		sb.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,%s,%s,0,0,0,,%s\n",
 			formatASSTime(entry.StartTime),
 			formatASSTime(entry.EndTime),
+			style,
+			name,
 			escapeASSText(entry.Text)))
 	}
 
-	return os.WriteFile(path, []byte(sb.String()), 0644)
+	return sb.String()
 }
 
 func formatSRTTime(d time.Duration) string {
@@ -0,0 +1,243 @@
+package summarize
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/llm"
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// kinds of index entries, in the order the markdown index lists them
+var indexKinds = []struct{ kind, heading string }{
+	{"topic", "Topics"},
+	{"person", "People"},
+	{"organization", "Organizations"},
+	{"place", "Places"},
+	{"work", "Works"},
+	{"term", "Terms"},
+}
+
+// IndexEntry is a name or topic and where it comes up
+type IndexEntry struct {
+	Term        string
+	Kind        string // topic, person, organization, place, work, or term
+	First       time.Duration
+	Occurrences []time.Duration // cues that mention the term, for names found in the text
+}
+
+// model answer
+type indexResponse struct {
+	Entries []struct {
+		Term  string `json:"term"`
+		Kind  string `json:"kind"`
+		First string `json:"first"`
+	} `json:"entries"`
+}
+
+// Index asks gen for the names and topics in the transcript in sub. Names
+// that appear in the text are located in it, so their timestamps do not
+// depend on the model.
+func Index(ctx context.Context, gen llm.Generator, sub *subtitle.Subtitle, opts Options) ([]IndexEntry, error) {
+	if len(sub.Entries) == 0 {
+		return nil, errs.Wrap(errs.KindInput, errors.New("transcript is empty"))
+	}
+
+	answer, err := gen.Generate(ctx, buildIndexPrompt(Transcript(sub), opts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to index transcript: %w", err)
+	}
+	var resp indexResponse
+	if err := llm.DecodeJSON(answer, &resp); err != nil {
+		return nil, fmt.Errorf("failed to index transcript: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var entries []IndexEntry
+	for _, e := range resp.Entries {
+		term := strings.TrimSpace(e.Term)
+		key := strings.ToLower(term)
+		if term == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		entry := IndexEntry{Term: term, Kind: indexKind(e.Kind)}
+		entry.Occurrences = occurrences(sub, term)
+		if len(entry.Occurrences) > 0 {
+			entry.First = entry.Occurrences[0]
+		} else if first, err := ParseTimestamp(e.First); err == nil {
+			entry.First = first
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].Term) < strings.ToLower(entries[j].Term)
+	})
+	return entries, nil
+}
+
+func buildIndexPrompt(transcript string, opts Options) string {
+	var sb strings.Builder
+	sb.WriteString("Below is the timestamped transcript of a recording. Build an index of it for an archive: ")
+	sb.WriteString("the main topics discussed, and the people, organizations, places, works (books, films, products, papers), ")
+	sb.WriteString("and technical terms mentioned by name.\n")
+	sb.WriteString("Write names as they appear in the transcript. Topics may be phrased in your own words. ")
+	sb.WriteString("For each entry give the timestamp where it first comes up.\n")
+	if opts.Language != "" {
+		fmt.Fprintf(&sb, "Write topics in %s; keep names as spoken.\n", opts.Language)
+	}
+	if opts.Prompt != "" {
+		fmt.Fprintf(&sb, "\nAdditional instructions:\n%s\n", opts.Prompt)
+	}
+	sb.WriteString("\nRespond with only a JSON object in this format:\n")
+	sb.WriteString(`{"entries": [{"term": "...", "kind": "topic|person|organization|place|work|term", "first": "0:00"}]}`)
+	sb.WriteString("\n\nTranscript:\n")
+	sb.WriteString(transcript)
+	return sb.String()
+}
+
+func indexKind(kind string) string {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	for _, k := range indexKinds {
+		if k.kind == kind {
+			return kind
+		}
+	}
+	return "term"
+}
+
+// start times of the cues whose text contains term as a whole word,
+// ignoring case
+func occurrences(sub *subtitle.Subtitle, term string) []time.Duration {
+	needle := strings.ToLower(term)
+	var times []time.Duration
+	for _, entry := range sub.Entries {
+		text := strings.ToLower(strings.Join(strings.Fields(entry.Text), " "))
+		if containsWord(text, needle) {
+			times = append(times, entry.StartTime)
+		}
+	}
+	return times
+}
+
+func containsWord(text, word string) bool {
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(word)
+		if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+// ASCII letters and digits; other bytes, including those of multi-byte
+// characters, end a word
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9'
+}
+
+// IndexFormat is an index file layout
+type IndexFormat string
+
+const (
+	IndexJSON     IndexFormat = "json"
+	IndexMarkdown IndexFormat = "markdown"
+)
+
+// ParseIndexFormat validates an --index-format value
+func ParseIndexFormat(s string) (IndexFormat, error) {
+	switch f := IndexFormat(strings.ToLower(strings.TrimSpace(s))); f {
+	case IndexJSON, IndexMarkdown:
+		return f, nil
+	case "md":
+		return IndexMarkdown, nil
+	}
+	return "", fmt.Errorf("unsupported index format %q: use json or markdown", s)
+}
+
+// Extension is the file extension for the format, with the dot
+func (f IndexFormat) Extension() string {
+	if f == IndexMarkdown {
+		return ".index.md"
+	}
+	return ".index.json"
+}
+
+// index entry as written to JSON
+type jsonIndexEntry struct {
+	Term         string    `json:"term"`
+	Kind         string    `json:"kind"`
+	First        string    `json:"first"`
+	FirstSeconds float64   `json:"first_seconds"`
+	Occurrences  []float64 `json:"occurrences_seconds,omitempty"`
+}
+
+// WriteIndex writes entries in format
+func WriteIndex(w io.Writer, entries []IndexEntry, format IndexFormat) error {
+	if format == IndexMarkdown {
+		return writeIndexMarkdown(w, entries)
+	}
+	out := make([]jsonIndexEntry, len(entries))
+	for i, e := range entries {
+		out[i] = jsonIndexEntry{
+			Term:         e.Term,
+			Kind:         e.Kind,
+			First:        FormatTimestamp(e.First),
+			FirstSeconds: e.First.Seconds(),
+		}
+		for _, t := range e.Occurrences {
+			out[i].Occurrences = append(out[i].Occurrences, t.Seconds())
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]any{"entries": out})
+}
+
+// at most this many timestamps are listed per entry in markdown
+const maxListedOccurrences = 10
+
+func writeIndexMarkdown(w io.Writer, entries []IndexEntry) error {
+	var sb strings.Builder
+	sb.WriteString("# Index\n")
+	for _, k := range indexKinds {
+		var section []IndexEntry
+		for _, e := range entries {
+			if e.Kind == k.kind {
+				section = append(section, e)
+			}
+		}
+		if len(section) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n## %s\n\n", k.heading)
+		for _, e := range section {
+			times := e.Occurrences
+			if len(times) == 0 {
+				times = []time.Duration{e.First}
+			}
+			stamps := make([]string, 0, min(len(times), maxListedOccurrences))
+			for _, t := range times[:min(len(times), maxListedOccurrences)] {
+				stamps = append(stamps, FormatTimestamp(t))
+			}
+			if len(times) > maxListedOccurrences {
+				stamps = append(stamps, "…")
+			}
+			fmt.Fprintf(&sb, "- **%s**: %s\n", e.Term, strings.Join(stamps, ", "))
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,61 @@
+package summarize
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/llm"
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+func TestIndex(t *testing.T) {
+	sub := &subtitle.Subtitle{Entries: []subtitle.Entry{
+		{StartTime: 0, EndTime: 3 * time.Second, Text: "Ada Lovelace wrote the first program."},
+		{StartTime: 10 * time.Second, EndTime: 13 * time.Second, Text: "It ran on the Analytical Engine."},
+		{StartTime: 70 * time.Second, EndTime: 73 * time.Second, Text: "Lovelace, Ada\nLovelace, again."},
+		{StartTime: 80 * time.Second, EndTime: 83 * time.Second, Text: "Adamant engineers"},
+	}}
+	gen := llm.GeneratorFunc(func(ctx context.Context, prompt string) (string, error) {
+		return `{"entries": [
+			{"term": "Ada", "kind": "person", "first": "0:00"},
+			{"term": "Analytical Engine", "kind": "work", "first": "0:09"},
+			{"term": "History of computing", "kind": "topic", "first": "0:05"},
+			{"term": "ada", "kind": "person", "first": "0:00"},
+			{"term": "Babbage", "kind": "alien", "first": "bad"}
+		]}`, nil
+	})
+
+	entries, err := Index(context.Background(), gen, sub, Options{})
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("Index() = %+v, want 4 entries", entries)
+	}
+	ada := entries[0]
+	if ada.Term != "Ada" || len(ada.Occurrences) != 2 || ada.Occurrences[1] != 70*time.Second {
+		t.Errorf("Ada = %+v, want found at 0:00 and 1:10 only", ada)
+	}
+	if engine := entries[1]; engine.First != 10*time.Second {
+		t.Errorf("Analytical Engine first = %v, want located at 0:10", engine.First)
+	}
+	if babbage := entries[2]; babbage.Kind != "term" || babbage.First != 0 {
+		t.Errorf("Babbage = %+v", babbage)
+	}
+	if topic := entries[3]; topic.First != 5*time.Second || topic.Occurrences != nil {
+		t.Errorf("topic = %+v, want the model's timestamp", topic)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteIndex(&buf, entries, IndexMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## Topics\n\n- **History of computing**: 0:05\n", "## People\n\n- **Ada**: 0:00, 1:10\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("markdown index missing %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package summarize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/llm"
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// Minutes are the structured notes of a meeting
+type Minutes struct {
+	Title       string
+	Attendees   []string
+	Summary     string
+	Decisions   []Decision
+	ActionItems []ActionItem
+}
+
+// Decision is something the meeting agreed on
+type Decision struct {
+	At   time.Duration // where it was agreed; negative when unknown
+	Text string
+}
+
+// ActionItem is a task someone took on
+type ActionItem struct {
+	At    time.Duration // where it was assigned; negative when unknown
+	Task  string
+	Owner string // empty when nobody took it
+	Due   string // as said, e.g. "Friday"; empty when not set
+}
+
+// model answer
+type minutesResponse struct {
+	Title     string   `json:"title"`
+	Attendees []string `json:"attendees"`
+	Summary   string   `json:"summary"`
+	Decisions []struct {
+		At   string `json:"at"`
+		Text string `json:"text"`
+	} `json:"decisions"`
+	ActionItems []struct {
+		At    string `json:"at"`
+		Task  string `json:"task"`
+		Owner string `json:"owner"`
+		Due   string `json:"due"`
+	} `json:"action_items"`
+}
+
+// TakeMinutes asks gen for the minutes of the meeting transcribed in sub.
+// Speaker labels in the transcript let the model attribute decisions and
+// tasks; without them the minutes only name people who are mentioned.
+func TakeMinutes(ctx context.Context, gen llm.Generator, sub *subtitle.Subtitle, opts Options) (*Minutes, error) {
+	if len(sub.Entries) == 0 {
+		return nil, errs.Wrap(errs.KindInput, errors.New("transcript is empty"))
+	}
+	speakers := Speakers(sub)
+	duration := sub.Entries[len(sub.Entries)-1].EndTime
+
+	answer, err := gen.Generate(ctx, buildMinutesPrompt(Transcript(sub), duration, speakers, opts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to take minutes: %w", err)
+	}
+	var resp minutesResponse
+	if err := llm.DecodeJSON(answer, &resp); err != nil {
+		return nil, fmt.Errorf("failed to take minutes: %w", err)
+	}
+
+	minutes := &Minutes{
+		Title:     strings.TrimSpace(resp.Title),
+		Attendees: uniqueNames(resp.Attendees),
+		Summary:   strings.TrimSpace(resp.Summary),
+	}
+	if len(minutes.Attendees) == 0 {
+		minutes.Attendees = uniqueNames(append(append([]string(nil), opts.Attendees...), speakers...))
+	}
+	for _, d := range resp.Decisions {
+		if text := strings.TrimSpace(d.Text); text != "" {
+			minutes.Decisions = append(minutes.Decisions, Decision{At: minutesTime(d.At, duration), Text: text})
+		}
+	}
+	for _, a := range resp.ActionItems {
+		if task := strings.TrimSpace(a.Task); task != "" {
+			minutes.ActionItems = append(minutes.ActionItems, ActionItem{
+				At:    minutesTime(a.At, duration),
+				Task:  task,
+				Owner: strings.TrimSpace(a.Owner),
+				Due:   strings.TrimSpace(a.Due),
+			})
+		}
+	}
+	// in meeting order, items without a time last
+	sort.SliceStable(minutes.Decisions, func(i, j int) bool {
+		return timeBefore(minutes.Decisions[i].At, minutes.Decisions[j].At)
+	})
+	sort.SliceStable(minutes.ActionItems, func(i, j int) bool {
+		return timeBefore(minutes.ActionItems[i].At, minutes.ActionItems[j].At)
+	})
+	return minutes, nil
+}
+
+// Speakers lists the speaker labels in sub in the order they first talk
+func Speakers(sub *subtitle.Subtitle) []string {
+	var speakers []string
+	for _, entry := range sub.Entries {
+		speakers = append(speakers, entry.Speaker)
+	}
+	return uniqueNames(speakers)
+}
+
+func buildMinutesPrompt(transcript string, duration time.Duration, speakers []string, opts Options) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Below is the timestamped transcript of a meeting %s long.\n\n", FormatTimestamp(duration))
+	sb.WriteString("Write its minutes: a short title, the attendees, a summary of one paragraph, ")
+	sb.WriteString("the decisions made, and the action items with who owns each and when it is due.\n")
+	if len(speakers) > 0 {
+		sb.WriteString("Each line starts with who is speaking. Use a speaker's real name when the transcript reveals it, ")
+		sb.WriteString("e.g. when someone is greeted or introduces themselves, and the label otherwise.\n")
+	}
+	if len(opts.Attendees) > 0 {
+		fmt.Fprintf(&sb, "The attendees are %s.\n", strings.Join(opts.Attendees, ", "))
+	}
+	sb.WriteString("Only list decisions that were agreed and tasks that someone took on or was given; leave the owner or due date empty when none was said. ")
+	sb.WriteString("Give each decision and action item the timestamp from the transcript where it was made.\n")
+	if opts.Language != "" {
+		fmt.Fprintf(&sb, "Write everything in %s.\n", opts.Language)
+	} else {
+		sb.WriteString("Write in the language of the transcript.\n")
+	}
+	if opts.Prompt != "" {
+		fmt.Fprintf(&sb, "\nAdditional instructions:\n%s\n", opts.Prompt)
+	}
+
+	sb.WriteString("\nRespond with only a JSON object in this format:\n")
+	sb.WriteString(`{"title": "...", "attendees": ["..."], "summary": "...", "decisions": [{"at": "0:00", "text": "..."}], "action_items": [{"at": "0:00", "task": "...", "owner": "...", "due": "..."}]}`)
+	sb.WriteString("\n\nTranscript:\n")
+	sb.WriteString(transcript)
+	return sb.String()
+}
+
+// a model timestamp, negative when missing or past the end of the meeting
+func minutesTime(s string, duration time.Duration) time.Duration {
+	at, err := ParseTimestamp(s)
+	if err != nil || at > duration {
+		return -1
+	}
+	return at
+}
+
+func timeBefore(a, b time.Duration) bool {
+	if a < 0 || b < 0 {
+		return a >= 0 && b < 0
+	}
+	return a < b
+}
+
+// trimmed, non-empty names without repeats, ignoring case
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, name)
+	}
+	return out
+}
+
+// WriteMinutes writes m as markdown: decisions as a list and action items
+// as a table, each with the timestamp where it came up
+func WriteMinutes(w io.Writer, m *Minutes) error {
+	var sb strings.Builder
+	title := m.Title
+	if title == "" {
+		title = "Meeting Minutes"
+	}
+	fmt.Fprintf(&sb, "# %s\n\n", title)
+	if len(m.Attendees) > 0 {
+		fmt.Fprintf(&sb, "**Attendees:** %s\n\n", strings.Join(m.Attendees, ", "))
+	}
+	if m.Summary != "" {
+		fmt.Fprintf(&sb, "## Summary\n\n%s\n\n", m.Summary)
+	}
+
+	sb.WriteString("## Decisions\n\n")
+	if len(m.Decisions) == 0 {
+		sb.WriteString("None recorded.\n\n")
+	}
+	for _, d := range m.Decisions {
+		if d.At >= 0 {
+			fmt.Fprintf(&sb, "- [%s] %s\n", FormatTimestamp(d.At), d.Text)
+		} else {
+			fmt.Fprintf(&sb, "- %s\n", d.Text)
+		}
+	}
+	if len(m.Decisions) > 0 {
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("## Action Items\n\n")
+	if len(m.ActionItems) == 0 {
+		sb.WriteString("None recorded.\n")
+	} else {
+		sb.WriteString("| Time | Owner | Task | Due |\n")
+		sb.WriteString("| --- | --- | --- | --- |\n")
+		for _, a := range m.ActionItems {
+			at := ""
+			if a.At >= 0 {
+				at = FormatTimestamp(a.At)
+			}
+			fmt.Fprintf(&sb, "| %s | %s | %s | %s |\n",
+				at, tableCell(a.Owner), tableCell(a.Task), tableCell(a.Due))
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// a markdown table cell on one line, with its pipes escaped
+func tableCell(s string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(s), " "), "|", `\|`)
+}
@@ -0,0 +1,86 @@
+package summarize
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/llm"
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+func TestTakeMinutes(t *testing.T) {
+	sub := &subtitle.Subtitle{Entries: []subtitle.Entry{
+		{StartTime: 0, EndTime: 3 * time.Second, Text: "Morning, Raj.", Speaker: "Speaker 1"},
+		{StartTime: 4 * time.Second, EndTime: 6 * time.Second, Text: "Hi Ana.", Speaker: "Speaker 2"},
+		{StartTime: 6 * time.Second, EndTime: 9 * time.Second, Text: "Let's ship on Monday.", Speaker: "Speaker 2"},
+		{StartTime: 90 * time.Second, EndTime: 95 * time.Second, Text: "I'll send the notes.", Speaker: "Speaker 1"},
+	}}
+	var prompt string
+	gen := llm.GeneratorFunc(func(ctx context.Context, p string) (string, error) {
+		prompt = p
+		return `{"title": "Release sync", "attendees": ["Ana", "Raj", "ana"], "summary": "Release planning.",
+			"decisions": [{"at": "0:06", "text": "Ship on Monday"}, {"at": "bad", "text": "Keep the beta"}, {"at": "0:01", "text": " "}],
+			"action_items": [{"at": "9:99:99", "task": "Book the room", "owner": "", "due": ""},
+				{"at": "1:30", "task": "Send the notes", "owner": "Ana", "due": "Friday"}]}`, nil
+	})
+
+	minutes, err := TakeMinutes(context.Background(), gen, sub, Options{})
+	if err != nil {
+		t.Fatalf("TakeMinutes() error = %v", err)
+	}
+	if !strings.Contains(prompt, "[0:04] Speaker 2: Hi Ana. Let's ship on Monday.\n[1:30] Speaker 1:") {
+		t.Errorf("prompt transcript not split by speaker:\n%s", prompt)
+	}
+	if len(minutes.Attendees) != 2 || minutes.Attendees[0] != "Ana" {
+		t.Errorf("Attendees = %v", minutes.Attendees)
+	}
+	if len(minutes.Decisions) != 2 || minutes.Decisions[0].At != 6*time.Second || minutes.Decisions[1].At >= 0 {
+		t.Errorf("Decisions = %+v, want the timed one first", minutes.Decisions)
+	}
+	if len(minutes.ActionItems) != 2 || minutes.ActionItems[0].Owner != "Ana" || minutes.ActionItems[1].At >= 0 {
+		t.Errorf("ActionItems = %+v", minutes.ActionItems)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMinutes(&buf, minutes); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# Release sync\n\n**Attendees:** Ana, Raj\n",
+		"- [0:06] Ship on Monday\n- Keep the beta\n",
+		"| 1:30 | Ana | Send the notes | Friday |\n|  |  | Book the room |  |\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("minutes missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestTakeMinutesFallsBackToSpeakers(t *testing.T) {
+	sub := &subtitle.Subtitle{Entries: []subtitle.Entry{
+		{StartTime: 0, EndTime: 3 * time.Second, Text: "Hello.", Speaker: "Speaker 1"},
+		{StartTime: 4 * time.Second, EndTime: 6 * time.Second, Text: "Hi.", Speaker: "Speaker 2"},
+	}}
+	gen := llm.GeneratorFunc(func(ctx context.Context, p string) (string, error) {
+		return `{"title": "", "attendees": [], "summary": "", "decisions": [], "action_items": []}`, nil
+	})
+
+	minutes, err := TakeMinutes(context.Background(), gen, sub, Options{Attendees: []string{"Ana"}})
+	if err != nil {
+		t.Fatalf("TakeMinutes() error = %v", err)
+	}
+	if got := strings.Join(minutes.Attendees, ","); got != "Ana,Speaker 1,Speaker 2" {
+		t.Errorf("Attendees = %q", got)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMinutes(&buf, minutes); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "# Meeting Minutes\n") || strings.Count(buf.String(), "None recorded.") != 2 {
+		t.Errorf("empty minutes:\n%s", buf.String())
+	}
+}
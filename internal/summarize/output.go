@@ -0,0 +1,96 @@
+package summarize
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ChapterFormat is a chapters file layout
+type ChapterFormat string
+
+const (
+	ChaptersYouTube    ChapterFormat = "youtube"    // "0:00 Title" lines for a video description
+	ChaptersFFMetadata ChapterFormat = "ffmetadata" // ffmpeg metadata, for muxing into the video
+)
+
+// ParseChapterFormat validates a --chapters-format value
+func ParseChapterFormat(s string) (ChapterFormat, error) {
+	switch f := ChapterFormat(strings.ToLower(strings.TrimSpace(s))); f {
+	case ChaptersYouTube, ChaptersFFMetadata:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported chapters format %q: use youtube or ffmetadata", s)
+}
+
+// Extension is the file extension for the format, with the dot
+func (f ChapterFormat) Extension() string {
+	if f == ChaptersFFMetadata {
+		return ".chapters.ffmeta"
+	}
+	return ".chapters.txt"
+}
+
+// WriteChapters writes chapters in format. duration ends the last chapter
+// in ffmetadata.
+func WriteChapters(w io.Writer, chapters []Chapter, format ChapterFormat, duration time.Duration) error {
+	var sb strings.Builder
+	switch format {
+	case ChaptersFFMetadata:
+		sb.WriteString(";FFMETADATA1\n")
+		for i, c := range chapters {
+			end := duration
+			if i+1 < len(chapters) {
+				end = chapters[i+1].Start
+			}
+			fmt.Fprintf(&sb, "\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+				c.Start.Milliseconds(),
+				end.Milliseconds(),
+				escapeFFMetadata(c.Title),
+			)
+		}
+	default:
+		for _, c := range chapters {
+			fmt.Fprintf(&sb, "%s %s\n", FormatTimestamp(c.Start), c.Title)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// ffmetadata escapes these with a backslash
+var ffmetadataEscaper = strings.NewReplacer(
+	`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", `\`+"\n",
+)
+
+func escapeFFMetadata(s string) string {
+	return ffmetadataEscaper.Replace(s)
+}
+
+// WriteMarkdown writes the summary as a markdown document
+func WriteMarkdown(w io.Writer, s *Summary) error {
+	var sb strings.Builder
+	if s.Title != "" {
+		fmt.Fprintf(&sb, "# %s\n\n", s.Title)
+	}
+	if s.Summary != "" {
+		fmt.Fprintf(&sb, "%s\n\n", s.Summary)
+	}
+	if len(s.KeyPoints) > 0 {
+		sb.WriteString("## Key Points\n\n")
+		for _, point := range s.KeyPoints {
+			fmt.Fprintf(&sb, "- %s\n", point)
+		}
+		sb.WriteString("\n")
+	}
+	if len(s.Chapters) > 0 {
+		sb.WriteString("## Chapters\n\n")
+		for _, c := range s.Chapters {
+			fmt.Fprintf(&sb, "- %s %s\n", FormatTimestamp(c.Start), c.Title)
+		}
+		sb.WriteString("\n")
+	}
+	_, err := io.WriteString(w, strings.TrimRight(sb.String(), "\n")+"\n")
+	return err
+}
@@ -0,0 +1,214 @@
+package summarize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/llm"
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// Chapter is a titled section of the media starting at Start
+type Chapter struct {
+	Start time.Duration
+	Title string
+}
+
+// Summary is what the model made of a transcript
+type Summary struct {
+	Title     string
+	Summary   string
+	KeyPoints []string
+	Chapters  []Chapter
+}
+
+type Options struct {
+	Language     string   // language to write in; empty for the transcript's
+	ChaptersOnly bool     // ask for chapter markers only
+	Prompt       string   // additional instructions
+	Attendees    []string // names of the people at a meeting, for minutes
+}
+
+// YouTube only shows chapters when there are at least three, the first at
+// 0:00, each at least ten seconds long
+const (
+	minChapters      = 3
+	minChapterLength = 10 * time.Second
+)
+
+// transcript lines are merged into paragraphs of about this length, each
+// with one timestamp, to keep the prompt short
+const paragraphLength = 30 * time.Second
+
+// model answer
+type response struct {
+	Title     string   `json:"title"`
+	Summary   string   `json:"summary"`
+	KeyPoints []string `json:"key_points"`
+	Chapters  []struct {
+		Start string `json:"start"`
+		Title string `json:"title"`
+	} `json:"chapters"`
+}
+
+// Summarize asks gen for a summary, key points, and chapter markers of the
+// transcript in sub
+func Summarize(ctx context.Context, gen llm.Generator, sub *subtitle.Subtitle, opts Options) (*Summary, error) {
+	if len(sub.Entries) == 0 {
+		return nil, errs.Wrap(errs.KindInput, errors.New("transcript is empty"))
+	}
+	duration := sub.Entries[len(sub.Entries)-1].EndTime
+
+	answer, err := gen.Generate(ctx, buildPrompt(Transcript(sub), duration, opts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize transcript: %w", err)
+	}
+	var resp response
+	if err := llm.DecodeJSON(answer, &resp); err != nil {
+		return nil, fmt.Errorf("failed to summarize transcript: %w", err)
+	}
+
+	summary := &Summary{
+		Title:   strings.TrimSpace(resp.Title),
+		Summary: strings.TrimSpace(resp.Summary),
+	}
+	for _, point := range resp.KeyPoints {
+		if point = strings.TrimSpace(point); point != "" {
+			summary.KeyPoints = append(summary.KeyPoints, point)
+		}
+	}
+	var chapters []Chapter
+	for _, c := range resp.Chapters {
+		start, err := ParseTimestamp(c.Start)
+		title := strings.TrimSpace(c.Title)
+		if err != nil || title == "" {
+			continue
+		}
+		chapters = append(chapters, Chapter{Start: start, Title: title})
+	}
+	summary.Chapters = NormalizeChapters(chapters, duration)
+	return summary, nil
+}
+
+// Transcript renders sub as timestamped paragraphs for a prompt. A new
+// paragraph starts when the speaker changes, labelled with their name.
+func Transcript(sub *subtitle.Subtitle) string {
+	var sb strings.Builder
+	var paragraph []string
+	var start time.Duration
+	var speaker string
+	flush := func() {
+		if len(paragraph) > 0 {
+			label := ""
+			if speaker != "" {
+				label = speaker + ": "
+			}
+			fmt.Fprintf(&sb, "[%s] %s%s\n", FormatTimestamp(start), label, strings.Join(paragraph, " "))
+			paragraph = nil
+		}
+	}
+	for _, entry := range sub.Entries {
+		if len(paragraph) > 0 && (entry.StartTime-start >= paragraphLength || entry.Speaker != speaker) {
+			flush()
+		}
+		if len(paragraph) == 0 {
+			start = entry.StartTime
+			speaker = entry.Speaker
+		}
+		if text := strings.Join(strings.Fields(entry.Text), " "); text != "" {
+			paragraph = append(paragraph, text)
+		}
+	}
+	flush()
+	return sb.String()
+}
+
+func buildPrompt(transcript string, duration time.Duration, opts Options) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Below is the timestamped transcript of a recording %s long.\n\n", FormatTimestamp(duration))
+	if opts.ChaptersOnly {
+		sb.WriteString("Divide it into chapters for a video player.\n")
+	} else {
+		sb.WriteString("Write a short title, a summary of one or two paragraphs, a list of the key points, and chapters for a video player.\n")
+	}
+	sb.WriteString("Chapters mark where the topic changes: the first starts at 0:00, each lasts at least 10 seconds, and each title is a few words. ")
+	sb.WriteString("Use timestamps from the transcript.\n")
+	if opts.Language != "" {
+		fmt.Fprintf(&sb, "Write everything in %s.\n", opts.Language)
+	} else {
+		sb.WriteString("Write in the language of the transcript.\n")
+	}
+	if opts.Prompt != "" {
+		fmt.Fprintf(&sb, "\nAdditional instructions:\n%s\n", opts.Prompt)
+	}
+
+	sb.WriteString("\nRespond with only a JSON object in this format:\n")
+	if opts.ChaptersOnly {
+		sb.WriteString(`{"chapters": [{"start": "0:00", "title": "..."}]}`)
+	} else {
+		sb.WriteString(`{"title": "...", "summary": "...", "key_points": ["..."], "chapters": [{"start": "0:00", "title": "..."}]}`)
+	}
+	sb.WriteString("\n\nTranscript:\n")
+	sb.WriteString(transcript)
+	return sb.String()
+}
+
+// NormalizeChapters sorts chapters, moves the first to 0:00, and drops
+// those after the end or too close to the previous one, so players accept
+// them. Fewer than three chapters are returned as none.
+func NormalizeChapters(chapters []Chapter, duration time.Duration) []Chapter {
+	sorted := append([]Chapter(nil), chapters...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	var out []Chapter
+	for _, c := range sorted {
+		if duration > 0 && c.Start >= duration-minChapterLength {
+			break
+		}
+		if len(out) == 0 {
+			c.Start = 0
+		} else if c.Start-out[len(out)-1].Start < minChapterLength {
+			continue
+		}
+		out = append(out, c)
+	}
+	if len(out) < minChapters {
+		return nil
+	}
+	return out
+}
+
+// ParseTimestamp reads "1:02:03", "2:03", or a number of seconds
+func ParseTimestamp(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.Trim(strings.TrimSpace(s), "[]"))
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 || s == "" {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	var total float64
+	for _, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		total = total*60 + n
+	}
+	return time.Duration(total * float64(time.Second)).Round(time.Second), nil
+}
+
+// FormatTimestamp writes d the way YouTube descriptions do: "2:03" or
+// "1:02:03"
+func FormatTimestamp(d time.Duration) string {
+	total := int64(d.Round(time.Second) / time.Second)
+	h, m, s := total/3600, total/60%60, total%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
@@ -0,0 +1,113 @@
+package summarize
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/llm"
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+func testTranscript() *subtitle.Subtitle {
+	return &subtitle.Subtitle{Entries: []subtitle.Entry{
+		{StartTime: 0, EndTime: 4 * time.Second, Text: "Welcome to the show."},
+		{StartTime: 5 * time.Second, EndTime: 9 * time.Second, Text: "Today we talk\nabout bread."},
+		{StartTime: 40 * time.Second, EndTime: 45 * time.Second, Text: "First, flour."},
+		{StartTime: 10 * time.Minute, EndTime: 10*time.Minute + 5*time.Second, Text: "Thanks for listening."},
+	}}
+}
+
+func TestSummarize(t *testing.T) {
+	var prompt string
+	gen := llm.GeneratorFunc(func(ctx context.Context, p string) (string, error) {
+		prompt = p
+		return "```json\n" + `{
+			"title": "Bread",
+			"summary": "A show about bread.",
+			"key_points": ["Flour matters", " "],
+			"chapters": [
+				{"start": "0:05", "title": "Intro"},
+				{"start": "0:40", "title": "Flour"},
+				{"start": "0:45", "title": "Too close"},
+				{"start": "5:00", "title": "Baking"},
+				{"start": "later", "title": "Broken"}
+			]
+		}` + "\n```", nil
+	})
+
+	got, err := Summarize(context.Background(), gen, testTranscript(), Options{Language: "Spanish"})
+	if err != nil {
+		t.Fatalf("Summarize() error = %v", err)
+	}
+	if !strings.Contains(prompt, "[0:00] Welcome to the show. Today we talk about bread.\n[0:40] First, flour.") ||
+		!strings.Contains(prompt, "in Spanish") {
+		t.Errorf("prompt = %q", prompt)
+	}
+	if got.Title != "Bread" || len(got.KeyPoints) != 1 {
+		t.Errorf("Summarize() = %+v", got)
+	}
+	want := []Chapter{{0, "Intro"}, {40 * time.Second, "Flour"}, {5 * time.Minute, "Baking"}}
+	if len(got.Chapters) != len(want) {
+		t.Fatalf("chapters = %+v, want %+v", got.Chapters, want)
+	}
+	for i := range want {
+		if got.Chapters[i] != want[i] {
+			t.Errorf("chapters[%d] = %+v, want %+v", i, got.Chapters[i], want[i])
+		}
+	}
+}
+
+func TestNormalizeChaptersTooFew(t *testing.T) {
+	chapters := []Chapter{{0, "Intro"}, {time.Minute, "Main"}, {10 * time.Minute, "After the end"}}
+	if got := NormalizeChapters(chapters, 5*time.Minute); got != nil {
+		t.Errorf("NormalizeChapters() = %+v, want none", got)
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"0:00", 0, false},
+		{"12:34", 12*time.Minute + 34*time.Second, false},
+		{"1:02:03", time.Hour + 2*time.Minute + 3*time.Second, false},
+		{"[0:40]", 40 * time.Second, false},
+		{"95", 95 * time.Second, false},
+		{"1:2:3:4", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimestamp(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseTimestamp(%q) = %v, %v", tt.input, got, err)
+		}
+	}
+}
+
+func TestWriteChapters(t *testing.T) {
+	chapters := []Chapter{{0, "Intro"}, {90 * time.Second, "Q&A; part=1"}, {time.Hour, "Outro"}}
+	tests := []struct {
+		format ChapterFormat
+		want   string
+	}{
+		{ChaptersYouTube, "0:00 Intro\n1:30 Q&A; part=1\n1:00:00 Outro\n"},
+		{ChaptersFFMetadata, ";FFMETADATA1\n\n" +
+			"[CHAPTER]\nTIMEBASE=1/1000\nSTART=0\nEND=90000\ntitle=Intro\n\n" +
+			"[CHAPTER]\nTIMEBASE=1/1000\nSTART=90000\nEND=3600000\ntitle=Q&A\\; part\\=1\n\n" +
+			"[CHAPTER]\nTIMEBASE=1/1000\nSTART=3600000\nEND=3700000\ntitle=Outro\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := WriteChapters(&buf, chapters, tt.format, 3700*time.Second); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("WriteChapters(%s) = %q, want %q", tt.format, buf.String(), tt.want)
+		}
+	}
+}
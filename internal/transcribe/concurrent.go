@@ -0,0 +1,170 @@
+package transcribe
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/pool"
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// transcribes one file; the shape of Transcriber.Transcribe
+type transcribeFunc func(ctx context.Context, audioPath string) (*Result, error)
+
+// runs a single-file transcriber over chunks in parallel
+type concurrentTranscriber struct {
+	Transcriber
+	options Options
+}
+
+// Concurrent turns any transcriber into one that transcribes chunks in
+// parallel, so middleware applied to t sees every chunk request. The
+// Limiter, OnChunk, and RemoveChunks options apply as for the built-in
+// providers.
+func Concurrent(t Transcriber, opts Options) StreamingTranscriber {
+	return &concurrentTranscriber{Transcriber: t, options: opts}
+}
+
+func (c *concurrentTranscriber) TranscribeWithChunks(
+	ctx context.Context,
+	chunks []audio.ChunkInfo,
+	concurrency int,
+) (*Result, error) {
+	return transcribeChunks(ctx, chunks, concurrency, c.options, c.Transcriber)
+}
+
+func (c *concurrentTranscriber) TranscribeStream(
+	ctx context.Context,
+	chunks <-chan audio.ChunkInfo,
+	concurrency int,
+) (*Result, error) {
+	return transcribeStream(ctx, chunks, concurrency, c.options, c.Transcriber)
+}
+
+func (c *concurrentTranscriber) Shutdown(ctx context.Context) error {
+	return Shutdown(ctx, c.Transcriber)
+}
+
+func (c *concurrentTranscriber) Close() error {
+	return Shutdown(context.Background(), c.Transcriber)
+}
+
+// transcribes a single chunk and shifts its timestamps by the chunk offset
+func transcribeChunk(
+	ctx context.Context,
+	transcribe transcribeFunc,
+	chunk audio.ChunkInfo,
+) ([]subtitle.Segment, error) {
+	result, err := transcribe(ctx, chunk.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	adjustedSegments := make([]subtitle.Segment, len(result.Segments))
+	for i, seg := range result.Segments {
+		adjustedSegments[i] = subtitle.Segment{
+			StartTime: seg.StartTime + chunk.StartTime,
+			EndTime:   seg.EndTime + chunk.StartTime,
+			Text:      seg.Text,
+			Speaker:   seg.Speaker,
+			Language:  seg.Language,
+		}
+	}
+
+	return adjustedSegments, nil
+}
+
+// transcribes already-cut chunks in parallel with t. When t is a Preparer,
+// as a provider that uploads chunks, the chunks after those in flight are
+// prepared meanwhile.
+func transcribeChunks(
+	ctx context.Context,
+	chunks []audio.ChunkInfo,
+	concurrency int,
+	opts Options,
+	t Transcriber,
+) (*Result, error) {
+	if len(chunks) == 0 {
+		return &Result{}, nil
+	}
+	if concurrency <= 0 {
+		concurrency = 3
+	}
+	if prepare := preparer(t); prepare != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		work := prepareAhead(ctx, prepare, chunkChannel(ctx, chunks), concurrency)
+		results, err := pool.Stream(ctx, work, min(concurrency, len(chunks)), chunkWorker(opts, t.Transcribe))
+		return mergePooled(results, err, opts)
+	}
+	results, err := pool.Run(ctx, chunks, concurrency, chunkWorker(opts, t.Transcribe))
+	return mergePooled(results, err, opts)
+}
+
+// transcribes chunks with t as they arrive on the channel, until it is
+// closed, preparing them ahead as transcribeChunks does
+func transcribeStream(
+	ctx context.Context,
+	chunks <-chan audio.ChunkInfo,
+	concurrency int,
+	opts Options,
+	t Transcriber,
+) (*Result, error) {
+	if concurrency <= 0 {
+		concurrency = 3
+	}
+	if prepare := preparer(t); prepare != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		chunks = prepareAhead(ctx, prepare, chunks, concurrency)
+	}
+	results, err := pool.Stream(ctx, chunks, concurrency, chunkWorker(opts, t.Transcribe))
+	return mergePooled(results, err, opts)
+}
+
+// transcribes one chunk within the limiter, then cleans up and reports it
+func chunkWorker(
+	opts Options,
+	transcribe transcribeFunc,
+) func(context.Context, audio.ChunkInfo) (chunkResult, error) {
+	transcribeOne := func(ctx context.Context, chunk audio.ChunkInfo) ([]subtitle.Segment, error) {
+		start := time.Now()
+		segments, err := transcribeChunk(ctx, transcribe, chunk)
+		if err == nil {
+			opts.Timings.finish(chunk, time.Since(start))
+		}
+		return segments, err
+	}
+	return func(ctx context.Context, chunk audio.ChunkInfo) (chunkResult, error) {
+		segments, err := transcribeLimited(ctx, opts.Limiter, chunk, transcribeOne)
+		if err != nil {
+			return chunkResult{}, fmt.Errorf("chunk %d failed: %w", chunk.Index, err)
+		}
+		if opts.RemoveChunks {
+			_ = os.Remove(chunk.Path)
+		}
+		if opts.OnChunk != nil {
+			opts.OnChunk()
+		}
+		return chunkResult{
+			Index:    chunk.Index,
+			EndTime:  chunk.EndTime,
+			Segments: segments,
+		}, nil
+	}
+}
+
+func mergePooled(results []pool.Result[chunkResult], err error, opts Options) (*Result, error) {
+	if err != nil {
+		return nil, err
+	}
+	chunks := make([]chunkResult, len(results))
+	for i, r := range results {
+		chunks[i] = r.Value
+	}
+	return mergeChunkResults(chunks, opts.Language), nil
+}
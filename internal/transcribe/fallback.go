@@ -0,0 +1,78 @@
+package transcribe
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+
+	"github.com/mgpai22/lipi/internal/middleware"
+)
+
+// FallbackModel is a model a request moves to when it keeps failing on the
+// models before it
+type FallbackModel struct {
+	Provider Provider
+	Model    string
+	APIKey   string
+}
+
+// label of the model in logs, e.g. gemini:gemini-2.5-flash
+func (m FallbackModel) String() string {
+	if m.Model == "" {
+		return string(m.Provider)
+	}
+	return string(m.Provider) + ":" + m.Model
+}
+
+// sends each request to its models in order, moving on when one fails
+// after its retries
+type fallbackTranscriber struct {
+	models       []FallbackModel
+	transcribers []Transcriber
+	options      Options
+}
+
+// builds the middleware chain of each model of a fallback chain; the first
+// model is the primary one
+func newFallback(ctx context.Context, models []FallbackModel, opts Options) (Transcriber, error) {
+	f := &fallbackTranscriber{models: models, options: opts}
+	for _, m := range models {
+		modelOpts := opts
+		modelOpts.Model = m.Model
+		modelOpts.Fallbacks = nil
+		t, err := newProvider(ctx, m.Provider, m.APIKey, modelOpts)
+		if err != nil {
+			_ = f.Shutdown(context.Background())
+			return nil, err
+		}
+		f.transcribers = append(f.transcribers, Chain(t, modelOpts.middleware(m.Provider)...))
+	}
+	return f, nil
+}
+
+func (f *fallbackTranscriber) Transcribe(ctx context.Context, audioPath string) (*Result, error) {
+	calls := make([]func(context.Context) (*Result, error), len(f.transcribers))
+	for i, t := range f.transcribers {
+		calls[i] = func(ctx context.Context) (*Result, error) {
+			return t.Transcribe(ctx, audioPath)
+		}
+	}
+	return middleware.Fallback(ctx, calls, func(next int, err error) {
+		if f.options.Logger != nil {
+			f.options.Logger.Warnw("Transcription failed, trying the next fallback model",
+				"audio", filepath.Base(audioPath),
+				"failed", f.models[next-1].String(),
+				"next", f.models[next].String(),
+				"error", err,
+			)
+		}
+	})
+}
+
+func (f *fallbackTranscriber) Shutdown(ctx context.Context) error {
+	var errList []error
+	for _, t := range f.transcribers {
+		errList = append(errList, Shutdown(ctx, t))
+	}
+	return errors.Join(errList...)
+}
@@ -1,17 +1,24 @@
 package transcribe
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"regexp"
-	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/gemini"
+	"github.com/mgpai22/lipi/internal/httpclient"
+	"github.com/mgpai22/lipi/internal/middleware"
 	"github.com/mgpai22/lipi/internal/subtitle"
 	"google.golang.org/genai"
 )
@@ -21,13 +28,26 @@ type GeminiTranscriber struct {
 	client  *genai.Client
 	model   string
 	options Options
+
+	mu sync.Mutex
+	// uploads Prepare started, by audio path, until their request takes them
+	pending map[string]*pendingUpload
+}
+
+// an upload started ahead of its chunk's request
+type pendingUpload struct {
+	done chan struct{}
+	file *genai.File
+	err  error
 }
 
 // segment from Gemini's JSON response
 type transcriptSegment struct {
-	Start float64 `json:"start"`
-	End   float64 `json:"end"`
-	Text  string  `json:"text"`
+	Start    float64 `json:"start"`
+	End      float64 `json:"end"`
+	Text     string  `json:"text"`
+	Speaker  string  `json:"speaker,omitempty"`
+	Language string  `json:"language,omitempty"`
 }
 
 func NewGeminiTranscriber(
@@ -36,7 +56,8 @@ func NewGeminiTranscriber(
 	opts Options,
 ) (*GeminiTranscriber, error) {
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey: apiKey,
+		APIKey:     apiKey,
+		HTTPClient: httpclient.Or(opts.HTTPClient),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
@@ -54,20 +75,169 @@ func NewGeminiTranscriber(
 	}, nil
 }
 
+// largest audio sent inline in the request instead of through the Files
+// API; requests are limited to 20 MB once the audio is base64-encoded
+const maxInlineAudioBytes = 14 << 20
+
 // transcribes single audio file
 func (t *GeminiTranscriber) Transcribe(
 	ctx context.Context,
 	audioPath string,
 ) (*Result, error) {
-	if _, err := os.Stat(audioPath); os.IsNotExist(err) {
+	info, err := os.Stat(audioPath)
+	if os.IsNotExist(err) {
 		return nil, fmt.Errorf("audio file not found: %s", audioPath)
 	}
 
-	uploadedFile, err := t.client.Files.UploadFromPath(ctx, audioPath, nil)
+	var segments []subtitle.Segment
+	if err == nil && info.Size() <= maxInlineAudioBytes {
+		data, err := os.ReadFile(audioPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audio file: %w", err)
+		}
+		part := genai.NewPartFromBytes(data, Media{Name: audioPath}.contentType())
+		segments, err = t.transcribePart(ctx, part, audioPath)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		uploadedFile, err := t.takeUpload(ctx, audioPath)
+		if err != nil {
+			return nil, err
+		}
+		segments, err = t.transcribeUploaded(ctx, uploadedFile, audioPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	duration, _ := audio.GetDuration(ctx, audioPath)
+
+	return &Result{
+		Segments: segments,
+		Language: t.options.Language,
+		Duration: duration,
+	}, nil
+}
+
+// uploads audio too large to send inline in the background, so the upload
+// of the next chunk overlaps the request for the current one
+func (t *GeminiTranscriber) Prepare(ctx context.Context, audioPath string) {
+	info, err := os.Stat(audioPath)
+	if err != nil || info.Size() <= maxInlineAudioBytes {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.pending[audioPath]; ok {
+		return
+	}
+	if t.pending == nil {
+		t.pending = make(map[string]*pendingUpload)
+	}
+	p := &pendingUpload{done: make(chan struct{})}
+	t.pending[audioPath] = p
+	go func() {
+		defer close(p.done)
+		p.file, p.err = t.upload(ctx, audioPath)
+	}()
+}
+
+// the upload Prepare started for audioPath, or a new one. A failed
+// pre-upload is tried again here, with this request's context.
+func (t *GeminiTranscriber) takeUpload(ctx context.Context, audioPath string) (*genai.File, error) {
+	t.mu.Lock()
+	p := t.pending[audioPath]
+	delete(t.pending, audioPath)
+	t.mu.Unlock()
+
+	if p != nil {
+		select {
+		case <-p.done:
+			if p.err == nil {
+				return p.file, nil
+			}
+		case <-ctx.Done():
+			go t.discard(p)
+			return nil, ctx.Err()
+		}
+	}
+	return t.upload(ctx, audioPath)
+}
+
+func (t *GeminiTranscriber) upload(ctx context.Context, audioPath string) (*genai.File, error) {
+	start := time.Now()
+	defer t.options.Timings.upload(audioPath, start)
+	file, err := middleware.Retry(ctx, uploadRetry, func(ctx context.Context) (*genai.File, error) {
+		return t.client.Files.UploadFromPath(ctx, audioPath, &genai.UploadFileConfig{
+			DisplayName: uploadDisplayName(audioPath),
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload audio file: %w", errs.Classify(err))
+	}
+	return file, nil
+}
+
+// deletes a pre-upload no request will use, once it finishes
+func (t *GeminiTranscriber) discard(p *pendingUpload) {
+	<-p.done
+	if p.err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	_, _ = t.client.Files.Delete(ctx, p.file.Name, nil)
+}
+
+// sends small media inline and uploads the rest straight from its reader,
+// so it never touches local disk
+func (t *GeminiTranscriber) TranscribeReader(
+	ctx context.Context,
+	media Media,
+) (*Result, error) {
+	// read up to the inline limit to learn whether the media fits
+	head, err := io.ReadAll(io.LimitReader(media.Reader, maxInlineAudioBytes+1))
 	if err != nil {
-		return nil, fmt.Errorf("failed to upload audio file: %w", err)
+		return nil, fmt.Errorf("failed to read audio: %w", err)
+	}
+
+	var segments []subtitle.Segment
+	if len(head) <= maxInlineAudioBytes {
+		part := genai.NewPartFromBytes(head, media.contentType())
+		segments, err = t.transcribePart(ctx, part, media.Name)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		reader := io.MultiReader(bytes.NewReader(head), media.Reader)
+		uploadedFile, err := t.client.Files.Upload(ctx, reader, &genai.UploadFileConfig{
+			MIMEType:    media.contentType(),
+			DisplayName: uploadDisplayName(media.Name),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload audio: %w", errs.Classify(err))
+		}
+		segments, err = t.transcribeUploaded(ctx, uploadedFile, media.Name)
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	return &Result{
+		Segments: segments,
+		Language: t.options.Language,
+		Duration: segmentsEnd(segments),
+	}, nil
+}
+
+// transcribes an uploaded file and deletes it afterwards; name labels the
+// saved raw response
+func (t *GeminiTranscriber) transcribeUploaded(
+	ctx context.Context,
+	uploadedFile *genai.File,
+	name string,
+) ([]subtitle.Segment, error) {
 	defer func() {
 		cleanupCtx, cancel := context.WithTimeout(
 			context.Background(),
@@ -77,33 +247,96 @@ func (t *GeminiTranscriber) Transcribe(
 		_, _ = t.client.Files.Delete(cleanupCtx, uploadedFile.Name, nil)
 	}()
 
+	return t.transcribePart(ctx, genai.NewPartFromURI(uploadedFile.URI, uploadedFile.MIMEType), name)
+}
+
+// put in front of the prompt when Gemini's safety filters blocked a chunk,
+// for one more attempt with the adjustable filters off
+const repromptNote = "This audio is from a film, TV programme, or recording being captioned for accessibility. " +
+	"Transcribe everything exactly as spoken, including violent, sexual, or offensive language, without censoring, summarizing, or leaving anything out. "
+
+// transcribes the audio in part, inline or uploaded. A chunk the safety
+// filters block is sent once more with a neutral note and relaxed filters.
+func (t *GeminiTranscriber) transcribePart(
+	ctx context.Context,
+	audioPart *genai.Part,
+	name string,
+) ([]subtitle.Segment, error) {
+	segments, err := t.generate(ctx, audioPart, name, false)
+	if !errors.Is(err, errs.ErrContentFiltered) {
+		return segments, err
+	}
+	if t.options.Logger != nil {
+		t.options.Logger.Warnw("Chunk blocked by Gemini's safety filters, sending it again with relaxed filters",
+			"audio", filepath.Base(name),
+			"error", err,
+		)
+	}
+	segments, err = t.generate(ctx, audioPart, name, true)
+	if errors.Is(err, errs.ErrContentFiltered) {
+		return nil, fmt.Errorf("%w (also with relaxed filters)", err)
+	}
+	return segments, err
+}
+
+// sends one transcription request; relaxed adds repromptNote and turns
+// the adjustable safety filters off
+func (t *GeminiTranscriber) generate(
+	ctx context.Context,
+	audioPart *genai.Part,
+	name string,
+	relaxed bool,
+) ([]subtitle.Segment, error) {
 	prompt := t.buildTranscriptionPrompt()
+	config := t.generateConfig(relaxed)
+	if relaxed {
+		prompt = repromptNote + prompt
+	}
 
 	parts := []*genai.Part{
 		genai.NewPartFromText(prompt),
-		genai.NewPartFromURI(uploadedFile.URI, uploadedFile.MIMEType),
+		audioPart,
 	}
 	contents := []*genai.Content{
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
 
-	result, err := t.client.Models.GenerateContent(ctx, t.model, contents, nil)
+	traced, done := t.options.Timings.trace(ctx, name)
+	defer done()
+	start := time.Now()
+	result, err := t.client.Models.GenerateContent(
+		traced,
+		t.model,
+		contents,
+		config,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("transcription failed: %w", err)
+		err = fmt.Errorf("transcription failed: %w", errs.Classify(err))
+		dumpRequest(t.options, ProviderGemini, t.model, name, prompt, start, nil, err)
+		return nil, err
+	}
+	if result != nil && result.UsageMetadata != nil {
+		meta := result.UsageMetadata
+		t.options.Usage.AddTokens(
+			int64(meta.PromptTokenCount),
+			int64(meta.CandidatesTokenCount),
+		)
+	}
+
+	if t.options.ResponseDir != "" {
+		raw, _ := json.MarshalIndent(result, "", "  ")
+		saveRawResponse(t.options.ResponseDir, name, raw)
 	}
 
 	segments, err := t.parseTranscriptionResponse(result)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse transcription: %w", err)
+		err = fmt.Errorf("failed to parse transcription: %w", err)
 	}
-
-	duration, _ := audio.GetDuration(audioPath)
-
-	return &Result{
-		Segments: segments,
-		Language: t.options.Language,
-		Duration: duration,
-	}, nil
+	dumpRequest(t.options, ProviderGemini, t.model, name, prompt, start, result, err)
+	if err != nil {
+		return nil, err
+	}
+	return segments, nil
 }
 
 // transcribes a single chunk and adjusts timestamps
@@ -111,29 +344,7 @@ func (t *GeminiTranscriber) TranscribeChunk(
 	ctx context.Context,
 	chunk audio.ChunkInfo,
 ) ([]subtitle.Segment, error) {
-	result, err := t.Transcribe(ctx, chunk.Path)
-	if err != nil {
-		return nil, err
-	}
-
-	// adjust timestamps based on chunk offset
-	adjustedSegments := make([]subtitle.Segment, len(result.Segments))
-	for i, seg := range result.Segments {
-		adjustedSegments[i] = subtitle.Segment{
-			StartTime: seg.StartTime + chunk.StartTime,
-			EndTime:   seg.EndTime + chunk.StartTime,
-			Text:      seg.Text,
-		}
-	}
-
-	return adjustedSegments, nil
-}
-
-// holds the result of transcribing a chunk
-type chunkResult struct {
-	Index    int
-	Segments []subtitle.Segment
-	Error    error
+	return transcribeChunk(ctx, t.Transcribe, chunk)
 }
 
 // transcribes multiple chunks in parallel
@@ -142,113 +353,27 @@ func (t *GeminiTranscriber) TranscribeWithChunks(
 	chunks []audio.ChunkInfo,
 	concurrency int,
 ) (*Result, error) {
-	if len(chunks) == 0 {
-		return &Result{}, nil
-	}
-
-	if concurrency <= 0 {
-		concurrency = 3
-	}
-
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	workChan := make(chan audio.ChunkInfo)
-	// buffer to avoid blocking sends if the consumer returns early.
-	resultChan := make(chan chunkResult, len(chunks))
-
-	var wg sync.WaitGroup
-	for i := 0; i < concurrency; i++ {
-		wg.Go(func() {
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case chunk, ok := <-workChan:
-					if !ok {
-						return
-					}
-					// if cancellation won the race with receiving work, stop
-					// promptly to avoid starting more uploads/transcriptions
-					if ctx.Err() != nil {
-						return
-					}
-
-					segments, err := t.TranscribeChunk(ctx, chunk)
-					if err != nil {
-						// cancel as soon as a worker hits an error so other
-						// workers stop scheduling further work quickly
-						cancel()
-					}
-					resultChan <- chunkResult{
-						Index:    chunk.Index,
-						Segments: segments,
-						Error:    err,
-					}
-				}
-			}
-		})
-	}
-
-	// feed work in a separate goroutine so we can stop enqueueing promptly once
-	// cancellation is triggered
-	go func() {
-		defer close(workChan)
-		for _, chunk := range chunks {
-			select {
-			case <-ctx.Done():
-				return
-			case workChan <- chunk:
-			}
-		}
-	}()
-
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	results := make([]chunkResult, 0, len(chunks))
-	var firstErr error
-	for result := range resultChan {
-		if result.Error != nil && firstErr == nil {
-			firstErr = fmt.Errorf(
-				"chunk %d failed: %w",
-				result.Index,
-				result.Error,
-			)
-			cancel()
-		}
-		if result.Error == nil {
-			results = append(results, result)
-		}
-	}
-	if firstErr != nil {
-		return nil, firstErr
-	}
-
-	// sort by index to maintain order
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Index < results[j].Index
-	})
-
-	// merge
-	var allSegments []subtitle.Segment
-	for _, r := range results {
-		allSegments = append(allSegments, r.Segments...)
-	}
+	return transcribeChunks(ctx, chunks, concurrency, t.options, t)
+}
 
-	// Calculate total duration from last ch`unk
-	var totalDuration time.Duration
-	if len(chunks) > 0 {
-		totalDuration = chunks[len(chunks)-1].EndTime
-	}
+// transcribes chunks as they arrive on the channel, until it is closed
+func (t *GeminiTranscriber) TranscribeStream(
+	ctx context.Context,
+	chunks <-chan audio.ChunkInfo,
+	concurrency int,
+) (*Result, error) {
+	return transcribeStream(ctx, chunks, concurrency, t.options, t)
+}
 
-	return &Result{
-		Segments: allSegments,
-		Language: t.options.Language,
-		Duration: totalDuration,
-	}, nil
+// request config for the transcription call, nil for provider defaults;
+// relaxed turns off the safety filters left at their defaults
+func (t *GeminiTranscriber) generateConfig(relaxed bool) *genai.GenerateContentConfig {
+	return gemini.Settings{
+		Temperature:      t.options.Temperature,
+		TopP:             t.options.TopP,
+		ThinkingBudget:   t.options.ThinkingBudget,
+		SafetyThresholds: t.options.SafetyThresholds,
+	}.Config(relaxed)
 }
 
 // creates the prompt for transcription
@@ -266,6 +391,30 @@ func (t *GeminiTranscriber) buildTranscriptionPrompt() string {
 		"where 'start' and 'end' are timestamps in seconds (as numbers). ",
 	)
 
+	if t.options.Diarize {
+		sb.WriteString(
+			"Also give each object a 'speaker' field naming who is talking, and start a new object whenever the speaker changes. ",
+		)
+		if len(t.options.Speakers) > 0 {
+			sb.WriteString(fmt.Sprintf(
+				"The speakers are %s: use their names when you can tell who is talking, otherwise 'Speaker 1', 'Speaker 2', and so on. ",
+				strings.Join(t.options.Speakers, ", "),
+			))
+		} else {
+			sb.WriteString(
+				"Use a speaker's name when they are introduced or addressed by name, otherwise 'Speaker 1', 'Speaker 2', and so on. ",
+			)
+		}
+	}
+
+	if t.options.Multilingual {
+		sb.WriteString(
+			"The speakers may switch between languages. Also give each object a 'language' field with the ISO 639-1 code of the language spoken, write each phrase in the language and script it is spoken in, and start a new object whenever the language changes. ",
+		)
+	}
+
+	sb.WriteString(t.options.Lyrics.instruction())
+
 	if t.options.Language != "" {
 		sb.WriteString(fmt.Sprintf("The audio is in %s. ", t.options.Language))
 	}
@@ -280,6 +429,15 @@ func (t *GeminiTranscriber) buildTranscriptionPrompt() string {
 		)
 	}
 
+	if len(t.options.Glossary) > 0 {
+		sb.WriteString(
+			fmt.Sprintf(
+				"Spell these names and terms exactly as written: %s. ",
+				strings.Join(t.options.Glossary.Terms(), ", "),
+			),
+		)
+	}
+
 	if t.options.Prompt != "" {
 		sb.WriteString(t.options.Prompt)
 		sb.WriteString(" ")
@@ -296,8 +454,11 @@ func (t *GeminiTranscriber) buildTranscriptionPrompt() string {
 func (t *GeminiTranscriber) parseTranscriptionResponse(
 	result *genai.GenerateContentResponse,
 ) ([]subtitle.Segment, error) {
+	if err := gemini.Blocked(result); err != nil {
+		return nil, err
+	}
 	if result == nil || len(result.Candidates) == 0 {
-		return nil, fmt.Errorf("empty response from Gemini")
+		return nil, errs.Mark(errs.ErrParse, fmt.Errorf("empty response from Gemini"))
 	}
 
 	// use only the first candidate to avoid concatenating multiple JSON arrays
@@ -317,18 +478,18 @@ func (t *GeminiTranscriber) parseTranscriptionResponse(
 	}
 
 	if responseText == "" {
-		return nil, fmt.Errorf("no text in Gemini response")
+		return nil, errs.Mark(gemini.ParseClass(result), fmt.Errorf("no text in Gemini response"))
 	}
 
 	responseText = cleanJSONResponse(responseText)
 
 	transcriptSegments, err := extractTranscriptSegments(responseText)
 	if err != nil {
-		return nil, fmt.Errorf(
+		return nil, errs.Mark(gemini.ParseClass(result), fmt.Errorf(
 			"failed to parse JSON response: %w (response: %s)",
 			err,
 			truncateString(responseText, 200),
-		)
+		))
 	}
 
 	// convert to subtitle segments
@@ -338,6 +499,8 @@ func (t *GeminiTranscriber) parseTranscriptionResponse(
 			StartTime: time.Duration(ts.Start * float64(time.Second)),
 			EndTime:   time.Duration(ts.End * float64(time.Second)),
 			Text:      strings.TrimSpace(ts.Text),
+			Speaker:   strings.TrimSpace(ts.Speaker),
+			Language:  strings.ToLower(strings.TrimSpace(ts.Language)),
 		}
 	}
 
@@ -444,9 +607,31 @@ func truncateString(s string, maxLen int) string {
 	return s[:maxLen] + "..."
 }
 
-// Close closes the Gemini client
-func (t *GeminiTranscriber) Close() error {
-	// The genai client doesn't have a Close method in the current SDK
-	// but we include this for future compatibility
+// deletes the uploads Prepare started that no request took, as when a run
+// failed early; waits for uploads still in progress until ctx is done
+func (t *GeminiTranscriber) Shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+
+	for _, p := range pending {
+		select {
+		case <-p.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if p.err == nil {
+			_, _ = t.client.Files.Delete(ctx, p.file.Name, nil)
+		}
+	}
 	return nil
 }
+
+// Close deletes leftover uploads, see Shutdown. The genai client itself
+// holds nothing to close.
+func (t *GeminiTranscriber) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return t.Shutdown(ctx)
+}
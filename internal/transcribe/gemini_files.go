@@ -0,0 +1,97 @@
+package transcribe
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/httpclient"
+	"github.com/mgpai22/lipi/internal/middleware"
+	"google.golang.org/genai"
+)
+
+// display names of the files lipi uploads start with this, so cleanup can
+// tell them from files other tools uploaded with the same key
+const uploadPrefix = "lipi-"
+
+// retries of a failed upload of a file on disk; rejected keys are not
+// retried
+var uploadRetry = middleware.RetryPolicy{
+	Attempts: 2,
+	Retryable: func(err error) bool {
+		return middleware.DefaultRetryable(errs.Classify(err))
+	},
+}
+
+func uploadDisplayName(name string) string {
+	return uploadPrefix + filepath.Base(name)
+}
+
+// Upload is a file lipi uploaded to the Gemini Files API
+type Upload struct {
+	Name        string // resource name, e.g. files/abc123
+	DisplayName string
+	Size        int64
+	Created     time.Time
+	Expires     time.Time
+}
+
+// GeminiFiles lists and deletes the files lipi uploaded with a Gemini API
+// key. Uploads are deleted once their chunk is transcribed; runs that
+// crashed or lost the connection can leave some behind until they expire.
+type GeminiFiles struct {
+	client *genai.Client
+}
+
+func NewGeminiFiles(ctx context.Context, apiKey string, opts Options) (*GeminiFiles, error) {
+	if apiKey == "" {
+		return nil, errs.Wrap(errs.KindAuth, fmt.Errorf("API key is required"))
+	}
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:     apiKey,
+		HTTPClient: httpclient.Or(opts.HTTPClient),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+	}
+	return &GeminiFiles{client: client}, nil
+}
+
+// List returns the files lipi uploaded, oldest first
+func (f *GeminiFiles) List(ctx context.Context) ([]Upload, error) {
+	var uploads []Upload
+	for file, err := range f.client.Files.All(ctx) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", errs.Classify(err))
+		}
+		if !strings.HasPrefix(file.DisplayName, uploadPrefix) {
+			continue
+		}
+		upload := Upload{
+			Name:        file.Name,
+			DisplayName: file.DisplayName,
+			Created:     file.CreateTime,
+			Expires:     file.ExpirationTime,
+		}
+		if file.SizeBytes != nil {
+			upload.Size = *file.SizeBytes
+		}
+		uploads = append(uploads, upload)
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].Created.Before(uploads[j].Created)
+	})
+	return uploads, nil
+}
+
+// Delete removes an uploaded file by its resource name
+func (f *GeminiFiles) Delete(ctx context.Context, name string) error {
+	if _, err := f.client.Files.Delete(ctx, name, nil); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", name, errs.Classify(err))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package transcribe
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGeminiFiles(t *testing.T) {
+	var deleted []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/files"):
+			_, _ = io.WriteString(w, `{"files":[
+				{"name":"files/new","displayName":"lipi-chunk_001.mp3","sizeBytes":"2048","createTime":"2026-10-14T10:00:00Z"},
+				{"name":"files/other","displayName":"notes.pdf","createTime":"2026-10-14T09:00:00Z"},
+				{"name":"files/old","displayName":"lipi-chunk_000.mp3","sizeBytes":"1024","createTime":"2026-10-14T08:00:00Z"}
+			]}`)
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/v1beta/"))
+			_, _ = io.WriteString(w, `{}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	files, err := NewGeminiFiles(context.Background(), "key", Options{
+		HTTPClient: &http.Client{Transport: handlerTransport{handler}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uploads, err := files.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uploads) != 2 || uploads[0].Name != "files/old" || uploads[1].Name != "files/new" {
+		t.Fatalf("uploads = %+v, want the two lipi files oldest first", uploads)
+	}
+	if uploads[0].Size != 1024 || !uploads[0].Created.Equal(time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("upload = %+v", uploads[0])
+	}
+
+	if err := files.Delete(context.Background(), "files/old"); err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || deleted[0] != "files/old" {
+		t.Errorf("deleted = %v", deleted)
+	}
+}
+
+func TestUploadDisplayName(t *testing.T) {
+	if got := uploadDisplayName("/tmp/lipi-123/chunk_004.mp3"); got != "lipi-chunk_004.mp3" {
+		t.Errorf("uploadDisplayName() = %q", got)
+	}
+}
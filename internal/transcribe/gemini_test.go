@@ -1,6 +1,12 @@
 package transcribe
 
 import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -159,6 +165,39 @@ func TestExtractTranscriptSegments(t *testing.T) {
 	}
 }
 
+func TestDiarizedPrompt(t *testing.T) {
+	transcriber := &GeminiTranscriber{options: Options{
+		Diarize:  true,
+		Speakers: []string{"Ana", "Raj"},
+	}}
+	prompt := transcriber.buildTranscriptionPrompt()
+	if !strings.Contains(prompt, "'speaker'") || !strings.Contains(prompt, "Ana, Raj") {
+		t.Errorf("prompt does not ask for speakers: %s", prompt)
+	}
+
+	segments, err := extractTranscriptSegments(
+		`[{"start": 0, "end": 2, "text": "Hi", "speaker": "Ana"}]`,
+	)
+	if err != nil || len(segments) != 1 || segments[0].Speaker != "Ana" {
+		t.Errorf("extractTranscriptSegments() = %+v, %v", segments, err)
+	}
+}
+
+func TestMultilingualPrompt(t *testing.T) {
+	transcriber := &GeminiTranscriber{options: Options{Multilingual: true}}
+	prompt := transcriber.buildTranscriptionPrompt()
+	if !strings.Contains(prompt, "'language'") {
+		t.Errorf("prompt does not ask for languages: %s", prompt)
+	}
+
+	segments, err := extractTranscriptSegments(
+		`[{"start": 0, "end": 2, "text": "Kya haal hai", "language": "hi"}, {"start": 2, "end": 4, "text": "All good"}]`,
+	)
+	if err != nil || len(segments) != 2 || segments[0].Language != "hi" || segments[1].Language != "" {
+		t.Errorf("extractTranscriptSegments() = %+v, %v", segments, err)
+	}
+}
+
 func TestCleanJSONResponse(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -227,3 +266,90 @@ func TestValidateSegments(t *testing.T) {
 		})
 	}
 }
+
+// serves requests with a handler instead of the network
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (h handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	h.handler.ServeHTTP(rec, req)
+	return rec.Result(), nil
+}
+
+func TestGeminiSendsSmallAudioInline(t *testing.T) {
+	var paths []string
+	var body string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"[{\"start\":0,\"end\":1.5,\"text\":\"Hola\"}]"}]}}]}`)
+	})
+	transcriber, err := NewGeminiTranscriber(context.Background(), "key", Options{
+		HTTPClient: &http.Client{Transport: handlerTransport{handler}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := transcriber.TranscribeReader(context.Background(), Media{
+		Reader: strings.NewReader("audio bytes"),
+		Name:   "chunk.mp3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Segments) != 1 || result.Segments[0].Text != "Hola" {
+		t.Errorf("segments = %+v", result.Segments)
+	}
+	if len(paths) != 1 || !strings.HasSuffix(paths[0], ":generateContent") {
+		t.Fatalf("requests = %v, want a single generateContent without an upload", paths)
+	}
+	inline := `"inlineData":{"data":"` + base64.StdEncoding.EncodeToString([]byte("audio bytes")) + `","mimeType":"audio/mpeg"}`
+	if !strings.Contains(body, inline) {
+		t.Errorf("request does not carry the audio inline: %s", body)
+	}
+}
+
+func TestGeminiRepromptsBlockedChunk(t *testing.T) {
+	var bodies []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		w.Header().Set("Content-Type", "application/json")
+		if len(bodies) == 1 {
+			_, _ = io.WriteString(w, `{"candidates":[{"finishReason":"SAFETY"}]}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"[{\"start\":0,\"end\":1.5,\"text\":\"Hola\"}]"}]}}]}`)
+	})
+	transcriber, err := NewGeminiTranscriber(context.Background(), "key", Options{
+		HTTPClient: &http.Client{Transport: handlerTransport{handler}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := transcriber.TranscribeReader(context.Background(), Media{
+		Reader: strings.NewReader("audio bytes"),
+		Name:   "chunk.mp3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Segments) != 1 || result.Segments[0].Text != "Hola" {
+		t.Errorf("segments = %+v", result.Segments)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(bodies))
+	}
+	if strings.Contains(bodies[0], "safetySettings") {
+		t.Errorf("first request relaxed the safety filters: %s", bodies[0])
+	}
+	if !strings.Contains(bodies[1], `"threshold":"BLOCK_NONE"`) {
+		t.Errorf("second request does not relax the safety filters: %s", bodies[1])
+	}
+}
@@ -0,0 +1,20 @@
+package transcribe
+
+import "context"
+
+// Groq serves the Whisper models through an OpenAI-compatible API
+const groqBaseURL = "https://api.groq.com/openai/v1/"
+
+// default Groq model; whisper-large-v3-turbo is faster but cannot
+// translate to English
+const defaultGroqModel = "whisper-large-v3"
+
+// NewGroqTranscriber returns a transcriber for the Whisper models hosted by
+// Groq, which take the same requests as OpenAI's audio API
+func NewGroqTranscriber(
+	ctx context.Context,
+	apiKey string,
+	opts Options,
+) (*OpenAITranscriber, error) {
+	return newWhisperTranscriber(ProviderGroq, groqBaseURL, defaultGroqModel, apiKey, opts)
+}
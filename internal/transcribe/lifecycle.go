@@ -0,0 +1,26 @@
+package transcribe
+
+import (
+	"context"
+	"io"
+)
+
+// Shutdowner is implemented by transcribers that hold connections, local
+// model handles, or uploaded files, and need a deadline to release them
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// Shutdown releases what t holds, through Shutdown when t implements it and
+// Close otherwise. Transcribers holding nothing need neither. Callers should
+// shut a transcriber down once they are done with it, using a context that
+// is not already cancelled so cleanup can still reach the provider.
+func Shutdown(ctx context.Context, t Transcriber) error {
+	switch t := t.(type) {
+	case Shutdowner:
+		return t.Shutdown(ctx)
+	case io.Closer:
+		return t.Close()
+	}
+	return nil
+}
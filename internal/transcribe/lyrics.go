@@ -0,0 +1,94 @@
+package transcribe
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// how sung content, such as song lyrics, ends up in the transcript
+type Lyrics string
+
+const (
+	// transcribed word for word like speech, without music symbols
+	LyricsKeep Lyrics = "keep"
+	// left out of the transcript
+	LyricsSkip Lyrics = "skip"
+	// transcribed between ♪ markers, as subtitles mark songs
+	LyricsTag Lyrics = "tag"
+)
+
+// music symbols models use to mark sung lines
+const lyricMarks = "♪♫"
+
+// what the Gemini prompt asks for sung content
+func (l Lyrics) instruction() string {
+	switch l {
+	case LyricsKeep:
+		return "Transcribe singing and song lyrics word for word like speech, without music symbols. "
+	case LyricsSkip:
+		return "Do not transcribe singing or song lyrics, only speech; wrap any sung words you do include in ♪ markers. "
+	case LyricsTag:
+		return "Transcribe singing and song lyrics word for word, wrapping each sung line in ♪ markers, e.g. '♪ Happy birthday to you ♪'. "
+	}
+	return ""
+}
+
+// WithLyrics applies policy to the segments of each response, catching
+// sung lines the prompt alone did not: skip drops segments marked with ♪,
+// keep removes the marks, and tag puts them around each sung segment
+func WithLyrics(policy Lyrics) Middleware {
+	return func(next Transcriber) Transcriber {
+		return decorate(next, func(ctx context.Context, audioPath string) (*Result, error) {
+			result, err := next.Transcribe(ctx, audioPath)
+			if err != nil {
+				return nil, err
+			}
+			filtered := *result
+			filtered.Segments = applyLyrics(result.Segments, policy)
+			return &filtered, nil
+		})
+	}
+}
+
+// segments with the policy applied to those marked as sung; marks alone,
+// for music without words, are dropped
+func applyLyrics(segments []subtitle.Segment, policy Lyrics) []subtitle.Segment {
+	out := make([]subtitle.Segment, 0, len(segments))
+	for _, seg := range segments {
+		if !strings.ContainsAny(seg.Text, lyricMarks) {
+			out = append(out, seg)
+			continue
+		}
+		if policy == LyricsSkip {
+			continue
+		}
+		lines := strings.Split(stripLyricMarks(seg.Text), "\n")
+		kept := lines[:0]
+		for _, line := range lines {
+			if line = strings.TrimSpace(line); line != "" {
+				kept = append(kept, line)
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		text := strings.Join(kept, "\n")
+		if policy == LyricsTag {
+			text = "♪ " + text + " ♪"
+		}
+		seg.Text = text
+		out = append(out, seg)
+	}
+	return out
+}
+
+func stripLyricMarks(text string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(lyricMarks, r) {
+			return -1
+		}
+		return r
+	}, text)
+}
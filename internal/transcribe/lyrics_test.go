@@ -0,0 +1,73 @@
+package transcribe
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+func TestApplyLyrics(t *testing.T) {
+	segments := []subtitle.Segment{
+		{EndTime: time.Second, Text: "Welcome to the show."},
+		{StartTime: time.Second, EndTime: 3 * time.Second, Text: "♪ Happy birthday\nto you ♪"},
+		{StartTime: 3 * time.Second, EndTime: 4 * time.Second, Text: "♫ ♫"},
+		{StartTime: 4 * time.Second, EndTime: 5 * time.Second, Text: "la la la ♪"},
+	}
+	tests := []struct {
+		policy Lyrics
+		want   []string
+	}{
+		{LyricsKeep, []string{"Welcome to the show.", "Happy birthday\nto you", "la la la"}},
+		{LyricsSkip, []string{"Welcome to the show."}},
+		{LyricsTag, []string{"Welcome to the show.", "♪ Happy birthday\nto you ♪", "♪ la la la ♪"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			got := applyLyrics(segments, tt.policy)
+			texts := make([]string, len(got))
+			for i, seg := range got {
+				texts[i] = seg.Text
+			}
+			if strings.Join(texts, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("applyLyrics() = %q, want %q", texts, tt.want)
+			}
+		})
+	}
+	if segments[1].Text != "♪ Happy birthday\nto you ♪" {
+		t.Error("applyLyrics() modified its input")
+	}
+}
+
+func TestLyricsPromptAndFilter(t *testing.T) {
+	transcriber := &GeminiTranscriber{options: Options{Lyrics: LyricsTag}}
+	if prompt := transcriber.buildTranscriptionPrompt(); !strings.Contains(prompt, "♪ markers") {
+		t.Errorf("prompt does not ask for marked lyrics: %s", prompt)
+	}
+	if prompt := (&GeminiTranscriber{}).buildTranscriptionPrompt(); strings.Contains(prompt, "lyrics") {
+		t.Errorf("prompt mentions lyrics without a policy: %s", prompt)
+	}
+
+	opts := Options{Lyrics: LyricsSkip}
+	wrapped := Chain(&fixedTranscriber{text: "♪ la la ♪"}, opts.middleware(ProviderGemini)...)
+	result, err := wrapped.Transcribe(context.Background(), "chunk.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Segments) != 0 {
+		t.Errorf("Segments = %+v, want sung lines skipped", result.Segments)
+	}
+	if cacheScope(ProviderGemini, opts) == cacheScope(ProviderGemini, Options{}) {
+		t.Error("lyrics policy should change the cache scope")
+	}
+}
+
+type fixedTranscriber struct {
+	text string
+}
+
+func (f *fixedTranscriber) Transcribe(ctx context.Context, audioPath string) (*Result, error) {
+	return &Result{Segments: []subtitle.Segment{{EndTime: time.Second, Text: f.text}}}, nil
+}
@@ -0,0 +1,92 @@
+package transcribe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// Media is audio read from a stream, such as an upload or an object in
+// storage, rather than from a local file
+type Media struct {
+	Reader      io.Reader
+	Name        string // file name, for its extension and raw response names
+	ContentType string // MIME type; derived from Name when empty
+	Size        int64  // size hint in bytes; 0 when unknown
+}
+
+// optional interface for transcribers that can send a stream to the
+// provider without staging it on disk
+type ReaderTranscriber interface {
+	TranscribeReader(ctx context.Context, media Media) (*Result, error)
+}
+
+// audio types the providers accept, for extensions mime does not know on
+// every platform
+var audioContentTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".aac":  "audio/aac",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+}
+
+func (m Media) contentType() string {
+	if m.ContentType != "" {
+		return m.ContentType
+	}
+	ext := strings.ToLower(filepath.Ext(m.Name))
+	if t, ok := audioContentTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// TranscribeReader transcribes media with t, streaming it to the provider
+// when t supports that and staging it in a temp file otherwise. Middleware
+// wrappers always stage, since retries need to send the audio again.
+func TranscribeReader(ctx context.Context, t Transcriber, media Media) (*Result, error) {
+	if media.Reader == nil {
+		return nil, fmt.Errorf("media has no reader")
+	}
+	if rt, ok := t.(ReaderTranscriber); ok {
+		return rt.TranscribeReader(ctx, media)
+	}
+
+	tmp, err := os.CreateTemp("", "lipi-media-*"+filepath.Ext(media.Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage media: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	_, err = io.Copy(tmp, media.Reader)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage media: %w", err)
+	}
+	return t.Transcribe(ctx, tmp.Name())
+}
+
+// end of the last segment, the best duration estimate for audio that was
+// never on disk to probe
+func segmentsEnd(segments []subtitle.Segment) time.Duration {
+	var end time.Duration
+	for _, seg := range segments {
+		end = max(end, seg.EndTime)
+	}
+	return end
+}
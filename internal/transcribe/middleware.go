@@ -0,0 +1,244 @@
+package transcribe
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/middleware"
+)
+
+// Middleware adds behaviour around each request of a transcriber
+type Middleware func(next Transcriber) Transcriber
+
+// Chain wraps t with mws; the first middleware sees each request first
+func Chain(t Transcriber, mws ...Middleware) Transcriber {
+	for i := len(mws) - 1; i >= 0; i-- {
+		t = mws[i](t)
+	}
+	return t
+}
+
+// transcriber that runs transcribe in front of next
+type decorated struct {
+	next       Transcriber
+	transcribe transcribeFunc
+	// reports audio whose request never reaches next, so preparing it
+	// would be wasted; nil when every request does
+	skipPrepare func(audioPath string) bool
+}
+
+func (d *decorated) Transcribe(ctx context.Context, audioPath string) (*Result, error) {
+	return d.transcribe(ctx, audioPath)
+}
+
+func (d *decorated) Shutdown(ctx context.Context) error {
+	return Shutdown(ctx, d.next)
+}
+
+func (d *decorated) Close() error {
+	return Shutdown(context.Background(), d.next)
+}
+
+func decorate(next Transcriber, transcribe transcribeFunc) Transcriber {
+	return &decorated{next: next, transcribe: transcribe}
+}
+
+// WithRetry sends failed requests again according to policy
+func WithRetry(policy middleware.RetryPolicy) Middleware {
+	return func(next Transcriber) Transcriber {
+		return decorate(next, func(ctx context.Context, audioPath string) (*Result, error) {
+			return middleware.Retry(ctx, policy, func(ctx context.Context) (*Result, error) {
+				return next.Transcribe(ctx, audioPath)
+			})
+		})
+	}
+}
+
+// WithRateLimit waits for limiter before each request
+func WithRateLimit(limiter *middleware.RateLimiter) Middleware {
+	return func(next Transcriber) Transcriber {
+		return decorate(next, func(ctx context.Context, audioPath string) (*Result, error) {
+			if err := limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+			return next.Transcribe(ctx, audioPath)
+		})
+	}
+}
+
+// WithLogging logs each request and its outcome at debug level
+func WithLogging(log *logging.Logger, provider Provider) Middleware {
+	return func(next Transcriber) Transcriber {
+		return decorate(next, func(ctx context.Context, audioPath string) (*Result, error) {
+			start := time.Now()
+			result, err := next.Transcribe(ctx, audioPath)
+			fields := []any{
+				"provider", string(provider),
+				"audio", filepath.Base(audioPath),
+				"elapsed", time.Since(start).String(),
+			}
+			if err != nil {
+				log.Debugw("Transcription request failed", append(fields, "error", err)...)
+			} else {
+				log.Debugw("Transcription request done", append(fields, "segments", len(result.Segments))...)
+			}
+			return result, err
+		})
+	}
+}
+
+// WithMetrics records each request in m
+func WithMetrics(m *middleware.Metrics) Middleware {
+	return func(next Transcriber) Transcriber {
+		return decorate(next, func(ctx context.Context, audioPath string) (*Result, error) {
+			start := time.Now()
+			result, err := next.Transcribe(ctx, audioPath)
+			m.Observe(start, err)
+			return result, err
+		})
+	}
+}
+
+// WithCache answers requests for audio already transcribed with the same
+// settings from cache. scope identifies the settings, see cacheScope.
+func WithCache(cache *middleware.Cache, scope string) Middleware {
+	return func(next Transcriber) Transcriber {
+		cached := func(audioPath string) (string, *Result) {
+			audioKey, err := middleware.FileKey(audioPath)
+			if err != nil {
+				return "", nil
+			}
+			key := middleware.Key(scope, audioKey)
+			var result Result
+			if cache.Get(key, &result) {
+				return key, &result
+			}
+			return key, nil
+		}
+		return &decorated{
+			next: next,
+			transcribe: func(ctx context.Context, audioPath string) (*Result, error) {
+				key, hit := cached(audioPath)
+				if hit != nil {
+					return hit, nil
+				}
+				result, err := next.Transcribe(ctx, audioPath)
+				if err == nil && key != "" {
+					// a failed write only costs a request on the next run
+					_ = cache.Put(key, result)
+				}
+				return result, err
+			},
+			skipPrepare: func(audioPath string) bool {
+				_, hit := cached(audioPath)
+				return hit != nil
+			},
+		}
+	}
+}
+
+// settings that change what a provider returns for the same audio
+func cacheScope(provider Provider, opts Options) string {
+	parts := []string{
+		"transcribe",
+		string(provider),
+		opts.Model,
+		opts.Language,
+		opts.TranscriptLanguage,
+		opts.Prompt,
+		formatFloat(opts.Temperature),
+		strings.Join(opts.Glossary.Terms(), "\n"),
+	}
+	// only runs with these settings extend the key, so existing cache
+	// entries stay valid
+	if opts.Diarize {
+		parts = append(parts, "diarize", strings.Join(opts.Speakers, "\n"))
+	}
+	if opts.Multilingual {
+		parts = append(parts, "multilingual")
+	}
+	if opts.Lyrics != "" {
+		parts = append(parts, "lyrics", string(opts.Lyrics))
+	}
+	if opts.TopP != nil || opts.ThinkingBudget != nil {
+		parts = append(parts, "decoding", formatFloat(opts.TopP), formatInt(opts.ThinkingBudget))
+	}
+	if len(opts.SafetyThresholds) > 0 {
+		parts = append(parts, "safety", formatThresholds(opts.SafetyThresholds))
+	}
+	return middleware.Key(parts...)
+}
+
+// an optional setting as it appears in cache keys, empty when unset
+func formatFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'g', -1, 64)
+}
+
+func formatInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+// thresholds as they appear in cache keys, sorted by category
+func formatThresholds(thresholds map[string]string) string {
+	pairs := make([]string, 0, len(thresholds))
+	for category, threshold := range thresholds {
+		pairs = append(pairs, category+"="+threshold)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// middleware selected by opts, outermost first: cached results skip
+// everything else, each retry waits for the rate limiter again, and the
+// lyrics policy filters each response
+func (opts Options) middleware(provider Provider) []Middleware {
+	var mws []Middleware
+	if opts.Cache != nil {
+		mws = append(mws, WithCache(opts.Cache, cacheScope(provider, opts)))
+	}
+	if opts.Logger != nil {
+		mws = append(mws, WithLogging(opts.Logger, provider))
+	}
+	if opts.Metrics != nil {
+		mws = append(mws, WithMetrics(opts.Metrics))
+	}
+	if opts.Retry.Attempts > 0 {
+		policy := opts.Retry
+		if opts.Metrics != nil || opts.Logger != nil {
+			onRetry := policy.OnRetry
+			policy.OnRetry = func(attempt int, err error) {
+				opts.Metrics.Retry()
+				if opts.Logger != nil {
+					opts.Logger.Debugw("Retrying transcription request",
+						"provider", string(provider),
+						"attempt", attempt,
+						"error", err,
+					)
+				}
+				if onRetry != nil {
+					onRetry(attempt, err)
+				}
+			}
+		}
+		mws = append(mws, WithRetry(policy))
+	}
+	if opts.RateLimit != nil {
+		mws = append(mws, WithRateLimit(opts.RateLimit))
+	}
+	// innermost, so cached results are filtered already
+	if opts.Lyrics != "" {
+		mws = append(mws, WithLyrics(opts.Lyrics))
+	}
+	return mws
+}
@@ -0,0 +1,189 @@
+package transcribe
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/middleware"
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// transcriber returning one segment per file, failing the first failures
+// requests for each
+type fakeTranscriber struct {
+	mu        sync.Mutex
+	failures  int
+	calls     map[string]int
+	shutdowns int
+}
+
+func (f *fakeTranscriber) Shutdown(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.shutdowns++
+	return nil
+}
+
+func (f *fakeTranscriber) Transcribe(ctx context.Context, audioPath string) (*Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.calls == nil {
+		f.calls = map[string]int{}
+	}
+	f.calls[audioPath]++
+	if f.calls[audioPath] <= f.failures {
+		return nil, errors.New("503 unavailable")
+	}
+	return &Result{Segments: []subtitle.Segment{
+		{StartTime: 0, EndTime: time.Second, Text: filepath.Base(audioPath)},
+	}}, nil
+}
+
+func TestShutdownReachesWrappedProvider(t *testing.T) {
+	fake := &fakeTranscriber{}
+	wrapped := Concurrent(Chain(fake, WithRetry(middleware.RetryPolicy{Attempts: 1})), Options{})
+
+	if err := Shutdown(context.Background(), wrapped); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if err := wrapped.(io.Closer).Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if fake.shutdowns != 2 {
+		t.Errorf("provider shut down %d times, want 2", fake.shutdowns)
+	}
+	if err := Shutdown(context.Background(), &OpenAITranscriber{}); err != nil {
+		t.Errorf("Shutdown() of a Close-only provider error = %v", err)
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	record := func(name string) Middleware {
+		return func(next Transcriber) Transcriber {
+			return decorate(next, func(ctx context.Context, audioPath string) (*Result, error) {
+				order = append(order, name)
+				return next.Transcribe(ctx, audioPath)
+			})
+		}
+	}
+
+	t1 := Chain(&fakeTranscriber{}, record("outer"), record("inner"))
+	if _, err := t1.Transcribe(context.Background(), "a.mp3"); err != nil {
+		t.Fatalf("Transcribe() error = %v", err)
+	}
+	if !slices.Equal(order, []string{"outer", "inner"}) {
+		t.Errorf("order = %v, want [outer inner]", order)
+	}
+}
+
+func TestConcurrentAppliesMiddlewarePerChunk(t *testing.T) {
+	fake := &fakeTranscriber{failures: 1}
+	metrics := &middleware.Metrics{}
+	opts := Options{
+		Retry:   middleware.RetryPolicy{Attempts: 1, Backoff: time.Millisecond},
+		Metrics: metrics,
+	}
+	var chunksDone atomic.Int32
+	opts.OnChunk = func() { chunksDone.Add(1) }
+
+	transcriber := Concurrent(Chain(fake, opts.middleware(ProviderGemini)...), opts)
+	chunks := []audio.ChunkInfo{
+		{Index: 0, Path: "chunk_000.mp3", StartTime: 0, EndTime: time.Minute},
+		{Index: 1, Path: "chunk_001.mp3", StartTime: time.Minute, EndTime: 2 * time.Minute},
+	}
+	result, err := transcriber.TranscribeWithChunks(context.Background(), chunks, 2)
+	if err != nil {
+		t.Fatalf("TranscribeWithChunks() error = %v", err)
+	}
+
+	if len(result.Segments) != 2 || result.Segments[1].StartTime != time.Minute {
+		t.Errorf("Segments = %+v, want two segments offset by their chunk", result.Segments)
+	}
+	if chunksDone.Load() != 2 {
+		t.Errorf("OnChunk called %d times, want 2", chunksDone.Load())
+	}
+	if got := metrics.Snapshot(); got.Requests != 2 || got.Retries != 2 {
+		t.Errorf("metrics = %+v, want 2 requests and 2 retries", got)
+	}
+}
+
+func TestCacheMiddleware(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chunk.mp3")
+	if err := os.WriteFile(path, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeTranscriber{}
+	cached := Chain(fake, WithCache(middleware.NewCache(filepath.Join(dir, "cache")), "scope"))
+	for range 2 {
+		if _, err := cached.Transcribe(context.Background(), path); err != nil {
+			t.Fatalf("Transcribe() error = %v", err)
+		}
+	}
+	if fake.calls[path] != 1 {
+		t.Errorf("provider called %d times, want 1", fake.calls[path])
+	}
+}
+
+func TestFactoryWrapsOnlyWithMiddleware(t *testing.T) {
+	ctx := context.Background()
+	plain, err := Factory(ctx, ProviderOpenAI, "fake-key", Options{})
+	if err != nil {
+		t.Fatalf("Factory() error = %v", err)
+	}
+	if _, ok := plain.(*OpenAITranscriber); !ok {
+		t.Errorf("Factory() without middleware = %T, want *OpenAITranscriber", plain)
+	}
+
+	wrapped, err := Factory(ctx, ProviderOpenAI, "fake-key", Options{
+		Retry: middleware.RetryPolicy{Attempts: 2},
+	})
+	if err != nil {
+		t.Fatalf("Factory() error = %v", err)
+	}
+	if _, ok := wrapped.(StreamingTranscriber); !ok {
+		t.Errorf("Factory() with retries = %T, want a StreamingTranscriber", wrapped)
+	}
+}
+
+func TestFallbackPerChunk(t *testing.T) {
+	primary := &fakeTranscriber{failures: 5}
+	fallback := &fakeTranscriber{}
+	f := &fallbackTranscriber{
+		models:       []FallbackModel{{Provider: ProviderGemini, Model: "gemini-2.5-pro"}, {Provider: ProviderGroq}},
+		transcribers: []Transcriber{Chain(primary, WithRetry(middleware.RetryPolicy{Attempts: 1, Backoff: time.Millisecond})), fallback},
+	}
+
+	chunks := []audio.ChunkInfo{
+		{Index: 0, Path: "chunk_000.mp3", StartTime: 0, EndTime: time.Minute},
+		{Index: 1, Path: "chunk_001.mp3", StartTime: time.Minute, EndTime: 2 * time.Minute},
+	}
+	result, err := Concurrent(f, Options{}).TranscribeWithChunks(context.Background(), chunks, 2)
+	if err != nil {
+		t.Fatalf("TranscribeWithChunks() error = %v", err)
+	}
+	if len(result.Segments) != 2 {
+		t.Errorf("segments = %d, want 2", len(result.Segments))
+	}
+	for _, chunk := range chunks {
+		if primary.calls[chunk.Path] != 2 || fallback.calls[chunk.Path] != 1 {
+			t.Errorf("%s: primary called %d times, fallback %d, want 2 and 1",
+				chunk.Path, primary.calls[chunk.Path], fallback.calls[chunk.Path])
+		}
+	}
+
+	if err := Shutdown(context.Background(), f); err != nil || primary.shutdowns != 1 || fallback.shutdowns != 1 {
+		t.Errorf("Shutdown() = %v, shutdowns %d and %d, want both shut down", err, primary.shutdowns, fallback.shutdowns)
+	}
+}
@@ -0,0 +1,86 @@
+package transcribe
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// ProviderMock replays a fixture instead of calling an API, so the generate
+// pipeline can run in CI without keys
+const ProviderMock Provider = "mock"
+
+// MockTranscriptEnv names the fixture the mock provider replays when set
+const MockTranscriptEnv = "LIPI_MOCK_TRANSCRIPT"
+
+// implements Transcriber by returning the same transcript for every file
+type MockTranscriber struct {
+	segments []subtitle.Segment
+	options  Options
+}
+
+func init() {
+	Register(ProviderMock, func(ctx context.Context, apiKey string, opts Options) (Transcriber, error) {
+		return NewMockTranscriber(os.Getenv(MockTranscriptEnv), opts)
+	})
+}
+
+// NewMockTranscriber loads fixture, a transcript in the JSON shape Gemini
+// returns. Its segments are returned for every file or chunk, so tests
+// should use a chunk duration longer than their media. Without a fixture,
+// each file gets one segment naming it.
+func NewMockTranscriber(fixture string, opts Options) (*MockTranscriber, error) {
+	m := &MockTranscriber{options: opts}
+	if fixture == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock transcript: %w", err)
+	}
+	segments, err := extractTranscriptSegments(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mock transcript %s: %w", fixture, err)
+	}
+	m.segments = make([]subtitle.Segment, len(segments))
+	for i, ts := range segments {
+		m.segments[i] = subtitle.Segment{
+			StartTime: time.Duration(ts.Start * float64(time.Second)),
+			EndTime:   time.Duration(ts.End * float64(time.Second)),
+			Text:      strings.TrimSpace(ts.Text),
+		}
+	}
+	return m, nil
+}
+
+func (m *MockTranscriber) Transcribe(ctx context.Context, audioPath string) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	segments := m.segments
+	if segments == nil {
+		name := strings.TrimSuffix(filepath.Base(audioPath), filepath.Ext(audioPath))
+		segments = []subtitle.Segment{{
+			StartTime: 0,
+			EndTime:   2 * time.Second,
+			Text:      "Mock transcript of " + name,
+		}}
+	}
+
+	var duration time.Duration
+	for _, seg := range segments {
+		duration = max(duration, seg.EndTime)
+	}
+	return &Result{
+		Segments: append([]subtitle.Segment(nil), segments...),
+		Language: m.options.Language,
+		Duration: duration,
+	}, nil
+}
@@ -4,23 +4,27 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"sort"
+	"path/filepath"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/httpclient"
 	"github.com/mgpai22/lipi/internal/subtitle"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 )
 
-// implements Transcriber interface using OpenAI Audio API
+// implements Transcriber interface using OpenAI Audio API, or an
+// OpenAI-compatible one such as Groq's
 type OpenAITranscriber struct {
-	client  openai.Client
-	model   string
-	options Options
+	client   openai.Client
+	provider Provider
+	model    string
+	options  Options
 }
 
 // segment from OpenAI Whisper verbose_json response
@@ -42,22 +46,40 @@ func NewOpenAITranscriber(
 	ctx context.Context,
 	apiKey string,
 	opts Options,
+) (*OpenAITranscriber, error) {
+	return newWhisperTranscriber(ProviderOpenAI, "", "whisper-1", apiKey, opts)
+}
+
+// a transcriber for the Whisper API of provider at baseURL, or OpenAI's
+// when baseURL is empty
+func newWhisperTranscriber(
+	provider Provider,
+	baseURL, defaultModel, apiKey string,
+	opts Options,
 ) (*OpenAITranscriber, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("API key is required")
 	}
 
-	client := openai.NewClient(option.WithAPIKey(apiKey))
+	clientOpts := []option.RequestOption{
+		option.WithAPIKey(apiKey),
+		option.WithHTTPClient(httpclient.Or(opts.HTTPClient)),
+	}
+	if baseURL != "" {
+		clientOpts = append(clientOpts, option.WithBaseURL(baseURL))
+	}
+	client := openai.NewClient(clientOpts...)
 
 	model := opts.Model
 	if model == "" {
-		model = "whisper-1"
+		model = defaultModel
 	}
 
 	return &OpenAITranscriber{
-		client:  client,
-		model:   model,
-		options: opts,
+		client:   client,
+		provider: provider,
+		model:    model,
+		options:  opts,
 	}, nil
 }
 
@@ -78,13 +100,54 @@ func (t *OpenAITranscriber) Transcribe(
 		_ = file.Close()
 	}()
 
-	duration, _ := audio.GetDuration(audioPath)
+	duration, _ := audio.GetDuration(ctx, audioPath)
+
+	return t.transcribeFile(ctx, file, audioPath, duration)
+}
 
+// sends media straight from its reader; Whisper's 25 MB limit is checked
+// up front when the size is known
+func (t *OpenAITranscriber) TranscribeReader(
+	ctx context.Context,
+	media Media,
+) (*Result, error) {
+	if media.Size > whisperMaxBytes {
+		return nil, errs.Wrap(errs.KindInput, fmt.Errorf(
+			"%s is %d bytes, over the %d byte Whisper upload limit",
+			media.Name,
+			media.Size,
+			whisperMaxBytes,
+		))
+	}
+
+	file := openai.File(media.Reader, filepath.Base(media.Name), media.contentType())
+	result, err := t.transcribeFile(ctx, file, media.Name, 0)
+	if err != nil {
+		return nil, err
+	}
+	if result.Duration == 0 {
+		result.Duration = segmentsEnd(result.Segments)
+	}
+	return result, nil
+}
+
+// largest file the Whisper API accepts
+const whisperMaxBytes = 25 << 20
+
+// name labels the saved raw response; duration is 0 when unknown
+func (t *OpenAITranscriber) transcribeFile(
+	ctx context.Context,
+	file io.Reader,
+	name string,
+	duration time.Duration,
+) (*Result, error) {
+	ctx, done := t.options.Timings.trace(ctx, name)
+	defer done()
 	if t.shouldUseTranslation() {
-		return t.transcribeWithTranslation(ctx, file, duration)
+		return t.transcribeWithTranslation(ctx, file, name, duration)
 	}
 
-	return t.transcribeWithTimestamps(ctx, file, duration)
+	return t.transcribeWithTimestamps(ctx, file, name, duration)
 }
 
 func (t *OpenAITranscriber) shouldUseTranslation() bool {
@@ -94,7 +157,8 @@ func (t *OpenAITranscriber) shouldUseTranslation() bool {
 
 func (t *OpenAITranscriber) transcribeWithTranslation(
 	ctx context.Context,
-	file *os.File,
+	file io.Reader,
+	name string,
 	duration time.Duration,
 ) (*Result, error) {
 	params := openai.AudioTranslationNewParams{
@@ -103,19 +167,29 @@ func (t *OpenAITranscriber) transcribeWithTranslation(
 		ResponseFormat: openai.AudioTranslationNewParamsResponseFormatVerboseJSON,
 	}
 
-	if t.options.Prompt != "" {
-		params.Prompt = openai.String(t.options.Prompt)
+	if prompt := t.whisperPrompt(); prompt != "" {
+		params.Prompt = openai.String(prompt)
+	}
+	if t.options.Temperature != nil {
+		params.Temperature = openai.Float(*t.options.Temperature)
 	}
 
+	start := time.Now()
 	resp, err := t.client.Audio.Translations.New(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("translation failed: %w", err)
+		err = fmt.Errorf("translation failed: %w", errs.Classify(err))
+		dumpRequest(t.options, t.provider, t.model, name, t.whisperPrompt(), start, nil, err)
+		return nil, err
 	}
 	if resp == nil {
 		return nil, fmt.Errorf("translation returned empty response")
 	}
+	t.options.Usage.AddAudio(duration)
+
+	saveRawResponse(t.options.ResponseDir, name, []byte(resp.RawJSON()))
 
 	segments, err := t.parseVerboseJSONResponse(resp.RawJSON(), duration)
+	dumpRequest(t.options, t.provider, t.model, name, t.whisperPrompt(), start, resp, err)
 	if err != nil {
 		segments = []subtitle.Segment{{
 			StartTime: 0,
@@ -133,7 +207,8 @@ func (t *OpenAITranscriber) transcribeWithTranslation(
 
 func (t *OpenAITranscriber) transcribeWithTimestamps(
 	ctx context.Context,
-	file *os.File,
+	file io.Reader,
+	name string,
 	duration time.Duration,
 ) (*Result, error) {
 	params := openai.AudioTranscriptionNewParams{
@@ -147,19 +222,29 @@ func (t *OpenAITranscriber) transcribeWithTimestamps(
 		params.Language = openai.String(t.options.Language)
 	}
 
-	if t.options.Prompt != "" {
-		params.Prompt = openai.String(t.options.Prompt)
+	if prompt := t.whisperPrompt(); prompt != "" {
+		params.Prompt = openai.String(prompt)
+	}
+	if t.options.Temperature != nil {
+		params.Temperature = openai.Float(*t.options.Temperature)
 	}
 
+	start := time.Now()
 	resp, err := t.client.Audio.Transcriptions.New(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("transcription failed: %w", err)
+		err = fmt.Errorf("transcription failed: %w", errs.Classify(err))
+		dumpRequest(t.options, t.provider, t.model, name, t.whisperPrompt(), start, nil, err)
+		return nil, err
 	}
 	if resp == nil {
 		return nil, fmt.Errorf("transcription returned empty response")
 	}
+	t.options.Usage.AddAudio(duration)
+
+	saveRawResponse(t.options.ResponseDir, name, []byte(resp.RawJSON()))
 
 	segments, err := t.parseVerboseJSONResponse(resp.RawJSON(), duration)
+	dumpRequest(t.options, t.provider, t.model, name, t.whisperPrompt(), start, resp, err)
 	if err != nil {
 		segments = []subtitle.Segment{{
 			StartTime: 0,
@@ -224,22 +309,7 @@ func (t *OpenAITranscriber) TranscribeChunk(
 	ctx context.Context,
 	chunk audio.ChunkInfo,
 ) ([]subtitle.Segment, error) {
-	result, err := t.Transcribe(ctx, chunk.Path)
-	if err != nil {
-		return nil, err
-	}
-
-	// adjust timestamps based on chunk offset
-	adjustedSegments := make([]subtitle.Segment, len(result.Segments))
-	for i, seg := range result.Segments {
-		adjustedSegments[i] = subtitle.Segment{
-			StartTime: seg.StartTime + chunk.StartTime,
-			EndTime:   seg.EndTime + chunk.StartTime,
-			Text:      seg.Text,
-		}
-	}
-
-	return adjustedSegments, nil
+	return transcribeChunk(ctx, t.Transcribe, chunk)
 }
 
 // transcribes multiple chunks in parallel
@@ -248,110 +318,32 @@ func (t *OpenAITranscriber) TranscribeWithChunks(
 	chunks []audio.ChunkInfo,
 	concurrency int,
 ) (*Result, error) {
-	if len(chunks) == 0 {
-		return &Result{}, nil
-	}
-
-	if concurrency <= 0 {
-		concurrency = 3
-	}
-
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	workChan := make(chan audio.ChunkInfo)
-	resultChan := make(chan chunkResult, len(chunks))
-
-	var wg sync.WaitGroup
-	for i := 0; i < concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case chunk, ok := <-workChan:
-					if !ok {
-						return
-					}
-					if ctx.Err() != nil {
-						return
-					}
-
-					segments, err := t.TranscribeChunk(ctx, chunk)
-					if err != nil {
-						cancel()
-					}
-					resultChan <- chunkResult{
-						Index:    chunk.Index,
-						Segments: segments,
-						Error:    err,
-					}
-				}
-			}
-		}()
-	}
-
-	go func() {
-		defer close(workChan)
-		for _, chunk := range chunks {
-			select {
-			case <-ctx.Done():
-				return
-			case workChan <- chunk:
-			}
-		}
-	}()
-
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	results := make([]chunkResult, 0, len(chunks))
-	var firstErr error
-	for result := range resultChan {
-		if result.Error != nil && firstErr == nil {
-			firstErr = fmt.Errorf(
-				"chunk %d failed: %w",
-				result.Index,
-				result.Error,
-			)
-			cancel()
-		}
-		if result.Error == nil {
-			results = append(results, result)
-		}
-	}
-	if firstErr != nil {
-		return nil, firstErr
-	}
-
-	// sort by index to maintain order
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Index < results[j].Index
-	})
-
-	// merge
-	var allSegments []subtitle.Segment
-	for _, r := range results {
-		allSegments = append(allSegments, r.Segments...)
-	}
-
-	// Calculate total duration from last chunk
-	var totalDuration time.Duration
-	if len(chunks) > 0 {
-		totalDuration = chunks[len(chunks)-1].EndTime
-	}
+	return transcribeChunks(ctx, chunks, concurrency, t.options, t)
+}
 
-	return &Result{
-		Segments: allSegments,
-		Language: t.options.Language,
-		Duration: totalDuration,
-	}, nil
+// transcribes chunks as they arrive on the channel, until it is closed
+func (t *OpenAITranscriber) TranscribeStream(
+	ctx context.Context,
+	chunks <-chan audio.ChunkInfo,
+	concurrency int,
+) (*Result, error) {
+	return transcribeStream(ctx, chunks, concurrency, t.options, t)
 }
 
 func (t *OpenAITranscriber) Close() error {
 	return nil
 }
+
+// whisper has no instructions channel; terms in the prompt bias its
+// spelling, and a ♪ biases it to mark songs for the lyrics policy
+func (t *OpenAITranscriber) whisperPrompt() string {
+	prompt := t.options.Prompt
+	if len(t.options.Glossary) > 0 {
+		terms := strings.Join(t.options.Glossary.Terms(), ", ")
+		prompt = strings.TrimSpace(prompt + " " + terms)
+	}
+	if t.options.Lyrics == LyricsSkip || t.options.Lyrics == LyricsTag {
+		prompt = strings.TrimSpace("♪ " + prompt)
+	}
+	return prompt
+}
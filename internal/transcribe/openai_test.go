@@ -1,6 +1,10 @@
 package transcribe
 
 import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 )
@@ -307,3 +311,53 @@ func TestFallbackSingleSegment(t *testing.T) {
 		t.Errorf("fallback segment text incorrect: %q", segments[0].Text)
 	}
 }
+
+// answers every request with a verbose_json transcript, recording it
+type recordingTransport struct {
+	requests []*http.Request
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.requests = append(rt.requests, req)
+	body := `{"text":"Hola","language":"es","duration":2,"segments":[{"start":0,"end":2,"text":" Hola"}]}`
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestGroqTranscriber(t *testing.T) {
+	transport := &recordingTransport{}
+	transcriber, err := NewGroqTranscriber(context.Background(), "groq-key", Options{
+		HTTPClient: &http.Client{Transport: transport},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transcriber.model != defaultGroqModel || transcriber.provider != ProviderGroq {
+		t.Errorf("transcriber = %s %s", transcriber.provider, transcriber.model)
+	}
+
+	result, err := transcriber.TranscribeReader(context.Background(), Media{
+		Reader: strings.NewReader("audio"),
+		Name:   "chunk.mp3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Segments) != 1 || result.Segments[0].Text != "Hola" {
+		t.Errorf("segments = %+v", result.Segments)
+	}
+	if len(transport.requests) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(transport.requests))
+	}
+	req := transport.requests[0]
+	if got := req.URL.String(); got != "https://api.groq.com/openai/v1/audio/transcriptions" {
+		t.Errorf("request URL = %s", got)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer groq-key" {
+		t.Errorf("Authorization = %q", got)
+	}
+}
@@ -0,0 +1,88 @@
+package transcribe
+
+import (
+	"context"
+
+	"github.com/mgpai22/lipi/internal/audio"
+)
+
+// Preparer is implemented by transcribers that can start on a chunk's audio
+// before its request, such as uploading it, so that work overlaps the
+// requests for earlier chunks. Prepare must not block; the request for
+// audioPath picks up what it started, and Shutdown releases what no request
+// picked up.
+type Preparer interface {
+	Prepare(ctx context.Context, audioPath string)
+}
+
+// the Prepare of t, or of the provider behind its middleware and fallbacks;
+// nil when there is none
+func preparer(t Transcriber) func(context.Context, string) {
+	switch t := t.(type) {
+	case Preparer:
+		return t.Prepare
+	case *decorated:
+		next := preparer(t.next)
+		if next == nil || t.skipPrepare == nil {
+			return next
+		}
+		return func(ctx context.Context, audioPath string) {
+			if !t.skipPrepare(audioPath) {
+				next(ctx, audioPath)
+			}
+		}
+	case *fallbackTranscriber:
+		// requests start on the primary model
+		return preparer(t.transcribers[0])
+	}
+	return nil
+}
+
+// relays chunks to the workers, preparing each as it is queued, so up to
+// ahead chunks are being prepared while earlier ones are transcribed
+func prepareAhead(
+	ctx context.Context,
+	prepare func(context.Context, string),
+	chunks <-chan audio.ChunkInfo,
+	ahead int,
+) <-chan audio.ChunkInfo {
+	out := make(chan audio.ChunkInfo, ahead)
+	go func() {
+		defer close(out)
+		for {
+			var chunk audio.ChunkInfo
+			select {
+			case <-ctx.Done():
+				return
+			case c, ok := <-chunks:
+				if !ok {
+					return
+				}
+				chunk = c
+			}
+			prepare(ctx, chunk.Path)
+			select {
+			case <-ctx.Done():
+				return
+			case out <- chunk:
+			}
+		}
+	}()
+	return out
+}
+
+// feeds chunks to a channel, for preparing a list of chunks ahead
+func chunkChannel(ctx context.Context, chunks []audio.ChunkInfo) <-chan audio.ChunkInfo {
+	out := make(chan audio.ChunkInfo)
+	go func() {
+		defer close(out)
+		for _, chunk := range chunks {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- chunk:
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,133 @@
+package transcribe
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/middleware"
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+// transcriber whose requests wait until the next chunk was prepared, so a
+// run only finishes when preparing overlaps the requests
+type preparingTranscriber struct {
+	mu       sync.Mutex
+	prepared map[string]chan struct{}
+	next     map[string]string
+}
+
+func (p *preparingTranscriber) signal(audioPath string) chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.prepared[audioPath] == nil {
+		p.prepared[audioPath] = make(chan struct{})
+	}
+	return p.prepared[audioPath]
+}
+
+func (p *preparingTranscriber) Prepare(ctx context.Context, audioPath string) {
+	close(p.signal(audioPath))
+}
+
+func (p *preparingTranscriber) Transcribe(ctx context.Context, audioPath string) (*Result, error) {
+	select {
+	case <-p.signal(audioPath):
+	default:
+		return nil, fmt.Errorf("%s was not prepared", audioPath)
+	}
+	if next := p.next[audioPath]; next != "" {
+		select {
+		case <-p.signal(next):
+		case <-time.After(5 * time.Second):
+			return nil, fmt.Errorf("%s was not prepared during the request for %s", next, audioPath)
+		}
+	}
+	return &Result{Segments: []subtitle.Segment{{EndTime: time.Second, Text: filepath.Base(audioPath)}}}, nil
+}
+
+func newPreparingChunks(n int) (*preparingTranscriber, []audio.ChunkInfo) {
+	p := &preparingTranscriber{prepared: map[string]chan struct{}{}, next: map[string]string{}}
+	chunks := make([]audio.ChunkInfo, n)
+	for i := range chunks {
+		chunks[i] = audio.ChunkInfo{
+			Index:     i,
+			Path:      fmt.Sprintf("chunk_%03d.mp3", i),
+			StartTime: time.Duration(i) * time.Minute,
+			EndTime:   time.Duration(i+1) * time.Minute,
+		}
+		if i > 0 {
+			p.next[chunks[i-1].Path] = chunks[i].Path
+		}
+	}
+	return p, chunks
+}
+
+func TestPrepareOverlapsRequests(t *testing.T) {
+	p, chunks := newPreparingChunks(4)
+	opts := Options{Retry: middleware.RetryPolicy{Attempts: 1, Backoff: time.Millisecond}}
+	transcriber := Concurrent(Chain(p, opts.middleware(ProviderGemini)...), opts)
+
+	// one worker: the request for each chunk waits on preparing the next
+	result, err := transcriber.TranscribeWithChunks(context.Background(), chunks, 1)
+	if err != nil {
+		t.Fatalf("TranscribeWithChunks() error = %v", err)
+	}
+	if len(result.Segments) != 4 {
+		t.Errorf("Segments = %+v, want 4", result.Segments)
+	}
+
+	p, chunks = newPreparingChunks(3)
+	stream := make(chan audio.ChunkInfo, len(chunks))
+	for _, chunk := range chunks {
+		stream <- chunk
+	}
+	close(stream)
+	if _, err := transcribeStream(context.Background(), stream, 1, Options{}, p); err != nil {
+		t.Fatalf("transcribeStream() error = %v", err)
+	}
+}
+
+func TestPrepareSkipsCachedAudio(t *testing.T) {
+	cache := middleware.NewCache(t.TempDir())
+	dir := t.TempDir()
+	chunk := filepath.Join(dir, "chunk_000.mp3")
+	if err := os.WriteFile(chunk, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var prepared []string
+	wrapped := Chain(&recordingPreparer{prepared: &prepared}, WithMetrics(&middleware.Metrics{}))
+	preparer(wrapped)(context.Background(), chunk)
+	if len(prepared) != 1 {
+		t.Fatalf("prepared = %v, want the chunk", prepared)
+	}
+
+	prepared = nil
+	wrapped = WithCache(cache, "scope")(&recordingPreparer{prepared: &prepared})
+	key, _ := middleware.FileKey(chunk)
+	if err := cache.Put(middleware.Key("scope", key), &Result{}); err != nil {
+		t.Fatal(err)
+	}
+	preparer(wrapped)(context.Background(), chunk)
+	if len(prepared) != 0 {
+		t.Errorf("prepared = %v, want nothing for cached audio", prepared)
+	}
+}
+
+type recordingPreparer struct {
+	prepared *[]string
+}
+
+func (r *recordingPreparer) Prepare(ctx context.Context, audioPath string) {
+	*r.prepared = append(*r.prepared, audioPath)
+}
+
+func (r *recordingPreparer) Transcribe(ctx context.Context, audioPath string) (*Result, error) {
+	return &Result{}, nil
+}
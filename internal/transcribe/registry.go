@@ -0,0 +1,85 @@
+package transcribe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// FactoryFunc creates the transcriber of a registered provider
+type FactoryFunc func(ctx context.Context, apiKey string, opts Options) (Transcriber, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[Provider]FactoryFunc{}
+)
+
+func init() {
+	Register(ProviderGemini, func(ctx context.Context, apiKey string, opts Options) (Transcriber, error) {
+		return NewGeminiTranscriber(ctx, apiKey, opts)
+	})
+	Register(ProviderOpenAI, func(ctx context.Context, apiKey string, opts Options) (Transcriber, error) {
+		return NewOpenAITranscriber(ctx, apiKey, opts)
+	})
+	Register(ProviderGroq, func(ctx context.Context, apiKey string, opts Options) (Transcriber, error) {
+		return NewGroqTranscriber(ctx, apiKey, opts)
+	})
+	Register(ProviderWhisper, func(context.Context, string, Options) (Transcriber, error) {
+		return nil, fmt.Errorf("whisper provider not yet implemented")
+	})
+}
+
+// Register makes a provider available to Factory under name, so programs
+// embedding lipi can add backends of their own. Factory still applies the
+// requested middleware to what factory returns. Register panics if name is
+// empty, already registered, or factory is nil, like database/sql drivers.
+func Register(name Provider, factory FactoryFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if name == "" {
+		panic("transcribe: Register with empty provider name")
+	}
+	if factory == nil {
+		panic("transcribe: Register factory is nil for " + string(name))
+	}
+	if _, dup := registry[name]; dup {
+		panic("transcribe: Register called twice for " + string(name))
+	}
+	registry[name] = factory
+}
+
+// Registered reports whether a provider is available under name
+func Registered(name Provider) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, ok := registry[name]
+	return ok
+}
+
+// Providers lists the registered providers in sorted order
+func Providers() []Provider {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]Provider, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+func newProvider(
+	ctx context.Context,
+	provider Provider,
+	apiKey string,
+	opts Options,
+) (Transcriber, error) {
+	registryMu.RLock()
+	factory, ok := registry[provider]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+	return factory(ctx, apiKey, opts)
+}
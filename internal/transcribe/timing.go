@@ -0,0 +1,143 @@
+package transcribe
+
+import (
+	"context"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/audio"
+)
+
+// ChunkTiming is where the time transcribing one chunk went
+type ChunkTiming struct {
+	Index int
+	Start time.Duration // of the chunk in the audio
+	// sending the audio: a file upload, or writing the request with the
+	// audio inline or as a form. An upload started ahead of the chunk's
+	// turn counts too, so the phases may add up to more than Total.
+	Upload time.Duration
+	// from the request being sent to the first byte of the answer
+	Inference time.Duration
+	// reading the rest of the answer and parsing it into segments
+	Parse time.Duration
+	// from the chunk's turn to its result; what the phases leave out is
+	// spent waiting: on rate limits, failed requests, and between retries
+	Total time.Duration
+}
+
+// Waiting is the part of Total spent in no phase
+func (c ChunkTiming) Waiting() time.Duration {
+	return max(c.Total-c.Upload-c.Inference-c.Parse, 0)
+}
+
+// Timings records the phases of each chunk's requests, adding up retries
+// and fallback models. It is safe for concurrent use; a nil Timings
+// records nothing.
+type Timings struct {
+	mu     sync.Mutex
+	chunks map[string]*ChunkTiming // by audio path
+}
+
+// the timing of the chunk at audioPath, created on first use; t.mu must
+// be held
+func (t *Timings) chunk(audioPath string) *ChunkTiming {
+	if t.chunks == nil {
+		t.chunks = make(map[string]*ChunkTiming)
+	}
+	c, ok := t.chunks[audioPath]
+	if !ok {
+		c = &ChunkTiming{Index: -1}
+		t.chunks[audioPath] = c
+	}
+	return c
+}
+
+// adds the time an upload of audioPath took since start
+func (t *Timings) upload(audioPath string, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.chunk(audioPath).Upload += time.Since(start)
+}
+
+// traces the HTTP requests made with the returned context into the phases
+// of audioPath: until a request is written is upload, and until the first
+// byte of its answer is inference. done, called once the answer is parsed,
+// adds the time since that first byte as parse.
+func (t *Timings) trace(ctx context.Context, audioPath string) (context.Context, func()) {
+	if t == nil {
+		return ctx, func() {}
+	}
+	var (
+		mu                   sync.Mutex
+		attempt, wrote, last time.Time
+		upload, inference    time.Duration
+	)
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			mu.Lock()
+			defer mu.Unlock()
+			attempt = time.Now()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			wrote = time.Now()
+			upload += wrote.Sub(attempt)
+		},
+		GotFirstResponseByte: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			last = time.Now()
+			inference += last.Sub(wrote)
+		},
+	}
+	done := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		c := t.chunk(audioPath)
+		c.Upload += upload
+		c.Inference += inference
+		if !last.IsZero() {
+			c.Parse += time.Since(last)
+		}
+	}
+	return httptrace.WithClientTrace(ctx, trace), done
+}
+
+// records the chunk's place and the time it took in all
+func (t *Timings) finish(chunk audio.ChunkInfo, total time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.chunk(chunk.Path)
+	c.Index = chunk.Index
+	c.Start = chunk.StartTime
+	c.Total += total
+}
+
+// Chunks returns the timing of each chunk transcribed, in chunk order
+func (t *Timings) Chunks() []ChunkTiming {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var chunks []ChunkTiming
+	for _, c := range t.chunks {
+		// phases of a request whose chunk never finished
+		if c.Index >= 0 {
+			chunks = append(chunks, *c)
+		}
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].Index < chunks[j].Index })
+	return chunks
+}
@@ -0,0 +1,68 @@
+package transcribe
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/audio"
+)
+
+func TestTimingsTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		time.Sleep(20 * time.Millisecond)
+		w.(http.Flusher).Flush()
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"text": "hi"}`))
+	}))
+	defer server.Close()
+
+	timings := &Timings{}
+	ctx, done := timings.trace(context.Background(), "chunk_001.mp3")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader("audio"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	done()
+
+	// a request whose chunk never finished is left out
+	if got := timings.Chunks(); len(got) != 0 {
+		t.Fatalf("Chunks() = %+v before the chunk finished", got)
+	}
+	timings.finish(audio.ChunkInfo{Path: "chunk_001.mp3", Index: 1, StartTime: time.Minute}, time.Second)
+
+	chunks := timings.Chunks()
+	if len(chunks) != 1 {
+		t.Fatalf("Chunks() = %+v, want one chunk", chunks)
+	}
+	c := chunks[0]
+	if c.Index != 1 || c.Start != time.Minute || c.Total != time.Second {
+		t.Errorf("chunk = %+v", c)
+	}
+	if c.Upload <= 0 || c.Inference < 20*time.Millisecond || c.Parse < 10*time.Millisecond {
+		t.Errorf("phases = upload %s, inference %s, parse %s", c.Upload, c.Inference, c.Parse)
+	}
+	if c.Waiting() != c.Total-c.Upload-c.Inference-c.Parse {
+		t.Errorf("Waiting() = %s", c.Waiting())
+	}
+
+	// recording into nil Timings is a no-op
+	var none *Timings
+	_, done = none.trace(context.Background(), "x")
+	done()
+	none.finish(audio.ChunkInfo{}, time.Second)
+	if none.Chunks() != nil {
+		t.Error("nil Timings has chunks")
+	}
+}
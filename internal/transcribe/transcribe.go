@@ -2,11 +2,19 @@ package transcribe
 
 import (
 	"context"
-	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/glossary"
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/middleware"
 	"github.com/mgpai22/lipi/internal/subtitle"
+	"github.com/mgpai22/lipi/internal/usage"
 )
 
 // transcription result
@@ -30,6 +38,46 @@ type ConcurrentTranscriber interface {
 	) (*Result, error)
 }
 
+// optional interface for transcribers that can start on chunks while later
+// chunks are still being cut
+type StreamingTranscriber interface {
+	ConcurrentTranscriber
+	TranscribeStream(
+		ctx context.Context,
+		chunks <-chan audio.ChunkInfo,
+		concurrency int,
+	) (*Result, error)
+}
+
+// holds the result of transcribing a chunk
+type chunkResult struct {
+	Index    int
+	EndTime  time.Duration
+	Segments []subtitle.Segment
+}
+
+// orders chunk results and merges their segments into a single result
+func mergeChunkResults(results []chunkResult, language string) *Result {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Index < results[j].Index
+	})
+
+	var allSegments []subtitle.Segment
+	var totalDuration time.Duration
+	for _, r := range results {
+		allSegments = append(allSegments, r.Segments...)
+		if r.EndTime > totalDuration {
+			totalDuration = r.EndTime
+		}
+	}
+
+	return &Result{
+		Segments: allSegments,
+		Language: language,
+		Duration: totalDuration,
+	}
+}
+
 // transcription service provider
 type Provider string
 
@@ -37,6 +85,7 @@ const (
 	ProviderWhisper Provider = "whisper"
 	ProviderOpenAI  Provider = "openai"
 	ProviderGemini  Provider = "gemini"
+	ProviderGroq    Provider = "groq"
 )
 
 // transcription options
@@ -45,23 +94,137 @@ type Options struct {
 	TranscriptLanguage string // Output language for transcript (default: "native")
 	Model              string
 	Prompt             string
+	Temperature        *float64          // Sampling temperature; nil keeps the provider default
+	TopP               *float64          // Nucleus sampling probability (gemini); nil keeps the provider default
+	ThinkingBudget     *int              // Tokens the model may spend thinking (gemini); nil keeps the model default
+	SafetyThresholds   map[string]string // Block threshold by harm category (gemini), e.g. HARM_CATEGORY_HATE_SPEECH: BLOCK_NONE
+	Glossary           glossary.Glossary // Names and terms to spell exactly
+	Diarize            bool              // Label who is speaking in each segment
+	Speakers           []string          // Names of the people speaking, to label them by when diarizing
+	Multilingual       bool              // Tag the language of each segment, for audio that switches languages
+	Lyrics             Lyrics            // How sung content is transcribed; empty leaves it to the model
+	ResponseDir        string            // When set, raw provider responses are saved here
+	Dump               *middleware.Dump  // When set, saves each request's prompt, raw response, and outcome
+	RemoveChunks       bool              // Delete each chunk file once it is transcribed
+	Usage              *usage.Meter      // When set, records tokens and audio sent to the provider
+	OnChunk            func()            // When set, called after each chunk is transcribed
+	Limiter            Limiter           // When set, bounds requests in flight across transcribers
+	HTTPClient         *http.Client      // When set, used for provider requests instead of the default client
+	Fallbacks          []FallbackModel   // Models a chunk moves to, in order, when it keeps failing on Model
+
+	// request middleware, composed by Factory when set
+	Retry     middleware.RetryPolicy  // Retries failed requests; zero Attempts disables it
+	RateLimit *middleware.RateLimiter // Spaces requests to stay under a per-minute quota
+	Cache     *middleware.Cache       // Reuses results for audio already transcribed
+	Logger    *logging.Logger         // Logs each request at debug level
+	Metrics   *middleware.Metrics     // Counts requests, failures, retries, and latency
+	Timings   *Timings                // Records how long each chunk spent uploading, in inference, and parsing
+}
+
+// Limiter bounds the chunk requests in flight across several transcribers,
+// such as the files of a batch sharing one rate-limit budget. A nil Limiter
+// does not limit.
+type Limiter chan struct{}
+
+// NewLimiter allows n requests in flight at once
+func NewLimiter(n int) Limiter {
+	return make(Limiter, n)
 }
 
-// creates transcriber based on provider
+// waits for a free slot; the caller must release it when done
+func (l Limiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l Limiter) release() {
+	if l != nil {
+		<-l
+	}
+}
+
+// transcribes a chunk once the limiter has a free slot
+func transcribeLimited(
+	ctx context.Context,
+	limiter Limiter,
+	chunk audio.ChunkInfo,
+	transcribe func(context.Context, audio.ChunkInfo) ([]subtitle.Segment, error),
+) ([]subtitle.Segment, error) {
+	if err := limiter.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer limiter.release()
+	return transcribe(ctx, chunk)
+}
+
+// creates the transcriber registered for provider, wrapped in the middleware
+// that opts asks for. With Fallbacks, every model gets its own middleware,
+// so a chunk moves on only once its retries are spent.
 func Factory(
 	ctx context.Context,
 	provider Provider,
 	apiKey string,
 	opts Options,
 ) (Transcriber, error) {
-	switch provider {
-	case ProviderGemini:
-		return NewGeminiTranscriber(ctx, apiKey, opts)
-	case ProviderWhisper:
-		return nil, fmt.Errorf("whisper provider not yet implemented")
-	case ProviderOpenAI:
-		return NewOpenAITranscriber(ctx, apiKey, opts)
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	if len(opts.Fallbacks) > 0 {
+		models := append([]FallbackModel{{Provider: provider, Model: opts.Model, APIKey: apiKey}}, opts.Fallbacks...)
+		t, err := newFallback(ctx, models, opts)
+		if err != nil {
+			return nil, err
+		}
+		return Concurrent(t, opts), nil
+	}
+
+	t, err := newProvider(ctx, provider, apiKey, opts)
+	if err != nil {
+		return nil, err
+	}
+	if mws := opts.middleware(provider); len(mws) > 0 {
+		return Concurrent(Chain(t, mws...), opts), nil
+	}
+	return t, nil
+}
+
+// saves a raw provider response next to other intermediate files, named
+// after the audio it belongs to; failures are ignored since this is debug data
+func saveRawResponse(dir, audioPath string, body []byte) {
+	if dir == "" || len(body) == 0 {
+		return
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	name := strings.TrimSuffix(
+		filepath.Base(audioPath),
+		filepath.Ext(audioPath),
+	) + ".response.json"
+	_ = os.WriteFile(filepath.Join(dir, name), body, 0644)
+}
+
+// saves a request for the audio at name to opts.Dump, if set
+func dumpRequest(
+	opts Options,
+	provider Provider,
+	model, name, prompt string,
+	start time.Time,
+	response any,
+	err error,
+) {
+	if opts.Dump == nil {
+		return
 	}
+	opts.Dump.Record(middleware.Exchange{
+		Operation: "transcribe",
+		Provider:  string(provider),
+		Model:     model,
+		Input:     filepath.Base(name),
+		Prompt:    prompt,
+	}, start, response, err)
 }
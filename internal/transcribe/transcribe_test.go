@@ -0,0 +1,152 @@
+package transcribe
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/audio"
+	"github.com/mgpai22/lipi/internal/subtitle"
+)
+
+func TestMergeChunkResultsOrdersOutOfOrderChunks(t *testing.T) {
+	results := []chunkResult{
+		{
+			Index:   2,
+			EndTime: 3 * time.Minute,
+			Segments: []subtitle.Segment{
+				{StartTime: 2 * time.Minute, Text: "third"},
+			},
+		},
+		{
+			Index:   0,
+			EndTime: time.Minute,
+			Segments: []subtitle.Segment{
+				{StartTime: 0, Text: "first"},
+			},
+		},
+		{
+			Index:   1,
+			EndTime: 2 * time.Minute,
+			Segments: []subtitle.Segment{
+				{StartTime: time.Minute, Text: "second"},
+			},
+		},
+	}
+
+	merged := mergeChunkResults(results, "en")
+
+	want := []string{"first", "second", "third"}
+	if len(merged.Segments) != len(want) {
+		t.Fatalf("expected %d segments, got %d", len(want), len(merged.Segments))
+	}
+	for i, text := range want {
+		if merged.Segments[i].Text != text {
+			t.Errorf("segment %d: expected %q, got %q", i, text, merged.Segments[i].Text)
+		}
+	}
+	if merged.Duration != 3*time.Minute {
+		t.Errorf("expected duration 3m, got %v", merged.Duration)
+	}
+	if merged.Language != "en" {
+		t.Errorf("expected language en, got %q", merged.Language)
+	}
+}
+
+func TestMergeChunkResultsEmpty(t *testing.T) {
+	merged := mergeChunkResults(nil, "")
+	if len(merged.Segments) != 0 || merged.Duration != 0 {
+		t.Errorf("expected empty result, got %+v", merged)
+	}
+}
+
+func TestLimiterBoundsRequestsInFlight(t *testing.T) {
+	limiter := NewLimiter(2)
+	var inFlight, peak atomic.Int32
+	transcribe := func(ctx context.Context, chunk audio.ChunkInfo) ([]subtitle.Segment, error) {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		inFlight.Add(-1)
+		return nil, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			_, _ = transcribeLimited(context.Background(), limiter, audio.ChunkInfo{Index: i}, transcribe)
+		})
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > 2 {
+		t.Errorf("peak requests in flight = %d, want at most 2", got)
+	}
+}
+
+func TestLimiterStopsWaitingOnCancel(t *testing.T) {
+	limiter := NewLimiter(1)
+	if err := limiter.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.acquire(ctx); err == nil {
+		t.Error("acquire() on a full limiter succeeded after cancel")
+	}
+
+	var unlimited Limiter
+	if err := unlimited.acquire(ctx); err != nil {
+		t.Errorf("nil limiter acquire() error = %v", err)
+	}
+}
+
+// transcriber that reports the contents of the file it was given
+type contentTranscriber struct{}
+
+func (contentTranscriber) Transcribe(ctx context.Context, audioPath string) (*Result, error) {
+	data, err := os.ReadFile(audioPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Segments: []subtitle.Segment{{Text: string(data)}}}, nil
+}
+
+func TestTranscribeReaderStagesForPathTranscribers(t *testing.T) {
+	media := Media{Reader: strings.NewReader("streamed audio"), Name: "upload.mp3"}
+	result, err := TranscribeReader(context.Background(), contentTranscriber{}, media)
+	if err != nil {
+		t.Fatalf("TranscribeReader() error = %v", err)
+	}
+	if len(result.Segments) != 1 || result.Segments[0].Text != "streamed audio" {
+		t.Errorf("Segments = %+v, want the streamed bytes", result.Segments)
+	}
+}
+
+func TestMediaContentType(t *testing.T) {
+	tests := []struct {
+		media Media
+		want  string
+	}{
+		{Media{Name: "a.mp3"}, "audio/mpeg"},
+		{Media{Name: "B.OGG"}, "audio/ogg"},
+		{Media{Name: "a.mp3", ContentType: "audio/x-custom"}, "audio/x-custom"},
+		{Media{Name: "blob"}, "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.media.contentType(); got != tt.want {
+			t.Errorf("contentType(%q) = %q, want %q", tt.media.Name, got, tt.want)
+		}
+	}
+}
@@ -3,11 +3,13 @@ package translate
 import (
 	"context"
 	"fmt"
-	"sort"
-	"sync"
+	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/httpclient"
 )
 
 // implements Translator using Anthropic Claude
@@ -26,7 +28,10 @@ func NewAnthropicTranslator(
 		return nil, fmt.Errorf("API key is required")
 	}
 
-	client := anthropic.NewClient(option.WithAPIKey(apiKey))
+	client := anthropic.NewClient(
+		option.WithAPIKey(apiKey),
+		option.WithHTTPClient(httpclient.Or(opts.HTTPClient)),
+	)
 
 	model := anthropic.Model(opts.Model)
 	if opts.Model == "" {
@@ -40,203 +45,92 @@ func NewAnthropicTranslator(
 	}, nil
 }
 
-func (t *AnthropicTranslator) batchSize() int {
-	if t.options.BatchSize > 0 {
-		return t.options.BatchSize
-	}
-	return DefaultBatchSize
-}
-
 func (t *AnthropicTranslator) Translate(
 	ctx context.Context,
 	items []TranslationItem,
 ) ([]TranslationResult, error) {
-	if len(items) == 0 {
-		return []TranslationResult{}, nil
-	}
-
-	batchSize := t.batchSize()
-	if len(items) <= batchSize {
-		return t.translateBatch(ctx, items)
-	}
-
-	var allResults []TranslationResult
-	for i := 0; i < len(items); i += batchSize {
-		end := i + batchSize
-		if end > len(items) {
-			end = len(items)
-		}
-
-		batch := items[i:end]
-		results, err := t.translateBatch(ctx, batch)
-		if err != nil {
-			return nil, fmt.Errorf("batch %d failed: %w", i/batchSize, err)
-		}
-		allResults = append(allResults, results...)
-	}
-
-	sort.Slice(allResults, func(i, j int) bool {
-		return allResults[i].Index < allResults[j].Index
-	})
-
-	return allResults, nil
+	return translateBatches(ctx, items, 1, t.options, t.translateBatch)
 }
 
-// Items are split into batches of BatchSize (default 50). Each batch becomes
-// one API request. Workers (up to concurrency) pull batches from a shared queue.
 func (t *AnthropicTranslator) TranslateWithConcurrency(
 	ctx context.Context,
 	items []TranslationItem,
 	concurrency int,
 ) ([]TranslationResult, error) {
-	if len(items) == 0 {
-		return []TranslationResult{}, nil
-	}
-
-	if concurrency <= 0 {
-		concurrency = 3
-	}
-
-	batchSize := t.batchSize()
-	var batches [][]TranslationItem
-	for i := 0; i < len(items); i += batchSize {
-		end := i + batchSize
-		if end > len(items) {
-			end = len(items)
-		}
-		batches = append(batches, items[i:end])
-	}
-
-	if len(batches) == 1 {
-		return t.translateBatch(ctx, batches[0])
-	}
-
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	type batchResult struct {
-		Index   int
-		Results []TranslationResult
-		Error   error
-	}
-
-	workChan := make(chan int)
-	resultChan := make(chan batchResult, len(batches))
-
-	var wg sync.WaitGroup
-	for i := 0; i < concurrency && i < len(batches); i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case batchIdx, ok := <-workChan:
-					if !ok {
-						return
-					}
-					if ctx.Err() != nil {
-						return
-					}
-
-					results, err := t.translateBatch(ctx, batches[batchIdx])
-					if err != nil {
-						cancel()
-					}
-					resultChan <- batchResult{
-						Index:   batchIdx,
-						Results: results,
-						Error:   err,
-					}
-				}
-			}
-		}()
-	}
-
-	go func() {
-		defer close(workChan)
-		for i := range batches {
-			select {
-			case <-ctx.Done():
-				return
-			case workChan <- i:
-			}
-		}
-	}()
-
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	results := make([]batchResult, 0, len(batches))
-	var firstErr error
-	for result := range resultChan {
-		if result.Error != nil && firstErr == nil {
-			firstErr = fmt.Errorf(
-				"batch %d failed: %w",
-				result.Index,
-				result.Error,
-			)
-			cancel()
-		}
-		if result.Error == nil {
-			results = append(results, result)
-		}
-	}
-
-	if firstErr != nil {
-		return nil, firstErr
-	}
-
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Index < results[j].Index
-	})
-
-	var allResults []TranslationResult
-	for _, r := range results {
-		allResults = append(allResults, r.Results...)
-	}
-
-	sort.Slice(allResults, func(i, j int) bool {
-		return allResults[i].Index < allResults[j].Index
-	})
-
-	return allResults, nil
+	return translateBatches(ctx, items, concurrency, t.options, t.translateBatch)
 }
 
 func (t *AnthropicTranslator) translateBatch(
 	ctx context.Context,
 	items []TranslationItem,
 ) ([]TranslationResult, error) {
-	prompt := BuildPrompt(t.options, items)
+	return sendWithReprompt(ctx, t.options, items, func(
+		ctx context.Context,
+		prompt string,
+		_ bool,
+	) ([]TranslationResult, error) {
+		return t.send(ctx, items, prompt)
+	})
+}
 
-	message, err := t.client.Messages.New(
-		ctx,
-		anthropic.MessageNewParams{
-			Model:     t.model,
-			MaxTokens: 4096,
-			Messages: []anthropic.MessageParam{
-				anthropic.NewUserMessage(
-					anthropic.NewTextBlock(prompt),
-				),
-			},
+// sends one translation request
+func (t *AnthropicTranslator) send(
+	ctx context.Context,
+	items []TranslationItem,
+	prompt string,
+) ([]TranslationResult, error) {
+	params := anthropic.MessageNewParams{
+		Model:     t.model,
+		MaxTokens: 4096,
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(
+				anthropic.NewTextBlock(prompt),
+			),
 		},
-	)
+	}
+	if t.options.Temperature != nil {
+		params.Temperature = anthropic.Float(*t.options.Temperature)
+	}
+	if t.options.TopP != nil {
+		params.TopP = anthropic.Float(*t.options.TopP)
+	}
+
+	start := time.Now()
+	message, err := t.client.Messages.New(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("translation failed: %w", err)
+		err = fmt.Errorf("translation failed: %w", errs.Classify(err))
+		dumpBatch(t.options, ProviderAnthropic, string(t.model), items, prompt, start, nil, err)
+		return nil, err
+	}
+	if message != nil {
+		t.options.Usage.AddTokens(
+			message.Usage.InputTokens,
+			message.Usage.OutputTokens,
+		)
 	}
 
-	return t.parseResponse(message, len(items))
+	results, err := t.parseResponse(message, len(items))
+	dumpBatch(t.options, ProviderAnthropic, string(t.model), items, prompt, start, message, err)
+	return results, err
 }
 
 func (t *AnthropicTranslator) parseResponse(
 	message *anthropic.Message,
 	expectedCount int,
 ) ([]TranslationResult, error) {
+	if message != nil && message.StopReason == anthropic.StopReasonRefusal {
+		return nil, errs.Mark(
+			errs.ErrContentFiltered,
+			fmt.Errorf("Anthropic refused to translate the batch"),
+		)
+	}
 	if message == nil || len(message.Content) == 0 {
-		return nil, fmt.Errorf("empty response from Anthropic")
+		return nil, errs.Mark(errs.ErrParse, fmt.Errorf("empty response from Anthropic"))
+	}
+
+	parseClass := errs.ErrParse
+	if message.StopReason == anthropic.StopReasonMaxTokens {
+		parseClass = errs.ErrResponseTruncated
 	}
 
 	var responseText string
@@ -247,31 +141,89 @@ func (t *AnthropicTranslator) parseResponse(
 	}
 
 	if responseText == "" {
-		return nil, fmt.Errorf("no text in Anthropic response")
+		return nil, errs.Mark(parseClass, fmt.Errorf("no text in Anthropic response"))
 	}
 
 	responseText = cleanJSONResponse(responseText)
 
 	results, err := extractTranslationResults(responseText)
 	if err != nil {
-		return nil, fmt.Errorf(
+		return nil, errs.Mark(parseClass, fmt.Errorf(
 			"failed to parse JSON response: %w (response: %s)",
 			err,
 			truncateString(responseText, 200),
-		)
+		))
 	}
 
 	if len(results) != expectedCount {
-		return nil, fmt.Errorf(
+		return nil, errs.Mark(errs.ErrParse, fmt.Errorf(
 			"expected %d results, got %d",
 			expectedCount,
 			len(results),
-		)
+		))
 	}
 
 	return results, nil
 }
 
+func (t *AnthropicTranslator) Model() string {
+	return string(t.model)
+}
+
+// Generate sends a free-form prompt using the translator's sampling
+// settings; Anthropic is not sent images
+func (t *AnthropicTranslator) Generate(
+	ctx context.Context,
+	prompt string,
+	images []Image,
+	maxTokens int,
+) (string, any, error) {
+	if err := refuseImages(ProviderAnthropic, images); err != nil {
+		return "", nil, err
+	}
+	params := anthropic.MessageNewParams{
+		Model:     t.model,
+		MaxTokens: int64(maxTokens),
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+		},
+	}
+	if t.options.Temperature != nil {
+		params.Temperature = anthropic.Float(*t.options.Temperature)
+	}
+	if t.options.TopP != nil {
+		params.TopP = anthropic.Float(*t.options.TopP)
+	}
+
+	message, err := t.client.Messages.New(ctx, params)
+	if err != nil {
+		return "", nil, fmt.Errorf("request failed: %w", errs.Classify(err))
+	}
+	if message == nil {
+		return "", nil, errs.Mark(errs.ErrParse, fmt.Errorf("empty response from Anthropic"))
+	}
+	t.options.Usage.AddTokens(message.Usage.InputTokens, message.Usage.OutputTokens)
+	switch message.StopReason {
+	case anthropic.StopReasonRefusal:
+		return "", message, errs.Mark(
+			errs.ErrContentFiltered,
+			fmt.Errorf("Anthropic refused the request"),
+		)
+	case anthropic.StopReasonMaxTokens:
+		return "", message, errs.Mark(
+			errs.ErrResponseTruncated,
+			fmt.Errorf("Anthropic response cut off at %d tokens", maxTokens),
+		)
+	}
+	var text strings.Builder
+	for _, block := range message.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	return text.String(), message, nil
+}
+
 func (t *AnthropicTranslator) Close() error {
 	return nil
 }
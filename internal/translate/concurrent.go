@@ -0,0 +1,128 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/mgpai22/lipi/internal/pool"
+)
+
+// translates one batch; the shape of Translator.Translate
+type translateFunc func(
+	ctx context.Context,
+	items []TranslationItem,
+) ([]TranslationResult, error)
+
+// batches the items of a translator and sends the batches in parallel
+type concurrentTranslator struct {
+	Translator
+	options Options
+}
+
+// Concurrent turns any translator into one that splits items into batches
+// of BatchSize and translates them in parallel, so middleware applied to t
+// sees every batch request. OnProgress applies as for the built-in
+// providers.
+func Concurrent(t Translator, opts Options) ConcurrentTranslator {
+	return &concurrentTranslator{Translator: t, options: opts}
+}
+
+func (c *concurrentTranslator) Translate(
+	ctx context.Context,
+	items []TranslationItem,
+) ([]TranslationResult, error) {
+	return translateBatches(ctx, items, 1, c.options, c.Translator.Translate)
+}
+
+func (c *concurrentTranslator) TranslateWithConcurrency(
+	ctx context.Context,
+	items []TranslationItem,
+	concurrency int,
+) ([]TranslationResult, error) {
+	return translateBatches(ctx, items, concurrency, c.options, c.Translator.Translate)
+}
+
+func (c *concurrentTranslator) Shutdown(ctx context.Context) error {
+	return Shutdown(ctx, c.Translator)
+}
+
+func (c *concurrentTranslator) Close() error {
+	return Shutdown(context.Background(), c.Translator)
+}
+
+func batchSize(opts Options) int {
+	if opts.BatchSize > 0 {
+		return opts.BatchSize
+	}
+	return DefaultBatchSize
+}
+
+// Items are split into batches of BatchSize (default 50). Each batch becomes
+// one API request. Workers (up to concurrency) pull batches from a shared queue.
+func translateBatches(
+	ctx context.Context,
+	items []TranslationItem,
+	concurrency int,
+	opts Options,
+	translate translateFunc,
+) ([]TranslationResult, error) {
+	if len(items) == 0 {
+		return []TranslationResult{}, nil
+	}
+
+	if concurrency <= 0 {
+		concurrency = 3
+	}
+
+	size := batchSize(opts)
+	var batches [][]TranslationItem
+	for i := 0; i < len(items); i += size {
+		end := min(i+size, len(items))
+		batches = append(batches, items[i:end])
+	}
+
+	translateOne := func(ctx context.Context, batch []TranslationItem) ([]TranslationResult, error) {
+		results, err := translate(ctx, batch)
+		if err == nil && opts.OnProgress != nil {
+			opts.OnProgress(len(results))
+		}
+		return results, err
+	}
+
+	if len(batches) == 1 {
+		return translateOne(ctx, batches[0])
+	}
+
+	// batches are numbered by their position for error messages
+	type batch struct {
+		index int
+		items []TranslationItem
+	}
+	work := make([]batch, len(batches))
+	for i, items := range batches {
+		work[i] = batch{index: i, items: items}
+	}
+
+	results, err := pool.Run(ctx, work, concurrency, func(ctx context.Context, b batch) ([]TranslationResult, error) {
+		results, err := translateOne(ctx, b.items)
+		if err != nil {
+			return nil, fmt.Errorf("batch %d failed: %w", b.index, err)
+		}
+		return results, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var allResults []TranslationResult
+	for _, r := range results {
+		allResults = append(allResults, r.Value...)
+	}
+
+	sort.Slice(allResults, func(i, j int) bool {
+		return allResults[i].Index < allResults[j].Index
+	})
+
+	return allResults, nil
+}
@@ -0,0 +1,43 @@
+package translate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/middleware"
+)
+
+// saves a batch request to opts.Dump, if set
+func dumpBatch(
+	opts Options,
+	provider Provider,
+	model string,
+	items []TranslationItem,
+	prompt string,
+	start time.Time,
+	response any,
+	err error,
+) {
+	if opts.Dump == nil {
+		return
+	}
+	opts.Dump.Record(middleware.Exchange{
+		Operation: "translate",
+		Provider:  string(provider),
+		Model:     model,
+		Input:     entryRange(items),
+		Prompt:    prompt,
+	}, start, response, err)
+}
+
+// the entries of a batch, numbered from 1 as in an SRT file
+func entryRange(items []TranslationItem) string {
+	if len(items) == 0 {
+		return "no entries"
+	}
+	first, last := items[0].Index+1, items[len(items)-1].Index+1
+	if first == last {
+		return fmt.Sprintf("entry %d", first)
+	}
+	return fmt.Sprintf("entries %d-%d", first, last)
+}
@@ -0,0 +1,81 @@
+package translate
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mgpai22/lipi/internal/middleware"
+)
+
+// FallbackModel is a model a batch moves to when it keeps failing on the
+// models before it
+type FallbackModel struct {
+	Provider Provider
+	Model    string
+	APIKey   string
+}
+
+// label of the model in logs, e.g. anthropic:claude-sonnet-4-5
+func (m FallbackModel) String() string {
+	if m.Model == "" {
+		return string(m.Provider)
+	}
+	return string(m.Provider) + ":" + m.Model
+}
+
+// sends each batch to its models in order, moving on when one fails after
+// its retries
+type fallbackTranslator struct {
+	models      []FallbackModel
+	translators []Translator
+	options     Options
+}
+
+// builds the middleware chain of each model of a fallback chain; the first
+// model is the primary one
+func newFallback(ctx context.Context, models []FallbackModel, opts Options) (Translator, error) {
+	f := &fallbackTranslator{models: models, options: opts}
+	for _, m := range models {
+		modelOpts := opts
+		modelOpts.Model = m.Model
+		modelOpts.Fallbacks = nil
+		modelOpts.OnProgress = nil // reported once per batch by the wrapper
+		t, err := newProvider(ctx, m.Provider, m.APIKey, modelOpts)
+		if err != nil {
+			_ = f.Shutdown(context.Background())
+			return nil, err
+		}
+		f.translators = append(f.translators, Chain(t, modelOpts.middleware(m.Provider)...))
+	}
+	return f, nil
+}
+
+func (f *fallbackTranslator) Translate(
+	ctx context.Context,
+	items []TranslationItem,
+) ([]TranslationResult, error) {
+	calls := make([]func(context.Context) ([]TranslationResult, error), len(f.translators))
+	for i, t := range f.translators {
+		calls[i] = func(ctx context.Context) ([]TranslationResult, error) {
+			return t.Translate(ctx, items)
+		}
+	}
+	return middleware.Fallback(ctx, calls, func(next int, err error) {
+		if f.options.Logger != nil {
+			f.options.Logger.Warnw("Translation failed, trying the next fallback model",
+				"batch", entryRange(items),
+				"failed", f.models[next-1].String(),
+				"next", f.models[next].String(),
+				"error", err,
+			)
+		}
+	})
+}
+
+func (f *fallbackTranslator) Shutdown(ctx context.Context) error {
+	var errList []error
+	for _, t := range f.translators {
+		errList = append(errList, Shutdown(ctx, t))
+	}
+	return errors.Join(errList...)
+}
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
-	"sort"
 	"strings"
-	"sync"
+	"time"
 
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/gemini"
+	"github.com/mgpai22/lipi/internal/httpclient"
 	"google.golang.org/genai"
 )
 
@@ -29,7 +31,8 @@ func NewGeminiTranslator(
 	}
 
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey: apiKey,
+		APIKey:     apiKey,
+		HTTPClient: httpclient.Or(opts.HTTPClient),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
@@ -49,177 +52,43 @@ func NewGeminiTranslator(
 
 const DefaultBatchSize = 50
 
-func (t *GeminiTranslator) batchSize() int {
-	if t.options.BatchSize > 0 {
-		return t.options.BatchSize
-	}
-	return DefaultBatchSize
-}
-
 func (t *GeminiTranslator) Translate(
 	ctx context.Context,
 	items []TranslationItem,
 ) ([]TranslationResult, error) {
-	if len(items) == 0 {
-		return []TranslationResult{}, nil
-	}
-
-	batchSize := t.batchSize()
-	if len(items) <= batchSize {
-		return t.translateBatch(ctx, items)
-	}
-
-	var allResults []TranslationResult
-	for i := 0; i < len(items); i += batchSize {
-		end := i + batchSize
-		if end > len(items) {
-			end = len(items)
-		}
-
-		batch := items[i:end]
-		results, err := t.translateBatch(ctx, batch)
-		if err != nil {
-			return nil, fmt.Errorf("batch %d failed: %w", i/batchSize, err)
-		}
-		allResults = append(allResults, results...)
-	}
-
-	sort.Slice(allResults, func(i, j int) bool {
-		return allResults[i].Index < allResults[j].Index
-	})
-
-	return allResults, nil
+	return translateBatches(ctx, items, 1, t.options, t.translateBatch)
 }
 
-// Items are split into batches of BatchSize (default 50). Each batch becomes
-// one API request. Workers (up to concurrency) pull batches from a shared queue.
 func (t *GeminiTranslator) TranslateWithConcurrency(
 	ctx context.Context,
 	items []TranslationItem,
 	concurrency int,
 ) ([]TranslationResult, error) {
-	if len(items) == 0 {
-		return []TranslationResult{}, nil
-	}
-
-	if concurrency <= 0 {
-		concurrency = 3
-	}
-
-	batchSize := t.batchSize()
-	var batches [][]TranslationItem
-	for i := 0; i < len(items); i += batchSize {
-		end := i + batchSize
-		if end > len(items) {
-			end = len(items)
-		}
-		batches = append(batches, items[i:end])
-	}
-
-	if len(batches) == 1 {
-		return t.translateBatch(ctx, batches[0])
-	}
-
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	type batchResult struct {
-		Index   int
-		Results []TranslationResult
-		Error   error
-	}
-
-	workChan := make(chan int)
-	resultChan := make(chan batchResult, len(batches))
-
-	var wg sync.WaitGroup
-	for i := 0; i < concurrency && i < len(batches); i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case batchIdx, ok := <-workChan:
-					if !ok {
-						return
-					}
-					if ctx.Err() != nil {
-						return
-					}
-
-					results, err := t.translateBatch(ctx, batches[batchIdx])
-					if err != nil {
-						cancel()
-					}
-					resultChan <- batchResult{
-						Index:   batchIdx,
-						Results: results,
-						Error:   err,
-					}
-				}
-			}
-		}()
-	}
-
-	go func() {
-		defer close(workChan)
-		for i := range batches {
-			select {
-			case <-ctx.Done():
-				return
-			case workChan <- i:
-			}
-		}
-	}()
-
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	results := make([]batchResult, 0, len(batches))
-	var firstErr error
-	for result := range resultChan {
-		if result.Error != nil && firstErr == nil {
-			firstErr = fmt.Errorf(
-				"batch %d failed: %w",
-				result.Index,
-				result.Error,
-			)
-			cancel()
-		}
-		if result.Error == nil {
-			results = append(results, result)
-		}
-	}
-
-	if firstErr != nil {
-		return nil, firstErr
-	}
-
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Index < results[j].Index
-	})
-
-	var allResults []TranslationResult
-	for _, r := range results {
-		allResults = append(allResults, r.Results...)
-	}
+	return translateBatches(ctx, items, concurrency, t.options, t.translateBatch)
+}
 
-	sort.Slice(allResults, func(i, j int) bool {
-		return allResults[i].Index < allResults[j].Index
+func (t *GeminiTranslator) translateBatch(
+	ctx context.Context,
+	items []TranslationItem,
+) ([]TranslationResult, error) {
+	return sendWithReprompt(ctx, t.options, items, func(
+		ctx context.Context,
+		prompt string,
+		relaxed bool,
+	) ([]TranslationResult, error) {
+		return t.send(ctx, items, prompt, relaxed)
 	})
-
-	return allResults, nil
 }
 
-func (t *GeminiTranslator) translateBatch(
+// sends one translation request; relaxed turns the adjustable safety
+// filters off
+func (t *GeminiTranslator) send(
 	ctx context.Context,
 	items []TranslationItem,
+	prompt string,
+	relaxed bool,
 ) ([]TranslationResult, error) {
-	prompt := BuildPrompt(t.options, items)
+	config := t.generateConfig(relaxed)
 
 	parts := []*genai.Part{
 		genai.NewPartFromText(prompt),
@@ -228,20 +97,46 @@ func (t *GeminiTranslator) translateBatch(
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
 
-	result, err := t.client.Models.GenerateContent(ctx, t.model, contents, nil)
+	start := time.Now()
+	result, err := t.client.Models.GenerateContent(ctx, t.model, contents, config)
 	if err != nil {
-		return nil, fmt.Errorf("translation failed: %w", err)
+		err = fmt.Errorf("translation failed: %w", errs.Classify(err))
+		dumpBatch(t.options, ProviderGemini, t.model, items, prompt, start, nil, err)
+		return nil, err
+	}
+	if result != nil && result.UsageMetadata != nil {
+		meta := result.UsageMetadata
+		t.options.Usage.AddTokens(
+			int64(meta.PromptTokenCount),
+			int64(meta.CandidatesTokenCount),
+		)
 	}
 
-	return t.parseResponse(result, len(items))
+	results, err := t.parseResponse(result, len(items))
+	dumpBatch(t.options, ProviderGemini, t.model, items, prompt, start, result, err)
+	return results, err
+}
+
+// request config for a batch, nil for provider defaults; relaxed turns
+// off the safety filters left at their defaults
+func (t *GeminiTranslator) generateConfig(relaxed bool) *genai.GenerateContentConfig {
+	return gemini.Settings{
+		Temperature:      t.options.Temperature,
+		TopP:             t.options.TopP,
+		ThinkingBudget:   t.options.ThinkingBudget,
+		SafetyThresholds: t.options.SafetyThresholds,
+	}.Config(relaxed)
 }
 
 func (t *GeminiTranslator) parseResponse(
 	result *genai.GenerateContentResponse,
 	expectedCount int,
 ) ([]TranslationResult, error) {
+	if err := gemini.Blocked(result); err != nil {
+		return nil, err
+	}
 	if result == nil || len(result.Candidates) == 0 {
-		return nil, fmt.Errorf("empty response from Gemini")
+		return nil, errs.Mark(errs.ErrParse, fmt.Errorf("empty response from Gemini"))
 	}
 
 	var responseText string
@@ -260,26 +155,26 @@ func (t *GeminiTranslator) parseResponse(
 	}
 
 	if responseText == "" {
-		return nil, fmt.Errorf("no text in Gemini response")
+		return nil, errs.Mark(gemini.ParseClass(result), fmt.Errorf("no text in Gemini response"))
 	}
 
 	responseText = cleanJSONResponse(responseText)
 
 	results, err := extractTranslationResults(responseText)
 	if err != nil {
-		return nil, fmt.Errorf(
+		return nil, errs.Mark(gemini.ParseClass(result), fmt.Errorf(
 			"failed to parse JSON response: %w (response: %s)",
 			err,
 			truncateString(responseText, 200),
-		)
+		))
 	}
 
 	if len(results) != expectedCount {
-		return nil, fmt.Errorf(
+		return nil, errs.Mark(errs.ErrParse, fmt.Errorf(
 			"expected %d results, got %d",
 			expectedCount,
 			len(results),
-		)
+		))
 	}
 
 	return results, nil
@@ -405,6 +300,54 @@ func truncateString(s string, maxLen int) string {
 	return s[:maxLen] + "..."
 }
 
+func (t *GeminiTranslator) Model() string {
+	return t.model
+}
+
+// Generate sends a free-form prompt, with any images, using the
+// translator's sampling and safety settings
+func (t *GeminiTranslator) Generate(
+	ctx context.Context,
+	prompt string,
+	images []Image,
+	maxTokens int,
+) (string, any, error) {
+	config := t.generateConfig(false)
+	if config == nil {
+		config = &genai.GenerateContentConfig{}
+	}
+	config.MaxOutputTokens = int32(maxTokens)
+
+	parts := []*genai.Part{genai.NewPartFromText(prompt)}
+	for _, image := range images {
+		parts = append(parts, genai.NewPartFromBytes(image.Data, image.MIMEType))
+	}
+	contents := []*genai.Content{
+		genai.NewContentFromParts(parts, genai.RoleUser),
+	}
+
+	result, err := t.client.Models.GenerateContent(ctx, t.model, contents, config)
+	if err != nil {
+		return "", nil, fmt.Errorf("request failed: %w", errs.Classify(err))
+	}
+	if result == nil {
+		return "", nil, errs.Mark(errs.ErrParse, fmt.Errorf("empty response from Gemini"))
+	}
+	if meta := result.UsageMetadata; meta != nil {
+		t.options.Usage.AddTokens(int64(meta.PromptTokenCount), int64(meta.CandidatesTokenCount))
+	}
+	if err := gemini.Blocked(result); err != nil {
+		return "", result, err
+	}
+	if gemini.Truncated(result) {
+		return "", result, errs.Mark(
+			errs.ErrResponseTruncated,
+			fmt.Errorf("Gemini response cut off at %d tokens", maxTokens),
+		)
+	}
+	return result.Text(), result, nil
+}
+
 func (t *GeminiTranslator) Close() error {
 	return nil
 }
@@ -1,7 +1,15 @@
 package translate
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/glossary"
+	"google.golang.org/genai"
 )
 
 func TestExtractTranslationResults(t *testing.T) {
@@ -246,6 +254,47 @@ func TestBuildPromptWithoutInputLanguage(t *testing.T) {
 	}
 }
 
+func TestBuildPromptWithGlossary(t *testing.T) {
+	opts := Options{
+		TargetLanguage: "English",
+		Glossary: glossary.Glossary{
+			{Term: "Naruto"},
+			{Term: "Konoha", Translation: "Hidden Leaf Village"},
+		},
+	}
+
+	prompt := BuildPrompt(opts, []TranslationItem{{Index: 0, Text: "Konoha"}})
+
+	if !contains(prompt, "- Konoha -> Hidden Leaf Village") {
+		t.Error("prompt should contain glossary translation")
+	}
+	if !contains(prompt, "- Naruto (keep as is)") {
+		t.Error("prompt should mark untranslated glossary terms")
+	}
+}
+
+func TestBuildPromptWithReading(t *testing.T) {
+	items := []TranslationItem{{Index: 0, Text: "こんにちは"}}
+
+	prompt := BuildPrompt(Options{TargetLanguage: "English"}, items)
+	if contains(prompt, "'reading'") {
+		t.Error("prompt should not ask for a reading unless one is set")
+	}
+
+	prompt = BuildPrompt(Options{TargetLanguage: "English", Reading: ReadingKana}, items)
+	if !contains(prompt, "'index', 'text', and 'reading' fields") {
+		t.Error("prompt should ask for a reading field")
+	}
+	if !contains(prompt, "hiragana") {
+		t.Error("prompt should describe kana readings")
+	}
+
+	results, ok := tryExtractResults(json.RawMessage(`[{"index": 0, "text": "Hello", "reading": "こんにちは"}]`))
+	if !ok || results[0].Reading != "こんにちは" {
+		t.Errorf("tryExtractResults() = %+v, %v", results, ok)
+	}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) &&
 		(s == substr || len(s) > 0 && containsHelper(s, substr))
@@ -259,3 +308,81 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestParseResponseErrorClass(t *testing.T) {
+	text := func(s string, reason genai.FinishReason) *genai.GenerateContentResponse {
+		return &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+			Content:      genai.NewContentFromText(s, genai.RoleModel),
+			FinishReason: reason,
+		}}}
+	}
+	tests := []struct {
+		name   string
+		result *genai.GenerateContentResponse
+		want   error
+	}{
+		{"empty", &genai.GenerateContentResponse{}, errs.ErrParse},
+		{"prompt blocked", &genai.GenerateContentResponse{
+			PromptFeedback: &genai.GenerateContentResponsePromptFeedback{
+				BlockReason: genai.BlockedReasonSafety,
+			},
+		}, errs.ErrContentFiltered},
+		{"safety", text("", genai.FinishReasonSafety), errs.ErrContentFiltered},
+		{"truncated", text(`[{"index": 1, "text": "Hol`, genai.FinishReasonMaxTokens), errs.ErrResponseTruncated},
+		{"malformed", text(`not json`, genai.FinishReasonStop), errs.ErrParse},
+	}
+
+	translator := &GeminiTranslator{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := translator.parseResponse(tt.result, 1)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("parseResponse() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendWithReprompt(t *testing.T) {
+	blocked := errs.Mark(errs.ErrContentFiltered, errors.New("blocked"))
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{"sent once", []error{nil}, 1, nil},
+		{"re-prompted", []error{blocked, nil}, 2, nil},
+		{"blocked twice", []error{blocked, blocked}, 2, errs.ErrContentFiltered},
+		{"other errors are not re-prompted", []error{errs.ErrParse}, 1, errs.ErrParse},
+	}
+
+	items := []TranslationItem{{Index: 0, Text: "Hello"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prompts []string
+			var relaxed []bool
+			_, err := sendWithReprompt(context.Background(), Options{TargetLanguage: "Spanish"}, items, func(
+				_ context.Context,
+				prompt string,
+				r bool,
+			) ([]TranslationResult, error) {
+				prompts = append(prompts, prompt)
+				relaxed = append(relaxed, r)
+				return nil, tt.errs[len(prompts)-1]
+			})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("sendWithReprompt() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(prompts) != tt.wantCalls {
+				t.Fatalf("sent %d times, want %d", len(prompts), tt.wantCalls)
+			}
+			if strings.HasPrefix(prompts[0], repromptNote) || relaxed[0] {
+				t.Error("first attempt was re-prompted")
+			}
+			if len(prompts) == 2 && (prompts[1] != repromptNote+prompts[0] || !relaxed[1]) {
+				t.Errorf("second attempt = %q (relaxed %v), want the note before the prompt", prompts[1], relaxed[1])
+			}
+		})
+	}
+}
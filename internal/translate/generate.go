@@ -0,0 +1,34 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+// TextGenerator is implemented by the built-in translators, whose client,
+// model, and sampling and safety settings also answer free-form prompts,
+// for features beyond translation such as summaries
+type TextGenerator interface {
+	// Model is the model the prompts are sent to
+	Model() string
+	// Generate answers prompt in at most maxTokens output tokens and
+	// returns the answer and the provider's response, for dumps
+	Generate(ctx context.Context, prompt string, images []Image, maxTokens int) (string, any, error)
+}
+
+// Image is a picture sent to the model with a prompt; only gemini reads
+// images
+type Image struct {
+	Data     []byte
+	MIMEType string // e.g. "image/jpeg"
+}
+
+// refuses images for providers that cannot read them
+func refuseImages(provider Provider, images []Image) error {
+	if len(images) == 0 {
+		return nil
+	}
+	return errs.Wrap(errs.KindInput, fmt.Errorf("provider %q cannot read images: use gemini", provider))
+}
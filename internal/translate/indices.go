@@ -0,0 +1,122 @@
+package translate
+
+import (
+	"context"
+
+	"github.com/mgpai22/lipi/internal/logging"
+)
+
+// times WithStrictIndices sends a batch again when its answer cannot be
+// matched to its items
+const strictIndexResends = 2
+
+// what is wrong with the indices of a batch's results
+type indexProblems struct {
+	outOfRange int // indices that are not in the batch
+	duplicates int // indices answered more than once
+	missing    int // items of the batch without a result
+}
+
+func (p indexProblems) ok() bool {
+	return p.outOfRange == 0 && p.duplicates == 0 && p.missing == 0
+}
+
+func (p indexProblems) fields() []any {
+	return []any{
+		"out_of_range", p.outOfRange,
+		"duplicates", p.duplicates,
+		"missing", p.missing,
+	}
+}
+
+// checks that results answer each of items exactly once, and returns the
+// results that do: the first one for each index of the batch
+func checkIndices(items []TranslationItem, results []TranslationResult) ([]TranslationResult, indexProblems) {
+	want := make(map[int]bool, len(items))
+	for _, item := range items {
+		want[item.Index] = true
+	}
+	var problems indexProblems
+	seen := make(map[int]bool, len(results))
+	valid := make([]TranslationResult, 0, len(results))
+	for _, r := range results {
+		switch {
+		case !want[r.Index]:
+			problems.outOfRange++
+		case seen[r.Index]:
+			problems.duplicates++
+		default:
+			seen[r.Index] = true
+			valid = append(valid, r)
+		}
+	}
+	problems.missing = len(items) - len(valid)
+	return valid, problems
+}
+
+// WithStrictIndices checks that each answer has one result for every item
+// of its batch. An answer with the right number of results but wrong or
+// repeated indices is re-mapped by order; any other mismatch sends the
+// batch again, and once the resends are spent only the results that match
+// an item are kept. log may be nil.
+func WithStrictIndices(log *logging.Logger) Middleware {
+	return func(next Translator) Translator {
+		return decorate(next, func(ctx context.Context, items []TranslationItem) ([]TranslationResult, error) {
+			var best []TranslationResult
+			for attempt := 0; ; attempt++ {
+				results, err := next.Translate(ctx, items)
+				if err != nil {
+					return nil, err
+				}
+				valid, problems := checkIndices(items, results)
+				if problems.ok() {
+					return results, nil
+				}
+				if len(results) == len(items) {
+					if log != nil {
+						log.Warnw("Re-mapping translation results by order",
+							append([]any{"batch", entryRange(items)}, problems.fields()...)...,
+						)
+					}
+					remapped := make([]TranslationResult, len(results))
+					for i, r := range results {
+						r.Index = items[i].Index
+						remapped[i] = r
+					}
+					return remapped, nil
+				}
+				if len(valid) > len(best) {
+					best = valid
+				}
+				if attempt == strictIndexResends {
+					if log != nil {
+						log.Warnw("Translation results still do not match the batch, keeping those that do",
+							append([]any{"batch", entryRange(items), "kept", len(best)}, problems.fields()...)...,
+						)
+					}
+					return best, nil
+				}
+				if log != nil {
+					log.Warnw("Translation results do not match the batch, sending it again",
+						append([]any{"batch", entryRange(items), "attempt", attempt + 1}, problems.fields()...)...,
+					)
+				}
+			}
+		})
+	}
+}
+
+// Untranslated returns the items that no result answers
+func Untranslated(items []TranslationItem, results []TranslationResult) []TranslationItem {
+	answered := make(map[int]bool, len(results))
+	for _, r := range results {
+		answered[r.Index] = true
+	}
+	var missing []TranslationItem
+	for _, item := range items {
+		if !answered[item.Index] {
+			missing = append(missing, item)
+		}
+	}
+	return missing
+}
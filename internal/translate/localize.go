@@ -0,0 +1,63 @@
+package translate
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// what the prompt asks of a localized translation, for the target language
+const localizeInstruction = "Localize for %s speakers: convert imperial and US units to metric " +
+	"(miles to kilometers, feet to meters, pounds to kilograms, °F to °C), rounding " +
+	"to natural values; write dates and times in the target locale's order and style " +
+	"(e.g. MM/DD to DD/MM, 3 PM to 15:00 where a 24-hour clock is usual); and use its " +
+	"decimal and thousands separators and currency formatting."
+
+// a quantity in imperial or US units, with its number
+var imperialRegex = regexp.MustCompile(
+	`(?i)(\d+(?:[.,]\d+)*)\s*(?:-\s*)?(miles?|mph|feet|foot|ft|inch(?:es)?|yards?|yds?|pounds?|lbs?|ounces?|oz|gallons?|gal|°\s*F|degrees? fahrenheit|fahrenheit)\b`,
+)
+
+// a numeric date written month first, as in the US
+var monthFirstDateRegex = regexp.MustCompile(`\b(\d{1,2})/(\d{1,2})(?:/(\d{2}|\d{4}))?\b`)
+
+// Unlocalized lists the quantities in imperial units and month-first dates
+// of original that translation still carries as they were: the same
+// number for a quantity, the same digits for a date. It is a heuristic; a
+// localized translation may repeat a number on purpose.
+func Unlocalized(original, translation string) []string {
+	var found []string
+	numbers := numbersIn(translation)
+	for _, m := range imperialRegex.FindAllStringSubmatch(original, -1) {
+		if numbers[normalizeNumber(m[1])] {
+			found = append(found, m[0])
+		}
+	}
+	for _, m := range monthFirstDateRegex.FindAllStringSubmatch(original, -1) {
+		month, _ := strconv.Atoi(m[1])
+		day, _ := strconv.Atoi(m[2])
+		// the same either way, or not month first
+		if month == day || month < 1 || month > 12 || day < 1 || day > 31 {
+			continue
+		}
+		if strings.Contains(translation, m[0]) {
+			found = append(found, m[0])
+		}
+	}
+	return found
+}
+
+var numberRegex = regexp.MustCompile(`\d+(?:[.,]\d+)*`)
+
+// the numbers of text, with separators left out so 1,000 and 1.000 match
+func numbersIn(text string) map[string]bool {
+	numbers := map[string]bool{}
+	for _, n := range numberRegex.FindAllString(text, -1) {
+		numbers[normalizeNumber(n)] = true
+	}
+	return numbers
+}
+
+func normalizeNumber(n string) string {
+	return strings.NewReplacer(",", "", ".", "").Replace(n)
+}
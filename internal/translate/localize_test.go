@@ -0,0 +1,43 @@
+package translate
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestUnlocalized(t *testing.T) {
+	tests := []struct {
+		name        string
+		original    string
+		translation string
+		want        []string
+	}{
+		{"converted units", "It's 5 miles away and 72°F.", "Está a 8 km y hace 22 °C.", nil},
+		{"unconverted units", "It's 5 miles away and 72°F.", "Está a 5 millas y hace 72 °F.", []string{"5 miles", "72°F"}},
+		{"separators differ", "It weighs 1,200 pounds.", "Pesa 1.200 libras.", []string{"1,200 pounds"}},
+		{"hyphenated", "a 10-foot wall", "un muro de 3 metros", nil},
+		{"date kept month first", "Due 03/14/2024.", "Para el 03/14/2024.", []string{"03/14/2024"}},
+		{"date reordered", "Due 03/14/2024.", "Para el 14/03/2024.", nil},
+		{"same day and month", "On 04/04 we meet.", "El 04/04 nos vemos.", nil},
+		{"nothing to localize", "Hello there.", "Hola.", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unlocalized(tt.original, tt.translation); !slices.Equal(got, tt.want) {
+				t.Errorf("Unlocalized() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildPromptWithLocalize(t *testing.T) {
+	items := []TranslationItem{{Index: 0, Text: "5 miles"}}
+	prompt := BuildPrompt(Options{TargetLanguage: "German", Localize: true, Reading: ReadingRomanization}, items)
+	if !strings.Contains(prompt, "10. Localize for German speakers") {
+		t.Errorf("prompt lacks the numbered localize instruction:\n%s", prompt)
+	}
+	if strings.Contains(BuildPrompt(Options{TargetLanguage: "German"}, items), "Localize") {
+		t.Error("prompt should not localize unless asked")
+	}
+}
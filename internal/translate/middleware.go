@@ -0,0 +1,260 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/middleware"
+)
+
+// Middleware adds behaviour around each request of a translator
+type Middleware func(next Translator) Translator
+
+// Chain wraps t with mws; the first middleware sees each request first
+func Chain(t Translator, mws ...Middleware) Translator {
+	for i := len(mws) - 1; i >= 0; i-- {
+		t = mws[i](t)
+	}
+	return t
+}
+
+// translator that runs translate in front of next
+type decorated struct {
+	next      Translator
+	translate translateFunc
+}
+
+func (d *decorated) Translate(
+	ctx context.Context,
+	items []TranslationItem,
+) ([]TranslationResult, error) {
+	return d.translate(ctx, items)
+}
+
+func (d *decorated) Shutdown(ctx context.Context) error {
+	return Shutdown(ctx, d.next)
+}
+
+func (d *decorated) Close() error {
+	return Shutdown(context.Background(), d.next)
+}
+
+func decorate(next Translator, translate translateFunc) Translator {
+	return &decorated{next: next, translate: translate}
+}
+
+// WithRetry sends failed requests again according to policy
+func WithRetry(policy middleware.RetryPolicy) Middleware {
+	return func(next Translator) Translator {
+		return decorate(next, func(ctx context.Context, items []TranslationItem) ([]TranslationResult, error) {
+			return middleware.Retry(ctx, policy, func(ctx context.Context) ([]TranslationResult, error) {
+				return next.Translate(ctx, items)
+			})
+		})
+	}
+}
+
+// WithRateLimit waits for limiter before each request
+func WithRateLimit(limiter *middleware.RateLimiter) Middleware {
+	return func(next Translator) Translator {
+		return decorate(next, func(ctx context.Context, items []TranslationItem) ([]TranslationResult, error) {
+			if err := limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+			return next.Translate(ctx, items)
+		})
+	}
+}
+
+// Limiter bounds the batch requests in flight across several translators,
+// such as the files of a batch sharing one worker budget. A nil Limiter
+// does not limit.
+type Limiter chan struct{}
+
+// NewLimiter allows n requests in flight at once
+func NewLimiter(n int) Limiter {
+	return make(Limiter, n)
+}
+
+// WithLimiter holds a slot of limiter for each request, retries included
+func WithLimiter(limiter Limiter) Middleware {
+	return func(next Translator) Translator {
+		return decorate(next, func(ctx context.Context, items []TranslationItem) ([]TranslationResult, error) {
+			select {
+			case limiter <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			defer func() { <-limiter }()
+			return next.Translate(ctx, items)
+		})
+	}
+}
+
+// WithLogging logs each request and its outcome at debug level
+func WithLogging(log *logging.Logger, provider Provider) Middleware {
+	return func(next Translator) Translator {
+		return decorate(next, func(ctx context.Context, items []TranslationItem) ([]TranslationResult, error) {
+			start := time.Now()
+			results, err := next.Translate(ctx, items)
+			fields := []any{
+				"provider", string(provider),
+				"items", len(items),
+				"elapsed", time.Since(start).String(),
+			}
+			if err != nil {
+				log.Debugw("Translation request failed", append(fields, "error", err)...)
+			} else {
+				log.Debugw("Translation request done", fields...)
+			}
+			return results, err
+		})
+	}
+}
+
+// WithMetrics records each request in m
+func WithMetrics(m *middleware.Metrics) Middleware {
+	return func(next Translator) Translator {
+		return decorate(next, func(ctx context.Context, items []TranslationItem) ([]TranslationResult, error) {
+			start := time.Now()
+			results, err := next.Translate(ctx, items)
+			m.Observe(start, err)
+			return results, err
+		})
+	}
+}
+
+// WithCache answers requests for items already translated with the same
+// settings from cache. scope identifies the settings, see cacheScope.
+func WithCache(cache *middleware.Cache, scope string) Middleware {
+	return func(next Translator) Translator {
+		return decorate(next, func(ctx context.Context, items []TranslationItem) ([]TranslationResult, error) {
+			input, err := json.Marshal(items)
+			if err != nil {
+				return next.Translate(ctx, items)
+			}
+			key := middleware.Key(scope, string(input))
+
+			var cached []TranslationResult
+			if cache.Get(key, &cached) {
+				return cached, nil
+			}
+			results, err := next.Translate(ctx, items)
+			if err == nil {
+				// a failed write only costs a request on the next run
+				_ = cache.Put(key, results)
+			}
+			return results, err
+		})
+	}
+}
+
+// settings that change what a provider returns for the same items
+func cacheScope(provider Provider, opts Options) string {
+	glossary, _ := json.Marshal(opts.Glossary)
+	parts := []string{
+		"translate",
+		string(provider),
+		opts.Model,
+		opts.InputLanguage,
+		opts.TargetLanguage,
+		opts.Prompt,
+		string(glossary),
+	}
+	// only runs with sampling settings extend the key, so existing cache
+	// entries stay valid
+	if opts.Temperature != nil || opts.TopP != nil || opts.ThinkingBudget != nil {
+		parts = append(parts,
+			"decoding",
+			formatFloat(opts.Temperature),
+			formatFloat(opts.TopP),
+			formatInt(opts.ThinkingBudget),
+		)
+	}
+	if len(opts.SafetyThresholds) > 0 {
+		parts = append(parts, "safety", formatThresholds(opts.SafetyThresholds))
+	}
+	if opts.Reading != "" {
+		parts = append(parts, "reading", string(opts.Reading))
+	}
+	if opts.Localize {
+		parts = append(parts, "localize")
+	}
+	return middleware.Key(parts...)
+}
+
+// an optional setting as it appears in cache keys, empty when unset
+func formatFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'g', -1, 64)
+}
+
+func formatInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+// thresholds as they appear in cache keys, sorted by category
+func formatThresholds(thresholds map[string]string) string {
+	pairs := make([]string, 0, len(thresholds))
+	for category, threshold := range thresholds {
+		pairs = append(pairs, category+"="+threshold)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// middleware selected by opts, outermost first: cached results skip
+// everything else, a batch sent again for mismatched indices goes through
+// the rest once more, and each retry waits for the rate limiter again
+func (opts Options) middleware(provider Provider) []Middleware {
+	var mws []Middleware
+	if opts.Cache != nil {
+		mws = append(mws, WithCache(opts.Cache, cacheScope(provider, opts)))
+	}
+	if opts.StrictIndices {
+		mws = append(mws, WithStrictIndices(opts.Logger))
+	}
+	if opts.Limiter != nil {
+		mws = append(mws, WithLimiter(opts.Limiter))
+	}
+	if opts.Logger != nil {
+		mws = append(mws, WithLogging(opts.Logger, provider))
+	}
+	if opts.Metrics != nil {
+		mws = append(mws, WithMetrics(opts.Metrics))
+	}
+	if opts.Retry.Attempts > 0 {
+		policy := opts.Retry
+		if opts.Metrics != nil || opts.Logger != nil {
+			onRetry := policy.OnRetry
+			policy.OnRetry = func(attempt int, err error) {
+				opts.Metrics.Retry()
+				if opts.Logger != nil {
+					opts.Logger.Debugw("Retrying translation request",
+						"provider", string(provider),
+						"attempt", attempt,
+						"error", err,
+					)
+				}
+				if onRetry != nil {
+					onRetry(attempt, err)
+				}
+			}
+		}
+		mws = append(mws, WithRetry(policy))
+	}
+	if opts.RateLimit != nil {
+		mws = append(mws, WithRateLimit(opts.RateLimit))
+	}
+	return mws
+}
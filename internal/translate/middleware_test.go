@@ -0,0 +1,201 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/middleware"
+)
+
+// translator that appends "!" to each item, failing its first failures
+// requests
+type fakeTranslator struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+}
+
+func (f *fakeTranslator) Translate(
+	ctx context.Context,
+	items []TranslationItem,
+) ([]TranslationResult, error) {
+	f.mu.Lock()
+	f.calls++
+	fail := f.calls <= f.failures
+	f.mu.Unlock()
+	if fail {
+		return nil, errors.New("503 unavailable")
+	}
+	results := make([]TranslationResult, len(items))
+	for i, item := range items {
+		results[i] = TranslationResult{Index: item.Index, Text: item.Text + "!"}
+	}
+	return results, nil
+}
+
+func TestConcurrentTranslatorBatchesAndRetries(t *testing.T) {
+	items := make([]TranslationItem, 5)
+	for i := range items {
+		items[i] = TranslationItem{Index: i, Text: "line"}
+	}
+
+	var progress atomic.Int64
+	opts := Options{
+		BatchSize:  2,
+		Retry:      middleware.RetryPolicy{Attempts: 1, Backoff: time.Millisecond},
+		OnProgress: func(n int) { progress.Add(int64(n)) },
+	}
+	fake := &fakeTranslator{failures: 1}
+	translator := Concurrent(Chain(fake, opts.middleware(ProviderGemini)...), opts)
+
+	results, err := translator.TranslateWithConcurrency(context.Background(), items, 3)
+	if err != nil {
+		t.Fatalf("TranslateWithConcurrency() error = %v", err)
+	}
+	if len(results) != 5 || results[4].Index != 4 || results[4].Text != "line!" {
+		t.Errorf("results = %+v, want five translated items in order", results)
+	}
+	if fake.calls != 4 {
+		t.Errorf("provider calls = %d, want 3 batches plus 1 retry", fake.calls)
+	}
+	if progress.Load() != 5 {
+		t.Errorf("progress = %d, want 5", progress.Load())
+	}
+}
+
+func TestFactoryWrapsOnlyWithMiddleware(t *testing.T) {
+	ctx := context.Background()
+	translator, err := Factory(ctx, ProviderGemini, "fake-key", Options{
+		TargetLanguage: "French",
+		RateLimit:      middleware.NewRateLimiter(60),
+	})
+	if err != nil {
+		t.Fatalf("Factory error: %v", err)
+	}
+	if _, ok := translator.(*concurrentTranslator); !ok {
+		t.Errorf("Factory() with a rate limit = %T, want the concurrent wrapper", translator)
+	}
+}
+
+// translator that records how many requests it serves at once
+type peakTranslator struct {
+	active, peak atomic.Int64
+}
+
+func (p *peakTranslator) Translate(
+	ctx context.Context,
+	items []TranslationItem,
+) ([]TranslationResult, error) {
+	n := p.active.Add(1)
+	defer p.active.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return make([]TranslationResult, len(items)), nil
+}
+
+func TestLimiterSharedAcrossTranslators(t *testing.T) {
+	items := make([]TranslationItem, 8)
+	for i := range items {
+		items[i] = TranslationItem{Index: i, Text: "line"}
+	}
+	opts := Options{BatchSize: 1, Limiter: NewLimiter(2)}
+	fake := &peakTranslator{}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		translator := Concurrent(Chain(fake, opts.middleware(ProviderGemini)...), opts)
+		wg.Go(func() {
+			if _, err := translator.TranslateWithConcurrency(context.Background(), items, 4); err != nil {
+				t.Errorf("TranslateWithConcurrency() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	if peak := fake.peak.Load(); peak > 2 {
+		t.Errorf("%d requests in flight, want at most 2", peak)
+	}
+}
+
+// translator that gives its answers in turn, repeating the last one
+type scriptedTranslator struct {
+	answers [][]TranslationResult
+	calls   int
+}
+
+func (s *scriptedTranslator) Translate(
+	ctx context.Context,
+	items []TranslationItem,
+) ([]TranslationResult, error) {
+	answer := s.answers[min(s.calls, len(s.answers)-1)]
+	s.calls++
+	return answer, nil
+}
+
+func TestStrictIndices(t *testing.T) {
+	items := []TranslationItem{{Index: 4, Text: "a"}, {Index: 5, Text: "b"}, {Index: 6, Text: "c"}}
+	tests := []struct {
+		name    string
+		answers [][]TranslationResult
+		want    []TranslationResult
+		calls   int
+	}{
+		{
+			"matching",
+			[][]TranslationResult{{{Index: 4, Text: "A"}, {Index: 5, Text: "B"}, {Index: 6, Text: "C"}}},
+			[]TranslationResult{{Index: 4, Text: "A"}, {Index: 5, Text: "B"}, {Index: 6, Text: "C"}},
+			1,
+		},
+		{
+			"numbered from the batch start, re-mapped by order",
+			[][]TranslationResult{{{Index: 0, Text: "A"}, {Index: 1, Text: "B"}, {Index: 2, Text: "C"}}},
+			[]TranslationResult{{Index: 4, Text: "A"}, {Index: 5, Text: "B"}, {Index: 6, Text: "C"}},
+			1,
+		},
+		{
+			"short answer sent again",
+			[][]TranslationResult{
+				{{Index: 4, Text: "A"}},
+				{{Index: 4, Text: "A"}, {Index: 5, Text: "B"}, {Index: 6, Text: "C"}},
+			},
+			[]TranslationResult{{Index: 4, Text: "A"}, {Index: 5, Text: "B"}, {Index: 6, Text: "C"}},
+			2,
+		},
+		{
+			"resends spent, matching results kept",
+			[][]TranslationResult{
+				{{Index: 4, Text: "A"}, {Index: 4, Text: "A2"}, {Index: 9, Text: "X"}, {Index: 6, Text: "C"}},
+			},
+			[]TranslationResult{{Index: 4, Text: "A"}, {Index: 6, Text: "C"}},
+			1 + strictIndexResends,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &scriptedTranslator{answers: tt.answers}
+			got, err := WithStrictIndices(nil)(fake).Translate(context.Background(), items)
+			if err != nil {
+				t.Fatalf("Translate() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Translate() = %+v, want %+v", got, tt.want)
+			}
+			if fake.calls != tt.calls {
+				t.Errorf("provider calls = %d, want %d", fake.calls, tt.calls)
+			}
+			missing := Untranslated(items, got)
+			if len(got) < len(items) && (len(missing) != 1 || missing[0].Index != 5) {
+				t.Errorf("Untranslated() = %+v, want entry 5", missing)
+			}
+		})
+	}
+}
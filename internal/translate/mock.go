@@ -0,0 +1,80 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ProviderMock looks translations up in a fixture instead of calling an
+// API, so the translate pipeline can run in CI without keys
+const ProviderMock Provider = "mock"
+
+// MockTranslationsEnv names the fixture the mock provider reads when set
+const MockTranslationsEnv = "LIPI_MOCK_TRANSLATIONS"
+
+// implements Translator with a fixed table of translations
+type MockTranslator struct {
+	translations map[string]string
+	options      Options
+}
+
+func init() {
+	Register(ProviderMock, func(ctx context.Context, apiKey string, opts Options) (Translator, error) {
+		return NewMockTranslator(os.Getenv(MockTranslationsEnv), opts)
+	})
+}
+
+// NewMockTranslator loads fixture, a JSON object mapping source texts to
+// their translations. Texts missing from it, or every text without a
+// fixture, come back tagged with the target language, e.g. "[es] Hello";
+// readings, when asked for, are the original tagged with the reading style.
+func NewMockTranslator(fixture string, opts Options) (*MockTranslator, error) {
+	m := &MockTranslator{options: opts}
+	if fixture == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock translations: %w", err)
+	}
+	if err := json.Unmarshal(data, &m.translations); err != nil {
+		return nil, fmt.Errorf("failed to parse mock translations %s: %w", fixture, err)
+	}
+	return m, nil
+}
+
+func (m *MockTranslator) Translate(
+	ctx context.Context,
+	items []TranslationItem,
+) ([]TranslationResult, error) {
+	return m.TranslateWithConcurrency(ctx, items, 1)
+}
+
+func (m *MockTranslator) TranslateWithConcurrency(
+	ctx context.Context,
+	items []TranslationItem,
+	concurrency int,
+) ([]TranslationResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]TranslationResult, len(items))
+	for i, item := range items {
+		text, ok := m.translations[item.Text]
+		if !ok {
+			text = "[" + m.options.TargetLanguage + "] " + item.Text
+		}
+		results[i] = TranslationResult{Index: item.Index, Text: text}
+		if m.options.Reading != "" {
+			results[i].Reading = "[" + string(m.options.Reading) + "] " + item.Text
+		}
+	}
+	if m.options.OnProgress != nil && len(items) > 0 {
+		m.options.OnProgress(len(items))
+	}
+	return results, nil
+}
@@ -3,9 +3,10 @@ package translate
 import (
 	"context"
 	"fmt"
-	"sort"
-	"sync"
+	"time"
 
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/httpclient"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 )
@@ -26,7 +27,10 @@ func NewOpenAITranslator(
 		return nil, fmt.Errorf("API key is required")
 	}
 
-	client := openai.NewClient(option.WithAPIKey(apiKey))
+	client := openai.NewClient(
+		option.WithAPIKey(apiKey),
+		option.WithHTTPClient(httpclient.Or(opts.HTTPClient)),
+	)
 
 	model := opts.Model
 	if model == "" {
@@ -40,192 +44,70 @@ func NewOpenAITranslator(
 	}, nil
 }
 
-func (t *OpenAITranslator) batchSize() int {
-	if t.options.BatchSize > 0 {
-		return t.options.BatchSize
-	}
-	return DefaultBatchSize
-}
-
 func (t *OpenAITranslator) Translate(
 	ctx context.Context,
 	items []TranslationItem,
 ) ([]TranslationResult, error) {
-	if len(items) == 0 {
-		return []TranslationResult{}, nil
-	}
-
-	batchSize := t.batchSize()
-	if len(items) <= batchSize {
-		return t.translateBatch(ctx, items)
-	}
-
-	var allResults []TranslationResult
-	for i := 0; i < len(items); i += batchSize {
-		end := i + batchSize
-		if end > len(items) {
-			end = len(items)
-		}
-
-		batch := items[i:end]
-		results, err := t.translateBatch(ctx, batch)
-		if err != nil {
-			return nil, fmt.Errorf("batch %d failed: %w", i/batchSize, err)
-		}
-		allResults = append(allResults, results...)
-	}
-
-	sort.Slice(allResults, func(i, j int) bool {
-		return allResults[i].Index < allResults[j].Index
-	})
-
-	return allResults, nil
+	return translateBatches(ctx, items, 1, t.options, t.translateBatch)
 }
 
-// Items are split into batches of BatchSize (default 50). Each batch becomes
-// one API request. Workers (up to concurrency) pull batches from a shared queue.
 func (t *OpenAITranslator) TranslateWithConcurrency(
 	ctx context.Context,
 	items []TranslationItem,
 	concurrency int,
 ) ([]TranslationResult, error) {
-	if len(items) == 0 {
-		return []TranslationResult{}, nil
-	}
-
-	if concurrency <= 0 {
-		concurrency = 3
-	}
-
-	batchSize := t.batchSize()
-	var batches [][]TranslationItem
-	for i := 0; i < len(items); i += batchSize {
-		end := i + batchSize
-		if end > len(items) {
-			end = len(items)
-		}
-		batches = append(batches, items[i:end])
-	}
-
-	if len(batches) == 1 {
-		return t.translateBatch(ctx, batches[0])
-	}
-
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	type batchResult struct {
-		Index   int
-		Results []TranslationResult
-		Error   error
-	}
-
-	workChan := make(chan int)
-	resultChan := make(chan batchResult, len(batches))
-
-	var wg sync.WaitGroup
-	for i := 0; i < concurrency && i < len(batches); i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case batchIdx, ok := <-workChan:
-					if !ok {
-						return
-					}
-					if ctx.Err() != nil {
-						return
-					}
-
-					results, err := t.translateBatch(ctx, batches[batchIdx])
-					if err != nil {
-						cancel()
-					}
-					resultChan <- batchResult{
-						Index:   batchIdx,
-						Results: results,
-						Error:   err,
-					}
-				}
-			}
-		}()
-	}
-
-	go func() {
-		defer close(workChan)
-		for i := range batches {
-			select {
-			case <-ctx.Done():
-				return
-			case workChan <- i:
-			}
-		}
-	}()
-
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	results := make([]batchResult, 0, len(batches))
-	var firstErr error
-	for result := range resultChan {
-		if result.Error != nil && firstErr == nil {
-			firstErr = fmt.Errorf(
-				"batch %d failed: %w",
-				result.Index,
-				result.Error,
-			)
-			cancel()
-		}
-		if result.Error == nil {
-			results = append(results, result)
-		}
-	}
-
-	if firstErr != nil {
-		return nil, firstErr
-	}
-
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Index < results[j].Index
-	})
-
-	var allResults []TranslationResult
-	for _, r := range results {
-		allResults = append(allResults, r.Results...)
-	}
-
-	sort.Slice(allResults, func(i, j int) bool {
-		return allResults[i].Index < allResults[j].Index
-	})
-
-	return allResults, nil
+	return translateBatches(ctx, items, concurrency, t.options, t.translateBatch)
 }
 
 func (t *OpenAITranslator) translateBatch(
 	ctx context.Context,
 	items []TranslationItem,
 ) ([]TranslationResult, error) {
-	prompt := BuildPrompt(t.options, items)
+	return sendWithReprompt(ctx, t.options, items, func(
+		ctx context.Context,
+		prompt string,
+		_ bool,
+	) ([]TranslationResult, error) {
+		return t.send(ctx, items, prompt)
+	})
+}
 
-	completion, err := t.client.Chat.Completions.New(
-		ctx,
-		openai.ChatCompletionNewParams{
-			Messages: []openai.ChatCompletionMessageParamUnion{
-				openai.UserMessage(prompt),
-			},
-			Model: t.model,
+// sends one translation request
+func (t *OpenAITranslator) send(
+	ctx context.Context,
+	items []TranslationItem,
+	prompt string,
+) ([]TranslationResult, error) {
+	params := openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage(prompt),
 		},
-	)
+		Model: t.model,
+	}
+	if t.options.Temperature != nil {
+		params.Temperature = openai.Float(*t.options.Temperature)
+	}
+	if t.options.TopP != nil {
+		params.TopP = openai.Float(*t.options.TopP)
+	}
+
+	start := time.Now()
+	completion, err := t.client.Chat.Completions.New(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("translation failed: %w", err)
+		err = fmt.Errorf("translation failed: %w", errs.Classify(err))
+		dumpBatch(t.options, ProviderOpenAI, t.model, items, prompt, start, nil, err)
+		return nil, err
+	}
+	if completion != nil {
+		t.options.Usage.AddTokens(
+			completion.Usage.PromptTokens,
+			completion.Usage.CompletionTokens,
+		)
 	}
 
-	return t.parseResponse(completion, len(items))
+	results, err := t.parseResponse(completion, len(items))
+	dumpBatch(t.options, ProviderOpenAI, t.model, items, prompt, start, completion, err)
+	return results, err
 }
 
 func (t *OpenAITranslator) parseResponse(
@@ -233,37 +115,100 @@ func (t *OpenAITranslator) parseResponse(
 	expectedCount int,
 ) ([]TranslationResult, error) {
 	if completion == nil || len(completion.Choices) == 0 {
-		return nil, fmt.Errorf("empty response from OpenAI")
+		return nil, errs.Mark(errs.ErrParse, fmt.Errorf("empty response from OpenAI"))
+	}
+
+	parseClass := errs.ErrParse
+	switch completion.Choices[0].FinishReason {
+	case "content_filter":
+		return nil, errs.Mark(
+			errs.ErrContentFiltered,
+			fmt.Errorf("response blocked by OpenAI content filter"),
+		)
+	case "length":
+		parseClass = errs.ErrResponseTruncated
 	}
 
 	responseText := completion.Choices[0].Message.Content
 
 	if responseText == "" {
-		return nil, fmt.Errorf("no text in OpenAI response")
+		return nil, errs.Mark(parseClass, fmt.Errorf("no text in OpenAI response"))
 	}
 
 	responseText = cleanJSONResponse(responseText)
 
 	results, err := extractTranslationResults(responseText)
 	if err != nil {
-		return nil, fmt.Errorf(
+		return nil, errs.Mark(parseClass, fmt.Errorf(
 			"failed to parse JSON response: %w (response: %s)",
 			err,
 			truncateString(responseText, 200),
-		)
+		))
 	}
 
 	if len(results) != expectedCount {
-		return nil, fmt.Errorf(
+		return nil, errs.Mark(errs.ErrParse, fmt.Errorf(
 			"expected %d results, got %d",
 			expectedCount,
 			len(results),
-		)
+		))
 	}
 
 	return results, nil
 }
 
+func (t *OpenAITranslator) Model() string {
+	return t.model
+}
+
+// Generate sends a free-form prompt using the translator's sampling
+// settings; OpenAI is not sent images
+func (t *OpenAITranslator) Generate(
+	ctx context.Context,
+	prompt string,
+	images []Image,
+	maxTokens int,
+) (string, any, error) {
+	if err := refuseImages(ProviderOpenAI, images); err != nil {
+		return "", nil, err
+	}
+	params := openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage(prompt),
+		},
+		Model:               t.model,
+		MaxCompletionTokens: openai.Int(int64(maxTokens)),
+	}
+	if t.options.Temperature != nil {
+		params.Temperature = openai.Float(*t.options.Temperature)
+	}
+	if t.options.TopP != nil {
+		params.TopP = openai.Float(*t.options.TopP)
+	}
+
+	completion, err := t.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return "", nil, fmt.Errorf("request failed: %w", errs.Classify(err))
+	}
+	if completion == nil || len(completion.Choices) == 0 {
+		return "", completion, errs.Mark(errs.ErrParse, fmt.Errorf("empty response from OpenAI"))
+	}
+	t.options.Usage.AddTokens(completion.Usage.PromptTokens, completion.Usage.CompletionTokens)
+	switch completion.Choices[0].FinishReason {
+	case "content_filter":
+		return "", completion, errs.Mark(
+			errs.ErrContentFiltered,
+			fmt.Errorf("response blocked by OpenAI content filter"),
+		)
+	case "length":
+		return "", completion, errs.Mark(
+			errs.ErrResponseTruncated,
+			fmt.Errorf("OpenAI response cut off at %d tokens", maxTokens),
+		)
+	}
+	return completion.Choices[0].Message.Content, completion, nil
+}
+
 func (t *OpenAITranslator) Close() error {
 	return nil
 }
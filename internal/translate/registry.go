@@ -0,0 +1,89 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// FactoryFunc creates the translator of a registered provider
+type FactoryFunc func(ctx context.Context, apiKey string, opts Options) (Translator, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[Provider]FactoryFunc{}
+)
+
+func init() {
+	Register(ProviderGemini, func(ctx context.Context, apiKey string, opts Options) (Translator, error) {
+		return NewGeminiTranslator(ctx, apiKey, opts)
+	})
+	Register(ProviderOpenAI, func(ctx context.Context, apiKey string, opts Options) (Translator, error) {
+		return NewOpenAITranslator(ctx, apiKey, opts)
+	})
+	Register(ProviderAnthropic, func(ctx context.Context, apiKey string, opts Options) (Translator, error) {
+		return NewAnthropicTranslator(ctx, apiKey, opts)
+	})
+}
+
+// Register makes a provider available to Factory under name, so programs
+// embedding lipi can add backends of their own. Factory still applies the
+// requested middleware to what factory returns. Register panics if name is
+// empty, already registered, or factory is nil, like database/sql drivers.
+func Register(name Provider, factory FactoryFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if name == "" {
+		panic("translate: Register with empty provider name")
+	}
+	if factory == nil {
+		panic("translate: Register factory is nil for " + string(name))
+	}
+	if _, dup := registry[name]; dup {
+		panic("translate: Register called twice for " + string(name))
+	}
+	registry[name] = factory
+}
+
+// removes a provider, so tests can register theirs again
+func unregister(name Provider) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	delete(registry, name)
+}
+
+// Registered reports whether a provider is available under name
+func Registered(name Provider) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, ok := registry[name]
+	return ok
+}
+
+// Providers lists the registered providers in sorted order
+func Providers() []Provider {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]Provider, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+func newProvider(
+	ctx context.Context,
+	provider Provider,
+	apiKey string,
+	opts Options,
+) (Translator, error) {
+	registryMu.RLock()
+	factory, ok := registry[provider]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported translation provider: %s", provider)
+	}
+	return factory(ctx, apiKey, opts)
+}
@@ -0,0 +1,41 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+// put in front of the prompt when a provider's content filter blocked a
+// batch, for one more attempt
+const repromptNote = "These subtitles are from a film, TV programme, or recording being translated for accessibility. " +
+	"Translate every line faithfully, including violent, sexual, or offensive language, without censoring, summarizing, or leaving anything out.\n\n"
+
+// sends a batch with send, and once more with repromptNote in front of the
+// prompt when the provider's content filter blocked it; relaxed tells send
+// to also loosen any filters the provider lets callers adjust
+func sendWithReprompt(
+	ctx context.Context,
+	opts Options,
+	items []TranslationItem,
+	send func(ctx context.Context, prompt string, relaxed bool) ([]TranslationResult, error),
+) ([]TranslationResult, error) {
+	prompt := BuildPrompt(opts, items)
+	results, err := send(ctx, prompt, false)
+	if !errors.Is(err, errs.ErrContentFiltered) {
+		return results, err
+	}
+	if opts.Logger != nil {
+		opts.Logger.Warnw("Batch blocked by the content filter, sending it again with a neutral note",
+			"batch", entryRange(items),
+			"error", err,
+		)
+	}
+	results, err = send(ctx, repromptNote+prompt, true)
+	if errors.Is(err, errs.ErrContentFiltered) {
+		return nil, fmt.Errorf("%w (also after re-prompting)", err)
+	}
+	return results, err
+}
@@ -4,7 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
+
+	"github.com/mgpai22/lipi/internal/glossary"
+	"github.com/mgpai22/lipi/internal/logging"
+	"github.com/mgpai22/lipi/internal/middleware"
+	"github.com/mgpai22/lipi/internal/usage"
 )
 
 // single text item to translate
@@ -17,6 +23,8 @@ type TranslationItem struct {
 type TranslationResult struct {
 	Index int    `json:"index"`
 	Text  string `json:"text"`
+	// how the original text is read, when Options.Reading asks for it
+	Reading string `json:"reading,omitempty"`
 }
 
 // interface for text translation
@@ -46,15 +54,60 @@ const (
 	ProviderAnthropic Provider = "anthropic"
 )
 
+// how a result spells out the pronunciation of the original text
+type Reading string
+
+const (
+	// Latin-script romanization, such as Hepburn romaji or pinyin
+	ReadingRomanization Reading = "romanization"
+	// kana readings, such as furigana for the kanji of Japanese text
+	ReadingKana Reading = "kana"
+)
+
 type Options struct {
 	InputLanguage  string
 	TargetLanguage string
 	Model          string
 	Prompt         string
-	BatchSize      int // items per API request (default 50)
+	Glossary       glossary.Glossary
+	Reading        Reading      // when set, results carry a reading of the original text
+	Localize       bool         // convert units, dates, numbers, and currency to the target's conventions
+	BatchSize      int          // items per API request (default 50)
+	StrictIndices  bool         // repair or resend batches whose results do not match their items
+	Usage          *usage.Meter // when set, records tokens sent to the provider
+
+	// sampling settings; nil keeps the provider default
+	Temperature    *float64
+	TopP           *float64
+	ThinkingBudget *int // tokens a Gemini model may spend thinking
+
+	// Gemini block thresholds by harm category, such as
+	// HARM_CATEGORY_HATE_SPEECH: BLOCK_NONE; unset categories keep the
+	// API default
+	SafetyThresholds map[string]string
+
+	// OnProgress, when set, receives the item count of each translated
+	// batch. Batches may finish concurrently.
+	OnProgress func(items int)
+	// HTTPClient, when set, is used for provider requests instead of the
+	// default client
+	HTTPClient *http.Client
+	// Fallbacks are the models a batch moves to, in order, when it keeps
+	// failing on Model
+	Fallbacks []FallbackModel
+
+	// request middleware, composed by Factory when set
+	Retry     middleware.RetryPolicy  // retries failed requests; zero Attempts disables it
+	RateLimit *middleware.RateLimiter // spaces requests to stay under a per-minute quota
+	Limiter   Limiter                 // bounds requests in flight across translators
+	Cache     *middleware.Cache       // reuses results for items already translated
+	Logger    *logging.Logger         // logs each request at debug level
+	Metrics   *middleware.Metrics     // counts requests, failures, retries, and latency
+	Dump      *middleware.Dump        // saves each request's prompt, raw response, and outcome
 }
 
-// creates Translator based on provider
+// creates the Translator registered for provider, wrapped in the middleware
+// that opts asks for
 func Factory(
 	ctx context.Context,
 	provider Provider,
@@ -65,16 +118,29 @@ func Factory(
 		return nil, fmt.Errorf("target language is required")
 	}
 
-	switch provider {
-	case ProviderGemini:
-		return NewGeminiTranslator(ctx, apiKey, opts)
-	case ProviderOpenAI:
-		return NewOpenAITranslator(ctx, apiKey, opts)
-	case ProviderAnthropic:
-		return NewAnthropicTranslator(ctx, apiKey, opts)
-	default:
-		return nil, fmt.Errorf("unsupported translation provider: %s", provider)
+	// every model of a fallback chain gets its own middleware, so a batch
+	// moves on only once its retries are spent
+	if len(opts.Fallbacks) > 0 {
+		models := append([]FallbackModel{{Provider: provider, Model: opts.Model, APIKey: apiKey}}, opts.Fallbacks...)
+		t, err := newFallback(ctx, models, opts)
+		if err != nil {
+			return nil, err
+		}
+		return Concurrent(t, opts), nil
 	}
+
+	mws := opts.middleware(provider)
+	if len(mws) == 0 {
+		return newProvider(ctx, provider, apiKey, opts)
+	}
+	// progress is reported once per batch by the wrapper
+	providerOpts := opts
+	providerOpts.OnProgress = nil
+	t, err := newProvider(ctx, provider, apiKey, providerOpts)
+	if err != nil {
+		return nil, err
+	}
+	return Concurrent(Chain(t, mws...), opts), nil
 }
 
 // BuildPrompt creates the translation prompt for LLM providers
@@ -102,11 +168,38 @@ func BuildPrompt(opts Options, items []TranslationItem) string {
 	)
 	sb.WriteString("4. Preserve line breaks (\\N) in the same positions.\n")
 	sb.WriteString("5. Return ONLY a JSON array with the same structure.\n")
-	sb.WriteString("6. Each object must have 'index' and 'text' fields.\n")
+	if opts.Reading != "" {
+		sb.WriteString("6. Each object must have 'index', 'text', and 'reading' fields.\n")
+	} else {
+		sb.WriteString("6. Each object must have 'index' and 'text' fields.\n")
+	}
 	sb.WriteString(
 		"7. The 'index' values must match the input indices exactly.\n",
 	)
-	sb.WriteString("8. Do not add any explanation or markdown formatting.\n\n")
+	sb.WriteString("8. Do not add any explanation or markdown formatting.\n")
+	var extra []string
+	if instruction := readingInstruction(opts.Reading); instruction != "" {
+		extra = append(extra, instruction)
+	}
+	if opts.Localize {
+		extra = append(extra, fmt.Sprintf(localizeInstruction, opts.TargetLanguage))
+	}
+	for i, instruction := range extra {
+		fmt.Fprintf(&sb, "%d. %s\n", 9+i, instruction)
+	}
+	sb.WriteString("\n")
+
+	if len(opts.Glossary) > 0 {
+		sb.WriteString("Glossary - use these renderings consistently:\n")
+		for _, e := range opts.Glossary {
+			if e.Translation != "" {
+				fmt.Fprintf(&sb, "- %s -> %s\n", e.Term, e.Translation)
+			} else {
+				fmt.Fprintf(&sb, "- %s (keep as is)\n", e.Term)
+			}
+		}
+		sb.WriteString("\n")
+	}
 
 	if opts.Prompt != "" {
 		fmt.Fprintf(&sb, "Additional instructions: %s\n\n", opts.Prompt)
@@ -121,3 +214,20 @@ func BuildPrompt(opts Options, items []TranslationItem) string {
 
 	return sb.String()
 }
+
+// what the prompt asks of the 'reading' field
+func readingInstruction(reading Reading) string {
+	switch reading {
+	case ReadingRomanization:
+		return "Set 'reading' to the romanization of the ORIGINAL text, not the translation " +
+			"(Hepburn romaji for Japanese, pinyin with tone marks for Chinese, Revised " +
+			"Romanization for Korean), keeping its line breaks; leave it empty for text " +
+			"already in the Latin alphabet."
+	case ReadingKana:
+		return "Set 'reading' to the ORIGINAL text with every kanji written in hiragana, " +
+			"as furigana would read it, keeping its line breaks; for languages without kana " +
+			"give the romanization instead, and leave it empty for text already in the " +
+			"Latin alphabet."
+	}
+	return ""
+}
@@ -48,6 +48,36 @@ func TestFactoryRejectsUnknownProvider(t *testing.T) {
 	}
 }
 
+func TestFactoryUsesRegisteredProvider(t *testing.T) {
+	fake := &fakeTranslator{}
+	Register("test-registered", func(ctx context.Context, apiKey string, opts Options) (Translator, error) {
+		return fake, nil
+	})
+	t.Cleanup(func() { unregister("test-registered") })
+	if !Registered("test-registered") {
+		t.Fatal("expected provider to be registered")
+	}
+
+	translator, err := Factory(context.Background(), "test-registered", "", Options{TargetLanguage: "French"})
+	if err != nil {
+		t.Fatalf("Factory() error = %v", err)
+	}
+	if translator != fake {
+		t.Errorf("expected the registered translator, got %T", translator)
+	}
+}
+
+func TestRegisterPanicsOnDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Register to panic for a built-in provider name")
+		}
+	}()
+	Register(ProviderGemini, func(context.Context, string, Options) (Translator, error) {
+		return nil, nil
+	})
+}
+
 func TestGeminiTranslatorImplementsConcurrentTranslator(t *testing.T) {
 	ctx := context.Background()
 	opts := Options{TargetLanguage: "Korean"}
@@ -0,0 +1,204 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/httpclient"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"google.golang.org/genai"
+)
+
+const (
+	defaultOpenAIModel     = openai.SpeechModelGPT4oMiniTTS
+	defaultGeminiModel     = "gemini-2.5-flash-preview-tts"
+	defaultElevenLabsModel = "eleven_multilingual_v2"
+)
+
+const elevenLabsURL = "https://api.elevenlabs.io"
+
+// largest clip read from a provider
+const maxAudioBytes = 32 << 20
+
+func newOpenAI(apiKey string, opts Options) synthesizeFunc {
+	clientOpts := []option.RequestOption{
+		option.WithAPIKey(apiKey),
+		option.WithHTTPClient(httpclient.Or(opts.HTTPClient)),
+	}
+	if opts.baseURL != "" {
+		clientOpts = append(clientOpts, option.WithBaseURL(opts.baseURL))
+	}
+	client := openai.NewClient(clientOpts...)
+	model := opts.Model
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+
+	return func(ctx context.Context, text string) (*Audio, error) {
+		params := openai.AudioSpeechNewParams{
+			Input:          text,
+			Model:          model,
+			Voice:          openai.AudioSpeechNewParamsVoice(opts.Voice),
+			ResponseFormat: openai.AudioSpeechNewParamsResponseFormatWAV,
+		}
+		// tts-1 and tts-1-hd take no instructions
+		if opts.Instructions != "" && !strings.HasPrefix(model, "tts-1") {
+			params.Instructions = openai.String(opts.Instructions)
+		}
+		resp, err := client.Audio.Speech.New(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", errs.Classify(err))
+		}
+		defer func() { _ = resp.Body.Close() }()
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxAudioBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read speech: %w", err)
+		}
+		return &Audio{Data: data, Ext: ".wav"}, nil
+	}
+}
+
+func newGemini(ctx context.Context, apiKey string, opts Options) (synthesizeFunc, error) {
+	config := &genai.ClientConfig{
+		APIKey:     apiKey,
+		HTTPClient: httpclient.Or(opts.HTTPClient),
+	}
+	if opts.baseURL != "" {
+		config.HTTPOptions.BaseURL = opts.baseURL
+	}
+	client, err := genai.NewClient(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+	}
+	model := opts.Model
+	if model == "" {
+		model = defaultGeminiModel
+	}
+
+	return func(ctx context.Context, text string) (*Audio, error) {
+		// the model reads any style direction out loud unless it is framed
+		// as one
+		prompt := text
+		if opts.Instructions != "" {
+			prompt = opts.Instructions + ":\n" + text
+		}
+		contents := []*genai.Content{genai.NewContentFromText(prompt, genai.RoleUser)}
+		result, err := client.Models.GenerateContent(ctx, model, contents, &genai.GenerateContentConfig{
+			ResponseModalities: []string{string(genai.ModalityAudio)},
+			SpeechConfig: &genai.SpeechConfig{
+				VoiceConfig: &genai.VoiceConfig{
+					PrebuiltVoiceConfig: &genai.PrebuiltVoiceConfig{VoiceName: opts.Voice},
+				},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", errs.Classify(err))
+		}
+		if result == nil || len(result.Candidates) == 0 || result.Candidates[0].Content == nil {
+			return nil, errs.Mark(errs.ErrParse, errors.New("empty response from Gemini"))
+		}
+		for _, part := range result.Candidates[0].Content.Parts {
+			if part.InlineData == nil || len(part.InlineData.Data) == 0 {
+				continue
+			}
+			// raw 16-bit samples, e.g. "audio/L16;codec=pcm;rate=24000"
+			rate := 24000
+			if _, params, err := mime.ParseMediaType(part.InlineData.MIMEType); err == nil {
+				if r, err := strconv.Atoi(params["rate"]); err == nil && r > 0 {
+					rate = r
+				}
+			}
+			pcm := part.InlineData.Data
+			data := append(WAVHeader(len(pcm), rate, 1), pcm...)
+			return &Audio{Data: data, Ext: ".wav"}, nil
+		}
+		return nil, errs.Mark(errs.ErrParse, errors.New("no audio in Gemini response"))
+	}, nil
+}
+
+func newElevenLabs(apiKey string, opts Options) synthesizeFunc {
+	client := httpclient.Or(opts.HTTPClient)
+	base := opts.baseURL
+	if base == "" {
+		base = elevenLabsURL
+	}
+	model := opts.Model
+	if model == "" {
+		model = defaultElevenLabsModel
+	}
+
+	return func(ctx context.Context, text string) (*Audio, error) {
+		body, err := json.Marshal(map[string]string{
+			"text":     text,
+			"model_id": model,
+		})
+		if err != nil {
+			return nil, err
+		}
+		endpoint := base + "/v1/text-to-speech/" + url.PathEscape(opts.Voice) + "?output_format=mp3_44100_128"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("xi-api-key", apiKey)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "audio/mpeg")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxAudioBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read speech: %w", err)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return nil, elevenLabsError(resp, data)
+		}
+		return &Audio{Data: data, Ext: ".mp3"}, nil
+	}
+}
+
+// ElevenLabs error bodies carry {"detail": {"status", "message"}}, or a
+// plain string detail
+func elevenLabsError(resp *http.Response, body []byte) error {
+	message := resp.Status
+	var apiErr struct {
+		Detail json.RawMessage `json:"detail"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil && len(apiErr.Detail) > 0 {
+		var detail struct {
+			Message string `json:"message"`
+		}
+		var text string
+		switch {
+		case json.Unmarshal(apiErr.Detail, &detail) == nil && detail.Message != "":
+			message = fmt.Sprintf("%s: %s", resp.Status, detail.Message)
+		case json.Unmarshal(apiErr.Detail, &text) == nil && text != "":
+			message = fmt.Sprintf("%s: %s", resp.Status, text)
+		}
+	}
+	err := fmt.Errorf("ElevenLabs request failed: %s", message)
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errs.Mark(errs.ErrAuth, err)
+	case http.StatusTooManyRequests:
+		return errs.Mark(errs.ErrRateLimited, err)
+	}
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return errs.Wrap(errs.KindInput, err)
+	}
+	return err
+}
@@ -0,0 +1,137 @@
+package tts
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/middleware"
+)
+
+// Synthesizer turns text into speech
+type Synthesizer interface {
+	Synthesize(ctx context.Context, text string) (*Audio, error)
+}
+
+// Audio is synthesized speech in a file format ffmpeg reads
+type Audio struct {
+	Data []byte
+	Ext  string // file extension for Data, e.g. ".wav"
+}
+
+// speech providers
+const (
+	ProviderOpenAI     = "openai"
+	ProviderGemini     = "gemini"
+	ProviderElevenLabs = "elevenlabs"
+)
+
+// Providers lists the speech providers
+func Providers() []string {
+	return []string{ProviderGemini, ProviderOpenAI, ProviderElevenLabs}
+}
+
+type Options struct {
+	Model        string
+	Voice        string // provider voice name, or an ElevenLabs voice ID
+	Instructions string // speaking style, for models that follow directions
+	HTTPClient   *http.Client
+
+	Retry     middleware.RetryPolicy  // retries failed requests; zero Attempts disables it
+	RateLimit *middleware.RateLimiter // spaces requests to stay under a per-minute quota
+
+	// endpoint, replaced in tests
+	baseURL string
+}
+
+// DefaultVoice is the voice used for provider when none is given
+func DefaultVoice(provider string) string {
+	switch provider {
+	case ProviderOpenAI:
+		return "alloy"
+	case ProviderGemini:
+		return "Kore"
+	case ProviderElevenLabs:
+		// "Rachel", one of the premade voices every account has
+		return "21m00Tcm4TlvDq8ikWAM"
+	}
+	return ""
+}
+
+// a provider request
+type synthesizeFunc func(ctx context.Context, text string) (*Audio, error)
+
+// synthesizer runs a provider request with the requested middleware
+type synthesizer struct {
+	opts Options
+	call synthesizeFunc
+}
+
+// New returns the Synthesizer for provider
+func New(ctx context.Context, provider, apiKey string, opts Options) (Synthesizer, error) {
+	if apiKey == "" {
+		return nil, errs.Wrap(errs.KindAuth, errors.New("API key is required"))
+	}
+	if opts.Voice == "" {
+		opts.Voice = DefaultVoice(provider)
+	}
+
+	var (
+		call synthesizeFunc
+		err  error
+	)
+	switch provider {
+	case ProviderOpenAI:
+		call = newOpenAI(apiKey, opts)
+	case ProviderGemini:
+		call, err = newGemini(ctx, apiKey, opts)
+	case ProviderElevenLabs:
+		call = newElevenLabs(apiKey, opts)
+	default:
+		return nil, errs.Wrap(errs.KindInput, fmt.Errorf(
+			"unsupported speech provider %q: use %s", provider, strings.Join(Providers(), ", "),
+		))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &synthesizer{opts: opts, call: call}, nil
+}
+
+func (s *synthesizer) Synthesize(ctx context.Context, text string) (*Audio, error) {
+	return middleware.Retry(ctx, s.opts.Retry, func(ctx context.Context) (*Audio, error) {
+		if err := s.opts.RateLimit.Wait(ctx); err != nil {
+			return nil, err
+		}
+		audio, err := s.call(ctx, text)
+		if err == nil && len(audio.Data) == 0 {
+			err = errs.Mark(errs.ErrParse, errors.New("no audio in response"))
+		}
+		return audio, err
+	})
+}
+
+// WAVHeader is the header of a 16-bit PCM WAV file holding dataSize bytes
+// of samples
+func WAVHeader(dataSize, sampleRate, channels int) []byte {
+	const bitsPerSample = 16
+	blockAlign := channels * bitsPerSample / 8
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+dataSize))
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(header[22:], uint16(channels))
+	binary.LittleEndian.PutUint32(header[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(header[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:], bitsPerSample)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(dataSize))
+	return header
+}
@@ -0,0 +1,133 @@
+package tts
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+func TestOpenAI(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/speech" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &got)
+		w.Header().Set("Content-Type", "audio/wav")
+		_, _ = w.Write([]byte("RIFF...."))
+	}))
+	defer server.Close()
+
+	s, err := New(context.Background(), ProviderOpenAI, "key", Options{
+		Instructions: "Speak warmly",
+		baseURL:      server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := s.Synthesize(context.Background(), "Hola")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(audio.Data) != "RIFF...." || audio.Ext != ".wav" {
+		t.Errorf("audio = %q %s", audio.Data, audio.Ext)
+	}
+	for key, want := range map[string]string{
+		"input":           "Hola",
+		"model":           defaultOpenAIModel,
+		"voice":           "alloy",
+		"response_format": "wav",
+		"instructions":    "Speak warmly",
+	} {
+		if got[key] != want {
+			t.Errorf("%s = %v, want %s", key, got[key], want)
+		}
+	}
+}
+
+func TestGemini(t *testing.T) {
+	pcm := []byte{1, 0, 2, 0, 3, 0}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/models/"+defaultGeminiModel+":generateContent") {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"voiceName":"Puck"`) {
+			t.Errorf("request lacks the voice: %s", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"candidates":[{"content":{"parts":[{"inlineData":{"mimeType":"audio/L16;codec=pcm;rate=16000","data":"`+
+			base64.StdEncoding.EncodeToString(pcm)+`"}}]}}]}`)
+	}))
+	defer server.Close()
+
+	s, err := New(context.Background(), ProviderGemini, "key", Options{Voice: "Puck", baseURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := s.Synthesize(context.Background(), "Hola")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audio.Ext != ".wav" || len(audio.Data) != 44+len(pcm) {
+		t.Fatalf("audio = %d bytes %s", len(audio.Data), audio.Ext)
+	}
+	if rate := binary.LittleEndian.Uint32(audio.Data[24:]); rate != 16000 {
+		t.Errorf("sample rate = %d, want 16000", rate)
+	}
+	if string(audio.Data[44:]) != string(pcm) {
+		t.Errorf("samples = %v", audio.Data[44:])
+	}
+}
+
+func TestElevenLabs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("xi-api-key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"detail":{"status":"invalid_api_key","message":"Invalid API key"}}`)
+			return
+		}
+		if r.URL.Path != "/v1/text-to-speech/voice123" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("output_format") == "" {
+			t.Error("no output format requested")
+		}
+		_, _ = w.Write([]byte("ID3"))
+	}))
+	defer server.Close()
+
+	s, err := New(context.Background(), ProviderElevenLabs, "key", Options{Voice: "voice123", baseURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := s.Synthesize(context.Background(), "Hola")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(audio.Data) != "ID3" || audio.Ext != ".mp3" {
+		t.Errorf("audio = %q %s", audio.Data, audio.Ext)
+	}
+
+	s, _ = New(context.Background(), ProviderElevenLabs, "wrong", Options{baseURL: server.URL})
+	_, err = s.Synthesize(context.Background(), "Hola")
+	if !errors.Is(err, errs.ErrAuth) || !strings.Contains(err.Error(), "Invalid API key") {
+		t.Errorf("err = %v, want a rejected key", err)
+	}
+}
+
+func TestNewRejectsUnknownProvider(t *testing.T) {
+	if _, err := New(context.Background(), "polly", "key", Options{}); errs.KindOf(err) != errs.KindInput {
+		t.Errorf("err = %v, want an input error", err)
+	}
+}
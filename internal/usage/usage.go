@@ -0,0 +1,74 @@
+package usage
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// provider API consumption for a run
+type Usage struct {
+	Requests     int64   `json:"requests"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	AudioSeconds float64 `json:"audio_seconds,omitempty"`
+}
+
+// Meter totals usage across concurrent requests. A nil Meter discards
+// everything, so providers can record unconditionally.
+type Meter struct {
+	requests     atomic.Int64
+	inputTokens  atomic.Int64
+	outputTokens atomic.Int64
+	audioMillis  atomic.Int64
+	parent       *Meter
+}
+
+// Child returns a meter for part of a run, such as one output file, whose
+// usage is also added to m
+func (m *Meter) Child() *Meter {
+	return &Meter{parent: m}
+}
+
+// records one request billed by tokens
+func (m *Meter) AddTokens(input, output int64) {
+	if m == nil {
+		return
+	}
+	m.requests.Add(1)
+	m.inputTokens.Add(input)
+	m.outputTokens.Add(output)
+	m.parent.AddTokens(input, output)
+}
+
+// records one request billed by audio length, as Whisper is
+func (m *Meter) AddAudio(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.requests.Add(1)
+	m.audioMillis.Add(d.Milliseconds())
+	m.parent.AddAudio(d)
+}
+
+// current totals
+func (m *Meter) Usage() Usage {
+	if m == nil {
+		return Usage{}
+	}
+	return Usage{
+		Requests:     m.requests.Load(),
+		InputTokens:  m.inputTokens.Load(),
+		OutputTokens: m.outputTokens.Load(),
+		AudioSeconds: float64(m.audioMillis.Load()) / 1000,
+	}
+}
+
+// sum of u and v, as for the attempts of one job
+func (u Usage) Add(v Usage) Usage {
+	return Usage{
+		Requests:     u.Requests + v.Requests,
+		InputTokens:  u.InputTokens + v.InputTokens,
+		OutputTokens: u.OutputTokens + v.OutputTokens,
+		AudioSeconds: u.AudioSeconds + v.AudioSeconds,
+	}
+}
@@ -0,0 +1,55 @@
+package usage
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMeter(t *testing.T) {
+	var m Meter
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			m.AddTokens(100, 20)
+		})
+	}
+	wg.Wait()
+	m.AddAudio(90 * time.Second)
+
+	got := m.Usage()
+	want := Usage{
+		Requests:     11,
+		InputTokens:  1000,
+		OutputTokens: 200,
+		AudioSeconds: 90,
+	}
+	if got != want {
+		t.Errorf("Usage() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNilMeter(t *testing.T) {
+	var m *Meter
+	m.AddTokens(1, 1)
+	m.AddAudio(time.Second)
+	if got := m.Usage(); got != (Usage{}) {
+		t.Errorf("nil meter Usage() = %+v, want zero", got)
+	}
+}
+
+func TestChildMeter(t *testing.T) {
+	var run Meter
+	file := run.Child()
+	file.AddTokens(10, 5)
+	run.AddTokens(1, 1)
+
+	if got := file.Usage(); got.Requests != 1 || got.InputTokens != 10 {
+		t.Errorf("child Usage() = %+v, want its own request only", got)
+	}
+	if got := run.Usage(); got.Requests != 2 || got.InputTokens != 11 {
+		t.Errorf("parent Usage() = %+v, want both requests", got)
+	}
+	var nilMeter *Meter
+	nilMeter.Child().AddAudio(time.Second)
+}
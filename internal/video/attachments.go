@@ -0,0 +1,51 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	ffmpegbin "github.com/mgpai22/lipi/internal/ffmpeg"
+)
+
+// ExtractAttachments saves the attachments of videoPath, such as the fonts
+// of a Matroska file, into dir under their own file names
+func (p *DefaultProcessor) ExtractAttachments(ctx context.Context, videoPath, dir string) error {
+	if _, err := os.Stat(videoPath); os.IsNotExist(err) {
+		return fmt.Errorf("video file not found: %s", videoPath)
+	}
+	videoPath, err := filepath.Abs(videoPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create attachments directory: %w", err)
+	}
+
+	ffmpegPath, err := ffmpegbin.FFmpegPath()
+	if err != nil {
+		return err
+	}
+
+	// attachments are dumped while the input is opened, into the working
+	// directory; the null output only stops ffmpeg asking for one
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-y", "-v", "error",
+		"-dump_attachment:t", "",
+		"-i", videoPath,
+		"-t", "0", "-f", "null", "-",
+	)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errs.Wrap(errs.KindFFmpeg, fmt.Errorf(
+			"ffmpeg attachment extraction failed: %w (%s)",
+			err,
+			strings.TrimSpace(string(out)),
+		))
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	ffmpegbin "github.com/mgpai22/lipi/internal/ffmpeg"
+)
+
+// HWAccel selects the video encoder used when re-encoding
+type HWAccel string
+
+const (
+	HWAccelAuto         HWAccel = "auto" // first working hardware encoder, else software
+	HWAccelNone         HWAccel = "none" // software x264
+	HWAccelNVENC        HWAccel = "nvenc"
+	HWAccelVAAPI        HWAccel = "vaapi"
+	HWAccelVideoToolbox HWAccel = "videotoolbox"
+)
+
+// render node VAAPI encodes on
+const vaapiDevice = "/dev/dri/renderD128"
+
+// ParseHWAccel validates a --hwaccel value
+func ParseHWAccel(s string) (HWAccel, error) {
+	switch accel := HWAccel(strings.ToLower(strings.TrimSpace(s))); accel {
+	case "":
+		return HWAccelAuto, nil
+	case HWAccelAuto, HWAccelNone, HWAccelNVENC, HWAccelVAAPI, HWAccelVideoToolbox:
+		return accel, nil
+	}
+	return "", fmt.Errorf(
+		"unsupported hwaccel %q: use auto, none, nvenc, vaapi, or videotoolbox",
+		s,
+	)
+}
+
+// hardware encoders tried by auto, in order
+func autoCandidates(goos string) []HWAccel {
+	if goos == "darwin" {
+		return []HWAccel{HWAccelVideoToolbox}
+	}
+	return []HWAccel{HWAccelNVENC, HWAccelVAAPI}
+}
+
+// options for burning subtitles into the video frames
+type BurnOptions struct {
+	HWAccel HWAccel
+}
+
+// BurnSubtitles writes a copy of videoPath to outputPath with the subtitles
+// rendered into the picture. Video is re-encoded with the selected encoder;
+// audio is stream-copied. With HWAccelAuto a failed hardware encode is
+// retried in software.
+func (p *DefaultProcessor) BurnSubtitles(
+	ctx context.Context,
+	videoPath, outputPath, subtitlePath string,
+	opts BurnOptions,
+) error {
+	if _, err := os.Stat(videoPath); os.IsNotExist(err) {
+		return fmt.Errorf("video file not found: %s", videoPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	ffmpegPath, err := ffmpegbin.FFmpegPath()
+	if err != nil {
+		return err
+	}
+
+	// the subtitles filter parses its argument, so the file is copied to a
+	// name that needs no escaping
+	stageDir, err := os.MkdirTemp(p.tempDir, "burn-*")
+	if err != nil {
+		return fmt.Errorf("failed to create burn directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(stageDir) }()
+	data, err := os.ReadFile(subtitlePath)
+	if err != nil {
+		return fmt.Errorf("failed to read subtitles: %w", err)
+	}
+	subtitleName := "subtitles" + strings.ToLower(filepath.Ext(subtitlePath))
+	if err := os.WriteFile(filepath.Join(stageDir, subtitleName), data, 0o644); err != nil {
+		return fmt.Errorf("failed to stage subtitles: %w", err)
+	}
+
+	auto := opts.HWAccel == "" || opts.HWAccel == HWAccelAuto
+	accel := opts.HWAccel
+	if auto {
+		accel = detectHWAccel(ctx, ffmpegPath)
+	}
+
+	run := func(accel HWAccel) error {
+		args, err := burnArgs(videoPath, outputPath, subtitleName, accel)
+		if err != nil {
+			return err
+		}
+		cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+		cmd.Dir = stageDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return errs.Wrap(errs.KindFFmpeg, fmt.Errorf(
+				"ffmpeg subtitle burn-in with %s failed: %w (%s)",
+				accel,
+				err,
+				strings.TrimSpace(string(out)),
+			))
+		}
+		return nil
+	}
+
+	err = run(accel)
+	if err != nil && auto && accel != HWAccelNone && ctx.Err() == nil {
+		err = run(HWAccelNone)
+	}
+	return err
+}
+
+// ffmpeg arguments for a burn-in with the given encoder; the subtitle file
+// is relative to the working directory
+func burnArgs(
+	videoPath, outputPath, subtitleName string,
+	accel HWAccel,
+) ([]string, error) {
+	videoPath, err := filepath.Abs(videoPath)
+	if err != nil {
+		return nil, err
+	}
+	outputPath, err = filepath.Abs(outputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := "subtitles=" + subtitleName
+
+	args := []string{"-y", "-v", "error"}
+	if accel == HWAccelVAAPI {
+		args = append(args, "-vaapi_device", vaapiDevice)
+	}
+	args = append(args,
+		"-i", videoPath,
+		"-map", "0:v:0",
+		"-map", "0:a?",
+	)
+
+	switch accel {
+	case HWAccelNVENC:
+		args = append(args, "-vf", filter,
+			"-c:v", "h264_nvenc", "-preset", "p5", "-cq", "21")
+	case HWAccelVAAPI:
+		// subtitles are drawn in software, then frames are uploaded
+		args = append(args, "-vf", filter+",format=nv12,hwupload",
+			"-c:v", "h264_vaapi", "-qp", "21")
+	case HWAccelVideoToolbox:
+		args = append(args, "-vf", filter,
+			"-c:v", "h264_videotoolbox", "-q:v", "65")
+	case HWAccelNone:
+		args = append(args, "-vf", filter,
+			"-c:v", "libx264", "-preset", "medium", "-crf", "20")
+	default:
+		return nil, fmt.Errorf("unsupported hwaccel %q", accel)
+	}
+
+	args = append(args, "-c:a", "copy", outputPath)
+	return args, nil
+}
+
+var (
+	detectMu      sync.Mutex
+	detectedAccel = map[string]HWAccel{}
+)
+
+// first hardware encoder that can encode a test clip with this ffmpeg, or
+// HWAccelNone; the result is cached per binary
+func detectHWAccel(ctx context.Context, ffmpegPath string) HWAccel {
+	detectMu.Lock()
+	defer detectMu.Unlock()
+	if accel, ok := detectedAccel[ffmpegPath]; ok {
+		return accel
+	}
+
+	accel := HWAccelNone
+	for _, candidate := range autoCandidates(runtime.GOOS) {
+		if candidate == HWAccelVAAPI {
+			if _, err := os.Stat(vaapiDevice); err != nil {
+				continue
+			}
+		}
+		if encoderWorks(ctx, ffmpegPath, candidate) {
+			accel = candidate
+			break
+		}
+	}
+	if ctx.Err() == nil {
+		detectedAccel[ffmpegPath] = accel
+	}
+	return accel
+}
+
+// encodes a few frames of a generated clip, which fails without a usable
+// device even when ffmpeg lists the encoder
+func encoderWorks(ctx context.Context, ffmpegPath string, accel HWAccel) bool {
+	args := []string{"-v", "error"}
+	var codec, filter string
+	switch accel {
+	case HWAccelNVENC:
+		codec = "h264_nvenc"
+	case HWAccelVAAPI:
+		args = append(args, "-vaapi_device", vaapiDevice)
+		codec, filter = "h264_vaapi", "format=nv12,hwupload"
+	case HWAccelVideoToolbox:
+		codec = "h264_videotoolbox"
+	default:
+		return false
+	}
+	args = append(args, "-f", "lavfi", "-i", "color=size=256x256:duration=0.2")
+	if filter != "" {
+		args = append(args, "-vf", filter)
+	}
+	args = append(args, "-c:v", codec, "-f", "null", "-")
+	return exec.CommandContext(ctx, ffmpegPath, args...).Run() == nil
+}
@@ -0,0 +1,327 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	ffmpegbin "github.com/mgpai22/lipi/internal/ffmpeg"
+)
+
+// subtitle file to add as a soft subtitle track
+type SubtitleTrack struct {
+	Path     string
+	Language string // ISO 639 code or English language name
+	Title    string
+	Default  bool
+}
+
+// options for MuxSubtitles
+type MuxOptions struct {
+	// font files to attach for ASS tracks; Matroska outputs only. Fonts
+	// the video already has attached under the same file name are skipped.
+	Fonts []string
+}
+
+// MuxSubtitles writes a copy of videoPath to outputPath with the tracks
+// added as selectable subtitle streams. Audio and video are stream-copied;
+// subtitles are converted to the codec the output container supports.
+// Chapters, metadata, and, in Matroska outputs, attachments such as fonts
+// are kept.
+func (p *DefaultProcessor) MuxSubtitles(
+	ctx context.Context,
+	videoPath, outputPath string,
+	tracks []SubtitleTrack,
+	opts MuxOptions,
+) error {
+	if len(tracks) == 0 {
+		return fmt.Errorf("no subtitle tracks to embed")
+	}
+	if len(opts.Fonts) > 0 && !IsMatroska(outputPath) {
+		return fmt.Errorf("fonts can only be attached to Matroska (.mkv) outputs, got %s", outputPath)
+	}
+	if _, err := os.Stat(videoPath); os.IsNotExist(err) {
+		return fmt.Errorf("video file not found: %s", videoPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// new tracks are numbered after any subtitle streams already present
+	probe, err := ffmpegbin.Probe(ctx, videoPath)
+	if err != nil {
+		probe = &ffmpegbin.ProbeResult{}
+	}
+
+	ffmpegPath, err := ffmpegbin.FFmpegPath()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, ffmpegPath, muxSubtitleArgs(videoPath, outputPath, tracks, opts, probe)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errs.Wrap(errs.KindFFmpeg, fmt.Errorf(
+			"ffmpeg subtitle muxing failed: %w (%s)",
+			err,
+			strings.TrimSpace(string(out)),
+		))
+	}
+
+	return nil
+}
+
+// ffmpeg arguments for MuxSubtitles, given the streams the video has
+func muxSubtitleArgs(
+	videoPath, outputPath string,
+	tracks []SubtitleTrack,
+	opts MuxOptions,
+	probe *ffmpegbin.ProbeResult,
+) []string {
+	existing := len(probe.StreamsOfType("subtitle"))
+	attachments := probe.StreamsOfType("attachment")
+
+	args := []string{"-y", "-v", "error", "-i", videoPath}
+	for _, t := range tracks {
+		args = append(args, "-i", t.Path)
+	}
+	args = append(args, "-map", "0")
+	args = append(args, dropAttachments(outputPath, len(attachments))...)
+	for i := range tracks {
+		args = append(args, "-map", strconv.Itoa(i+1))
+	}
+	args = append(args,
+		"-map_metadata", "0",
+		"-map_chapters", "0",
+		"-c", "copy",
+		"-c:s", subtitleCodecFor(outputPath),
+	)
+
+	for i, t := range tracks {
+		stream := fmt.Sprintf("s:s:%d", existing+i)
+		if lang := LanguageCode(t.Language); lang != "" {
+			args = append(args, "-metadata:"+stream, "language="+lang)
+		}
+		if t.Title != "" {
+			args = append(args, "-metadata:"+stream, "title="+t.Title)
+		}
+		disposition := "0"
+		if t.Default {
+			disposition = "default"
+		}
+		args = append(args, "-disposition:"+stream[2:], disposition)
+	}
+
+	// attached streams are numbered after the ones copied from the video
+	attached := make(map[string]bool, len(attachments))
+	for _, a := range attachments {
+		attached[strings.ToLower(a.Filename)] = true
+	}
+	index := len(attachments)
+	for _, font := range opts.Fonts {
+		name := strings.ToLower(filepath.Base(font))
+		if attached[name] {
+			continue
+		}
+		attached[name] = true
+		args = append(args,
+			"-attach", font,
+			fmt.Sprintf("-metadata:s:t:%d", index), "mimetype="+fontMimeType(font),
+		)
+		index++
+	}
+	return append(args, outputPath)
+}
+
+// the -map arguments leaving out the video's attachments when the output
+// container cannot hold them
+func dropAttachments(outputPath string, attachments int) []string {
+	if attachments == 0 || IsMatroska(outputPath) {
+		return nil
+	}
+	return []string{"-map", "-0:t"}
+}
+
+// IsMatroska reports whether outputPath is a Matroska file, the one
+// container that holds attachments such as fonts
+func IsMatroska(outputPath string) bool {
+	switch strings.ToLower(filepath.Ext(outputPath)) {
+	case ".mkv", ".mka", ".mks":
+		return true
+	}
+	return false
+}
+
+// the MIME type Matroska players look for to load an attached font
+func fontMimeType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".otf", ".otc":
+		return "application/vnd.ms-opentype"
+	}
+	return "application/x-truetype-font"
+}
+
+// audio file to add as an audio track
+type AudioTrack struct {
+	Path     string
+	Language string // ISO 639 code or English language name
+	Title    string
+	// Replace drops the video's own audio tracks instead of keeping them
+	// behind the new one
+	Replace bool
+}
+
+// MuxAudio writes a copy of videoPath to outputPath with the track added
+// as the default audio stream. Other streams are stream-copied; the new
+// track is encoded for the output container. Chapters, metadata, and, in
+// Matroska outputs, attachments are kept.
+func (p *DefaultProcessor) MuxAudio(
+	ctx context.Context,
+	videoPath, outputPath string,
+	track AudioTrack,
+) error {
+	if _, err := os.Stat(videoPath); os.IsNotExist(err) {
+		return fmt.Errorf("video file not found: %s", videoPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	existing, attachments := 0, 0
+	if probe, err := ffmpegbin.Probe(ctx, videoPath); err == nil {
+		existing = len(probe.StreamsOfType("audio"))
+		attachments = len(probe.StreamsOfType("attachment"))
+	}
+
+	ffmpegPath, err := ffmpegbin.FFmpegPath()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, ffmpegPath, muxAudioArgs(videoPath, outputPath, track, existing, attachments)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errs.Wrap(errs.KindFFmpeg, fmt.Errorf(
+			"ffmpeg audio muxing failed: %w (%s)",
+			err,
+			strings.TrimSpace(string(out)),
+		))
+	}
+
+	return nil
+}
+
+// ffmpeg arguments for MuxAudio, given the number of audio streams and
+// attachments the video has
+func muxAudioArgs(videoPath, outputPath string, track AudioTrack, existing, attachments int) []string {
+	args := []string{
+		"-y", "-v", "error",
+		"-i", videoPath,
+		"-i", track.Path,
+		"-map", "0",
+	}
+	args = append(args, dropAttachments(outputPath, attachments)...)
+	// the new track is numbered after the audio streams kept
+	index := existing
+	if track.Replace {
+		args = append(args, "-map", "-0:a")
+		index = 0
+	}
+	stream := fmt.Sprintf("a:%d", index)
+	args = append(args,
+		"-map", "1:a:0",
+		"-map_metadata", "0",
+		"-map_chapters", "0",
+		"-c", "copy",
+		"-c:"+stream, audioCodecFor(outputPath),
+	)
+	if lang := LanguageCode(track.Language); lang != "" {
+		args = append(args, "-metadata:s:"+stream, "language="+lang)
+	}
+	if track.Title != "" {
+		args = append(args, "-metadata:s:"+stream, "title="+track.Title)
+	}
+	if !track.Replace {
+		for i := range existing {
+			args = append(args, fmt.Sprintf("-disposition:a:%d", i), "0")
+		}
+	}
+	args = append(args, "-disposition:"+stream, "default", outputPath)
+	return args
+}
+
+// picks an audio codec the output container can hold
+func audioCodecFor(outputPath string) string {
+	if strings.EqualFold(filepath.Ext(outputPath), ".webm") {
+		return "libopus"
+	}
+	return "aac"
+}
+
+// picks a subtitle codec the output container can hold
+func subtitleCodecFor(outputPath string) string {
+	switch strings.ToLower(filepath.Ext(outputPath)) {
+	case ".mp4", ".m4v", ".mov":
+		return "mov_text"
+	case ".webm":
+		return "webvtt"
+	default:
+		// Matroska and most others keep SRT/ASS as-is
+		return "copy"
+	}
+}
+
+var languageCodes = map[string]string{
+	"ar": "ara", "arabic": "ara",
+	"de": "ger", "german": "ger",
+	"en": "eng", "english": "eng",
+	"es": "spa", "spanish": "spa",
+	"fr": "fre", "french": "fre",
+	"hi": "hin", "hindi": "hin",
+	"it": "ita", "italian": "ita",
+	"ja": "jpn", "japanese": "jpn",
+	"ko": "kor", "korean": "kor",
+	"nl": "dut", "dutch": "dut",
+	"pl": "pol", "polish": "pol",
+	"pt": "por", "portuguese": "por",
+	"ru": "rus", "russian": "rus",
+	"sv": "swe", "swedish": "swe",
+	"tr": "tur", "turkish": "tur",
+	"uk": "ukr", "ukrainian": "ukr",
+	"vi": "vie", "vietnamese": "vie",
+	"zh": "chi", "chinese": "chi",
+}
+
+// ShortLanguageCode converts a language name or two-letter code to the ISO
+// 639-1 code media servers expect in subtitle file names. Anything else,
+// such as a regional tag like pt-BR, is returned trimmed.
+func ShortLanguageCode(lang string) string {
+	lower := strings.ToLower(strings.TrimSpace(lang))
+	code, ok := languageCodes[lower]
+	if !ok {
+		return strings.TrimSpace(lang)
+	}
+	for short, long := range languageCodes {
+		if len(short) == 2 && long == code {
+			return short
+		}
+	}
+	return lower
+}
+
+// LanguageCode converts a language code or name to the ISO 639-2 code
+// players expect in stream metadata. Unknown three-letter codes pass
+// through; anything else is dropped.
+func LanguageCode(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if code, ok := languageCodes[lang]; ok {
+		return code
+	}
+	if len(lang) == 3 {
+		return lang
+	}
+	return ""
+}
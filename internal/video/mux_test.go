@@ -0,0 +1,228 @@
+package video
+
+import (
+	"strings"
+	"testing"
+
+	ffmpegbin "github.com/mgpai22/lipi/internal/ffmpeg"
+)
+
+func TestSubtitleCodecFor(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"movie.mkv", "copy"},
+		{"movie.MP4", "mov_text"},
+		{"clip.mov", "mov_text"},
+		{"clip.webm", "webvtt"},
+	}
+
+	for _, tt := range tests {
+		if got := subtitleCodecFor(tt.path); got != tt.want {
+			t.Errorf("subtitleCodecFor(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMuxAudioArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      string
+		replace     bool
+		existing    int
+		attachments int
+		want        []string
+		absent      []string
+	}{
+		{
+			name:     "kept",
+			output:   "out.mkv",
+			existing: 2,
+			want: []string{
+				"-map 0 -map 1:a:0 -map_metadata 0 -map_chapters 0 -c copy -c:a:2 aac",
+				"-metadata:s:a:2 language=spa",
+				"-disposition:a:0 0 -disposition:a:1 0 -disposition:a:2 default out.mkv",
+			},
+			absent: []string{"-map -0:a"},
+		},
+		{
+			name:        "replaced",
+			output:      "out.webm",
+			replace:     true,
+			existing:    1,
+			attachments: 2,
+			want: []string{
+				"-map 0 -map -0:t -map -0:a -map 1:a:0 -map_metadata 0 -map_chapters 0 -c copy -c:a:0 libopus",
+				"-disposition:a:0 default out.webm",
+			},
+			absent: []string{"-disposition:a:0 0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := strings.Join(muxAudioArgs("in.mkv", tt.output, AudioTrack{
+				Path:     "dub.wav",
+				Language: "es",
+				Replace:  tt.replace,
+			}, tt.existing, tt.attachments), " ")
+			for _, want := range tt.want {
+				if !strings.Contains(args, want) {
+					t.Errorf("muxAudioArgs() = %s, want %s", args, want)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(args, absent) {
+					t.Errorf("muxAudioArgs() = %s, want no %s", args, absent)
+				}
+			}
+		})
+	}
+}
+
+func TestMuxSubtitleArgs(t *testing.T) {
+	probe := &ffmpegbin.ProbeResult{Streams: []ffmpegbin.Stream{
+		{CodecType: "video"},
+		{CodecType: "subtitle"},
+		{CodecType: "attachment", Filename: "NotoSansJP.otf"},
+	}}
+	tracks := []SubtitleTrack{{Path: "ja.ass", Language: "ja", Default: true}}
+
+	tests := []struct {
+		name   string
+		output string
+		fonts  []string
+		want   []string
+		absent []string
+	}{
+		{
+			name:   "matroska",
+			output: "out.mkv",
+			fonts:  []string{"fonts/NotoSansJP.otf", "fonts/Gothic.ttf"},
+			want: []string{
+				"-map 0 -map 1 -map_metadata 0 -map_chapters 0 -c copy -c:s copy",
+				"-metadata:s:s:1 language=jpn -disposition:s:1 default",
+				"-attach fonts/Gothic.ttf -metadata:s:t:1 mimetype=application/x-truetype-font out.mkv",
+			},
+			absent: []string{"-map -0:t", "-attach fonts/NotoSansJP.otf"},
+		},
+		{
+			name:   "mp4",
+			output: "out.mp4",
+			want: []string{
+				"-map 0 -map -0:t -map 1 -map_metadata 0 -map_chapters 0 -c copy -c:s mov_text",
+			},
+			absent: []string{"-attach"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := strings.Join(muxSubtitleArgs("in.mkv", tt.output, tracks, MuxOptions{Fonts: tt.fonts}, probe), " ")
+			for _, want := range tt.want {
+				if !strings.Contains(args, want) {
+					t.Errorf("muxSubtitleArgs() = %s, want %s", args, want)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(args, absent) {
+					t.Errorf("muxSubtitleArgs() = %s, want no %s", args, absent)
+				}
+			}
+		})
+	}
+}
+
+func TestLanguageCode(t *testing.T) {
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{"en", "eng"},
+		{"Spanish", "spa"},
+		{" ja ", "jpn"},
+		{"tgl", "tgl"},
+		{"native", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := LanguageCode(tt.lang); got != tt.want {
+			t.Errorf("LanguageCode(%q) = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestShortLanguageCode(t *testing.T) {
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{"en", "en"},
+		{"Japanese", "ja"},
+		{" spanish ", "es"},
+		{"pt-BR", "pt-BR"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ShortLanguageCode(tt.lang); got != tt.want {
+			t.Errorf("ShortLanguageCode(%q) = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestParseHWAccel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    HWAccel
+		wantErr bool
+	}{
+		{"", HWAccelAuto, false},
+		{"auto", HWAccelAuto, false},
+		{"NVENC", HWAccelNVENC, false},
+		{" vaapi ", HWAccelVAAPI, false},
+		{"none", HWAccelNone, false},
+		{"qsv", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseHWAccel(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseHWAccel(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestBurnArgs(t *testing.T) {
+	tests := []struct {
+		accel  HWAccel
+		codec  string
+		filter string
+	}{
+		{HWAccelNone, "libx264", "subtitles=subtitles.srt"},
+		{HWAccelNVENC, "h264_nvenc", "subtitles=subtitles.srt"},
+		{HWAccelVAAPI, "h264_vaapi", "subtitles=subtitles.srt,format=nv12,hwupload"},
+		{HWAccelVideoToolbox, "h264_videotoolbox", "subtitles=subtitles.srt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.accel), func(t *testing.T) {
+			args, err := burnArgs("in.mkv", "out.mkv", "subtitles.srt", tt.accel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			joined := strings.Join(args, " ")
+			if !strings.Contains(joined, "-c:v "+tt.codec) || !strings.Contains(joined, "-vf "+tt.filter) {
+				t.Errorf("burnArgs() = %v, want codec %s and filter %s", args, tt.codec, tt.filter)
+			}
+			if !strings.Contains(joined, "-c:a copy") {
+				t.Errorf("burnArgs() = %v, want audio stream-copied", args)
+			}
+		})
+	}
+
+	if _, err := burnArgs("in.mkv", "out.mkv", "subtitles.srt", HWAccelAuto); err == nil {
+		t.Error("expected auto to be resolved before building arguments")
+	}
+}
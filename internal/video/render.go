@@ -0,0 +1,180 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	ffmpegbin "github.com/mgpai22/lipi/internal/ffmpeg"
+	"github.com/mgpai22/lipi/internal/fonts"
+)
+
+// options for rendering a single frame of subtitles
+type RenderOptions struct {
+	// video to draw the subtitles over; a black canvas when empty
+	VideoPath string
+	// canvas size without a video
+	Width, Height int
+	// directory of more fonts for the renderer
+	FontsDir string
+}
+
+// the canvas size without a video or a script resolution
+const (
+	DefaultCanvasWidth  = 1920
+	DefaultCanvasHeight = 1080
+)
+
+// RenderFrame writes an image of the subtitles as shown at the given time,
+// drawn by ffmpeg's libass renderer over the video frame at that time or
+// over a black canvas. The fonts attached to a Matroska video are used as
+// they are when it plays.
+func (p *DefaultProcessor) RenderFrame(
+	ctx context.Context,
+	subtitlePath, outputPath string,
+	at time.Duration,
+	opts RenderOptions,
+) error {
+	if opts.VideoPath != "" {
+		if _, err := os.Stat(opts.VideoPath); os.IsNotExist(err) {
+			return fmt.Errorf("video file not found: %s", opts.VideoPath)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	ffmpegPath, err := ffmpegbin.FFmpegPath()
+	if err != nil {
+		return err
+	}
+
+	// staged like a burn-in, so the filter arguments need no escaping
+	stageDir, err := os.MkdirTemp(p.tempDir, "render-*")
+	if err != nil {
+		return fmt.Errorf("failed to create render directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(stageDir) }()
+	data, err := os.ReadFile(subtitlePath)
+	if err != nil {
+		return fmt.Errorf("failed to read subtitles: %w", err)
+	}
+	subtitleName := "subtitles" + strings.ToLower(filepath.Ext(subtitlePath))
+	if err := os.WriteFile(filepath.Join(stageDir, subtitleName), data, 0o644); err != nil {
+		return fmt.Errorf("failed to stage subtitles: %w", err)
+	}
+
+	fontsDir := ""
+	if opts.FontsDir != "" {
+		if fontsDir, err = filepath.Abs(opts.FontsDir); err != nil {
+			return err
+		}
+	}
+	if opts.VideoPath != "" && IsMatroska(opts.VideoPath) {
+		attached := filepath.Join(stageDir, "fonts")
+		if err := p.ExtractAttachments(ctx, opts.VideoPath, attached); err != nil {
+			return err
+		}
+		if fontsDir != "" {
+			if err := copyFonts(fontsDir, attached); err != nil {
+				return err
+			}
+		}
+		fontsDir = "fonts"
+	}
+
+	args, err := renderArgs(subtitleName, outputPath, at, fontsDir, opts)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+	cmd.Dir = stageDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errs.Wrap(errs.KindFFmpeg, fmt.Errorf(
+			"ffmpeg subtitle render failed: %w (%s)",
+			err,
+			strings.TrimSpace(string(out)),
+		))
+	}
+	if info, err := os.Stat(outputPath); err != nil || info.Size() == 0 {
+		return errs.Wrap(errs.KindFFmpeg, fmt.Errorf("ffmpeg rendered no frame at %s", at))
+	}
+	return nil
+}
+
+// ffmpeg arguments for rendering one frame; the subtitle file and a
+// relative fonts directory are relative to the working directory
+func renderArgs(
+	subtitleName, outputPath string,
+	at time.Duration,
+	fontsDir string,
+	opts RenderOptions,
+) ([]string, error) {
+	outputPath, err := filepath.Abs(outputPath)
+	if err != nil {
+		return nil, err
+	}
+	seconds := strconv.FormatFloat(at.Seconds(), 'f', 3, 64)
+
+	filter := "subtitles=" + subtitleName
+	if fontsDir != "" {
+		filter += ":fontsdir=" + fontsDir
+	}
+
+	args := []string{"-y", "-v", "error"}
+	if opts.VideoPath != "" {
+		videoPath, err := filepath.Abs(opts.VideoPath)
+		if err != nil {
+			return nil, err
+		}
+		// the input is seeked, but -copyts keeps the frame at its own time,
+		// which is the time the subtitles are drawn for
+		args = append(args,
+			"-ss", seconds, "-copyts",
+			"-i", videoPath,
+			"-map", "0:v:0",
+			"-vf", filter,
+		)
+	} else {
+		width, height := opts.Width, opts.Height
+		if width <= 0 || height <= 0 {
+			width, height = DefaultCanvasWidth, DefaultCanvasHeight
+		}
+		// the canvas starts at 0, so its first frame is moved to the time
+		// instead of generating every frame before it
+		args = append(args,
+			"-f", "lavfi",
+			"-i", fmt.Sprintf("color=c=black:s=%dx%d:r=25", width, height),
+			"-vf", "setpts=PTS+"+seconds+"/TB,"+filter,
+		)
+	}
+	args = append(args, "-frames:v", "1", "-update", "1", outputPath)
+	return args, nil
+}
+
+// copies the font files of src into dst, alongside the attached fonts
+func copyFonts(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return fmt.Errorf("failed to read fonts directory: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || !fonts.IsFontFile(e.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(src, e.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read font: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dst, e.Name()), data, 0o644); err != nil {
+			return fmt.Errorf("failed to stage font: %w", err)
+		}
+	}
+	return nil
+}
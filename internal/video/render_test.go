@@ -0,0 +1,64 @@
+package video
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		fontsDir string
+		opts     RenderOptions
+		want     []string
+		absent   []string
+	}{
+		{
+			name: "video",
+			opts: RenderOptions{VideoPath: "episode.mkv"},
+			want: []string{
+				"-ss 312.500 -copyts -i ",
+				"-map 0:v:0 -vf subtitles=subtitles.ass -frames:v 1",
+			},
+			absent: []string{"lavfi", "setpts"},
+		},
+		{
+			name:     "canvas",
+			fontsDir: "fonts",
+			opts:     RenderOptions{Width: 1280, Height: 720},
+			want: []string{
+				"-f lavfi -i color=c=black:s=1280x720:r=25",
+				"-vf setpts=PTS+312.500/TB,subtitles=subtitles.ass:fontsdir=fonts -frames:v 1",
+			},
+			absent: []string{"-ss", "-copyts"},
+		},
+		{
+			name: "default canvas",
+			want: []string{"color=c=black:s=1920x1080:r=25"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := renderArgs("subtitles.ass", "frame.png", 312500*time.Millisecond, tt.fontsDir, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Join(args, " ")
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("args %q missing %q", got, want)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(got, absent) {
+					t.Errorf("args %q contain %q", got, absent)
+				}
+			}
+			if !strings.HasSuffix(got, "frame.png") {
+				t.Errorf("args %q do not end in the output", got)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	ffmpeg "github.com/u2takey/ffmpeg-go"
 
+	"github.com/mgpai22/lipi/internal/errs"
 	ffmpegbin "github.com/mgpai22/lipi/internal/ffmpeg"
 )
 
@@ -34,14 +35,44 @@ type Processor interface {
 
 	// retrieves video file information
 	GetInfo(ctx context.Context, videoPath string) (*Info, error)
+
+	// adds soft subtitle tracks to a copy of the video
+	MuxSubtitles(
+		ctx context.Context,
+		videoPath, outputPath string,
+		tracks []SubtitleTrack,
+		opts MuxOptions,
+	) error
+
+	// saves the attachments of the video, such as fonts, into a directory
+	ExtractAttachments(ctx context.Context, videoPath, dir string) error
+
+	// renders subtitles into the picture of a re-encoded copy of the video
+	BurnSubtitles(
+		ctx context.Context,
+		videoPath, outputPath, subtitlePath string,
+		opts BurnOptions,
+	) error
+
+	// renders the subtitles shown at a time into an image
+	RenderFrame(
+		ctx context.Context,
+		subtitlePath, outputPath string,
+		at time.Duration,
+		opts RenderOptions,
+	) error
 }
 
 // holds options for audio extraction
 type ExtractAudioOptions struct {
-	Format     string // Output format (wav, mp3, aac, flac)
+	Format     string // Output format (wav, mp3, aac, opus, flac)
 	SampleRate int    // Sample rate in Hz (e.g., 16000, 44100, 48000)
 	Channels   int    // Number of channels (1 = mono, 2 = stereo)
 	Bitrate    string // Bitrate for lossy formats (e.g., "128k", "320k")
+	// skip corrupt packets and decode errors instead of failing
+	IgnoreDecodeErrors bool
+	// excerpt of the video to extract: from Start, for Length when set
+	Start, Length time.Duration
 }
 
 // returns sensible defaults for audio extraction
@@ -106,6 +137,11 @@ func (p *DefaultProcessor) ExtractAudio(
 		if opts.Bitrate != "" {
 			kwargs["b:a"] = opts.Bitrate
 		}
+	case "opus":
+		kwargs["acodec"] = "libopus"
+		if opts.Bitrate != "" {
+			kwargs["b:a"] = opts.Bitrate
+		}
 	case "flac":
 		kwargs["acodec"] = "flac"
 	case "wav":
@@ -119,14 +155,29 @@ func (p *DefaultProcessor) ExtractAudio(
 		return err
 	}
 
-	err = ffmpeg.Input(videoPath).
+	var inputArgs []ffmpeg.KwArgs
+	if opts.IgnoreDecodeErrors {
+		inputArgs = append(inputArgs, ffmpegbin.TolerantInputArgs())
+		kwargs["af"] = ffmpegbin.ResyncAudioFilter
+	}
+	if opts.Start > 0 {
+		inputArgs = append(inputArgs, ffmpeg.KwArgs{"ss": opts.Start.Seconds()})
+	}
+	if opts.Length > 0 {
+		kwargs["t"] = opts.Length.Seconds()
+	}
+	cmd := ffmpeg.Input(videoPath, inputArgs...).
 		Output(outputPath, kwargs).
 		OverWriteOutput().
 		SetFfmpegPath(ffmpegPath).
-		Run()
+		Compile()
+	err = ffmpegbin.RunContext(ctx, cmd)
 
 	if err != nil {
-		return fmt.Errorf("ffmpeg extraction failed: %w", err)
+		return errs.Wrap(
+			errs.KindFFmpeg,
+			fmt.Errorf("ffmpeg extraction failed: %w", err),
+		)
 	}
 
 	return nil
@@ -137,6 +188,22 @@ func (p *DefaultProcessor) GetInfo(
 	ctx context.Context,
 	videoPath string,
 ) (*Info, error) {
-	//TODO: Implement
-	return nil, nil
+	probe, err := ffmpegbin.Probe(ctx, videoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe video: %w", err)
+	}
+
+	info := &Info{
+		Path:     videoPath,
+		Duration: probe.Duration,
+		HasAudio: probe.HasAudio(),
+	}
+	if streams := probe.StreamsOfType("video"); len(streams) > 0 {
+		info.Width = streams[0].Width
+		info.Height = streams[0].Height
+		info.FrameRate = streams[0].FrameRate
+		info.Codec = streams[0].CodecName
+	}
+
+	return info, nil
 }
@@ -0,0 +1,262 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+// Caption is a caption track of a video
+type Caption struct {
+	ID       string `json:"id"`
+	Language string `json:"language"`
+	Name     string `json:"name"`
+	Draft    bool   `json:"is_draft"`
+}
+
+// Upload is a caption file to add to a video
+type Upload struct {
+	VideoID  string
+	Language string // BCP-47 code, e.g. "es" or "pt-BR"
+	Name     string // track name shown in the player, empty for none
+	Draft    bool   // keep the track unpublished
+	Data     []byte // SRT or WebVTT
+}
+
+// resource as the captions API sends and accepts it
+type captionResource struct {
+	ID      string         `json:"id,omitempty"`
+	Snippet captionSnippet `json:"snippet"`
+}
+
+type captionSnippet struct {
+	VideoID  string `json:"videoId,omitempty"`
+	Language string `json:"language,omitempty"`
+	Name     string `json:"name"`
+	IsDraft  bool   `json:"isDraft"`
+}
+
+// error body of the YouTube Data API
+type apiError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Errors  []struct {
+			Reason string `json:"reason"`
+		} `json:"errors"`
+	} `json:"error"`
+}
+
+// whether the API refused the request for the project's daily quota,
+// which it reports as 403
+func (e apiError) quotaExceeded() bool {
+	for _, detail := range e.Error.Errors {
+		if detail.Reason == "quotaExceeded" || detail.Reason == "rateLimitExceeded" {
+			return true
+		}
+	}
+	return false
+}
+
+func (r captionResource) caption() Caption {
+	return Caption{
+		ID:       r.ID,
+		Language: r.Snippet.Language,
+		Name:     r.Snippet.Name,
+		Draft:    r.Snippet.IsDraft,
+	}
+}
+
+// ListCaptions returns the caption tracks of a video
+func (c *Client) ListCaptions(ctx context.Context, accessToken, videoID string) ([]Caption, error) {
+	query := url.Values{"part": {"snippet"}, "videoId": {videoID}}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		c.apiURL+"/captions?"+query.Encode(),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Items []captionResource `json:"items"`
+	}
+	if err := c.do(req, accessToken, &list); err != nil {
+		return nil, fmt.Errorf("failed to list captions: %w", err)
+	}
+	captions := make([]Caption, len(list.Items))
+	for i, item := range list.Items {
+		captions[i] = item.caption()
+	}
+	return captions, nil
+}
+
+// UploadCaption adds a caption track to a video
+func (c *Client) UploadCaption(ctx context.Context, accessToken string, upload Upload) (Caption, error) {
+	resource := captionResource{Snippet: captionSnippet{
+		VideoID:  upload.VideoID,
+		Language: upload.Language,
+		Name:     upload.Name,
+		IsDraft:  upload.Draft,
+	}}
+	caption, err := c.sendCaption(ctx, http.MethodPost, accessToken, resource, upload.Data)
+	if err != nil {
+		return Caption{}, fmt.Errorf("failed to upload captions: %w", err)
+	}
+	return caption, nil
+}
+
+// UpdateCaption replaces the file and draft state of an existing track
+func (c *Client) UpdateCaption(ctx context.Context, accessToken, id string, upload Upload) (Caption, error) {
+	resource := captionResource{ID: id, Snippet: captionSnippet{
+		Name:    upload.Name,
+		IsDraft: upload.Draft,
+	}}
+	caption, err := c.sendCaption(ctx, http.MethodPut, accessToken, resource, upload.Data)
+	if err != nil {
+		return Caption{}, fmt.Errorf("failed to update captions: %w", err)
+	}
+	return caption, nil
+}
+
+// sends the resource and file as one multipart/related upload
+func (c *Client) sendCaption(
+	ctx context.Context,
+	method, accessToken string,
+	resource captionResource,
+	data []byte,
+) (Caption, error) {
+	metadata, err := json.Marshal(resource)
+	if err != nil {
+		return Caption{}, err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		data        []byte
+	}{
+		{"application/json; charset=UTF-8", metadata},
+		{"application/octet-stream", data},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return Caption{}, err
+		}
+		if _, err := w.Write(part.data); err != nil {
+			return Caption{}, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return Caption{}, err
+	}
+
+	query := url.Values{"part": {"snippet"}, "uploadType": {"multipart"}}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		method,
+		c.uploadURL+"/captions?"+query.Encode(),
+		&body,
+	)
+	if err != nil {
+		return Caption{}, err
+	}
+	req.Header.Set("Content-Type", "multipart/related; boundary="+mw.Boundary())
+
+	var created captionResource
+	if err := c.do(req, accessToken, &created); err != nil {
+		return Caption{}, err
+	}
+	return created.caption(), nil
+}
+
+// sends an authorized API request and decodes the JSON reply into out.
+// Rejected tokens and missing permissions are auth errors, unknown videos
+// and tracks input errors.
+func (c *Client) do(req *http.Request, accessToken string, out any) error {
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return json.Unmarshal(body, out)
+	}
+
+	message := resp.Status
+	var apiErr apiError
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Error.Message != "" {
+		message = fmt.Sprintf("%s: %s", resp.Status, apiErr.Error.Message)
+	}
+	err = errors.New(message)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, apiErr.quotaExceeded():
+		return errs.Wrap(errs.KindRateLimit, err)
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		return errs.Wrap(errs.KindAuth, err)
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusBadRequest:
+		return errs.Wrap(errs.KindInput, err)
+	}
+	return err
+}
+
+// ParseVideoID accepts a video ID or a watch, share, shorts, or embed URL
+// and returns the ID
+func ParseVideoID(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if validVideoID(s) {
+		return s, nil
+	}
+	u, err := url.Parse(s)
+	if err == nil && u.Host != "" {
+		host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+		path := strings.Trim(u.Path, "/")
+		var id string
+		switch {
+		case host == "youtu.be":
+			id = path
+		case host == "youtube.com" || host == "m.youtube.com" || host == "music.youtube.com":
+			if path == "watch" {
+				id = u.Query().Get("v")
+			} else if prefix, rest, ok := strings.Cut(path, "/"); ok &&
+				(prefix == "shorts" || prefix == "embed" || prefix == "live") {
+				id = rest
+			}
+		}
+		if validVideoID(id) {
+			return id, nil
+		}
+	}
+	return "", errs.Wrap(errs.KindInput, fmt.Errorf("not a YouTube video ID or URL: %q", s))
+}
+
+// video IDs are 11 characters of URL-safe base64
+func validVideoID(s string) bool {
+	if len(s) != 11 {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,198 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mgpai22/lipi/internal/errs"
+	"github.com/mgpai22/lipi/internal/httpclient"
+)
+
+// Scope is the OAuth scope caption uploads need
+const Scope = "https://www.googleapis.com/auth/youtube.force-ssl"
+
+const (
+	deviceCodeURL = "https://oauth2.googleapis.com/device/code"
+	tokenURL      = "https://oauth2.googleapis.com/token"
+	apiURL        = "https://www.googleapis.com/youtube/v3"
+	uploadURL     = "https://www.googleapis.com/upload/youtube/v3"
+)
+
+// default wait between token polls when Google does not name one
+const defaultPollInterval = 5 * time.Second
+
+// ErrInvalidGrant is returned when a refresh token was revoked or expired,
+// and the device flow has to run again
+var ErrInvalidGrant = errors.New("refresh token was revoked or expired")
+
+// Client talks to Google's OAuth endpoints and the YouTube Data API with an
+// OAuth client of the "TVs and Limited Input devices" type
+type Client struct {
+	http         *http.Client
+	clientID     string
+	clientSecret string
+
+	// endpoints, replaced in tests
+	deviceCodeURL, tokenURL, apiURL, uploadURL string
+}
+
+// New returns a client for the OAuth client clientID; a nil httpClient
+// uses the default client
+func New(httpClient *http.Client, clientID, clientSecret string) *Client {
+	return &Client{
+		http:          httpclient.Or(httpClient),
+		clientID:      clientID,
+		clientSecret:  clientSecret,
+		deviceCodeURL: deviceCodeURL,
+		tokenURL:      tokenURL,
+		apiURL:        apiURL,
+		uploadURL:     uploadURL,
+	}
+}
+
+// DeviceCode is a pending authorization: the user enters UserCode at
+// VerificationURL while the client polls for the token
+type DeviceCode struct {
+	DeviceCode      string        `json:"device_code"`
+	UserCode        string        `json:"user_code"`
+	VerificationURL string        `json:"verification_url"`
+	ExpiresIn       int           `json:"expires_in"`
+	Interval        time.Duration `json:"-"`
+}
+
+// Token is an OAuth token. RefreshToken is only set by the device flow.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// error body of Google's token endpoints
+type tokenError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// RequestDeviceCode starts the device flow
+func (c *Client) RequestDeviceCode(ctx context.Context) (*DeviceCode, error) {
+	var resp struct {
+		DeviceCode
+		Interval int `json:"interval"`
+	}
+	err := c.postForm(ctx, c.deviceCodeURL, url.Values{
+		"client_id": {c.clientID},
+		"scope":     {Scope},
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start device authorization: %w", err)
+	}
+	code := resp.DeviceCode
+	code.Interval = time.Duration(resp.Interval) * time.Second
+	if code.Interval <= 0 {
+		code.Interval = defaultPollInterval
+	}
+	return &code, nil
+}
+
+// PollToken waits until the user approves code and returns the token, or
+// fails when they deny it or the code expires
+func (c *Client) PollToken(ctx context.Context, code *DeviceCode) (*Token, error) {
+	interval := code.Interval
+	for {
+		var token Token
+		err := c.postForm(ctx, c.tokenURL, url.Values{
+			"client_id":     {c.clientID},
+			"client_secret": {c.clientSecret},
+			"device_code":   {code.DeviceCode},
+			"grant_type":    {"urn:ietf:params:oauth:grant-type:device_code"},
+		}, &token)
+		var tokenErr *tokenError
+		switch {
+		case err == nil:
+			return &token, nil
+		case !errors.As(err, &tokenErr):
+			return nil, fmt.Errorf("failed to get token: %w", err)
+		case tokenErr.Code == "authorization_pending":
+		case tokenErr.Code == "slow_down":
+			interval += defaultPollInterval
+		case tokenErr.Code == "access_denied":
+			return nil, errs.Wrap(errs.KindAuth, errors.New("authorization was denied"))
+		case tokenErr.Code == "expired_token":
+			return nil, errs.Wrap(errs.KindAuth, errors.New("the code expired before it was entered; run the command again"))
+		default:
+			return nil, errs.Wrap(errs.KindAuth, fmt.Errorf("failed to get token: %w", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Refresh exchanges a stored refresh token for an access token
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	var token Token
+	err := c.postForm(ctx, c.tokenURL, url.Values{
+		"client_id":     {c.clientID},
+		"client_secret": {c.clientSecret},
+		"refresh_token": {refreshToken},
+		"grant_type":    {"refresh_token"},
+	}, &token)
+	var tokenErr *tokenError
+	if errors.As(err, &tokenErr) && tokenErr.Code == "invalid_grant" {
+		return nil, errs.Wrap(errs.KindAuth, ErrInvalidGrant)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	return &token, nil
+}
+
+func (e *tokenError) Error() string {
+	if e.Description != "" {
+		return e.Code + ": " + e.Description
+	}
+	return e.Code
+}
+
+// posts a form and decodes the JSON reply into out; an OAuth error reply
+// is returned as *tokenError
+func (c *Client) postForm(ctx context.Context, endpoint string, form url.Values, out any) error {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		endpoint,
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var tokenErr tokenError
+		if json.Unmarshal(body, &tokenErr) == nil && tokenErr.Code != "" {
+			return &tokenErr
+		}
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,185 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mgpai22/lipi/internal/errs"
+)
+
+// client whose endpoints all point at server
+func testClient(server *httptest.Server) *Client {
+	c := New(server.Client(), "id", "secret")
+	c.deviceCodeURL = server.URL + "/device/code"
+	c.tokenURL = server.URL + "/token"
+	c.apiURL = server.URL + "/youtube/v3"
+	c.uploadURL = server.URL + "/upload/youtube/v3"
+	return c
+}
+
+func TestDeviceFlow(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		switch r.URL.Path {
+		case "/device/code":
+			if r.Form.Get("scope") != Scope {
+				t.Errorf("scope = %q", r.Form.Get("scope"))
+			}
+			_, _ = io.WriteString(w, `{"device_code":"dev","user_code":"ABCD-EFGH","verification_url":"https://www.google.com/device","expires_in":1800}`)
+		case "/token":
+			polls++
+			if polls == 1 {
+				w.WriteHeader(http.StatusPreconditionRequired)
+				_, _ = io.WriteString(w, `{"error":"authorization_pending"}`)
+				return
+			}
+			if r.Form.Get("device_code") != "dev" {
+				t.Errorf("device_code = %q", r.Form.Get("device_code"))
+			}
+			_, _ = io.WriteString(w, `{"access_token":"access","refresh_token":"refresh","expires_in":3599}`)
+		}
+	}))
+	defer server.Close()
+	client := testClient(server)
+
+	code, err := client.RequestDeviceCode(context.Background())
+	if err != nil {
+		t.Fatalf("RequestDeviceCode() error = %v", err)
+	}
+	if code.UserCode != "ABCD-EFGH" || code.Interval != defaultPollInterval {
+		t.Errorf("RequestDeviceCode() = %+v", code)
+	}
+
+	code.Interval = 0
+	token, err := client.PollToken(context.Background(), code)
+	if err != nil {
+		t.Fatalf("PollToken() error = %v", err)
+	}
+	if token.AccessToken != "access" || token.RefreshToken != "refresh" || polls != 2 {
+		t.Errorf("PollToken() = %+v after %d polls", token, polls)
+	}
+}
+
+func TestRefreshRevoked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"error":"invalid_grant","error_description":"Token has been expired or revoked."}`)
+	}))
+	defer server.Close()
+
+	_, err := testClient(server).Refresh(context.Background(), "old")
+	if !errors.Is(err, ErrInvalidGrant) || errs.KindOf(err) != errs.KindAuth {
+		t.Errorf("Refresh() error = %v, want ErrInvalidGrant", err)
+	}
+}
+
+func TestUploadCaption(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/upload/youtube/v3/captions" || r.Method != http.MethodPost {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer access" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "multipart/related" {
+			t.Fatalf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		mr := multipart.NewReader(r.Body, params["boundary"])
+
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resource captionResource
+		if err := json.NewDecoder(part).Decode(&resource); err != nil {
+			t.Fatal(err)
+		}
+		if resource.Snippet.VideoID != "dQw4w9WgXcQ" || resource.Snippet.Language != "es" ||
+			!resource.Snippet.IsDraft {
+			t.Errorf("snippet = %+v", resource.Snippet)
+		}
+		part, err = mr.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := io.ReadAll(part); string(data) != "1\n" {
+			t.Errorf("caption file = %q", data)
+		}
+
+		_, _ = io.WriteString(w, `{"id":"cap1","snippet":{"videoId":"dQw4w9WgXcQ","language":"es","name":"","isDraft":true}}`)
+	}))
+	defer server.Close()
+
+	caption, err := testClient(server).UploadCaption(context.Background(), "access", Upload{
+		VideoID:  "dQw4w9WgXcQ",
+		Language: "es",
+		Draft:    true,
+		Data:     []byte("1\n"),
+	})
+	if err != nil {
+		t.Fatalf("UploadCaption() error = %v", err)
+	}
+	if caption.ID != "cap1" || !caption.Draft {
+		t.Errorf("UploadCaption() = %+v", caption)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   errs.Kind
+	}{
+		{"expired token", http.StatusUnauthorized, `{"error":{"code":401,"message":"Invalid Credentials"}}`, errs.KindAuth},
+		{"not the owner", http.StatusForbidden, `{"error":{"code":403,"message":"forbidden","errors":[{"reason":"forbidden"}]}}`, errs.KindAuth},
+		{"quota", http.StatusForbidden, `{"error":{"code":403,"message":"quota","errors":[{"reason":"quotaExceeded"}]}}`, errs.KindRateLimit},
+		{"unknown video", http.StatusNotFound, `{"error":{"code":404,"message":"videoNotFound"}}`, errs.KindInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer server.Close()
+
+			_, err := testClient(server).ListCaptions(context.Background(), "access", "dQw4w9WgXcQ")
+			if err == nil || errs.KindOf(err) != tt.want {
+				t.Errorf("ListCaptions() error = %v (kind %s), want kind %s", err, errs.KindOf(err), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVideoID(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"dQw4w9WgXcQ", "dQw4w9WgXcQ", false},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42", "dQw4w9WgXcQ", false},
+		{"https://youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ", false},
+		{"https://youtube.com/shorts/dQw4w9WgXcQ", "dQw4w9WgXcQ", false},
+		{"https://vimeo.com/12345678901", "", true},
+		{"not-an-id", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseVideoID(tt.input)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseVideoID(%q) = %q, %v", tt.input, got, err)
+			}
+		})
+	}
+}
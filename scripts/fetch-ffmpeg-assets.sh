@@ -3,14 +3,19 @@ set -euo pipefail
 
 ROOT_DIR=$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)
 ASSET_DIR="$ROOT_DIR/internal/ffmpeg/assets"
+SUMS_FILE="$ROOT_DIR/internal/ffmpeg/checksums.txt"
 VERSION="6.1"
 BASE_URL="https://github.com/ffbinaries/ffbinaries-prebuilt/releases/download"
 
 ASSETS=(
   "ffmpeg-${VERSION}-linux-64.zip"
   "ffprobe-${VERSION}-linux-64.zip"
+  "ffmpeg-${VERSION}-linux-32.zip"
+  "ffprobe-${VERSION}-linux-32.zip"
   "ffmpeg-${VERSION}-linux-arm-64.zip"
   "ffprobe-${VERSION}-linux-arm-64.zip"
+  "ffmpeg-${VERSION}-linux-armhf-32.zip"
+  "ffprobe-${VERSION}-linux-armhf-32.zip"
   "ffmpeg-${VERSION}-macos-64.zip"
   "ffprobe-${VERSION}-macos-64.zip"
   "ffmpeg-${VERSION}-win-64.zip"
@@ -23,5 +28,14 @@ for asset in "${ASSETS[@]}"; do
   url="${BASE_URL}/v${VERSION}/${asset}"
   echo "Downloading ${asset}..."
   curl -fsSL -o "$ASSET_DIR/$asset" "$url"
+  if grep -q " ${asset}\$" "$SUMS_FILE"; then
+    (cd "$ASSET_DIR" && grep " ${asset}\$" "$SUMS_FILE" | sha256sum -c --quiet -) || {
+      rm -f "$ASSET_DIR/$asset"
+      echo "Checksum mismatch for ${asset}" >&2
+      exit 1
+    }
+  else
+    echo "Warning: no pinned checksum for ${asset}" >&2
+  fi
   echo "Saved to $ASSET_DIR/$asset"
 done
@@ -0,0 +1,40 @@
+#!/usr/bin/env bash
+# Downloads every ffmpeg asset lipi can fetch and pins its SHA-256 in
+# internal/ffmpeg/checksums.txt. Run after changing the ffmpeg version and
+# review the diff before committing.
+set -euo pipefail
+
+ROOT_DIR=$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)
+SUMS_FILE="$ROOT_DIR/internal/ffmpeg/checksums.txt"
+VERSION="6.1"
+BASE_URL="https://github.com/ffbinaries/ffbinaries-prebuilt/releases/download"
+
+ASSETS=(
+  "ffmpeg-${VERSION}-linux-64.zip"
+  "ffprobe-${VERSION}-linux-64.zip"
+  "ffmpeg-${VERSION}-linux-32.zip"
+  "ffprobe-${VERSION}-linux-32.zip"
+  "ffmpeg-${VERSION}-linux-arm-64.zip"
+  "ffprobe-${VERSION}-linux-arm-64.zip"
+  "ffmpeg-${VERSION}-linux-armhf-32.zip"
+  "ffprobe-${VERSION}-linux-armhf-32.zip"
+  "ffmpeg-${VERSION}-macos-64.zip"
+  "ffprobe-${VERSION}-macos-64.zip"
+  "ffmpeg-${VERSION}-win-64.zip"
+  "ffprobe-${VERSION}-win-64.zip"
+)
+
+TMP_DIR=$(mktemp -d)
+trap 'rm -rf "$TMP_DIR"' EXIT
+
+{
+  grep '^#' "$SUMS_FILE"
+  for asset in "${ASSETS[@]}"; do
+    echo "Downloading ${asset}..." >&2
+    curl -fsSL -o "$TMP_DIR/$asset" "${BASE_URL}/v${VERSION}/${asset}"
+    (cd "$TMP_DIR" && sha256sum "$asset")
+  done
+} > "$TMP_DIR/checksums.txt"
+
+mv "$TMP_DIR/checksums.txt" "$SUMS_FILE"
+echo "Wrote $SUMS_FILE"
@@ -0,0 +1,37 @@
+#!/usr/bin/env bash
+# Downloads every Noto font lipi fonts check --download can install and pins
+# its SHA-256 in internal/fonts/checksums.txt. Run after changing a font's
+# release in internal/fonts/download.go and review the diff before committing.
+set -euo pipefail
+
+ROOT_DIR=$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)
+SUMS_FILE="$ROOT_DIR/internal/fonts/checksums.txt"
+CJK_BASE_URL="https://github.com/notofonts/noto-cjk/raw/Sans2.004/Sans/OTF"
+NOTO_BASE_URL="https://github.com/notofonts/notofonts.github.io/raw/noto-monthly-release-2024.12.01/fonts"
+
+URLS=(
+  "${CJK_BASE_URL}/Japanese/NotoSansCJKjp-Regular.otf"
+  "${CJK_BASE_URL}/Korean/NotoSansCJKkr-Regular.otf"
+  "${CJK_BASE_URL}/SimplifiedChinese/NotoSansCJKsc-Regular.otf"
+  "${NOTO_BASE_URL}/NotoSansArabic/hinted/ttf/NotoSansArabic-Regular.ttf"
+  "${NOTO_BASE_URL}/NotoSansHebrew/hinted/ttf/NotoSansHebrew-Regular.ttf"
+  "${NOTO_BASE_URL}/NotoSansDevanagari/hinted/ttf/NotoSansDevanagari-Regular.ttf"
+  "${NOTO_BASE_URL}/NotoSansThai/hinted/ttf/NotoSansThai-Regular.ttf"
+  "${NOTO_BASE_URL}/NotoSans/hinted/ttf/NotoSans-Regular.ttf"
+)
+
+TMP_DIR=$(mktemp -d)
+trap 'rm -rf "$TMP_DIR"' EXIT
+
+{
+  grep '^#' "$SUMS_FILE"
+  for url in "${URLS[@]}"; do
+    font=$(basename "$url")
+    echo "Downloading ${font}..." >&2
+    curl -fsSL -o "$TMP_DIR/$font" "$url"
+    (cd "$TMP_DIR" && sha256sum "$font")
+  done
+} > "$TMP_DIR/checksums.txt"
+
+mv "$TMP_DIR/checksums.txt" "$SUMS_FILE"
+echo "Wrote $SUMS_FILE"